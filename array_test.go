@@ -8,6 +8,8 @@ import (
 
 func TestArrayArithmetic(t *testing.T) {
 	quicktestSlice(t, "c(1,2,3) + c(4,5,6)", []float64{5, 7, 9}, 0)
+	quicktestSlice(t, "c(1,2,3,4,5) * c(2,3)", []float64{2, 6, 6, 12, 10}, 0)
+	quicktestSlice(t, "c(1,2,3) / c(2)", []float64{0.5, 1, 1.5}, 0)
 }
 
 func ExampleArrayArithmentic() {
@@ -26,23 +28,51 @@ func ExampleArrayArithmentic() {
 //[1] 1 4 9 16
 }
 
-// TODO these comparisons need further considerations
 func ExampleArrayComparison() {
 	eval.EvalFileForTest("test/operator/array_comparison.r")
 // Output:
-//[1] NaN NaN NaN
-//[1] 1 2 3
+//[1] FALSE FALSE FALSE
+//[1] TRUE TRUE TRUE
 //[1] 1 NaN 3
-//[1] 1 NaN 3
-//[1] NaN 2 NaN
+//[1] TRUE NA TRUE
+//[1] FALSE TRUE FALSE
 //
-//[1] 4 5 6
-//[1] 1 5 6
-//[1] NaN NaN NaN
-//[1] NaN 2 NaN
+//[1] TRUE TRUE TRUE
+//[1] TRUE TRUE TRUE
+//[1] FALSE FALSE FALSE
+//[1] FALSE TRUE FALSE
 //
-//[1] 4 5 6
-//[1] NaN NaN NaN
-//[1] NaN NaN NaN
-//[1] 1 NaN NaN NaN 1
+//[1] TRUE TRUE TRUE
+//[1] FALSE FALSE FALSE
+//[1] FALSE FALSE FALSE
+//[1] TRUE FALSE FALSE FALSE TRUE
+}
+
+func ExampleRecycling() {
+	eval.EvalFileForTest("test/operator/recycling.r")
+// Output:
+//[1] 11 22 13 24
+//[1] 11 22 13
+//Warning message:
+//longer object length is not a multiple of shorter object length
+//a	b	c	d
+//1	4	3	8
+//a	b	c	d
+//1	4	3	8
+//named numeric(0)
+//named logical(0)
+//	[,1]	[,2]
+//[1]	11	13
+//[2]	22	24
+//Error: non-conformable arrays
+//Error: dims [product 4] do not match the length of object [5]
+//[1] TRUE FALSE NA
+//Warning message:
+//longer object length is not a multiple of shorter object length
+//[1] TRUE NA NA
+//a	b
+//FALSE	TRUE
+//a	b	c	d
+//FALSE	TRUE	TRUE	FALSE
+//[1] 0 0 2
 }
@@ -7,49 +7,77 @@ import (
 
 // TODO map on slices of same length instead of single values
 func FEQUAL(x float64, y float64) float64 {
-	if x == y {
+	if anyNA(x, y) {
+		return NA
+	} else if x == y {
 		return x
 	} else {
 		return math.NaN()
 	}
 }
 func FUNEQUAL(x float64, y float64) float64 {
-	if x != y {
+	if anyNA(x, y) {
+		return NA
+	} else if x != y {
 		return x
 	} else {
 		return math.NaN()
 	}
 }
 func FLESS(x float64, y float64) float64 { 
-	if x < y {
+	if anyNA(x, y) {
+		return NA
+	} else if x < y {
 		return y
 	} else {
 		return math.NaN()
 	}
 }
 func FLESSEQUAL(x float64, y float64) float64 { 
-	if x <= y {
+	if anyNA(x, y) {
+		return NA
+	} else if x <= y {
 		return y
 	} else {
 		return math.NaN()
 	}
 }
 func FPLUS(x float64, y float64) float64 { 
+	if anyNA(x, y) {
+		return NA
+	}
 	return x + y
 }
 func FMINUS(x float64, y float64) float64 { 
+	if anyNA(x, y) {
+		return NA
+	}
 	return x - y
 }
 func FMULTIPLICATION(x float64, y float64) float64 { 
+	if anyNA(x, y) {
+		return NA
+	}
 	return x * y
 }
 func FDIVISION(x float64, y float64) float64 { 
+	if anyNA(x, y) {
+		return NA
+	}
 	return x / y
 }
 func FMODULUS(x float64, y float64) float64 { 
+	if anyNA(x, y) {
+		return NA
+	}
 	return math.Mod(x, y)
 }
 func FEXPONENTIATION(x float64, y float64) float64 { 
+	if y == 0 || x == 1 { // even for missing values
+		return 1
+	} else if anyNA(x, y) {
+		return NA
+	}
 	return math.Pow(x, y)
 }
 
@@ -71,18 +99,16 @@ func MapAI(FUN func(float64, float64) float64, x []float64, y float64) []float64
 	return r
 }
 
+// the shorter slice is recycled, a zero-length slice gives a zero-length result
 func MapAA(FUN func(float64, float64) float64, x []float64, y []float64) []float64 {
 	lenx := len(x)
 	leny := len(y)
-	sliceLen := IntMin(lenx,leny)
-	resultLen := IntMax(lenx,leny)
-	
-	r := make([]float64,resultLen)
-
-	for base := 0; base < resultLen; base += sliceLen {
-		for i := base ; (i < (base+sliceLen) && i < resultLen); i++ {
-			r[i] = FUN(x[i % lenx], y[i % leny])
-		}
+	if lenx == 0 || leny == 0 {
+		return []float64{}
+	}
+	r := make([]float64,IntMax(lenx,leny))
+	for i := range r {
+		r[i] = FUN(x[i % lenx], y[i % leny])
 	}
 	return r
 }
@@ -0,0 +1,214 @@
+package calc
+
+import (
+	"math"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/Special.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/Round.html
+// Functions of one or two doubles, which are not covered by package math
+// or differ from R in their special cases. Missing values are checked by the caller.
+
+func Sign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	default:
+		return x // zero or NaN
+	}
+}
+
+// zero and negative integers are poles
+func Gamma(x float64) float64 {
+	if x == 0 || (x < 0 && x == math.Floor(x)) {
+		return math.NaN()
+	}
+	return math.Gamma(x)
+}
+
+func Lgamma(x float64) float64 {
+	r, _ := math.Lgamma(x)
+	return r
+}
+
+func Factorial(x float64) float64 {
+	return Gamma(x + 1)
+}
+
+func Lfactorial(x float64) float64 {
+	return Lgamma(x + 1)
+}
+
+// asymptotic series after shifting the argument above 10, reflection for negative values
+func Digamma(x float64) float64 {
+	switch {
+	case math.IsNaN(x) || math.IsInf(x, 1):
+		return x
+	case math.IsInf(x, -1) || (x <= 0 && x == math.Floor(x)):
+		return math.NaN()
+	}
+	r := 0.0
+	if x < 0 {
+		r = -math.Pi / math.Tan(math.Pi*x)
+		x = 1 - x
+	}
+	for x < 10 {
+		r -= 1 / x
+		x++
+	}
+	f := 1 / (x * x)
+	t := f * (-1.0/12 + f*(1.0/120+f*(-1.0/252+f*(1.0/240+f*(-1.0/132+f*(691.0/32760+f*(-1.0/12)))))))
+	return r + math.Log(x) - 0.5/x + t
+}
+
+// largest argument of gamma without overflow
+const gammaMax = 171.61447887182298
+
+func Beta(a float64, b float64) float64 {
+	switch {
+	case math.IsNaN(a) || math.IsNaN(b):
+		return a + b
+	case a < 0 || b < 0:
+		return math.NaN()
+	case a == 0 || b == 0:
+		return math.Inf(1)
+	case math.IsInf(a, 1) || math.IsInf(b, 1):
+		return 0
+	case a+b < gammaMax:
+		return (1 / math.Gamma(a+b)) * (math.Gamma(a) * math.Gamma(b))
+	default:
+		return math.Exp(Lbeta(a, b))
+	}
+}
+
+func Lbeta(a float64, b float64) float64 {
+	p, q := math.Min(a, b), math.Max(a, b)
+	switch {
+	case math.IsNaN(a) || math.IsNaN(b):
+		return a + b
+	case p < 0:
+		return math.NaN()
+	case p == 0:
+		return math.Inf(1)
+	case math.IsInf(q, 1):
+		return math.Inf(-1)
+	default:
+		return Lgamma(p) + Lgamma(q) - Lgamma(p+q)
+	}
+}
+
+func isInt(x float64) bool {
+	return math.Abs(x-math.RoundToEven(x)) <= 1e-7*math.Max(1, math.Abs(x))
+}
+
+func lfastchoose(n float64, k float64) float64 {
+	return -math.Log(n+1) - Lbeta(n-k+1, k+1)
+}
+
+// small k are multiplied out, k is rounded to an integer
+func Choose(n float64, k float64) float64 {
+	const smallK = 30
+	if math.IsNaN(n) || math.IsNaN(k) {
+		return n + k
+	}
+	k = math.RoundToEven(k)
+	if k < smallK {
+		if n-k < k && n >= 0 && isInt(n) {
+			k = math.RoundToEven(n - k)
+		}
+		if k < 0 {
+			return 0
+		}
+		if k == 0 {
+			return 1
+		}
+		r := n
+		for j := 2.0; j <= k; j++ {
+			r *= (n - j + 1) / j
+		}
+		if isInt(n) {
+			return math.RoundToEven(r)
+		}
+		return r
+	}
+	if n < 0 {
+		r := Choose(-n+k-1, k)
+		if math.Mod(k, 2) != 0 {
+			return -r
+		}
+		return r
+	}
+	if isInt(n) {
+		n = math.RoundToEven(n)
+		if n < k {
+			return 0
+		}
+		if n-k < smallK {
+			return Choose(n, n-k)
+		}
+		return math.RoundToEven(math.Exp(lfastchoose(n, k)))
+	}
+	if n < k-1 {
+		lg, sign := math.Lgamma(n - k + 1)
+		return float64(sign) * math.Exp(Lgamma(n+1)-Lgamma(k+1)-lg)
+	}
+	return math.Exp(lfastchoose(n, k))
+}
+
+// IEC 60559: the nearest of both candidates with the given number of digits,
+// the even one if both are equally near
+func Round(x float64, digits float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) || math.IsNaN(digits) || math.IsInf(digits, 1) {
+		return x
+	}
+	if math.IsInf(digits, -1) {
+		return 0
+	}
+	d := math.RoundToEven(digits)
+	if d == 0 {
+		return math.RoundToEven(x)
+	}
+	if d < 0 {
+		p := math.Pow(10, -d)
+		return math.RoundToEven(x/p) * p
+	}
+	if d > 323 {
+		return x
+	}
+	p := math.Pow(10, d)
+	scaled := x * p
+	if math.IsInf(scaled, 0) || math.Abs(scaled) > 1<<52 {
+		return x
+	}
+	lower, upper := math.Floor(scaled), math.Ceil(scaled)
+	if lower == upper {
+		return lower / p
+	}
+	dl, du := x-lower/p, upper/p-x
+	switch {
+	case dl < du:
+		return lower / p
+	case du < dl:
+		return upper / p
+	case math.Mod(lower, 2) == 0:
+		return lower / p
+	default:
+		return upper / p
+	}
+}
+
+func Signif(x float64, digits float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) || x == 0 || math.IsNaN(digits) {
+		return x
+	}
+	if digits > 22 {
+		return x
+	}
+	if digits < 1 {
+		digits = 1
+	}
+	l10 := math.Floor(math.Log10(math.Abs(x)))
+	return Round(x, math.RoundToEven(digits)-1-l10)
+}
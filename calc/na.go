@@ -0,0 +1,21 @@
+package calc
+
+import (
+	"math"
+)
+
+// https://cran.r-project.org/doc/manuals/R-data.html#Special-values
+// A missing value of type double is a NaN with the lower word 1954.
+// Any other NaN is not a number, but not missing.
+
+var NA = math.Float64frombits(0x7FF00000000007A2)
+
+func IsNA(x float64) bool {
+	return math.IsNaN(x) && uint32(math.Float64bits(x)) == 1954
+}
+
+// as hardware does not reliably propagate the payload of NaNs,
+// missing values are checked before any calculation
+func anyNA(x float64, y float64) bool {
+	return IsNA(x) || IsNA(y)
+}
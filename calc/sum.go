@@ -0,0 +1,92 @@
+package calc
+
+import (
+	"math"
+	"math/big"
+)
+
+// Sums are accumulated with a mantissa of 64 bits like the long double used by R,
+// so that results agree to the last digit. Infinite values and NaN are summed as doubles.
+
+const longDouble = 64
+
+func finite(x []float64) bool {
+	for _, v := range x {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func longSum(x []float64) *big.Float {
+	s := new(big.Float).SetPrec(longDouble)
+	v := new(big.Float).SetPrec(longDouble)
+	for _, f := range x {
+		s.Add(s, v.SetFloat64(f))
+	}
+	return s
+}
+
+// the sum divided by d before rounding to a double
+func SumQuotient(x []float64, d float64) float64 {
+	if !finite(x) || math.IsInf(d, 0) || math.IsNaN(d) || d == 0 {
+		s := 0.0
+		for _, v := range x {
+			s += v
+		}
+		return s / d
+	}
+	s := longSum(x)
+	r, _ := s.Quo(s, big.NewFloat(d)).Float64()
+	return r
+}
+
+func Sum(x []float64) float64 {
+	return SumQuotient(x, 1)
+}
+
+// the mean is refined by the mean of the deviations
+func Mean(x []float64) float64 {
+	n := float64(len(x))
+	if n == 0 {
+		return math.NaN()
+	}
+	if !finite(x) {
+		return SumQuotient(x, n)
+	}
+	count := new(big.Float).SetPrec(longDouble).SetFloat64(n)
+	m := longSum(x)
+	m.Quo(m, count)
+	if f, _ := m.Float64(); math.IsInf(f, 0) {
+		return f
+	}
+	t := new(big.Float).SetPrec(longDouble)
+	v := new(big.Float).SetPrec(longDouble)
+	for _, f := range x {
+		v.SetFloat64(f)
+		t.Add(t, v.Sub(v, m))
+	}
+	m.Add(m, t.Quo(t, count))
+	r, _ := m.Float64()
+	return r
+}
+
+// the running sum is rounded to doubles
+func Cumsum(x []float64) []float64 {
+	r := make([]float64, len(x))
+	s := new(big.Float).SetPrec(longDouble)
+	v := new(big.Float).SetPrec(longDouble)
+	plain := 0.0
+	for n, f := range x {
+		if math.IsNaN(f) || math.IsInf(f, 0) || math.IsNaN(plain) || math.IsInf(plain, 0) {
+			plain += f
+			r[n] = plain
+			continue
+		}
+		s.Add(s, v.SetFloat64(f))
+		r[n], _ = s.Float64()
+		plain = r[n]
+	}
+	return r
+}
@@ -39,3 +39,24 @@ func ExampleDimNames() {
 //ERROR: length of 'dimnames' [2] not equal to array extent
 //ERROR: length of 'dimnames' [1] not equal to array extent
 }
+
+func ExampleMatrixIndex() {
+	eval.EvalFileForTest("test/dimensions/index.r")
+// Output:
+//[1] 6
+//[1] 3 4
+//	[,1]	[,2]	[,3]
+//[1]	1	3	5
+//	b1	b3
+//a1	1	5
+//a2	2	6
+//	b1	b2	b3
+//a1	7	3	5
+//a2	8	4	100
+//Error: incorrect number of dimensions
+//Error: object 'undefined_index' not found
+//Error: invalid subscript type 'list'
+//Error: invalid subscript type 'list'
+//[1] 5
+//Error: can't mix positive and negative subscripts
+}
@@ -10,37 +10,39 @@ func EvalApply(ev *Evaluator, funcname string, f *VSEXP, argNames []string, eval
 
 	ev.openFrame()
 	defer ev.closeFrame()
+	defer func(closure *VSEXP) { ev.closure = closure }(ev.closure)
+	defer func(call string) { ev.call = call }(ev.call)
 
 	if (TRACE || DEBUG) {
-		println("Insert arguments of call to function \"" + funcname + "\" into new top frame:")
+		ev.println("Insert arguments of call to function \"" + funcname + "\" into new top frame:")
 	}
 	for n, fieldname := range argNames {
 		if (TRACE || DEBUG) {
-			print("\targ[",n+1, "]\t", fieldname)
+			ev.print("\targ[",n+1, "]\t", fieldname)
 		}
 		if fieldname != "..." {
 			value := evaluatedArgs[n]
 			if value == nil {
 				defaultExpr := f.Fieldlist[n].Default
 				if defaultExpr == nil { 
-					fmt.Printf("Error in %s() : ", funcname)
-					fmt.Printf("argument \"%s\" is missing, with no default\n", fieldname)
+					fmt.Fprintf(ev.out, "Error in %s() : ", funcname)
+					fmt.Fprintf(ev.out, "argument \"%s\" is missing, with no default\n", fieldname)
 					return nil
 				} else {
 					if DEBUG {
-						print("\tDEFAULT")
+						ev.print("\tDEFAULT")
 					}
 					value = EvalExpr(ev, defaultExpr)
 				}
 			} 
 			if (TRACE || DEBUG) {
-				print("\t= ")
-				PrintResult(value)
+				ev.print("\t= ")
+				PrintResult(ev.log, value)
 			}
 			ev.topFrame.Insert(fieldname, value)
 		} else {
 			if (TRACE || DEBUG) {
-				println()
+				ev.println()
 			}
 		}
 	}
@@ -48,17 +50,18 @@ func EvalApply(ev *Evaluator, funcname string, f *VSEXP, argNames []string, eval
 		DumpFrames(ev)
 	}
 	if (TRACE || DEBUG) {
-		println("Eval body of function \"" + funcname + "\":")
+		ev.println("Eval body of function \"" + funcname + "\":")
 	}
 	if f.Body==nil{
 		panic("EvalCall: body==nil")
 	}
+	ev.closure, ev.call = f, funcname
 	r=EvalStmt(ev, f.Body)
 	if r != nil {
 		if (TRACE || DEBUG) {
-			println("Return from function \"" + funcname + "\" with result: ")
-			PrintResult(r)
-			println("End of result")
+			ev.println("Return from function \"" + funcname + "\" with result: ")
+			PrintResult(ev.log, r)
+			ev.println("End of result")
 		}
 		return r
 	} else {
@@ -70,12 +73,14 @@ func EvalApplyFrameToBody(ev *Evaluator, funcname string, f *VSEXP, frame *Frame
 	TRACE := ev.Trace
 	DEBUG := ev.Debug
 	if (TRACE || DEBUG) {
-		println("EvalApplyFrameToBody \"" + funcname + "\" ENTERING Frame")
+		ev.println("EvalApplyFrameToBody \"" + funcname + "\" ENTERING Frame")
 	}
 
 	frame.Outer = ev.topFrame
 	ev.topFrame = frame
 	defer ev.closeFrame()
+	defer func(closure *VSEXP) { ev.closure = closure }(ev.closure)
+	defer func(call string) { ev.call = call }(ev.call)
 
 	if DEBUG {
 		DumpFrames(ev)
@@ -83,12 +88,13 @@ func EvalApplyFrameToBody(ev *Evaluator, funcname string, f *VSEXP, frame *Frame
 	if f.Body==nil{
 		panic("EvalCall: function body==nil")
 	}
+	ev.closure, ev.call = f, funcname
 	r=EvalStmt(ev, f.Body)
 	if r != nil {
 		if (TRACE || DEBUG) {
-			println("Return from function \"" + funcname + "\" with result: ")
-			PrintResult(r)
-			println("End of result")
+			ev.println("Return from function \"" + funcname + "\" with result: ")
+			PrintResult(ev.log, r)
+			ev.println("End of result")
 		}
 		return r
 	} else {
@@ -10,7 +10,7 @@ import (
 func doAttributeReplacement(ev *Evaluator,lhs *ast.CallExpr, rhs ast.Expr) SEXPItf {
 	TRACE := ev.Trace
 	if TRACE {
-		println("attribute replacement:")
+		ev.println("attribute replacement:")
 	}
 	funcobject := lhs.Fun
 	attribute := funcobject.(*ast.Ident).Name
@@ -36,21 +36,41 @@ func doAttributeReplacement(ev *Evaluator,lhs *ast.CallExpr, rhs ast.Expr) SEXPI
 	case "dimnames":
 		vlen := value.Length()
 		if object.Dim()==nil {
-			fmt.Printf("ERROR: 'dimnames' applied to non-array\n")
+			fmt.Fprintf(ev.out, "ERROR: 'dimnames' applied to non-array\n")
 			return nil
 		} else if vlen != len(object.Dim()) {
-			fmt.Printf("ERROR: length of 'dimnames' [%d] must match that of 'dims' [%d]\n",vlen,len(object.Dim()))
+			fmt.Fprintf(ev.out, "ERROR: length of 'dimnames' [%d] must match that of 'dims' [%d]\n",vlen,len(object.Dim()))
 			return nil
 		} else {
 			slice := value.(*RSEXP).Slice
 			for n,v := range object.Dim() {
 				if slice[n].Length() != v {
-					fmt.Printf("ERROR: length of 'dimnames' [%d] not equal to array extent\n",n+1)
+					fmt.Fprintf(ev.out, "ERROR: length of 'dimnames' [%d] not equal to array extent\n",n+1)
 					return nil
 				}
 			}
 			object.DimnamesSet(value.(*RSEXP))
 		}
+	case "names":
+		if isAtomic(value) { // names are coerced as by as.character
+			value = coerceVector(ev, value, STRSXP)
+		}
+		switch value.(type){
+			case *TSEXP:
+				names := append([]string(nil), stringSlice(value.(*TSEXP))...)
+				if len(names) > object.Length() {
+					fmt.Fprintf(ev.out, "ERROR: 'names' attribute [%d] must be the same length as the vector [%d]\n",len(names),object.Length())
+					return nil
+				}
+				for len(names) < object.Length() {
+					names = append(names, NA_CHARACTER)
+				}
+				object.NamesSet(names)
+			case *NSEXP:
+				object.NamesSet(nil)
+			default:
+				return errorf("invalid 'names' attribute")
+		}
 	case "class":
 		switch value.(type){
 			case *TSEXP:
@@ -67,3 +87,48 @@ func doAttributeReplacement(ev *Evaluator,lhs *ast.CallExpr, rhs ast.Expr) SEXPI
 }
 
 
+
+func init() {
+	registerBuiltin("attr", []string{"x", "which", "exact"}, EvalAttr)
+}
+
+// names, dim, dimnames and class of any object, further attributes of language objects, lists and numeric vectors
+func EvalAttr(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "attr", "argument \"x\" is missing, with no default")
+	}
+	which := args.Values[1]
+	if which == nil || sexpType(which) != STRSXP || which.Length() != 1 {
+		return builtinError(ev, "attr", "exactly one attribute 'which' must be given")
+	}
+	candidates := []string{"names", "dim", "dimnames", "class"}
+	values := []SEXPItf{namesOrNull(x.Names()), nil, nil, nil}
+	if x.Dimnames() != nil {
+		values[2] = x.Dimnames()
+	}
+	if dim := x.Dim(); dim != nil {
+		values[1] = &ISEXP{ValuePos: node.Fun.Pos(), Slice: append([]int{}, dim...)}
+	}
+	if class := x.Class(); class != nil {
+		values[3] = &TSEXP{ValuePos: node.Fun.Pos(), String: *class}
+	}
+	var attributes *RSEXP
+	switch x := x.(type) {
+	case *QSEXP:
+		attributes = x.Attributes
+	case *RSEXP:
+		attributes = x.Attributes
+	case *VSEXP:
+		attributes = x.Attributes
+	}
+	if attributes != nil {
+		candidates = append(candidates, attributes.Names()...)
+		values = append(values, attributes.Slice...)
+	}
+	k := matchName(asStrings(which)[0], candidates, args.logical(2, false))
+	if k < 0 || values[k] == nil {
+		return &NSEXP{ValuePos: node.Fun.Pos()}
+	}
+	return values[k]
+}
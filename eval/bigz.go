@@ -0,0 +1,604 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"roq/lib/ast"
+	"roq/lib/token"
+)
+
+// https://cran.r-project.org/web/packages/gmp/gmp.pdf
+// Exact integers and rationals of any size as the classes bigz and bigq of the package gmp,
+// computed by math/big. Both are vectors of rationals held in external values, those of
+// bigz always with denominator one. Missing values, as the results of a division by zero,
+// are nil.
+//
+// Ordinary numbers are converted exactly in arithmetic: doubles with a fraction give bigq,
+// all other values join bigz. Division always gives bigq, %/% gives bigz and %% keeps the
+// class. as.bigz truncates towards zero, as.bigq keeps the exact value of doubles.
+
+func init() {
+	registerBuiltin("as.bigz", []string{"a"}, EvalAsBigz)
+	registerBuiltin("as.bigq", []string{"n", "d"}, EvalAsBigq)
+	registerBuiltin("is.bigz", []string{"x"}, EvalIsBigz)
+	registerBuiltin("is.bigq", []string{"x"}, EvalIsBigq)
+	registerBuiltin("numerator", []string{"q"}, EvalNumerator)
+	registerBuiltin("denominator", []string{"q"}, EvalDenominator)
+	registerBuiltin("gcd", []string{"a", "b"}, EvalGcd)
+	registerBuiltin("lcm", []string{"a", "b"}, EvalLcm)
+	registerBuiltin("factorialZ", []string{"n"}, EvalFactorialZ)
+	registerBuiltin("chooseZ", []string{"n", "k"}, EvalChooseZ)
+	registerBuiltin("isprime", []string{"n", "reps"}, EvalIsprime)
+	registerBuiltin("%/%", []string{"e1", "e2"}, EvalIntegerDivision)
+}
+
+type bigNumbers struct {
+	rational bool
+	values   []*big.Rat
+}
+
+func newBig(pos token.Pos, rational bool, values []*big.Rat) *XSEXP {
+	class := "bigz"
+	if rational {
+		class = "bigq"
+	}
+	r := &XSEXP{ValuePos: pos, Pointer: &bigNumbers{rational: rational, values: values}}
+	r.ClassSet(&class)
+	return r
+}
+
+func bigValues(x SEXPItf) (*bigNumbers, bool) {
+	if p, ok := x.(*XSEXP); ok {
+		b, ok := p.Pointer.(*bigNumbers)
+		return b, ok
+	}
+	return nil, false
+}
+
+func isBig(x SEXPItf) bool {
+	_, ok := bigValues(x)
+	return ok
+}
+
+func bigTruncate(v *big.Rat) *big.Rat {
+	if v == nil || v.IsInt() {
+		return v
+	}
+	return new(big.Rat).SetInt(new(big.Int).Quo(v.Num(), v.Denom()))
+}
+
+// the values of x as rationals, with truncate only their integer parts. Strings are
+// decimal numbers, fractions as "1/3" or integers with the prefixes 0x, 0o and 0b.
+func asBig(x SEXPItf, truncate bool) (*bigNumbers, bool) {
+	r := &bigNumbers{}
+	if b, ok := bigValues(x); ok {
+		if !truncate {
+			return b, true
+		}
+		r.values = make([]*big.Rat, len(b.values))
+		for k, v := range b.values {
+			r.values[k] = bigTruncate(v)
+		}
+		return r, true
+	}
+	warn := false
+	switch sexpType(x) {
+	case NILSXP:
+	case LGLSXP, INTSXP, REALSXP:
+		for _, v := range asFloats(x, &warn) {
+			var q *big.Rat
+			if !math.IsNaN(v) && !math.IsInf(v, 0) {
+				q = new(big.Rat).SetFloat64(v)
+			}
+			r.values = append(r.values, q)
+		}
+	case STRSXP:
+		for _, s := range asStrings(x) {
+			q, ok := new(big.Rat).SetString(s)
+			if !ok || s == NA_CHARACTER {
+				q = nil
+			}
+			r.values = append(r.values, q)
+		}
+	default:
+		return nil, false
+	}
+	for k, v := range r.values {
+		if truncate {
+			r.values[k] = bigTruncate(v)
+		} else if v != nil && !v.IsInt() {
+			r.rational = true
+		}
+	}
+	return r, true
+}
+
+func bigArgument(ev *Evaluator, funcname string, formal string, x SEXPItf, truncate bool) (*bigNumbers, bool) {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return nil, false
+	}
+	b, ok := asBig(x, truncate)
+	if !ok {
+		builtinError(ev, funcname, "cannot convert '%s' to a big number", formal)
+	}
+	return b, ok
+}
+
+func (b *bigNumbers) strings() []string {
+	r := make([]string, len(b.values))
+	for k, v := range b.values {
+		switch {
+		case v == nil:
+			r[k] = "NA"
+		case b.rational:
+			r[k] = v.RatString()
+		default:
+			r[k] = v.Num().String()
+		}
+	}
+	return r
+}
+
+// conversion by as.vector and its variants, to the nearest doubles or exact strings
+func (b *bigNumbers) vector(x SEXPItf, t SEXPTYPE) SEXPItf {
+	var r SEXPItf
+	if t == STRSXP {
+		r = &TSEXP{ValuePos: x.Pos(), Slice: b.strings()}
+	} else {
+		slice := make([]float64, len(b.values))
+		for k, v := range b.values {
+			slice[k] = math.NaN()
+			if v != nil {
+				slice[k], _ = v.Float64()
+			}
+		}
+		r = &VSEXP{ValuePos: x.Pos(), Slice: slice}
+	}
+	r.NamesSet(x.Names())
+	return r
+}
+
+func printBig(w io.Writer, x *XSEXP, b *bigNumbers) {
+	class, kind := "bigz", "Big Integer ('bigz')"
+	if b.rational {
+		class, kind = "bigq", "Big Rational ('bigq')"
+	}
+	switch len(b.values) {
+	case 0:
+		fmt.Fprintf(w, "%s(0)\n", class)
+		return
+	case 1:
+		fmt.Fprintf(w, "%s :\n", kind)
+	default:
+		fmt.Fprintf(w, "%s object of length %d:\n", kind, len(b.values))
+	}
+	printVector(w, x, b.strings())
+}
+
+func bigPower(u *big.Rat, e int64) *big.Rat {
+	if e < 0 {
+		if u.Sign() == 0 {
+			return nil
+		}
+		u, e = new(big.Rat).Inv(u), -e
+	}
+	num := new(big.Int).Exp(u.Num(), big.NewInt(e), nil)
+	den := new(big.Int).Exp(u.Denom(), big.NewInt(e), nil)
+	return new(big.Rat).SetFrac(num, den)
+}
+
+// the floored division of R, with the remainder of the sign of the divisor
+func bigOp(op string, u *big.Rat, v *big.Rat) *big.Rat {
+	if u == nil || v == nil {
+		return nil
+	}
+	r := new(big.Rat)
+	switch op {
+	case "+":
+		return r.Add(u, v)
+	case "-":
+		return r.Sub(u, v)
+	case "*":
+		return r.Mul(u, v)
+	case "^":
+		return bigPower(u, v.Num().Int64())
+	}
+	if v.Sign() == 0 {
+		return nil
+	}
+	q := r.Quo(u, v)
+	if op == "/" {
+		return q
+	}
+	f := new(big.Rat).SetInt(new(big.Int).Div(q.Num(), q.Denom()))
+	if op == "%/%" {
+		return f
+	}
+	return r.Sub(u, f.Mul(f, v))
+}
+
+// the binary operators with a bigz or bigq operand
+func bigArithmetic(ev *Evaluator, op string, x SEXPItf, y SEXPItf) SEXPItf {
+	a, ok := asBig(x, false)
+	b, ok2 := asBig(y, false)
+	if !ok || !ok2 {
+		return errorf("non-numeric argument to binary operator")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	rational := a.rational || b.rational
+	switch op {
+	case "/":
+		rational = true
+	case "%/%":
+		rational = false
+	case "^":
+		for _, e := range b.values {
+			if e == nil {
+				continue
+			}
+			if !e.IsInt() {
+				return errorf("exponent must be an integer")
+			}
+			if !e.Num().IsInt64() {
+				return errorf("exponent too large")
+			}
+			rational = rational || e.Sign() < 0
+		}
+	}
+	values := make([]*big.Rat, o.length)
+	for k := range values {
+		values[k] = bigOp(op, a.values[k%len(a.values)], b.values[k%len(b.values)])
+	}
+	return o.setAttributes(newBig(x.Pos(), rational, values))
+}
+
+// integer division of ordinary numbers is floored as %%, integers by zero are missing
+func EvalIntegerDivision(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, y := args.Values[0], args.Values[1]
+	if x == nil {
+		return builtinError(ev, "%/%", "argument \"e1\" is missing, with no default")
+	}
+	if y == nil {
+		return builtinError(ev, "%/%", "argument \"e2\" is missing, with no default")
+	}
+	if isBig(x) || isBig(y) {
+		return bigArithmetic(ev, "%/%", x, y)
+	}
+	t, ok := arithmeticType(token.MODULUS, x, y)
+	if !ok {
+		return errorf("non-numeric argument to binary operator")
+	}
+	if t == CPLXSXP {
+		return errorf("invalid operation on complex numbers")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	if t == INTSXP {
+		a, b := asIntegers(x, &warn), asIntegers(y, &warn)
+		r := make([]int, o.length)
+		for k := range r {
+			u, v := a[k%len(a)], b[k%len(b)]
+			r[k] = NA_INTEGER
+			if u != NA_INTEGER && v != NA_INTEGER && v != 0 {
+				r[k] = int(math.Floor(float64(u) / float64(v)))
+			}
+		}
+		if len(r) == 1 && isScalar(x) && isScalar(y) {
+			return o.setAttributes(&ISEXP{Immediate: float64(r[0]), Integer: r[0]})
+		}
+		return o.setAttributes(&ISEXP{Slice: r})
+	}
+	a, b := asFloats(x, &warn), asFloats(y, &warn)
+	r := make([]float64, o.length)
+	for k := range r {
+		r[k] = math.Floor(a[k%len(a)] / b[k%len(b)])
+	}
+	if len(r) == 1 && isScalar(x) && isScalar(y) {
+		return o.setAttributes(&VSEXP{Immediate: r[0]})
+	}
+	return o.setAttributes(&VSEXP{Slice: r})
+}
+
+func EvalAsBigz(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	a, ok := bigArgument(ev, "as.bigz", "a", args.Values[0], true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := newBig(node.Fun.Pos(), false, a.values)
+	r.NamesSet(args.Values[0].Names())
+	return r
+}
+
+func EvalAsBigq(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n, ok := bigArgument(ev, "as.bigq", "n", args.Values[0], false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if d := args.Values[1]; d != nil {
+		r := bigArithmetic(ev, "/", args.Values[0], d)
+		if b, ok := bigValues(r); ok {
+			return newBig(node.Fun.Pos(), true, b.values)
+		}
+		return r
+	}
+	r := newBig(node.Fun.Pos(), true, n.values)
+	r.NamesSet(args.Values[0].Names())
+	return r
+}
+
+func isBigClass(node *ast.CallExpr, x SEXPItf, rational bool) SEXPItf {
+	if b, ok := bigValues(x); ok && b.rational == rational {
+		return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: TRUE}
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: FALSE}
+}
+
+func EvalIsBigz(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return isBigClass(node, args.Values[0], false)
+}
+
+func EvalIsBigq(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return isBigClass(node, args.Values[0], true)
+}
+
+// the parts of reduced fractions, the denominators of missing values are missing too
+func bigParts(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, part func(*big.Rat) *big.Int) SEXPItf {
+	q, ok := bigArgument(ev, funcname, "q", x, false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values := make([]*big.Rat, len(q.values))
+	for k, v := range q.values {
+		if v != nil {
+			values[k] = new(big.Rat).SetInt(part(v))
+		}
+	}
+	r := newBig(node.Fun.Pos(), false, values)
+	r.NamesSet(x.Names())
+	return r
+}
+
+func EvalNumerator(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return bigParts(ev, node, "numerator", args.Values[0], (*big.Rat).Num)
+}
+
+func EvalDenominator(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return bigParts(ev, node, "denominator", args.Values[0], (*big.Rat).Denom)
+}
+
+// a function of two integers recycled as the arithmetic operators, arguments are truncated
+func bigIntegerOp(ev *Evaluator, node *ast.CallExpr, funcname string, args *Arguments, f func(*big.Int, *big.Int) *big.Int) SEXPItf {
+	x, y := args.Values[0], args.Values[1]
+	a, ok := bigArgument(ev, funcname, "a", x, true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	b, ok := bigArgument(ev, funcname, "b", y, true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values := make([]*big.Rat, o.length)
+	for k := range values {
+		u, v := a.values[k%len(a.values)], b.values[k%len(b.values)]
+		if u != nil && v != nil {
+			values[k] = new(big.Rat).SetInt(f(u.Num(), v.Num()))
+		}
+	}
+	return o.setAttributes(newBig(node.Fun.Pos(), false, values))
+}
+
+func EvalGcd(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return bigIntegerOp(ev, node, "gcd", args, func(u *big.Int, v *big.Int) *big.Int {
+		return new(big.Int).GCD(nil, nil, u, v)
+	})
+}
+
+func EvalLcm(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return bigIntegerOp(ev, node, "lcm", args, func(u *big.Int, v *big.Int) *big.Int {
+		if u.Sign() == 0 || v.Sign() == 0 {
+			return new(big.Int)
+		}
+		r := new(big.Int).Mul(u, v)
+		r.Quo(r, new(big.Int).GCD(nil, nil, u, v))
+		return r.Abs(r)
+	})
+}
+
+// factorials of negative numbers are missing
+func EvalFactorialZ(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n, ok := bigArgument(ev, "factorialZ", "n", args.Values[0], true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values := make([]*big.Rat, len(n.values))
+	for k, v := range n.values {
+		if v != nil && v.Sign() >= 0 && v.Num().IsInt64() {
+			values[k] = new(big.Rat).SetInt(new(big.Int).MulRange(1, v.Num().Int64()))
+		}
+	}
+	return newBig(node.Fun.Pos(), false, values)
+}
+
+// the binomial coefficient of any integer n as the product (n-k+1)/1 * ... * n/k,
+// each partial product is itself a binomial coefficient
+func binomialZ(n *big.Int, k int64) *big.Int {
+	r := big.NewInt(1)
+	if k < 0 {
+		return r.SetInt64(0)
+	}
+	t := new(big.Int)
+	for i := int64(1); i <= k; i++ {
+		t.Sub(n, big.NewInt(k-i))
+		r.Mul(r, t)
+		r.Quo(r, big.NewInt(i))
+	}
+	return r
+}
+
+func EvalChooseZ(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return bigIntegerOp(ev, node, "chooseZ", args, func(n *big.Int, k *big.Int) *big.Int {
+		if !k.IsInt64() {
+			return new(big.Int)
+		}
+		return binomialZ(n, k.Int64())
+	})
+}
+
+// 2 for primes, 1 for probable primes above 2^64, where the test of math/big is
+// no longer exact, and 0 for composite numbers
+func EvalIsprime(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n, ok := bigArgument(ev, "isprime", "n", args.Values[0], true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	reps := args.float(1, 40)
+	if math.IsNaN(reps) || reps < 0 {
+		return builtinError(ev, "isprime", "invalid 'reps'")
+	}
+	r := make([]int, len(n.values))
+	for k, v := range n.values {
+		switch {
+		case v == nil:
+			r[k] = NA_INTEGER
+		case !v.Num().ProbablyPrime(int(reps)):
+			r[k] = 0
+		case v.Num().IsUint64():
+			r[k] = 2
+		default:
+			r[k] = 1
+		}
+	}
+	if len(r) == 1 {
+		return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(r[0]), Integer: r[0]}
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Slice: r}
+}
+
+// the comparison operators with a bigz or bigq operand give logicals, missing for NA
+func bigComparison(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	a, ok := asBig(x, false)
+	b, ok2 := asBig(y, false)
+	if !ok || !ok2 {
+		return errorf("comparison of these types is not implemented")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(a.values) == 0 || len(b.values) == 0 {
+		return &LSEXP{ValuePos: x.Pos(), Slice: []int{}}
+	}
+	r := make([]int, o.length)
+	for k := range r {
+		u, v := a.values[k%len(a.values)], b.values[k%len(b.values)]
+		if u == nil || v == nil {
+			r[k] = NA_LOGICAL
+			continue
+		}
+		c := u.Cmp(v)
+		switch op {
+		case token.EQUAL:
+			r[k] = logical(c == 0)
+		case token.UNEQUAL:
+			r[k] = logical(c != 0)
+		case token.LESS:
+			r[k] = logical(c < 0)
+		case token.LESSEQUAL:
+			r[k] = logical(c <= 0)
+		case token.GREATER:
+			r[k] = logical(c > 0)
+		case token.GREATEREQUAL:
+			r[k] = logical(c >= 0)
+		}
+	}
+	if len(r) == 1 {
+		return o.setAttributes(&LSEXP{ValuePos: x.Pos(), Immediate: r[0]})
+	}
+	return o.setAttributes(&LSEXP{ValuePos: x.Pos(), Slice: r})
+}
+
+// sum and prod of arguments of which one is a bigz or bigq, the result is bigq if any
+// argument is
+func bigSummary(ev *Evaluator, pos token.Pos, funcname string, dots []SEXPItf, narm bool) SEXPItf {
+	r, rational := big.NewRat(0, 1), false
+	if funcname == "prod" {
+		r.SetInt64(1)
+	}
+	for _, x := range dots {
+		b, ok := asBig(x, false)
+		if !ok {
+			return builtinError(ev, funcname, "invalid 'type' (%s) of argument", typeName(sexpType(x)))
+		}
+		rational = rational || b.rational
+		for _, v := range b.values {
+			switch {
+			case v == nil && narm:
+			case v == nil || r == nil:
+				r = nil
+			case funcname == "prod":
+				r.Mul(r, v)
+			default:
+				r.Add(r, v)
+			}
+		}
+	}
+	return newBig(pos, rational, []*big.Rat{r})
+}
+
+func hasBig(values []SEXPItf) bool {
+	for _, x := range values {
+		if isBig(x) {
+			return true
+		}
+	}
+	return false
+}
+
+// c() of big numbers and ordinary numbers, bigq if any argument is
+func bigCombine(ev *Evaluator, pos token.Pos, values []SEXPItf) SEXPItf {
+	var r []*big.Rat
+	rational := false
+	for _, x := range values {
+		b, ok := asBig(x, false)
+		if !ok {
+			return builtinError(ev, "c", "cannot convert '%s' to a big number", typeName(sexpType(x)))
+		}
+		rational = rational || b.rational
+		r = append(r, b.values...)
+	}
+	return newBig(pos, rational, r)
+}
+
+// a single subscript selects elements, those out of range are NA
+func bigIndex(ev *Evaluator, array SEXPItf, b *bigNumbers, index []ast.Expr) SEXPItf {
+	subscripts, _ := evalIndexArguments(ev, index)
+	if len(subscripts) != 1 {
+		return errorf("incorrect number of dimensions")
+	}
+	if subscripts[0] == nil {
+		return array
+	}
+	offsets := iteratorOffsets(EvalIndexExpressionToIterator(ev, subscripts[0], array.Length(), array.Names()))
+	values := make([]*big.Rat, len(offsets))
+	for k, offset := range offsets {
+		if offset >= 0 && offset < len(b.values) {
+			values[k] = b.values[offset]
+		}
+	}
+	r := newBig(array.Pos(), b.rational, values)
+	r.NamesSet(selectNames(array.Names(), offsets))
+	return r
+}
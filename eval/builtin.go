@@ -0,0 +1,208 @@
+package eval
+
+import (
+	"fmt"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strconv"
+	"strings"
+)
+
+// Builtins are implemented in Go. Their arguments are evaluated before the call
+// and matched against the formals in the same way as for closures:
+// exact names first, then unique partial names and finally by position.
+// Formals after ... are only matched by their exact name.
+
+type Arguments struct {
+	Values   []SEXPItf // aligned with formals, nil if missing
+	Dots     []SEXPItf // arguments matched by ...
+	DotNames []string
+}
+
+type builtinFunction func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf
+
+type builtin struct {
+	name    string
+	formals []string
+	fun     builtinFunction
+}
+
+var builtins = map[string]*builtin{}
+
+func registerBuiltin(name string, formals []string, fun builtinFunction) {
+	builtins[name] = &builtin{name: name, formals: formals, fun: fun}
+}
+
+// functions in the switch of EvalCallBuiltin, which take their arguments unevaluated
+var internalFunctions = map[string]bool{
+	"c": true, "list": true, "print": true, "pairlist": true, "cat": true, "length": true,
+	"dimnames": true, "names": true, "dim": true, "unlist": true, "typeof": true, "class": true,
+	"as.numeric": true, "as.double": true, "as.integer": true, "as.logical": true,
+	"as.complex": true, "as.character": true, "as.list": true, "as.vector": true,
+	"is.na": true, "is.null": true, "is.numeric": true, "is.double": true, "is.integer": true,
+	"is.logical": true, "is.complex": true, "is.character": true, "is.list": true,
+	"is.atomic": true, "is.function": true,
+}
+
+// a builtin as value, internal functions get their evaluated arguments through a temporary frame
+func lookupBuiltin(name string) *builtin {
+	if b, ok := builtins[name]; ok {
+		return b
+	}
+	if !internalFunctions[name] {
+		return nil
+	}
+	return &builtin{name: name, formals: []string{"..."}, fun: func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+		ev.openFrame()
+		defer ev.closeFrame()
+		exprs := make([]ast.Expr, len(args.Dots))
+		for n, v := range args.Dots {
+			tmp := ".arg" + strconv.Itoa(n+1)
+			ev.topFrame.Insert(tmp, v)
+			exprs[n] = &ast.Ident{NamePos: node.Pos(), Name: tmp}
+			if args.DotNames[n] != "" {
+				exprs[n] = &ast.TaggedExpr{Tag: args.DotNames[n], Rhs: exprs[n]}
+			}
+		}
+		call := &ast.CallExpr{Fun: &ast.Ident{NamePos: node.Pos(), Name: name}, Left: node.Pos(), Args: exprs, Right: node.Pos()}
+		return EvalCall(ev, name, call)
+	}}
+}
+
+// builtinError raises an error of the call of funcname, errorf one without a call. Both
+// return values only to be used in return statements, they never return.
+func builtinError(ev *Evaluator, funcname string, format string, a ...interface{}) SEXPItf {
+	panic(&condition{call: funcname + "()", message: fmt.Sprintf(format, a...)})
+}
+
+func errorf(format string, a ...interface{}) SEXPItf {
+	panic(&condition{message: fmt.Sprintf(format, a...)})
+}
+
+func matchBuiltinArgs(ev *Evaluator, funcname string, formals []string, values []SEXPItf, tags []string) (*Arguments, bool) {
+	dots := len(formals)
+	for k, formal := range formals {
+		if formal == "..." {
+			dots = k
+			break
+		}
+	}
+	r := &Arguments{Values: make([]SEXPItf, len(formals))}
+	filled := make([]bool, len(formals))
+	matched := make([]bool, len(values))
+
+	for n, tag := range tags { // exact matching
+		if tag == "" {
+			continue
+		}
+		for k, formal := range formals {
+			if formal == tag && k != dots {
+				if filled[k] {
+					builtinError(ev, funcname, "formal argument \"%s\" matched by multiple actual arguments", formal)
+					return nil, false
+				}
+				r.Values[k], filled[k], matched[n] = values[n], true, true
+				break
+			}
+		}
+	}
+	for n, tag := range tags { // partial matching
+		if tag == "" || matched[n] {
+			continue
+		}
+		candidate := -1
+		for k := 0; k < dots; k++ {
+			if !filled[k] && strings.HasPrefix(formals[k], tag) {
+				if candidate >= 0 {
+					builtinError(ev, funcname, "argument %d matches multiple formal arguments", n+1)
+					return nil, false
+				}
+				candidate = k
+			}
+		}
+		if candidate >= 0 {
+			r.Values[candidate], filled[candidate], matched[n] = values[n], true, true
+		}
+	}
+	k := 0
+	for n, v := range values { // positional matching
+		if matched[n] {
+			continue
+		}
+		if tags[n] == "" {
+			for k < dots && filled[k] {
+				k++
+			}
+			if k < dots {
+				r.Values[k], filled[k], matched[n] = v, true, true
+				continue
+			}
+		}
+		if dots == len(formals) {
+			if tags[n] != "" {
+				builtinError(ev, funcname, "unused argument (%s = %s)", tags[n], deparseValue(v))
+			} else {
+				builtinError(ev, funcname, "unused argument (%s)", deparseValue(v))
+			}
+			return nil, false
+		}
+		r.Dots = append(r.Dots, v)
+		r.DotNames = append(r.DotNames, tags[n])
+	}
+	return r, true
+}
+
+// short description of a value for error messages
+func deparseValue(v SEXPItf) string {
+	if v == nil || !isAtomic(v) {
+		return typeName(sexpType(v))
+	}
+	s := asStrings(v)
+	if len(s) != 1 {
+		return typeName(sexpType(v))
+	}
+	if s[0] == NA_CHARACTER {
+		return "NA"
+	}
+	if sexpType(v) == STRSXP {
+		return "\"" + s[0] + "\""
+	}
+	return s[0]
+}
+
+func callBuiltin(ev *Evaluator, node *ast.CallExpr, funcname string, b *builtin) SEXPItf {
+	defer un(trace(ev, "builtin "+funcname))
+	values, tags := EvalArgswithDotDotArguments(ev, funcname, node.Args)
+	for _, v := range values {
+		if e, ok := v.(*ESEXP); ok {
+			return e // the error is already reported
+		}
+	}
+	args, ok := matchBuiltinArgs(ev, funcname, b.formals, values, tags)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return b.fun(ev, node, args)
+}
+
+// a missing or NULL argument gives the default, otherwise the first element is used
+func (a *Arguments) logical(k int, def bool) bool {
+	v := a.Values[k]
+	if v == nil || sexpType(v) == NILSXP || !isAtomic(v) || v.Length() == 0 {
+		return def
+	}
+	return asLogicals(v)[0] == TRUE
+}
+
+func (a *Arguments) float(k int, def float64) float64 {
+	v := a.Values[k]
+	if v == nil || sexpType(v) == NILSXP || !isAtomic(v) || v.Length() == 0 {
+		return def
+	}
+	warn := false
+	return asFloats(v, &warn)[0]
+}
+
+func (a *Arguments) missing(k int) bool {
+	return a.Values[k] == nil
+}
@@ -35,21 +35,20 @@ func tryPartialMatch(partial string, argNames []string, collectedArgs []ast.Expr
 	return i,fieldindex 
 }
 
-func arityOK(funcname string, arity int, node *ast.CallExpr) bool {
+func arityOK(ev *Evaluator, funcname string, arity int, node *ast.CallExpr) bool {
 	if len(node.Args) == arity {
 		return true
 	} else {
-		fmt.Printf("%d arguments passed to '%s' which requires %d\n", len(node.Args), funcname, arity)
+		fmt.Fprintf(ev.out, "%d arguments passed to '%s' which requires %d\n", len(node.Args), funcname, arity)
 		return false
 	}
 }
 
 // TODO use results field of funcType
 func EvalCallBuiltin(ev *Evaluator, node *ast.CallExpr, funcname string) (r SEXPItf) {
-	DEBUG := ev.Debug
 	switch funcname {
 	case "print": // TODO arity
-		if arityOK(funcname, 1, node) {
+		if arityOK(ev, funcname, 1, node) {
 			return EvalPrint(ev, node)
 		} else {
 			return &ESEXP{Kind: token.ILLEGAL}
@@ -60,21 +59,31 @@ func EvalCallBuiltin(ev *Evaluator, node *ast.CallExpr, funcname string) (r SEXP
 		return EvalCat(ev, node)
 	// TODO eval arg
 	case "length":
-		if arityOK(funcname, 1, node) {
+		if arityOK(ev, funcname, 1, node) {
 			return EvalLength(ev, node)
 		} else {
 			return &ESEXP{Kind: token.ILLEGAL}
 		}
 	case "dimnames":
-		if arityOK(funcname, 1, node) {
+		if arityOK(ev, funcname, 1, node) {
 			object := EvalExpr(ev, node.Args[0])
 			r := object.Dimnames()
 			return r
 		} else {
 			return &ESEXP{Kind: token.ILLEGAL}
 		}
+	case "names":
+		if arityOK(ev, funcname, 1, node) {
+			object := EvalExpr(ev, node.Args[0])
+			if object.Names() == nil {
+				return &NSEXP{}
+			}
+			return &TSEXP{Slice: object.Names()}
+		} else {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
 	case "dim":
-		if arityOK(funcname, 1, node) {
+		if arityOK(ev, funcname, 1, node) {
 			object := EvalExpr(ev, node.Args[0])
 			r := new(ISEXP)
 			r.DimSet(object.Dim())
@@ -83,21 +92,42 @@ func EvalCallBuiltin(ev *Evaluator, node *ast.CallExpr, funcname string) (r SEXP
 		} else {
 			return &ESEXP{Kind: token.ILLEGAL}
 		}
+	case "as.numeric", "as.double", "as.integer", "as.logical", "as.complex", "as.character", "as.list", "as.vector":
+		return EvalAs(ev, node, funcname)
+	case "is.na", "is.null", "is.numeric", "is.double", "is.integer", "is.logical", "is.complex", "is.character", "is.list", "is.atomic", "is.function":
+		return EvalIs(ev, node, funcname)
+	case "unlist":
+		return EvalUnlist(ev, node)
 	case "typeof":
 		return EvalTypeof(ev, node)
 	case "class":
 		return EvalClass(ev, node)
 	case "remove":
 		for _, arg := range node.Args {
-			ev.topFrame.Delete(arg.(*ast.Ident).Name, DEBUG)
+			ev.topFrame.Delete(ev, arg.(*ast.Ident).Name)
 		}
 	case "options":
-		return nil
+		return EvalOptions(ev, node)
+	case "library", "require", "requireNamespace":
+		return EvalLibrary(ev, node, funcname)
+	case "go":
+		return EvalGo(ev, node)
+	case "select":
+		return EvalSelect(ev, node)
+	case "future":
+		return EvalFuture(ev, node)
+	case "%<-%", "futureAssign":
+		return EvalFutureAssign(ev, node, funcname)
+	case "plan":
+		return EvalPlan(ev, node)
 	case "quit":
 		ev.state = eofState
 		return &ESEXP{Kind: token.EOF}
 	default:
-		fmt.Printf("Error in %s(): could not find function \"%s\"\n",funcname,funcname)
+		if b, ok := builtins[funcname]; ok {
+			return callBuiltin(ev, node, funcname, b)
+		}
+		fmt.Fprintf(ev.out, "Error in %s(): could not find function \"%s\"\n",funcname,funcname)
 		return &ESEXP{Kind: token.ILLEGAL}
 	}
 	return
@@ -153,12 +183,12 @@ func CollectArgs(ev *Evaluator, node *ast.CallExpr, funcname string, argNames []
 	for k, v := range taggedArgs {
 		matches, fieldindex := tryPartialMatch(k, argNames, collectedArgs)
 		if matches > 1 {
-			fmt.Printf("Error in %s() : ",funcname)
-			fmt.Printf("argument %s matches multiple formal arguments\n", k)
+			fmt.Fprintf(ev.out, "Error in %s() : ",funcname)
+			fmt.Fprintf(ev.out, "argument %s matches multiple formal arguments\n", k)
 			return nil, errors.New("Golang error: argument matches multiple formal arguments" )
 		} else if matches == 1 {
 			if TRACE {
-				println("argument", k, "matches one formal argument:", argNames[fieldindex])
+				ev.println("argument", k, "matches one formal argument:", argNames[fieldindex])
 			}
 			collectedArgs[fieldindex] = v
 			delete(taggedArgs, k)
@@ -167,21 +197,21 @@ func CollectArgs(ev *Evaluator, node *ast.CallExpr, funcname string, argNames []
 
 	// check unused named arguments // TODO double check
 	if len(taggedArgs) > 0 {
-		fmt.Printf("Error in %s() : ",funcname)
-		fmt.Printf("unused argument")
+		fmt.Fprintf(ev.out, "Error in %s() : ",funcname)
+		fmt.Fprintf(ev.out, "unused argument")
 		if len(taggedArgs) > 1 {
-			fmt.Printf("s")
+			fmt.Fprintf(ev.out, "s")
 		}
-		fmt.Printf(" (")
+		fmt.Fprintf(ev.out, " (")
 		start := true
 		for k, _ := range taggedArgs {
 			if !start {
-				fmt.Printf(", ")
+				fmt.Fprintf(ev.out, ", ")
 			}
-			fmt.Printf("%s =", k) // TODO: should ast.expressions carry their input string?
+			fmt.Fprintf(ev.out, "%s =", k) // TODO: should ast.expressions carry their input string?
 			start = false
 		}
-		fmt.Printf(")\n")
+		fmt.Fprintf(ev.out, ")\n")
 		return nil, errors.New("Golang error: Unused named arg" )
 	}
 
@@ -197,42 +227,42 @@ func CollectArgs(ev *Evaluator, node *ast.CallExpr, funcname string, argNames []
 
 	// check unused positional arguments
 	if len(untaggedArgs) > j { // CONT
-		fmt.Printf("Error in %s() : ",funcname)
-		fmt.Printf("unused argument")
+		fmt.Fprintf(ev.out, "Error in %s() : ",funcname)
+		fmt.Fprintf(ev.out, "unused argument")
 		if len(untaggedArgs)-j > 1 {
-			fmt.Printf("s")
+			fmt.Fprintf(ev.out, "s")
 		}
-		fmt.Printf(" (")
+		fmt.Fprintf(ev.out, " (")
 		start := true
 		// TODO: some caching
 		for n := len(argNames) + 1; n < len(argNames)+len(untaggedArgs)+1; n++ {
 			if !start {
-				fmt.Printf(", ")
+				fmt.Fprintf(ev.out, ", ")
 			}
-			fmt.Printf("pos %d",n)
+			fmt.Fprintf(ev.out, "pos %d",n)
 			start = false
 		}
-		fmt.Printf(")\n")
+		fmt.Fprintf(ev.out, ")\n")
 		return nil, errors.New("Golang error: Unused positional arg" )
 	}
 	return collectedArgs, nil
 }
 
 func PrintAstExpression(ev *Evaluator, n int, arg ast.Expr){
-	print("\t",n,"=")
+	ev.print("\t",n,"=")
 	switch arg.(type) {
 	case *ast.BasicLit:
 		if arg.(*ast.BasicLit).Kind==token.ELLIPSIS {
-			println("\t\t","...")
+			ev.println("\t\t","...")
 		} else {
-			println(" (literal)\t",arg.(*ast.BasicLit).Value)
+			ev.println(" (literal)\t",arg.(*ast.BasicLit).Value)
 		}
 	default:
 		if arg != nil{
-			print(" (evaluated)\t")
-			PrintResult(EvalExprMute(ev,arg))
+			ev.print(" (evaluated)\t")
+			PrintResult(ev.log, EvalExprMute(ev,arg))
 		} else{
-			println("\tnil")
+			ev.println("\tnil")
 		}
 	}
 }
@@ -243,21 +273,21 @@ func PrintListofAstExpressions(ev *Evaluator, arglist []ast.Expr){
 	}
 }
 
-func PrintListofSExpressions(valuelist []SEXPItf){
+func PrintListofSExpressions(ev *Evaluator, valuelist []SEXPItf){
 	for n,v := range valuelist {
 		if v==nil{
-			println("\tnil")
+			ev.println("\tnil")
 		} else {
-			print("\t",n+1,"= ")
-			PrintResult(v)
+			ev.print("\t",n+1,"= ")
+			PrintResult(ev.log, v)
 		}
 	}
 }
 
-func PrintArgNames(namelist []string){
+func PrintArgNames(ev *Evaluator, namelist []string){
 	for n,arg := range namelist {
-		print("\t",n+1,"=\t")
-		println(arg)
+		ev.print("\t",n+1,"=\t")
+		ev.println(arg)
 	}
 }
 
@@ -269,20 +299,22 @@ func EvalCall(ev *Evaluator, funcname string, node *ast.CallExpr) (r SEXPItf) {
 	DEBUG := ev.Debug
 	if funcname == "c" {
 		if TRACE {
-			println("Call to protected function: " + funcname)
+			ev.println("Call to protected function: " + funcname)
 		}
 		return EvalColumn(ev, node)
 	} else if funcname == "list" {
 		if TRACE {
-			println("Call to protected function: " + funcname)
+			ev.println("Call to protected function: " + funcname)
 		}
 		return EvalList(ev, node)
 	}
 	
 	thefunction := ev.topFrame.Recursive(funcname)
-	if thefunction == nil {
+	if sexpType(thefunction) == BUILTINSXP {
+		return callBuiltin(ev, node, funcname, thefunction.(*VSEXP).builtin)
+	} else if !isFunction(thefunction) { // objects, which are not functions, are skipped
 		if TRACE || DEBUG{
-			println("Call to builtin: " + funcname)
+			ev.println("Call to builtin: " + funcname)
 		}
 		return EvalCallBuiltin(ev, node, funcname)
 	} else {
@@ -290,7 +322,7 @@ func EvalCall(ev *Evaluator, funcname string, node *ast.CallExpr) (r SEXPItf) {
 			return EvalCallwithEllipsis(ev, node, thefunction)
 		} else {
 			if TRACE {
-				println("Call to function: " + funcname)
+				ev.println("Call to function: " + funcname)
 			}
 			argNames := getArgNames(thefunction.(*VSEXP))
 			collectedArgs, err := CollectArgs(ev, node, funcname, argNames)
@@ -311,14 +343,14 @@ func EvalArgs(ev *Evaluator, funcname string, collectedArgs []ast.Expr) ([]SEXPI
 	evaluatedArgs := make([]SEXPItf, len(collectedArgs))
 
 	if DEBUG {
-		println("Eval args for function \"" + funcname + "\":")
+		ev.println("Eval args for function \"" + funcname + "\":")
 	}
 	for n, v := range collectedArgs {
 		if v != nil {
 			val := EvalExprOrAssignment(ev, v)
 			if DEBUG {
-				print("\targ[",n,"] = ")
-				PrintResult(val)
+				ev.print("\targ[",n,"] = ")
+				PrintResult(ev.log, val)
 			}
 			evaluatedArgs[int(n)] = val
 		}
@@ -0,0 +1,383 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"strconv"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/printCoefmat.html
+// Summaries of models and tests are printed as R does: numbers of a column are
+// formatted together with a common number of decimals, p-values by format.pval and
+// tables right justified in columns of equal width.
+
+// the exponent and the number of significant digits of x shown with at most digits digits
+func scientific(x float64, digits int) (int, int) {
+	if x == 0 {
+		return 0, 1
+	}
+	s := strconv.FormatFloat(math.Abs(x), 'e', digits-1, 64)
+	e := strings.IndexByte(s, 'e')
+	kpower, _ := strconv.Atoi(s[e+1:])
+	mantissa := strings.TrimRight(strings.Replace(s[:e], ".", "", 1), "0")
+	if mantissa == "" {
+		return kpower, 1
+	}
+	return kpower, len(mantissa)
+}
+
+func formatNonFinite(x float64) string {
+	switch {
+	case calc.IsNA(x):
+		return "NA"
+	case math.IsNaN(x):
+		return "NaN"
+	case math.IsInf(x, 1):
+		return "Inf"
+	}
+	return "-Inf"
+}
+
+// R's format(x, digits = digits): fixed notation with the decimals needed to show each value
+// with digits significant digits, unless scientific notation is narrower
+func formatReal(values []float64, digits int) []string {
+	neg, finite := false, false
+	mxsl, rgt, mxns, mxe := 1, 0, 1, 1
+	for _, x := range values {
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			continue
+		}
+		finite = true
+		kpower, nsig := scientific(x, digits)
+		sleft := 1
+		if kpower >= 1 {
+			sleft = kpower + 1
+		}
+		if x < 0 {
+			neg = true
+			sleft++
+		}
+		if sleft > mxsl {
+			mxsl = sleft
+		}
+		if r := nsig - kpower - 1; r > rgt {
+			rgt = r
+		}
+		if nsig > mxns {
+			mxns = nsig
+		}
+		if kpower >= 100 || kpower <= -100 {
+			mxe = 2
+		}
+	}
+	fixed := true
+	if finite {
+		wF := mxsl + rgt
+		if rgt > 0 {
+			wF++
+		}
+		wE := mxns + 3 + mxe
+		if mxns > 1 {
+			wE++
+		}
+		if neg {
+			wE++
+		}
+		fixed = wF <= wE
+	}
+	r := make([]string, len(values))
+	for k, x := range values {
+		if x == 0 {
+			x = 0 // without the sign of negative zero
+		}
+		switch {
+		case math.IsNaN(x) || math.IsInf(x, 0):
+			r[k] = formatNonFinite(x)
+		case fixed:
+			r[k] = strconv.FormatFloat(x, 'f', rgt, 64)
+		default:
+			r[k] = strconv.FormatFloat(x, 'e', mxns-1, 64)
+		}
+	}
+	return justify(r, true)
+}
+
+// strings padded to a common width
+func justify(values []string, right bool) []string {
+	width := 0
+	for _, s := range values {
+		if len(s) > width {
+			width = len(s)
+		}
+	}
+	r := make([]string, len(values))
+	for k, s := range values {
+		if right {
+			r[k] = strings.Repeat(" ", width-len(s)) + s
+		} else {
+			r[k] = s + strings.Repeat(" ", width-len(s))
+		}
+	}
+	return r
+}
+
+// R's formatC(x, digits = digits) as in C's %g
+func formatC(x float64, digits int) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return formatNonFinite(x)
+	}
+	return strconv.FormatFloat(x, 'g', digits, 64)
+}
+
+// rounded to decimals, which may be negative
+func roundDecimals(x float64, decimals int) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	p := math.Pow(10, float64(decimals))
+	return math.Round(x*p) / p
+}
+
+func signif(x float64, digits int) float64 {
+	if x == 0 || math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	return roundDecimals(x, digits-1-int(math.Floor(math.Log10(math.Abs(x)))))
+}
+
+// values close to zero compared to the largest one are rounded to zero
+func zapsmall(values []float64, digits int) []float64 {
+	mx := 0.
+	for _, x := range values {
+		if !math.IsNaN(x) && !math.IsInf(x, 0) && math.Abs(x) > mx {
+			mx = math.Abs(x)
+		}
+	}
+	decimals := digits
+	if mx > 0 {
+		decimals = int(math.Floor(float64(digits) - math.Log10(mx) + 0.5))
+		if decimals < 0 {
+			decimals = 0
+		}
+	}
+	r := make([]float64, len(values))
+	for k, x := range values {
+		r[k] = roundDecimals(x, decimals)
+	}
+	return r
+}
+
+// R's format.pval: values below eps are shown as bound, small values in scientific notation
+func formatPval(values []float64, digits int) []string {
+	const eps = 2.220446049250313e-16
+	r := make([]string, len(values))
+	var fixed, small []int
+	for k, p := range values {
+		switch {
+		case math.IsNaN(p):
+			r[k] = "NA"
+		case p < eps:
+		case p == 0 || math.Floor(math.Log10(p)) >= -3:
+			fixed = append(fixed, k)
+		default:
+			small = append(small, k)
+		}
+	}
+	width := 0
+	for _, group := range [][]int{fixed, small} {
+		x := make([]float64, len(group))
+		for j, k := range group {
+			x[j] = values[k]
+		}
+		for j, s := range formatReal(x, digits) {
+			r[group[j]] = s
+			if len(s) > width {
+				width = len(s)
+			}
+		}
+	}
+	d := digits - 2
+	if d < 1 {
+		d = 1
+	}
+	sep := " "
+	if len(fixed)+len(small) > 0 {
+		if d > 1 && d+6 > width {
+			d = width - 7
+			if d < 1 {
+				d = 1
+			}
+		}
+		if d == 1 && width <= 6 {
+			sep = ""
+		}
+	} else if d == 1 {
+		sep = ""
+	}
+	for k, p := range values {
+		if !math.IsNaN(p) && p < eps {
+			r[k] = "<" + sep + formatReal([]float64{eps}, d)[0]
+		}
+	}
+	return r
+}
+
+func significanceStars(p float64) string {
+	switch {
+	case math.IsNaN(p):
+		return ""
+	case p <= 0.001:
+		return "***"
+	case p <= 0.01:
+		return "**"
+	case p <= 0.05:
+		return "*"
+	case p <= 0.1:
+		return "."
+	}
+	return " "
+}
+
+const significanceLegend = "Signif. codes:  0 ‘***’ 0.001 ‘**’ 0.01 ‘*’ 0.05 ‘.’ 0.1 ‘ ’ 1"
+
+// a character matrix with row names left and columns right justified, as print(quote = FALSE, right = TRUE)
+func printTable(w io.Writer, rownames []string, colnames []string, columns [][]string) {
+	lines := justify(append([]string{""}, rownames...), false)
+	for k, c := range columns {
+		c = justify(append([]string{colnames[k]}, c...), true)
+		for row := range lines {
+			lines[row] += " " + c[row]
+		}
+	}
+	for _, line := range lines {
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// a named vector with names above values in columns of common width
+func printNamedColumns(w io.Writer, names []string, values []string, gap int) {
+	cells := justify(append(append([]string{}, names...), values...), true)
+	sep := strings.Repeat(" ", gap)
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(cells[:len(names)], sep), " "))
+	fmt.Fprintln(w, strings.TrimRight(strings.Join(cells[len(names):], sep), " "))
+}
+
+// a table of estimates, test statistics and p-values
+type coefTable struct {
+	rownames  []string
+	colnames  []string
+	columns   [][]float64 // missing values as NaN
+	digits    int
+	estimates []int  // coefficients and standard errors, formatted together
+	tests     []int  // test statistics
+	zap       []int  // columns with small values rounded to zero
+	pvalue    bool   // the last column holds p-values
+	naPrint   string // shown for missing values
+}
+
+func contains(indices []int, k int) bool {
+	for _, i := range indices {
+		if i == k {
+			return true
+		}
+	}
+	return false
+}
+
+// as R's printCoefmat with significance stars
+func (t *coefTable) print(w io.Writer) {
+	nc := len(t.columns)
+	cells := make([][]string, nc)
+	columns := make([][]float64, nc)
+	for k, c := range t.columns {
+		columns[k] = c
+		if contains(t.zap, k) {
+			columns[k] = zapsmall(c, t.digits)
+		}
+	}
+	digTst := t.digits - 1
+	if digTst > 5 {
+		digTst = 5
+	} else if digTst < 1 {
+		digTst = 1
+	}
+	if len(t.estimates) > 0 {
+		var values []float64
+		lo := math.Inf(1)
+		for _, k := range t.estimates {
+			for _, x := range columns[k] {
+				values = append(values, x)
+				if a := math.Abs(x); a != 0 && !math.IsNaN(a) && !math.IsInf(a, 0) {
+					lo = math.Min(lo, a)
+				}
+			}
+		}
+		digmin := 1 // the decimals are chosen for the smallest value
+		if !math.IsInf(lo, 1) {
+			digmin = 1 + int(math.Floor(math.Log10(lo)))
+		}
+		decimals := t.digits - digmin
+		if decimals < 1 {
+			decimals = 1
+		}
+		for k := range values {
+			values[k] = roundDecimals(values[k], decimals)
+		}
+		formatted := formatReal(values, t.digits)
+		rows := len(t.rownames)
+		for j, k := range t.estimates {
+			cells[k] = formatted[j*rows : (j+1)*rows]
+		}
+	}
+	for k := range t.columns {
+		switch {
+		case contains(t.estimates, k):
+		case contains(t.tests, k):
+			values := make([]float64, len(columns[k]))
+			for row, x := range columns[k] {
+				values[row] = roundDecimals(x, digTst)
+			}
+			cells[k] = formatReal(values, t.digits)
+		case t.pvalue && k == nc-1:
+			cells[k] = formatPval(columns[k], digTst)
+		default:
+			cells[k] = formatReal(columns[k], t.digits)
+		}
+		missing := false
+		for row, x := range t.columns[k] {
+			if math.IsNaN(x) {
+				cells[k][row] = t.naPrint
+				missing = true
+			}
+		}
+		if missing { // the width of the values without the missing ones
+			for row, s := range cells[k] {
+				cells[k][row] = strings.TrimLeft(s, " ")
+			}
+			cells[k] = justify(cells[k], true)
+		}
+	}
+	colnames := t.colnames
+	stars := false
+	if t.pvalue {
+		for _, p := range t.columns[nc-1] {
+			stars = stars || p < 0.1
+		}
+	}
+	if stars {
+		signif := make([]string, len(t.rownames))
+		for row, p := range t.columns[nc-1] {
+			signif[row] = significanceStars(p)
+		}
+		cells = append(cells, justify(signif, false))
+		colnames = append(append([]string{}, colnames...), "")
+	}
+	printTable(w, t.rownames, colnames, cells)
+	if stars {
+		fmt.Fprintln(w, "---")
+		fmt.Fprintln(w, significanceLegend)
+	}
+}
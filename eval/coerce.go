@@ -0,0 +1,858 @@
+package eval
+
+import (
+	"math"
+	"math/cmplx"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strconv"
+	"strings"
+)
+
+// https://cran.r-project.org/doc/manuals/R-lang.html#Vector-objects
+//
+// The output type is determined from the highest type of the components in the hierarchy
+// NULL < raw < logical < integer < double < complex < character < list
+// raw vectors are not supported yet.
+
+func sexpType(x SEXPItf) SEXPTYPE {
+	switch x.(type) {
+	case nil, *NSEXP:
+		return NILSXP
+	case *LSEXP:
+		return LGLSXP
+	case *ISEXP:
+		return INTSXP
+	case *VSEXP:
+		if x.(*VSEXP).Body != nil {
+			return CLOSXP
+		}
+		if x.(*VSEXP).builtin != nil {
+			return BUILTINSXP
+		}
+		return REALSXP
+	case *CSEXP:
+		return CPLXSXP
+	case *TSEXP:
+		return STRSXP
+	case *RSEXP:
+		return VECSXP
+	case *XSEXP:
+		return EXTPTRSXP
+	default:
+		return ANYSXP
+	}
+}
+
+func coercionRank(t SEXPTYPE) int {
+	switch t {
+	case NILSXP:
+		return 0
+	case RAWSXP:
+		return 1
+	case LGLSXP:
+		return 2
+	case INTSXP:
+		return 3
+	case REALSXP:
+		return 4
+	case CPLXSXP:
+		return 5
+	case STRSXP:
+		return 6
+	default:
+		return 7 // everything else can only be kept in a list
+	}
+}
+
+func highestType(values []SEXPItf) SEXPTYPE {
+	var r SEXPTYPE = NILSXP
+	for _, v := range values {
+		t := sexpType(v)
+		if coercionRank(t) > coercionRank(r) {
+			r = t
+		}
+	}
+	if coercionRank(r) == 7 {
+		return VECSXP
+	}
+	return r
+}
+
+func isFunction(x SEXPItf) bool {
+	t := sexpType(x)
+	return t == CLOSXP || t == BUILTINSXP
+}
+
+func isAtomic(x SEXPItf) bool {
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP, CPLXSXP, STRSXP:
+		return true
+	default:
+		return false
+	}
+}
+
+// formatting of doubles as done by as.character: up to 15 significant digits,
+// scientific format only if shorter
+func formatNumber(v float64) string {
+	switch {
+	case calc.IsNA(v):
+		return "NA"
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	sci := strconv.FormatFloat(v, 'e', 14, 64)
+	mantissa, exponent := sci[:strings.IndexByte(sci, 'e')], sci[strings.IndexByte(sci, 'e'):]
+	if strings.Contains(mantissa, ".") {
+		mantissa = strings.TrimRight(strings.TrimRight(mantissa, "0"), ".")
+	}
+	sci = mantissa + exponent
+	rounded, _ := strconv.ParseFloat(sci, 64)
+	fixed := strconv.FormatFloat(rounded, 'f', -1, 64)
+	if len(fixed) <= len(sci) {
+		return fixed
+	}
+	return sci
+}
+
+func formatInteger(v int) string {
+	if v == NA_INTEGER {
+		return "NA"
+	}
+	return strconv.Itoa(v)
+}
+
+func formatComplex(v complex128) string {
+	if calc.IsNA(real(v)) || calc.IsNA(imag(v)) {
+		return "NA"
+	}
+	im := formatNumber(imag(v))
+	if !strings.HasPrefix(im, "-") {
+		im = "+" + im
+	}
+	return formatNumber(real(v)) + im + "i"
+}
+
+// strings are parsed like numeric literals, surrounding white space is ignored
+func parseNumber(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "NA" || s == NA_CHARACTER {
+		return calc.NA, true
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		v, err := strconv.ParseInt(s[2:], 16, 64)
+		return float64(v), err == nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && err.(*strconv.NumError).Err == strconv.ErrRange {
+		return v, true
+	}
+	return v, err == nil
+}
+
+var naComplex = complex(calc.NA, calc.NA)
+
+func isNAComplex(v complex128) bool {
+	return calc.IsNA(real(v)) || calc.IsNA(imag(v))
+}
+
+func parseLogical(s string) int {
+	switch s {
+	case "T", "TRUE", "true", "True":
+		return TRUE
+	case "F", "FALSE", "false", "False":
+		return FALSE
+	default:
+		return NA_LOGICAL
+	}
+}
+
+// lists can be coerced to atomic vectors if all elements are of length one
+func listAtoms(ev *Evaluator, x *RSEXP, typename string) ([]SEXPItf, bool) {
+	for _, v := range x.Slice {
+		if !isAtomic(v) || v.Length() != 1 {
+			errorf("(list) object cannot be coerced to type '%s'", typename)
+			return nil, false
+		}
+	}
+	return x.Slice, true
+}
+
+// the following conversions return a new slice for all types
+// a flag is set, when missing values had to be introduced
+
+func asLogicals(x SEXPItf) []int {
+	switch x.(type) {
+	case *LSEXP:
+		return append([]int(nil), logicalSlice(x.(*LSEXP))...)
+	case *ISEXP:
+		slice := integerSlice(x.(*ISEXP))
+		r := make([]int, len(slice))
+		for n, v := range slice {
+			switch v {
+			case NA_INTEGER:
+				r[n] = NA_LOGICAL
+			case 0:
+				r[n] = FALSE
+			default:
+				r[n] = TRUE
+			}
+		}
+		return r
+	case *VSEXP:
+		slice := floatSlice(x.(*VSEXP))
+		r := make([]int, len(slice))
+		for n, v := range slice {
+			switch {
+			case math.IsNaN(v):
+				r[n] = NA_LOGICAL
+			case v == 0:
+				r[n] = FALSE
+			default:
+				r[n] = TRUE
+			}
+		}
+		return r
+	case *CSEXP:
+		slice := complexSlice(x.(*CSEXP))
+		r := make([]int, len(slice))
+		for n, v := range slice {
+			switch {
+			case cmplx.IsNaN(v):
+				r[n] = NA_LOGICAL
+			case v == 0:
+				r[n] = FALSE
+			default:
+				r[n] = TRUE
+			}
+		}
+		return r
+	case *TSEXP:
+		slice := stringSlice(x.(*TSEXP))
+		r := make([]int, len(slice))
+		for n, v := range slice {
+			r[n] = parseLogical(v)
+		}
+		return r
+	case *RSEXP:
+		var r []int
+		for _, v := range x.(*RSEXP).Slice {
+			r = append(r, asLogicals(v)...)
+		}
+		return r
+	default:
+		return []int{}
+	}
+}
+
+func asIntegers(x SEXPItf, warn *bool) []int {
+	switch x.(type) {
+	case *LSEXP:
+		return append([]int(nil), logicalSlice(x.(*LSEXP))...)
+	case *ISEXP:
+		return append([]int(nil), integerSlice(x.(*ISEXP))...)
+	case *VSEXP, *CSEXP, *TSEXP:
+		floats := asFloats(x, warn)
+		r := make([]int, len(floats))
+		for n, v := range floats {
+			switch {
+			case math.IsNaN(v):
+				r[n] = NA_INTEGER
+			case math.Abs(v) >= math.MaxInt32+1:
+				r[n] = NA_INTEGER
+				*warn = true
+			default:
+				r[n] = int(v)
+			}
+		}
+		return r
+	case *RSEXP:
+		var r []int
+		for _, v := range x.(*RSEXP).Slice {
+			r = append(r, asIntegers(v, warn)...)
+		}
+		return r
+	default:
+		return []int{}
+	}
+}
+
+func asFloats(x SEXPItf, warn *bool) []float64 {
+	switch x.(type) {
+	case *LSEXP, *ISEXP:
+		var slice []int
+		if l, ok := x.(*LSEXP); ok {
+			slice = logicalSlice(l)
+		} else {
+			slice = integerSlice(x.(*ISEXP))
+		}
+		r := make([]float64, len(slice))
+		for n, v := range slice {
+			if v == NA_INTEGER {
+				r[n] = calc.NA
+			} else {
+				r[n] = float64(v)
+			}
+		}
+		return r
+	case *VSEXP:
+		return append([]float64(nil), floatSlice(x.(*VSEXP))...)
+	case *CSEXP:
+		slice := complexSlice(x.(*CSEXP))
+		r := make([]float64, len(slice))
+		for n, v := range slice {
+			r[n] = real(v)
+		}
+		return r
+	case *TSEXP:
+		slice := stringSlice(x.(*TSEXP))
+		r := make([]float64, len(slice))
+		for n, v := range slice {
+			f, ok := parseNumber(v)
+			if !ok {
+				f = calc.NA
+				*warn = true
+			}
+			r[n] = f
+		}
+		return r
+	case *RSEXP:
+		var r []float64
+		for _, v := range x.(*RSEXP).Slice {
+			r = append(r, asFloats(v, warn)...)
+		}
+		return r
+	default:
+		return []float64{}
+	}
+}
+
+func asComplexes(x SEXPItf, warn *bool) []complex128 {
+	switch x.(type) {
+	case *CSEXP:
+		return append([]complex128(nil), complexSlice(x.(*CSEXP))...)
+	case *TSEXP:
+		slice := stringSlice(x.(*TSEXP))
+		r := make([]complex128, len(slice))
+		for n, v := range slice {
+			if f, ok := parseNumber(v); ok {
+				r[n] = complex(f, 0)
+			} else if c, err := strconv.ParseComplex(strings.TrimSpace(v), 128); err == nil {
+				r[n] = c
+			} else {
+				r[n] = naComplex
+				*warn = true
+			}
+		}
+		return r
+	case *RSEXP:
+		var r []complex128
+		for _, v := range x.(*RSEXP).Slice {
+			r = append(r, asComplexes(v, warn)...)
+		}
+		return r
+	default:
+		floats := asFloats(x, warn)
+		r := make([]complex128, len(floats))
+		for n, v := range floats {
+			if calc.IsNA(v) {
+				r[n] = naComplex
+			} else {
+				r[n] = complex(v, 0)
+			}
+		}
+		return r
+	}
+}
+
+func asStrings(x SEXPItf) []string {
+	switch x.(type) {
+	case *LSEXP:
+		slice := logicalSlice(x.(*LSEXP))
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = formatLogical(v)
+			if v == NA_LOGICAL {
+				r[n] = NA_CHARACTER
+			}
+		}
+		return r
+	case *ISEXP:
+		slice := integerSlice(x.(*ISEXP))
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = formatInteger(v)
+			if v == NA_INTEGER {
+				r[n] = NA_CHARACTER
+			}
+		}
+		return r
+	case *VSEXP:
+		slice := floatSlice(x.(*VSEXP))
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = formatNumber(v)
+			if calc.IsNA(v) {
+				r[n] = NA_CHARACTER
+			}
+		}
+		return r
+	case *CSEXP:
+		slice := complexSlice(x.(*CSEXP))
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = formatComplex(v)
+			if isNAComplex(v) {
+				r[n] = NA_CHARACTER
+			}
+		}
+		return r
+	case *TSEXP:
+		return append([]string(nil), stringSlice(x.(*TSEXP))...)
+	case *RSEXP:
+		var r []string
+		for _, v := range x.(*RSEXP).Slice {
+			r = append(r, asStrings(v)...)
+		}
+		return r
+	default:
+		return []string{}
+	}
+}
+
+// elements of atomic vectors become vectors of length one
+func asElements(ev *Evaluator, x SEXPItf) []SEXPItf {
+	switch x.(type) {
+	case *RSEXP:
+		return append([]SEXPItf(nil), x.(*RSEXP).Slice...)
+	case nil, *NSEXP:
+		return []SEXPItf{}
+	default:
+		if !isAtomic(x) {
+			return []SEXPItf{x}
+		}
+		r := make([]SEXPItf, x.Length())
+		for n := range r {
+			r[n] = listElement(ev, x, n)
+		}
+		return r
+	}
+}
+
+// the result is a vector of the given type, which keeps the form of scalars
+func newVector(ev *Evaluator, t SEXPTYPE, pos token.Pos, x SEXPItf, scalar bool, warn *bool) SEXPItf {
+	switch t {
+	case LGLSXP:
+		slice := asLogicals(x)
+		if scalar && len(slice) == 1 {
+			return &LSEXP{ValuePos: pos, Immediate: slice[0]}
+		}
+		return &LSEXP{ValuePos: pos, Slice: slice}
+	case INTSXP:
+		slice := asIntegers(x, warn)
+		if scalar && len(slice) == 1 {
+			return &ISEXP{ValuePos: pos, Immediate: float64(slice[0]), Integer: slice[0]}
+		}
+		return &ISEXP{ValuePos: pos, Slice: slice}
+	case REALSXP:
+		slice := asFloats(x, warn)
+		if scalar && len(slice) == 1 {
+			return &VSEXP{ValuePos: pos, Immediate: slice[0]}
+		}
+		return &VSEXP{ValuePos: pos, Slice: slice}
+	case CPLXSXP:
+		slice := asComplexes(x, warn)
+		if scalar && len(slice) == 1 {
+			return &CSEXP{ValuePos: pos, Immediate: slice[0]}
+		}
+		return &CSEXP{ValuePos: pos, Slice: slice}
+	case STRSXP:
+		slice := asStrings(x)
+		if scalar && len(slice) == 1 {
+			return &TSEXP{ValuePos: pos, String: slice[0]}
+		}
+		return &TSEXP{ValuePos: pos, Slice: slice}
+	case VECSXP:
+		return &RSEXP{ValuePos: pos, Slice: asElements(ev, x)}
+	default:
+		return &NSEXP{ValuePos: pos}
+	}
+}
+
+func isScalar(x SEXPItf) bool {
+	switch x.(type) {
+	case *LSEXP:
+		return x.(*LSEXP).Slice == nil
+	case *ISEXP:
+		return x.(*ISEXP).Slice == nil
+	case *VSEXP:
+		return x.(*VSEXP).Slice == nil
+	case *CSEXP:
+		return x.(*CSEXP).Slice == nil
+	case *TSEXP:
+		return x.(*TSEXP).Slice == nil
+	default:
+		return false
+	}
+}
+
+// coercion keeps names, dim and dimnames
+func coerceVector(ev *Evaluator, x SEXPItf, t SEXPTYPE) SEXPItf {
+	if sexpType(x) == t {
+		return x
+	}
+	if x == nil {
+		x = &NSEXP{}
+	}
+	warn := false
+	r := newVector(ev, t, x.Pos(), x, isScalar(x), &warn)
+	if warn {
+		ev.warning("", "NAs introduced by coercion")
+	}
+	if x != nil {
+		r.NamesSet(x.Names())
+		r.DimSet(x.Dim())
+		r.DimnamesSet(x.Dimnames())
+	}
+	return r
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/c.html
+// names of the arguments are combined with the names of their elements
+func elementNames(tag string, x SEXPItf) []string {
+	length := x.Length()
+	if _, ok := x.(*NSEXP); ok || x == nil {
+		return nil
+	}
+	if !isAtomic(x) && sexpType(x) != VECSXP {
+		length = 1
+	}
+	names := x.Names()
+	r := make([]string, length)
+	for n := range r {
+		var name string
+		if n < len(names) {
+			name = names[n]
+		}
+		switch {
+		case tag == "":
+			r[n] = name
+		case name != "":
+			r[n] = tag + "." + name
+		case length == 1:
+			r[n] = tag
+		default:
+			r[n] = tag + strconv.Itoa(n+1)
+		}
+	}
+	return r
+}
+
+// nested lists are expanded into their elements, if recursive
+func flattenLists(values []SEXPItf, tags []string) ([]SEXPItf, []string) {
+	var flatValues []SEXPItf
+	var flatTags []string
+	for n, v := range values {
+		if list, ok := v.(*RSEXP); ok {
+			innerValues, innerTags := flattenLists(list.Slice, elementNames(tags[n], list))
+			flatValues = append(flatValues, innerValues...)
+			flatTags = append(flatTags, innerTags...)
+		} else {
+			flatValues = append(flatValues, v)
+			flatTags = append(flatTags, tags[n])
+		}
+	}
+	return flatValues, flatTags
+}
+
+// combine is the common part of c() and unlist()
+func combine(ev *Evaluator, pos token.Pos, values []SEXPItf, tags []string, recursive bool, useNames bool) SEXPItf {
+	if tags == nil {
+		tags = make([]string, len(values))
+	}
+	if recursive {
+		values, tags = flattenLists(values, tags)
+	}
+	t := highestType(values)
+	if t == NILSXP {
+		return &NSEXP{ValuePos: pos}
+	}
+	var names []string
+	named := false
+	for n, v := range values {
+		vnames := elementNames(tags[n], v)
+		for _, name := range vnames {
+			if name != "" {
+				named = true
+			}
+		}
+		names = append(names, vnames...)
+	}
+	warn := false
+	var r SEXPItf
+	switch t {
+	case VECSXP:
+		var slice []SEXPItf
+		for _, v := range values {
+			slice = append(slice, asElements(ev, v)...)
+		}
+		r = &RSEXP{ValuePos: pos, Slice: slice}
+	case LGLSXP:
+		slice := []int{}
+		for _, v := range values {
+			slice = append(slice, asLogicals(v)...)
+		}
+		r = &LSEXP{ValuePos: pos, Slice: slice}
+	case INTSXP:
+		slice := []int{}
+		for _, v := range values {
+			slice = append(slice, asIntegers(v, &warn)...)
+		}
+		r = &ISEXP{ValuePos: pos, Slice: slice}
+	case REALSXP:
+		slice := []float64{}
+		for _, v := range values {
+			slice = append(slice, asFloats(v, &warn)...)
+		}
+		r = &VSEXP{ValuePos: pos, Slice: slice}
+	case CPLXSXP:
+		slice := []complex128{}
+		for _, v := range values {
+			slice = append(slice, asComplexes(v, &warn)...)
+		}
+		r = &CSEXP{ValuePos: pos, Slice: slice}
+	case STRSXP:
+		slice := []string{}
+		for _, v := range values {
+			slice = append(slice, asStrings(v)...)
+		}
+		r = &TSEXP{ValuePos: pos, Slice: slice}
+	}
+	if named && useNames {
+		r.NamesSet(names)
+	}
+	return r
+}
+
+func EvalUnlist(ev *Evaluator, node *ast.CallExpr) SEXPItf {
+	values, tags := EvalArgswithDotDotArguments(ev, "unlist", node.Args)
+	if len(values) == 0 {
+		return builtinError(ev, "unlist", "argument \"x\" is missing, with no default")
+	}
+	recursive, useNames := true, true
+	for n, tag := range tags {
+		switch tag {
+		case "recursive":
+			recursive = isTrue(ev, values[n])
+		case "use.names":
+			useNames = isTrue(ev, values[n])
+		}
+	}
+	list, ok := values[0].(*RSEXP)
+	if !ok {
+		return values[0]
+	}
+	return combine(ev, node.Fun.Pos(), list.Slice, elementNames("", list), recursive, useNames)
+}
+
+// as.vector and its variants drop all attributes, except names of lists
+func asVector(ev *Evaluator, funcname string, x SEXPItf, t SEXPTYPE) SEXPItf {
+	if b, ok := bigValues(x); ok {
+		x = b.vector(x, t)
+	}
+	if s, ok := sparseValue(x); ok {
+		x = sparseDense(x, s)
+	}
+	if t == ANYSXP {
+		t = sexpType(x)
+	}
+	if list, ok := x.(*RSEXP); ok && t != VECSXP {
+		if _, ok := listAtoms(ev, list, typeName(t)); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+	} else if !isAtomic(x) && t != VECSXP && sexpType(x) != NILSXP {
+		return builtinError(ev, funcname, "cannot coerce type '%s' to vector of type '%s'", typeName(sexpType(x)), typeName(t))
+	}
+	warn := false
+	r := newVector(ev, t, x.Pos(), x, isScalar(x), &warn)
+	if warn {
+		ev.warning("", "NAs introduced by coercion")
+	}
+	if t == VECSXP {
+		r.NamesSet(x.Names())
+	}
+	return r
+}
+
+func typeName(t SEXPTYPE) string {
+	switch t {
+	case NILSXP:
+		return "NULL"
+	case LGLSXP:
+		return "logical"
+	case INTSXP:
+		return "integer"
+	case REALSXP:
+		return "double"
+	case CPLXSXP:
+		return "complex"
+	case STRSXP:
+		return "character"
+	case VECSXP:
+		return "list"
+	case CLOSXP:
+		return "closure"
+	case EXTPTRSXP:
+		return "externalptr"
+	default:
+		return "any"
+	}
+}
+
+func modeType(mode string) (SEXPTYPE, bool) {
+	switch mode {
+	case "any":
+		return ANYSXP, true
+	case "logical":
+		return LGLSXP, true
+	case "integer":
+		return INTSXP, true
+	case "numeric", "double":
+		return REALSXP, true
+	case "complex":
+		return CPLXSXP, true
+	case "character":
+		return STRSXP, true
+	case "list":
+		return VECSXP, true
+	default:
+		return NILSXP, false
+	}
+}
+
+// as.numeric, as.double, as.integer, as.logical, as.complex, as.character, as.list and as.vector
+func EvalAs(ev *Evaluator, node *ast.CallExpr, funcname string) SEXPItf {
+	values, tags := EvalArgswithDotDotArguments(ev, funcname, node.Args)
+	if len(values) == 0 {
+		return builtinError(ev, funcname, "argument \"x\" is missing, with no default")
+	}
+	x := values[0]
+	if x == nil {
+		x = &NSEXP{}
+	}
+	var t SEXPTYPE
+	if funcname == "as.vector" {
+		mode := "any"
+		for n, v := range values[1:] {
+			if tags[n+1] == "mode" || tags[n+1] == "" {
+				if s, ok := v.(*TSEXP); ok && v.Length() == 1 {
+					mode = stringSlice(s)[0]
+				}
+			}
+		}
+		var ok bool
+		t, ok = modeType(mode)
+		if !ok {
+			return builtinError(ev, "as.vector", "invalid 'mode' argument")
+		}
+	} else {
+		t, _ = modeType(strings.TrimPrefix(funcname, "as."))
+	}
+	return asVector(ev, funcname, x, t)
+}
+
+// is.na is vectorised, all other type predicates return a single logical
+func EvalIs(ev *Evaluator, node *ast.CallExpr, funcname string) SEXPItf {
+	if !arityOK(ev, funcname, 1, node) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	x := EvalExpr(ev, node.Args[0])
+	var r bool
+	switch funcname {
+	case "is.na":
+		return isNA(x)
+	case "is.null":
+		r = sexpType(x) == NILSXP
+	case "is.numeric":
+		r = sexpType(x) == INTSXP || sexpType(x) == REALSXP
+	case "is.double":
+		r = sexpType(x) == REALSXP
+	case "is.integer":
+		r = sexpType(x) == INTSXP
+	case "is.logical":
+		r = sexpType(x) == LGLSXP
+	case "is.complex":
+		r = sexpType(x) == CPLXSXP
+	case "is.character":
+		r = sexpType(x) == STRSXP
+	case "is.list":
+		r = sexpType(x) == VECSXP
+	case "is.atomic":
+		r = isAtomic(x)
+	case "is.function":
+		r = isFunction(x)
+	}
+	if r {
+		return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: TRUE}
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: FALSE}
+}
+
+func isNA(x SEXPItf) SEXPItf {
+	var slice []int
+	switch x.(type) {
+	case *LSEXP, *ISEXP:
+		var values []int
+		if l, ok := x.(*LSEXP); ok {
+			values = logicalSlice(l)
+		} else {
+			values = integerSlice(x.(*ISEXP))
+		}
+		for _, v := range values {
+			slice = append(slice, logical(v == NA_INTEGER))
+		}
+	case *VSEXP:
+		if isFunction(x) {
+			return &LSEXP{ValuePos: x.Pos(), Immediate: FALSE}
+		}
+		for _, v := range floatSlice(x.(*VSEXP)) {
+			slice = append(slice, logical(math.IsNaN(v)))
+		}
+	case *CSEXP:
+		for _, v := range complexSlice(x.(*CSEXP)) {
+			slice = append(slice, logical(cmplx.IsNaN(v)))
+		}
+	case *TSEXP:
+		for _, v := range stringSlice(x.(*TSEXP)) {
+			slice = append(slice, logical(v == NA_CHARACTER))
+		}
+	case *RSEXP:
+		for _, v := range x.(*RSEXP).Slice {
+			na := isAtomic(v) && v.Length() == 1 && logicalSlice(isNA(v).(*LSEXP))[0] == TRUE
+			slice = append(slice, logical(na))
+		}
+	default:
+		return &LSEXP{Slice: []int{}}
+	}
+	r := &LSEXP{ValuePos: x.Pos(), Slice: slice}
+	if isScalar(x) {
+		r = &LSEXP{ValuePos: x.Pos(), Immediate: slice[0]}
+	}
+	r.NamesSet(x.Names())
+	r.DimSet(x.Dim())
+	r.DimnamesSet(x.Dimnames())
+	return r
+}
+
+func logical(b bool) int {
+	if b {
+		return TRUE
+	}
+	return FALSE
+}
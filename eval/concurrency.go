@@ -0,0 +1,335 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"time"
+)
+
+// Communicating sequential processes as in Go: go() evaluates a call in a goroutine,
+// which works on a copy of all variables visible at the time of the call. Goroutines
+// exchange values only through channels, which copy every value sent, so that neither
+// side sees later modifications by the other. The main program does not wait for
+// its goroutines, as in Go.
+// Operations on channels block until they can proceed, the timeout argument of send()
+// and recv() and the context of the evaluator turn a wait, which would never end, into an error.
+
+func init() {
+	registerBuiltin("chan", []string{"buffer"}, EvalChan)
+	registerBuiltin("send", []string{"ch", "value", "timeout"}, EvalSend)
+	registerBuiltin("recv", []string{"ch", "timeout"}, EvalRecv)
+	registerBuiltin("close", []string{"con", "..."}, EvalClose)
+}
+
+type channel struct {
+	c chan SEXPItf
+}
+
+func (ch *channel) String() string {
+	return fmt.Sprintf("channel: buffer %d", cap(ch.c))
+}
+
+// values are copied including attributes and elements of lists, functions share their body
+func deepCopy(x SEXPItf) SEXPItf {
+	var r SEXPItf
+	switch x.(type) {
+	case nil:
+		return nil
+	case *CSEXP:
+		c := *x.(*CSEXP)
+		c.Slice = append([]complex128(nil), x.(*CSEXP).Slice...)
+		r = &c
+	case *RSEXP:
+		l := *x.(*RSEXP)
+		l.Slice = nil
+		for _, v := range x.(*RSEXP).Slice {
+			l.Slice = append(l.Slice, deepCopy(v))
+		}
+		l.CAR, l.CDR = deepCopy(l.CAR), deepCopy(l.CDR)
+		r = &l
+	case *VSEXP, *ISEXP, *LSEXP, *TSEXP:
+		r = copyVector(x)
+	case *NSEXP:
+		return &NSEXP{ValuePos: x.Pos()}
+	default:
+		return x // errors, quoted expressions and channels are never modified
+	}
+	if names := x.Names(); names != nil {
+		r.NamesSet(append([]string(nil), names...))
+	}
+	if dim := x.Dim(); dim != nil {
+		r.DimSet(append([]int(nil), dim...))
+	}
+	if dimnames := x.Dimnames(); dimnames != nil {
+		r.DimnamesSet(deepCopy(dimnames).(*RSEXP))
+	}
+	if class := x.Class(); class != nil {
+		c := *class
+		r.ClassSet(&c)
+	}
+	return r
+}
+
+// a copy of all variables visible from f, which can be used as global frame of a goroutine
+func (f *Frame) flatCopy() *Frame {
+	global := NewFrame(nil)
+	frames := []*Frame{}
+	for s := f; s != nil; s = s.Outer {
+		frames = append(frames, s)
+	}
+	for n := len(frames) - 1; n >= 0; n-- {
+		for k, v := range frames[n].Objects {
+			global.Insert(k, deepCopy(v))
+		}
+	}
+	return global
+}
+
+// An evaluator for a goroutine, which sees nothing but the given global frame
+func (e *Evaluator) isolate(global *Frame) *Evaluator {
+	f := e.fork()
+	f.topFrame = global
+	f.globalFrame = global
+	f.putRNGstate() // not the seed of the parent
+	return f
+}
+
+func channelArgument(ev *Evaluator, funcname string, x SEXPItf) (*channel, bool) {
+	if p, ok := x.(*XSEXP); ok {
+		if ch, ok := p.Pointer.(*channel); ok {
+			return ch, true
+		}
+	}
+	if x == nil {
+		builtinError(ev, funcname, "argument \"ch\" is missing, with no default")
+	} else {
+		builtinError(ev, funcname, "'%s' is not a channel", deparseValue(x))
+	}
+	return nil, false
+}
+
+// a panic of Go, like sending on a closed channel, as error of funcname, errors of R
+// are raised again
+func recoverError(ev *Evaluator, funcname string, r *SEXPItf) {
+	if p := recover(); p != nil {
+		if _, ok := p.(*condition); ok {
+			panic(p)
+		}
+		*r = builtinError(ev, funcname, "%v", p)
+	}
+}
+
+// go(f(x)) evaluates f and x at once and calls f in a new goroutine, go(f) calls f without arguments
+func EvalGo(ev *Evaluator, node *ast.CallExpr) SEXPItf {
+	if !arityOK(ev, "go", 1, node) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	var f SEXPItf
+	values, tags := []SEXPItf{}, []string{}
+	funcname := "FUN"
+	if call, ok := node.Args[0].(*ast.CallExpr); ok {
+		if ident, ok := call.Fun.(*ast.Ident); ok {
+			funcname = ident.Name
+			if v := ev.topFrame.Recursive(funcname); isFunction(v) {
+				f = v
+			} else if b := lookupBuiltin(funcname); b != nil {
+				f = &VSEXP{ValuePos: ident.Pos(), builtin: b}
+			}
+		} else {
+			f = EvalExpr(ev, call.Fun)
+		}
+		if f == nil {
+			return builtinError(ev, "go", "could not find function \"%s\"", funcname)
+		}
+		values, tags = EvalArgswithDotDotArguments(ev, funcname, call.Args)
+		for _, v := range values {
+			if isError(v) {
+				return v
+			}
+		}
+	} else {
+		f = EvalExpr(ev, node.Args[0])
+	}
+	if isError(f) {
+		return f
+	}
+	if !isFunction(f) {
+		return builtinError(ev, "go", "argument is not a function call")
+	}
+	g := ev.isolate(ev.topFrame.flatCopy())
+	for n := range values {
+		values[n] = deepCopy(values[n])
+	}
+	go func() {
+		defer printWarnings(g)
+		defer func() {
+			if p := recover(); p != nil {
+				if c, ok := p.(*condition); ok {
+					fmt.Fprintln(ev.out, c)
+				} else {
+					fmt.Fprintf(ev.out, "Error in go() : %v\n", p)
+				}
+			}
+		}()
+		callFunction(g, node, funcname, f.(*VSEXP), values, tags)
+	}()
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+func EvalChan(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	buffer := args.float(0, 0)
+	if buffer < 0 || math.IsNaN(buffer) {
+		return builtinError(ev, "chan", "invalid 'buffer' argument")
+	}
+	r := &XSEXP{ValuePos: node.Fun.Pos(), Pointer: &channel{c: make(chan SEXPItf, int(buffer))}}
+	class := "channel"
+	r.ClassSet(&class)
+	return r
+}
+
+// blocks until the value is received or buffered
+func EvalSend(ev *Evaluator, node *ast.CallExpr, args *Arguments) (r SEXPItf) {
+	ch, ok := channelArgument(ev, "send", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if args.missing(1) {
+		return builtinError(ev, "send", "argument \"value\" is missing, with no default")
+	}
+	timeout := timeoutArgument(ev, "send", args, 2)
+	defer recoverError(ev, "send", &r)
+	waitChannel(ev, "send", reflect.SelectCase{Dir: reflect.SelectSend, Chan: reflect.ValueOf(ch.c), Send: reflect.ValueOf(deepCopy(args.Values[1]))}, timeout)
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+// blocks until a value is sent, NULL if the channel is closed
+func EvalRecv(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	ch, ok := channelArgument(ev, "recv", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	timeout := timeoutArgument(ev, "recv", args, 1)
+	if v, ok := waitChannel(ev, "recv", reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.c)}, timeout); ok {
+		return v.Interface().(SEXPItf)
+	}
+	return &NSEXP{}
+}
+
+// in seconds, Inf if missing
+func timeoutArgument(ev *Evaluator, funcname string, args *Arguments, n int) float64 {
+	timeout := args.float(n, math.Inf(1))
+	if timeout < 0 || math.IsNaN(timeout) {
+		builtinError(ev, funcname, "invalid 'timeout' argument")
+	}
+	return timeout
+}
+
+// waits for the operation c on a channel, an error is raised if the context of the evaluator
+// is done or the timeout has expired first
+func waitChannel(ev *Evaluator, funcname string, c reflect.SelectCase, timeout float64) (reflect.Value, bool) {
+	// an operation, which can proceed at once, does so even with a timeout of zero
+	if chosen, v, ok := reflect.Select([]reflect.SelectCase{c, {Dir: reflect.SelectDefault}}); chosen == 0 {
+		return v, ok
+	}
+	cases := []reflect.SelectCase{c, {Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ev.ctx.Done())}}
+	if !math.IsInf(timeout, 1) {
+		t := time.NewTimer(time.Duration(timeout * float64(time.Second)))
+		defer t.Stop()
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(t.C)})
+	}
+	chosen, v, ok := reflect.Select(cases)
+	switch chosen {
+	case 1:
+		builtinError(ev, funcname, "%v", ev.ctx.Err())
+	case 2:
+		builtinError(ev, funcname, "timeout after %g seconds", timeout)
+	}
+	return v, ok
+}
+
+func EvalClose(ev *Evaluator, node *ast.CallExpr, args *Arguments) (r SEXPItf) {
+	ch, ok := channelArgument(ev, "close", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	defer recoverError(ev, "close", &r)
+	close(ch.c)
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+// select(recv(a), send(b, x), default = expr) waits for the first case, which can proceed,
+// and returns list(index, value), where value is the received value. If no case is ready,
+// the default expression is evaluated and returned as value with index 0.
+func EvalSelect(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
+	cases := []reflect.SelectCase{}
+	var def ast.Expr
+	for _, arg := range node.Args {
+		if tagged, ok := arg.(*ast.TaggedExpr); ok {
+			if tagged.Tag == "default" {
+				def = tagged.Rhs
+				continue
+			}
+			arg = tagged.Rhs
+		}
+		call, ok := arg.(*ast.CallExpr)
+		var op string
+		if ok {
+			if ident, ok := call.Fun.(*ast.Ident); ok {
+				op = ident.Name
+			}
+		}
+		switch {
+		case op == "recv" && len(call.Args) == 1:
+		case op == "send" && len(call.Args) == 2:
+		default:
+			return builtinError(ev, "select", "cases must be calls of recv(ch) or send(ch, value)")
+		}
+		v := EvalExpr(ev, call.Args[0])
+		if isError(v) {
+			return v
+		}
+		ch, ok := channelArgument(ev, "select", v)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		c := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch.c)}
+		if op == "send" {
+			v := EvalExpr(ev, call.Args[1])
+			if isError(v) {
+				return v
+			}
+			c.Dir, c.Send = reflect.SelectSend, reflect.ValueOf(deepCopy(v))
+		}
+		cases = append(cases, c)
+	}
+	if def == nil && len(cases) == 0 {
+		return builtinError(ev, "select", "no cases and no default")
+	}
+	done := len(cases)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ev.ctx.Done())})
+	if def != nil {
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectDefault})
+	}
+	defer recoverError(ev, "select", &r)
+	chosen, received, ok := reflect.Select(cases)
+	var value SEXPItf = &NSEXP{}
+	switch {
+	case chosen == done:
+		return builtinError(ev, "select", "%v", ev.ctx.Err())
+	case cases[chosen].Dir == reflect.SelectDefault:
+		chosen = -1
+		value = EvalExpr(ev, def)
+	case cases[chosen].Dir == reflect.SelectRecv && ok:
+		value = received.Interface().(SEXPItf)
+	}
+	index := &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(chosen + 1), Integer: chosen + 1}
+	l := &RSEXP{ValuePos: node.Fun.Pos(), Slice: []SEXPItf{index, value}}
+	l.NamesSet([]string{"index", "value"})
+	return l
+}
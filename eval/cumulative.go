@@ -0,0 +1,344 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/cumsum.html
+// Cumulative functions keep the names of their argument. Integers stay integers,
+// except for cumprod. Once a value is missing, all following values are missing.
+
+func init() {
+	registerBuiltin("cumsum", []string{"x"}, EvalCumsum)
+	registerBuiltin("cumprod", []string{"x"}, EvalCumprod)
+	registerBuiltin("cummax", []string{"x"}, EvalCummax)
+	registerBuiltin("cummin", []string{"x"}, EvalCummin)
+	registerBuiltin("diff", []string{"x", "lag", "differences", "..."}, EvalDiff)
+	registerBuiltin("pmax", []string{"...", "na.rm"}, EvalPmax)
+	registerBuiltin("pmin", []string{"...", "na.rm"}, EvalPmin)
+}
+
+func cumulativeArgument(ev *Evaluator, funcname string, x SEXPItf) bool {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"x\" is missing, with no default")
+		return false
+	}
+	switch sexpType(x) {
+	case NILSXP, LGLSXP, INTSXP, REALSXP, CPLXSXP:
+		return true
+	default:
+		builtinError(ev, funcname, "invalid 'type' (%s) of argument", typeName(sexpType(x)))
+		return false
+	}
+}
+
+func cumulativeFloats(node *ast.CallExpr, x SEXPItf, f func(float64, float64) float64) SEXPItf {
+	warn := false
+	slice := asFloats(x, &warn)
+	r := make([]float64, len(slice))
+	for n, v := range slice {
+		switch {
+		case n > 0 && calc.IsNA(r[n-1]):
+			r[n] = calc.NA
+		case calc.IsNA(v) || n == 0:
+			r[n] = v
+		default:
+			r[n] = f(r[n-1], v)
+		}
+	}
+	result := &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	result.NamesSet(x.Names())
+	return result
+}
+
+func cumulativeIntegers(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, f func(int, int) int) SEXPItf {
+	warn := false
+	slice := asIntegers(x, &warn)
+	r := make([]int, len(slice))
+	for n, v := range slice {
+		switch {
+		case n > 0 && r[n-1] == NA_INTEGER, v == NA_INTEGER:
+			r[n] = NA_INTEGER
+		case n == 0:
+			r[n] = v
+		default:
+			r[n] = f(r[n-1], v)
+			if r[n] > math.MaxInt32 || r[n] <= math.MinInt32 {
+				ev.warning("", "integer overflow in '"+funcname+"'; use '"+funcname+"(as.numeric(.))'")
+				r[n] = NA_INTEGER
+			}
+		}
+	}
+	result := &ISEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	result.NamesSet(x.Names())
+	return result
+}
+
+func cumulativeComplexes(node *ast.CallExpr, x SEXPItf, f func(complex128, complex128) complex128) SEXPItf {
+	warn := false
+	slice := asComplexes(x, &warn)
+	r := make([]complex128, len(slice))
+	for n, v := range slice {
+		if n == 0 {
+			r[n] = v
+		} else {
+			r[n] = f(r[n-1], v)
+		}
+	}
+	result := &CSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	result.NamesSet(x.Names())
+	return result
+}
+
+func EvalCumsum(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !cumulativeArgument(ev, "cumsum", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	switch sexpType(x) {
+	case NILSXP, LGLSXP, INTSXP:
+		return cumulativeIntegers(ev, node, "cumsum", x, func(a, b int) int { return a + b })
+	case CPLXSXP:
+		return cumulativeComplexes(node, x, func(a, b complex128) complex128 { return a + b })
+	default:
+		warn := false
+		slice := asFloats(x, &warn)
+		r := calc.Cumsum(slice)
+		for n := range r {
+			if calc.IsNA(slice[n]) || (n > 0 && calc.IsNA(r[n-1])) {
+				r[n] = calc.NA
+			}
+		}
+		result := &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+		result.NamesSet(x.Names())
+		return result
+	}
+}
+
+func EvalCumprod(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !cumulativeArgument(ev, "cumprod", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if sexpType(x) == CPLXSXP {
+		return cumulativeComplexes(node, x, func(a, b complex128) complex128 { return a * b })
+	}
+	return cumulativeFloats(node, x, func(a, b float64) float64 { return a * b })
+}
+
+// a NaN stays, unless a missing value follows
+func cumulativeExtremum(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, sign int) SEXPItf {
+	if !cumulativeArgument(ev, funcname, x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	switch sexpType(x) {
+	case CPLXSXP:
+		return builtinError(ev, funcname, "'%s' not defined for complex numbers", funcname)
+	case NILSXP, LGLSXP, INTSXP:
+		return cumulativeIntegers(ev, node, funcname, x, func(a, b int) int {
+			if (b-a)*sign > 0 {
+				return b
+			}
+			return a
+		})
+	default:
+		return cumulativeFloats(node, x, func(a, b float64) float64 {
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return math.NaN()
+			}
+			if (sign > 0 && b > a) || (sign < 0 && b < a) {
+				return b
+			}
+			return a
+		})
+	}
+}
+
+func EvalCummax(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return cumulativeExtremum(ev, node, "cummax", args.Values[0], 1)
+}
+
+func EvalCummin(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return cumulativeExtremum(ev, node, "cummin", args.Values[0], -1)
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/diff.html
+// differences of vectors or of the rows of a matrix
+func EvalDiff(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !cumulativeArgument(ev, "diff", x) || sexpType(x) == CPLXSXP {
+		if x != nil && sexpType(x) == CPLXSXP {
+			return builtinError(ev, "diff", "'diff' not implemented for complex numbers")
+		}
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	lag, differences := args.float(1, 1), args.float(2, 1)
+	if lag < 1 || differences < 1 || lag != math.Floor(lag) || differences != math.Floor(differences) {
+		return builtinError(ev, "diff", "'lag' and 'differences' must be integers >= 1")
+	}
+	rows, cols := x.Length(), 1
+	if dim := x.Dim(); len(dim) == 2 {
+		rows, cols = dim[0], dim[1]
+	}
+	result := x
+	for d := 0; d < int(differences); d++ {
+		offsets := []int{}
+		previous := []int{}
+		for col := 0; col < cols; col++ {
+			for row := int(lag); row < rows; row++ {
+				offsets = append(offsets, row+col*rows)
+				previous = append(previous, row-int(lag)+col*rows)
+			}
+		}
+		if rows < int(lag) {
+			rows = 0
+		} else {
+			rows -= int(lag)
+		}
+		result = EvalArithmetic(ev, token.MINUS, indexElements(ev, result, offsets), indexElements(ev, result, previous))
+		if len(x.Dim()) == 2 {
+			result.DimSet([]int{rows, cols})
+		}
+	}
+	if len(x.Dim()) != 2 {
+		names := x.Names()
+		if names != nil {
+			n := len(names) - result.Length()
+			result.NamesSet(names[n:])
+		}
+	}
+	return result
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/Extremes.html
+// elementwise extremes of recycled arguments with the attributes of the first one
+func parallelExtremum(ev *Evaluator, node *ast.CallExpr, args *Arguments, funcname string, sign int) SEXPItf {
+	values := args.Dots
+	if len(values) == 0 {
+		return builtinError(ev, funcname, "no arguments")
+	}
+	t, ok := summaryType(ev, funcname, values)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if t == CPLXSXP {
+		return builtinError(ev, funcname, "invalid input type")
+	}
+	if t == NILSXP || t == LGLSXP {
+		t = INTSXP
+	}
+	length := 0
+	for _, v := range values {
+		if v.Length() == 0 {
+			warn := false
+			return newVector(ev, t, node.Fun.Pos(), &LSEXP{Slice: []int{}}, false, &warn)
+		}
+		length = calc.IntMax(length, v.Length())
+	}
+	for _, v := range values {
+		if length%v.Length() != 0 {
+			ev.warning("", "an argument will be fractionally recycled")
+			break
+		}
+	}
+	narm := args.logical(1, false)
+	warn := false
+	var r SEXPItf
+	switch t {
+	case INTSXP:
+		slice := make([]int, length)
+		for n := range slice {
+			slice[n] = parallelInteger(values, n, narm, sign)
+		}
+		r = &ISEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+	case REALSXP:
+		slice := make([]float64, length)
+		for n := range slice {
+			slice[n] = parallelFloat(values, n, narm, sign)
+		}
+		r = &VSEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+	case STRSXP:
+		slice := make([]string, length)
+		for n := range slice {
+			slice[n] = parallelString(values, n, narm, sign)
+		}
+		r = &TSEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+	default:
+		return builtinError(ev, funcname, "invalid input type")
+	}
+	if values[0].Length() == length {
+		r.NamesSet(values[0].Names())
+		r.DimSet(values[0].Dim())
+		r.DimnamesSet(values[0].Dimnames())
+	}
+	if length == 1 && isScalar(values[0]) {
+		return newVector(ev, t, node.Fun.Pos(), r, true, &warn)
+	}
+	return r
+}
+
+// the extreme of the n-th elements of all values, missing if any is missing and not removed
+func parallelInteger(values []SEXPItf, n int, narm bool, sign int) int {
+	warn := false
+	r := NA_INTEGER
+	for _, v := range values {
+		slice := asIntegers(v, &warn)
+		e := slice[n%len(slice)]
+		switch {
+		case e == NA_INTEGER:
+			if !narm {
+				return NA_INTEGER
+			}
+		case r == NA_INTEGER || (e-r)*sign > 0:
+			r = e
+		}
+	}
+	return r
+}
+
+func parallelFloat(values []SEXPItf, n int, narm bool, sign int) float64 {
+	warn := false
+	r, first := calc.NA, true
+	for _, v := range values {
+		slice := asFloats(v, &warn)
+		e := slice[n%len(slice)]
+		switch {
+		case math.IsNaN(e):
+			if !narm {
+				return e
+			}
+		case first || (sign > 0 && e > r) || (sign < 0 && e < r):
+			r, first = e, false
+		}
+	}
+	return r
+}
+
+func parallelString(values []SEXPItf, n int, narm bool, sign int) string {
+	r, first := NA_CHARACTER, true
+	for _, v := range values {
+		slice := asStrings(v)
+		e := slice[n%len(slice)]
+		switch {
+		case e == NA_CHARACTER:
+			if !narm {
+				return e
+			}
+		case first || strings.Compare(e, r)*sign > 0:
+			r, first = e, false
+		}
+	}
+	return r
+}
+
+func EvalPmax(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return parallelExtremum(ev, node, args, "pmax", 1)
+}
+
+func EvalPmin(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return parallelExtremum(ev, node, args, "pmin", -1)
+}
@@ -0,0 +1,145 @@
+package eval
+
+import (
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strings"
+)
+
+// Expressions are deparsed as R does: spaces around binary operators
+// except for ^, :, $, @ and /, and parentheses where the precedence of
+// the tree requires them, e.g. after substitutions into formulae.
+
+func deparse(x ast.Expr) string {
+	var b strings.Builder
+	deparseTo(&b, x)
+	return b.String()
+}
+
+func deparseTo(b *strings.Builder, x ast.Expr) {
+	switch e := x.(type) {
+	case nil:
+		b.WriteString("NULL")
+	case *ast.Ident:
+		b.WriteString(e.Name)
+	case *ast.BasicLit:
+		deparseLiteral(b, e)
+	case *ast.Ellipsis:
+		b.WriteString("...")
+	case *ast.ParenExpr:
+		b.WriteString("(")
+		deparseTo(b, e.X)
+		b.WriteString(")")
+	case *ast.UnaryExpr:
+		b.WriteString(e.Op.String())
+		deparseOperand(b, e.X, deparsePrecedence(e), false)
+	case *ast.BinaryExpr:
+		deparseBinary(b, e.X, e.Op.String(), e.Op.Precedence(), e.Y)
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && len(e.Args) == 2 && strings.HasPrefix(id.Name, "%") {
+			deparseBinary(b, e.Args[0], id.Name, token.SPECIAL.Precedence(), e.Args[1])
+			return
+		}
+		deparseTo(b, e.Fun)
+		deparseArgs(b, "(", e.Args, ")")
+	case *ast.ArbitraryCallExpr:
+		deparseTo(b, e.Fun)
+		deparseArgs(b, "(", e.Args, ")")
+	case *ast.TaggedExpr:
+		b.WriteString(e.Tag)
+		b.WriteString(" = ")
+		deparseTo(b, e.Rhs)
+	case *ast.IndexExpr:
+		deparseTo(b, e.Array)
+		deparseArgs(b, "[", e.Index, "]")
+	case *ast.ListIndexExpr:
+		deparseTo(b, e.Array)
+		deparseArgs(b, "[[", e.Index, "]]")
+	case *ast.QuotedExpr:
+		b.WriteString("quote(")
+		deparseTo(b, e.X)
+		b.WriteString(")")
+	case *ast.FuncLit:
+		b.WriteString("function(...) NULL")
+	case *ast.BlockExpr:
+		b.WriteString("{...}")
+	default:
+		b.WriteString("<expression>")
+	}
+}
+
+func deparseLiteral(b *strings.Builder, e *ast.BasicLit) {
+	switch e.Kind {
+	case token.STRING:
+		b.WriteString("\"" + strings.ReplaceAll(e.Value, "\"", "\\\"") + "\"")
+	case token.INT, token.FLOAT, token.IMAG:
+		b.WriteString(e.Value)
+	case token.NA:
+		if isDot(e) {
+			b.WriteString(".")
+		} else {
+			b.WriteString(e.Kind.String())
+		}
+	default:
+		b.WriteString(e.Kind.String())
+	}
+}
+
+func deparseArgs(b *strings.Builder, open string, args []ast.Expr, close string) {
+	b.WriteString(open)
+	for n, a := range args {
+		if n > 0 {
+			b.WriteString(", ")
+		}
+		if a != nil {
+			deparseTo(b, a)
+		}
+	}
+	b.WriteString(close)
+}
+
+func deparseBinary(b *strings.Builder, x ast.Expr, op string, prec int, y ast.Expr) {
+	// ^ is right associative, all others left associative
+	right := op == "^"
+	deparseOperand(b, x, prec, right)
+	switch op {
+	case "^", ":", "$", "@", "/":
+		b.WriteString(op)
+	default:
+		b.WriteString(" " + op + " ")
+	}
+	deparseOperand(b, y, prec, !right)
+}
+
+// operands binding less tightly than the operator are parenthesized, on the
+// associative side only if they bind even less
+func deparseOperand(b *strings.Builder, x ast.Expr, prec int, strict bool) {
+	p := deparsePrecedence(x)
+	if p < prec || (strict && p == prec) {
+		b.WriteString("(")
+		deparseTo(b, x)
+		b.WriteString(")")
+		return
+	}
+	deparseTo(b, x)
+}
+
+func deparsePrecedence(x ast.Expr) int {
+	switch e := x.(type) {
+	case *ast.BinaryExpr:
+		return e.Op.Precedence()
+	case *ast.UnaryExpr:
+		if e.Op == token.TILDE {
+			return token.TILDE.Precedence()
+		}
+		if e.Op == token.NOT {
+			return token.NOT.Precedence()
+		}
+		return token.UNARYMINUS.Precedence()
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && len(e.Args) == 2 && strings.HasPrefix(id.Name, "%") {
+			return token.SPECIAL.Precedence()
+		}
+	}
+	return token.DOUBLECOLON.Precedence() + 1
+}
@@ -0,0 +1,452 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"roq/random"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/Distributions.html
+// Every distribution has a density d, a distribution function p, a quantile function q
+// and random deviates r. All arguments are recycled to the longest and the result keeps
+// the attributes of the first argument when it has full length. Parameters without
+// default which are resolved together, such as rate and scale, are handed to prepare,
+// which may choose another parametrization of the distribution.
+
+type distribution struct {
+	params   []string
+	required int       // the leading parameters without default
+	defaults []float64 // of all parameters, NaN if missing ones are left to prepare
+	ncp      bool      // the argument for the non-centrality is accepted, but not implemented
+	prepare  func(ev *Evaluator, funcname string, params [][]float64) (*distribution, [][]float64, bool)
+	density  func(x float64, p []float64, logD bool) float64
+	cdf      func(q float64, p []float64, lowerTail bool, logP bool) float64
+	quantile func(p float64, par []float64, lowerTail bool, logP bool) float64
+	draw     func(g *random.Generator, p []float64) float64
+	integer  bool // the deviates are counts
+}
+
+var noDefault = math.NaN()
+
+var distributions = map[string]*distribution{
+	"norm": {
+		params:   []string{"mean", "sd"},
+		defaults: []float64{0, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dnorm(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pnorm(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qnorm(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Norm(p[0], p[1]) },
+	},
+	"unif": {
+		params:   []string{"min", "max"},
+		defaults: []float64{0, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dunif(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Punif(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qunif(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Unif(p[0], p[1]) },
+	},
+	"exp": {
+		params:   []string{"rate"},
+		defaults: []float64{1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dexp(x, 1/p[0], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pexp(q, 1/p[0], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qexp(x, 1/p[0], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Exp(1 / p[0]) },
+	},
+	"gamma": {
+		params:   []string{"shape", "rate", "scale"},
+		required: 1,
+		defaults: []float64{noDefault, noDefault, noDefault},
+		prepare:  gammaScale,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dgamma(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pgamma(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qgamma(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Gamma(p[0], p[1]) },
+	},
+	"beta": {
+		params:   []string{"shape1", "shape2"},
+		required: 2,
+		defaults: []float64{noDefault, noDefault},
+		ncp:      true,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dbeta(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pbeta(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qbeta(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Beta(p[0], p[1]) },
+	},
+	"t": {
+		params:   []string{"df"},
+		required: 1,
+		defaults: []float64{noDefault},
+		ncp:      true,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dt(x, p[0], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pt(q, p[0], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qt(x, p[0], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.T(p[0]) },
+	},
+	"chisq": {
+		params:   []string{"df"},
+		required: 1,
+		defaults: []float64{noDefault},
+		ncp:      true,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dchisq(x, p[0], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pchisq(q, p[0], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qchisq(x, p[0], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Chisq(p[0]) },
+	},
+	"f": {
+		params:   []string{"df1", "df2"},
+		required: 2,
+		defaults: []float64{noDefault, noDefault},
+		ncp:      true,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Df(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pf(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qf(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.F(p[0], p[1]) },
+	},
+	"binom": {
+		params:   []string{"size", "prob"},
+		required: 2,
+		defaults: []float64{noDefault, noDefault},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dbinom(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pbinom(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qbinom(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Binom(p[0], p[1]) },
+		integer:  true,
+	},
+	"pois": {
+		params:   []string{"lambda"},
+		required: 1,
+		defaults: []float64{noDefault},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dpois(x, p[0], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Ppois(q, p[0], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qpois(x, p[0], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Pois(p[0]) },
+		integer:  true,
+	},
+	"geom": {
+		params:   []string{"prob"},
+		required: 1,
+		defaults: []float64{noDefault},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dgeom(x, p[0], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pgeom(q, p[0], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qgeom(x, p[0], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Geom(p[0]) },
+		integer:  true,
+	},
+	"nbinom": {
+		params:   []string{"size", "prob", "mu"},
+		required: 1,
+		defaults: []float64{noDefault, noDefault, noDefault},
+		prepare:  nbinomMean,
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dnbinom(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pnbinom(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qnbinom(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.NBinom(p[0], p[1]) },
+		integer:  true,
+	},
+	"hyper": {
+		params:   []string{"m", "n", "k"},
+		required: 3,
+		defaults: []float64{noDefault, noDefault, noDefault},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dhyper(x, p[0], p[1], p[2], lg) },
+		cdf: func(q float64, p []float64, lt bool, lg bool) float64 {
+			return numeric.Phyper(q, p[0], p[1], p[2], lt, lg)
+		},
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 {
+			return numeric.Qhyper(x, p[0], p[1], p[2], lt, lg)
+		},
+		draw:    func(g *random.Generator, p []float64) float64 { return g.Hyper(p[0], p[1], p[2]) },
+		integer: true,
+	},
+	"lnorm": {
+		params:   []string{"meanlog", "sdlog"},
+		defaults: []float64{0, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dlnorm(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Plnorm(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qlnorm(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Lnorm(p[0], p[1]) },
+	},
+	"weibull": {
+		params:   []string{"shape", "scale"},
+		required: 1,
+		defaults: []float64{noDefault, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dweibull(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pweibull(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qweibull(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Weibull(p[0], p[1]) },
+	},
+	"cauchy": {
+		params:   []string{"location", "scale"},
+		defaults: []float64{0, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dcauchy(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Pcauchy(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qcauchy(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Cauchy(p[0], p[1]) },
+	},
+	"logis": {
+		params:   []string{"location", "scale"},
+		defaults: []float64{0, 1},
+		density:  func(x float64, p []float64, lg bool) float64 { return numeric.Dlogis(x, p[0], p[1], lg) },
+		cdf:      func(q float64, p []float64, lt bool, lg bool) float64 { return numeric.Plogis(q, p[0], p[1], lt, lg) },
+		quantile: func(x float64, p []float64, lt bool, lg bool) float64 { return numeric.Qlogis(x, p[0], p[1], lt, lg) },
+		draw:     func(g *random.Generator, p []float64) float64 { return g.Logis(p[0], p[1]) },
+	},
+}
+
+// the negative binomial distribution parametrized by its mean
+var nbinomMu = &distribution{
+	density: func(x float64, p []float64, lg bool) float64 { return numeric.DnbinomMu(x, p[0], p[1], lg) },
+	cdf: func(q float64, p []float64, lt bool, lg bool) float64 {
+		return numeric.PnbinomMu(q, p[0], p[1], lt, lg)
+	},
+	quantile: func(x float64, p []float64, lt bool, lg bool) float64 {
+		return numeric.QnbinomMu(x, p[0], p[1], lt, lg)
+	},
+	draw:    func(g *random.Generator, p []float64) float64 { return g.NBinomMu(p[0], p[1]) },
+	integer: true,
+}
+
+func init() {
+	for name, dist := range distributions {
+		name, dist := name, dist
+		params := dist.params
+		if dist.ncp {
+			params = append(params[:len(params):len(params)], "ncp")
+		}
+		first := "n"
+		for _, p := range params {
+			if p == "n" {
+				first = "nn"
+			}
+		}
+		registerBuiltin("d"+name, join("x", params, "log"), func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			logD := args.logical(len(params)+1, false)
+			return evalDistribution(ev, node, "d"+name, dist, args, func(d *distribution, x float64, p []float64) float64 {
+				return d.density(x, p, logD)
+			})
+		})
+		registerBuiltin("p"+name, join("q", params, "lower.tail", "log.p"), func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			lowerTail, logP := args.logical(len(params)+1, true), args.logical(len(params)+2, false)
+			return evalDistribution(ev, node, "p"+name, dist, args, func(d *distribution, x float64, p []float64) float64 {
+				return d.cdf(x, p, lowerTail, logP)
+			})
+		})
+		registerBuiltin("q"+name, join("p", params, "lower.tail", "log.p"), func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			lowerTail, logP := args.logical(len(params)+1, true), args.logical(len(params)+2, false)
+			return evalDistribution(ev, node, "q"+name, dist, args, func(d *distribution, x float64, p []float64) float64 {
+				return d.quantile(x, p, lowerTail, logP)
+			})
+		})
+		registerBuiltin("r"+name, join(first, params), func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			return evalRandom(ev, node, "r"+name, dist, args)
+		})
+	}
+}
+
+func join(first string, params []string, flags ...string) []string {
+	return append(append([]string{first}, params...), flags...)
+}
+
+// the values of the parameters, which follow the first argument in the formals
+func distributionParameters(ev *Evaluator, funcname string, dist *distribution, args *Arguments, deviates bool) (*distribution, [][]float64, bool) {
+	params := make([][]float64, len(dist.params))
+	formals := lookupBuiltin(funcname).formals
+	for k := range dist.params {
+		v := args.Values[k+1]
+		switch {
+		case v == nil && k < dist.required:
+			builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formals[k+1])
+			return nil, nil, false
+		case v == nil && math.IsNaN(dist.defaults[k]):
+			continue
+		case v == nil:
+			params[k] = []float64{dist.defaults[k]}
+			continue
+		case deviates && sexpType(v) != LGLSXP && sexpType(v) != INTSXP && sexpType(v) != REALSXP && sexpType(v) != NILSXP:
+			builtinError(ev, funcname, "invalid arguments")
+			return nil, nil, false
+		case sexpType(v) != NILSXP && !mathArgument(ev, funcname, formals[k+1], v, false):
+			return nil, nil, false
+		}
+		warn := false
+		params[k] = asFloats(v, &warn)
+	}
+	if dist.ncp && args.Values[len(dist.params)+1] != nil {
+		builtinError(ev, funcname, "non-central distributions are not implemented")
+		return nil, nil, false
+	}
+	if dist.prepare == nil {
+		return dist, params, true
+	}
+	d, params, ok := dist.prepare(ev, funcname, params)
+	if d == nil {
+		d = dist
+	}
+	return d, params, ok
+}
+
+// the scale from the rate unless both are given
+func gammaScale(ev *Evaluator, funcname string, params [][]float64) (*distribution, [][]float64, bool) {
+	shape, rate, scale := params[0], params[1], params[2]
+	switch {
+	case rate != nil && scale != nil:
+		for n := 0; n < len(rate) && n < len(scale); n++ {
+			if math.Abs(rate[n]*scale[n]-1) >= 1e-15 {
+				builtinError(ev, funcname, "specify 'rate' or 'scale' but not both")
+				return nil, nil, false
+			}
+		}
+		ev.warning(funcname+"()", "specify 'rate' or 'scale' but not both")
+	case scale == nil && rate == nil:
+		scale = []float64{1}
+	case scale == nil:
+		scale = make([]float64, len(rate))
+		for n, r := range rate {
+			scale[n] = 1 / r
+		}
+	}
+	return nil, [][]float64{shape, scale}, true
+}
+
+// either the probability or the mean
+func nbinomMean(ev *Evaluator, funcname string, params [][]float64) (*distribution, [][]float64, bool) {
+	size, prob, mu := params[0], params[1], params[2]
+	switch {
+	case prob != nil && mu != nil:
+		builtinError(ev, funcname, "'prob' and 'mu' both specified")
+		return nil, nil, false
+	case mu != nil:
+		return nbinomMu, [][]float64{size, mu}, true
+	case prob == nil:
+		builtinError(ev, funcname, "argument \"prob\" is missing, with no default")
+		return nil, nil, false
+	}
+	return nil, [][]float64{size, prob}, true
+}
+
+// the length of the recycled arguments, zero if one of them is empty
+func recycledLength(slices ...[]float64) int {
+	n := 0
+	for _, s := range slices {
+		if len(s) == 0 {
+			return 0
+		}
+		if len(s) > n {
+			n = len(s)
+		}
+	}
+	return n
+}
+
+func evalDistribution(ev *Evaluator, node *ast.CallExpr, funcname string, dist *distribution, args *Arguments, f func(d *distribution, x float64, p []float64) float64) SEXPItf {
+	x := args.Values[0]
+	if !mathArgument(ev, funcname, lookupBuiltin(funcname).formals[0], x, false) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	d, params, ok := distributionParameters(ev, funcname, dist, args, false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	xs := asFloats(x, &warn)
+	r := make([]float64, recycledLength(append([][]float64{xs}, params...)...))
+	p := make([]float64, len(params))
+	nan := false
+	for n := range r {
+		u := xs[n%len(xs)]
+		na := calc.IsNA(u)
+		given := !math.IsNaN(u)
+		for k, v := range params {
+			p[k] = v[n%len(v)]
+			na = na || calc.IsNA(p[k])
+			given = given && !math.IsNaN(p[k])
+		}
+		if na {
+			r[n] = calc.NA
+			continue
+		}
+		r[n] = f(d, u, p)
+		nan = nan || (math.IsNaN(r[n]) && given)
+	}
+	if nan {
+		ev.warning("", "NaNs produced")
+	}
+	if len(xs) == len(r) {
+		return mathResult(x, newFloats(node.Fun.Pos(), r, isScalar(x)))
+	}
+	return newFloats(node.Fun.Pos(), r, false)
+}
+
+// the number of values is the length of n or its value
+func randomCount(ev *Evaluator, funcname string, n SEXPItf) (int, bool) {
+	if n == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", lookupBuiltin(funcname).formals[0])
+		return 0, false
+	}
+	if !isAtomic(n) || sexpType(n) == STRSXP && n.Length() == 1 {
+		builtinError(ev, funcname, "invalid arguments")
+		return 0, false
+	}
+	if n.Length() != 1 {
+		return n.Length(), true
+	}
+	warn := false
+	dn := asFloats(n, &warn)[0]
+	if math.IsNaN(dn) || dn < 0 || dn > math.MaxInt32 {
+		builtinError(ev, funcname, "invalid arguments")
+		return 0, false
+	}
+	return int(dn), true
+}
+
+// deviates drawn with recycled parameters, counts are integers unless a value is too large
+func evalRandom(ev *Evaluator, node *ast.CallExpr, funcname string, dist *distribution, args *Arguments) SEXPItf {
+	n, ok := randomCount(ev, funcname, args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	d, params, ok := distributionParameters(ev, funcname, dist, args, true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	ev.getRNGstate()
+	defer ev.putRNGstate()
+	r := make([]float64, n)
+	invalid := false
+	p := make([]float64, len(params))
+	for i := range r {
+		if recycledLength(params...) == 0 {
+			r[i] = calc.NA
+			invalid = true
+			continue
+		}
+		for k, v := range params {
+			p[k] = v[i%len(v)]
+		}
+		r[i] = d.draw(ev.rng, p)
+		if math.IsNaN(r[i]) {
+			invalid = true
+		}
+	}
+	if invalid {
+		ev.warning("", "NAs produced")
+	}
+	if !d.integer {
+		return &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	}
+	slice := make([]int, len(r))
+	for n, v := range r {
+		switch {
+		case math.IsNaN(v):
+			slice[n] = NA_INTEGER
+		case v > math.MaxInt32:
+			return &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+		default:
+			slice[n] = int(v)
+		}
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+}
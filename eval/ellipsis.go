@@ -6,29 +6,32 @@ import (
 )
 
 
-func EvalArgswithDotDotArguments(ev *Evaluator, funcname string, arglist []ast.Expr)[]SEXPItf{
+// tags of the arguments are returned as names, untagged arguments have empty names
+func EvalArgswithDotDotArguments(ev *Evaluator, funcname string, arglist []ast.Expr) ([]SEXPItf, []string) {
 	DEBUG := ev.Debug
 	evaluatedArgs := make([]SEXPItf, 0, len(arglist))
+	names := make([]string, 0, len(arglist))
 	if DEBUG {
-		println("EvalArgswithDotDotArguments")
+		ev.println("EvalArgswithDotDotArguments")
 	}
 	for n, arg := range arglist { // TODO: strictly left to right
 		if arg != nil {
 			var val SEXPItf
 			if DEBUG {
-				print("\tProcessing: ", n, ":\t")
+				ev.print("\tProcessing: ", n, ":\t")
 			}
 			switch arg.(type) {
 			case *ast.BasicLit:
 				val=EvalExprOrAssignment(ev, arg)
 				if DEBUG {
-					print("appending evaluated argument:\t")
-					PrintResult(val)
+					ev.print("appending evaluated argument:\t")
+					PrintResult(ev.log, val)
 				}
 				evaluatedArgs=append(evaluatedArgs,val)
+				names=append(names,"")
 			case *ast.Ellipsis:
 				if DEBUG {
-					println(" ELLIPSIS")
+					ev.println(" ELLIPSIS")
 					DumpFrames(ev)
 				}
 				for k:=1; k<=len(ev.topFrame.Objects); k++ {
@@ -36,10 +39,11 @@ func EvalArgswithDotDotArguments(ev *Evaluator, funcname string, arglist []ast.E
 					obj := ev.topFrame.Objects[key] 
 					if obj != nil{
 						if DEBUG {
-							print("\t\tappending dotdotvalue (evaluated):\t", key, "=")
-							PrintResult(obj)
+							ev.print("\t\tappending dotdotvalue (evaluated):\t", key, "=")
+							PrintResult(ev.log, obj)
 						}
 						evaluatedArgs=append(evaluatedArgs,obj)
+						names=append(names,"")
 					}
 				}
 				if DEBUG {
@@ -48,14 +52,20 @@ func EvalArgswithDotDotArguments(ev *Evaluator, funcname string, arglist []ast.E
 			default:
 				val = EvalExprOrAssignment(ev, arg)
 				if DEBUG {
-					print("appending evaluated argument (non-literal):\t")
-					PrintResult(val)
+					ev.print("appending evaluated argument (non-literal):\t")
+					PrintResult(ev.log, val)
 				}
 				evaluatedArgs=append(evaluatedArgs,val)
+				switch arg.(type) {
+				case *ast.TaggedExpr:
+					names=append(names,arg.(*ast.TaggedExpr).Tag)
+				default:
+					names=append(names,"")
+				}
 			}
 		}
 	}
-	return evaluatedArgs
+	return evaluatedArgs, names
 }
 
 
@@ -65,13 +75,13 @@ func EvalCallwithEllipsis(ev *Evaluator, node *ast.CallExpr, thefunction SEXPItf
 	funcobject := node.Fun
 	funcname := funcobject.(*ast.Ident).Name
 	if TRACE || DEBUG {
-		println("EvalCallwithEllipsis: " + funcname)
+		ev.println("EvalCallwithEllipsis: " + funcname)
 	}
 	argNames := getArgNames(thefunction.(*VSEXP))
 	if DEBUG {
-		println("\tList of arg names of function: " + funcname)
-		PrintArgNames(argNames)
-		println("\tList of supplied args to call to function: " + funcname)
+		ev.println("\tList of arg names of function: " + funcname)
+		PrintArgNames(ev, argNames)
+		ev.println("\tList of supplied args to call to function: " + funcname)
 		PrintListofAstExpressions(ev,node.Args)
 	}
 	frame := CollectArgsIntoFrameWithVariableArity(ev, node, argNames)
@@ -85,7 +95,7 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 	frame := NewFrame(nil)
 
 	if DEBUG {
-		println("\tCollectArgsIntoFrameWithVariableArity:", funcname)
+		ev.println("\tCollectArgsIntoFrameWithVariableArity:", funcname)
 	}
 	
 	// collect tagged arguments (unevaluated) in an array of call position numbers
@@ -96,7 +106,7 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 			a := arg.(*ast.TaggedExpr)
 			taggedArgs[a.Tag] = n + 1 // one above default zero value
 			if DEBUG {
-				println("\t\ttagged argument collected:", a.Tag, "  pos: ",n+1)
+				ev.println("\t\ttagged argument collected:", a.Tag, "  pos: ",n+1)
 			}
 		}
 	}
@@ -110,19 +120,19 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 	// callindex:  position in parameter list of function call
 	for fieldindex, fieldname := range argNames {
 		if DEBUG {
-			print("\t\tsearching parameter: '", fieldname,"' ")
+			ev.print("\t\tsearching parameter: '", fieldname,"' ")
 		}
 		callindex := taggedArgs[fieldname] 
 		if callindex != 0 { // missing index return default zero value
 			frame.Insert(fieldname, EvalExpr(ev, node.Args[callindex-1]))
 			usedArgs[callindex-1] = true
 			if DEBUG {
-				println("=> found at position:", callindex-1, "argument number:", fieldindex)
+				ev.println("=> found at position:", callindex-1, "argument number:", fieldindex)
 			}
 			delete(taggedArgs, fieldname)
 		} else {
 			if DEBUG {
-				println("=> not found")
+				ev.println("=> not found")
 			}
 		}
 	}
@@ -130,14 +140,14 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 	// find partially matching tags in the remaining tagged args
 	for fieldname, callindex := range taggedArgs {
 		if DEBUG {
-			println("\t\tsearching partial match for: ",fieldname)
+			ev.println("\t\tsearching partial match for: ",fieldname)
 		}
 		matches, fieldindex := tryPartialMatch(fieldname, argNames, make([]ast.Expr,len(argNames)))
 		if matches > 1 {
 			panic("Error: argument matches multiple formal arguments:"+funcname+"(.."+fieldname+"..)" )
 		} else if matches == 1 && usedArgs[callindex-1] == false {
 			if DEBUG {
-				println("\t\targument '"+fieldname+"' matches one formal argument:", argNames[fieldindex])
+				ev.println("\t\targument '"+fieldname+"' matches one formal argument:", argNames[fieldindex])
 			}
 			frame.Insert(argNames[fieldindex], EvalExpr(ev, node.Args[callindex-1]))
 			usedArgs[callindex-1] = true
@@ -156,11 +166,11 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 		}
 		if frame.Lookup(fieldname) != nil {
 			if DEBUG {
-				println("\t\tpositional argument already satisfied:   pos:", n+1, j, fieldname)
+				ev.println("\t\tpositional argument already satisfied:   pos:", n+1, j, fieldname)
 			}
 		} else {
 			if DEBUG {
-				println("\t\tcollecting positional argument:   pos:", n+1, j, fieldname)
+				ev.println("\t\tcollecting positional argument:   pos:", n+1, j, fieldname)
 			}
 			frame.Insert(fieldname, EvalExpr(ev, node.Args[j]))
 			usedArgs[j] = true
@@ -179,8 +189,8 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 					obj := ev.topFrame.Objects[key] 
 					if obj != nil{
 						if DEBUG {
-							print("\t\tappending dotdotvalue (evaluated):", new, "= ")
-							PrintResult(obj)
+							ev.print("\t\tappending dotdotvalue (evaluated):", new, "= ")
+							PrintResult(ev.log, obj)
 						}
 						frame.Insert(new, obj)
 						n++
@@ -189,8 +199,8 @@ func CollectArgsIntoFrameWithVariableArity(ev *Evaluator, node *ast.CallExpr, ar
 			default:
 				fieldname := ".." + strconv.Itoa(n)
 				if DEBUG {
-					print("\t\tappending unused argument from call: ", fieldname, "= ")
-					PrintResult(EvalExpr(ev,node.Args[callindex]))
+					ev.print("\t\tappending unused argument from call: ", fieldname, "= ")
+					PrintResult(ev.log, EvalExpr(ev,node.Args[callindex]))
 				}
 				frame.Insert(fieldname, EvalExpr(ev, node.Args[callindex]))
 				n++
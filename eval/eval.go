@@ -8,13 +8,20 @@
 package eval
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"sync"
+	"roq/calc"
 	"roq/lib/ast"
 	"roq/lib/parser"
 	"roq/lib/token"
+	"roq/random"
 	"math"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 
@@ -40,6 +47,15 @@ type Evaluator struct {
 
 	Invisible bool
 	state     LoopState
+	warnings  *[]string // shared with copies of the evaluator inside loops
+	options   map[string]SEXPItf
+	closure   *VSEXP // the function being evaluated, used by Recall
+	call      string // the name of the function being evaluated, used by stop
+	futures   *futurePlan // shared with copies of the evaluator inside loops
+	rng       *random.Generator // shared with copies of the evaluator inside loops
+	out       io.Writer   // results, errors and warnings
+	log       io.Writer   // tracing and debugging
+	ctx       context.Context // cancels blocking operations on channels, shared with forks
 
 	// frame
 	topFrame *Frame // top-most frame; may be pkgFrame
@@ -54,15 +70,26 @@ func (e *Evaluator) closeFrame() {
 	e.topFrame = e.topFrame.Outer
 }
 
+// diagnostic output, written without spaces between operands like the builtin print
+func (e *Evaluator) print(args ...interface{}) {
+	for _, a := range args {
+		fmt.Fprint(e.log, a)
+	}
+}
+
+func (e *Evaluator) println(args ...interface{}) {
+	fmt.Fprintln(e.log, args...)
+}
+
 func trace(e *Evaluator, args ...interface{}) *Evaluator {
 	if e.Trace {
 		i := 2 * e.indent
 		for i > 0 {
-			print(" ")
+			e.print(" ")
 			i--
 		}
-		fmt.Print(args...)
-		print("\n")
+		e.print(args...)
+		e.print("\n")
 		e.indent++
 	}
 	return e
@@ -90,10 +117,37 @@ func EvalInit(fset *token.FileSet, filename string, src interface{}, mode parser
 		panic("roq/eval.evalInit: no token.FileSet provided (fset == nil)")
 	}
 
-	e := Evaluator{Trace: traceflag, Debug: debugflag, indent: 0, topFrame: nil}
+	return NewEvaluator(os.Stdout, os.Stderr, traceflag, debugflag), err
+}
+
+// NewEvaluator returns an interpreter with an empty global frame, which prints to out
+// and writes tracing and debugging information to log. Evaluators share no mutable state,
+// so that several of them can be used concurrently, each from one goroutine at a time.
+func NewEvaluator(out io.Writer, log io.Writer, traceflag bool, debugflag bool) *Evaluator {
+	e := Evaluator{Trace: traceflag, Debug: debugflag, indent: 0, topFrame: nil, warnings: new([]string), options: map[string]SEXPItf{}, futures: sequentialPlan(), rng: random.New(), ctx: context.Background()}
+	e.out = &syncWriter{w: out}
+	e.log = &syncWriter{w: log}
 	e.topFrame = NewFrame(e.topFrame)
 	e.globalFrame = e.topFrame
-	return &e, err
+	return &e
+}
+
+// SetContext lets send(), recv() and select() raise an error, when ctx is done. The context
+// is passed on to the goroutines, which are started by the evaluator afterwards.
+func (e *Evaluator) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// output shared by the goroutines of an evaluator
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
 }
 
 
@@ -101,7 +155,7 @@ func EvalInit(fset *token.FileSet, filename string, src interface{}, mode parser
 func EvalStmt(ev *Evaluator, s ast.Stmt) (r SEXPItf) {
 	DEBUG := ev.Debug
 	if DEBUG && r==nil {
-		println("EvalStmt: nil")
+		ev.println("EvalStmt: nil")
 	}
 	switch s.(type) {
 	case *ast.ExprStmt:
@@ -112,9 +166,9 @@ func EvalStmt(ev *Evaluator, s ast.Stmt) (r SEXPItf) {
 	case *ast.EmptyStmt:
 		if DEBUG {
 			if s.(*ast.EmptyStmt).Implicit{
-				// println("emptyStmt (implicit)") // too many messages
+				// ev.println("emptyStmt (implicit)") // too many messages
 			} else {
-				println("emptyStmt")
+				ev.println("emptyStmt")
 			}
 		}
 		return nil
@@ -123,11 +177,11 @@ func EvalStmt(ev *Evaluator, s ast.Stmt) (r SEXPItf) {
 		trace(ev, "ifStmt")
 		e := s.(*ast.IfStmt)
 		testresult := EvalExpr(ev, e.Cond)
-		if testresult != nil && isTrue(testresult) {
-			if DEBUG {println("TRUE")}
+		if testresult != nil && isTrue(ev, testresult) {
+			if DEBUG {ev.println("TRUE")}
 			return EvalStmt(ev, e.Body)
 		} else if e.Else != nil {
-			if DEBUG {println("FALSE")}
+			if DEBUG {ev.println("FALSE")}
 			return EvalStmt(ev, e.Else)
 		}
 	case *ast.WhileStmt:
@@ -174,7 +228,7 @@ func EvalStmt(ev *Evaluator, s ast.Stmt) (r SEXPItf) {
 		return &ESEXP{Kind: token.EOF}
 	default:
 		givenType := reflect.TypeOf(s)
-		println("?Stmt:", givenType.String())
+		ev.println("?Stmt:", givenType.String())
 	}
 	return &ESEXP{Kind: token.ILLEGAL}
 }
@@ -184,6 +238,26 @@ func doAssignment(ev *Evaluator, lhs ast.Expr, rhs ast.Expr) SEXPItf {
 	switch lhs.(type) {
 	case *ast.CallExpr:
 		doAttributeReplacement(ev, lhs.(*ast.CallExpr), rhs)
+	case *ast.IndexExpr:
+		if inner := lhs.(*ast.IndexExpr).Array; !isIdent(inner) {
+			doNestedAssignment(ev, lhs, inner, rhs)
+		} else {
+			doIndexedAssignment(ev, lhs.(*ast.IndexExpr), rhs)
+		}
+	case *ast.ListIndexExpr:
+		if inner := lhs.(*ast.ListIndexExpr).Array; !isIdent(inner) {
+			doNestedAssignment(ev, lhs, inner, rhs)
+		} else {
+			doListIndexedAssignment(ev, lhs.(*ast.ListIndexExpr), rhs)
+		}
+	case *ast.BinaryExpr:
+		if lhs.(*ast.BinaryExpr).Op == token.SUBSET {
+			if inner := lhs.(*ast.BinaryExpr).X; !isIdent(inner) {
+				doNestedAssignment(ev, lhs, inner, rhs)
+			} else {
+				doListSubsetAssignment(ev, lhs.(*ast.BinaryExpr), rhs)
+			}
+		}
 	case *ast.Ident:
 		target := getIdent(ev, lhs)
 		defer un(ev)
@@ -195,6 +269,38 @@ func doAssignment(ev *Evaluator, lhs ast.Expr, rhs ast.Expr) SEXPItf {
 	return value
 }
 
+func isIdent(ex ast.Expr) bool {
+	_, ok := ex.(*ast.Ident)
+	return ok
+}
+
+// Nested replacements like l$a[2] <- v are done as in R: the value of the inner
+// expression l$a is modified as *tmp* and then assigned to l$a, which may be nested again.
+func doNestedAssignment(ev *Evaluator, lhs ast.Expr, inner ast.Expr, rhs ast.Expr) {
+	tmp := &ast.Ident{NamePos: inner.Pos(), Name: "*tmp*"}
+	ev.topFrame.Insert(tmp.Name, EvalExpr(ev, inner))
+	switch lhs.(type) {
+	case *ast.IndexExpr:
+		e := *lhs.(*ast.IndexExpr)
+		e.Array = tmp
+		doIndexedAssignment(ev, &e, rhs)
+	case *ast.ListIndexExpr:
+		e := *lhs.(*ast.ListIndexExpr)
+		e.Array = tmp
+		doListIndexedAssignment(ev, &e, rhs)
+	case *ast.BinaryExpr:
+		e := *lhs.(*ast.BinaryExpr)
+		e.X = tmp
+		doListSubsetAssignment(ev, &e, rhs)
+	}
+	// the modified value is passed on by another name, as *tmp* is reused by the next level
+	value := &ast.Ident{NamePos: inner.Pos(), Name: "*value*"}
+	ev.topFrame.Insert(value.Name, ev.topFrame.Objects[tmp.Name])
+	delete(ev.topFrame.Objects, tmp.Name)
+	doAssignment(ev, inner, value)
+	delete(ev.topFrame.Objects, value.Name)
+}
+
 func doSuperAssignment(ev *Evaluator, lhs ast.Expr, rhs ast.Expr) SEXPItf {
 	var value SEXPItf
 	switch lhs.(type) {
@@ -217,10 +323,10 @@ func EvalExprOrAssignment(ev *Evaluator, ex ast.Expr) SEXPItf {
 	DEBUG := ev.Debug
 	TRACE := ev.Trace
 	if TRACE {
-		println("Expr or assignment:")
+		ev.println("Expr or assignment:")
 	}
 	if DEBUG && ex==nil {
-		println("EvalExprOrAssignment: nil")
+		ev.println("EvalExprOrAssignment: nil")
 	}
 	switch ex.(type) {
 	case *ast.BinaryExpr:
@@ -263,36 +369,59 @@ func EvalLiteral(ev *Evaluator, node *ast.BasicLit) SEXPItf {
 		}
 		trace(ev, "BasicLit ", node.Kind.String()," = ", vint)
 		return &ISEXP{ValuePos: node.ValuePos, Immediate: vfloat, Integer: vint}
+	case token.IMAG:
+		vfloat, err := strconv.ParseFloat(strings.TrimSuffix(node.Value, "i"), 64)
+		if err != nil {
+			panic(err)
+		}
+		trace(ev, "BasicLit ", node.Kind.String()," = ", vfloat)
+		return &CSEXP{ValuePos: node.ValuePos, Immediate: complex(0, vfloat)}
 	case token.STRING:
 		trace(ev, "BasicLit ", node.Kind.String()," = ", node.Value)
 		return &TSEXP{ValuePos: node.ValuePos, String: node.Value}
 	case token.TRUE:
 		trace(ev, "BasicLit ", node.Kind.String())
-		return &VSEXP{ValuePos: node.ValuePos, Immediate: 1}   	// in R: TRUE+1 = 2
-	case token.NULL, token.FALSE:								// TODO just return nil?
+		return &LSEXP{ValuePos: node.ValuePos, Immediate: TRUE}   	// in R: TRUE+1 = 2
+	case token.FALSE:
+		trace(ev, "BasicLit ", node.Kind.String())
+		return &LSEXP{ValuePos: node.ValuePos, Immediate: FALSE}
+	case token.NULL:												// TODO just return nil?
 		trace(ev, "BasicLit ", node.Kind.String())
 		return &NSEXP{ValuePos: node.ValuePos}
 	case token.INF:
 		trace(ev, "BasicLit ", node.Kind.String())
 		return &VSEXP{ValuePos: node.ValuePos, Immediate: math.Inf(+1)}
-	case token.NAN, token.NA:
+	case token.NAN:
 		trace(ev, "BasicLit ", node.Kind.String())
 		return &VSEXP{ValuePos: node.ValuePos, Immediate: math.NaN()}
+	case token.NA:													// logical, as the lowest type in the hierarchy
+		trace(ev, "BasicLit ", node.Kind.String())
+		return &LSEXP{ValuePos: node.ValuePos, Immediate: NA_LOGICAL}
+	case token.NA_INTEGER:
+		trace(ev, "BasicLit ", node.Kind.String())
+		return &ISEXP{ValuePos: node.ValuePos, Immediate: calc.NA, Integer: NA_INTEGER}
+	case token.NA_REAL:
+		trace(ev, "BasicLit ", node.Kind.String())
+		return &VSEXP{ValuePos: node.ValuePos, Immediate: calc.NA}
+	case token.NA_CHARACTER:
+		trace(ev, "BasicLit ", node.Kind.String())
+		return &TSEXP{ValuePos: node.ValuePos, String: NA_CHARACTER}
 	case token.IDENT:
 		trace(ev, "BasicLit ", node.Kind.String()," = ", node.Value)
 		if DEBUG {
-			println("Retrieving identifier: " + node.Value)
+			ev.println("Retrieving identifier: " + node.Value)
 		}
 		r :=  ev.topFrame.Recursive(node.Value)
 		if r==nil {
 			if node.Value=="version" {
 				return &ESEXP{Kind: token.VERSION}
+			} else if b := lookupBuiltin(node.Value); b != nil {
+				return &VSEXP{ValuePos: node.ValuePos, builtin: b}
 			} else {
-				fmt.Printf("Error: object '%s' not found\n", node.Value)
-				return nil
+				return errorf("object '%s' not found", node.Value)
 			}
 		} else {
-			return r
+			return forceFuture(ev, r)
 		}
 	default:
 		panic("Unknown basic literal:"+node.Kind.String()+"\n")
@@ -319,12 +448,11 @@ func EvalQuotedExpr(ev *Evaluator, ex ast.Expr) SEXPItf {
 	case *ast.Ident:
 		trace(ev, "Ident '"+ex.(*ast.Ident).Name+"'")
 		if DEBUG {
-			println("Retrieving identifier for unquoting: " + ex.(*ast.Ident).Name)
+			ev.println("Retrieving identifier for unquoting: " + ex.(*ast.Ident).Name)
 		}
 		r :=  ev.topFrame.Recursive(ex.(*ast.Ident).Name)
 		if r==nil {
-			fmt.Printf("Error: object '%s' not found\n", ex.(*ast.Ident).Name)
-			return nil
+			return errorf("object '%s' not found", ex.(*ast.Ident).Name)
 		} else {
 			switch r.(type) {
 			case *QSEXP:
@@ -351,19 +479,26 @@ func EvalExpr(ev *Evaluator, ex ast.Expr) SEXPItf {
 	case *ast.Ident:
 		trace(ev, "Ident '"+ex.(*ast.Ident).Name+"'")
 		if DEBUG {
-			println("Retrieving identifier: " + ex.(*ast.Ident).Name)
+			ev.println("Retrieving identifier: " + ex.(*ast.Ident).Name)
 		}
 		r :=  ev.topFrame.Recursive(ex.(*ast.Ident).Name)
 		if r==nil {
 			if ex.(*ast.Ident).Name=="version" {
 				return &ESEXP{Kind: token.VERSION}
+			} else if b := lookupBuiltin(ex.(*ast.Ident).Name); b != nil {
+				return &VSEXP{ValuePos: ex.Pos(), builtin: b}
 			} else {
-				fmt.Printf("Error: object '%s' not found\n", ex.(*ast.Ident).Name)
-				return nil
+				return errorf("object '%s' not found", ex.(*ast.Ident).Name)
 			}
 		} else {
-			return r
+			return forceFuture(ev, r)
 		}
+	case *ast.BlockExpr:
+		r := EvalStmt(ev, ex.(*ast.BlockExpr).Body)
+		if r == nil {
+			return &NSEXP{}
+		}
+		return r
 	case *ast.FuncLit:
 		node := ex.(*ast.FuncLit)
 		defer un(ev)
@@ -385,8 +520,11 @@ func EvalExpr(ev *Evaluator, ex ast.Expr) SEXPItf {
 	case *ast.UnaryExpr:
 		return evalUnary(ev, ex.(*ast.UnaryExpr))
 	case *ast.CallExpr:
-		funcobject := ex.(*ast.CallExpr).Fun
-		return EvalCall(ev, funcobject.(*ast.Ident).Name, ex.(*ast.CallExpr))
+		funcobject, ok := ex.(*ast.CallExpr).Fun.(*ast.Ident)
+		if !ok {
+			return errorf("only functions given by name can be called")
+		}
+		return EvalCall(ev, funcobject.Name, ex.(*ast.CallExpr))
 	case *ast.QuotedExpr:
 		return &QSEXP{X: ex.(*ast.QuotedExpr).X}
 	case *ast.EvalExpr:
@@ -409,12 +547,12 @@ func EvalExpr(ev *Evaluator, ex ast.Expr) SEXPItf {
 	case *ast.ParenExpr:
 		node := ex.(*ast.ParenExpr)
 		if DEBUG {
-			println("ParenExpr")
+			ev.println("ParenExpr")
 		}
 		return EvalExpr(ev, node.X)
 	default:
 		givenType := reflect.TypeOf(ex)
-		println("?EvalExpr:", givenType.String())
+		ev.println("?EvalExpr:", givenType.String())
 		return &ESEXP{Kind: token.ILLEGAL}
 	}
 }
@@ -422,9 +560,17 @@ func EvalExpr(ev *Evaluator, ex ast.Expr) SEXPItf {
 func evalUnary(ev *Evaluator, node *ast.UnaryExpr) SEXPItf {
 	defer un(ev)
 	trace(ev, "UnaryExpr")
-		if node.Op==token.MINUS {
-			targetExpr := EvalExpr(ev,node.X).(*VSEXP)
-			return EvalOp(node.Op,&VSEXP{Immediate: 0},targetExpr)
+		if node.Op==token.TILDE {
+			return evalFormula(ev, node)
+		} else if node.Op==token.MINUS || node.Op==token.PLUS {
+			targetExpr := EvalExpr(ev,node.X)
+			return EvalArithmetic(ev, node.Op, &ISEXP{Immediate: 0, Integer: 0}, targetExpr)
+		} else if node.Op==token.NOT {
+			targetExpr := EvalExpr(ev,node.X)
+			if targetExpr == nil {
+				return nil
+			}
+			return EvalNot(ev, targetExpr)
 		} else {
 			panic("Unknown unary operator")
 		}
@@ -433,13 +579,28 @@ func evalUnary(ev *Evaluator, node *ast.UnaryExpr) SEXPItf {
 func evalBinary(ev *Evaluator, node *ast.BinaryExpr) SEXPItf {
 	defer un(ev)
 	trace(ev, "BinaryExpr")
+	if node.Op == token.SUBSET {
+		return EvalListSubset(ev, node)
+	}
+	if node.Op == token.TILDE {
+		return evalFormula(ev, node)
+	}
 	x := EvalExpr(ev, node.X)
+	if _, ok := x.(*ESEXP); ok {
+		return x // the error is already reported
+	}
 	un(traceff(ev, node.Op.String()))
 	switch node.Op {
-	case token.AND, token.ANDVECTOR:
-		if isTrue(x) {
+	case token.ANDVECTOR, token.ORVECTOR:
+		y := EvalExpr(ev, node.Y)
+		if x == nil || y == nil {
+			return nil
+		}
+		return EvalLogical(ev, node.Op, x, y)
+	case token.AND:
+		if isTrue(ev, x) {
 			y := EvalExpr(ev, node.Y)
-			if isTrue(y) {
+			if isTrue(ev, y) {
 				return y
 			} else {
 				return nil
@@ -447,12 +608,12 @@ func evalBinary(ev *Evaluator, node *ast.BinaryExpr) SEXPItf {
 		} else {
 			return nil
 		}
-	case token.OR, token.ORVECTOR:
-		if isTrue(x) {
+	case token.OR:
+		if isTrue(ev, x) {
 			return x
 		} else {
 			y := EvalExpr(ev, node.Y)
-			if isTrue(y) {
+			if isTrue(ev, y) {
 				return y
 			} else {
 				return nil
@@ -460,49 +621,24 @@ func evalBinary(ev *Evaluator, node *ast.BinaryExpr) SEXPItf {
 		}
 	case token.SEQUENCE:
 		// TODO: same for INDEXDOMAIN
-		low := EvalExpr(ev, node.X)
-		high := EvalExpr(ev, node.Y)
-		slice := make([]float64, 1+high.IntegerGet()-low.IntegerGet())
-		start := low.FloatGet()
-		for n, _ := range slice {
-			slice[n] = start
-			start = start + 1
+		y := EvalExpr(ev, node.Y)
+		if _, ok := y.(*ESEXP); ok {
+			return y
 		}
-		return &VSEXP{Slice: slice}
+		return EvalColon(ev, x, y)
 	case token.LESS, token.LESSEQUAL, token.GREATER, token.GREATEREQUAL, token.EQUAL, token.UNEQUAL:
 		y := EvalExpr(ev, node.Y)
 		if x == nil || y == nil {
 			return nil
-		} else if assertVSEXPVSEXP(x,y) {
-			return EvalComp(node.Op, x.(*VSEXP), y.(*VSEXP))
-		} else {
-			return &ESEXP{Kind: token.ILLEGAL}
 		}
+		return EvalComparison(ev, node.Op, x, y)
 	default:
 		y := EvalExpr(ev, node.Y)
 		if x == nil || y == nil {
 			return nil
-		} else if assertVSEXPVSEXP(x,y) {
-			return EvalOp(node.Op, x.(*VSEXP), y.(*VSEXP))
-		} else {
-			return &ESEXP{Kind: token.ILLEGAL}
 		}
+		return EvalArithmetic(ev, node.Op, x, y)
 	}
 }
 
-func assertVSEXPVSEXP(x SEXPItf,y SEXPItf) bool{
-	switch x.(type) {
-	case *VSEXP:
-		switch y.(type) {
-		case *VSEXP:
-			return true
-		default: 
-			fmt.Printf("Error: non-numeric argument to binary operator\n")
-			return false
-		}
-	default:
-		fmt.Printf("Error: non-numeric argument to binary operator\n")
-		return false
-	}
-}
 	
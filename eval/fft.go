@@ -0,0 +1,270 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/fft.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/convolve.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/filter.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/nextn.html
+// Transforms of any length keep the attributes of their argument, arrays are transformed
+// along all dimensions. Filters return plain vectors or matrices instead of time series.
+
+func init() {
+	registerBuiltin("fft", []string{"z", "inverse"}, EvalFFT)
+	registerBuiltin("mvfft", []string{"z", "inverse"}, EvalMvfft)
+	registerBuiltin("convolve", []string{"x", "y", "conj", "type"}, EvalConvolve)
+	registerBuiltin("filter", []string{"x", "filter", "method", "sides", "circular", "init"}, EvalLinearFilter)
+	registerBuiltin("nextn", []string{"n", "factors"}, EvalNextn)
+}
+
+func fftArgument(ev *Evaluator, funcname string, formal string, z SEXPItf) bool {
+	if z == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return false
+	}
+	switch sexpType(z) {
+	case LGLSXP, INTSXP, REALSXP, CPLXSXP:
+		return true
+	}
+	builtinError(ev, funcname, "non-numeric argument")
+	return false
+}
+
+func newComplexes(pos token.Pos, slice []complex128, scalar bool) *CSEXP {
+	if scalar && len(slice) == 1 {
+		return &CSEXP{ValuePos: pos, Immediate: slice[0]}
+	}
+	return &CSEXP{ValuePos: pos, Slice: slice}
+}
+
+func EvalFFT(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	z := args.Values[0]
+	if !fftArgument(ev, "fft", "z", z) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	inverse := args.logical(1, false)
+	warn := false
+	var r []complex128
+	if dim := z.Dim(); len(dim) > 1 {
+		r = numeric.FFTArray(asComplexes(z, &warn), dim, inverse)
+	} else {
+		r = numeric.FFT(asComplexes(z, &warn), inverse)
+	}
+	return mathResult(z, newComplexes(node.Fun.Pos(), r, isScalar(z)))
+}
+
+// the columns of a matrix are transformed separately
+func EvalMvfft(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	z := args.Values[0]
+	if !fftArgument(ev, "mvfft", "z", z) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	dim := z.Dim()
+	if len(dim) != 2 {
+		return builtinError(ev, "mvfft", "vector-valued (multivariate) series required")
+	}
+	inverse := args.logical(1, false)
+	warn := false
+	values := asComplexes(z, &warn)
+	r := make([]complex128, 0, len(values))
+	for col := 0; col < dim[1]; col++ {
+		r = append(r, numeric.FFT(values[col*dim[0]:(col+1)*dim[0]], inverse)...)
+	}
+	return mathResult(z, newComplexes(node.Fun.Pos(), r, false))
+}
+
+// the convolution by the product of the transforms as R does, with noise of rounding
+func EvalConvolve(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, y := args.Values[0], args.Values[1]
+	if !fftArgument(ev, "convolve", "x", x) || !fftArgument(ev, "convolve", "y", y) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	conj := args.logical(2, true)
+	convolution, ok := choiceArgument(ev, "convolve", args.Values[3], []string{"circular", "open", "filter"})
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	a, b := asComplexes(x, &warn), asComplexes(y, &warn)
+	n, n1 := len(a), len(b)-1
+	if convolution == "circular" {
+		if len(b) != n {
+			return builtinError(ev, "convolve", "length mismatch in convolution")
+		}
+	} else {
+		a = append(make([]complex128, n1), a...)
+		b = append(b, make([]complex128, n-1)...)
+		n = len(b)
+	}
+	fa, fb := numeric.FFT(a, false), numeric.FFT(b, false)
+	for k := range fa {
+		if conj {
+			fb[k] = complex(real(fb[k]), -imag(fb[k]))
+		}
+		fa[k] *= fb[k]
+	}
+	c := numeric.FFT(fa, true)
+	if convolution == "filter" {
+		c = c[n1 : n-n1]
+	}
+	pos := node.Fun.Pos()
+	if sexpType(x) == CPLXSXP || sexpType(y) == CPLXSXP {
+		for k := range c {
+			c[k] /= complex(float64(n), 0)
+		}
+		return &CSEXP{ValuePos: pos, Slice: c}
+	}
+	r := make([]float64, len(c))
+	for k, v := range c {
+		r[k] = real(v) / float64(n)
+	}
+	return &VSEXP{ValuePos: pos, Slice: r}
+}
+
+// the moving sums of cfilter, with the filter centered for two sides
+func convolutionFilter(x []float64, filter []float64, sides int, circular bool) []float64 {
+	nx, nf := len(x), len(filter)
+	nshift := 0
+	if sides == 2 {
+		nshift = nf / 2
+	}
+	out := make([]float64, nx)
+	for i := range out {
+		out[i] = calc.NA
+		if !circular && (i+nshift-(nf-1) < 0 || i+nshift >= nx) {
+			continue
+		}
+		z := 0.
+		for j := 0; j < nf; j++ {
+			ii := ((i+nshift-j)%nx + nx) % nx
+			if math.IsNaN(x[ii]) {
+				z = calc.NA
+				break
+			}
+			z += filter[j] * x[ii]
+		}
+		out[i] = z
+	}
+	return out
+}
+
+// the autoregression of rfilter started from the values init, the latest first
+func recursiveFilter(x []float64, filter []float64, init []float64) []float64 {
+	nf := len(filter)
+	r := make([]float64, nf+len(x))
+	for k, v := range init {
+		r[nf-1-k] = v
+	}
+	for i, v := range x {
+		sum := v
+		for j := 0; j < nf; j++ {
+			tmp := r[nf+i-j-1]
+			if math.IsNaN(tmp) {
+				sum = calc.NA
+				break
+			}
+			sum += tmp * filter[j]
+		}
+		r[nf+i] = sum
+	}
+	return r[nf:]
+}
+
+func EvalLinearFilter(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !numericArgument(ev, "filter", "x", x) || !numericArgument(ev, "filter", "filter", args.Values[1]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	method, ok := choiceArgument(ev, "filter", args.Values[2], []string{"convolution", "recursive"})
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	filter := asFloats(args.Values[1], &warn)
+	for _, v := range filter {
+		if math.IsNaN(v) {
+			return builtinError(ev, "filter", "missing values in 'filter'")
+		}
+	}
+	series, _ := columns(x)
+	n, nf := len(series[0]), len(filter)
+	r := make([]float64, 0, n*len(series))
+	if method == "convolution" {
+		if nf > n {
+			return builtinError(ev, "filter", "'filter' is longer than time series")
+		}
+		sides := args.float(3, 2)
+		if sides != 1 && sides != 2 {
+			return builtinError(ev, "filter", "argument 'sides' must be 1 or 2")
+		}
+		circular := args.logical(4, false)
+		for _, s := range series {
+			r = append(r, convolutionFilter(s, filter, int(sides), circular)...)
+		}
+	} else {
+		init := make([]float64, nf*len(series))
+		if v := args.Values[5]; v != nil {
+			init = asFloats(v, &warn)
+			rows, cols := len(init), 1
+			if dim := v.Dim(); len(dim) == 2 {
+				rows, cols = dim[0], dim[1]
+			}
+			if rows != nf {
+				return builtinError(ev, "filter", "length of 'init' must equal length of 'filter'")
+			}
+			if cols != 1 && cols != len(series) {
+				if len(series) == 1 {
+					return builtinError(ev, "filter", "'init' must have %d column", len(series))
+				}
+				return builtinError(ev, "filter", "'init' must have 1 or %d columns", len(series))
+			}
+		}
+		for k, s := range series {
+			start := (k * nf) % len(init)
+			r = append(r, recursiveFilter(s, filter, init[start:start+nf])...)
+		}
+	}
+	pos := node.Fun.Pos()
+	if dim := x.Dim(); len(dim) == 2 {
+		return matrixResult(pos, &numeric.Matrix{Rows: dim[0], Cols: dim[1], Data: r}, nil, dimnamesAt(x, 1))
+	}
+	return &VSEXP{ValuePos: pos, Slice: r}
+}
+
+func EvalNextn(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if !numericArgument(ev, "nextn", "n", args.Values[0]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	factors := []int{2, 3, 5}
+	if v := args.Values[1]; v != nil {
+		factors = asIntegers(v, &warn)
+	}
+	if len(factors) == 0 {
+		return builtinError(ev, "nextn", "no factors")
+	}
+	for _, f := range factors {
+		if f == NA_INTEGER || f <= 1 {
+			return builtinError(ev, "nextn", "invalid factors")
+		}
+	}
+	n := asIntegers(args.Values[0], &warn)
+	r := make([]int, len(n))
+	for k, v := range n {
+		r[k] = NA_INTEGER
+		if v != NA_INTEGER {
+			r[k] = numeric.Nextn(v, factors)
+		}
+	}
+	pos := node.Fun.Pos()
+	if len(r) == 1 {
+		return &ISEXP{ValuePos: pos, Immediate: float64(r[0]), Integer: r[0]}
+	}
+	return &ISEXP{ValuePos: pos, Slice: r}
+}
@@ -0,0 +1,932 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/formula.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/terms.formula.html
+// Model formulae are quoted expressions of class "formula", which keep the frame
+// they were created in to find variables not given as data. Their terms are
+// encoded as in R's terms.formula: sets of variables combined by +, -, :, *, /,
+// %in% and ^, ordered by the number of variables. Character and logical variables
+// are factors with their sorted values as levels, coded by treatment contrasts.
+
+func init() {
+	registerBuiltin("terms", []string{"x", "data", "keep.order", "..."}, EvalTerms)
+	registerBuiltin("all.vars", []string{"expr", "functions", "max.names", "unique"}, EvalAllVars)
+	registerBuiltin("update.formula", []string{"old", "new", "..."}, EvalUpdateFormula)
+	registerBuiltin("model.frame", []string{"formula", "data", "subset", "na.action", "..."}, EvalModelFrame)
+	registerBuiltin("model.matrix", []string{"object", "data", "contrasts.arg", "..."}, EvalModelMatrix)
+}
+
+// y ~ x and ~ x evaluate to themselves with the frame of the evaluation
+func evalFormula(ev *Evaluator, x ast.Expr) SEXPItf {
+	class := "formula"
+	r := &QSEXP{ValuePos: x.Pos(), X: x, Frame: ev.topFrame}
+	r.ClassSet(&class)
+	return r
+}
+
+func isFormula(x SEXPItf) bool {
+	q, ok := x.(*QSEXP)
+	return ok && q.Class() != nil && (*q.Class() == "formula" || *q.Class() == "terms")
+}
+
+func formulaArgument(ev *Evaluator, funcname string, formal string, x SEXPItf) (*QSEXP, bool) {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return nil, false
+	}
+	if !isFormula(x) {
+		builtinError(ev, funcname, "argument is not a valid model")
+		return nil, false
+	}
+	return x.(*QSEXP), true
+}
+
+// the response and the right hand side, the response is nil for one-sided formulae
+func formulaSides(f *QSEXP) (ast.Expr, ast.Expr) {
+	switch e := f.X.(type) {
+	case *ast.BinaryExpr:
+		return e.X, e.Y
+	case *ast.UnaryExpr:
+		return nil, e.X
+	}
+	return nil, nil
+}
+
+func printFormula(w io.Writer, f *QSEXP) {
+	fmt.Fprintln(w, deparse(f.X.(ast.Expr)))
+	if f.Attributes == nil {
+		return
+	}
+	for k, name := range f.Attributes.Names() {
+		fmt.Fprintf(w, "attr(,\"%s\")\n", name)
+		v := f.Attributes.Slice[k]
+		if q, ok := v.(*QSEXP); ok {
+			fmt.Fprintln(w, deparse(q.X.(ast.Expr)))
+		} else {
+			PrintResult(w, v)
+		}
+	}
+}
+
+// a lone . is scanned as NA, which stands for the remaining data in formulae
+func isDot(x ast.Expr) bool {
+	lit, ok := x.(*ast.BasicLit)
+	return ok && lit.Kind == token.NA && lit.Value == "."
+}
+
+// a term is the set of its variables, given by their increasing indices
+type term []int
+
+func (t term) union(u term) term {
+	r := append(append(term(nil), t...), u...)
+	sort.Ints(r)
+	n := 0
+	for k, v := range r {
+		if k == 0 || v != r[n-1] {
+			r[n] = v
+			n++
+		}
+	}
+	return r[:n]
+}
+
+// whether all variables of u are in t
+func (t term) contains(u term) bool {
+	for _, v := range u {
+		k := sort.SearchInts(t, v)
+		if k == len(t) || t[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (t term) equal(u term) bool {
+	return len(t) == len(u) && t.contains(u)
+}
+
+func (t term) without(v int) term {
+	var r term
+	for _, w := range t {
+		if w != v {
+			r = append(r, w)
+		}
+	}
+	return r
+}
+
+// terms without repeats in the order of their first appearance
+func plusTerms(a []term, b []term) []term {
+	var r []term
+	for _, t := range append(append([]term(nil), a...), b...) {
+		found := false
+		for _, u := range r {
+			found = found || u.equal(t)
+		}
+		if !found && len(t) > 0 {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+func interactTerms(a []term, b []term) []term {
+	var r []term
+	for _, t := range a {
+		for _, u := range b {
+			r = append(r, t.union(u))
+		}
+	}
+	return plusTerms(r, nil)
+}
+
+func allVariables(a []term) term {
+	var r term
+	for _, t := range a {
+		r = r.union(t)
+	}
+	return r
+}
+
+type modelTerms struct {
+	variables []ast.Expr
+	labels    []string // deparsed variables
+	terms     []term
+	intercept bool
+	response  bool
+	data      []string // the names of the data for ., nil if there are none
+	allowDot  bool     // without data . is a variable
+	negate    bool     // on the right of -, where 1 removes the intercept
+	expanded  bool     // whether . was replaced by the data
+	err       string
+}
+
+func (m *modelTerms) variable(x ast.Expr) int {
+	label := deparse(x)
+	for k, l := range m.labels {
+		if l == label {
+			return k
+		}
+	}
+	m.variables = append(m.variables, x)
+	m.labels = append(m.labels, label)
+	return len(m.labels) - 1
+}
+
+func (m *modelTerms) encode(x ast.Expr) []term {
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		return m.encode(e.X)
+	case *ast.BasicLit:
+		if isDot(e) {
+			return m.dot(e)
+		}
+		if e.Kind == token.INT || e.Kind == token.FLOAT {
+			if v, err := strconv.ParseFloat(e.Value, 64); err == nil && (v == 0 || v == 1) {
+				m.intercept = (v == 1) != m.negate
+				return nil
+			}
+		}
+		m.err = "invalid model formula"
+		return nil
+	case *ast.UnaryExpr:
+		switch e.Op {
+		case token.PLUS:
+			return m.encode(e.X)
+		case token.MINUS:
+			return m.remove(nil, e.X)
+		}
+		m.err = "invalid model formula in ExtractVars"
+		return nil
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.PLUS:
+			a := m.encode(e.X)
+			return plusTerms(a, m.encode(e.Y))
+		case token.MINUS:
+			return m.remove(m.encode(e.X), e.Y)
+		case token.SEQUENCE:
+			a := m.encode(e.X)
+			return interactTerms(a, m.encode(e.Y))
+		case token.MULTIPLICATION:
+			a := m.encode(e.X)
+			b := m.encode(e.Y)
+			return plusTerms(plusTerms(a, b), interactTerms(a, b))
+		case token.DIVISION:
+			a := m.encode(e.X)
+			return plusTerms(a, interactTerms(m.encode(e.Y), []term{allVariables(a)}))
+		case token.EXPONENTIATION:
+			a := m.encode(e.X)
+			lit, ok := e.Y.(*ast.BasicLit)
+			n, err := 0, error(nil)
+			if ok {
+				n, err = strconv.Atoi(lit.Value)
+			}
+			if !ok || err != nil || n < 1 {
+				m.err = "invalid power in formula"
+				return nil
+			}
+			r := a
+			for k := 1; k < n; k++ {
+				r = plusTerms(r, interactTerms(r, a))
+			}
+			return r
+		case token.TILDE:
+			m.err = "invalid model formula"
+			return nil
+		}
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && id.Name == "%in%" && len(e.Args) == 2 {
+			a := m.encode(e.Args[0])
+			return interactTerms(a, []term{allVariables(m.encode(e.Args[1]))})
+		}
+	}
+	return []term{{m.variable(x)}}
+}
+
+// . expands to the data except the response, without data it is kept for update.formula
+func (m *modelTerms) dot(x *ast.BasicLit) []term {
+	if m.data != nil {
+		var r []term
+		for _, name := range m.data {
+			if !m.response || m.labels[0] != name {
+				r = append(r, term{m.variable(&ast.Ident{NamePos: x.ValuePos, Name: name})})
+			}
+		}
+		m.expanded = true
+		return plusTerms(r, nil)
+	}
+	if !m.allowDot {
+		m.err = "'.' in formula and no 'data' argument"
+		return nil
+	}
+	return []term{{m.variable(x)}}
+}
+
+// terms of the right side of - are removed, where 0 and 1 have the opposite effect
+func (m *modelTerms) remove(a []term, y ast.Expr) []term {
+	m.negate = !m.negate
+	b := m.encode(y)
+	m.negate = !m.negate
+	var r []term
+	for _, t := range a {
+		found := false
+		for _, u := range b {
+			found = found || u.equal(t)
+		}
+		if !found {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+// the terms of a formula, data gives the variables for .
+func newModelTerms(f *QSEXP, data []string, allowDot bool, keepOrder bool) (*modelTerms, string) {
+	m := &modelTerms{intercept: true, data: data, allowDot: allowDot}
+	lhs, rhs := formulaSides(f)
+	if lhs != nil {
+		m.response = true
+		m.variable(lhs)
+	}
+	m.terms = m.encode(rhs)
+	if m.err != "" {
+		return nil, m.err
+	}
+	if m.response {
+		// the response is never a term
+		var r []term
+		for _, t := range m.terms {
+			if !t.equal(term{0}) {
+				r = append(r, t)
+			}
+		}
+		m.terms = r
+	}
+	if !keepOrder {
+		sort.SliceStable(m.terms, func(i, j int) bool {
+			return len(m.terms[i]) < len(m.terms[j])
+		})
+	}
+	return m, ""
+}
+
+func (m *modelTerms) label(t term) string {
+	labels := make([]string, len(t))
+	for k, v := range t {
+		labels[k] = m.labels[v]
+	}
+	return strings.Join(labels, ":")
+}
+
+// 1 if a variable is coded by contrasts in a term, 2 if by indicators for all levels:
+// contrasts are used, if the term without the variable is empty or in a preceding term
+func (m *modelTerms) code(k int, v int) int {
+	rest := m.terms[k].without(v)
+	if len(rest) == 0 {
+		return 1
+	}
+	for _, t := range m.terms[:k] {
+		if t.contains(rest) {
+			return 1
+		}
+	}
+	return 2
+}
+
+// the terms object is the formula with the attributes of R
+func (m *modelTerms) object(f *QSEXP) *QSEXP {
+	pos := f.ValuePos
+	labels := make([]string, len(m.terms))
+	order := make([]int, len(m.terms))
+	for k, t := range m.terms {
+		labels[k] = m.label(t)
+		order[k] = len(t)
+	}
+	var factors SEXPItf = &ISEXP{ValuePos: pos, Slice: []int{}}
+	if len(m.terms) > 0 {
+		slice := make([]int, len(m.labels)*len(m.terms))
+		for k, t := range m.terms {
+			for _, v := range t {
+				slice[v+k*len(m.labels)] = m.code(k, v)
+			}
+		}
+		factors = &ISEXP{ValuePos: pos, Slice: slice}
+		factors.DimSet([]int{len(m.labels), len(m.terms)})
+		factors.DimnamesSet(&RSEXP{Slice: []SEXPItf{&TSEXP{Slice: m.labels}, &TSEXP{Slice: labels}}})
+	}
+	variables := &QSEXP{ValuePos: pos, X: &ast.CallExpr{Fun: &ast.Ident{NamePos: pos, Name: "list"}, Args: m.variables}}
+	attributes := &RSEXP{ValuePos: pos, Slice: []SEXPItf{
+		variables,
+		factors,
+		&TSEXP{ValuePos: pos, Slice: labels},
+		&ISEXP{ValuePos: pos, Slice: order},
+		&ISEXP{ValuePos: pos, Immediate: float64(logical(m.intercept)), Integer: logical(m.intercept)},
+		&ISEXP{ValuePos: pos, Immediate: float64(logical(m.response)), Integer: logical(m.response)},
+	}}
+	attributes.NamesSet([]string{"variables", "factors", "term.labels", "order", "intercept", "response"})
+	class := "terms"
+	r := &QSEXP{ValuePos: pos, X: f.X, Frame: f.Frame, Attributes: attributes}
+	if lhs, _ := formulaSides(f); m.expanded && lhs != nil {
+		r.X = &ast.BinaryExpr{X: lhs, OpPos: pos, Op: token.TILDE, Y: m.expr(pos)}
+	} else if m.expanded {
+		r.X = &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: m.expr(pos)}
+	}
+	r.ClassSet(&class)
+	return r
+}
+
+// the right hand side of the simplified formula
+func (m *modelTerms) expr(pos token.Pos) ast.Expr {
+	var r ast.Expr
+	for _, t := range m.terms {
+		var x ast.Expr
+		for _, v := range t {
+			if x == nil {
+				x = m.variables[v]
+			} else {
+				x = &ast.BinaryExpr{X: x, OpPos: pos, Op: token.SEQUENCE, Y: m.variables[v]}
+			}
+		}
+		if r == nil {
+			r = x
+		} else {
+			r = &ast.BinaryExpr{X: r, OpPos: pos, Op: token.PLUS, Y: x}
+		}
+	}
+	one := &ast.BasicLit{ValuePos: pos, Kind: token.INT, Value: "1"}
+	switch {
+	case r == nil && m.intercept:
+		return one
+	case r == nil:
+		return &ast.UnaryExpr{OpPos: pos, Op: token.MINUS, X: one}
+	case !m.intercept:
+		return &ast.BinaryExpr{X: r, OpPos: pos, Op: token.MINUS, Y: one}
+	}
+	return r
+}
+
+func dataArgument(ev *Evaluator, funcname string, data SEXPItf) (*RSEXP, bool) {
+	if data == nil || sexpType(data) == NILSXP {
+		return nil, true
+	}
+	l, ok := data.(*RSEXP)
+	if !ok || l.Slice == nil || (len(l.Slice) > 0 && l.Names() == nil) {
+		builtinError(ev, funcname, "'data' must be a data.frame, environment, or list")
+		return nil, false
+	}
+	return l, true
+}
+
+func formulaTerms(ev *Evaluator, funcname string, f *QSEXP, data *RSEXP) (*modelTerms, bool) {
+	var names []string
+	if data != nil {
+		names = data.Names()
+		if names == nil {
+			names = []string{}
+		}
+	}
+	m, err := newModelTerms(f, names, false, false)
+	if err != "" {
+		builtinError(ev, funcname, "%s", err)
+		return nil, false
+	}
+	return m, true
+}
+
+func EvalTerms(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := formulaArgument(ev, "terms", "x", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if f.Attributes != nil {
+		return f
+	}
+	data, ok := dataArgument(ev, "terms", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	var names []string
+	if data != nil {
+		names = data.Names()
+	}
+	m, err := newModelTerms(f, names, false, args.logical(2, false))
+	if err != "" {
+		return builtinError(ev, "terms", "%s", err)
+	}
+	return m.object(f)
+}
+
+// the names of variables in order of appearance, functions are skipped unless requested
+func allVars(x ast.Expr, functions bool, names *[]string) {
+	if isDot(x) {
+		*names = append(*names, ".")
+		return
+	}
+	switch e := x.(type) {
+	case *ast.Ident:
+		*names = append(*names, e.Name)
+	case *ast.ParenExpr:
+		allVars(e.X, functions, names)
+	case *ast.UnaryExpr:
+		if functions {
+			*names = append(*names, e.Op.String())
+		}
+		allVars(e.X, functions, names)
+	case *ast.BinaryExpr:
+		if functions {
+			*names = append(*names, e.Op.String())
+		}
+		allVars(e.X, functions, names)
+		allVars(e.Y, functions, names)
+	case *ast.CallExpr:
+		if _, ok := e.Fun.(*ast.Ident); ok {
+			if functions {
+				allVars(e.Fun, functions, names)
+			}
+		} else {
+			allVars(e.Fun, functions, names)
+		}
+		for _, a := range e.Args {
+			allVars(a, functions, names)
+		}
+	case *ast.TaggedExpr:
+		allVars(e.Rhs, functions, names)
+	case *ast.IndexExpr:
+		allVars(e.Array, functions, names)
+		for _, a := range e.Index {
+			allVars(a, functions, names)
+		}
+	case *ast.ListIndexExpr:
+		allVars(e.Array, functions, names)
+		for _, a := range e.Index {
+			allVars(a, functions, names)
+		}
+	}
+}
+
+func EvalAllVars(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "all.vars", "argument \"expr\" is missing, with no default")
+	}
+	quoted := []SEXPItf{x}
+	if l, ok := x.(*RSEXP); ok {
+		quoted = l.Slice
+	}
+	var names []string
+	for _, v := range quoted {
+		if q, ok := v.(*QSEXP); ok {
+			if e, ok := q.X.(ast.Expr); ok {
+				allVars(e, args.logical(1, false), &names)
+			}
+		}
+	}
+	if args.logical(3, true) {
+		var unique []string
+		for _, name := range names {
+			if matchName(name, unique, true) < 0 {
+				unique = append(unique, name)
+			}
+		}
+		names = unique
+	}
+	if max := int(args.float(2, -1)); max >= 0 && max < len(names) {
+		names = names[:max]
+	}
+	return &TSEXP{ValuePos: node.Fun.Pos(), Slice: append([]string{}, names...)}
+}
+
+// a copy of the expression with . replaced
+func substituteDot(x ast.Expr, by ast.Expr) ast.Expr {
+	if by == nil {
+		return x
+	}
+	if isDot(x) {
+		return by
+	}
+	switch e := x.(type) {
+	case *ast.ParenExpr:
+		return &ast.ParenExpr{Left: e.Left, X: substituteDot(e.X, by), Right: e.Right}
+	case *ast.UnaryExpr:
+		return &ast.UnaryExpr{OpPos: e.OpPos, Op: e.Op, X: substituteDot(e.X, by)}
+	case *ast.BinaryExpr:
+		return &ast.BinaryExpr{X: substituteDot(e.X, by), OpPos: e.OpPos, Op: e.Op, Y: substituteDot(e.Y, by)}
+	case *ast.CallExpr:
+		args := make([]ast.Expr, len(e.Args))
+		for k, a := range e.Args {
+			args[k] = substituteDot(a, by)
+		}
+		return &ast.CallExpr{Fun: e.Fun, Left: e.Left, Args: args, Right: e.Right}
+	case *ast.TaggedExpr:
+		return &ast.TaggedExpr{X: e.X, Tag: e.Tag, OpPos: e.OpPos, Rhs: substituteDot(e.Rhs, by)}
+	}
+	return x
+}
+
+// the dots of the new formula stand for the sides of the old one, the result is simplified
+func EvalUpdateFormula(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	old, ok := formulaArgument(ev, "update.formula", "old", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	new, ok := formulaArgument(ev, "update.formula", "new", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	oldLhs, oldRhs := formulaSides(old)
+	newLhs, newRhs := formulaSides(new)
+	lhs := oldLhs
+	if newLhs != nil {
+		lhs = substituteDot(newLhs, oldLhs)
+	}
+	pos := node.Fun.Pos()
+	var x ast.Expr = &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: substituteDot(newRhs, oldRhs)}
+	if lhs != nil {
+		x = &ast.BinaryExpr{X: lhs, OpPos: pos, Op: token.TILDE, Y: substituteDot(newRhs, oldRhs)}
+	}
+	r := &QSEXP{ValuePos: pos, X: x, Frame: old.Frame}
+	m, err := newModelTerms(r, nil, true, false)
+	if err != "" {
+		return builtinError(ev, "update.formula", "%s", err)
+	}
+	if lhs != nil {
+		r.X = &ast.BinaryExpr{X: lhs, OpPos: pos, Op: token.TILDE, Y: m.expr(pos)}
+	} else {
+		r.X = &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: m.expr(pos)}
+	}
+	class := "formula"
+	r.ClassSet(&class)
+	return r
+}
+
+// the variables of the terms evaluated in the data and the frame of the formula, with complete rows
+type modelFrame struct {
+	terms   *modelTerms
+	columns []SEXPItf
+	rows    []int // the selected rows, counted from 0
+}
+
+// a variable is a column of the data with its label, or evaluated with the columns as objects
+func evalVariable(ev *Evaluator, x ast.Expr, label string, data *RSEXP, frame *Frame) SEXPItf {
+	if data != nil {
+		if k := matchName(label, data.Names(), true); k >= 0 {
+			return data.Slice[k]
+		}
+	}
+	if frame == nil {
+		frame = ev.topFrame
+	}
+	saved := ev.topFrame
+	ev.topFrame = NewFrame(frame)
+	if data != nil {
+		for k, name := range data.Names() {
+			ev.topFrame.Insert(name, data.Slice[k])
+		}
+	}
+	r := EvalExpr(ev, x)
+	ev.topFrame = saved
+	if r == nil {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return r
+}
+
+// the name of an na.action given as function or character string
+func naAction(ev *Evaluator, funcname string, x SEXPItf) (string, bool) {
+	switch {
+	case x == nil || sexpType(x) == NILSXP:
+		return "na.omit", true
+	case sexpType(x) == BUILTINSXP:
+		return x.(*VSEXP).builtin.name, true
+	case sexpType(x) == STRSXP && x.Length() == 1:
+		name := asStrings(x)[0]
+		if name == "na.omit" || name == "na.exclude" || name == "na.fail" || name == "na.pass" {
+			return name, true
+		}
+	}
+	builtinError(ev, funcname, "invalid 'na.action' argument")
+	return "", false
+}
+
+// the selected rows without missing values, unless they pass
+func completeRows(ev *Evaluator, funcname string, columns []SEXPItf, n int, rows []int, action string) ([]int, bool) {
+	complete := make([]bool, n)
+	for k := range complete {
+		complete[k] = true
+	}
+	for _, c := range columns {
+		markIncomplete(c, complete)
+	}
+	var r []int
+	for _, row := range rows {
+		if complete[row] || action == "na.pass" {
+			r = append(r, row)
+		} else if action == "na.fail" {
+			builtinError(ev, funcname, "missing values in object")
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+func newModelFrame(ev *Evaluator, funcname string, f *QSEXP, dataArg SEXPItf, subset SEXPItf, na SEXPItf) (*modelFrame, bool) {
+	data, ok := dataArgument(ev, funcname, dataArg)
+	if !ok {
+		return nil, false
+	}
+	m, ok := formulaTerms(ev, funcname, f, data)
+	if !ok {
+		return nil, false
+	}
+	action, ok := naAction(ev, funcname, na)
+	if !ok {
+		return nil, false
+	}
+	mf := &modelFrame{terms: m}
+	n := -1
+	for k, x := range m.variables {
+		v := evalVariable(ev, x, m.labels[k], data, f.Frame)
+		if isError(v) {
+			return nil, false
+		}
+		switch sexpType(v) {
+		case LGLSXP, INTSXP, REALSXP, CPLXSXP, STRSXP:
+		default:
+			builtinError(ev, funcname, "invalid type (%s) for variable '%s'", typeName(sexpType(v)), m.labels[k])
+			return nil, false
+		}
+		if n < 0 {
+			n = v.Length()
+		} else if v.Length() != n {
+			builtinError(ev, funcname, "variable lengths differ (found for '%s')", m.labels[k])
+			return nil, false
+		}
+		mf.columns = append(mf.columns, v)
+	}
+	for row := 0; row < n; row++ {
+		mf.rows = append(mf.rows, row)
+	}
+	if subset != nil && sexpType(subset) != NILSXP {
+		warn := false
+		var rows []int
+		if sexpType(subset) == LGLSXP {
+			for row, l := range asLogicals(subset) {
+				if l == TRUE && row < n {
+					rows = append(rows, row)
+				}
+			}
+		} else {
+			for _, i := range asIntegers(subset, &warn) {
+				if i >= 1 && i <= n {
+					rows = append(rows, i-1)
+				}
+			}
+		}
+		mf.rows = rows
+	}
+	if mf.rows, ok = completeRows(ev, funcname, mf.columns, n, mf.rows, action); !ok {
+		return nil, false
+	}
+	if len(mf.rows) < n {
+		for k, c := range mf.columns {
+			mf.columns[k] = indexElements(ev, c, mf.rows)
+		}
+	}
+	return mf, true
+}
+
+func (mf *modelFrame) list(pos token.Pos) *RSEXP {
+	r := &RSEXP{ValuePos: pos, Slice: append([]SEXPItf{}, mf.columns...)}
+	r.NamesSet(append([]string{}, mf.terms.labels...))
+	class := "data.frame"
+	r.ClassSet(&class)
+	return r
+}
+
+func EvalModelFrame(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := formulaArgument(ev, "model.frame", "formula", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	mf, ok := newModelFrame(ev, "model.frame", f, args.Values[1], args.Values[2], args.Values[3])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return mf.list(node.Fun.Pos())
+}
+
+// the sorted distinct values of a character or logical variable, nil for numeric ones
+func factorLevels(x SEXPItf) []string {
+	if sexpType(x) != STRSXP && sexpType(x) != LGLSXP {
+		return nil
+	}
+	var levels []string
+	for _, s := range asStrings(x) {
+		if s != NA_CHARACTER && matchName(s, levels, true) < 0 {
+			levels = append(levels, s)
+		}
+	}
+	sort.Strings(levels)
+	return levels
+}
+
+// the levels of the factors by their labels
+func (mf *modelFrame) xlevels() map[string][]string {
+	r := map[string][]string{}
+	for k, c := range mf.columns {
+		if levels := factorLevels(c); levels != nil {
+			r[mf.terms.labels[k]] = levels
+		}
+	}
+	return r
+}
+
+// the columns of a variable and their names: the values of numeric variables,
+// indicators of the levels of factors, without the first one for contrasts
+func variableColumns(ev *Evaluator, funcname string, x SEXPItf, label string, levels []string, code int) ([][]float64, []string, bool) {
+	if levels == nil {
+		warn := false
+		return [][]float64{asFloats(x, &warn)}, []string{label}, true
+	}
+	values := asStrings(x)
+	for _, s := range values {
+		if matchName(s, levels, true) < 0 {
+			builtinError(ev, funcname, "factor %s has new level %s", label, s)
+			return nil, nil, false
+		}
+	}
+	if code == 1 {
+		if len(levels) < 2 {
+			builtinError(ev, "contrasts<-", "contrasts can be applied only to factors with 2 or more levels")
+			return nil, nil, false
+		}
+		levels = levels[1:]
+	}
+	columns := make([][]float64, len(levels))
+	names := make([]string, len(levels))
+	for k, level := range levels {
+		columns[k] = make([]float64, len(values))
+		for row, s := range values {
+			if s == level {
+				columns[k][row] = 1
+			}
+		}
+		names[k] = label + level
+	}
+	return columns, names, true
+}
+
+// an intercept, then the columns of each term as products of the columns of its variables,
+// with the names of the columns and the terms they belong to, 0 for the intercept
+func (mf *modelFrame) design(ev *Evaluator, funcname string, xlevels map[string][]string) (*numeric.Matrix, []string, []int, bool) {
+	m := mf.terms
+	n := len(mf.rows)
+	var columns [][]float64
+	var names []string
+	var assign []int
+	if m.intercept {
+		ones := make([]float64, n)
+		for k := range ones {
+			ones[k] = 1
+		}
+		columns = append(columns, ones)
+		names = append(names, "(Intercept)")
+		assign = append(assign, 0)
+	}
+	codes := make([]map[int]int, len(m.terms))
+	for k, t := range m.terms {
+		codes[k] = map[int]int{}
+		for _, v := range t {
+			codes[k][v] = m.code(k, v)
+		}
+	}
+	// without intercept the first factor is coded by all its levels
+	if !m.intercept {
+	first:
+		for k, t := range m.terms {
+			for _, v := range t {
+				if xlevels[m.labels[v]] != nil {
+					codes[k][v] = 2
+					break first
+				}
+			}
+		}
+	}
+	for k, t := range m.terms {
+		termColumns := [][]float64{nil}
+		termNames := []string{""}
+		for _, v := range t {
+			vc, vn, ok := variableColumns(ev, funcname, mf.columns[v], m.labels[v], xlevels[m.labels[v]], codes[k][v])
+			if !ok {
+				return nil, nil, nil, false
+			}
+			var products [][]float64
+			var productNames []string
+			for j, c := range vc {
+				for i, p := range termColumns {
+					product := make([]float64, n)
+					for row := range product {
+						product[row] = c[row]
+						if p != nil {
+							product[row] *= p[row]
+						}
+					}
+					products = append(products, product)
+					if termNames[i] == "" {
+						productNames = append(productNames, vn[j])
+					} else {
+						productNames = append(productNames, termNames[i]+":"+vn[j])
+					}
+				}
+			}
+			termColumns, termNames = products, productNames
+		}
+		columns = append(columns, termColumns...)
+		names = append(names, termNames...)
+		for range termColumns {
+			assign = append(assign, k+1)
+		}
+	}
+	x := numeric.NewMatrix(n, len(columns))
+	for k, c := range columns {
+		copy(x.Col(k), c)
+	}
+	return x, names, assign, true
+}
+
+// the original row numbers
+func (mf *modelFrame) rownames() []string {
+	r := make([]string, len(mf.rows))
+	for k, row := range mf.rows {
+		r[k] = strconv.Itoa(row + 1)
+	}
+	return r
+}
+
+func EvalModelMatrix(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := formulaArgument(ev, "model.matrix", "object", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	mf, ok := newModelFrame(ev, "model.matrix", f, args.Values[1], nil, nil)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	x, names, _, ok := mf.design(ev, "model.matrix", mf.xlevels())
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return matrixResult(node.Fun.Pos(), x, mf.rownames(), names)
+}
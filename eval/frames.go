@@ -24,8 +24,8 @@ func DumpFrames(ev *Evaluator) {
 func (f *Frame) Dump(ev *Evaluator, level int) {
 	n := 1
 	for key,value := range f.Objects {
-		print("DUMP\t",level,"\t",n,":\t",key,"\t")
-		PrintResult(value)
+		ev.print("DUMP\t",level,"\t",n,":\t",key,"\t")
+		PrintResult(ev.log, value)
 		n++
 	}
 	if f.Outer != nil {
@@ -60,18 +60,18 @@ func (f *Frame) Recursive(name string) (r SEXPItf) {
 	return
 }
 
-func (f *Frame) Delete(name string, DEBUG bool) () {
+func (f *Frame) Delete(ev *Evaluator, name string) () {
 	r := f.Objects[name]
 	if r == nil {
 		if f.Outer != nil {
-			f.Outer.Delete(name, DEBUG)
+			f.Outer.Delete(ev, name)
 		} else {
-			print("In remove(",name,") : object '",name,"' not found\n")
+			ev.warning("remove("+name+")", "object '"+name+"' not found")
 		}
 	} else {
 		delete(f.Objects,name)
-		if DEBUG {
-			println("Removed object: ",name)
+		if ev.Debug {
+			ev.println("Removed object: ",name)
 		} 
 	}
 }
@@ -0,0 +1,608 @@
+package eval
+
+import (
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strconv"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/lapply.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/funprog.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/do.call.html
+// Functions are applied to evaluated arguments: closures through EvalApply,
+// builtins through their registered function, so both can be used interchangeably.
+
+func init() {
+	registerBuiltin("match.fun", []string{"FUN", "descend"}, EvalMatchFun)
+	registerBuiltin("lapply", []string{"X", "FUN", "..."}, EvalLapply)
+	registerBuiltin("sapply", []string{"X", "FUN", "...", "simplify", "USE.NAMES"}, EvalSapply)
+	registerBuiltin("vapply", []string{"X", "FUN", "FUN.VALUE", "...", "USE.NAMES"}, EvalVapply)
+	registerBuiltin("mapply", []string{"FUN", "...", "MoreArgs", "SIMPLIFY", "USE.NAMES"}, EvalMapply)
+	registerBuiltin("Map", []string{"f", "..."}, EvalMap)
+	registerBuiltin("Reduce", []string{"f", "x", "init", "right", "accumulate", "simplify"}, EvalReduce)
+	registerBuiltin("Filter", []string{"f", "x"}, EvalFilter)
+	registerBuiltin("Position", []string{"f", "x", "right", "nomatch"}, EvalPosition)
+	registerBuiltin("Find", []string{"f", "x", "right", "nomatch"}, EvalFind)
+	registerBuiltin("do.call", []string{"what", "args", "quote", "envir"}, EvalDoCall)
+	registerBuiltin("Vectorize", []string{"FUN", "vectorize.args", "SIMPLIFY", "USE.NAMES"}, EvalVectorize)
+	registerBuiltin("Recall", []string{"..."}, EvalRecall)
+}
+
+// a function given as closure, builtin or by its name
+func matchFunction(ev *Evaluator, funcname string, f SEXPItf) (*VSEXP, bool) {
+	if isFunction(f) {
+		return f.(*VSEXP), true
+	}
+	if sexpType(f) == STRSXP && f.Length() == 1 {
+		name := asStrings(f)[0]
+		if v := ev.topFrame.Recursive(name); isFunction(v) {
+			return v.(*VSEXP), true
+		}
+		if b := lookupBuiltin(name); b != nil {
+			return &VSEXP{ValuePos: f.Pos(), builtin: b}, true
+		}
+		builtinError(ev, funcname, "object '%s' of mode 'function' was not found", name)
+		return nil, false
+	}
+	builtinError(ev, funcname, "'%s' is not a function, character or symbol", deparseValue(f))
+	return nil, false
+}
+
+func functionName(f *VSEXP, def string) string {
+	if f.builtin != nil {
+		return f.builtin.name
+	}
+	return def
+}
+
+func formalNames(f *VSEXP) []string {
+	if f.builtin != nil {
+		return f.builtin.formals
+	}
+	return getArgNames(f)
+}
+
+// arguments are matched to the formals as for calls, the dots of a closure are kept as ..1, ..2 etc.
+func callFunction(ev *Evaluator, node *ast.CallExpr, funcname string, f *VSEXP, values []SEXPItf, tags []string) SEXPItf {
+	funcname = functionName(f, funcname)
+	if f.builtin != nil {
+		args, ok := matchBuiltinArgs(ev, funcname, f.builtin.formals, values, tags)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		call := &ast.CallExpr{Fun: &ast.Ident{NamePos: node.Pos(), Name: funcname}, Left: node.Pos(), Right: node.Pos()}
+		return f.builtin.fun(ev, call, args)
+	}
+	argNames := getArgNames(f)
+	args, ok := matchBuiltinArgs(ev, funcname, argNames, values, tags)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if !f.ellipsis {
+		r := EvalApply(ev, funcname, f, argNames, args.Values)
+		if r == nil {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		return r
+	}
+	frame := NewFrame(nil)
+	for k, name := range argNames {
+		if name != "..." && args.Values[k] != nil {
+			frame.Insert(name, args.Values[k])
+		}
+	}
+	for n, v := range args.Dots {
+		frame.Insert(".."+strconv.Itoa(n+1), v)
+	}
+	return EvalApplyFrameToBody(ev, funcname, f, frame)
+}
+
+func isError(x SEXPItf) bool {
+	_, ok := x.(*ESEXP)
+	return ok
+}
+
+func EvalMatchFun(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := matchFunction(ev, "match.fun", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return f
+}
+
+// FUN applied to each element of X followed by further arguments
+func applyElements(ev *Evaluator, node *ast.CallExpr, f *VSEXP, X SEXPItf, extra []SEXPItf, extraTags []string) ([]SEXPItf, bool) {
+	elements := asElements(ev, X)
+	r := make([]SEXPItf, len(elements))
+	for n, e := range elements {
+		values := append([]SEXPItf{e}, extra...)
+		tags := append([]string{""}, extraTags...)
+		r[n] = callFunction(ev, node, "FUN", f, values, tags)
+		if isError(r[n]) {
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+// names of X, or its values, if it is an unnamed character vector
+func useNames(X SEXPItf, use bool) []string {
+	if !use {
+		return nil
+	}
+	if X.Names() == nil && sexpType(X) == STRSXP {
+		return asStrings(X)
+	}
+	return X.Names()
+}
+
+// results of common length are simplified to a vector or to a matrix with a column for each result,
+// lists of length one to a list of their elements as by unlist(recursive = FALSE)
+func simplifyResults(ev *Evaluator, pos token.Pos, values []SEXPItf, names []string) SEXPItf {
+	if r, ok := unlistResults(pos, values, names); ok {
+		return r
+	}
+	length := -1
+	for _, v := range values {
+		if !isAtomic(v) || (length >= 0 && v.Length() != length) {
+			length = -1
+			break
+		}
+		length = v.Length()
+	}
+	if len(values) == 0 || length < 1 {
+		r := &RSEXP{ValuePos: pos, Slice: values}
+		r.NamesSet(names)
+		return r
+	}
+	r := combine(ev, pos, values, nil, false, length == 1 && names == nil)
+	if length == 1 {
+		if names != nil {
+			r.NamesSet(names)
+		}
+		return r
+	}
+	r.NamesSet(nil)
+	r.DimSet([]int{length, len(values)})
+	if rownames := values[0].Names(); rownames != nil || names != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(rownames), namesOrNull(names)}})
+	}
+	return r
+}
+
+// the names of the elements are those of the results, prefixed by the names of X
+func unlistResults(pos token.Pos, values []SEXPItf, names []string) (SEXPItf, bool) {
+	if len(values) == 0 {
+		return nil, false
+	}
+	elements := make([]SEXPItf, len(values))
+	flatNames := make([]string, len(values))
+	named := false
+	for k, v := range values {
+		l, ok := v.(*RSEXP)
+		if !ok || l.Slice == nil || len(l.Slice) != 1 {
+			return nil, false
+		}
+		elements[k] = l.Slice[0]
+		if names != nil {
+			flatNames[k] = names[k]
+		}
+		if inner := l.Names(); inner != nil && inner[0] != "" {
+			if flatNames[k] != "" {
+				flatNames[k] += "."
+			}
+			flatNames[k] += inner[0]
+		}
+		named = named || flatNames[k] != ""
+	}
+	r := &RSEXP{ValuePos: pos, Slice: elements}
+	if named {
+		r.NamesSet(flatNames)
+	}
+	return r, true
+}
+
+func EvalLapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	X := args.Values[0]
+	if X == nil {
+		return builtinError(ev, "lapply", "argument \"X\" is missing, with no default")
+	}
+	f, ok := matchFunction(ev, "lapply", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values, ok := applyElements(ev, node, f, X, args.Dots, args.DotNames)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+	r.NamesSet(X.Names())
+	return r
+}
+
+func EvalSapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	X := args.Values[0]
+	if X == nil {
+		return builtinError(ev, "sapply", "argument \"X\" is missing, with no default")
+	}
+	f, ok := matchFunction(ev, "sapply", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values, ok := applyElements(ev, node, f, X, args.Dots, args.DotNames)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	names := useNames(X, args.logical(4, true))
+	if !args.logical(3, true) {
+		r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+		r.NamesSet(names)
+		return r
+	}
+	return simplifyResults(ev, node.Fun.Pos(), values, names)
+}
+
+// results must have the length of FUN.VALUE and a type, which can be coerced to its type
+func EvalVapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	X, template := args.Values[0], args.Values[2]
+	if X == nil {
+		return builtinError(ev, "vapply", "argument \"X\" is missing, with no default")
+	}
+	if template == nil || !isAtomic(template) && sexpType(template) != VECSXP {
+		return builtinError(ev, "vapply", "'FUN.VALUE' must be a vector")
+	}
+	f, ok := matchFunction(ev, "vapply", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values, ok := applyElements(ev, node, f, X, args.Dots, args.DotNames)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	t, length := sexpType(template), template.Length()
+	for n, v := range values {
+		if v.Length() != length {
+			return builtinError(ev, "vapply", "values must be length %d,\n but FUN(X[[%d]]) result is length %d", length, n+1, v.Length())
+		}
+		vt := sexpType(v)
+		if vt != t && (coercionRank(vt) > coercionRank(t) || t == STRSXP || t == VECSXP || !isAtomic(v)) {
+			return builtinError(ev, "vapply", "values must be type '%s',\n but FUN(X[[%d]]) result is type '%s'", typeName(t), n+1, typeName(vt))
+		}
+		values[n] = coerceVector(ev, v, t)
+	}
+	names := useNames(X, args.logical(4, true))
+	pos := node.Fun.Pos()
+	if len(values) == 0 {
+		warn := false
+		r := newVector(ev, t, pos, &LSEXP{Slice: []int{}}, false, &warn)
+		if length != 1 {
+			r.DimSet([]int{length, 0})
+		}
+		return r
+	}
+	if t == VECSXP {
+		r := &RSEXP{ValuePos: pos}
+		for _, v := range values {
+			r.Slice = append(r.Slice, asElements(ev, v)...)
+		}
+		r.NamesSet(names)
+		return r
+	}
+	r := combine(ev, pos, values, nil, false, length == 1 && names == nil)
+	if length == 1 {
+		if names != nil {
+			r.NamesSet(names)
+		}
+		return r
+	}
+	r.NamesSet(nil)
+	r.DimSet([]int{length, len(values)})
+	rownames := template.Names()
+	if rownames == nil {
+		rownames = values[0].Names()
+	}
+	if rownames != nil || names != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(rownames), namesOrNull(names)}})
+	}
+	return r
+}
+
+// FUN applied to the recycled elements of all vectors followed by further arguments
+func mapplyValues(ev *Evaluator, node *ast.CallExpr, f *VSEXP, vectors []SEXPItf, tags []string, more []SEXPItf, moreTags []string) ([]SEXPItf, bool) {
+	length := 0
+	for _, v := range vectors {
+		length = calc.IntMax(length, v.Length())
+	}
+	for _, v := range vectors {
+		if v.Length() == 0 && length > 0 {
+			builtinError(ev, "mapply", "zero-length inputs cannot be mixed with those of non-zero length")
+			return nil, false
+		}
+	}
+	for _, v := range vectors {
+		if length%v.Length() != 0 {
+			ev.warning("", "longer argument not a multiple of length of shorter")
+			break
+		}
+	}
+	elements := make([][]SEXPItf, len(vectors))
+	for k, v := range vectors {
+		elements[k] = asElements(ev, v)
+	}
+	r := make([]SEXPItf, length)
+	for n := range r {
+		values := make([]SEXPItf, len(vectors))
+		for k := range vectors {
+			values[k] = elements[k][n%len(elements[k])]
+		}
+		r[n] = callFunction(ev, node, "FUN", f, append(values, more...), append(append([]string(nil), tags...), moreTags...))
+		if isError(r[n]) {
+			return nil, false
+		}
+	}
+	return r, true
+}
+
+func mapplyNames(vectors []SEXPItf, use bool) []string {
+	if len(vectors) == 0 {
+		return nil
+	}
+	return useNames(vectors[0], use)
+}
+
+func EvalMapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := matchFunction(ev, "mapply", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	var more []SEXPItf
+	var moreTags []string
+	if m := args.Values[2]; m != nil && sexpType(m) != NILSXP {
+		if sexpType(m) != VECSXP {
+			return builtinError(ev, "mapply", "argument 'MoreArgs' of 'mapply' is not a list")
+		}
+		more = asElements(ev, m)
+		moreTags = make([]string, len(more))
+		copy(moreTags, m.Names())
+	}
+	values, ok := mapplyValues(ev, node, f, args.Dots, args.DotNames, more, moreTags)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	names := mapplyNames(args.Dots, args.logical(4, true))
+	if !args.logical(3, true) {
+		r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+		r.NamesSet(names)
+		return r
+	}
+	return simplifyResults(ev, node.Fun.Pos(), values, names)
+}
+
+func EvalMap(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := matchFunction(ev, "Map", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values, ok := mapplyValues(ev, node, f, args.Dots, args.DotNames, nil, nil)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+	r.NamesSet(mapplyNames(args.Dots, true))
+	return r
+}
+
+// successive application from the left, or from the right
+func EvalReduce(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := matchFunction(ev, "Reduce", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	items := asElements(ev, args.Values[1])
+	right, accumulate := args.logical(3, false), args.logical(4, false)
+	if init := args.Values[2]; init != nil {
+		if right {
+			items = append(items, init)
+		} else {
+			items = append([]SEXPItf{init}, items...)
+		}
+	}
+	if len(items) == 0 {
+		if accumulate {
+			return &RSEXP{ValuePos: node.Fun.Pos(), Slice: []SEXPItf{}}
+		}
+		return &NSEXP{}
+	}
+	out := make([]SEXPItf, len(items))
+	if right {
+		last := len(items) - 1
+		out[last] = items[last]
+		for n := last - 1; n >= 0; n-- {
+			out[n] = callFunction(ev, node, "f", f, []SEXPItf{items[n], out[n+1]}, []string{"", ""})
+			if isError(out[n]) {
+				return out[n]
+			}
+		}
+		if !accumulate {
+			return out[0]
+		}
+	} else {
+		out[0] = items[0]
+		for n := 1; n < len(items); n++ {
+			out[n] = callFunction(ev, node, "f", f, []SEXPItf{out[n-1], items[n]}, []string{"", ""})
+			if isError(out[n]) {
+				return out[n]
+			}
+		}
+		if !accumulate {
+			return out[len(out)-1]
+		}
+	}
+	if args.logical(5, true) {
+		return simplifyResults(ev, node.Fun.Pos(), out, nil)
+	}
+	return &RSEXP{ValuePos: node.Fun.Pos(), Slice: out}
+}
+
+// offsets of the elements, for which f gives TRUE, stopping at the first one, if requested
+func predicateOffsets(ev *Evaluator, node *ast.CallExpr, funcname string, args *Arguments, first bool, right bool) ([]int, bool) {
+	f, ok := matchFunction(ev, funcname, args.Values[0])
+	if !ok {
+		return nil, false
+	}
+	elements := asElements(ev, args.Values[1])
+	offsets := []int{}
+	for k := range elements {
+		n := k
+		if right {
+			n = len(elements) - 1 - k
+		}
+		v := callFunction(ev, node, "f", f, []SEXPItf{elements[n]}, []string{""})
+		if isError(v) {
+			return nil, false
+		}
+		truth, ok := truthValues(v)
+		if ok && len(truth) > 0 && truth[0] == TRUE {
+			offsets = append(offsets, n)
+			if first {
+				break
+			}
+		}
+	}
+	return offsets, true
+}
+
+func EvalFilter(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	offsets, ok := predicateOffsets(ev, node, "Filter", args, false, false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if args.Values[1] == nil {
+		return &NSEXP{}
+	}
+	return repeated(ev, args.Values[1], offsets)
+}
+
+func EvalPosition(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	offsets, ok := predicateOffsets(ev, node, "Position", args, true, args.logical(2, false))
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(offsets) == 0 {
+		if args.Values[3] != nil {
+			return args.Values[3]
+		}
+		return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: calc.NA, Integer: NA_INTEGER}
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(offsets[0] + 1), Integer: offsets[0] + 1}
+}
+
+func EvalFind(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	offsets, ok := predicateOffsets(ev, node, "Find", args, true, args.logical(2, false))
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(offsets) == 0 {
+		if args.Values[3] != nil {
+			return args.Values[3]
+		}
+		return &NSEXP{}
+	}
+	return asElements(ev, args.Values[1])[offsets[0]]
+}
+
+// the elements of args are the arguments, their names become tags
+func EvalDoCall(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	what := args.Values[0]
+	f, ok := matchFunction(ev, "do.call", what)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	funcname := "what"
+	if sexpType(what) == STRSXP {
+		funcname = asStrings(what)[0]
+	}
+	list := args.Values[1]
+	if list != nil && sexpType(list) != VECSXP && sexpType(list) != NILSXP {
+		return builtinError(ev, "do.call", "second argument must be a list")
+	}
+	values := asElements(ev, list)
+	tags := make([]string, len(values))
+	if list != nil {
+		copy(tags, list.Names())
+	}
+	return callFunction(ev, node, funcname, f, values, tags)
+}
+
+// a builtin, which maps FUN over the given vectorized arguments, all others are passed on
+func EvalVectorize(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f, ok := matchFunction(ev, "Vectorize", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	formals := formalNames(f)
+	vectorized := map[string]bool{}
+	if v := args.Values[1]; v != nil {
+		for _, name := range asStrings(v) {
+			found := false
+			for _, formal := range formals {
+				found = found || (formal == name && formal != "...")
+			}
+			if !found {
+				return builtinError(ev, "Vectorize", "must specify names of formal arguments for 'vectorize'")
+			}
+			vectorized[name] = true
+		}
+	} else {
+		for _, formal := range formals {
+			if formal != "..." {
+				vectorized[formal] = true
+			}
+		}
+	}
+	if len(vectorized) == 0 {
+		return f
+	}
+	simplify, use := args.logical(2, true), args.logical(3, true)
+	name := functionName(f, "FUN")
+	return &VSEXP{ValuePos: node.Fun.Pos(), builtin: &builtin{name: name, formals: formals, fun: func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+		var vectors, more []SEXPItf
+		var tags, moreTags []string
+		for k, formal := range formals {
+			switch {
+			case formal == "...":
+				more = append(more, args.Dots...)
+				moreTags = append(moreTags, args.DotNames...)
+			case args.Values[k] == nil:
+			case vectorized[formal]:
+				vectors = append(vectors, args.Values[k])
+				tags = append(tags, formal)
+			default:
+				more = append(more, args.Values[k])
+				moreTags = append(moreTags, formal)
+			}
+		}
+		if len(vectors) == 0 {
+			return callFunction(ev, node, name, f, more, moreTags)
+		}
+		values, ok := mapplyValues(ev, node, f, vectors, tags, more, moreTags)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		names := mapplyNames(vectors, use)
+		if !simplify {
+			r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+			r.NamesSet(names)
+			return r
+		}
+		return simplifyResults(ev, node.Fun.Pos(), values, names)
+	}}}
+}
+
+func EvalRecall(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if ev.closure == nil {
+		return builtinError(ev, "Recall", "Recall called from outside a closure")
+	}
+	return callFunction(ev, node, "Recall", ev.closure, args.Dots, args.DotNames)
+}
@@ -0,0 +1,437 @@
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strings"
+	"sync"
+)
+
+// https://cran.r-project.org/web/packages/future/vignettes/future-1-overview.html
+// A future evaluates its expression on an evaluator, which sees only copies of the
+// globals of the expression. These are found by walking the expression and the bodies
+// of functions it refers to for identifiers, which are not assigned before. The plan
+// decides, whether futures are evaluated at once or on a pool of goroutines. Nested
+// futures and futures within goroutines are always evaluated sequentially. The output
+// of a future is kept until its value is requested.
+
+func init() {
+	registerBuiltin("value", []string{"future", "..."}, EvalValue)
+	registerBuiltin("resolved", []string{"x", "..."}, EvalResolved)
+}
+
+type future struct {
+	done     chan bool // closed, when the value is available
+	value    SEXPItf
+	err      *condition // the error, if the evaluation failed
+	output   bytes.Buffer
+	warnings []string
+	relayed  sync.Once // output and warnings are relayed only once
+	implicit bool      // created by %<-%, resolved when the variable is read
+}
+
+func (f *future) String() string {
+	if f.isResolved() {
+		return "future: resolved"
+	}
+	return "future: unresolved"
+}
+
+func (f *future) isResolved() bool {
+	select {
+	case <-f.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// the strategy how tasks are run, shared with copies of the evaluator inside loops
+type futurePlan struct {
+	name     string
+	executor executor
+}
+
+type executor interface {
+	submit(task func())
+}
+
+type sequential struct{}
+
+func (sequential) submit(task func()) {
+	task()
+}
+
+// a new goroutine for each task, at most as many at the same time as workers
+type pool struct {
+	slots chan bool
+}
+
+func (p *pool) submit(task func()) {
+	p.slots <- true // blocks, while all workers are busy
+	go func() {
+		defer func() { <-p.slots }()
+		task()
+	}()
+}
+
+func sequentialPlan() *futurePlan {
+	return &futurePlan{name: "sequential", executor: sequential{}}
+}
+
+// identifiers, which are read before they are assigned, in the order of their first use
+type globalsWalker struct {
+	names []string
+	seen  map[string]bool
+}
+
+func (w *globalsWalker) use(name string, bound map[string]bool) {
+	if !bound[name] && !w.seen[name] {
+		w.seen[name] = true
+		w.names = append(w.names, name)
+	}
+}
+
+func (w *globalsWalker) assign(target ast.Expr, value ast.Expr, bound map[string]bool, local bool) {
+	w.expr(value, bound)
+	if ident, ok := target.(*ast.Ident); ok {
+		if local {
+			bound[ident.Name] = true
+		}
+	} else {
+		w.expr(target, bound) // replacement of elements or attributes reads the object
+	}
+}
+
+func (w *globalsWalker) function(fields []*ast.Field, body ast.Stmt, bound map[string]bool) {
+	inner := make(map[string]bool, len(bound))
+	for k := range bound {
+		inner[k] = true
+	}
+	for _, field := range fields {
+		if ident, ok := field.Type.(*ast.Ident); ok {
+			inner[ident.Name] = true
+		}
+		if field.Default != nil {
+			w.expr(field.Default, inner)
+		}
+	}
+	w.stmt(body, inner)
+}
+
+func (w *globalsWalker) exprs(list []ast.Expr, bound map[string]bool) {
+	for _, ex := range list {
+		w.expr(ex, bound)
+	}
+}
+
+func (w *globalsWalker) expr(ex ast.Expr, bound map[string]bool) {
+	switch node := ex.(type) {
+	case *ast.Ident:
+		w.use(node.Name, bound)
+	case *ast.BasicLit:
+		if node.Kind == token.IDENT {
+			w.use(node.Value, bound)
+		}
+	case *ast.FuncLit:
+		w.function(node.Type.Params.List, node.Body, bound)
+	case *ast.BlockExpr:
+		w.stmt(node.Body, bound)
+	case *ast.ParenExpr:
+		w.expr(node.X, bound)
+	case *ast.EvalExpr:
+		w.expr(node.X, bound)
+	case *ast.SelectorExpr:
+		w.expr(node.X, bound)
+	case *ast.IndexExpr:
+		w.expr(node.Array, bound)
+		w.exprs(node.Index, bound)
+	case *ast.ListIndexExpr:
+		w.expr(node.Array, bound)
+		w.exprs(node.Index, bound)
+	case *ast.CallExpr:
+		w.expr(node.Fun, bound)
+		w.exprs(node.Args, bound)
+	case *ast.ArbitraryCallExpr:
+		w.expr(node.Fun, bound)
+		w.exprs(node.Args, bound)
+	case *ast.UnaryExpr:
+		w.expr(node.X, bound)
+	case *ast.TaggedExpr:
+		w.expr(node.Rhs, bound)
+	case *ast.KeyValueExpr:
+		w.expr(node.Value, bound)
+	case *ast.BinaryExpr:
+		switch node.Op {
+		case token.LEFTASSIGNMENT, token.SHORTASSIGNMENT:
+			w.assign(node.X, node.Y, bound, true)
+		case token.RIGHTASSIGNMENT:
+			w.assign(node.Y, node.X, bound, true)
+		case token.SUPERLEFTASSIGNMENT:
+			w.assign(node.X, node.Y, bound, false)
+		case token.SUPERRIGHTASSIGNMENT:
+			w.assign(node.Y, node.X, bound, false)
+		default:
+			w.expr(node.X, bound)
+			w.expr(node.Y, bound)
+		}
+	}
+}
+
+func (w *globalsWalker) stmt(s ast.Stmt, bound map[string]bool) {
+	switch node := s.(type) {
+	case *ast.ExprStmt:
+		w.expr(node.X, bound)
+	case *ast.BlockStmt:
+		for _, stmt := range node.List {
+			w.stmt(stmt, bound)
+		}
+	case *ast.ReturnStmt:
+		if node.Result != nil {
+			w.expr(node.Result, bound)
+		}
+	case *ast.IfStmt:
+		w.expr(node.Cond, bound)
+		w.stmt(node.Body, bound)
+		if node.Else != nil {
+			w.stmt(node.Else, bound)
+		}
+	case *ast.WhileStmt:
+		w.expr(node.Cond, bound)
+		w.stmt(node.Body, bound)
+	case *ast.RepeatStmt:
+		w.stmt(node.Body, bound)
+	case *ast.ForStmt:
+		w.expr(node.Iterable, bound)
+		bound[node.Parameter.Name] = true
+		w.stmt(node.Body, bound)
+	}
+}
+
+// copies of the globals of ex, including those of the closures it refers to
+func futureGlobals(ev *Evaluator, ex ast.Expr) *Frame {
+	w := &globalsWalker{seen: map[string]bool{}}
+	w.expr(ex, map[string]bool{})
+	global := NewFrame(nil)
+	for n := 0; n < len(w.names); n++ { // the list grows with the globals of closures
+		v := ev.topFrame.Recursive(w.names[n])
+		if v == nil {
+			continue
+		}
+		global.Insert(w.names[n], deepCopy(v))
+		if f, ok := v.(*VSEXP); ok && f.Body != nil {
+			w.function(f.Fieldlist, f.Body, map[string]bool{})
+		}
+	}
+	return global
+}
+
+// the expression is submitted to the executor of the current plan
+func newFuture(ev *Evaluator, ex ast.Expr, implicit bool) *XSEXP {
+	f := &future{done: make(chan bool), implicit: implicit}
+	g := ev.isolate(futureGlobals(ev, ex))
+	g.out = &syncWriter{w: &f.output}
+	plan := ev.futures
+	if plan == nil {
+		plan = sequentialPlan()
+	}
+	plan.executor.submit(func() {
+		defer close(f.done)
+		defer func() {
+			f.warnings = *g.warnings
+			if p := recover(); p != nil {
+				if c, ok := p.(*condition); ok {
+					f.err = c
+				} else {
+					f.err = &condition{call: "future()", message: fmt.Sprint(p)}
+				}
+			} else if e, ok := f.value.(*ESEXP); ok {
+				f.err = &condition{call: "value()", message: e.Message}
+				if e.Message == "" {
+					f.err.message = "evaluation of future failed"
+				}
+			}
+		}()
+		f.value = EvalExprOrAssignment(g, ex)
+		if f.value == nil {
+			f.value = &ESEXP{Kind: token.ILLEGAL}
+		}
+	})
+	r := &XSEXP{ValuePos: ex.Pos(), Pointer: f}
+	class := "Future"
+	r.ClassSet(&class)
+	return r
+}
+
+// waits for the value, output and warnings are relayed by the first call,
+// the error of a failed evaluation is raised again by every call
+func futureValue(ev *Evaluator, f *future) SEXPItf {
+	<-f.done
+	f.relayed.Do(func() {
+		ev.out.Write(f.output.Bytes())
+		*ev.warnings = append(*ev.warnings, f.warnings...)
+	})
+	if f.err != nil {
+		panic(f.err)
+	}
+	return f.value
+}
+
+// the value of a variable assigned by %<-%
+func forceFuture(ev *Evaluator, x SEXPItf) SEXPItf {
+	if p, ok := x.(*XSEXP); ok {
+		if f, ok := p.Pointer.(*future); ok && f.implicit {
+			return futureValue(ev, f)
+		}
+	}
+	return x
+}
+
+func futureArgument(ev *Evaluator, funcname string, x SEXPItf) (*future, bool) {
+	if p, ok := x.(*XSEXP); ok {
+		if f, ok := p.Pointer.(*future); ok {
+			return f, true
+		}
+	}
+	if x == nil {
+		builtinError(ev, funcname, "argument \"future\" is missing, with no default")
+	} else {
+		builtinError(ev, funcname, "'%s' is not a future", deparseValue(x))
+	}
+	return nil, false
+}
+
+func EvalFuture(ev *Evaluator, node *ast.CallExpr) SEXPItf {
+	if len(node.Args) == 0 {
+		return builtinError(ev, "future", "argument \"expr\" is missing, with no default")
+	}
+	ex := node.Args[0]
+	if tagged, ok := ex.(*ast.TaggedExpr); ok {
+		ex = tagged.Rhs
+	}
+	return newFuture(ev, ex, false)
+}
+
+// x %<-% expr and futureAssign("x", expr)
+func EvalFutureAssign(ev *Evaluator, node *ast.CallExpr, funcname string) SEXPItf {
+	if !arityOK(ev, funcname, 2, node) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	var name string
+	switch target := node.Args[0].(type) {
+	case *ast.Ident:
+		name = target.Name
+	case *ast.BasicLit:
+		if target.Kind == token.IDENT {
+			name = target.Value
+		} else {
+			name = strings.Trim(target.Value, "\"'")
+		}
+	default:
+		return builtinError(ev, funcname, "invalid assignment target")
+	}
+	ev.topFrame.Insert(name, newFuture(ev, node.Args[1], true))
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+// plan(), plan("multicore", workers = 4) or plan(sequential)
+func EvalPlan(ev *Evaluator, node *ast.CallExpr) SEXPItf {
+	old := "sequential"
+	if ev.futures != nil {
+		old = ev.futures.name
+	}
+	if len(node.Args) == 0 {
+		return &TSEXP{ValuePos: node.Fun.Pos(), String: old}
+	}
+	var strategy string
+	workers := defaultCores(ev)
+	for n, arg := range node.Args {
+		switch a := arg.(type) {
+		case *ast.TaggedExpr:
+			v := EvalExpr(ev, a.Rhs)
+			if isError(v) {
+				return v
+			}
+			switch {
+			case a.Tag == "workers" && isAtomic(v) && v.Length() == 1:
+				warn := false
+				workers = int(asFloats(v, &warn)[0])
+			case a.Tag == "strategy" && sexpType(v) == STRSXP:
+				strategy = asStrings(v)[0]
+			default:
+				return builtinError(ev, "plan", "invalid argument '%s'", a.Tag)
+			}
+		case *ast.Ident:
+			if n == 0 {
+				strategy = a.Name
+			}
+		default:
+			v := EvalExpr(ev, arg)
+			if n != 0 || sexpType(v) != STRSXP {
+				return builtinError(ev, "plan", "invalid strategy")
+			}
+			strategy = asStrings(v)[0]
+		}
+	}
+	if workers < 1 {
+		return builtinError(ev, "plan", "'workers' must be >= 1")
+	}
+	switch strategy {
+	case "sequential":
+		ev.futures = sequentialPlan()
+	case "multicore", "multisession", "multiprocess":
+		ev.futures = &futurePlan{name: strategy, executor: &pool{slots: make(chan bool, workers)}}
+	default:
+		return builtinError(ev, "plan", "unknown strategy '%s'", strategy)
+	}
+	ev.Invisible = true
+	return &TSEXP{ValuePos: node.Fun.Pos(), String: old}
+}
+
+// the values of a future or a list of futures
+func EvalValue(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if l, ok := x.(*RSEXP); ok {
+		r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: make([]SEXPItf, len(l.Slice))}
+		for n, e := range l.Slice {
+			f, ok := futureArgument(ev, "value", e)
+			if !ok {
+				return &ESEXP{Kind: token.ILLEGAL}
+			}
+			if r.Slice[n] = futureValue(ev, f); isError(r.Slice[n]) {
+				return r.Slice[n]
+			}
+		}
+		r.NamesSet(l.Names())
+		return r
+	}
+	f, ok := futureArgument(ev, "value", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return futureValue(ev, f)
+}
+
+func EvalResolved(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if args.missing(0) {
+		return builtinError(ev, "resolved", "argument \"x\" is missing, with no default")
+	}
+	elements := []SEXPItf{args.Values[0]}
+	if l, ok := args.Values[0].(*RSEXP); ok {
+		elements = l.Slice
+	}
+	r := make([]int, len(elements))
+	for n, e := range elements {
+		f, ok := futureArgument(ev, "resolved", e)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		r[n] = logical(f.isResolved())
+	}
+	l := &LSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	l.NamesSet(args.Values[0].Names())
+	return l
+}
@@ -0,0 +1,621 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/glm.html
+// Generalized linear models are fitted by iteratively reweighted least squares as R's
+// glm.fit. The families gaussian, binomial and poisson are lists of class "family" with
+// the names of the family and the link; their functions are looked up by these names.
+
+func init() {
+	registerBuiltin("glm", []string{"formula", "family", "data", "weights", "subset", "na.action", "control", "..."}, EvalGlm)
+	registerBuiltin("gaussian", []string{"link"}, EvalGaussian)
+	registerBuiltin("binomial", []string{"link"}, EvalBinomial)
+	registerBuiltin("poisson", []string{"link"}, EvalPoisson)
+	registerPrintMethod("glm", printGlm)
+	registerPrintMethod("summary.glm", printSummaryGlm)
+	registerPrintMethod("family", printFamily)
+}
+
+type glmFamily struct {
+	family string
+	link   string
+}
+
+// the links each family accepts, the first is the default
+var familyLinks = map[string][]string{
+	"gaussian": {"identity", "log", "inverse"},
+	"binomial": {"logit", "probit", "cloglog", "log"},
+	"poisson":  {"log", "identity", "sqrt"},
+}
+
+const (
+	dblEpsilon     = 2.220446049250313e-16
+	logitThreshold = 30.
+)
+
+func (f glmFamily) linkfun(mu float64) float64 {
+	switch f.link {
+	case "log":
+		return math.Log(mu)
+	case "inverse":
+		return 1 / mu
+	case "logit":
+		return math.Log(mu / (1 - mu))
+	case "probit":
+		return numeric.Qnorm(mu, 0, 1, true, false)
+	case "cloglog":
+		return math.Log(-math.Log(1 - mu))
+	case "sqrt":
+		return math.Sqrt(mu)
+	}
+	return mu
+}
+
+func (f glmFamily) linkinv(eta float64) float64 {
+	switch f.link {
+	case "log":
+		return math.Max(math.Exp(eta), dblEpsilon)
+	case "inverse":
+		return 1 / eta
+	case "logit":
+		var t float64
+		switch {
+		case eta < -logitThreshold:
+			t = dblEpsilon
+		case eta > logitThreshold:
+			t = 1 / dblEpsilon
+		default:
+			t = math.Exp(eta)
+		}
+		return t / (1 + t)
+	case "probit":
+		threshold := -numeric.Qnorm(dblEpsilon, 0, 1, true, false)
+		return numeric.Pnorm(math.Min(math.Max(eta, -threshold), threshold), 0, 1, true, false)
+	case "cloglog":
+		return math.Max(math.Min(-math.Expm1(-math.Exp(eta)), 1-dblEpsilon), dblEpsilon)
+	case "sqrt":
+		return eta * eta
+	}
+	return eta
+}
+
+// the derivative of the mean by the linear predictor
+func (f glmFamily) muEta(eta float64) float64 {
+	switch f.link {
+	case "log":
+		return math.Max(math.Exp(eta), dblEpsilon)
+	case "inverse":
+		return -1 / (eta * eta)
+	case "logit":
+		if eta > logitThreshold || eta < -logitThreshold {
+			return dblEpsilon
+		}
+		e := math.Exp(eta)
+		return e / ((1 + e) * (1 + e))
+	case "probit":
+		return math.Max(numeric.Dnorm(eta, 0, 1, false), dblEpsilon)
+	case "cloglog":
+		eta = math.Min(eta, 700)
+		return math.Max(math.Exp(eta)*math.Exp(-math.Exp(eta)), dblEpsilon)
+	case "sqrt":
+		return 2 * eta
+	}
+	return 1
+}
+
+func (f glmFamily) variance(mu float64) float64 {
+	switch f.family {
+	case "binomial":
+		return mu * (1 - mu)
+	case "poisson":
+		return mu
+	}
+	return 1
+}
+
+func yLogY(y float64, mu float64) float64 {
+	if y > 0 {
+		return y * math.Log(y/mu)
+	}
+	return 0
+}
+
+// the contribution of an observation to the deviance
+func (f glmFamily) devResid(y float64, mu float64, wt float64) float64 {
+	switch f.family {
+	case "binomial":
+		return 2 * wt * (yLogY(y, mu) + yLogY(1-y, 1-mu))
+	case "poisson":
+		if y > 0 {
+			return 2 * wt * (y*math.Log(y/mu) - (y - mu))
+		}
+		return 2 * mu * wt
+	}
+	return wt * (y - mu) * (y - mu)
+}
+
+// minus twice the log likelihood, without the parameters
+func (f glmFamily) aic(y []float64, mu []float64, wt []float64, dev float64) float64 {
+	r := 0.
+	switch f.family {
+	case "binomial":
+		for k := range y {
+			if wt[k] > 0 {
+				r -= 2 * numeric.Dbinom(math.Round(wt[k]*y[k]), math.Round(wt[k]), mu[k], true)
+			}
+		}
+	case "poisson":
+		for k := range y {
+			r -= 2 * numeric.Dpois(y[k], mu[k], true) * wt[k]
+		}
+	default:
+		n := float64(len(y))
+		r = n*(math.Log(2*math.Pi*dev/n)+1) + 2
+		for _, w := range wt {
+			r -= math.Log(w)
+		}
+	}
+	return r
+}
+
+func (f glmFamily) mustart(y float64, wt float64) float64 {
+	switch f.family {
+	case "binomial":
+		return (wt*y + 0.5) / (wt + 1)
+	case "poisson":
+		return y + 0.1
+	}
+	return y
+}
+
+// whether the dispersion is fixed to 1
+func (f glmFamily) fixedDispersion() bool {
+	return f.family == "binomial" || f.family == "poisson"
+}
+
+func (f glmFamily) object(pos token.Pos) *RSEXP {
+	return classList(pos, "family", []string{"family", "link"},
+		&TSEXP{ValuePos: pos, String: f.family},
+		&TSEXP{ValuePos: pos, String: f.link})
+}
+
+func familyBuiltin(ev *Evaluator, node *ast.CallExpr, family string, link SEXPItf) SEXPItf {
+	f := glmFamily{family: family, link: familyLinks[family][0]}
+	if link != nil {
+		if sexpType(link) != STRSXP || link.Length() != 1 {
+			return builtinError(ev, family, "link \"%s\" not available for %s family", deparseValue(link), family)
+		}
+		f.link = asStrings(link)[0]
+		if matchName(f.link, familyLinks[family], true) < 0 {
+			return builtinError(ev, family, "link \"%s\" not available for %s family", f.link, family)
+		}
+	}
+	return f.object(node.Fun.Pos())
+}
+
+func EvalGaussian(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return familyBuiltin(ev, node, "gaussian", args.Values[0])
+}
+
+func EvalBinomial(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return familyBuiltin(ev, node, "binomial", args.Values[0])
+}
+
+func EvalPoisson(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return familyBuiltin(ev, node, "poisson", args.Values[0])
+}
+
+// a family given as family object, as function or by its name
+func familyArgument(ev *Evaluator, x SEXPItf) (glmFamily, bool) {
+	var name string
+	switch {
+	case x == nil:
+		return glmFamily{"gaussian", "identity"}, true
+	case sexpType(x) == BUILTINSXP:
+		name = x.(*VSEXP).builtin.name
+	case sexpType(x) == STRSXP && x.Length() == 1:
+		name = asStrings(x)[0]
+	case x.Class() != nil && *x.Class() == "family":
+		return familyOf(x.(*RSEXP)), true
+	}
+	if links, ok := familyLinks[name]; ok {
+		return glmFamily{name, links[0]}, true
+	}
+	builtinError(ev, "glm", "'family' not recognized")
+	return glmFamily{}, false
+}
+
+// the family of a fit or of a family object
+func familyOf(x *RSEXP) glmFamily {
+	if f := listComponent(x, "family"); f != nil && f.Class() != nil && *f.Class() == "family" {
+		x = f.(*RSEXP)
+	}
+	return glmFamily{asStrings(listComponent(x, "family"))[0], asStrings(listComponent(x, "link"))[0]}
+}
+
+type glmFit struct {
+	*leastSquares // the last weighted least squares fit
+	coef          []float64
+	eta           []float64
+	mu            []float64
+	residuals     []float64 // working residuals
+	weights       []float64 // working weights
+	deviance      float64
+	nullDeviance  float64
+	aic           float64
+	iter          int
+	converged     bool
+	dfResidual    int
+	dfNull        int
+}
+
+// iteratively reweighted least squares as glm.fit
+func fitGlm(ev *Evaluator, f glmFamily, x *numeric.Matrix, y []float64, prior []float64, intercept bool, epsilon float64, maxit int) (*glmFit, bool) {
+	n := len(y)
+	for _, v := range y {
+		switch {
+		case f.family == "binomial" && (v < 0 || v > 1):
+			builtinError(ev, "glm", "y values must be 0 <= y <= 1")
+			return nil, false
+		case f.family == "poisson" && v < 0:
+			builtinError(ev, "glm", "negative values not allowed for the 'Poisson' family")
+			return nil, false
+		}
+	}
+	r := &glmFit{eta: make([]float64, n), mu: make([]float64, n)}
+	for k := range y {
+		r.eta[k] = f.linkfun(f.mustart(y[k], prior[k]))
+		r.mu[k] = f.linkinv(r.eta[k])
+	}
+	deviance := func(mu []float64) float64 {
+		d := 0.
+		for k := range y {
+			d += f.devResid(y[k], mu[k], prior[k])
+		}
+		return d
+	}
+	devold := deviance(r.mu)
+	z := make([]float64, n)
+	w := make([]float64, n)
+	for r.iter = 1; r.iter <= maxit; r.iter++ {
+		for k := range y {
+			d := f.muEta(r.eta[k])
+			z[k] = r.eta[k] + (y[k]-r.mu[k])/d
+			w[k] = prior[k] * d * d / f.variance(r.mu[k])
+		}
+		r.leastSquares = fitLeastSquares(x, z, w, math.Min(1e-07, epsilon/1000))
+		r.eta = linearPredictor(x, r.leastSquares.coef)
+		for k := range y {
+			r.mu[k] = f.linkinv(r.eta[k])
+		}
+		r.deviance = deviance(r.mu)
+		if math.Abs(r.deviance-devold)/(math.Abs(r.deviance)+0.1) < epsilon {
+			r.converged = true
+			break
+		}
+		devold = r.deviance
+	}
+	if r.iter > maxit {
+		r.iter = maxit
+	}
+	if !r.converged {
+		ev.warning("", "glm.fit: algorithm did not converge")
+	}
+	for _, mu := range r.mu {
+		if f.family == "binomial" && (mu > 1-10*dblEpsilon || mu < 10*dblEpsilon) {
+			ev.warning("", "glm.fit: fitted probabilities numerically 0 or 1 occurred")
+			break
+		}
+		if f.family == "poisson" && mu < 10*dblEpsilon {
+			ev.warning("", "glm.fit: fitted rates numerically 0 occurred")
+			break
+		}
+	}
+	r.coef = r.leastSquares.coef
+	r.residuals = make([]float64, n)
+	r.weights = w
+	for k := range y {
+		r.residuals[k] = (y[k] - r.mu[k]) / f.muEta(r.eta[k])
+	}
+	wtdmu, sw := f.linkinv(0), 0.
+	if intercept {
+		wtdmu = 0
+		for k := range y {
+			wtdmu += prior[k] * y[k]
+			sw += prior[k]
+		}
+		wtdmu /= sw
+	}
+	nok := 0
+	for k := range y {
+		r.nullDeviance += f.devResid(y[k], wtdmu, prior[k])
+		if prior[k] != 0 {
+			nok++
+		}
+	}
+	r.dfNull, r.dfResidual = nok, nok-r.qr.Rank
+	if intercept {
+		r.dfNull--
+	}
+	r.aic = f.aic(y, r.mu, prior, r.deviance) + 2*float64(r.qr.Rank)
+	return r, true
+}
+
+// epsilon and maxit of a control list
+func glmControl(x SEXPItf) (float64, int) {
+	epsilon, maxit := 1e-8, 25
+	if v := componentFloats(x, "epsilon"); len(v) > 0 {
+		epsilon = v[0]
+	}
+	if v := componentFloats(x, "maxit"); len(v) > 0 {
+		maxit = int(v[0])
+	}
+	return epsilon, maxit
+}
+
+func EvalGlm(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	family, ok := familyArgument(ev, args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	m, ok := newLinearModel(ev, "glm", args.Values[0], args.Values[2], args.Values[4], args.Values[3], args.Values[5])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	prior := m.weights
+	if prior == nil {
+		prior = make([]float64, len(m.y))
+		for k := range prior {
+			prior[k] = 1
+		}
+	}
+	epsilon, maxit := glmControl(args.Values[6])
+	fit, ok := fitGlm(ev, family, m.x, m.y, prior, m.frame.terms.intercept, epsilon, maxit)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	pos := node.Fun.Pos()
+	rownames := m.frame.rownames()
+	return classList(pos, "glm", []string{"coefficients", "residuals", "fitted.values", "effects", "rank", "qr", "family",
+		"linear.predictors", "deviance", "aic", "null.deviance", "iter", "weights", "prior.weights", "df.residual",
+		"df.null", "y", "converged", "assign", "xlevels", "call", "terms", "model"},
+		namedFloats(pos, naCoefficients(fit.coef), m.names),
+		namedFloats(pos, fit.residuals, rownames),
+		namedFloats(pos, fit.mu, rownames),
+		namedFloats(pos, fit.effects, effectNames(fit.qr, m.names)),
+		integerValue(pos, fit.qr.Rank),
+		qrObject(pos, fit.qr, rownames, m.names),
+		family.object(pos),
+		namedFloats(pos, fit.eta, rownames),
+		&VSEXP{ValuePos: pos, Immediate: fit.deviance},
+		&VSEXP{ValuePos: pos, Immediate: fit.aic},
+		&VSEXP{ValuePos: pos, Immediate: fit.nullDeviance},
+		integerValue(pos, fit.iter),
+		namedFloats(pos, fit.weights, rownames),
+		namedFloats(pos, prior, rownames),
+		integerValue(pos, fit.dfResidual),
+		integerValue(pos, fit.dfNull),
+		namedFloats(pos, m.y, rownames),
+		&LSEXP{ValuePos: pos, Immediate: logical(fit.converged)},
+		&ISEXP{ValuePos: pos, Slice: m.assign},
+		xlevelsList(pos, m),
+		matchedCall(node, []string{"formula", "family", "data", "weights", "subset", "na.action", "control", "..."}),
+		m.frame.terms.object(args.Values[0].(*QSEXP)),
+		m.frame.list(pos))
+}
+
+func glmResiduals(ev *Evaluator, object *RSEXP, t string) SEXPItf {
+	working := listComponent(object, "residuals")
+	if t == "working" {
+		return working
+	}
+	f := familyOf(object)
+	y := componentFloats(object, "y")
+	mu := componentFloats(object, "fitted.values")
+	wt := componentFloats(object, "prior.weights")
+	r := make([]float64, len(y))
+	for k := range y {
+		switch t {
+		case "response":
+			r[k] = y[k] - mu[k]
+		case "pearson":
+			r[k] = (y[k] - mu[k]) * math.Sqrt(wt[k]) / math.Sqrt(f.variance(mu[k]))
+		default:
+			r[k] = math.Sqrt(math.Max(f.devResid(y[k], mu[k], wt[k]), 0))
+			if y[k] < mu[k] {
+				r[k] = -r[k]
+			}
+		}
+	}
+	return namedFloats(object.ValuePos, r, working.Names())
+}
+
+// the dispersion is estimated by the Pearson statistic unless it is fixed
+func glmDispersion(object *RSEXP) float64 {
+	if familyOf(object).fixedDispersion() {
+		return 1
+	}
+	df := float64(componentInteger(object, "df.residual"))
+	if df == 0 {
+		return math.NaN()
+	}
+	w := componentFloats(object, "weights")
+	s := 0.
+	for k, r := range componentFloats(object, "residuals") {
+		s += w[k] * r * r
+	}
+	return s / df
+}
+
+func summaryGlm(ev *Evaluator, pos token.Pos, object *RSEXP) SEXPItf {
+	d, cov, ok := unscaledCovariance(ev, "summary.glm", object)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	dispersion := glmDispersion(object)
+	fixed := familyOf(object).fixedDispersion()
+	rdf := componentInteger(object, "df.residual")
+	scaled := numeric.NewMatrix(cov.Rows, cov.Cols)
+	for k, v := range cov.Data {
+		scaled.Data[k] = v * dispersion
+	}
+	return classList(pos, "summary.glm", []string{"call", "terms", "family", "deviance", "aic", "df.residual",
+		"null.deviance", "df.null", "iter", "deviance.resid", "coefficients", "aliased", "dispersion", "df",
+		"cov.unscaled", "cov.scaled"},
+		listComponent(object, "call"),
+		listComponent(object, "terms"),
+		listComponent(object, "family"),
+		listComponent(object, "deviance"),
+		listComponent(object, "aic"),
+		listComponent(object, "df.residual"),
+		listComponent(object, "null.deviance"),
+		listComponent(object, "df.null"),
+		listComponent(object, "iter"),
+		glmResiduals(ev, object, "deviance"),
+		coefficientMatrix(pos, object, d, cov, dispersion, float64(rdf), fixed),
+		aliased(pos, object),
+		&VSEXP{ValuePos: pos, Immediate: dispersion},
+		&ISEXP{ValuePos: pos, Slice: []int{d.Rank, rdf, d.QR.Cols}},
+		matrixResult(pos, cov, nil, nil),
+		matrixResult(pos, scaled, nil, nil))
+}
+
+func printGlm(w io.Writer, x *RSEXP) {
+	printCall(w, x, "  ")
+	fmt.Fprintln(w, "Coefficients:")
+	printCoefficients(w, x)
+	fmt.Fprintf(w, "\nDegrees of Freedom: %d Total (i.e. Null);  %d Residual\n", componentInteger(x, "df.null"), componentInteger(x, "df.residual"))
+	fmt.Fprintf(w, "Null Deviance:\t    %s\n", formatReal([]float64{signif(componentFloats(x, "null.deviance")[0], modelDigits)}, 7)[0])
+	fmt.Fprintf(w, "Residual Deviance: %s \tAIC: %s\n", formatReal([]float64{signif(componentFloats(x, "deviance")[0], modelDigits)}, 7)[0],
+		formatReal([]float64{signif(componentFloats(x, "aic")[0], modelDigits)}, 7)[0])
+}
+
+func printSummaryGlm(w io.Writer, x *RSEXP) {
+	printCall(w, x, "\n")
+	printCoefficientTable(w, x)
+	f := familyOf(x)
+	fmt.Fprintf(w, "\n(Dispersion parameter for %s family taken to be %s)\n\n", f.family, formatReal(componentFloats(x, "dispersion"), 7)[0])
+	deviances := formatReal([]float64{componentFloats(x, "null.deviance")[0], componentFloats(x, "deviance")[0]}, modelDigits+1)
+	dfs := justify([]string{formatInteger(componentInteger(x, "df.null")), formatInteger(componentInteger(x, "df.residual"))}, true)
+	fmt.Fprintf(w, "    Null deviance: %s  on %s  degrees of freedom\n", deviances[0], dfs[0])
+	fmt.Fprintf(w, "Residual deviance: %s  on %s  degrees of freedom\n", deviances[1], dfs[1])
+	fmt.Fprintf(w, "AIC: %s\n\n", formatReal(componentFloats(x, "aic"), modelDigits+1)[0])
+	fmt.Fprintf(w, "Number of Fisher Scoring iterations: %d\n\n", componentInteger(x, "iter"))
+}
+
+func printFamily(w io.Writer, x *RSEXP) {
+	f := familyOf(x)
+	fmt.Fprintf(w, "\nFamily: %s \nLink function: %s \n\n", f.family, f.link)
+}
+
+// the test of an anova table, "" for none, by default Chisq for families with fixed
+// dispersion and F otherwise
+func anovaTest(ev *Evaluator, test SEXPItf, object *RSEXP) (string, bool) {
+	t := "F"
+	if *object.Class() == "glm" && familyOf(object).fixedDispersion() {
+		t = "Chisq"
+	}
+	if test != nil && sexpType(test) == STRSXP && test.Length() == 1 {
+		t = asStrings(test)[0]
+		if t == "LRT" {
+			t = "Chisq"
+		}
+	} else if test != nil && sexpType(test) == LGLSXP && asLogicals(test)[0] == FALSE {
+		t = ""
+	}
+	if t != "" && t != "Chisq" && t != "F" {
+		builtinError(ev, "anova", "test \"%s\" is not supported", t)
+		return "", false
+	}
+	return t, true
+}
+
+// the deviances of the models adding one term after the other, with a chi-squared or F test
+func anovaGlm(ev *Evaluator, pos token.Pos, object *RSEXP, test SEXPItf) SEXPItf {
+	f := familyOf(object)
+	t, ok := anovaTest(ev, test, object)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	terms := listComponent(object, "terms").(*QSEXP)
+	mf := &modelFrame{}
+	var msg string
+	if mf.terms, msg = newModelTerms(terms, nil, false, true); msg != "" {
+		return builtinError(ev, "anova", "%s", msg)
+	}
+	model := listComponent(object, "model").(*RSEXP)
+	mf.columns = model.Slice
+	for k := range componentFloats(object, "y") {
+		mf.rows = append(mf.rows, k)
+	}
+	xlevels := map[string][]string{}
+	if l, ok := listComponent(object, "xlevels").(*RSEXP); ok {
+		for k, name := range l.Names() {
+			xlevels[name] = asStrings(l.Slice[k])
+		}
+	}
+	x, _, assign, ok := mf.design(ev, "anova", xlevels)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	y := componentFloats(object, "y")
+	prior := componentFloats(object, "prior.weights")
+	labels := asStrings(listComponent(terms.Attributes, "term.labels"))
+	rownames := []string{"NULL"}
+	columns := [][]float64{{calc.NA}, {calc.NA}, {float64(componentInteger(object, "df.null"))}, {componentFloats(object, "null.deviance")[0]}}
+	for k := range labels {
+		var cols []int
+		for j, a := range assign {
+			if a <= k+1 {
+				cols = append(cols, j)
+			}
+		}
+		sub := numeric.NewMatrix(x.Rows, len(cols))
+		for j, c := range cols {
+			copy(sub.Col(j), x.Col(c))
+		}
+		fit, ok := fitGlm(ev, f, sub, y, prior, mf.terms.intercept, 1e-8, 25)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		last := len(columns[2]) - 1
+		rownames = append(rownames, labels[k])
+		columns[0] = append(columns[0], columns[2][last]-float64(fit.dfResidual))
+		columns[1] = append(columns[1], columns[3][last]-fit.deviance)
+		columns[2] = append(columns[2], float64(fit.dfResidual))
+		columns[3] = append(columns[3], fit.deviance)
+	}
+	colnames := []string{"Df", "Deviance", "Resid. Df", "Resid. Dev"}
+	dispersion := glmDispersion(object)
+	rdf := float64(componentInteger(object, "df.residual"))
+	switch t {
+	case "Chisq":
+		p := []float64{calc.NA}
+		for k := 1; k < len(rownames); k++ {
+			p = append(p, numeric.Pchisq(columns[1][k]/dispersion, columns[0][k], false, false))
+		}
+		columns = append(columns, p)
+		colnames = append(colnames, "Pr(>Chi)")
+	case "F":
+		fs, p := []float64{calc.NA}, []float64{calc.NA}
+		for k := 1; k < len(rownames); k++ {
+			v := columns[1][k] / columns[0][k] / dispersion
+			fs = append(fs, v)
+			p = append(p, numeric.Pf(v, columns[0][k], rdf, false, false))
+		}
+		columns = append(columns, fs, p)
+		colnames = append(colnames, "F", "Pr(>F)")
+	}
+	lhs, _ := formulaSides(terms)
+	heading := []string{fmt.Sprintf("Analysis of Deviance Table\n\nModel: %s, link: %s\n\nResponse: %s\n\nTerms added sequentially (first to last)\n\n", f.family, f.link, deparse(lhs))}
+	return anovaTable(pos, heading, rownames, colnames, columns)
+}
@@ -0,0 +1,1447 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"sort"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/t.test.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/wilcox.test.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/chisq.test.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/cor.test.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/fisher.test.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/shapiro.test.html
+// Classical tests return lists of class htest with the components of R in the same
+// order, among them the statistic, its parameter, the p-value, a confidence interval
+// with the attribute conf.level and the estimate. They are printed as print.htest does.
+
+var (
+	tTestFormals      = []string{"x", "y", "alternative", "mu", "paired", "var.equal", "conf.level", "..."}
+	wilcoxTestFormals = []string{"x", "y", "alternative", "mu", "paired", "exact", "correct", "conf.int", "conf.level", "tol.root", "digits.rank", "..."}
+	chisqTestFormals  = []string{"x", "y", "correct", "p", "rescale.p"}
+	corTestFormals    = []string{"x", "y", "alternative", "method", "exact", "conf.level", "continuity", "..."}
+	fisherTestFormals = []string{"x", "y", "or", "alternative", "conf.int", "conf.level"}
+)
+
+func init() {
+	registerBuiltin("t.test", tTestFormals, EvalTtest)
+	registerBuiltin("wilcox.test", wilcoxTestFormals, EvalWilcoxTest)
+	registerBuiltin("chisq.test", chisqTestFormals, EvalChisqTest)
+	registerBuiltin("cor.test", corTestFormals, EvalCorTest)
+	registerBuiltin("fisher.test", fisherTestFormals, EvalFisherTest)
+	registerBuiltin("shapiro.test", []string{"x"}, EvalShapiroTest)
+	registerPrintMethod("htest", printHtest)
+}
+
+var alternatives = []string{"two.sided", "less", "greater"}
+
+// the choice matching a prefix of the argument, the first one by default
+func choiceArgument(ev *Evaluator, funcname string, x SEXPItf, choices []string) (string, bool) {
+	if x == nil {
+		return choices[0], true
+	}
+	if sexpType(x) == STRSXP && x.Length() == 1 {
+		if k := matchName(asStrings(x)[0], choices, false); k >= 0 {
+			return choices[k], true
+		}
+	}
+	builtinError(ev, funcname, "'arg' should be one of “%s”", strings.Join(choices, "”, “"))
+	return "", false
+}
+
+func confLevelArgument(ev *Evaluator, funcname string, x SEXPItf) (float64, bool) {
+	if x == nil {
+		return 0.95, true
+	}
+	warn := false
+	v := asFloats(x, &warn)
+	if len(v) != 1 || !isFinite(v[0]) || v[0] < 0 || v[0] > 1 {
+		builtinError(ev, funcname, "'conf.level' must be a single number between 0 and 1")
+		return 0, false
+	}
+	return v[0], true
+}
+
+// the deparsed argument matched to formal, as the data names of R's tests
+func argumentText(node *ast.CallExpr, formals []string, formal string) string {
+	call := matchedCall(node, formals).X.(*ast.CallExpr)
+	for _, a := range call.Args {
+		if t, ok := a.(*ast.TaggedExpr); ok && t.Tag == formal {
+			return deparse(t.Rhs)
+		}
+	}
+	return ""
+}
+
+func isNull(x SEXPItf) bool {
+	return x == nil || sexpType(x) == NILSXP
+}
+
+func nonMissing(x []float64) []float64 {
+	r := []float64{}
+	for _, v := range x {
+		if !math.IsNaN(v) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// the pairs without missing values
+func completePairs(x []float64, y []float64) ([]float64, []float64) {
+	a, b := []float64{}, []float64{}
+	for k := range x {
+		if !math.IsNaN(x[k]) && !math.IsNaN(y[k]) {
+			a = append(a, x[k])
+			b = append(b, y[k])
+		}
+	}
+	return a, b
+}
+
+// the samples of a one or two sample test, the differences for paired samples
+func testSamples(ev *Evaluator, node *ast.CallExpr, funcname string, formals []string, args *Arguments, paired bool) ([]float64, []float64, string, bool) {
+	if !numericArgument(ev, funcname, "x", args.Values[0]) {
+		return nil, nil, "", false
+	}
+	warn := false
+	x := asFloats(args.Values[0], &warn)
+	dname := argumentText(node, formals, "x")
+	if isNull(args.Values[1]) {
+		if paired {
+			builtinError(ev, funcname, "'y' is missing for paired test")
+			return nil, nil, "", false
+		}
+		return nonMissing(x), nil, dname, true
+	}
+	if !numericArgument(ev, funcname, "y", args.Values[1]) {
+		return nil, nil, "", false
+	}
+	y := asFloats(args.Values[1], &warn)
+	dname += " and " + argumentText(node, formals, "y")
+	if !paired {
+		return nonMissing(x), nonMissing(y), dname, true
+	}
+	if len(x) != len(y) {
+		builtinError(ev, funcname, "'x' and 'y' must have the same length")
+		return nil, nil, "", false
+	}
+	x, y = completePairs(x, y)
+	d := make([]float64, len(x))
+	for k := range x {
+		d[k] = x[k] - y[k]
+	}
+	return d, nil, dname, true
+}
+
+// the components of a test result in R's order
+type htestList struct {
+	pos    token.Pos
+	names  []string
+	values []SEXPItf
+}
+
+func (l *htestList) add(name string, value SEXPItf) {
+	l.names = append(l.names, name)
+	l.values = append(l.values, value)
+}
+
+func (l *htestList) named(name string, label string, v float64) {
+	l.add(name, namedFloats(l.pos, []float64{v}, []string{label}))
+}
+
+func (l *htestList) value(name string, v float64) {
+	l.add(name, &VSEXP{ValuePos: l.pos, Immediate: v})
+}
+
+func (l *htestList) text(name string, s string) {
+	l.add(name, &TSEXP{ValuePos: l.pos, String: s})
+}
+
+func (l *htestList) null(name string) {
+	l.add(name, &NSEXP{ValuePos: l.pos})
+}
+
+func (l *htestList) confInt(lower float64, upper float64, level float64) {
+	r := &VSEXP{ValuePos: l.pos, Slice: []float64{lower, upper}}
+	r.Attributes = &RSEXP{ValuePos: l.pos, Slice: []SEXPItf{&VSEXP{ValuePos: l.pos, Immediate: level}}}
+	r.Attributes.NamesSet([]string{"conf.level"})
+	l.add("conf.int", r)
+}
+
+func (l *htestList) result() *RSEXP {
+	return classList(l.pos, "htest", l.names, l.values...)
+}
+
+// the p-value of a statistic with distribution function cdf
+func tailProbability(alternative string, cdf func(lowerTail bool) float64) float64 {
+	switch alternative {
+	case "less":
+		return cdf(true)
+	case "greater":
+		return cdf(false)
+	}
+	return 2 * math.Min(cdf(true), cdf(false))
+}
+
+func EvalTtest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	alternative, ok := choiceArgument(ev, "t.test", args.Values[2], alternatives)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	mu := args.float(3, 0)
+	if args.Values[3] != nil && (args.Values[3].Length() != 1 || math.IsNaN(mu)) {
+		return builtinError(ev, "t.test", "'mu' must be a single number")
+	}
+	paired := args.logical(4, false)
+	varEqual := args.logical(5, false)
+	level, ok := confLevelArgument(ev, "t.test", args.Values[6])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	x, y, dname, ok := testSamples(ev, node, "t.test", tTestFormals, args, paired)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	nx := float64(len(x))
+	mx := calc.Mean(x)
+	vx := covariance(x, x)
+	var df, stderr, tstat float64
+	var method string
+	var estimate []float64
+	var estimateNames []string
+	if y == nil {
+		if nx < 2 {
+			return builtinError(ev, "t.test", "not enough 'x' observations")
+		}
+		df = nx - 1
+		stderr = math.Sqrt(vx / nx)
+		if stderr < 10*2.220446049250313e-16*math.Abs(mx) {
+			return builtinError(ev, "t.test", "data are essentially constant")
+		}
+		tstat = (mx - mu) / stderr
+		method = "One Sample t-test"
+		estimate, estimateNames = []float64{mx}, []string{"mean of x"}
+		if paired {
+			method = "Paired t-test"
+			estimateNames = []string{"mean difference"}
+		}
+	} else {
+		ny := float64(len(y))
+		switch {
+		case nx < 1 || (!varEqual && nx < 2):
+			return builtinError(ev, "t.test", "not enough 'x' observations")
+		case ny < 1 || (!varEqual && ny < 2):
+			return builtinError(ev, "t.test", "not enough 'y' observations")
+		case varEqual && nx+ny < 3:
+			return builtinError(ev, "t.test", "not enough observations")
+		}
+		my := calc.Mean(y)
+		vy := covariance(y, y)
+		method = "Welch Two Sample t-test"
+		estimate, estimateNames = []float64{mx, my}, []string{"mean of x", "mean of y"}
+		if varEqual {
+			method = " Two Sample t-test"
+			df = nx + ny - 2
+			v := 0.
+			if nx > 1 {
+				v += (nx - 1) * vx
+			}
+			if ny > 1 {
+				v += (ny - 1) * vy
+			}
+			v /= df
+			stderr = math.Sqrt(v * (1/nx + 1/ny))
+		} else {
+			stderrx := math.Sqrt(vx / nx)
+			stderry := math.Sqrt(vy / ny)
+			stderr = math.Sqrt(stderrx*stderrx + stderry*stderry)
+			df = math.Pow(stderr, 4) / (math.Pow(stderrx, 4)/(nx-1) + math.Pow(stderry, 4)/(ny-1))
+		}
+		if stderr < 10*2.220446049250313e-16*math.Max(math.Abs(mx), math.Abs(my)) {
+			return builtinError(ev, "t.test", "data are essentially constant")
+		}
+		tstat = (mx - my - mu) / stderr
+	}
+	var pval, lower, upper float64
+	switch alternative {
+	case "less":
+		pval = numeric.Pt(tstat, df, true, false)
+		lower, upper = math.Inf(-1), tstat+numeric.Qt(level, df, true, false)
+	case "greater":
+		pval = numeric.Pt(tstat, df, false, false)
+		lower, upper = tstat-numeric.Qt(level, df, true, false), math.Inf(1)
+	default:
+		pval = 2 * numeric.Pt(-math.Abs(tstat), df, true, false)
+		q := numeric.Qt(1-(1-level)/2, df, true, false)
+		lower, upper = tstat-q, tstat+q
+	}
+	nullName := "mean"
+	switch {
+	case paired:
+		nullName = "mean difference"
+	case y != nil:
+		nullName = "difference in means"
+	}
+	l := &htestList{pos: pos}
+	l.named("statistic", "t", tstat)
+	l.named("parameter", "df", df)
+	l.value("p.value", pval)
+	l.confInt(mu+lower*stderr, mu+upper*stderr, level)
+	l.add("estimate", namedFloats(pos, estimate, estimateNames))
+	l.named("null.value", nullName, mu)
+	l.value("stderr", stderr)
+	l.text("alternative", alternative)
+	l.text("method", method)
+	l.text("data.name", dname)
+	return l.result()
+}
+
+// the sum of t^3 - t over the groups of t equal values
+func tieCorrection(r []float64) float64 {
+	sorted := append([]float64{}, r...)
+	sort.Float64s(sorted)
+	s := 0.
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+		t := float64(j - i)
+		s += t*t*t - t
+		i = j
+	}
+	return s
+}
+
+// average ranks of the values rounded to digits significant digits, if finite
+func signifRanks(x []float64, digits float64) []float64 {
+	if isFinite(digits) {
+		rounded := make([]float64, len(x))
+		for k, v := range x {
+			rounded[k] = signif(v, int(digits))
+		}
+		x = rounded
+	}
+	return averageRanks(x)
+}
+
+// the continuity correction of a normal approximation
+func continuityCorrection(alternative string, z float64) float64 {
+	switch alternative {
+	case "greater":
+		return 0.5
+	case "less":
+		return -0.5
+	}
+	return calc.Sign(z) * 0.5
+}
+
+// uniroot(f, c(lower, upper), tol = tol)$root with the values of f at the bounds
+func rootOf(f func(float64) float64, lower float64, upper float64, flower float64, fupper float64, tol float64) float64 {
+	root, _, _ := numeric.Zeroin(lower, upper, flower, fupper, f, tol, 1000)
+	return root
+}
+
+func EvalWilcoxTest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	alternative, ok := choiceArgument(ev, "wilcox.test", args.Values[2], alternatives)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	mu := args.float(3, 0)
+	if args.Values[3] != nil && (args.Values[3].Length() != 1 || !isFinite(mu)) {
+		return builtinError(ev, "wilcox.test", "'mu' must be a single number")
+	}
+	paired := args.logical(4, false)
+	correct := args.logical(6, true)
+	confInt := args.logical(7, false)
+	level, ok := confLevelArgument(ev, "wilcox.test", args.Values[8])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	tolRoot := args.float(9, 1e-4)
+	digitsRank := args.float(10, math.Inf(1))
+	x, y, dname, ok := testSamples(ev, node, "wilcox.test", wilcoxTestFormals, args, paired)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(x) < 1 {
+		return builtinError(ev, "wilcox.test", "not enough (non-missing) 'x' observations")
+	}
+	exactArg := !isNull(args.Values[5])
+	exact := args.logical(5, false)
+	alpha := 1 - level
+	var stat, pval float64
+	var method, statName, estimateName string
+	var lower, upper, estimate float64
+	if y == nil {
+		method, statName, estimateName = "Wilcoxon signed rank test", "V", "(pseudo)median"
+		d := []float64{}
+		zeroes := false
+		for _, v := range x {
+			if v-mu == 0 {
+				zeroes = true
+			} else {
+				d = append(d, v-mu)
+			}
+		}
+		n := float64(len(d))
+		if !exactArg {
+			exact = n < 50
+		}
+		abs := make([]float64, len(d))
+		for k, v := range d {
+			abs[k] = math.Abs(v)
+		}
+		r := signifRanks(abs, digitsRank)
+		for k, v := range d {
+			if v > 0 {
+				stat += r[k]
+			}
+		}
+		ties := tieCorrection(r) > 0
+		// the observations without zeroes
+		for k := range d {
+			d[k] += mu
+		}
+		if exact && !ties && !zeroes {
+			method = "Wilcoxon signed rank exact test"
+			pval = tailProbability(alternative, func(lowerTail bool) float64 {
+				if lowerTail {
+					return numeric.Psignrank(stat, n, true, false)
+				}
+				return numeric.Psignrank(stat-1, n, false, false)
+			})
+			if alternative == "two.sided" {
+				pval = math.Min(pval, 1)
+			}
+			if confInt {
+				// the Walsh averages
+				diffs := []float64{}
+				for i := range d {
+					for j := i; j < len(d); j++ {
+						diffs = append(diffs, (d[i]+d[j])/2)
+					}
+				}
+				sort.Float64s(diffs)
+				var achieved float64
+				a := alpha
+				if alternative == "two.sided" {
+					a = alpha / 2
+				}
+				qu := numeric.Qsignrank(a, n, true, false)
+				if qu == 0 {
+					qu = 1
+				}
+				ql := n*(n+1)/2 - qu
+				achieved = numeric.Psignrank(math.Trunc(qu)-1, n, true, false)
+				switch alternative {
+				case "two.sided":
+					achieved *= 2
+					lower, upper = diffs[int(qu)-1], diffs[int(ql)]
+				case "greater":
+					lower, upper = diffs[int(qu)-1], math.Inf(1)
+				default:
+					lower, upper = math.Inf(-1), diffs[int(ql)]
+				}
+				if achieved-alpha > alpha/2 {
+					ev.warning("wilcox.test()", "requested conf.level not achievable")
+					level = 1 - signif(achieved, 2)
+				}
+				estimate = medianFloats(diffs)
+			}
+		} else {
+			z := stat - n*(n+1)/4
+			sigma := math.Sqrt(n*(n+1)*(2*n+1)/24 - tieCorrection(r)/48)
+			if correct {
+				z -= continuityCorrection(alternative, z)
+				method += " with continuity correction"
+			}
+			z /= sigma
+			pval = tailProbability(alternative, func(lowerTail bool) float64 { return numeric.Pnorm(z, 0, 1, lowerTail, false) })
+			if confInt {
+				failed := false
+				w := func(mu float64, correct bool) float64 {
+					xd := []float64{}
+					for _, v := range d {
+						if v-mu != 0 {
+							xd = append(xd, v-mu)
+						}
+					}
+					nx := float64(len(xd))
+					abs := make([]float64, len(xd))
+					for k, v := range xd {
+						abs[k] = math.Abs(v)
+					}
+					dr := signifRanks(abs, digitsRank)
+					zd := -nx * (nx + 1) / 4
+					for k, v := range xd {
+						if v > 0 {
+							zd += dr[k]
+						}
+					}
+					sigma := math.Sqrt(nx*(nx+1)*(2*nx+1)/24 - tieCorrection(dr)/48)
+					if sigma == 0 {
+						failed = true
+						return math.NaN()
+					}
+					if correct {
+						zd -= continuityCorrection(alternative, zd)
+					}
+					return zd / sigma
+				}
+				mumin, mumax := d[0], d[0]
+				for _, v := range d {
+					mumin, mumax = math.Min(mumin, v), math.Max(mumax, v)
+				}
+				wmumin, wmumax := w(mumin, correct), w(mumax, correct)
+				if failed {
+					return builtinError(ev, "wilcox.test", "cannot compute confidence interval when all observations are zero or tied")
+				}
+				root := func(zq float64) float64 {
+					return rootOf(func(mu float64) float64 { return w(mu, correct) - zq }, mumin, mumax, wmumin-zq, wmumax-zq, tolRoot)
+				}
+				a := alpha
+				for {
+					mindiff := wmumin - numeric.Qnorm(a, 0, 1, false, false)
+					maxdiff := wmumax - numeric.Qnorm(a, 0, 1, true, false)
+					switch alternative {
+					case "two.sided":
+						mindiff = wmumin - numeric.Qnorm(a/2, 0, 1, false, false)
+						maxdiff = wmumax - numeric.Qnorm(a/2, 0, 1, true, false)
+					case "greater":
+						maxdiff = 0
+					case "less":
+						mindiff = 0
+					}
+					if mindiff < 0 || maxdiff > 0 {
+						a *= 2
+					} else {
+						break
+					}
+				}
+				if a >= 1 || 1-level < a*0.75 {
+					level = 1 - math.Min(1, a)
+					ev.warning("wilcox.test()", "requested conf.level not achievable")
+				}
+				median := medianFloats(d)
+				switch alternative {
+				case "two.sided":
+					lower, upper = median, median
+					if a < 1 {
+						lower = root(numeric.Qnorm(a/2, 0, 1, false, false))
+						upper = root(numeric.Qnorm(a/2, 0, 1, true, false))
+					}
+				case "greater":
+					lower, upper = median, math.Inf(1)
+					if a < 1 {
+						lower = root(numeric.Qnorm(a, 0, 1, false, false))
+					}
+				default:
+					lower, upper = math.Inf(-1), median
+					if a < 1 {
+						upper = root(numeric.Qnorm(a, 0, 1, true, false))
+					}
+				}
+				// the estimate without continuity correction
+				estimate = rootOf(func(mu float64) float64 { return w(mu, false) }, mumin, mumax, w(mumin, false), w(mumax, false), tolRoot)
+			}
+			if exact && ties {
+				ev.warning("wilcox.test()", "cannot compute exact p-value with ties")
+				if confInt {
+					ev.warning("wilcox.test()", "cannot compute exact confidence interval with ties")
+				}
+			}
+			if exact && zeroes {
+				ev.warning("wilcox.test()", "cannot compute exact p-value with zeroes")
+				if confInt {
+					ev.warning("wilcox.test()", "cannot compute exact confidence interval with zeroes")
+				}
+			}
+		}
+	} else {
+		if len(y) < 1 {
+			return builtinError(ev, "wilcox.test", "not enough 'y' observations")
+		}
+		method, statName, estimateName = "Wilcoxon rank sum test", "W", "difference in location"
+		nx, ny := float64(len(x)), float64(len(y))
+		if !exactArg {
+			exact = nx < 50 && ny < 50
+		}
+		pooled := make([]float64, 0, len(x)+len(y))
+		for _, v := range x {
+			pooled = append(pooled, v-mu)
+		}
+		r := signifRanks(append(pooled, y...), digitsRank)
+		stat = -nx * (nx + 1) / 2
+		for k := range x {
+			stat += r[k]
+		}
+		ties := tieCorrection(r) > 0
+		if exact && !ties {
+			method = "Wilcoxon rank sum exact test"
+			pval = tailProbability(alternative, func(lowerTail bool) float64 {
+				if lowerTail {
+					return numeric.Pwilcox(stat, nx, ny, true, false)
+				}
+				return numeric.Pwilcox(stat-1, nx, ny, false, false)
+			})
+			if alternative == "two.sided" {
+				pval = math.Min(pval, 1)
+			}
+			if confInt {
+				diffs := []float64{}
+				for _, a := range x {
+					for _, b := range y {
+						diffs = append(diffs, a-b)
+					}
+				}
+				sort.Float64s(diffs)
+				a := alpha
+				if alternative == "two.sided" {
+					a = alpha / 2
+				}
+				qu := numeric.Qwilcox(a, nx, ny, true, false)
+				if qu == 0 {
+					qu = 1
+				}
+				ql := nx*ny - qu
+				achieved := numeric.Pwilcox(math.Trunc(qu)-1, nx, ny, true, false)
+				switch alternative {
+				case "two.sided":
+					achieved *= 2
+					lower, upper = diffs[int(qu)-1], diffs[int(ql)]
+				case "greater":
+					lower, upper = diffs[int(qu)-1], math.Inf(1)
+				default:
+					lower, upper = math.Inf(-1), diffs[int(ql)]
+				}
+				if achieved-alpha > alpha/2 {
+					ev.warning("wilcox.test()", "requested conf.level not achievable")
+					level = 1 - signif(achieved, 2)
+				}
+				estimate = medianFloats(diffs)
+			}
+		} else {
+			z := stat - nx*ny/2
+			sigma := math.Sqrt((nx * ny / 12) * ((nx + ny + 1) - tieCorrection(r)/((nx+ny)*(nx+ny-1))))
+			if correct {
+				z -= continuityCorrection(alternative, z)
+				method += " with continuity correction"
+			}
+			z /= sigma
+			pval = tailProbability(alternative, func(lowerTail bool) float64 { return numeric.Pnorm(z, 0, 1, lowerTail, false) })
+			if confInt {
+				failed := false
+				w := func(mu float64, correct bool) float64 {
+					dr := make([]float64, 0, len(x)+len(y))
+					for _, v := range x {
+						dr = append(dr, v-mu)
+					}
+					dr = signifRanks(append(dr, y...), digitsRank)
+					dz := -nx*(nx+1)/2 - nx*ny/2
+					for k := range x {
+						dz += dr[k]
+					}
+					sigma := math.Sqrt((nx * ny / 12) * ((nx + ny + 1) - tieCorrection(dr)/((nx+ny)*(nx+ny-1))))
+					if sigma == 0 {
+						failed = true
+						return math.NaN()
+					}
+					if correct {
+						dz -= continuityCorrection(alternative, dz)
+					}
+					return dz / sigma
+				}
+				mumin, mumax := math.Inf(1), math.Inf(-1)
+				for _, a := range x {
+					for _, b := range y {
+						mumin, mumax = math.Min(mumin, a-b), math.Max(mumax, a-b)
+					}
+				}
+				wmumin, wmumax := w(mumin, correct), w(mumax, correct)
+				if failed {
+					return builtinError(ev, "wilcox.test", "cannot compute confidence interval when all observations are tied")
+				}
+				// the bounds themselves in extreme cases
+				root := func(zq float64) float64 {
+					switch {
+					case wmumin-zq <= 0:
+						return mumin
+					case wmumax-zq >= 0:
+						return mumax
+					}
+					return rootOf(func(mu float64) float64 { return w(mu, correct) - zq }, mumin, mumax, wmumin-zq, wmumax-zq, tolRoot)
+				}
+				switch alternative {
+				case "two.sided":
+					lower = root(numeric.Qnorm(alpha/2, 0, 1, false, false))
+					upper = root(numeric.Qnorm(alpha/2, 0, 1, true, false))
+				case "greater":
+					lower, upper = root(numeric.Qnorm(alpha, 0, 1, false, false)), math.Inf(1)
+				default:
+					lower, upper = math.Inf(-1), root(numeric.Qnorm(alpha, 0, 1, true, false))
+				}
+				estimate = rootOf(func(mu float64) float64 { return w(mu, false) }, mumin, mumax, w(mumin, false), w(mumax, false), tolRoot)
+			}
+			if exact && ties {
+				ev.warning("wilcox.test()", "cannot compute exact p-value with ties")
+				if confInt {
+					ev.warning("wilcox.test()", "cannot compute exact confidence intervals with ties")
+				}
+			}
+		}
+	}
+	nullName := "location"
+	if paired || y != nil {
+		nullName = "location shift"
+	}
+	l := &htestList{pos: pos}
+	l.named("statistic", statName, stat)
+	l.null("parameter")
+	l.value("p.value", pval)
+	l.named("null.value", nullName, mu)
+	l.text("alternative", alternative)
+	l.text("method", method)
+	l.text("data.name", dname)
+	if confInt {
+		l.confInt(lower, upper, level)
+		l.named("estimate", estimateName, estimate)
+	}
+	return l.result()
+}
+
+// the sorted distinct values of a variable as labels, and the index of the level of each value
+func variableLevels(x SEXPItf) ([]string, []int) {
+	codes := make([]int, x.Length())
+	if levels := factorLevels(x); levels != nil {
+		for k, s := range asStrings(x) {
+			codes[k] = matchName(s, levels, true)
+		}
+		return levels, codes
+	}
+	warn := false
+	values := asFloats(x, &warn)
+	distinct := []float64{}
+	for _, v := range values {
+		if !math.IsNaN(v) {
+			distinct = append(distinct, v)
+		}
+	}
+	sort.Float64s(distinct)
+	levels := []string{}
+	var last float64
+	for k, v := range distinct {
+		if k == 0 || v != last {
+			levels = append(levels, formatFloat(v))
+		}
+		last = v
+	}
+	for k, v := range values {
+		codes[k] = -1
+		if !math.IsNaN(v) {
+			codes[k] = matchName(formatFloat(v), levels, true)
+		}
+	}
+	return levels, codes
+}
+
+// the counts of a contingency table given as matrix, or of two variables
+func contingencyTable(ev *Evaluator, node *ast.CallExpr, funcname string, formals []string, x SEXPItf, y SEXPItf) (*numeric.Matrix, []string, []string, string, bool) {
+	dname := argumentText(node, formals, "x")
+	if dim := x.Dim(); len(dim) == 2 {
+		warn := false
+		return &numeric.Matrix{Rows: dim[0], Cols: dim[1], Data: asFloats(x, &warn)}, dimnamesAt(x, 0), dimnamesAt(x, 1), dname, true
+	}
+	if isNull(y) {
+		builtinError(ev, funcname, "if 'x' is not a matrix, 'y' must be given")
+		return nil, nil, nil, "", false
+	}
+	if x.Length() != y.Length() {
+		builtinError(ev, funcname, "'x' and 'y' must have the same length")
+		return nil, nil, nil, "", false
+	}
+	rows, xcodes := variableLevels(x)
+	cols, ycodes := variableLevels(y)
+	if len(rows) < 2 || len(cols) < 2 {
+		builtinError(ev, funcname, "'x' and 'y' must have at least 2 levels")
+		return nil, nil, nil, "", false
+	}
+	m := numeric.NewMatrix(len(rows), len(cols))
+	for k := range xcodes {
+		if xcodes[k] >= 0 && ycodes[k] >= 0 {
+			m.Set(xcodes[k], ycodes[k], m.At(xcodes[k], ycodes[k])+1)
+		}
+	}
+	return m, rows, cols, dname + " and " + argumentText(node, formals, "y"), true
+}
+
+func EvalChisqTest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	x := args.Values[0]
+	// character variables are only counted in a table
+	if (x == nil || isNull(args.Values[1])) && !numericArgument(ev, "chisq.test", "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	correct := args.logical(2, true)
+	var observed *numeric.Matrix
+	var rownames, colnames []string
+	var dname string
+	matrix := false
+	if dim := x.Dim(); len(dim) == 2 && dim[0] > 1 && dim[1] > 1 || !isNull(args.Values[1]) && len(dim) != 2 {
+		var ok bool
+		observed, rownames, colnames, dname, ok = contingencyTable(ev, node, "chisq.test", chisqTestFormals, x, args.Values[1])
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		matrix = true
+	} else {
+		warn := false
+		v := asFloats(x, &warn)
+		observed = &numeric.Matrix{Rows: len(v), Cols: 1, Data: v}
+		rownames = x.Names()
+		dname = argumentText(node, chisqTestFormals, "x")
+	}
+	n := 0.
+	for _, v := range observed.Data {
+		if math.IsNaN(v) || v < 0 {
+			return builtinError(ev, "chisq.test", "all entries of 'x' must be nonnegative and finite")
+		}
+		n += v
+	}
+	if n == 0 {
+		return builtinError(ev, "chisq.test", "at least one entry of 'x' must be positive")
+	}
+	var method string
+	var stat, df float64
+	expected := numeric.NewMatrix(observed.Rows, observed.Cols)
+	variance := numeric.NewMatrix(observed.Rows, observed.Cols)
+	if matrix {
+		method = "Pearson's Chi-squared test"
+		nr, nc := observed.Rows, observed.Cols
+		sr, sc := make([]float64, nr), make([]float64, nc)
+		for i := 0; i < nr; i++ {
+			for j := 0; j < nc; j++ {
+				sr[i] += observed.At(i, j)
+				sc[j] += observed.At(i, j)
+			}
+		}
+		for i := 0; i < nr; i++ {
+			for j := 0; j < nc; j++ {
+				expected.Set(i, j, sr[i]*sc[j]/n)
+				variance.Set(i, j, sc[j]*sr[i]*(n-sr[i])*(n-sc[j])/(n*n*n))
+			}
+		}
+		yates := 0.
+		if correct && nr == 2 && nc == 2 {
+			yates = 0.5
+			for k := range observed.Data {
+				yates = math.Min(yates, math.Abs(observed.Data[k]-expected.Data[k]))
+			}
+			if yates > 0 {
+				method += " with Yates' continuity correction"
+			}
+		}
+		for k := range observed.Data {
+			d := math.Abs(observed.Data[k]-expected.Data[k]) - yates
+			stat += d * d / expected.Data[k]
+		}
+		df = float64((nr - 1) * (nc - 1))
+	} else {
+		k := observed.Rows
+		if k == 1 {
+			return builtinError(ev, "chisq.test", "'x' must at least have 2 elements")
+		}
+		p := make([]float64, k)
+		for i := range p {
+			p[i] = 1 / float64(k)
+		}
+		if v := args.Values[3]; v != nil {
+			warn := false
+			p = asFloats(v, &warn)
+		}
+		if len(p) != k {
+			return builtinError(ev, "chisq.test", "'x' and 'p' must have the same number of elements")
+		}
+		sum := 0.
+		for _, v := range p {
+			if v < 0 {
+				return builtinError(ev, "chisq.test", "probabilities must be non-negative.")
+			}
+			sum += v
+		}
+		if math.Abs(sum-1) > math.Sqrt(2.220446049250313e-16) {
+			if !args.logical(4, false) {
+				return builtinError(ev, "chisq.test", "probabilities must sum to 1.")
+			}
+			for i := range p {
+				p[i] /= sum
+			}
+		}
+		method = "Chi-squared test for given probabilities"
+		for i, v := range observed.Data {
+			expected.Data[i] = n * p[i]
+			variance.Data[i] = n * p[i] * (1 - p[i])
+			stat += (v - expected.Data[i]) * (v - expected.Data[i]) / expected.Data[i]
+		}
+		df = float64(k - 1)
+	}
+	small := false
+	residuals := numeric.NewMatrix(observed.Rows, observed.Cols)
+	stdres := numeric.NewMatrix(observed.Rows, observed.Cols)
+	for k, e := range expected.Data {
+		small = small || e < 5
+		residuals.Data[k] = (observed.Data[k] - e) / math.Sqrt(e)
+		stdres.Data[k] = (observed.Data[k] - e) / math.Sqrt(variance.Data[k])
+	}
+	if small {
+		ev.warning("chisq.test()", "Chi-squared approximation may be incorrect")
+	}
+	shaped := func(m *numeric.Matrix) SEXPItf {
+		if matrix {
+			return matrixResult(pos, m, rownames, colnames)
+		}
+		return namedFloats(pos, m.Data, rownames)
+	}
+	l := &htestList{pos: pos}
+	l.named("statistic", "X-squared", stat)
+	l.named("parameter", "df", df)
+	l.value("p.value", numeric.Pchisq(stat, df, false, false))
+	l.text("method", method)
+	l.text("data.name", dname)
+	if x.Dim() != nil || !matrix {
+		l.add("observed", x)
+	} else {
+		l.add("observed", matrixResult(pos, observed, rownames, colnames))
+	}
+	l.add("expected", shaped(expected))
+	l.add("residuals", shaped(residuals))
+	l.add("stdres", shaped(stdres))
+	return l.result()
+}
+
+// the sizes of the groups of equal values
+func tieGroups(x []float64) []float64 {
+	sorted := append([]float64{}, x...)
+	sort.Float64s(sorted)
+	r := []float64{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j < len(sorted) && sorted[j] == sorted[i] {
+			j++
+		}
+		if j-i > 1 {
+			r = append(r, float64(j-i))
+		}
+		i = j
+	}
+	return r
+}
+
+func EvalCorTest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	alternative, ok := choiceArgument(ev, "cor.test", args.Values[2], alternatives)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	method, ok := choiceArgument(ev, "cor.test", args.Values[3], []string{"pearson", "kendall", "spearman"})
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	for k, formal := range []string{"x", "y"} {
+		if args.Values[k] == nil {
+			return builtinError(ev, "cor.test", "argument \"%s\" is missing, with no default", formal)
+		}
+		switch sexpType(args.Values[k]) {
+		case LGLSXP, INTSXP, REALSXP:
+		default:
+			return builtinError(ev, "cor.test", "'%s' must be a numeric vector", formal)
+		}
+	}
+	warn := false
+	x, y := asFloats(args.Values[0], &warn), asFloats(args.Values[1], &warn)
+	if len(x) != len(y) {
+		return builtinError(ev, "cor.test", "'x' and 'y' must have the same length")
+	}
+	dname := argumentText(node, corTestFormals, "x") + " and " + argumentText(node, corTestFormals, "y")
+	x, y = completePairs(x, y)
+	n := float64(len(x))
+	exactArg := !isNull(args.Values[4])
+	exact := args.logical(4, false)
+	l := &htestList{pos: pos}
+	if method == "pearson" {
+		if n < 3 {
+			return builtinError(ev, "cor.test", "not enough finite observations")
+		}
+		level, ok := confLevelArgument(ev, "cor.test", args.Values[5])
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		r := correlation(x, y)
+		df := n - 2
+		stat := math.Sqrt(df) * r / math.Sqrt(1-r*r)
+		l.named("statistic", "t", stat)
+		l.named("parameter", "df", df)
+		l.value("p.value", tailProbability(alternative, func(lowerTail bool) float64 { return numeric.Pt(stat, df, lowerTail, false) }))
+		l.named("estimate", "cor", r)
+		l.named("null.value", "correlation", 0)
+		l.text("alternative", alternative)
+		l.text("method", "Pearson's product-moment correlation")
+		l.text("data.name", dname)
+		if n > 3 {
+			z := math.Atanh(r)
+			sigma := 1 / math.Sqrt(n-3)
+			var lower, upper float64
+			switch alternative {
+			case "less":
+				lower, upper = math.Inf(-1), z+sigma*numeric.Qnorm(level, 0, 1, true, false)
+			case "greater":
+				lower, upper = z-sigma*numeric.Qnorm(level, 0, 1, true, false), math.Inf(1)
+			default:
+				q := sigma * numeric.Qnorm((1+level)/2, 0, 1, true, false)
+				lower, upper = z-q, z+q
+			}
+			l.confInt(math.Tanh(lower), math.Tanh(upper), level)
+		}
+		return l.result()
+	}
+	if n < 2 {
+		return builtinError(ev, "cor.test", "not enough finite observations")
+	}
+	xties, yties := tieGroups(x), tieGroups(y)
+	ties := len(xties) > 0 || len(yties) > 0
+	var statName, estimateName, nullName, title string
+	var stat, r, pval float64
+	if method == "kendall" {
+		statName, estimateName, nullName, title = "T", "tau", "tau", "Kendall's rank correlation tau"
+		r = kendall(x, y)
+		if !exactArg {
+			exact = n < 50
+		}
+		switch {
+		case !isFinite(r):
+			r, stat, pval = calc.NA, calc.NA, calc.NA
+		case exact && !ties:
+			stat = math.Round((r + 1) * n * (n - 1) / 4)
+			pkendall := func(q float64) float64 { return numeric.Pkendall(q, len(x)) }
+			switch alternative {
+			case "two.sided":
+				p := pkendall(stat)
+				if stat > n*(n-1)/4 {
+					p = 1 - pkendall(stat-1)
+				}
+				pval = math.Min(2*p, 1)
+			case "greater":
+				pval = 1 - pkendall(stat-1)
+			default:
+				pval = pkendall(stat)
+			}
+		default:
+			sum := func(t []float64, f func(float64) float64) float64 {
+				s := 0.
+				for _, v := range t {
+					s += f(v)
+				}
+				return s
+			}
+			t0 := n * (n - 1) / 2
+			t1 := sum(xties, func(t float64) float64 { return t * (t - 1) }) / 2
+			t2 := sum(yties, func(t float64) float64 { return t * (t - 1) }) / 2
+			s := r * math.Sqrt((t0-t1)*(t0-t2))
+			v0 := n * (n - 1) * (2*n + 5)
+			vt := sum(xties, func(t float64) float64 { return t * (t - 1) * (2*t + 5) })
+			vu := sum(yties, func(t float64) float64 { return t * (t - 1) * (2*t + 5) })
+			v1 := sum(xties, func(t float64) float64 { return t * (t - 1) }) * sum(yties, func(t float64) float64 { return t * (t - 1) })
+			v2 := sum(xties, func(t float64) float64 { return t * (t - 1) * (t - 2) }) * sum(yties, func(t float64) float64 { return t * (t - 1) * (t - 2) })
+			varS := (v0-vt-vu)/18 + v1/(2*n*(n-1)) + v2/(9*n*(n-1)*(n-2))
+			if exact && ties {
+				ev.warning("cor.test()", "Cannot compute exact p-value with ties")
+			}
+			if args.logical(6, false) {
+				s = calc.Sign(s) * (math.Abs(s) - 1)
+			}
+			statName = "z"
+			stat = s / math.Sqrt(varS)
+			pval = tailProbability(alternative, func(lowerTail bool) float64 { return numeric.Pnorm(stat, 0, 1, lowerTail, false) })
+		}
+	} else {
+		statName, estimateName, nullName, title = "S", "rho", "rho", "Spearman's rank correlation rho"
+		r = correlation(averageRanks(x), averageRanks(y))
+		if !exactArg {
+			exact = true
+		}
+		if !isFinite(r) {
+			r, stat, pval = calc.NA, calc.NA, calc.NA
+		} else {
+			pspearman := func(q float64, lowerTail bool) float64 {
+				if n <= 1290 && exact {
+					is := math.Round(q)
+					if lowerTail {
+						is += 2
+					}
+					return numeric.Prho(is, len(x), lowerTail)
+				}
+				// the asymptotic t distribution
+				den := n * (n*n - 1) / 6
+				r := 1 - q/den
+				return numeric.Pt(r/math.Sqrt((1-r*r)/(n-2)), n-2, !lowerTail, false)
+			}
+			stat = (n*n*n - n) * (1 - r) / 6
+			if ties && exact {
+				exact = false
+				ev.warning("cor.test()", "Cannot compute exact p-value with ties")
+			}
+			switch alternative {
+			case "two.sided":
+				pval = math.Min(2*pspearman(stat, stat <= (n*n*n-n)/6), 1)
+			case "greater":
+				pval = pspearman(stat, true)
+			default:
+				pval = pspearman(stat, false)
+			}
+		}
+	}
+	l.named("statistic", statName, stat)
+	l.null("parameter")
+	l.value("p.value", pval)
+	l.named("estimate", estimateName, r)
+	l.named("null.value", nullName, 0)
+	l.text("alternative", alternative)
+	l.text("method", title)
+	l.text("data.name", dname)
+	return l.result()
+}
+
+// the non-central hypergeometric distribution of the first cell of a 2 x 2 table
+type noncentralHyper struct {
+	lo, hi  float64
+	support []float64
+	logdc   []float64
+}
+
+func (h *noncentralHyper) density(ncp float64) []float64 {
+	d := make([]float64, len(h.support))
+	max := math.Inf(-1)
+	for k, s := range h.support {
+		d[k] = h.logdc[k] + math.Log(ncp)*s
+		max = math.Max(max, d[k])
+	}
+	sum := 0.
+	for k := range d {
+		d[k] = math.Exp(d[k] - max)
+		sum += d[k]
+	}
+	for k := range d {
+		d[k] /= sum
+	}
+	return d
+}
+
+func (h *noncentralHyper) mean(ncp float64) float64 {
+	switch {
+	case ncp == 0:
+		return h.lo
+	case math.IsInf(ncp, 1):
+		return h.hi
+	}
+	m := 0.
+	for k, d := range h.density(ncp) {
+		m += h.support[k] * d
+	}
+	return m
+}
+
+func (h *noncentralHyper) cdf(q float64, ncp float64, upperTail bool) float64 {
+	switch {
+	case ncp == 0:
+		if upperTail == (q <= h.lo) || !upperTail == (q >= h.lo) && !upperTail {
+			return 1
+		}
+		return 0
+	case math.IsInf(ncp, 1):
+		if upperTail && q <= h.hi || !upperTail && q >= h.hi {
+			return 1
+		}
+		return 0
+	}
+	p := 0.
+	for k, d := range h.density(ncp) {
+		if upperTail && h.support[k] >= q || !upperTail && h.support[k] <= q {
+			p += d
+		}
+	}
+	return p
+}
+
+func EvalFisherTest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "fisher.test", "argument \"x\" is missing, with no default")
+	}
+	t, _, _, dname, ok := contingencyTable(ev, node, "fisher.test", fisherTestFormals, x, args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	for k, v := range t.Data {
+		if math.IsNaN(v) || v < 0 || math.IsInf(v, 0) {
+			return builtinError(ev, "fisher.test", "all entries of 'x' must be nonnegative and finite")
+		}
+		t.Data[k] = math.Round(v)
+	}
+	if t.Rows < 2 || t.Cols < 2 {
+		return builtinError(ev, "fisher.test", "'x' must have at least 2 rows and columns")
+	}
+	if t.Rows != 2 || t.Cols != 2 {
+		return builtinError(ev, "fisher.test", "only 2 x 2 tables are supported")
+	}
+	or := args.float(2, 1)
+	if args.Values[2] != nil && (args.Values[2].Length() != 1 || math.IsNaN(or) || or < 0) {
+		return builtinError(ev, "fisher.test", "'or' must be a single number between 0 and Inf")
+	}
+	alternative, ok := choiceArgument(ev, "fisher.test", args.Values[3], alternatives)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	confInt := args.logical(4, true)
+	level, ok := confLevelArgument(ev, "fisher.test", args.Values[5])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	m := t.At(0, 0) + t.At(1, 0)
+	n := t.At(0, 1) + t.At(1, 1)
+	k := t.At(0, 0) + t.At(0, 1)
+	x11 := t.At(0, 0)
+	h := &noncentralHyper{lo: math.Max(0, k-n), hi: math.Min(k, m)}
+	for s := h.lo; s <= h.hi; s++ {
+		h.support = append(h.support, s)
+		h.logdc = append(h.logdc, numeric.Dhyper(s, m, n, k, true))
+	}
+	pnhyper := func(q float64, ncp float64, upperTail bool) float64 {
+		if ncp == 1 {
+			if upperTail {
+				return numeric.Phyper(x11-1, m, n, k, false, false)
+			}
+			return numeric.Phyper(x11, m, n, k, true, false)
+		}
+		return h.cdf(q, ncp, upperTail)
+	}
+	var pval float64
+	switch alternative {
+	case "less":
+		pval = pnhyper(x11, or, false)
+	case "greater":
+		pval = pnhyper(x11, or, true)
+	default:
+		switch {
+		case or == 0 && x11 == h.lo, math.IsInf(or, 1) && x11 == h.hi:
+			pval = 1
+		case or == 0, math.IsInf(or, 1):
+			pval = 0
+		default:
+			const relErr = 1 + 1e-7
+			d := h.density(or)
+			bound := d[int(x11-h.lo)] * relErr
+			for _, v := range d {
+				if v <= bound {
+					pval += v
+				}
+			}
+		}
+	}
+	eps := 2.220446049250313e-16
+	// the conditional maximum likelihood estimate solves E(X) = x
+	estimate := 1.
+	switch mu := h.mean(1); {
+	case x11 == h.lo:
+		estimate = 0
+	case x11 == h.hi:
+		estimate = math.Inf(1)
+	case mu > x11:
+		f := func(t float64) float64 { return h.mean(t) - x11 }
+		estimate = rootOf(f, 0, 1, f(0), f(1), defaultTolerance)
+	case mu < x11:
+		f := func(t float64) float64 { return h.mean(1/t) - x11 }
+		estimate = 1 / rootOf(f, eps, 1, f(eps), f(1), defaultTolerance)
+	}
+	ncpUpper := func(alpha float64) float64 {
+		if x11 == h.hi {
+			return math.Inf(1)
+		}
+		switch p := pnhyper(x11, 1, false); {
+		case p < alpha:
+			f := func(t float64) float64 { return pnhyper(x11, t, false) - alpha }
+			return rootOf(f, 0, 1, f(0), f(1), defaultTolerance)
+		case p > alpha:
+			f := func(t float64) float64 { return pnhyper(x11, 1/t, false) - alpha }
+			return 1 / rootOf(f, eps, 1, f(eps), f(1), defaultTolerance)
+		}
+		return 1
+	}
+	ncpLower := func(alpha float64) float64 {
+		if x11 == h.lo {
+			return 0
+		}
+		switch p := pnhyper(x11, 1, true); {
+		case p > alpha:
+			f := func(t float64) float64 { return pnhyper(x11, t, true) - alpha }
+			return rootOf(f, 0, 1, f(0), f(1), defaultTolerance)
+		case p < alpha:
+			f := func(t float64) float64 { return pnhyper(x11, 1/t, true) - alpha }
+			return 1 / rootOf(f, eps, 1, f(eps), f(1), defaultTolerance)
+		}
+		return 1
+	}
+	l := &htestList{pos: pos}
+	l.value("p.value", pval)
+	if confInt {
+		switch alternative {
+		case "less":
+			l.confInt(0, ncpUpper(1-level), level)
+		case "greater":
+			l.confInt(ncpLower(1-level), math.Inf(1), level)
+		default:
+			alpha := (1 - level) / 2
+			l.confInt(ncpLower(alpha), ncpUpper(alpha), level)
+		}
+	} else {
+		l.null("conf.int")
+	}
+	l.named("estimate", "odds ratio", estimate)
+	l.named("null.value", "odds ratio", or)
+	l.text("alternative", alternative)
+	l.text("method", "Fisher's Exact Test for Count Data")
+	l.text("data.name", dname)
+	return l.result()
+}
+
+func EvalShapiroTest(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if !numericArgument(ev, "shapiro.test", "x", args.Values[0]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	x := nonMissing(asFloats(args.Values[0], &warn))
+	sort.Float64s(x)
+	n := len(x)
+	if n < 3 || n > 5000 {
+		return builtinError(ev, "shapiro.test", "sample size must be between 3 and 5000")
+	}
+	rng := x[n-1] - x[0]
+	if rng < 1e-10 {
+		return builtinError(ev, "shapiro.test", "all 'x' values are identical")
+	}
+	if rng < 1 { // rescaled against a zero range
+		for k := range x {
+			x[k] /= rng
+		}
+	}
+	w, pw, ifault := numeric.Swilk(x)
+	if ifault > 0 && ifault != numeric.SwilkUnsorted {
+		return builtinError(ev, "shapiro.test", "ifault=%d. This should not happen", ifault)
+	}
+	l := &htestList{pos: node.Fun.Pos()}
+	l.named("statistic", "W", w)
+	l.value("p.value", pw)
+	l.text("method", "Shapiro-Wilk normality test")
+	l.text("data.name", argumentText(node, []string{"x"}, "x"))
+	return l.result()
+}
+
+// the words of text in lines shorter than width, as strwrap
+func strwrap(text string, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		switch {
+		case line == "":
+			line = word
+		case len(line)+1+len(word) >= width:
+			lines = append(lines, line)
+			line = word
+		default:
+			line += " " + word
+		}
+	}
+	return append(lines, line)
+}
+
+func printHtest(w io.Writer, x *RSEXP) {
+	component := func(name string) SEXPItf {
+		if c := listComponent(x, name); !isNull(c) {
+			return c
+		}
+		return nil
+	}
+	const width = 72 // 0.9 * getOption("width")
+	fmt.Fprintln(w)
+	if method := component("method"); method != nil {
+		for _, line := range strwrap(asStrings(method)[0], width) {
+			fmt.Fprintln(w, "\t"+line)
+		}
+	}
+	fmt.Fprintln(w)
+	if dname := component("data.name"); dname != nil {
+		fmt.Fprintf(w, "data:  %s\n", asStrings(dname)[0])
+	}
+	var out []string
+	for _, name := range []string{"statistic", "parameter"} {
+		if c := component(name); c != nil {
+			warn := false
+			values := formatReal(asFloats(c, &warn), 5)
+			for k, label := range c.Names() {
+				out = append(out, label+" = "+values[k])
+			}
+		}
+	}
+	if c := component("p.value"); c != nil {
+		warn := false
+		fp := formatPval(asFloats(c, &warn), 4)[0]
+		if strings.HasPrefix(fp, "<") {
+			out = append(out, "p-value "+fp)
+		} else {
+			out = append(out, "p-value = "+fp)
+		}
+	}
+	if len(out) > 0 {
+		for _, line := range strwrap(strings.Join(out, ", "), width) {
+			fmt.Fprintln(w, line)
+		}
+	}
+	if c := component("alternative"); c != nil {
+		alternative := asStrings(c)[0]
+		fmt.Fprint(w, "alternative hypothesis: ")
+		if nv := component("null.value"); nv != nil && nv.Length() == 1 {
+			relations := map[string]string{"two.sided": "not equal to", "less": "less than", "greater": "greater than"}
+			warn := false
+			fmt.Fprintf(w, "true %s is %s %s\n", nv.Names()[0], relations[alternative], formatC(asFloats(nv, &warn)[0], 7))
+		} else if nv != nil {
+			fmt.Fprintf(w, "%s\nnull values:\n", alternative)
+			PrintResult(w, nv)
+		} else {
+			fmt.Fprintln(w, alternative)
+		}
+	}
+	if c, ok := component("conf.int").(*VSEXP); ok {
+		level := 0.95
+		if c.Attributes != nil {
+			level = componentFloats(c.Attributes, "conf.level")[0]
+		}
+		fmt.Fprintf(w, "%s percent confidence interval:\n %s\n", formatReal([]float64{100 * level}, 7)[0],
+			strings.Join(formatReal(floatSlice(c)[:2], 7), " "))
+	}
+	if c := component("estimate"); c != nil {
+		warn := false
+		fmt.Fprintln(w, "sample estimates:")
+		printNamedColumns(w, c.Names(), formatReal(asFloats(c, &warn), 7), 1)
+	}
+	fmt.Fprintln(w)
+}
@@ -1,11 +1,12 @@
 package eval
 
 import (
+	"roq/calc"
 	"roq/lib/ast"
 	"roq/lib/token"
 	"math"
 	"strconv"
-	"reflect" // TODO: make obsolete
+	"strings"
 )
 
 // nodes inside brackets are evaluated within a special domain, the index domain. 
@@ -18,6 +19,9 @@ import (
 // boolean vector
 
 // iterators return either a positive number, which should be used as offset or -1, indicating the end.
+// missing elements, selected by NA or by names not found, are indicated by naOffset.
+
+const naOffset = -2
 
 
 type IteratorItf interface {
@@ -46,6 +50,10 @@ type (
 		Len 	int  // cached length
 		Counter	int
 	}
+	SliceIterator struct{	// offsets already resolved, e.g. from names
+		Offsets	[]int
+		Counter	int
+	}
 )
 
 func (x *FullIterator) Length() int { 
@@ -55,7 +63,10 @@ func (x *RangeIterator) Length() int {
 	return 1+(x.End - x.Start)
 }
 func (x *ArrayIterator) Length() int {
-	return x.Length()
+	return len(x.Slice)
+}
+func (x *SliceIterator) Length() int {
+	return len(x.Offsets)
 }
 func (x *OnceIterator) Length() int { 
 	return 1
@@ -65,9 +76,9 @@ func (x *EmptyIterator) Length() int {
 }
 
 func (x *FullIterator) Next() int { 
-	x.Counter +=1
 	if (x.Counter < x.Max){ 
-		return x.Counter
+		x.Counter +=1
+		return x.Counter-1
 	} else {
 		return -1
 	}
@@ -83,13 +94,21 @@ func (x *RangeIterator) Next() int {
 func (x *ArrayIterator) Next() int {
 	a := x.Slice
 	if (x.Counter < len(a)){ 
-			i := int(a[x.Counter]) // TODO ISEXP
+			i := int(a[x.Counter]) // only positive subscripts, see numericToIterator
 			x.Counter +=1
 			return i-1
 	} else {
 		return -1
 	}
 }
+func (x *SliceIterator) Next() int {
+	if (x.Counter < len(x.Offsets)){
+		x.Counter +=1
+		return x.Offsets[x.Counter-1]
+	} else {
+		return -1
+	}
+}
 func (x *OnceIterator) Next() int { 
 	if x.Done { 
 		return -1
@@ -104,28 +123,42 @@ func (x *EmptyIterator) Next() int {
 
 
 
-func EvalRangeExpressionToIterator(ev *Evaluator, a SEXPItf, b SEXPItf) IteratorItf {
+// ranges of positive subscripts are iterated without evaluating them into an array
+func EvalRangeExpressionToIterator(ev *Evaluator, a SEXPItf, b SEXPItf, extent int) IteratorItf {
+	low := a.IntegerGet()
+	high := b.IntegerGet()
+	if low < 1 || high < low {
+		step := 1
+		if high < low {
+			step = -1
+		}
+		values := make([]float64, 0, 1+(high-low)*step)
+		for i := low; i != high+step; i += step {
+			values = append(values, float64(i))
+		}
+		return numericToIterator(ev, values, extent)
+	}
 	r := new(RangeIterator)
-	r.Start = a.IntegerGet() - 2
+	r.Start = low - 2
 	r.Counter = r.Start
-	r.End = b.IntegerGet() -2
+	r.End = high - 2
 	return r
 }
 
-func IndexValueAsInt(node *ast.BasicLit) int{
+func IndexValueAsInt(ev *Evaluator, node *ast.BasicLit) int{
 	switch node.Kind {
 	case token.FLOAT:
 		vfloat, err := strconv.ParseFloat(node.Value, 64) // TODO: support for all R formatted values
 		if err != nil {
-			print("ERROR:")
-			println(err)
+			ev.print("ERROR:")
+			ev.println(err)
 		}
 		return int(math.Floor(vfloat))
 	case token.INT:
 		vint, err := strconv.Atoi(node.Value)
 		if err != nil {
-			print("ERROR:")
-			println(err)
+			ev.print("ERROR:")
+			ev.println(err)
 		}
 		return vint
 	case token.NULL:
@@ -133,80 +166,191 @@ func IndexValueAsInt(node *ast.BasicLit) int{
 	case token.IDENT:
 		return 0
 	default:
-		println("Unknown node.Kind for index")
+		ev.println("Unknown node.Kind for index")
 		return 0
 	}
 }
 
-func EvalSexpressionToIterator(sexp SEXPItf) IteratorItf {
+// character subscripts are matched against the names of the indexed dimension
+func namesToIterator(strings []string, names []string) IteratorItf {
+	r := new(SliceIterator)
+	r.Offsets = make([]int, len(strings))
+	for n, s := range strings {
+		r.Offsets[n] = naOffset
+		for k, name := range names {
+			if name == s {
+				r.Offsets[n] = k
+				break
+			}
+		}
+	}
+	return r
+}
+
+// https://cran.r-project.org/doc/manuals/R-lang.html#Indexing-by-vectors
+// positive subscripts select, negative subscripts exclude elements, zeros are dropped
+// subscripts are truncated towards zero
+func numericToIterator(ev *Evaluator, values []float64, extent int) IteratorItf {
+	positive, negative, other := false, false, false
+	for _, v := range values {
+		switch {
+		case math.IsNaN(v):
+			other = true
+		case v >= 1:
+			positive = true
+		case v <= -1:
+			negative = true
+		default:
+			other = true // zero
+		}
+	}
+	if negative {
+		if positive || hasNaN(values) {
+			errorf("can't mix positive and negative subscripts")
+			return new(EmptyIterator)
+		}
+		excluded := make([]bool, extent)
+		for _, v := range values {
+			i := int(-v) - 1
+			if i >= 0 && i < extent {
+				excluded[i] = true
+			}
+		}
+		r := new(SliceIterator)
+		r.Offsets = make([]int, 0, extent)
+		for i, e := range excluded {
+			if !e {
+				r.Offsets = append(r.Offsets, i)
+			}
+		}
+		return r
+	}
+	if !other {
+		r := new(ArrayIterator)
+		r.Slice = values
+		r.Len = r.Length()
+		return r
+	}
+	r := new(SliceIterator)
+	r.Offsets = make([]int, 0, len(values))
+	for _, v := range values {
+		if math.IsNaN(v) {
+			r.Offsets = append(r.Offsets, naOffset)
+		} else if v >= 1 {
+			r.Offsets = append(r.Offsets, int(v)-1)
+		}
+	}
+	return r
+}
+
+func hasNaN(values []float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// logical subscripts are recycled up to the extent
+func logicalToIterator(values []int, extent int) IteratorItf {
+	r := new(SliceIterator)
+	if len(values) == 0 {
+		return r
+	}
+	length := extent
+	if len(values) > length {
+		length = len(values)
+	}
+	r.Offsets = make([]int, 0, length)
+	for i := 0; i < length; i++ {
+		switch values[i%len(values)] {
+		case TRUE:
+			r.Offsets = append(r.Offsets, i)
+		case FALSE:
+		default:
+			r.Offsets = append(r.Offsets, naOffset)
+		}
+	}
+	return r
+}
+
+func EvalSexpressionToIterator(ev *Evaluator, sexp SEXPItf, extent int, names []string) IteratorItf {
 	switch sexp.(type) {
 	case *ISEXP:
-		r := new(OnceIterator)
-		r.Offset=int(math.Floor(sexp.(*ISEXP).Immediate))
-		return r
+		slice := integerSlice(sexp.(*ISEXP))
+		values := make([]float64, len(slice))
+		for n, v := range slice {
+			values[n] = float64(v)
+		}
+		return numericToIterator(ev, values, extent)
 	case *VSEXP:
-		if sexp.(*VSEXP).Slice == nil {
+		if sexp.(*VSEXP).Slice == nil && sexp.(*VSEXP).Immediate >= 1 {
 			r := new(OnceIterator)
 			r.Offset=int(math.Floor(sexp.(*VSEXP).Immediate))
 			return r
 		} else {
-			r := new(ArrayIterator)
-			r.Slice=sexp.(*VSEXP).Slice
-			return r
+			return numericToIterator(ev, floatSlice(sexp.(*VSEXP)), extent)
 		}
+	case *LSEXP:
+		return logicalToIterator(logicalSlice(sexp.(*LSEXP)), extent)
+	case *TSEXP:
+		return namesToIterator(stringSlice(sexp.(*TSEXP)), names)
+	case *NSEXP:
+		return new(EmptyIterator)
+	case nil, *ESEXP:
+		return new(EmptyIterator) // the error is already printed
 	default:
-		givenType := reflect.TypeOf(sexp)
-		println("?IndexSExpr:", givenType.String())
-		r := new(EmptyIterator)
-		return r
+		errorf("invalid subscript type '%s'", typeName(sexpType(sexp)))
+		return new(EmptyIterator)
 	}
 }
 
-func EvalIndexExpressionToIterator(ev *Evaluator, ex ast.Expr) IteratorItf {
+// extent and names are those of the indexed dimension, an empty subscript selects all
+func EvalIndexExpressionToIterator(ev *Evaluator, ex ast.Expr, extent int, names []string) IteratorItf {
 	defer un(trace(ev, "EvalIndexExpressionToIterator"))
 	switch ex.(type) {
-	case *ast.Ident:
-		sexp:=EvalExpr(ev,ex)
-		return EvalSexpressionToIterator(sexp)
+	case nil:
+		r := new(FullIterator)
+		r.Max = extent
+		return r
 	case *ast.BasicLit:
 		ev.Invisible = false
 		node := ex.(*ast.BasicLit)
 		defer un(trace(ev, "BasicLit ", node.Kind.String()))
-		index := IndexValueAsInt(node)
-		if index == 0 {
-			obj := ev.topFrame.Recursive(node.Value)
-			if obj == nil {
-				print("error: object '", node.Value, "' not found\n")
-				return new(EmptyIterator)
-			} else {
-				r := new(ArrayIterator)
-				r.Slice = obj.(*VSEXP).Slice // TODO: check this
-				r.Len=r.Length()
+		switch node.Kind {
+		case token.FLOAT, token.INT:
+			index := IndexValueAsInt(ev, node)
+			if index > 0 {
+				r := new(OnceIterator)
+				r.Offset=index
 				return r
 			}
-		} else {
-			r := new(OnceIterator)
-			r.Offset=index
-			return r
 		}
+		return EvalSexpressionToIterator(ev, EvalExpr(ev, ex), extent, names)
 	case *ast.BinaryExpr:
 		ev.Invisible = false
 		node := ex.(*ast.BinaryExpr)
 		if node.Op == token.SEQUENCE {
-			return EvalRangeExpressionToIterator(ev, EvalExpr(ev,node.X).(*VSEXP),EvalExpr(ev,node.Y).(*VSEXP))
+			return EvalRangeExpressionToIterator(ev, EvalExpr(ev,node.X), EvalExpr(ev,node.Y), extent)
 		} else {
 			sexp:=evalBinary(ev,node)
-			return EvalSexpressionToIterator(sexp)
+			return EvalSexpressionToIterator(ev, sexp, extent, names)
 		}
 	default:
 		ev.Invisible = false
-		givenType := reflect.TypeOf(ex)
-		println("?IndexExpr:", givenType.String())
+		sexp := EvalExpr(ev, ex)
+		return EvalSexpressionToIterator(ev, sexp, extent, names)
 	}
-	r := new(EmptyIterator)
-	return r
 }
 
+func iteratorOffsets(iterator IteratorItf) []int {
+	r := make([]int, 0, 4)
+	for n := iterator.Next(); n != -1; n = iterator.Next() {
+		r = append(r, n)
+	}
+	return r
+}
 
 // https://cran.r-project.org/doc/manuals/R-lang.html#Indexing-by-vectors
 // A special case is the zero index, which has null effects: 
@@ -214,36 +358,760 @@ func EvalIndexExpressionToIterator(ev *Evaluator, ex ast.Expr) IteratorItf {
 // negative indices has the same effect as if they were omitted.
 
 
+// subscripts are split from tagged arguments of the index operator, drop defaults to TRUE
+func evalIndexArguments(ev *Evaluator, index []ast.Expr) (subscripts []ast.Expr, drop bool) {
+	drop = true
+	subscripts = make([]ast.Expr, 0, len(index))
+	for _, ex := range index {
+		switch ex.(type) {
+		case *ast.TaggedExpr:
+			tagged := ex.(*ast.TaggedExpr)
+			switch tagged.Tag {
+			case "drop":
+				drop = isTrue(ev, EvalExpr(ev, tagged.Rhs))
+			case "exact":
+			default:
+				subscripts = append(subscripts, tagged.Rhs)
+			}
+		default:
+			subscripts = append(subscripts, ex)
+		}
+	}
+	return subscripts, drop
+}
+
+func dimnamesAt(array SEXPItf, k int) []string {
+	dimnames := array.Dimnames()
+	if dimnames == nil || k >= len(dimnames.Slice) {
+		return nil
+	}
+	switch dimnames.Slice[k].(type) {
+	case *TSEXP:
+		return stringSlice(dimnames.Slice[k].(*TSEXP))
+	default:
+		return nil
+	}
+}
+
+// a numeric matrix with one column per dimension selects single elements by its rows
+func isIndexMatrix(sexp SEXPItf, dim []int) bool {
+	switch sexp.(type) {
+	case *VSEXP:
+		sdim := sexp.Dim()
+		return len(dim) > 1 && len(sdim) == 2 && sdim[1] == len(dim)
+	default:
+		return false
+	}
+}
+
+func indexMatrixOffsets(m *VSEXP, dim []int) ([]int, bool) {
+	rows := m.Dim()[0]
+	r := make([]int, rows)
+	for row := 0; row < rows; row++ {
+		offset := 0
+		stride := 1
+		for k, extent := range dim {
+			i := int(math.Floor(m.Slice[row+rows*k])) - 1
+			if i < 0 || i >= extent {
+				return nil, false
+			}
+			offset += i * stride
+			stride *= extent
+		}
+		r[row] = offset
+	}
+	return r, true
+}
+
+// offsets of all selected elements in column-major order
+// for more than one subscript, the selected offsets within each dimension are returned as well
+func evalIndexOffsets(ev *Evaluator, array SEXPItf, subscripts []ast.Expr) ([]int, [][]int, bool) {
+	dim := array.Dim()
+	if len(subscripts) == 1 {
+		if subscripts[0] != nil && len(dim) > 1 {
+			sexp := EvalExpr(ev, subscripts[0])
+			if isIndexMatrix(sexp, dim) {
+				offsets, ok := indexMatrixOffsets(sexp.(*VSEXP), dim)
+				if !ok {
+					errorf("subscript out of bounds")
+				}
+				return offsets, nil, ok
+			}
+			return iteratorOffsets(EvalSexpressionToIterator(ev, sexp, array.Length(), array.Names())), nil, true
+		}
+		iterator := EvalIndexExpressionToIterator(ev, subscripts[0], array.Length(), array.Names())
+		return iteratorOffsets(iterator), nil, true
+	}
+	if len(subscripts) != len(dim) {
+		errorf("incorrect number of dimensions")
+		return nil, nil, false
+	}
+	selected := make([][]int, len(dim))
+	total := 1
+	for k, ex := range subscripts {
+		iterator := EvalIndexExpressionToIterator(ev, ex, dim[k], dimnamesAt(array, k))
+		selected[k] = iteratorOffsets(iterator)
+		for _, i := range selected[k] {
+			if i < 0 || i >= dim[k] {
+				errorf("subscript out of bounds")
+				return nil, nil, false
+			}
+		}
+		total *= len(selected[k])
+	}
+	offsets := make([]int, total)
+	counter := make([]int, len(dim)) // odometer over the selection, first dimension fastest
+	for n := 0; n < total; n++ {
+		offset := 0
+		stride := 1
+		for k := range dim {
+			offset += selected[k][counter[k]] * stride
+			stride *= dim[k]
+		}
+		offsets[n] = offset
+		for k := range counter {
+			counter[k]++
+			if counter[k] < len(selected[k]) {
+				break
+			}
+			counter[k] = 0
+		}
+	}
+	return offsets, selected, true
+}
+
+// select elements at zero-based offsets, offsets out of range give missing values
+func indexElements(ev *Evaluator, array SEXPItf, offsets []int) SEXPItf {
+	length := array.Length()
+	switch array.(type) {
+	case *VSEXP:
+		if isFunction(array) {
+			return errorf("object of type 'closure' is not subsettable")
+		}
+		slice := floatSlice(array.(*VSEXP))
+		r := make([]float64, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = slice[i]
+			} else {
+				r[n] = calc.NA
+			}
+		}
+		return &VSEXP{ValuePos: array.Pos(), Slice: r}
+	case *ISEXP:
+		slice := integerSlice(array.(*ISEXP))
+		r := make([]int, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = slice[i]
+			} else {
+				r[n] = NA_INTEGER
+			}
+		}
+		return &ISEXP{ValuePos: array.Pos(), Slice: r}
+	case *LSEXP:
+		slice := logicalSlice(array.(*LSEXP))
+		r := make([]int, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = slice[i]
+			} else {
+				r[n] = NA_LOGICAL
+			}
+		}
+		return &LSEXP{ValuePos: array.Pos(), Slice: r}
+	case *CSEXP:
+		slice := complexSlice(array.(*CSEXP))
+		r := make([]complex128, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = slice[i]
+			} else {
+				r[n] = naComplex
+			}
+		}
+		return &CSEXP{ValuePos: array.Pos(), Slice: r}
+	case *TSEXP:
+		slice := stringSlice(array.(*TSEXP))
+		r := make([]string, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = slice[i]
+			} else {
+				r[n] = NA_CHARACTER
+			}
+		}
+		return &TSEXP{ValuePos: array.Pos(), Slice: r}
+	case *RSEXP:
+		r := make([]SEXPItf, len(offsets))
+		for n, i := range offsets {
+			if i >= 0 && i < length {
+				r[n] = array.(*RSEXP).Slice[i]
+			} else {
+				r[n] = &NSEXP{}
+			}
+		}
+		return &RSEXP{ValuePos: array.Pos(), Slice: r}
+	default:
+		return errorf("object is not subsettable")
+	}
+}
+
+func selectNames(names []string, offsets []int) []string {
+	if names == nil {
+		return nil
+	}
+	r := make([]string, len(offsets))
+	for n, i := range offsets {
+		if i >= 0 && i < len(names) {
+			r[n] = names[i]
+		} else {
+			r[n] = "<NA>"
+		}
+	}
+	return r
+}
+
 // TODO consistant naming for index, value and toplevel domain:
 // evalExprI -> ISEXPR
 func EvalIndexedArray(ev *Evaluator, node *ast.IndexExpr) SEXPItf {
 	array := EvalExpr(ev,node.Array)
 	if array == nil {
 		panic("array not found\n")
-	} else {
-		iterator := EvalIndexExpressionToIterator(ev, node.Index)
-		r := make([]float64,0,array.Length())
-		var n int
-		for true {
-			n = iterator.Next()
-			if n >= 0 {
-				element := array.(*VSEXP).Slice[n]
-				r = append(r,element)
+	}
+	if s, ok := sparseValue(array); ok {
+		return sparseIndex(ev, array, s, node.Index)
+	}
+	if b, ok := bigValues(array); ok {
+		return bigIndex(ev, array, b, node.Index)
+	}
+	subscripts, drop := evalIndexArguments(ev, node.Index)
+	if len(subscripts) == 1 && subscripts[0] == nil {
+		return array
+	}
+	offsets, selected, ok := evalIndexOffsets(ev, array, subscripts)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := indexElements(ev, array, offsets)
+	if selected == nil {
+		r.NamesSet(selectNames(array.Names(), offsets))
+		return r
+	}
+
+	// result of matrix indexing keeps dimensions, unless they are dropped
+	dim := make([]int, 0, len(selected))
+	dimnames := make([]SEXPItf, 0, len(selected))
+	withDimnames := false
+	for k, s := range selected {
+		if drop && len(s) == 1 {
+			continue
+		}
+		dim = append(dim, len(s))
+		names := selectNames(dimnamesAt(array, k), s)
+		if names != nil {
+			withDimnames = true
+			dimnames = append(dimnames, &TSEXP{Slice: names})
+		} else {
+			dimnames = append(dimnames, &NSEXP{})
+		}
+	}
+	if len(dim) > 1 {
+		r.DimSet(dim)
+		if withDimnames {
+			r.DimnamesSet(&RSEXP{Slice: dimnames})
+		}
+	} else if len(dim) == 1 {
+		switch dimnames[0].(type) {
+		case *TSEXP:
+			r.NamesSet(stringSlice(dimnames[0].(*TSEXP)))
+		}
+	}
+	return r
+}
+
+// partial matching of names, only unique prefixes are accepted
+func matchName(s string, names []string, exact bool) int {
+	partial := -1
+	for k, name := range names {
+		if name == s {
+			return k
+		}
+		if !exact && strings.HasPrefix(name, s) {
+			if partial >= 0 {
+				return -1 // ambiguous
+			}
+			partial = k
+		}
+	}
+	if partial >= 0 {
+		return partial
+	}
+	return -1
+}
+
+// [[ ]] selects exactly one element, either by position or by name
+func evalListOffset(ev *Evaluator, sexp SEXPItf, extent int, names []string, exact bool) (int, bool) {
+	if sexp.Length() != 1 {
+		if sexp.Length() == 0 {
+			errorf("attempt to select less than one element")
+		} else {
+			errorf("attempt to select more than one element")
+		}
+		return 0, false
+	}
+	var i int
+	switch sexp.(type) {
+	case *TSEXP:
+		return matchName(stringSlice(sexp.(*TSEXP))[0], names, exact), true
+	case *VSEXP:
+		v := floatSlice(sexp.(*VSEXP))[0]
+		if math.IsNaN(v) {
+			return -1, true
+		}
+		i = int(v)
+	case *ISEXP:
+		i = integerSlice(sexp.(*ISEXP))[0]
+	case *LSEXP:
+		i = logicalSlice(sexp.(*LSEXP))[0]
+	default:
+		errorf("invalid subscript type")
+		return 0, false
+	}
+	if i < 0 {
+		errorf("invalid negative subscript")
+		return 0, false
+	} else if i == 0 {
+		errorf("attempt to select less than one element")
+		return 0, false
+	} else if i > extent {
+		errorf("subscript out of bounds")
+		return 0, false
+	}
+	return i - 1, true
+}
+
+func evalListIndexArguments(ev *Evaluator, index []ast.Expr) (subscripts []ast.Expr, exact bool) {
+	exact = true
+	subscripts = make([]ast.Expr, 0, len(index))
+	for _, ex := range index {
+		switch ex.(type) {
+		case *ast.TaggedExpr:
+			tagged := ex.(*ast.TaggedExpr)
+			if tagged.Tag == "exact" {
+				exact = isTrue(ev, EvalExpr(ev, tagged.Rhs))
 			} else {
-				break
+				subscripts = append(subscripts, tagged.Rhs)
 			}
-		}  
-		return &VSEXP{ValuePos: array.Pos(), Slice:r}
+		default:
+			subscripts = append(subscripts, ex)
+		}
+	}
+	return subscripts, exact
+}
+
+// element at offset, atomic vectors return a scalar
+func listElement(ev *Evaluator, array SEXPItf, offset int) SEXPItf {
+	switch array.(type) {
+	case *RSEXP:
+		return array.(*RSEXP).Slice[offset]
+	case *VSEXP:
+		return &VSEXP{ValuePos: array.Pos(), Immediate: floatSlice(array.(*VSEXP))[offset]}
+	case *ISEXP:
+		v := integerSlice(array.(*ISEXP))[offset]
+		return &ISEXP{ValuePos: array.Pos(), Immediate: float64(v), Integer: v}
+	case *LSEXP:
+		return &LSEXP{ValuePos: array.Pos(), Immediate: logicalSlice(array.(*LSEXP))[offset]}
+	case *CSEXP:
+		return &CSEXP{ValuePos: array.Pos(), Immediate: complexSlice(array.(*CSEXP))[offset]}
+	case *TSEXP:
+		return &TSEXP{ValuePos: array.Pos(), String: stringSlice(array.(*TSEXP))[offset]}
+	default:
+		return errorf("object is not subsettable")
 	}
 }
 
+// evaluates the offset for x[[i]] and x[[i,j]]
+func evalListIndexOffset(ev *Evaluator, array SEXPItf, index []ast.Expr) (int, bool) {
+	subscripts, exact := evalListIndexArguments(ev, index)
+	dim := array.Dim()
+	if len(subscripts) == 1 && subscripts[0] != nil {
+		sexp := EvalExpr(ev, subscripts[0])
+		return evalListOffset(ev, sexp, array.Length(), array.Names(), exact)
+	}
+	if len(subscripts) != len(dim) || len(dim) < 2 {
+		errorf("incorrect number of subscripts")
+		return 0, false
+	}
+	offset := 0
+	stride := 1
+	for k, ex := range subscripts {
+		if ex == nil {
+			errorf("invalid subscript")
+			return 0, false
+		}
+		i, ok := evalListOffset(ev, EvalExpr(ev, ex), dim[k], dimnamesAt(array, k), exact)
+		if !ok {
+			return 0, false
+		}
+		if i < 0 {
+			errorf("subscript out of bounds")
+			return 0, false
+		}
+		offset += i * stride
+		stride *= dim[k]
+	}
+	return offset, true
+}
+
+// x[[i]] selects a single element, names are matched exactly unless exact=FALSE
 func EvalIndexedList(ev *Evaluator, node *ast.ListIndexExpr) SEXPItf {
-	list := EvalExpr(ev,node.Array)
-	if list == nil {
-		panic("list not found\n")
+	list := EvalExpr(ev, node.Array)
+	switch list.(type) {
+	case nil, *NSEXP:
+		return &NSEXP{}
+	case *ESEXP:
+		return list
+	}
+	offset, ok := evalListIndexOffset(ev, list, node.Index)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if offset < 0 {
+		if _, ok := list.(*RSEXP); ok {
+			return &NSEXP{}
+		}
+		return errorf("subscript out of bounds")
+	}
+	return listElement(ev, list, offset)
+}
+
+// x$name is x[["name", exact=FALSE]] for lists
+func EvalListSubset(ev *Evaluator, node *ast.BinaryExpr) SEXPItf {
+	list := EvalExpr(ev, node.X)
+	name := subsetName(ev, node.Y)
+	switch list.(type) {
+	case *RSEXP:
+		offset := matchName(name, list.Names(), false)
+		if offset < 0 {
+			return &NSEXP{}
+		}
+		return list.(*RSEXP).Slice[offset]
+	case nil, *NSEXP:
+		return &NSEXP{}
+	case *ESEXP:
+		return list
+	default:
+		return errorf("$ operator is invalid for atomic vectors")
+	}
+}
+
+// the name after $ is either an identifier or a string
+func subsetName(ev *Evaluator, ex ast.Expr) string {
+	switch ex.(type) {
+	case *ast.Ident:
+		return ex.(*ast.Ident).Name
+	case *ast.BasicLit:
+		return strings.Trim(ex.(*ast.BasicLit).Value, "\"'`")
+	default:
+		return getIdent(ev, ex)
+	}
+}
+
+// vectors are copied before modification, as they might be bound to other names as well
+func copyVector(x SEXPItf) SEXPItf {
+	switch x.(type) {
+	case *VSEXP:
+		r := *x.(*VSEXP)
+		r.Slice = append([]float64(nil), floatSlice(x.(*VSEXP))...)
+		return &r
+	case *ISEXP:
+		r := *x.(*ISEXP)
+		r.Slice = append([]int(nil), integerSlice(x.(*ISEXP))...)
+		return &r
+	case *LSEXP:
+		r := *x.(*LSEXP)
+		r.Slice = append([]int(nil), logicalSlice(x.(*LSEXP))...)
+		return &r
+	case *TSEXP:
+		r := *x.(*TSEXP)
+		r.Slice = append([]string(nil), stringSlice(x.(*TSEXP))...)
+		return &r
+	case *RSEXP:
+		r := *x.(*RSEXP)
+		if r.Slice != nil { // an empty list is kept apart from a cons cell
+			r.Slice = append([]SEXPItf{}, x.(*RSEXP).Slice...)
+		}
+		return &r
+	default:
+		return x
+	}
+}
+
+// elements of value are recycled over the offsets, vectors grow for offsets beyond their length
+func assignElements(ev *Evaluator, object SEXPItf, offsets []int, value SEXPItf) bool {
+	if len(offsets) == 0 {
+		return true
+	}
+	length := object.Length()
+	for _, i := range offsets {
+		if i >= length {
+			length = i + 1
+		}
+	}
+	switch object.(type) {
+	case *VSEXP:
+		var values []float64
+		switch value.(type) {
+		case *VSEXP:
+			values = floatSlice(value.(*VSEXP))
+		case *ISEXP:
+			for _, v := range integerSlice(value.(*ISEXP)) {
+				values = append(values, float64(v))
+			}
+		case *LSEXP:
+			for _, v := range logicalSlice(value.(*LSEXP)) {
+				if v == NA_LOGICAL {
+					values = append(values, calc.NA)
+				} else {
+					values = append(values, float64(v))
+				}
+			}
+		default:
+			errorf("incompatible types in subassignment")
+			return false
+		}
+		if len(values) == 0 {
+			errorf("replacement has length zero")
+			return false
+		}
+		o := object.(*VSEXP)
+		for len(o.Slice) < length {
+			o.Slice = append(o.Slice, calc.NA)
+		}
+		for n, i := range offsets {
+			o.Slice[i] = values[n%len(values)]
+		}
+	case *ISEXP:
+		switch value.(type) {
+		case *ISEXP:
+		default:
+			errorf("incompatible types in subassignment")
+			return false
+		}
+		values := integerSlice(value.(*ISEXP))
+		o := object.(*ISEXP)
+		for len(o.Slice) < length {
+			o.Slice = append(o.Slice, 0)
+		}
+		for n, i := range offsets {
+			o.Slice[i] = values[n%len(values)]
+		}
+	case *LSEXP:
+		switch value.(type) {
+		case *LSEXP:
+		default:
+			errorf("incompatible types in subassignment")
+			return false
+		}
+		values := logicalSlice(value.(*LSEXP))
+		o := object.(*LSEXP)
+		for len(o.Slice) < length {
+			o.Slice = append(o.Slice, NA_LOGICAL)
+		}
+		for n, i := range offsets {
+			o.Slice[i] = values[n%len(values)]
+		}
+	case *TSEXP:
+		switch value.(type) {
+		case *TSEXP:
+		default:
+			errorf("incompatible types in subassignment")
+			return false
+		}
+		values := stringSlice(value.(*TSEXP))
+		o := object.(*TSEXP)
+		for len(o.Slice) < length {
+			o.Slice = append(o.Slice, NA_CHARACTER)
+		}
+		for n, i := range offsets {
+			o.Slice[i] = values[n%len(values)]
+		}
+	case *RSEXP:
+		var values []SEXPItf
+		switch value.(type) {
+		case *RSEXP:
+			values = value.(*RSEXP).Slice
+		default:
+			values = []SEXPItf{value}
+		}
+		o := object.(*RSEXP)
+		for len(o.Slice) < length {
+			o.Slice = append(o.Slice, &NSEXP{})
+		}
+		for n, i := range offsets {
+			o.Slice[i] = values[n%len(values)]
+		}
+	default:
+		errorf("object is not subsettable")
+		return false
+	}
+	return true
+}
+
+// x[i] <- value and x[i,j] <- value
+func doIndexedAssignment(ev *Evaluator, lhs *ast.IndexExpr, rhs ast.Expr) SEXPItf {
+	target := getIdent(ev, lhs.Array)
+	defer un(trace(ev, "indexed assignment: "+target+"[] <- "))
+	value := EvalExpr(ev, rhs)
+	object := ev.topFrame.Recursive(target)
+	if object == nil {
+		return errorf("object '%s' not found", target)
+	}
+	subscripts, _ := evalIndexArguments(ev, lhs.Index)
+	var offsets []int
+	var names []string
+	if len(subscripts) == 1 && subscripts[0] == nil {
+		offsets = iteratorOffsets(&FullIterator{Max: object.Length()})
+	} else if len(subscripts) == 1 && len(object.Dim()) < 2 {
+		sexp := EvalExpr(ev, subscripts[0])
+		if t, ok := sexp.(*TSEXP); ok {
+			offsets, names = namesToOffsets(stringSlice(t), object.Names(), object.Length())
+		} else {
+			offsets = iteratorOffsets(EvalSexpressionToIterator(ev, sexp, object.Length(), object.Names()))
+		}
+	} else {
+		var ok bool
+		offsets, _, ok = evalIndexOffsets(ev, object, subscripts)
+		if !ok {
+			return nil
+		}
+	}
+	for _, i := range offsets {
+		if i < 0 {
+			return errorf("NAs are not allowed in subscripted assignments")
+		}
+	}
+	object = copyVector(object)
+	if assignElements(ev, object, offsets, value) {
+		if names == nil && object.Names() != nil {
+			names = object.Names()
+			for len(names) < object.Length() {
+				names = append(names, "")
+			}
+		}
+		if names != nil {
+			object.NamesSet(names)
+		}
+		ev.topFrame.Insert(target, object)
+	}
+	return value
+}
+
+// x[[i]] <- value replaces a single element, assigning NULL removes it from a list
+func doListIndexedAssignment(ev *Evaluator, lhs *ast.ListIndexExpr, rhs ast.Expr) SEXPItf {
+	target := getIdent(ev, lhs.Array)
+	defer un(trace(ev, "indexed assignment: "+target+"[[]] <- "))
+	value := EvalExpr(ev, rhs)
+	object := ev.topFrame.Recursive(target)
+	if object == nil {
+		return errorf("object '%s' not found", target)
+	}
+	subscripts, _ := evalListIndexArguments(ev, lhs.Index)
+	var offset int
+	var names []string
+	if len(subscripts) == 1 && subscripts[0] != nil {
+		sexp := EvalExpr(ev, subscripts[0])
+		if t, ok := sexp.(*TSEXP); ok && sexp.Length() == 1 {
+			var offsets []int
+			offsets, names = namesToOffsets(stringSlice(t), object.Names(), object.Length())
+			offset = offsets[0]
+		} else {
+			var ok bool
+			offset, ok = evalListOffset(ev, sexp, math.MaxInt32, nil, true)
+			if !ok {
+				return nil
+			}
+		}
 	} else {
-		return list.(*RSEXP).Slice[IndexValueAsInt(node.Index.(*ast.BasicLit))-1]
+		var ok bool
+		offset, ok = evalListIndexOffset(ev, object, lhs.Index)
+		if !ok {
+			return nil
+		}
+	}
+	return replaceListElement(ev, target, object, offset, names, value)
+}
+
+// x$name <- value
+func doListSubsetAssignment(ev *Evaluator, lhs *ast.BinaryExpr, rhs ast.Expr) SEXPItf {
+	target := getIdent(ev, lhs.X)
+	defer un(trace(ev, "subset assignment: "+target+"$ <- "))
+	value := EvalExpr(ev, rhs)
+	object := ev.topFrame.Recursive(target)
+	switch object.(type) {
+	case nil, *NSEXP:
+		object = &RSEXP{Slice: []SEXPItf{}}
+	case *RSEXP:
+	default:
+		return errorf("$ operator is invalid for atomic vectors")
 	}
+	offsets, names := namesToOffsets([]string{subsetName(ev, lhs.Y)}, object.Names(), object.Length())
+	return replaceListElement(ev, target, object, offsets[0], names, value)
 }
 
+func replaceListElement(ev *Evaluator, target string, object SEXPItf, offset int, names []string, value SEXPItf) SEXPItf {
+	if names == nil && object.Names() != nil {
+		names = object.Names()
+	}
+	object = copyVector(object)
+	if list, ok := object.(*RSEXP); ok {
+		if _, null := value.(*NSEXP); null || value == nil {
+			if offset < len(list.Slice) {
+				list.Slice = append(list.Slice[:offset], list.Slice[offset+1:]...)
+				if offset < len(names) {
+					names = append(append([]string(nil), names[:offset]...), names[offset+1:]...)
+				}
+				list.NamesSet(names)
+			}
+			ev.topFrame.Insert(target, list)
+			return value
+		}
+		value = &RSEXP{Slice: []SEXPItf{value}}
+	} else if value.Length() != 1 {
+		return errorf("more elements supplied than there are to replace")
+	}
+	if assignElements(ev, object, []int{offset}, value) {
+		if names != nil {
+			for len(names) < object.Length() {
+				names = append(names, "")
+			}
+			object.NamesSet(names)
+		}
+		ev.topFrame.Insert(target, object)
+	}
+	return value
+}
 
+// names not found are appended, giving offsets beyond the current length
+func namesToOffsets(strings []string, names []string, length int) ([]int, []string) {
+	r := append([]string(nil), names...)
+	for len(r) < length {
+		r = append(r, "")
+	}
+	offsets := make([]int, len(strings))
+	for n, s := range strings {
+		offsets[n] = -1
+		for k, name := range r {
+			if name == s {
+				offsets[n] = k
+				break
+			}
+		}
+		if offsets[n] < 0 {
+			offsets[n] = len(r)
+			r = append(r, s)
+		}
+	}
+	return offsets, r
+}
@@ -1,6 +1,10 @@
 package eval
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"roq/lib/ast"
 	"roq/lib/parser"
 	"roq/lib/token"
 )
@@ -27,45 +31,101 @@ func EvalFileForTest(filename string){
 
 // parser might be started with filename or various other sources (string, []byte, *bytes.Buffer, io.Reader)
 func EvalMain(filePtr *string, src interface{}, parserOpts parser.Mode, TRACE bool, DEBUG bool, PRINT bool) SEXPItf{
+	ev := NewEvaluator(os.Stdout, os.Stderr, TRACE, DEBUG)
+	r, _ := ev.Eval(*filePtr, src, parserOpts, PRINT) // errors are already printed
+	return r
+}
+
+// Eval parses and evaluates the statements of src one after the other and returns the value
+// of the last one. Visible values are printed, if PRINT is set. Syntax errors and failures of
+// the evaluator stop the evaluation, they are printed as well and returned as error.
+func (ev *Evaluator) Eval(filename string, src interface{}, parserOpts parser.Mode, PRINT bool) (SEXPItf, error) {
 	var returnExpression SEXPItf
+	DEBUG := ev.Debug
 
 	fset := token.NewFileSet() // positions are relative to fset
 
-	p, errp := parser.ParseInit(fset, *filePtr, src, parserOpts)
-	if errp != nil {
-		panic(errp)
-	}
-	ev, erre := EvalInit(fset, *filePtr, src, parser.AllErrors, TRACE, DEBUG)
-	if erre != nil {
-		panic(erre)
+	p, err := parser.ParseInit(fset, filename, src, parserOpts, ev.out, ev.log)
+	if err != nil {
+		fmt.Fprintf(ev.out, "Error: %v\n", err)
+		return nil, err
 	}
 
 	for true {
-		stmt, tok := parser.ParseIter(p) 	// main iterator calls parse.stmt
+		stmt, tok, err := parseNext(p)
+		if err == nil {
+			if _, ok := stmt.(*ast.BadStmt); ok {
+				err = errors.New("unexpected input")
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(ev.out, "Error: %v\n", err)
+			return returnExpression, err
+		}
 		if tok == token.EOF {
 			if DEBUG {
-				println("EOF token found")
+				ev.println("EOF token found")
 			}
 			break
 		}
 		if stmt==nil {
-			panic("EvalMain: stmt==nil")
+			err = errors.New("no statement")
+			fmt.Fprintf(ev.out, "Error: %v\n", err)
+			return returnExpression, err
+		}
+		sexp, err := evalToplevel(ev, stmt)
+		if err != nil {
+			fmt.Fprintf(ev.out, "Error: %v\n", err)
+			printWarnings(ev)
+			return returnExpression, err
 		}
-		sexp := EvalStmt(ev, stmt)
 		if sexp != nil {
 			if ev.Invisible { 				// invisibility is stored in the evaluator and is set during assignment
 				ev.Invisible = false		// unsetting invisiblity again
 			} else if PRINT{
-				PrintResult(sexp)
+				PrintResult(ev.out, sexp)
 			}
+		}
+		printWarnings(ev)
+		if sexp != nil {
 			returnExpression = sexp
 			if ev.state == eofState {
 				if DEBUG {
-					println("terminating...")
+					ev.println("terminating...")
 				}
 				break
 			}
 		}
 	}
-	return returnExpression
+	return returnExpression, nil
+}
+
+// panics of the parser and syntax errors are returned as error
+func parseNext(p *parser.Parser) (stmt ast.Stmt, tok token.Token, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			err = fmt.Errorf("%v", x)
+		}
+	}()
+	stmt, tok = parser.ParseIter(p) 	// main iterator calls parse.stmt
+	return stmt, tok, parser.Errors(p)
+}
+
+// errors of R are printed, other panics of the evaluator are returned as error, the evaluator
+// continues in the global frame
+func evalToplevel(ev *Evaluator, stmt ast.Stmt) (r SEXPItf, err error) {
+	defer func() {
+		if x := recover(); x != nil {
+			if c, ok := x.(*condition); ok {
+				fmt.Fprintln(ev.out, c)
+			} else {
+				err = fmt.Errorf("%v", x)
+			}
+			ev.topFrame = ev.globalFrame
+			ev.state = normalState
+			ev.Invisible = false
+			ev.indent = 0
+		}
+	}()
+	return EvalStmt(ev, stmt), nil
 }
@@ -0,0 +1,415 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"sort"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/approxfun.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/splinefun.html
+// The points are sorted and ties in x are combined as by R's regularize.values.
+// approxfun and splinefun return builtins, which keep the interpolation as Go closure.
+
+func init() {
+	registerBuiltin("approx", []string{"x", "y", "xout", "method", "n", "yleft", "yright", "rule", "f", "ties", "na.rm"}, EvalApprox)
+	registerBuiltin("approxfun", []string{"x", "y", "method", "yleft", "yright", "rule", "f", "ties", "na.rm"}, EvalApproxfun)
+	registerBuiltin("spline", []string{"x", "y", "n", "method", "xmin", "xmax", "xout", "ties"}, EvalSpline)
+	registerBuiltin("splinefun", []string{"x", "y", "method", "ties"}, EvalSplinefun)
+}
+
+// the points sorted by unique x, ties are combined by the function ties or by the mean with a warning
+func regularizeValues(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, y SEXPItf, ties SEXPItf, naRm bool) ([]float64, []float64, bool) {
+	warn := false
+	var xs, ys []float64
+	switch {
+	case x == nil:
+		builtinError(ev, funcname, "argument \"x\" is missing, with no default")
+		return nil, nil, false
+	case !isNull(y):
+		if !numericArgument(ev, funcname, "y", y) || !numericArgument(ev, funcname, "x", x) {
+			return nil, nil, false
+		}
+		xs, ys = asFloats(x, &warn), asFloats(y, &warn)
+		if len(xs) != len(ys) {
+			builtinError(ev, funcname, "'x' and 'y' lengths differ")
+			return nil, nil, false
+		}
+	case sexpType(x) == VECSXP && !isNull(listComponent(x.(*RSEXP), "x")) && !isNull(listComponent(x.(*RSEXP), "y")):
+		xs, ys = componentFloats(x.(*RSEXP), "x"), componentFloats(x.(*RSEXP), "y")
+	default:
+		if !numericArgument(ev, funcname, "x", x) {
+			return nil, nil, false
+		}
+		ys = asFloats(x, &warn)
+		xs = make([]float64, len(ys))
+		for k := range xs {
+			xs[k] = float64(k + 1)
+		}
+	}
+	px, py := []float64{}, []float64{}
+	for k := range xs {
+		switch {
+		case math.IsNaN(xs[k]) && !naRm:
+			builtinError(ev, funcname, "approx(x,y, .., na.rm=FALSE): NA values in x are not allowed")
+			return nil, nil, false
+		case (math.IsNaN(xs[k]) || math.IsNaN(ys[k])) && naRm:
+		default:
+			px, py = append(px, xs[k]), append(py, ys[k])
+		}
+	}
+	ordered := false
+	var combine *VSEXP
+	switch {
+	case ties == nil:
+	case sexpType(ties) == STRSXP && ties.Length() == 1 && asStrings(ties)[0] == "ordered":
+		return px, py, true
+	case sexpType(ties) == VECSXP && ties.Length() == 2 && isFunction(ties.(*RSEXP).Slice[1]):
+		t := ties.(*RSEXP).Slice
+		ordered = sexpType(t[0]) == STRSXP && asStrings(t[0])[0] == "ordered"
+		combine = t[1].(*VSEXP)
+	case isFunction(ties) || sexpType(ties) == STRSXP:
+		f, ok := matchFunction(ev, funcname, ties)
+		if !ok {
+			return nil, nil, false
+		}
+		combine = f
+	default:
+		builtinError(ev, funcname, "'ties' is not \"ordered\", a function, or list(<string>, <function>)")
+		return nil, nil, false
+	}
+	if !ordered && !sort.Float64sAreSorted(px) {
+		o := make([]int, len(px))
+		for k := range o {
+			o[k] = k
+		}
+		sort.SliceStable(o, func(i, j int) bool { return px[o[i]] < px[o[j]] })
+		sx, sy := make([]float64, len(o)), make([]float64, len(o))
+		for k, i := range o {
+			sx[k], sy[k] = px[i], py[i]
+		}
+		px, py = sx, sy
+	}
+	// the values of each x in the order of their first appearance
+	groups := map[float64][]float64{}
+	ux := []float64{}
+	for k, v := range px {
+		if _, ok := groups[v]; !ok {
+			ux = append(ux, v)
+		}
+		groups[v] = append(groups[v], py[k])
+	}
+	if len(ux) == len(px) {
+		return px, py, true
+	}
+	if ties == nil {
+		ev.warning(funcname+"()", "collapsing to unique 'x' values")
+	}
+	uy := make([]float64, len(ux))
+	for k, v := range ux {
+		if combine == nil {
+			uy[k] = calc.Mean(groups[v])
+			continue
+		}
+		r := callFunction(ev, node, funcname, combine, []SEXPItf{&VSEXP{ValuePos: node.Pos(), Slice: groups[v]}}, []string{""})
+		if isError(r) {
+			return nil, nil, false
+		}
+		uy[k] = asFloats(r, &warn)[0]
+	}
+	return ux, uy, true
+}
+
+// the interpolation of approx and approxfun
+type approximation struct {
+	x, y          []float64
+	constant      bool
+	yleft, yright float64
+	f             float64
+}
+
+func newApproximation(ev *Evaluator, node *ast.CallExpr, funcname string, args *Arguments, formals []string) (*approximation, bool) {
+	value := func(formal string) SEXPItf {
+		for k, f := range formals {
+			if f == formal {
+				return args.Values[k]
+			}
+		}
+		return nil
+	}
+	a := &approximation{}
+	if m := value("method"); m != nil {
+		switch matchName(asStrings(m)[0], []string{"linear", "constant"}, false) {
+		case 0:
+		case 1:
+			a.constant = true
+		default:
+			builtinError(ev, funcname, "invalid interpolation method")
+			return nil, false
+		}
+	}
+	warn := false
+	rule := []float64{1}
+	if r := value("rule"); r != nil {
+		rule = asFloats(r, &warn)
+		if sexpType(r) == STRSXP || len(rule) < 1 || len(rule) > 2 {
+			builtinError(ev, funcname, "is.numeric(rule) is not TRUE")
+			return nil, false
+		}
+	}
+	if len(rule) == 1 {
+		rule = append(rule, rule[0])
+	}
+	naRm := true
+	if v := value("na.rm"); v != nil {
+		naRm = asLogicals(v)[0] == TRUE
+	}
+	x, y, ok := regularizeValues(ev, node, funcname, args.Values[0], args.Values[1], value("ties"), naRm)
+	if !ok {
+		return nil, false
+	}
+	nx := 0
+	for _, v := range y {
+		if !math.IsNaN(v) {
+			nx++
+		}
+	}
+	if nx <= 1 {
+		if !a.constant {
+			builtinError(ev, funcname, "need at least two non-NA values to interpolate")
+			return nil, false
+		}
+		if nx == 0 {
+			builtinError(ev, funcname, "zero non-NA points")
+			return nil, false
+		}
+	}
+	a.x, a.y = x, y
+	a.yleft, a.yright = calc.NA, calc.NA
+	if rule[0] != 1 {
+		a.yleft = y[0]
+	}
+	if rule[1] != 1 {
+		a.yright = y[len(y)-1]
+	}
+	for _, bound := range []struct {
+		formal string
+		v      *float64
+	}{{"yleft", &a.yleft}, {"yright", &a.yright}, {"f", &a.f}} {
+		if v := value(bound.formal); v != nil {
+			if v.Length() != 1 {
+				builtinError(ev, funcname, "length(%s) == 1L is not TRUE", bound.formal)
+				return nil, false
+			}
+			*bound.v = asFloats(v, &warn)[0]
+		}
+	}
+	return a, true
+}
+
+func (a *approximation) values(v []float64) []float64 {
+	r := make([]float64, len(v))
+	for k, u := range v {
+		r[k] = numeric.Approx(u, a.x, a.y, a.constant, a.yleft, a.yright, a.f)
+	}
+	return r
+}
+
+// seq(from, to, length.out = n)
+func equallySpaced(from float64, to float64, n int) []float64 {
+	r := make([]float64, n)
+	by := (to - from) / float64(n-1)
+	for k := range r {
+		r[k] = from + float64(k)*by
+	}
+	if n > 1 {
+		r[n-1] = to
+	} else {
+		r[0] = from
+	}
+	return r
+}
+
+func pointsList(pos token.Pos, x []float64, y []float64) *RSEXP {
+	r := &RSEXP{ValuePos: pos, Slice: []SEXPItf{&VSEXP{ValuePos: pos, Slice: x}, &VSEXP{ValuePos: pos, Slice: y}}}
+	r.NamesSet([]string{"x", "y"})
+	return r
+}
+
+func EvalApprox(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	a, ok := newApproximation(ev, node, "approx", args, builtins["approx"].formals)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	var xout []float64
+	if v := args.Values[2]; v != nil {
+		xout = asFloats(v, &warn)
+	} else {
+		n := int(args.float(4, 50))
+		if n <= 0 {
+			return builtinError(ev, "approx", "'approx' requires n >= 1")
+		}
+		xout = equallySpaced(a.x[0], a.x[len(a.x)-1], n)
+	}
+	return pointsList(node.Fun.Pos(), xout, a.values(xout))
+}
+
+func EvalApproxfun(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	a, ok := newApproximation(ev, node, "approxfun", args, builtins["approxfun"].formals)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return &VSEXP{ValuePos: node.Fun.Pos(), builtin: &builtin{name: "approxfun", formals: []string{"v"}, fun: func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+		v := args.Values[0]
+		if !numericArgument(ev, "approxfun", "v", v) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		warn := false
+		return &VSEXP{ValuePos: node.Fun.Pos(), Slice: a.values(asFloats(v, &warn))}
+	}}}
+}
+
+// the methods of spline and splinefun by their numbers in R
+var splineMethods = []string{"periodic", "natural", "fmm", "monoH.FC", "hyman"}
+
+// the interpolating splines of spline and splinefun
+type interpolation struct {
+	spline  *numeric.Spline
+	hermite *numeric.Hermite
+}
+
+func newInterpolation(ev *Evaluator, node *ast.CallExpr, funcname string, method string, x SEXPItf, y SEXPItf, ties SEXPItf) (*interpolation, bool) {
+	xs, ys, ok := regularizeValues(ev, node, funcname, x, y, ties, true)
+	if !ok {
+		return nil, false
+	}
+	nx := len(xs)
+	if nx == 0 {
+		builtinError(ev, funcname, "zero non-NA points")
+		return nil, false
+	}
+	if method == "periodic" && ys[0] != ys[nx-1] {
+		ev.warning(funcname+"()", "spline: first and last y values differ - using y[1] for both")
+		ys[nx-1] = ys[0]
+	}
+	if method == "hyman" {
+		increasing, decreasing := true, true
+		for k := 1; k < nx; k++ {
+			increasing = increasing && ys[k] >= ys[k-1]
+			decreasing = decreasing && ys[k] <= ys[k-1]
+		}
+		if !increasing && !decreasing {
+			builtinError(ev, funcname, "'y' must be increasing or decreasing")
+			return nil, false
+		}
+	}
+	if method == "monoH.FC" {
+		if nx < 2 {
+			builtinError(ev, funcname, "n must be at least two")
+			return nil, false
+		}
+		n1 := nx - 1
+		sx := make([]float64, n1)
+		for k := range sx {
+			sx[k] = (ys[k+1] - ys[k]) / (xs[k+1] - xs[k])
+		}
+		m := make([]float64, nx)
+		m[0], m[n1] = sx[0], sx[n1-1]
+		for k := 1; k < n1; k++ {
+			m[k] = (sx[k] + sx[k-1]) / 2
+		}
+		numeric.MonoFC(m, sx)
+		return &interpolation{hermite: &numeric.Hermite{X: xs, Y: ys, M: m}}, true
+	}
+	k := matchName(method, splineMethods, true) + 1
+	s := numeric.NewSpline(int(math.Min(3, float64(k))), xs, ys)
+	if method == "hyman" {
+		s.Hyman()
+	}
+	return &interpolation{spline: s}, true
+}
+
+// the values of the derivative of order deriv at u
+func (s *interpolation) values(u []float64, deriv int) []float64 {
+	if s.hermite != nil {
+		return s.hermite.Eval(u, deriv, false)
+	}
+	r := s.spline.Derivative(deriv).Eval(u)
+	// the linear continuation of natural splines to the left
+	if deriv > 0 && s.spline.Method == numeric.SplineNatural {
+		for k, v := range u {
+			if v <= s.spline.X[0] {
+				r[k] = 0
+				if deriv == 1 {
+					r[k] = s.spline.B[0]
+				}
+			}
+		}
+	}
+	return r
+}
+
+func splineMethod(ev *Evaluator, funcname string, x SEXPItf, choices []string) (string, bool) {
+	if x != nil && sexpType(x) == STRSXP && x.Length() == 1 {
+		if k := matchName(asStrings(x)[0], choices, false); k >= 0 {
+			return choices[k], true
+		}
+		builtinError(ev, funcname, "invalid interpolation method")
+		return "", false
+	}
+	return choiceArgument(ev, funcname, x, choices)
+}
+
+func EvalSpline(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	method := "fmm"
+	if m := args.Values[3]; m != nil {
+		var ok bool
+		if method, ok = splineMethod(ev, "spline", m, []string{"periodic", "natural", "fmm", "hyman"}); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+	}
+	s, ok := newInterpolation(ev, node, "spline", method, args.Values[0], args.Values[1], args.Values[7])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	var xout []float64
+	if v := args.Values[6]; v != nil {
+		xout = asFloats(v, &warn)
+	} else {
+		x := s.spline.X
+		n := int(args.float(2, float64(3*len(x))))
+		if n <= 0 {
+			return builtinError(ev, "spline", "'spline' requires n >= 1")
+		}
+		xout = equallySpaced(args.float(4, x[0]), args.float(5, x[len(x)-1]), n)
+	}
+	return pointsList(node.Fun.Pos(), xout, s.values(xout, 0))
+}
+
+func EvalSplinefun(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	method, ok := splineMethod(ev, "splinefun", args.Values[2], []string{"fmm", "periodic", "natural", "monoH.FC", "hyman"})
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	s, ok := newInterpolation(ev, node, "splinefun", method, args.Values[0], args.Values[1], args.Values[3])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return &VSEXP{ValuePos: node.Fun.Pos(), builtin: &builtin{name: "splinefun", formals: []string{"x", "deriv"}, fun: func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+		x := args.Values[0]
+		if !numericArgument(ev, "splinefun", "x", x) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		deriv := int(args.float(1, 0))
+		if deriv < 0 || deriv > 3 {
+			return builtinError(ev, "splinefun", "'deriv' must be between 0 and 3")
+		}
+		warn := false
+		return &VSEXP{ValuePos: node.Fun.Pos(), Slice: s.values(asFloats(x, &warn), deriv)}
+	}}}
+}
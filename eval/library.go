@@ -0,0 +1,49 @@
+package eval
+
+import (
+	"roq/lib/ast"
+	"roq/lib/token"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/library.html
+// All builtins are available without attaching a package. library() and require()
+// only check, whether the package is one of those, which are part of roq.
+
+var packages = map[string]bool{
+	"base": true, "stats": true, "utils": true, "methods": true, "graphics": true,
+	"grDevices": true, "datasets": true, "parallel": true,
+}
+
+func EvalLibrary(ev *Evaluator, node *ast.CallExpr, funcname string) SEXPItf {
+	if len(node.Args) == 0 {
+		return builtinError(ev, funcname, "argument \"package\" is missing, with no default")
+	}
+	var name string
+	switch arg := node.Args[0].(type) {
+	case *ast.Ident:
+		name = arg.Name
+	case *ast.BasicLit:
+		if arg.Kind == token.IDENT {
+			name = arg.Value
+		} else {
+			name = EvalExpr(ev, arg).(*TSEXP).String
+		}
+	default:
+		v := EvalExpr(ev, arg)
+		if sexpType(v) != STRSXP || v.Length() != 1 {
+			return builtinError(ev, funcname, "'package' must be of length 1")
+		}
+		name = asStrings(v)[0]
+	}
+	ev.Invisible = true
+	if funcname == "library" {
+		if !packages[name] {
+			return builtinError(ev, "library", "there is no package called ‘%s’", name)
+		}
+		return &NSEXP{}
+	}
+	if !packages[name] {
+		ev.warning(funcname+"("+name+")", "there is no package called ‘"+name+"’")
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: logical(packages[name])}
+}
@@ -0,0 +1,516 @@
+package eval
+
+import (
+	"math"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/solve.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/qr.html
+// Decompositions of numeric matrices, computed in package numeric. Vectors are
+// taken as matrices with a single column. Decompositions are returned as lists
+// with the components of R, so that qr objects can be passed to qr.coef and friends.
+
+func init() {
+	registerBuiltin("solve", []string{"a", "b", "tol", "..."}, EvalSolve)
+	registerBuiltin("qr", []string{"x", "tol", "LAPACK", "..."}, EvalQr)
+	registerBuiltin("qr.coef", []string{"qr", "y"}, EvalQrCoef)
+	registerBuiltin("qr.qy", []string{"qr", "y"}, EvalQrQy)
+	registerBuiltin("qr.qty", []string{"qr", "y"}, EvalQrQty)
+	registerBuiltin("qr.fitted", []string{"qr", "y", "k"}, EvalQrFitted)
+	registerBuiltin("qr.resid", []string{"qr", "y"}, EvalQrResid)
+	registerBuiltin("qr.Q", []string{"qr", "complete"}, EvalQrQ)
+	registerBuiltin("qr.R", []string{"qr", "complete"}, EvalQrR)
+	registerBuiltin("chol", []string{"x", "pivot", "..."}, EvalChol)
+	registerBuiltin("chol2inv", []string{"x", "size", "LINPACK"}, EvalChol2inv)
+	registerBuiltin("svd", []string{"x", "nu", "nv", "LINPACK"}, EvalSvd)
+	registerBuiltin("eigen", []string{"x", "symmetric", "only.values", "EISPACK"}, EvalEigen)
+	registerBuiltin("determinant", []string{"x", "logarithm", "..."}, EvalDeterminant)
+	registerBuiltin("det", []string{"x", "..."}, EvalDet)
+	registerBuiltin("backsolve", []string{"r", "x", "k", "upper.tri", "transpose"}, EvalBacksolve)
+	registerBuiltin("forwardsolve", []string{"l", "x", "k", "upper.tri", "transpose"}, EvalForwardsolve)
+}
+
+// a numeric matrix with finite values, a vector gives a single column
+func matrixArgument(ev *Evaluator, funcname string, formal string, x SEXPItf) (*numeric.Matrix, bool) {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return nil, false
+	}
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+	default:
+		builtinError(ev, funcname, "'%s' must be a numeric matrix", formal)
+		return nil, false
+	}
+	warn := false
+	slice := append([]float64(nil), asFloats(x, &warn)...)
+	for _, v := range slice {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			builtinError(ev, funcname, "infinite or missing values in '%s'", formal)
+			return nil, false
+		}
+	}
+	if dim := x.Dim(); len(dim) == 2 {
+		return &numeric.Matrix{Rows: dim[0], Cols: dim[1], Data: slice}, true
+	}
+	return &numeric.Matrix{Rows: len(slice), Cols: 1, Data: slice}, true
+}
+
+func squareArgument(ev *Evaluator, funcname string, formal string, x SEXPItf) (*numeric.Matrix, bool) {
+	m, ok := matrixArgument(ev, funcname, formal, x)
+	if ok && m.Rows != m.Cols {
+		builtinError(ev, funcname, "'%s' must be a square matrix", formal)
+		return nil, false
+	}
+	return m, ok
+}
+
+// the rows and the columns are named if any names are given
+func matrixResult(pos token.Pos, m *numeric.Matrix, rownames []string, colnames []string) *VSEXP {
+	r := &VSEXP{ValuePos: pos, Slice: m.Data}
+	r.DimSet([]int{m.Rows, m.Cols})
+	if rownames != nil || colnames != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(rownames), namesOrNull(colnames)}})
+	}
+	return r
+}
+
+// a matrix for a matrix argument, otherwise the single column as vector
+func vectorOrMatrix(pos token.Pos, x SEXPItf, m *numeric.Matrix, rownames []string, colnames []string) *VSEXP {
+	if len(x.Dim()) == 2 {
+		return matrixResult(pos, m, rownames, colnames)
+	}
+	r := &VSEXP{ValuePos: pos, Slice: m.Data}
+	r.NamesSet(rownames)
+	return r
+}
+
+func listComponent(x SEXPItf, name string) SEXPItf {
+	if l, ok := x.(*RSEXP); ok {
+		if k := matchName(name, l.Names(), true); k >= 0 {
+			return l.Slice[k]
+		}
+	}
+	return nil
+}
+
+func classList(pos token.Pos, class string, names []string, values ...SEXPItf) *RSEXP {
+	r := &RSEXP{ValuePos: pos, Slice: values}
+	r.NamesSet(names)
+	if class != "" {
+		r.ClassSet(&class)
+	}
+	return r
+}
+
+func isQr(x SEXPItf) bool {
+	_, ok := x.(*RSEXP)
+	return ok && x.Class() != nil && *x.Class() == "qr" && listComponent(x, "qr") != nil
+}
+
+// the decomposition of a qr object
+func qrArgument(ev *Evaluator, funcname string, x SEXPItf, msg string) (*numeric.QR, SEXPItf, bool) {
+	if x == nil || !isQr(x) {
+		builtinError(ev, funcname, "%s", msg)
+		return nil, nil, false
+	}
+	qr := listComponent(x, "qr")
+	m, ok := matrixArgument(ev, funcname, "qr", qr)
+	if !ok {
+		return nil, nil, false
+	}
+	warn := false
+	d := &numeric.QR{QR: m, Qraux: asFloats(listComponent(x, "qraux"), &warn), Rank: asIntegers(listComponent(x, "rank"), &warn)[0]}
+	for _, p := range asIntegers(listComponent(x, "pivot"), &warn) {
+		d.Pivot = append(d.Pivot, p-1)
+	}
+	return d, qr, true
+}
+
+// the response of a qr function with as many rows as the decomposed matrix
+func qrResponse(ev *Evaluator, funcname string, d *numeric.QR, y SEXPItf) (*numeric.Matrix, bool) {
+	m, ok := matrixArgument(ev, funcname, "y", y)
+	if ok && m.Rows != d.QR.Rows {
+		builtinError(ev, funcname, "'qr' and 'y' must have the same number of rows")
+		return nil, false
+	}
+	return m, ok
+}
+
+func EvalSolve(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	a, b := args.Values[0], args.Values[1]
+	if a != nil && isQr(a) {
+		return solveQr(ev, node, a, b)
+	}
+	m, ok := matrixArgument(ev, "solve", "a", a)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if m.Rows != m.Cols {
+		return builtinError(ev, "solve", "'a' (%d x %d) must be square", m.Rows, m.Cols)
+	}
+	rhs := numeric.Identity(m.Rows, m.Rows)
+	colnames := dimnamesAt(a, 0)
+	if b != nil {
+		if rhs, ok = matrixArgument(ev, "solve", "b", b); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		if rhs.Rows != m.Rows {
+			return builtinError(ev, "solve", "'b' (%d x %d) must be compatible with 'a' (%d x %d)", rhs.Rows, rhs.Cols, m.Rows, m.Cols)
+		}
+		colnames = dimnamesAt(b, 1)
+	}
+	lu := numeric.NewLU(m)
+	if lu.Singular != 0 {
+		return builtinError(ev, "solve", "Lapack routine dgesv: system is exactly singular: U[%d,%d] = 0", lu.Singular, lu.Singular)
+	}
+	if rcond := lu.Rcond(m); rcond < args.float(2, 2.220446049250313e-16) {
+		return builtinError(ev, "solve", "system is computationally singular: reciprocal condition number = %.6g", rcond)
+	}
+	x := lu.Solve(rhs)
+	if b != nil {
+		return vectorOrMatrix(node.Fun.Pos(), b, x, dimnamesAt(a, 1), colnames)
+	}
+	return matrixResult(node.Fun.Pos(), x, dimnamesAt(a, 1), colnames)
+}
+
+// solve.qr
+func solveQr(ev *Evaluator, node *ast.CallExpr, a SEXPItf, b SEXPItf) SEXPItf {
+	d, qr, ok := qrArgument(ev, "solve", a, "")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if d.QR.Rows != d.QR.Cols {
+		return builtinError(ev, "solve", "only square matrices can be inverted")
+	}
+	if d.Rank < d.QR.Cols {
+		return builtinError(ev, "solve", "singular matrix 'a' in solve")
+	}
+	if b == nil {
+		return matrixResult(node.Fun.Pos(), d.Coef(numeric.Identity(d.QR.Rows, d.QR.Rows)), dimnamesAt(qr, 1), nil)
+	}
+	y, ok := qrResponse(ev, "solve", d, b)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return vectorOrMatrix(node.Fun.Pos(), b, d.Coef(y), dimnamesAt(qr, 1), dimnamesAt(b, 1))
+}
+
+func EvalQr(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	m, ok := matrixArgument(ev, "qr", "x", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if args.logical(2, false) {
+		return builtinError(ev, "qr", "LAPACK = TRUE is not supported")
+	}
+	d := numeric.NewQR(m, args.float(1, 1e-07))
+	return qrObject(node.Fun.Pos(), d, dimnamesAt(x, 0), dimnamesAt(x, 1))
+}
+
+// the decomposition with the column names in pivoted order
+func qrObject(pos token.Pos, d *numeric.QR, rownames []string, names []string) *RSEXP {
+	pivot := make([]int, len(d.Pivot))
+	var colnames []string
+	for k, p := range d.Pivot {
+		pivot[k] = p + 1
+		if names != nil {
+			colnames = append(colnames, names[p])
+		}
+	}
+	return classList(pos, "qr", []string{"qr", "rank", "qraux", "pivot"},
+		matrixResult(pos, d.QR, rownames, colnames),
+		&ISEXP{ValuePos: pos, Immediate: float64(d.Rank), Integer: d.Rank},
+		&VSEXP{ValuePos: pos, Slice: d.Qraux},
+		&ISEXP{ValuePos: pos, Slice: pivot})
+}
+
+// the coefficients of aliased columns are NA
+func EvalQrCoef(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	d, qr, ok := qrArgument(ev, "qr.coef", args.Values[0], "first argument must be a QR decomposition")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	y, ok := qrResponse(ev, "qr.coef", d, args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	var rownames []string
+	if names := dimnamesAt(qr, 1); names != nil {
+		rownames = make([]string, len(names))
+		for k, p := range d.Pivot {
+			rownames[p] = names[k]
+		}
+	}
+	return vectorOrMatrix(node.Fun.Pos(), args.Values[1], d.Coef(y), rownames, dimnamesAt(args.Values[1], 1))
+}
+
+func qrApply(ev *Evaluator, node *ast.CallExpr, funcname string, args *Arguments, f func(*numeric.QR, *numeric.Matrix) *numeric.Matrix) SEXPItf {
+	d, _, ok := qrArgument(ev, funcname, args.Values[0], "argument is not a QR decomposition")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	y, ok := qrResponse(ev, funcname, d, args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(args.Values) > 2 && !args.missing(2) {
+		d.Rank = int(args.float(2, float64(d.Rank)))
+	}
+	y0 := args.Values[1]
+	return vectorOrMatrix(node.Fun.Pos(), y0, f(d, y), dimnamesAt(y0, 0), dimnamesAt(y0, 1))
+}
+
+func EvalQrQy(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return qrApply(ev, node, "qr.qy", args, (*numeric.QR).Qy)
+}
+
+func EvalQrQty(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return qrApply(ev, node, "qr.qty", args, (*numeric.QR).Qty)
+}
+
+func EvalQrFitted(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return qrApply(ev, node, "qr.fitted", args, func(d *numeric.QR, y *numeric.Matrix) *numeric.Matrix {
+		return d.Fitted(y, false)
+	})
+}
+
+func EvalQrResid(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return qrApply(ev, node, "qr.resid", args, func(d *numeric.QR, y *numeric.Matrix) *numeric.Matrix {
+		return d.Fitted(y, true)
+	})
+}
+
+// the first min(n, p) columns of Q, all of them if complete
+func EvalQrQ(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	d, _, ok := qrArgument(ev, "qr.Q", args.Values[0], "argument is not a QR decomposition")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	n := d.QR.Rows
+	cols := min(n, d.QR.Cols)
+	if args.logical(1, false) {
+		cols = n
+	}
+	return matrixResult(node.Fun.Pos(), d.Qy(numeric.Identity(n, cols)), nil, nil)
+}
+
+// the upper triangle of the first min(n, p) rows, all rows if complete
+func EvalQrR(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	d, qr, ok := qrArgument(ev, "qr.R", args.Values[0], "argument is not a QR decomposition")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	rows := min(d.QR.Rows, d.QR.Cols)
+	if args.logical(1, false) {
+		rows = d.QR.Rows
+	}
+	r := numeric.NewMatrix(rows, d.QR.Cols)
+	for j := 0; j < r.Cols; j++ {
+		for i := 0; i <= j && i < rows; i++ {
+			r.Set(i, j, d.QR.At(i, j))
+		}
+	}
+	rownames := dimnamesAt(qr, 0)
+	if rownames != nil {
+		rownames = rownames[:rows]
+	}
+	return matrixResult(node.Fun.Pos(), r, rownames, dimnamesAt(qr, 1))
+}
+
+// only the upper triangle of x is used
+func EvalChol(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	m, ok := squareArgument(ev, "chol", "a", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if args.logical(1, false) {
+		return builtinError(ev, "chol", "pivoting is not supported")
+	}
+	r, minor := numeric.Cholesky(m)
+	if minor != 0 {
+		return builtinError(ev, "chol", "the leading minor of order %d is not positive", minor)
+	}
+	return matrixResult(node.Fun.Pos(), r, dimnamesAt(x, 0), dimnamesAt(x, 1))
+}
+
+func EvalChol2inv(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	m, ok := matrixArgument(ev, "chol2inv", "x", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	size := int(args.float(1, float64(m.Cols)))
+	switch {
+	case size > m.Cols:
+		return builtinError(ev, "chol2inv", "'size' cannot exceed ncol(x) = %d", m.Cols)
+	case size > m.Rows:
+		return builtinError(ev, "chol2inv", "'size' cannot exceed nrow(x) = %d", m.Rows)
+	}
+	for k := 0; k < size; k++ {
+		if m.At(k, k) == 0 {
+			return builtinError(ev, "chol2inv", "element (%d, %d) is zero, so the inverse cannot be computed", k+1, k+1)
+		}
+	}
+	return matrixResult(node.Fun.Pos(), numeric.Chol2inv(m, size), nil, nil)
+}
+
+// u and v are left out for nu = 0 and nv = 0, more than min(n, p) vectors complete the basis
+func EvalSvd(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	m, ok := matrixArgument(ev, "svd", "x", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if m.Rows == 0 || m.Cols == 0 {
+		return builtinError(ev, "svd", "a dimension is zero")
+	}
+	np := min(m.Rows, m.Cols)
+	nu := int(args.float(1, float64(np)))
+	nv := int(args.float(2, float64(np)))
+	if nu < 0 || nu > m.Rows {
+		return builtinError(ev, "svd", "invalid 'nu' argument")
+	}
+	if nv < 0 || nv > m.Cols {
+		return builtinError(ev, "svd", "invalid 'nv' argument")
+	}
+	d := numeric.NewSVD(m)
+	pos := node.Fun.Pos()
+	names := []string{"d"}
+	values := []SEXPItf{&VSEXP{ValuePos: pos, Slice: d.D}}
+	vectors := func(name string, v *numeric.Matrix, k int) {
+		if k == 0 {
+			return
+		}
+		if k > np {
+			v = numeric.CompleteBasis(v, k)
+		}
+		v.Cols = k
+		v.Data = v.Data[:v.Rows*k]
+		names = append(names, name)
+		values = append(values, matrixResult(pos, v, nil, nil))
+	}
+	vectors("u", d.U, nu)
+	vectors("v", d.V, nv)
+	return classList(pos, "", names, values...)
+}
+
+// symmetric matrices are recognized if not specified, complex values give complex vectors
+func EvalEigen(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	m, ok := matrixArgument(ev, "eigen", "x", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if m.Rows != m.Cols {
+		return builtinError(ev, "eigen", "non-square matrix in 'eigen'")
+	}
+	pos := node.Fun.Pos()
+	n := m.Rows
+	var values, vectors SEXPItf
+	if args.logical(1, m.IsSymmetric(100*2.220446049250313e-16)) {
+		d, v := numeric.SymmetricEigen(m)
+		values, vectors = &VSEXP{ValuePos: pos, Slice: d}, matrixResult(pos, v, nil, nil)
+	} else {
+		d, v := numeric.Eigen(m)
+		isReal := true
+		for _, z := range d {
+			isReal = isReal && imag(z) == 0
+		}
+		if isReal {
+			slice := make([]float64, n)
+			data := make([]float64, 0, n*n)
+			for k, z := range d {
+				slice[k] = real(z)
+				for _, c := range v[k] {
+					data = append(data, real(c))
+				}
+			}
+			values = &VSEXP{ValuePos: pos, Slice: slice}
+			vectors = matrixResult(pos, &numeric.Matrix{Rows: n, Cols: n, Data: data}, nil, nil)
+		} else {
+			data := make([]complex128, 0, n*n)
+			for _, col := range v {
+				data = append(data, col...)
+			}
+			c := &CSEXP{ValuePos: pos, Slice: data}
+			c.DimSet([]int{n, n})
+			values, vectors = &CSEXP{ValuePos: pos, Slice: d}, c
+		}
+	}
+	if args.logical(2, false) {
+		vectors = &NSEXP{}
+	}
+	return classList(pos, "eigen", []string{"values", "vectors"}, values, vectors)
+}
+
+// the logarithm of the modulus and the sign, from the LU decomposition
+func determinant(ev *Evaluator, funcname string, x SEXPItf) (float64, int, bool) {
+	m, ok := squareArgument(ev, funcname, "a", x)
+	if !ok {
+		return 0, 0, false
+	}
+	lu := numeric.NewLU(m)
+	if lu.Singular != 0 {
+		return math.Inf(-1), 1, true
+	}
+	modulus, sign := lu.LogDet()
+	return modulus, int(sign), true
+}
+
+func EvalDeterminant(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	modulus, sign, ok := determinant(ev, "determinant", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if !args.logical(1, true) {
+		modulus = math.Exp(modulus)
+	}
+	pos := node.Fun.Pos()
+	return classList(pos, "det", []string{"modulus", "sign"},
+		&VSEXP{ValuePos: pos, Immediate: modulus},
+		&ISEXP{ValuePos: pos, Immediate: float64(sign), Integer: sign})
+}
+
+func EvalDet(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	modulus, sign, ok := determinant(ev, "det", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return &VSEXP{ValuePos: node.Fun.Pos(), Immediate: float64(sign) * math.Exp(modulus)}
+}
+
+// the triangular systems of backsolve and forwardsolve, which only differ in the default of upper.tri
+func triangularSolve(ev *Evaluator, node *ast.CallExpr, args *Arguments, formal string, upper bool) SEXPItf {
+	r, ok := matrixArgument(ev, "backsolve", formal, args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	x := args.Values[1]
+	b, ok := matrixArgument(ev, "backsolve", "x", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	k := int(args.float(2, float64(r.Cols)))
+	if k <= 0 || k > r.Rows || k > r.Cols {
+		return builtinError(ev, "backsolve", "invalid 'k' argument")
+	}
+	if k > b.Rows {
+		return builtinError(ev, "backsolve", "invalid argument values in 'backsolve'")
+	}
+	s, zero := numeric.TriangularSolve(r, b, k, args.logical(3, upper), args.logical(4, false))
+	if zero != 0 {
+		return builtinError(ev, "backsolve", "singular matrix in 'backsolve'. First zero in diagonal [%d]", zero)
+	}
+	return vectorOrMatrix(node.Fun.Pos(), x, s, nil, nil)
+}
+
+func EvalBacksolve(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return triangularSolve(ev, node, args, "r", true)
+}
+
+func EvalForwardsolve(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return triangularSolve(ev, node, args, "l", false)
+}
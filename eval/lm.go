@@ -0,0 +1,871 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/lm.html
+// Linear models are fitted by the QR decomposition of the model matrix, weighted by the
+// square roots of the prior weights. The fit is a list of class "lm" with the components
+// of R, which coef, residuals, fitted, predict, anova and summary take apart. As there is
+// no dispatch on classes, these functions handle lm and glm objects themselves.
+
+func init() {
+	registerBuiltin("lm", []string{"formula", "data", "subset", "weights", "na.action", "..."}, EvalLm)
+	registerBuiltin("coef", []string{"object", "..."}, EvalCoef)
+	registerBuiltin("coefficients", []string{"object", "..."}, EvalCoef)
+	registerBuiltin("residuals", []string{"object", "type", "..."}, EvalResiduals)
+	registerBuiltin("resid", []string{"object", "type", "..."}, EvalResiduals)
+	registerBuiltin("fitted", []string{"object", "..."}, EvalFitted)
+	registerBuiltin("fitted.values", []string{"object", "..."}, EvalFitted)
+	registerBuiltin("predict", []string{"object", "newdata", "type", "..."}, EvalPredict)
+	registerBuiltin("anova", []string{"object", "...", "test"}, EvalAnova)
+	registerBuiltin("summary", []string{"object", "..."}, EvalSummary)
+	registerPrintMethod("lm", printLm)
+	registerPrintMethod("summary.lm", printSummaryLm)
+	registerPrintMethod("anova", printAnova)
+}
+
+// the call with all arguments named by the formals they match, as match.call
+func matchedCall(node *ast.CallExpr, formals []string) *QSEXP {
+	filled := map[string]bool{}
+	tags := make([]string, len(node.Args))
+	for n, a := range node.Args {
+		if t, ok := a.(*ast.TaggedExpr); ok {
+			tags[n] = t.Tag
+			if k := matchName(t.Tag, formals, false); k >= 0 && formals[k] != "..." {
+				tags[n] = formals[k]
+			}
+			filled[tags[n]] = true
+		}
+	}
+	call := &ast.CallExpr{Fun: node.Fun, Left: node.Left, Right: node.Right}
+	k := 0
+	for n, a := range node.Args {
+		if t, ok := a.(*ast.TaggedExpr); ok {
+			call.Args = append(call.Args, &ast.TaggedExpr{X: t.X, Tag: tags[n], OpPos: t.OpPos, Rhs: t.Rhs})
+			continue
+		}
+		for k < len(formals) && formals[k] != "..." && filled[formals[k]] {
+			k++
+		}
+		if k < len(formals) && formals[k] != "..." {
+			call.Args = append(call.Args, &ast.TaggedExpr{X: &ast.Ident{NamePos: a.Pos(), Name: formals[k]}, Tag: formals[k], OpPos: a.Pos(), Rhs: a})
+			k++
+		} else {
+			call.Args = append(call.Args, a)
+		}
+	}
+	return &QSEXP{ValuePos: node.Fun.Pos(), X: call}
+}
+
+func deparseCall(call SEXPItf) string {
+	if q, ok := call.(*QSEXP); ok {
+		if x, ok := q.X.(ast.Expr); ok {
+			return deparse(x)
+		}
+	}
+	return "NULL"
+}
+
+// the response, the model matrix and the prior weights of a formula
+type linearModel struct {
+	frame   *modelFrame
+	x       *numeric.Matrix
+	names   []string // of the columns
+	assign  []int    // the terms of the columns, 0 for the intercept
+	xlevels map[string][]string
+	y       []float64
+	weights []float64 // nil without prior weights
+}
+
+func newLinearModel(ev *Evaluator, funcname string, formula SEXPItf, data SEXPItf, subset SEXPItf, weights SEXPItf, na SEXPItf) (*linearModel, bool) {
+	f, ok := formulaArgument(ev, funcname, "formula", formula)
+	if !ok {
+		return nil, false
+	}
+	mf, ok := newModelFrame(ev, funcname, f, data, subset, na)
+	if !ok {
+		return nil, false
+	}
+	if !mf.terms.response {
+		builtinError(ev, funcname, "the formula needs a response")
+		return nil, false
+	}
+	y := mf.columns[0]
+	if sexpType(y) != REALSXP && sexpType(y) != INTSXP && sexpType(y) != LGLSXP {
+		builtinError(ev, funcname, "invalid type (%s) of the response", typeName(sexpType(y)))
+		return nil, false
+	}
+	m := &linearModel{frame: mf, xlevels: mf.xlevels()}
+	warn := false
+	m.y = asFloats(y, &warn)
+	if m.x, m.names, m.assign, ok = mf.design(ev, funcname, m.xlevels); !ok {
+		return nil, false
+	}
+	if weights != nil && sexpType(weights) != NILSXP {
+		w := asFloats(weights, &warn)
+		m.weights = make([]float64, len(mf.rows))
+		for k, row := range mf.rows {
+			if row >= len(w) || math.IsNaN(w[row]) || w[row] < 0 {
+				builtinError(ev, funcname, "missing or negative weights not allowed")
+				return nil, false
+			}
+			m.weights[k] = w[row]
+		}
+	}
+	return m, true
+}
+
+// a weighted least squares fit, coefficients of aliased columns are NaN
+type leastSquares struct {
+	qr        *numeric.QR
+	coef      []float64
+	fitted    []float64
+	residuals []float64
+	effects   []float64
+}
+
+func fitLeastSquares(x *numeric.Matrix, y []float64, w []float64, tol float64) *leastSquares {
+	xw := x.Clone()
+	yw := &numeric.Matrix{Rows: len(y), Cols: 1, Data: append([]float64(nil), y...)}
+	if w != nil {
+		for i, wi := range w {
+			s := math.Sqrt(wi)
+			yw.Data[i] *= s
+			for j := 0; j < xw.Cols; j++ {
+				xw.Set(i, j, xw.At(i, j)*s)
+			}
+		}
+	}
+	r := &leastSquares{qr: numeric.NewQR(xw, tol)}
+	r.coef = r.qr.Coef(yw).Data
+	r.effects = r.qr.Qty(yw).Data
+	r.fitted = linearPredictor(x, r.coef)
+	r.residuals = make([]float64, len(y))
+	for i := range y {
+		r.residuals[i] = y[i] - r.fitted[i]
+	}
+	return r
+}
+
+// x times the coefficients, where aliased ones count as zero
+func linearPredictor(x *numeric.Matrix, coef []float64) []float64 {
+	r := make([]float64, x.Rows)
+	for j, b := range coef {
+		if math.IsNaN(b) {
+			continue
+		}
+		for i, v := range x.Col(j) {
+			r[i] += v * b
+		}
+	}
+	return r
+}
+
+func namedFloats(pos token.Pos, values []float64, names []string) *VSEXP {
+	r := &VSEXP{ValuePos: pos, Slice: values}
+	r.NamesSet(names)
+	return r
+}
+
+func integerValue(pos token.Pos, v int) *ISEXP {
+	return &ISEXP{ValuePos: pos, Immediate: float64(v), Integer: v}
+}
+
+// the missing value for aliased coefficients
+func naCoefficients(coef []float64) []float64 {
+	r := make([]float64, len(coef))
+	for k, b := range coef {
+		if math.IsNaN(b) {
+			b = calc.NA
+		}
+		r[k] = b
+	}
+	return r
+}
+
+// the names of the effects are those of the columns within the rank
+func effectNames(d *numeric.QR, names []string) []string {
+	r := make([]string, d.QR.Rows)
+	for k, p := range d.Pivot {
+		if k < d.Rank && k < len(r) {
+			r[k] = names[p]
+		}
+	}
+	return r
+}
+
+func xlevelsList(pos token.Pos, m *linearModel) *RSEXP {
+	var names []string
+	var values []SEXPItf
+	for _, label := range m.frame.terms.labels {
+		if levels, ok := m.xlevels[label]; ok {
+			names = append(names, label)
+			values = append(values, &TSEXP{ValuePos: pos, Slice: levels})
+		}
+	}
+	r := &RSEXP{ValuePos: pos, Slice: append([]SEXPItf{}, values...)}
+	r.NamesSet(names)
+	return r
+}
+
+func EvalLm(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	m, ok := newLinearModel(ev, "lm", args.Values[0], args.Values[1], args.Values[2], args.Values[3], args.Values[4])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	pos := node.Fun.Pos()
+	fit := fitLeastSquares(m.x, m.y, m.weights, 1e-07)
+	rownames := m.frame.rownames()
+	names := []string{"coefficients", "residuals", "effects", "rank", "fitted.values"}
+	values := []SEXPItf{
+		namedFloats(pos, naCoefficients(fit.coef), m.names),
+		namedFloats(pos, fit.residuals, rownames),
+		namedFloats(pos, fit.effects, effectNames(fit.qr, m.names)),
+		integerValue(pos, fit.qr.Rank),
+		namedFloats(pos, fit.fitted, rownames),
+	}
+	if m.weights != nil {
+		names = append(names, "weights")
+		values = append(values, namedFloats(pos, m.weights, rownames))
+	}
+	names = append(names, "assign", "qr", "df.residual", "xlevels", "call", "terms", "model")
+	values = append(values,
+		&ISEXP{ValuePos: pos, Slice: m.assign},
+		qrObject(pos, fit.qr, rownames, m.names),
+		integerValue(pos, m.x.Rows-fit.qr.Rank),
+		xlevelsList(pos, m),
+		matchedCall(node, []string{"formula", "data", "subset", "weights", "na.action", "..."}),
+		m.frame.terms.object(args.Values[0].(*QSEXP)),
+		m.frame.list(pos))
+	return classList(pos, "lm", names, values...)
+}
+
+func isModel(x SEXPItf) bool {
+	_, ok := x.(*RSEXP)
+	return ok && x.Class() != nil && (*x.Class() == "lm" || *x.Class() == "glm")
+}
+
+func modelArgument(ev *Evaluator, funcname string, x SEXPItf) (*RSEXP, bool) {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"object\" is missing, with no default")
+		return nil, false
+	}
+	if !isModel(x) {
+		builtinError(ev, funcname, "no applicable method for '%s' applied to an object of class \"%s\"", funcname, classOf(x))
+		return nil, false
+	}
+	return x.(*RSEXP), true
+}
+
+// the first class of an object as class() shows it
+func classOf(x SEXPItf) string {
+	if x.Class() != nil {
+		return *x.Class()
+	}
+	switch sexpType(x) {
+	case REALSXP:
+		return "numeric"
+	case CLOSXP, BUILTINSXP:
+		return "function"
+	}
+	return typeName(sexpType(x))
+}
+
+func componentFloats(x SEXPItf, name string) []float64 {
+	c := listComponent(x, name)
+	if c == nil || !isAtomic(c) {
+		return nil
+	}
+	warn := false
+	return asFloats(c, &warn)
+}
+
+func componentInteger(x SEXPItf, name string) int {
+	c := listComponent(x, name)
+	if c == nil || !isAtomic(c) || c.Length() == 0 {
+		return 0
+	}
+	warn := false
+	return asIntegers(c, &warn)[0]
+}
+
+func EvalCoef(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "coef", "argument \"object\" is missing, with no default")
+	}
+	if c := listComponent(x, "coefficients"); c != nil {
+		return c
+	}
+	return &NSEXP{ValuePos: node.Fun.Pos()}
+}
+
+func EvalFitted(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "fitted", "argument \"object\" is missing, with no default")
+	}
+	if c := listComponent(x, "fitted.values"); c != nil {
+		return c
+	}
+	return &NSEXP{ValuePos: node.Fun.Pos()}
+}
+
+func EvalResiduals(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "residuals", "argument \"object\" is missing, with no default")
+	}
+	if !isModel(x) {
+		if c := listComponent(x, "residuals"); c != nil {
+			return c
+		}
+		return &NSEXP{ValuePos: node.Fun.Pos()}
+	}
+	types := []string{"working", "response", "deviance", "pearson", "partial"}
+	t := "deviance"
+	if v := args.Values[1]; v != nil && sexpType(v) == STRSXP && v.Length() > 0 {
+		k := matchName(asStrings(v)[0], types, false)
+		if k < 0 || types[k] == "partial" {
+			return builtinError(ev, "residuals", "'arg' should be one of “working”, “response”, “deviance”, “pearson”")
+		}
+		t = types[k]
+	}
+	r := listComponent(x, "residuals")
+	if *x.Class() == "glm" {
+		return glmResiduals(ev, x.(*RSEXP), t)
+	}
+	if w := componentFloats(x, "weights"); w != nil && (t == "deviance" || t == "pearson") {
+		warn := false
+		res := asFloats(r, &warn)
+		values := make([]float64, len(res))
+		for k := range res {
+			values[k] = res[k] * math.Sqrt(w[k])
+		}
+		return namedFloats(node.Fun.Pos(), values, r.Names())
+	}
+	return r
+}
+
+// the model frame of new data for the terms of a fit without the response
+func newDataFrame(ev *Evaluator, funcname string, object *RSEXP, newdata SEXPItf) (*linearModel, bool) {
+	terms, ok := listComponent(object, "terms").(*QSEXP)
+	if !ok {
+		builtinError(ev, funcname, "the object has no terms")
+		return nil, false
+	}
+	_, rhs := formulaSides(terms)
+	f := &QSEXP{ValuePos: terms.ValuePos, X: &ast.UnaryExpr{OpPos: terms.ValuePos, Op: token.TILDE, X: rhs}, Frame: terms.Frame}
+	mf, ok := newModelFrame(ev, funcname, f, newdata, nil, &TSEXP{String: "na.pass"})
+	if !ok {
+		return nil, false
+	}
+	m := &linearModel{frame: mf, xlevels: map[string][]string{}}
+	if l, ok := listComponent(object, "xlevels").(*RSEXP); ok {
+		for k, name := range l.Names() {
+			m.xlevels[name] = asStrings(l.Slice[k])
+		}
+	}
+	if m.x, m.names, m.assign, ok = mf.design(ev, funcname, m.xlevels); !ok {
+		return nil, false
+	}
+	return m, true
+}
+
+func EvalPredict(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if x := args.Values[0]; x != nil && classOf(x) == "smooth.spline" {
+		deriv := 0.
+		for k, name := range args.DotNames {
+			if name == "deriv" || name == "" && k == 0 {
+				warn := false
+				deriv = asFloats(args.Dots[k], &warn)[0]
+			}
+		}
+		return predictSmoothSpline(ev, node.Fun.Pos(), x.(*RSEXP), args.Values[1], int(deriv))
+	}
+	object, ok := modelArgument(ev, "predict", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	glm := *object.Class() == "glm"
+	response := false
+	if v := args.Values[2]; v != nil && sexpType(v) == STRSXP && v.Length() > 0 {
+		types := []string{"link", "response", "terms"}
+		k := matchName(asStrings(v)[0], types, false)
+		if k < 0 || k == 2 {
+			return builtinError(ev, "predict", "'type' should be one of “link”, “response”")
+		}
+		response = k == 1
+	}
+	if args.Values[1] == nil || sexpType(args.Values[1]) == NILSXP {
+		if glm && !response {
+			return listComponent(object, "linear.predictors")
+		}
+		return listComponent(object, "fitted.values")
+	}
+	m, ok := newDataFrame(ev, "predict", object, args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	coef := componentFloats(object, "coefficients")
+	if len(coef) != m.x.Cols {
+		return builtinError(ev, "predict", "the model matrix of 'newdata' does not match the coefficients")
+	}
+	for _, b := range coef {
+		if math.IsNaN(b) {
+			ev.warning("", "prediction from rank-deficient fit; attr(*, \"non-estim\") has doubtful cases")
+			break
+		}
+	}
+	eta := linearPredictor(m.x, coef)
+	if glm && response {
+		family := familyOf(object)
+		for k, v := range eta {
+			eta[k] = family.linkinv(v)
+		}
+	}
+	return namedFloats(node.Fun.Pos(), eta, m.frame.rownames())
+}
+
+func EvalSummary(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	object, ok := modelArgument(ev, "summary", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if *object.Class() == "glm" {
+		return summaryGlm(ev, node.Fun.Pos(), object)
+	}
+	return summaryLm(ev, node.Fun.Pos(), object)
+}
+
+// the qr decomposition of a fit and the variance of the coefficients up to the dispersion
+func unscaledCovariance(ev *Evaluator, funcname string, object *RSEXP) (*numeric.QR, *numeric.Matrix, bool) {
+	d, _, ok := qrArgument(ev, funcname, listComponent(object, "qr"), "the object has no QR decomposition")
+	if !ok {
+		return nil, nil, false
+	}
+	return d, numeric.Chol2inv(d.QR, d.Rank), true
+}
+
+// the coefficient table in pivoted order: estimates, standard errors, test statistics and p-values
+func coefficientMatrix(pos token.Pos, object *RSEXP, d *numeric.QR, cov *numeric.Matrix, dispersion float64, df float64, z bool) *VSEXP {
+	coef := componentFloats(object, "coefficients")
+	names := listComponent(object, "coefficients").Names()
+	p := d.Rank
+	m := numeric.NewMatrix(p, 4)
+	rownames := make([]string, p)
+	for k := 0; k < p; k++ {
+		est := coef[d.Pivot[k]]
+		se := math.Sqrt(cov.At(k, k) * dispersion)
+		t := est / se
+		m.Set(k, 0, est)
+		m.Set(k, 1, se)
+		m.Set(k, 2, t)
+		if z {
+			m.Set(k, 3, 2*numeric.Pnorm(-math.Abs(t), 0, 1, true, false))
+		} else {
+			m.Set(k, 3, 2*numeric.Pt(-math.Abs(t), df, true, false))
+		}
+		rownames[k] = names[d.Pivot[k]]
+	}
+	colnames := []string{"Estimate", "Std. Error", "t value", "Pr(>|t|)"}
+	if z {
+		colnames = []string{"Estimate", "Std. Error", "z value", "Pr(>|z|)"}
+	}
+	return matrixResult(pos, m, rownames, colnames)
+}
+
+func aliased(pos token.Pos, object *RSEXP) *LSEXP {
+	c := listComponent(object, "coefficients")
+	warn := false
+	values := asFloats(c, &warn)
+	slice := make([]int, len(values))
+	for k, b := range values {
+		slice[k] = logical(math.IsNaN(b))
+	}
+	r := &LSEXP{ValuePos: pos, Slice: slice}
+	r.NamesSet(c.Names())
+	return r
+}
+
+func hasIntercept(object *RSEXP) bool {
+	if terms, ok := listComponent(object, "terms").(*QSEXP); ok && terms.Attributes != nil {
+		if v := listComponent(terms.Attributes, "intercept"); v != nil {
+			warn := false
+			return asIntegers(v, &warn)[0] == 1
+		}
+	}
+	return false
+}
+
+func summaryLm(ev *Evaluator, pos token.Pos, object *RSEXP) SEXPItf {
+	d, cov, ok := unscaledCovariance(ev, "summary.lm", object)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := componentFloats(object, "residuals")
+	f := componentFloats(object, "fitted.values")
+	w := componentFloats(object, "weights")
+	weight := func(k int) float64 {
+		if w == nil {
+			return 1
+		}
+		return w[k]
+	}
+	p := d.Rank
+	rdf := float64(componentInteger(object, "df.residual"))
+	intercept := hasIntercept(object)
+	mss, rss, mean, sw := 0., 0., 0., 0.
+	if intercept {
+		for k := range f {
+			mean += weight(k) * f[k]
+			sw += weight(k)
+		}
+		mean /= sw
+	}
+	residuals := make([]float64, len(r))
+	for k := range r {
+		mss += weight(k) * (f[k] - mean) * (f[k] - mean)
+		rss += weight(k) * r[k] * r[k]
+		residuals[k] = r[k] * math.Sqrt(weight(k))
+	}
+	resvar := rss / rdf
+	names := []string{"call", "terms", "residuals", "coefficients", "aliased", "sigma", "df", "r.squared", "adj.r.squared"}
+	dfInt := 0.
+	if intercept {
+		dfInt = 1
+	}
+	rsquared, adjusted := 0., 0.
+	if float64(p) != dfInt {
+		rsquared = mss / (mss + rss)
+		adjusted = 1 - (1-rsquared)*((float64(len(r))-dfInt)/rdf)
+	}
+	values := []SEXPItf{
+		listComponent(object, "call"),
+		listComponent(object, "terms"),
+		namedFloats(pos, residuals, listComponent(object, "residuals").Names()),
+		coefficientMatrix(pos, object, d, cov, resvar, rdf, false),
+		aliased(pos, object),
+		&VSEXP{ValuePos: pos, Immediate: math.Sqrt(resvar)},
+		&ISEXP{ValuePos: pos, Slice: []int{p, int(rdf), d.QR.Cols}},
+		&VSEXP{ValuePos: pos, Immediate: rsquared},
+		&VSEXP{ValuePos: pos, Immediate: adjusted},
+	}
+	if float64(p) != dfInt {
+		names = append(names, "fstatistic")
+		values = append(values, namedFloats(pos, []float64{(mss / (float64(p) - dfInt)) / resvar, float64(p) - dfInt, rdf}, []string{"value", "numdf", "dendf"}))
+	}
+	names = append(names, "cov.unscaled")
+	values = append(values, matrixResult(pos, cov, nil, nil))
+	if w != nil {
+		names = append(names, "weights")
+		values = append(values, listComponent(object, "weights"))
+	}
+	return classList(pos, "summary.lm", names, values...)
+}
+
+// the digits of R's print methods for models, max(3, getOption("digits") - 3)
+const modelDigits = 4
+
+func printCall(w io.Writer, x *RSEXP, sep string) {
+	fmt.Fprintf(w, "\nCall:%s%s\n\n", sep, deparseCall(listComponent(x, "call")))
+}
+
+func printCoefficients(w io.Writer, x *RSEXP) {
+	c := listComponent(x, "coefficients")
+	if c == nil || c.Length() == 0 {
+		fmt.Fprintln(w, "No coefficients")
+		return
+	}
+	warn := false
+	printNamedColumns(w, c.Names(), formatReal(asFloats(c, &warn), modelDigits), 2)
+}
+
+func printLm(w io.Writer, x *RSEXP) {
+	printCall(w, x, "\n")
+	fmt.Fprintln(w, "Coefficients:")
+	printCoefficients(w, x)
+	fmt.Fprintln(w)
+}
+
+// the coefficient table with rows of NA for aliased coefficients
+func printCoefficientTable(w io.Writer, x *RSEXP) {
+	coefs := listComponent(x, "coefficients")
+	al := listComponent(x, "aliased")
+	if al == nil || al.Length() == 0 {
+		fmt.Fprintln(w, "\nNo Coefficients")
+		return
+	}
+	warn := false
+	df := asIntegers(listComponent(x, "df"), &warn)
+	if df[2] > df[0] {
+		fmt.Fprintf(w, "Coefficients: (%d not defined because of singularities)\n", df[2]-df[0])
+	} else {
+		fmt.Fprintln(w, "Coefficients:")
+	}
+	values := asFloats(coefs, &warn)
+	rownames := dimnamesAt(coefs, 0)
+	t := &coefTable{
+		rownames:  al.Names(),
+		colnames:  dimnamesAt(coefs, 1),
+		columns:   make([][]float64, 4),
+		digits:    modelDigits,
+		estimates: []int{0, 1},
+		tests:     []int{2},
+		pvalue:    true,
+		naPrint:   "NA",
+	}
+	for j := range t.columns {
+		t.columns[j] = make([]float64, len(t.rownames))
+		for k, name := range t.rownames {
+			t.columns[j][k] = math.NaN()
+			if i := matchName(name, rownames, true); i >= 0 {
+				t.columns[j][k] = values[i+j*len(rownames)]
+			}
+		}
+	}
+	t.print(w)
+}
+
+func printSummaryLm(w io.Writer, x *RSEXP) {
+	printCall(w, x, "\n")
+	warn := false
+	r := listComponent(x, "residuals")
+	residuals := asFloats(r, &warn)
+	df := asIntegers(listComponent(x, "df"), &warn)
+	rdf := df[1]
+	if listComponent(x, "weights") != nil {
+		fmt.Fprint(w, "Weighted ")
+	}
+	fmt.Fprintln(w, "Residuals:")
+	switch {
+	case rdf > 5:
+		sorted := append([]float64(nil), residuals...)
+		sort.Float64s(sorted)
+		q := zapsmall(quantiles(sorted, []float64{0, 0.25, 0.5, 0.75, 1}, 7), modelDigits+1)
+		printNamedColumns(w, []string{"Min", "1Q", "Median", "3Q", "Max"}, formatReal(q, modelDigits), 1)
+	case rdf > 0:
+		printNamedColumns(w, r.Names(), formatReal(residuals, modelDigits), 1)
+	default:
+		fmt.Fprintf(w, "ALL %d residuals are 0: no residual degrees of freedom!\n", df[0])
+	}
+	fmt.Fprintln(w)
+	printCoefficientTable(w, x)
+	sigma := componentFloats(x, "sigma")[0]
+	fmt.Fprintf(w, "\nResidual standard error: %s on %d degrees of freedom\n", formatReal([]float64{signif(sigma, modelDigits)}, 7)[0], rdf)
+	if f := componentFloats(x, "fstatistic"); f != nil {
+		fmt.Fprintf(w, "Multiple R-squared:  %s,\tAdjusted R-squared:  %s\n",
+			formatC(componentFloats(x, "r.squared")[0], modelDigits), formatC(componentFloats(x, "adj.r.squared")[0], modelDigits))
+		fmt.Fprintf(w, "F-statistic: %s on %s and %s DF,  p-value: %s\n", formatC(f[0], modelDigits), formatFloat(f[1]), formatFloat(f[2]),
+			formatPval([]float64{numeric.Pf(f[0], f[1], f[2], false, false)}, modelDigits)[0])
+	}
+	fmt.Fprintln(w)
+}
+
+// the weighted sum of squared residuals of a linear model
+func residualSumOfSquares(object *RSEXP) float64 {
+	ssr := 0.
+	w := componentFloats(object, "weights")
+	for k, r := range componentFloats(object, "residuals") {
+		if w != nil {
+			r *= math.Sqrt(w[k])
+		}
+		ssr += r * r
+	}
+	return ssr
+}
+
+// sequential sums of squares of the terms of a linear model
+func anovaLm(ev *Evaluator, pos token.Pos, object *RSEXP) SEXPItf {
+	d, _, ok := qrArgument(ev, "anova", listComponent(object, "qr"), "the object has no QR decomposition")
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	effects := componentFloats(object, "effects")
+	warn := false
+	assign := asIntegers(listComponent(object, "assign"), &warn)
+	terms := listComponent(object, "terms").(*QSEXP)
+	labels := append([]string{"(Intercept)"}, asStrings(listComponent(terms.Attributes, "term.labels"))...)
+	ss := map[int]float64{}
+	df := map[int]int{}
+	var order []int
+	for k := 0; k < d.Rank; k++ {
+		a := assign[d.Pivot[k]]
+		if _, ok := df[a]; !ok {
+			order = append(order, a)
+		}
+		ss[a] += effects[k] * effects[k]
+		df[a]++
+	}
+	sort.Ints(order)
+	ssr := residualSumOfSquares(object)
+	dfr := float64(componentInteger(object, "df.residual"))
+	var rownames []string
+	columns := make([][]float64, 5)
+	for _, a := range order {
+		if a == 0 && hasIntercept(object) {
+			continue
+		}
+		ms := ss[a] / float64(df[a])
+		f := ms / (ssr / dfr)
+		rownames = append(rownames, labels[a])
+		columns[0] = append(columns[0], float64(df[a]))
+		columns[1] = append(columns[1], ss[a])
+		columns[2] = append(columns[2], ms)
+		columns[3] = append(columns[3], f)
+		columns[4] = append(columns[4], numeric.Pf(f, float64(df[a]), dfr, false, false))
+	}
+	rownames = append(rownames, "Residuals")
+	columns[0] = append(columns[0], dfr)
+	columns[1] = append(columns[1], ssr)
+	columns[2] = append(columns[2], ssr/dfr)
+	columns[3] = append(columns[3], calc.NA)
+	columns[4] = append(columns[4], calc.NA)
+	lhs, _ := formulaSides(terms)
+	heading := []string{"Analysis of Variance Table\n", "Response: " + deparse(lhs)}
+	return anovaTable(pos, heading, rownames, []string{"Df", "Sum Sq", "Mean Sq", "F value", "Pr(>F)"}, columns)
+}
+
+// a list of columns with the class anova, whose heading and row names are attributes
+func anovaTable(pos token.Pos, heading []string, rownames []string, colnames []string, columns [][]float64) *RSEXP {
+	values := make([]SEXPItf, len(columns))
+	for k, c := range columns {
+		values[k] = &VSEXP{ValuePos: pos, Slice: c}
+	}
+	r := classList(pos, "anova", colnames, values...)
+	r.Attributes = &RSEXP{ValuePos: pos, Slice: []SEXPItf{&TSEXP{ValuePos: pos, Slice: heading}, &TSEXP{ValuePos: pos, Slice: rownames}}}
+	r.Attributes.NamesSet([]string{"heading", "row.names"})
+	return r
+}
+
+// the comparison of nested models by the changes of their residual sums of squares or
+// deviances, tested against the scale of the model with the least residual degrees of freedom
+func anovaModels(ev *Evaluator, pos token.Pos, models []*RSEXP, test SEXPItf) SEXPItf {
+	class := *models[0].Class()
+	n := len(componentFloats(models[0], "residuals"))
+	var rownames, formulas []string
+	resdf, resdev := make([]float64, len(models)), make([]float64, len(models))
+	big := 0
+	for k, m := range models {
+		if *m.Class() != class {
+			return builtinError(ev, "anova", "models must all be of class \"%s\"", class)
+		}
+		if len(componentFloats(m, "residuals")) != n {
+			return builtinError(ev, "anova", "models were not all fitted to the same size of dataset")
+		}
+		rownames = append(rownames, strconv.Itoa(k+1))
+		formulas = append(formulas, fmt.Sprintf("Model %d: %s", k+1, deparse(listComponent(m, "terms").(*QSEXP).X.(ast.Expr))))
+		resdf[k] = float64(componentInteger(m, "df.residual"))
+		if class == "glm" {
+			resdev[k] = componentFloats(m, "deviance")[0]
+		} else {
+			resdev[k] = residualSumOfSquares(m)
+		}
+		if resdf[k] < resdf[big] {
+			big = k
+		}
+	}
+	t, ok := anovaTest(ev, test, models[big])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	df, dev := []float64{calc.NA}, []float64{calc.NA}
+	for k := 1; k < len(models); k++ {
+		df = append(df, resdf[k-1]-resdf[k])
+		dev = append(dev, resdev[k-1]-resdev[k])
+	}
+	heading := []string{"Analysis of Variance Table\n", strings.Join(formulas, "\n")}
+	colnames := []string{"Res.Df", "RSS", "Df", "Sum of Sq"}
+	scale := resdev[big] / resdf[big]
+	if class == "glm" {
+		heading[0] = "Analysis of Deviance Table\n"
+		colnames = []string{"Resid. Df", "Resid. Dev", "Df", "Deviance"}
+		scale = glmDispersion(models[big])
+	}
+	columns := [][]float64{resdf, resdev, df, dev}
+	switch t {
+	case "Chisq":
+		p := []float64{calc.NA}
+		for k := 1; k < len(models); k++ {
+			p = append(p, numeric.Pchisq(dev[k]/scale, math.Abs(df[k]), false, false))
+		}
+		columns = append(columns, p)
+		colnames = append(colnames, "Pr(>Chi)")
+	case "F":
+		fs, p := []float64{calc.NA}, []float64{calc.NA}
+		for k := 1; k < len(models); k++ {
+			f := calc.NA
+			if df[k] != 0 {
+				f = dev[k] / df[k] / scale
+			}
+			fs = append(fs, f)
+			p = append(p, numeric.Pf(f, math.Abs(df[k]), resdf[big], false, false))
+		}
+		columns = append(columns, fs, p)
+		colnames = append(colnames, "F", "Pr(>F)")
+	}
+	return anovaTable(pos, heading, rownames, colnames, columns)
+}
+
+func EvalAnova(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	object, ok := modelArgument(ev, "anova", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if len(args.Dots) > 0 {
+		models := []*RSEXP{object}
+		for _, x := range args.Dots {
+			m, ok := modelArgument(ev, "anova", x)
+			if !ok {
+				return &ESEXP{Kind: token.ILLEGAL}
+			}
+			models = append(models, m)
+		}
+		return anovaModels(ev, node.Fun.Pos(), models, args.Values[2])
+	}
+	if *object.Class() == "glm" {
+		return anovaGlm(ev, node.Fun.Pos(), object, args.Values[2])
+	}
+	return anovaLm(ev, node.Fun.Pos(), object)
+}
+
+// as print.anova: the test statistics and p-values identified by the column names
+func printAnova(w io.Writer, x *RSEXP) {
+	if x.Attributes == nil {
+		PrintResultR(w, x)
+		return
+	}
+	for _, line := range asStrings(listComponent(x.Attributes, "heading")) {
+		fmt.Fprintln(w, line)
+	}
+	t := &coefTable{
+		rownames: asStrings(listComponent(x.Attributes, "row.names")),
+		colnames: x.Names(),
+		digits:   5,
+		naPrint:  "",
+	}
+	warn := false
+	for k, name := range t.colnames {
+		t.columns = append(t.columns, asFloats(x.Slice[k], &warn))
+		switch {
+		case k == len(t.colnames)-1 && strings.HasPrefix(name, "Pr("):
+			t.pvalue = true
+		case strings.HasSuffix(name, " value") || name == "F" || name == "Cp" || name == "Chisq":
+			t.tests = append(t.tests, k)
+		case !strings.HasSuffix(name, "Df"):
+			t.zap = append(t.zap, k)
+		}
+	}
+	t.print(w)
+}
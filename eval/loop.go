@@ -10,7 +10,7 @@ func EvalLoop(ev *Evaluator, e *ast.BlockStmt, cond ast.Expr) SEXPItf {
 	evloop = *ev
 	evloop.state = loopState
 	var rstate LoopState
-	for cond == nil || isTrue(EvalExpr(&evloop, cond)) {
+	for cond == nil || isTrue(ev, EvalExpr(&evloop, cond)) {
 		evloop.state = loopState
 		for n := 0; n < len(e.List); n++ {
 			EvalStmt(&evloop, e.List[n])
@@ -32,10 +32,11 @@ func EvalLoop(ev *Evaluator, e *ast.BlockStmt, cond ast.Expr) SEXPItf {
 
 func EvalFor(ev *Evaluator, e *ast.BlockStmt, identifier string, iterable SEXPItf) SEXPItf {
 	switch iterable.(type) {
-	case *VSEXP:
+	case *VSEXP, *ISEXP, *LSEXP, *CSEXP, *TSEXP:
 		return EvalForLoopOverVector(ev, e, identifier, iterable)
-	case *TSEXP:
-		return EvalForLoopOverStringArray(ev, e, identifier, iterable)
+	case *NSEXP:
+		ev.Invisible = true
+		return &NSEXP{}
 	case *RSEXP:
 		return EvalForLoopOverList(ev, e, identifier, iterable)
 	default:
@@ -74,50 +75,17 @@ func EvalForLoopOverList(ev *Evaluator, e *ast.BlockStmt, identifier string, ite
 	return &NSEXP{}
 }
 
+// the loop variable is a scalar of the type of the vector
 func EvalForLoopOverVector(ev *Evaluator, e *ast.BlockStmt, identifier string, iterable SEXPItf) SEXPItf {
-	if iterable.(*VSEXP).Slice == nil {
-		panic("Vector expected\n")
-	}
 	defer un(trace(ev, "LoopOverVector"))
 	var evloop Evaluator
 	evloop = *ev
 	evloop.state = loopState
 	var rstate LoopState
-	for _, v := range iterable.(*VSEXP).Slice {
-		evloop.state = loopState
-		// TODO: make use of cached position in map
-		ev.topFrame.Insert(identifier, &VSEXP{Immediate: v})
-		for n := 0; n < len(e.List); n++ {
-			EvalStmt(&evloop, e.List[n])
-			rstate = evloop.state
-			if rstate == nextState {
-				break
-			}
-		}
-		if rstate == nextState {
-			continue
-		}
-		if rstate == breakState {
-			break
-		}
-	}
-	ev.Invisible = true
-	return &NSEXP{}
-}
-
-func EvalForLoopOverStringArray(ev *Evaluator, e *ast.BlockStmt, identifier string, iterable SEXPItf) SEXPItf {
-	if iterable.(*TSEXP).Slice == nil {
-		panic("String array expected\n")
-	}
-	defer un(trace(ev, "LoopOverStringArray"))
-	var evloop Evaluator
-	evloop = *ev
-	evloop.state = loopState
-	var rstate LoopState
-	for _, v := range iterable.(*TSEXP).Slice {
+	for n := 0; n < iterable.Length(); n++ {
 		evloop.state = loopState
 		// TODO: make use of cached position in map
-		ev.topFrame.Insert(identifier, &TSEXP{String: v})
+		ev.topFrame.Insert(identifier, listElement(ev, iterable, n))
 		for n := 0; n < len(e.List); n++ {
 			EvalStmt(&evloop, e.List[n])
 			rstate = evloop.state
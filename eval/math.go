@@ -0,0 +1,333 @@
+package eval
+
+import (
+	"math"
+	"math/cmplx"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/groupGeneric.html
+// Mathematical functions work elementwise on numbers and keep the attributes of their argument.
+// Integers and logicals are used as doubles, some functions accept complex numbers.
+// A NaN as result of a number gives a warning.
+
+var mathFunctions = map[string]func(float64) float64{
+	"sqrt":       math.Sqrt,
+	"exp":        math.Exp,
+	"log1p":      math.Log1p,
+	"expm1":      math.Expm1,
+	"log2":       math.Log2,
+	"log10":      math.Log10,
+	"sin":        math.Sin,
+	"cos":        math.Cos,
+	"tan":        math.Tan,
+	"asin":       math.Asin,
+	"acos":       math.Acos,
+	"atan":       math.Atan,
+	"sinh":       math.Sinh,
+	"cosh":       math.Cosh,
+	"tanh":       math.Tanh,
+	"asinh":      math.Asinh,
+	"acosh":      math.Acosh,
+	"atanh":      math.Atanh,
+	"sign":       calc.Sign,
+	"floor":      math.Floor,
+	"ceiling":    math.Ceil,
+	"trunc":      math.Trunc,
+	"gamma":      calc.Gamma,
+	"lgamma":     calc.Lgamma,
+	"digamma":    calc.Digamma,
+	"factorial":  calc.Factorial,
+	"lfactorial": calc.Lfactorial,
+}
+
+var complexFunctions = map[string]func(complex128) complex128{
+	"sqrt":  cmplx.Sqrt,
+	"exp":   cmplx.Exp,
+	"sin":   cmplx.Sin,
+	"cos":   cmplx.Cos,
+	"tan":   cmplx.Tan,
+	"asin":  cmplx.Asin,
+	"acos":  cmplx.Acos,
+	"atan":  cmplx.Atan,
+	"sinh":  cmplx.Sinh,
+	"cosh":  cmplx.Cosh,
+	"tanh":  cmplx.Tanh,
+	"asinh": cmplx.Asinh,
+	"acosh": cmplx.Acosh,
+	"atanh": cmplx.Atanh,
+}
+
+// the parts of complex numbers, numbers have the imaginary part zero
+var complexParts = map[string]func(complex128) float64{
+	"Re":  func(z complex128) float64 { return real(z) },
+	"Im":  func(z complex128) float64 { return imag(z) },
+	"Mod": cmplx.Abs,
+	"Arg": cmplx.Phase,
+}
+
+func init() {
+	for name, f := range complexParts {
+		name, f := name, f
+		registerBuiltin(name, []string{"z"}, func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			return EvalComplexPart(ev, node, name, args.Values[0], f)
+		})
+	}
+	registerBuiltin("Conj", []string{"z"}, EvalConj)
+	for name, f := range mathFunctions {
+		name, f := name, f
+		registerBuiltin(name, []string{"x"}, func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			return EvalMath(ev, node, name, args.Values[0], f, complexFunctions[name])
+		})
+	}
+	registerBuiltin("abs", []string{"x"}, EvalAbs)
+	registerBuiltin("log", []string{"x", "base"}, EvalLog)
+	registerBuiltin("round", []string{"x", "digits"}, EvalRound)
+	registerBuiltin("signif", []string{"x", "digits"}, EvalSignif)
+	registerBuiltin("beta", []string{"a", "b"}, EvalBeta)
+	registerBuiltin("lbeta", []string{"a", "b"}, EvalLbeta)
+	registerBuiltin("choose", []string{"n", "k"}, EvalChoose)
+	registerBuiltin("lchoose", []string{"n", "k"}, EvalLchoose)
+}
+
+func mathArgument(ev *Evaluator, funcname string, formal string, x SEXPItf, complexAllowed bool) bool {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return false
+	}
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+		return true
+	case CPLXSXP:
+		if complexAllowed {
+			return true
+		}
+		builtinError(ev, funcname, "unimplemented complex function")
+		return false
+	default:
+		builtinError(ev, funcname, "non-numeric argument to mathematical function")
+		return false
+	}
+}
+
+// missing values stay missing, NaN produced from a number is reported
+func mapFloats(ev *Evaluator, slice []float64, f func(float64) float64) []float64 {
+	r := make([]float64, len(slice))
+	nan := false
+	for n, v := range slice {
+		if calc.IsNA(v) {
+			r[n] = calc.NA
+			continue
+		}
+		r[n] = f(v)
+		nan = nan || (math.IsNaN(r[n]) && !math.IsNaN(v))
+	}
+	if nan {
+		ev.warning("", "NaNs produced")
+	}
+	return r
+}
+
+func mapComplexes(slice []complex128, f func(complex128) complex128) []complex128 {
+	r := make([]complex128, len(slice))
+	for n, v := range slice {
+		if isNAComplex(v) {
+			r[n] = naComplex
+		} else {
+			r[n] = f(v)
+		}
+	}
+	return r
+}
+
+// the result has the attributes of x
+func mathResult(x SEXPItf, r SEXPItf) SEXPItf {
+	r.NamesSet(x.Names())
+	r.DimSet(x.Dim())
+	r.DimnamesSet(x.Dimnames())
+	return r
+}
+
+func newFloats(pos token.Pos, slice []float64, scalar bool) *VSEXP {
+	if scalar && len(slice) == 1 {
+		return &VSEXP{ValuePos: pos, Immediate: slice[0]}
+	}
+	return &VSEXP{ValuePos: pos, Slice: slice}
+}
+
+func EvalMath(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, f func(float64) float64, fc func(complex128) complex128) SEXPItf {
+	if !mathArgument(ev, funcname, "x", x, fc != nil) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	pos := node.Fun.Pos()
+	warn := false
+	if sexpType(x) == CPLXSXP {
+		slice := mapComplexes(asComplexes(x, &warn), fc)
+		if isScalar(x) {
+			return mathResult(x, &CSEXP{ValuePos: pos, Immediate: slice[0]})
+		}
+		return mathResult(x, &CSEXP{ValuePos: pos, Slice: slice})
+	}
+	return mathResult(x, newFloats(pos, mapFloats(ev, asFloats(x, &warn), f), isScalar(x)))
+}
+
+func EvalComplexPart(ev *Evaluator, node *ast.CallExpr, funcname string, z SEXPItf, f func(complex128) float64) SEXPItf {
+	if !mathArgument(ev, funcname, "z", z, true) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	slice := asComplexes(z, &warn)
+	r := make([]float64, len(slice))
+	for n, v := range slice {
+		if isNAComplex(v) {
+			r[n] = calc.NA
+		} else {
+			r[n] = f(v)
+		}
+	}
+	return mathResult(z, newFloats(node.Fun.Pos(), r, isScalar(z)))
+}
+
+// numbers are their own conjugates
+func EvalConj(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	z := args.Values[0]
+	if !mathArgument(ev, "Conj", "z", z, true) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if sexpType(z) != CPLXSXP {
+		return z
+	}
+	return EvalMath(ev, node, "Conj", z, nil, cmplx.Conj)
+}
+
+// integers stay integers, complex numbers give their modulus
+func EvalAbs(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !mathArgument(ev, "abs", "x", x, true) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	pos := node.Fun.Pos()
+	warn := false
+	switch sexpType(x) {
+	case LGLSXP, INTSXP:
+		slice := asIntegers(x, &warn)
+		r := make([]int, len(slice))
+		for n, v := range slice {
+			if v < 0 && v != NA_INTEGER {
+				v = -v
+			}
+			r[n] = v
+		}
+		if isScalar(x) {
+			return mathResult(x, &ISEXP{ValuePos: pos, Immediate: float64(r[0]), Integer: r[0]})
+		}
+		return mathResult(x, &ISEXP{ValuePos: pos, Slice: r})
+	case CPLXSXP:
+		slice := asComplexes(x, &warn)
+		r := make([]float64, len(slice))
+		for n, v := range slice {
+			if isNAComplex(v) {
+				r[n] = calc.NA
+			} else {
+				r[n] = cmplx.Abs(v)
+			}
+		}
+		return mathResult(x, newFloats(pos, r, isScalar(x)))
+	default:
+		return mathResult(x, newFloats(pos, mapFloats(ev, asFloats(x, &warn), math.Abs), isScalar(x)))
+	}
+}
+
+// both arguments are recycled as for arithmetic operators
+func EvalMath2(ev *Evaluator, node *ast.CallExpr, funcname string, formals [2]string, x SEXPItf, y SEXPItf, f func(float64, float64) float64) SEXPItf {
+	if !mathArgument(ev, funcname, formals[0], x, false) || !mathArgument(ev, funcname, formals[1], y, false) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	a, b := asFloats(x, &warn), asFloats(y, &warn)
+	r := make([]float64, o.length)
+	nan := false
+	for n := range r {
+		u, v := a[n%len(a)], b[n%len(b)]
+		if calc.IsNA(u) || calc.IsNA(v) {
+			r[n] = calc.NA
+			continue
+		}
+		r[n] = f(u, v)
+		nan = nan || (math.IsNaN(r[n]) && !math.IsNaN(u) && !math.IsNaN(v))
+	}
+	if nan {
+		ev.warning("", "NaNs produced")
+	}
+	return o.setAttributes(newFloats(node.Fun.Pos(), r, isScalar(x) && isScalar(y)))
+}
+
+func EvalLog(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if args.missing(1) {
+		return EvalMath(ev, node, "log", x, math.Log, cmplx.Log)
+	}
+	if sexpType(x) == CPLXSXP || sexpType(args.Values[1]) == CPLXSXP {
+		if !mathArgument(ev, "log", "x", x, true) || !mathArgument(ev, "log", "base", args.Values[1], true) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		warn := false
+		base := cmplx.Log(asComplexes(args.Values[1], &warn)[0])
+		return EvalMath(ev, node, "log", coerceVector(ev, x, CPLXSXP), nil, func(z complex128) complex128 { return cmplx.Log(z) / base })
+	}
+	return EvalMath2(ev, node, "log", [2]string{"x", "base"}, x, args.Values[1], func(x float64, base float64) float64 {
+		if base == 10 {
+			return math.Log10(x)
+		} else if base == 2 {
+			return math.Log2(x)
+		}
+		return math.Log(x) / math.Log(base)
+	})
+}
+
+func EvalRound(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	digits := args.Values[1]
+	if digits == nil {
+		digits = &VSEXP{Immediate: 0}
+	}
+	if x := args.Values[0]; sexpType(x) == CPLXSXP {
+		// both parts are rounded
+		d := args.float(1, 0)
+		return EvalMath(ev, node, "round", x, nil, func(z complex128) complex128 {
+			return complex(calc.Round(real(z), d), calc.Round(imag(z), d))
+		})
+	}
+	return EvalMath2(ev, node, "round", [2]string{"x", "digits"}, args.Values[0], digits, calc.Round)
+}
+
+func EvalSignif(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	digits := args.Values[1]
+	if digits == nil {
+		digits = &VSEXP{Immediate: 6}
+	}
+	return EvalMath2(ev, node, "signif", [2]string{"x", "digits"}, args.Values[0], digits, calc.Signif)
+}
+
+func EvalBeta(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return EvalMath2(ev, node, "beta", [2]string{"a", "b"}, args.Values[0], args.Values[1], calc.Beta)
+}
+
+func EvalLbeta(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return EvalMath2(ev, node, "lbeta", [2]string{"a", "b"}, args.Values[0], args.Values[1], calc.Lbeta)
+}
+
+func EvalChoose(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return EvalMath2(ev, node, "choose", [2]string{"n", "k"}, args.Values[0], args.Values[1], calc.Choose)
+}
+
+func EvalLchoose(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return EvalMath2(ev, node, "lchoose", [2]string{"n", "k"}, args.Values[0], args.Values[1], func(n float64, k float64) float64 {
+		return math.Log(math.Abs(calc.Choose(n, k)))
+	})
+}
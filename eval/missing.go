@@ -0,0 +1,211 @@
+package eval
+
+import (
+	"math"
+	"math/cmplx"
+	"roq/calc"
+	"roq/lib/ast"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/NA.html
+// Missing values are typed: NA is logical, NA_integer_, NA_real_ and NA_character_ are
+// of the other atomic types. NaN is a double, which is not a number, but not missing.
+// is.na is TRUE for both, is.nan only for NaN.
+
+func init() {
+	registerBuiltin("is.nan", []string{"x"}, EvalIsNaN)
+	registerBuiltin("anyNA", []string{"x", "recursive"}, EvalAnyNA)
+	registerBuiltin("na.omit", []string{"object", "..."}, EvalNaOmit)
+	registerBuiltin("na.fail", []string{"object", "..."}, EvalNaFail)
+	registerBuiltin("na.pass", []string{"object", "..."}, EvalNaPass)
+	registerBuiltin("complete.cases", []string{"..."}, EvalCompleteCases)
+}
+
+func EvalIsNaN(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "is.nan", "argument \"x\" is missing, with no default")
+	}
+	if !isAtomic(x) && sexpType(x) != NILSXP {
+		return builtinError(ev, "is.nan", "default method not implemented for type '%s'", typeName(sexpType(x)))
+	}
+	slice := make([]int, x.Length())
+	switch x.(type) {
+	case *VSEXP:
+		for n, v := range floatSlice(x.(*VSEXP)) {
+			slice[n] = logical(math.IsNaN(v) && !calc.IsNA(v))
+		}
+	case *CSEXP:
+		for n, v := range complexSlice(x.(*CSEXP)) {
+			slice[n] = logical(cmplx.IsNaN(v) && !isNAComplex(v))
+		}
+	}
+	var r *LSEXP
+	if isScalar(x) {
+		r = &LSEXP{ValuePos: node.Fun.Pos(), Immediate: slice[0]}
+	} else {
+		r = &LSEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+	}
+	r.NamesSet(x.Names())
+	r.DimSet(x.Dim())
+	r.DimnamesSet(x.Dimnames())
+	return r
+}
+
+func anyMissing(x SEXPItf, recursive bool) bool {
+	if list, ok := x.(*RSEXP); ok {
+		for _, v := range list.Slice {
+			if (recursive || (isAtomic(v) && v.Length() == 1)) && anyMissing(v, recursive) {
+				return true
+			}
+		}
+		return false
+	}
+	if !isAtomic(x) {
+		return false
+	}
+	for _, v := range logicalSlice(isNA(x).(*LSEXP)) {
+		if v == TRUE {
+			return true
+		}
+	}
+	return false
+}
+
+func EvalAnyNA(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "anyNA", "argument \"x\" is missing, with no default")
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Immediate: logical(anyMissing(x, args.logical(1, false)))}
+}
+
+// complete rows of a vector, a matrix or the columns of a list
+func markIncomplete(x SEXPItf, complete []bool) bool {
+	if list, ok := x.(*RSEXP); ok {
+		for _, v := range list.Slice {
+			if !markIncomplete(v, complete) {
+				return false
+			}
+		}
+		return true
+	}
+	rows := x.Length()
+	if dim := x.Dim(); len(dim) == 2 {
+		rows = dim[0]
+	}
+	if rows != len(complete) {
+		return false
+	}
+	for n, v := range logicalSlice(isNA(x).(*LSEXP)) {
+		if v == TRUE {
+			complete[n%rows] = false
+		}
+	}
+	return true
+}
+
+func caseCount(x SEXPItf) int {
+	if list, ok := x.(*RSEXP); ok {
+		if len(list.Slice) == 0 {
+			return 0
+		}
+		return caseCount(list.Slice[0])
+	}
+	if dim := x.Dim(); len(dim) == 2 {
+		return dim[0]
+	}
+	return x.Length()
+}
+
+func EvalCompleteCases(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if len(args.Dots) == 0 {
+		return builtinError(ev, "complete.cases", "no input has determined the number of cases")
+	}
+	complete := make([]bool, caseCount(args.Dots[0]))
+	for n := range complete {
+		complete[n] = true
+	}
+	for _, x := range args.Dots {
+		if !isAtomic(x) && sexpType(x) != VECSXP {
+			return builtinError(ev, "complete.cases", "invalid 'type' (%s) of argument", typeName(sexpType(x)))
+		}
+		if !markIncomplete(x, complete) {
+			return builtinError(ev, "complete.cases", "not all arguments have the same length")
+		}
+	}
+	slice := make([]int, len(complete))
+	for n, c := range complete {
+		slice[n] = logical(c)
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Slice: slice}
+}
+
+// vectors drop missing elements, matrices drop rows with missing values
+// the attribute na.action is not set
+func EvalNaOmit(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "na.omit", "argument \"object\" is missing, with no default")
+	}
+	if !isAtomic(x) {
+		return x
+	}
+	dim := x.Dim()
+	rows := x.Length()
+	if len(dim) == 2 {
+		rows = dim[0]
+	}
+	complete := make([]bool, rows)
+	for n := range complete {
+		complete[n] = true
+	}
+	markIncomplete(x, complete)
+	var kept []int
+	for n, c := range complete {
+		if c {
+			kept = append(kept, n)
+		}
+	}
+	if len(dim) != 2 {
+		r := indexElements(ev, x, kept)
+		r.NamesSet(selectNames(x.Names(), kept))
+		return r
+	}
+	offsets := make([]int, 0, len(kept)*dim[1])
+	for col := 0; col < dim[1]; col++ {
+		for _, row := range kept {
+			offsets = append(offsets, row+col*rows)
+		}
+	}
+	r := indexElements(ev, x, offsets)
+	r.DimSet([]int{len(kept), dim[1]})
+	if x.Dimnames() != nil {
+		rownames := dimnamesAt(x, 0)
+		dimnames := &RSEXP{Slice: append([]SEXPItf(nil), x.Dimnames().Slice...)}
+		if rownames != nil {
+			dimnames.Slice[0] = &TSEXP{Slice: selectNames(rownames, kept)}
+		}
+		r.DimnamesSet(dimnames)
+	}
+	return r
+}
+
+func EvalNaFail(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "na.fail", "argument \"object\" is missing, with no default")
+	}
+	if anyMissing(x, true) {
+		return builtinError(ev, "na.fail", "missing values in object")
+	}
+	return x
+}
+
+func EvalNaPass(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "na.pass", "argument \"object\" is missing, with no default")
+	}
+	return x
+}
@@ -0,0 +1,684 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/optim.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/optimize.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/uniroot.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/integrate.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/nlm.html
+// The numerical routines of package numeric call back into R functions, which
+// are applied to the parameters followed by the further arguments in the dots.
+
+func init() {
+	registerBuiltin("optim", []string{"par", "fn", "gr", "...", "method", "lower", "upper", "control", "hessian"}, EvalOptim)
+	registerBuiltin("optimize", []string{"f", "interval", "...", "lower", "upper", "maximum", "tol"}, EvalOptimize)
+	registerBuiltin("optimise", []string{"f", "interval", "...", "lower", "upper", "maximum", "tol"}, EvalOptimize)
+	registerBuiltin("uniroot", []string{"f", "interval", "...", "lower", "upper", "f.lower", "f.upper", "tol", "maxiter"}, EvalUniroot)
+	registerBuiltin("integrate", []string{"f", "lower", "upper", "...", "subdivisions", "rel.tol", "abs.tol", "stop.on.error"}, EvalIntegrate)
+	registerBuiltin("nlm", []string{"f", "p", "...", "hessian", "typsize", "fscale", "print.level", "ndigit", "gradtol", "stepmax", "steptol", "iterlim"}, EvalNlm)
+	registerPrintMethod("integrate", printIntegrate)
+}
+
+// the default tolerance .Machine$double.eps^0.25
+var defaultTolerance = math.Pow(2.220446049250313e-16, 0.25)
+
+// an R function called by a numerical routine, after the first error it is not called again
+type callback struct {
+	ev       *Evaluator
+	node     *ast.CallExpr
+	funcname string
+	f        *VSEXP
+	extra    []SEXPItf
+	tags     []string
+	names    []string // of the parameters
+	failed   bool
+}
+
+func newCallback(ev *Evaluator, node *ast.CallExpr, funcname string, f SEXPItf, args *Arguments) (*callback, bool) {
+	fun, ok := matchFunction(ev, funcname, f)
+	if !ok {
+		return nil, false
+	}
+	return &callback{ev: ev, node: node, funcname: funcname, f: fun, extra: args.Dots, tags: args.DotNames}, true
+}
+
+// the numeric values of the function at x, nil after an error
+func (c *callback) values(x []float64) []float64 {
+	if c.failed {
+		return nil
+	}
+	var arg SEXPItf
+	if len(x) == 1 && c.names == nil {
+		arg = &VSEXP{ValuePos: c.node.Pos(), Immediate: x[0]}
+	} else {
+		arg = namedFloats(c.node.Pos(), append([]float64{}, x...), c.names)
+	}
+	values := append([]SEXPItf{arg}, c.extra...)
+	tags := append([]string{""}, c.tags...)
+	r := callFunction(c.ev, c.node, c.funcname, c.f, values, tags)
+	if isError(r) {
+		c.failed = true
+		return nil
+	}
+	switch sexpType(r) {
+	case LGLSXP, INTSXP, REALSXP:
+		warn := false
+		return asFloats(r, &warn)
+	}
+	return nil
+}
+
+// the error is reported once
+func (c *callback) fail(format string, a ...interface{}) float64 {
+	if !c.failed {
+		builtinError(c.ev, c.funcname, format, a...)
+		c.failed = true
+	}
+	return math.NaN()
+}
+
+// a scalar value of the function, checked as the caller does
+func (c *callback) scalar(x []float64, msg string) float64 {
+	v := c.values(x)
+	if c.failed {
+		return math.NaN()
+	}
+	if len(v) != 1 {
+		return c.fail(msg, len(v))
+	}
+	return v[0]
+}
+
+// a numeric argument recycled to length n
+func recycledFloats(x SEXPItf, n int, def float64) []float64 {
+	r := make([]float64, n)
+	var v []float64
+	if x != nil {
+		warn := false
+		v = asFloats(x, &warn)
+	}
+	for k := range r {
+		if len(v) == 0 {
+			r[k] = def
+		} else {
+			r[k] = v[k%len(v)]
+		}
+	}
+	return r
+}
+
+func namedCounts(pos token.Pos, fncount int, grcount int) *ISEXP {
+	r := &ISEXP{ValuePos: pos, Slice: []int{fncount, grcount}}
+	r.NamesSet([]string{"function", "gradient"})
+	return r
+}
+
+var optimMethods = []string{"Nelder-Mead", "BFGS", "CG", "L-BFGS-B", "SANN", "Brent"}
+
+var optimControls = []string{"trace", "fnscale", "parscale", "ndeps", "maxit", "abstol", "reltol", "alpha", "beta", "gamma", "REPORT",
+	"warn.1d.NelderMead", "type", "lmm", "factr", "pgtol", "temp", "tmax"}
+
+// the objective and its gradient on the parameters divided by parscale, divided by fnscale
+type scaledObjective struct {
+	fn       *callback
+	gr       *callback // nil for finite differences
+	n        int
+	fnscale  float64
+	parscale []float64
+	ndeps    []float64
+	lower    []float64 // scaled bounds for the differences
+	upper    []float64
+}
+
+func (o *scaledObjective) unscaled(p []float64) []float64 {
+	x := make([]float64, o.n)
+	for i := range x {
+		x[i] = p[i] * o.parscale[i]
+	}
+	return x
+}
+
+func (o *scaledObjective) value(p []float64) float64 {
+	return o.fn.scalar(o.unscaled(p), "objective function in optim evaluates to length %d not 1") / o.fnscale
+}
+
+func (o *scaledObjective) gradient(p []float64, df []float64) {
+	if o.gr != nil {
+		v := o.gr.values(o.unscaled(p))
+		if o.gr.failed {
+			return
+		}
+		if len(v) != o.n {
+			o.gr.fail("gradient in optim evaluated to length %d not %d", len(v), o.n)
+			return
+		}
+		for i := range df {
+			df[i] = v[i] * o.parscale[i] / o.fnscale
+		}
+		return
+	}
+	x := append([]float64{}, p...)
+	for i := range x {
+		eps := o.ndeps[i]
+		epsused := eps
+		tmp := p[i] + eps
+		if tmp > o.upper[i] {
+			tmp = o.upper[i]
+			epsused = tmp - p[i]
+		}
+		x[i] = tmp
+		val1 := o.value(x)
+		tmp = p[i] - eps
+		if tmp < o.lower[i] {
+			tmp = o.lower[i]
+			eps = p[i] - tmp
+		}
+		x[i] = tmp
+		val2 := o.value(x)
+		df[i] = (val1 - val2) / (epsused + eps)
+		if !isFinite(df[i]) {
+			o.fn.fail("non-finite finite-difference value [%d]", i+1)
+		}
+		x[i] = p[i]
+	}
+}
+
+func (o *scaledObjective) failed() bool {
+	return o.fn.failed || o.gr != nil && o.gr.failed
+}
+
+// the Hessian at the scaled parameters p by differences of the gradient, as optimHess
+func (o *scaledObjective) hessian(p []float64) *numeric.Matrix {
+	h := numeric.NewMatrix(o.n, o.n)
+	dpar := append([]float64{}, p...)
+	df1 := make([]float64, o.n)
+	df2 := make([]float64, o.n)
+	for i := 0; i < o.n; i++ {
+		eps := o.ndeps[i]
+		dpar[i] = p[i] + eps
+		o.gradient(dpar, df1)
+		dpar[i] = p[i] - eps
+		o.gradient(dpar, df2)
+		for j := 0; j < o.n; j++ {
+			h.Set(i, j, o.fnscale*(df1[j]-df2[j])/(2*eps*o.parscale[i]*o.parscale[j]))
+		}
+		dpar[i] = p[i]
+	}
+	for i := 0; i < o.n; i++ {
+		for j := 0; j < i; j++ {
+			v := 0.5 * (h.At(i, j) + h.At(j, i))
+			h.Set(i, j, v)
+			h.Set(j, i, v)
+		}
+	}
+	return h
+}
+
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+func EvalOptim(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	if !numericArgument(ev, "optim", "par", args.Values[0]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	par := asFloats(args.Values[0], &warn)
+	n := len(par)
+	fn, ok := newCallback(ev, node, "optim", args.Values[1], args)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	fn.names = args.Values[0].Names()
+	o := &scaledObjective{fn: fn, n: n, fnscale: 1}
+	if args.Values[2] != nil && sexpType(args.Values[2]) != NILSXP {
+		if o.gr, ok = newCallback(ev, node, "optim", args.Values[2], args); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		o.gr.names = fn.names
+	}
+	method := "Nelder-Mead"
+	if m := args.Values[4]; m != nil {
+		if sexpType(m) != STRSXP || m.Length() < 1 {
+			return builtinError(ev, "optim", "'method' must be one of %s", strings.Join(optimMethods, ", "))
+		}
+		k := matchName(asStrings(m)[0], optimMethods, false)
+		if k < 0 {
+			return builtinError(ev, "optim", "'arg' should be one of %s", strings.Join(optimMethods, ", "))
+		}
+		method = optimMethods[k]
+	}
+	lower := recycledFloats(args.Values[5], n, math.Inf(-1))
+	upper := recycledFloats(args.Values[6], n, math.Inf(1))
+	bounded := false
+	for k := range lower {
+		bounded = bounded || !math.IsInf(lower[k], -1) || !math.IsInf(upper[k], 1)
+	}
+	if bounded && method != "L-BFGS-B" && method != "Brent" {
+		ev.warning("", "bounds can only be used with method L-BFGS-B (or Brent)")
+		method = "L-BFGS-B"
+	}
+	c := numeric.OptimControl{Maxit: 100, Abstol: math.Inf(-1), Reltol: math.Sqrt(2.220446049250313e-16),
+		Alpha: 1, Beta: 0.5, Gamma: 2, Type: 1, Lmm: 5, Factr: 1e7, Pgtol: 0}
+	if method == "Nelder-Mead" {
+		c.Maxit = 500
+	}
+	o.parscale = recycledFloats(nil, n, 1)
+	o.ndeps = recycledFloats(nil, n, 1e-3)
+	warn1d := true
+	if control, ok := args.Values[7].(*RSEXP); ok {
+		var unknown []string
+		for k, name := range control.Names() {
+			v := control.Slice[k]
+			x := 0.
+			if isAtomic(v) && v.Length() > 0 {
+				x = asFloats(v, &warn)[0]
+			}
+			switch name {
+			case "fnscale":
+				o.fnscale = x
+			case "parscale":
+				o.parscale = recycledFloats(v, n, 1)
+			case "ndeps":
+				o.ndeps = recycledFloats(v, n, 1e-3)
+			case "maxit":
+				c.Maxit = int(x)
+			case "abstol":
+				c.Abstol = x
+			case "reltol":
+				c.Reltol = x
+			case "alpha":
+				c.Alpha = x
+			case "beta":
+				c.Beta = x
+			case "gamma":
+				c.Gamma = x
+			case "type":
+				c.Type = int(x)
+			case "lmm":
+				c.Lmm = int(x)
+			case "factr":
+				c.Factr = x
+			case "pgtol":
+				c.Pgtol = x
+			case "warn.1d.NelderMead":
+				warn1d = x != 0
+			default:
+				if matchName(name, optimControls, true) < 0 {
+					unknown = append(unknown, name)
+				}
+			}
+		}
+		if len(unknown) > 0 {
+			ev.warning("", "unknown names in control: "+strings.Join(unknown, ", "))
+		}
+	}
+	if c.Type < 1 || c.Type > 3 {
+		return builtinError(ev, "optim", "unknown 'type' in \"CG\" method of 'optim'")
+	}
+	if method == "Nelder-Mead" && n == 1 && warn1d {
+		ev.warning("", "one-dimensional optimization by Nelder-Mead is unreliable:\nuse \"Brent\" or optimize() directly")
+	}
+	p := make([]float64, n)
+	o.lower = make([]float64, n)
+	o.upper = make([]float64, n)
+	for i := range p {
+		p[i] = par[i] / o.parscale[i]
+		o.lower[i] = math.Inf(-1)
+		o.upper[i] = math.Inf(1)
+		if method == "L-BFGS-B" {
+			o.lower[i] = lower[i] / o.parscale[i]
+			o.upper[i] = upper[i] / o.parscale[i]
+		}
+	}
+	var r numeric.OptimResult
+	var message SEXPItf = &NSEXP{ValuePos: pos}
+	switch method {
+	case "Nelder-Mead":
+		r = numeric.Nmmin(o.value, p, c)
+		r.Grcount = NA_INTEGER
+	case "BFGS":
+		r = numeric.Vmmin(o.value, o.gradient, p, c)
+	case "CG":
+		r = numeric.Cgmin(o.value, o.gradient, p, c)
+	case "L-BFGS-B":
+		r = numeric.Lbfgsb(o.value, o.gradient, p, o.lower, o.upper, c)
+		message = &TSEXP{ValuePos: pos, String: r.Message}
+	case "Brent":
+		if n != 1 {
+			return builtinError(ev, "optim", "method = \"Brent\" is only available for one-dimensional optimization")
+		}
+		if math.IsInf(lower[0], 0) || math.IsInf(upper[0], 0) {
+			return builtinError(ev, "optim", "'lower' and 'upper' must be finite values")
+		}
+		x := numeric.Fmin(lower[0], upper[0], func(x float64) float64 {
+			return o.fn.scalar([]float64{x}, "objective function in optim evaluates to length %d not 1") / o.fnscale
+		}, c.Reltol)
+		r = numeric.OptimResult{Par: []float64{x / o.parscale[0]}, Fncount: NA_INTEGER, Grcount: NA_INTEGER}
+		r.Value = o.value(r.Par)
+	default:
+		return builtinError(ev, "optim", "method '%s' is not supported", method)
+	}
+	if o.failed() {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	switch {
+	case r.Fail == numeric.OptimNotFinite && method == "BFGS":
+		return builtinError(ev, "optim", "initial value in 'vmmin' is not finite")
+	case r.Fail == numeric.OptimNotFinite && method == "L-BFGS-B":
+		return builtinError(ev, "optim", "L-BFGS-B needs finite values of 'fn'")
+	case r.Fail == numeric.OptimNotFinite:
+		return builtinError(ev, "optim", "function cannot be evaluated at initial parameters")
+	}
+	names := []string{"par", "value", "counts", "convergence", "message"}
+	values := []SEXPItf{
+		namedFloats(pos, o.unscaled(r.Par), fn.names),
+		&VSEXP{ValuePos: pos, Immediate: r.Value * o.fnscale},
+		namedCounts(pos, r.Fncount, r.Grcount),
+		integerValue(pos, r.Fail),
+		message}
+	if args.logical(8, false) {
+		h := o.hessian(r.Par)
+		if o.failed() {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		names = append(names, "hessian")
+		values = append(values, matrixResult(pos, h, fn.names, fn.names))
+	}
+	return classList(pos, "", names, values...)
+}
+
+// the interval of optimize and uniroot from interval or lower and upper
+func searchInterval(ev *Evaluator, funcname string, args *Arguments, lowerArg int, upperArg int) (float64, float64, bool) {
+	warn := false
+	lower, upper := math.NaN(), math.NaN()
+	if x := args.Values[1]; x != nil {
+		v := asFloats(x, &warn)
+		if len(v) > 0 {
+			lower, upper = v[0], v[0]
+			for _, e := range v {
+				lower = math.Min(lower, e)
+				upper = math.Max(upper, e)
+			}
+		}
+	}
+	if x := args.Values[lowerArg]; x != nil {
+		lower = asFloats(x, &warn)[0]
+	}
+	if x := args.Values[upperArg]; x != nil {
+		upper = asFloats(x, &warn)[0]
+	}
+	if args.Values[1] == nil && (args.Values[lowerArg] == nil || args.Values[upperArg] == nil) {
+		builtinError(ev, funcname, "argument \"interval\" is missing, with no default")
+		return 0, 0, false
+	}
+	return lower, upper, true
+}
+
+func EvalOptimize(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	f, ok := newCallback(ev, node, "optimize", args.Values[0], args)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	lower, upper, ok := searchInterval(ev, "optimize", args, 3, 4)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	switch {
+	case !isFinite(lower):
+		return builtinError(ev, "optimize", "invalid 'xmin' value")
+	case !isFinite(upper):
+		return builtinError(ev, "optimize", "invalid 'xmax' value")
+	case lower >= upper:
+		return builtinError(ev, "optimize", "'xmin' not less than 'xmax'")
+	}
+	maximum := args.logical(5, false)
+	tol := args.float(6, defaultTolerance)
+	sign := 1.
+	if maximum {
+		sign = -1
+	}
+	replaced := false
+	x := numeric.Fmin(lower, upper, func(x float64) float64 {
+		v := f.scalar([]float64{x}, "invalid function value in 'optimize'")
+		if !isFinite(v) && !f.failed {
+			if !replaced {
+				ev.warning("", "NA/Inf replaced by maximum positive value")
+				replaced = true
+			}
+			return math.MaxFloat64
+		}
+		return sign * v
+	}, tol)
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	objective := f.values([]float64{x})
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	name := "minimum"
+	if maximum {
+		name = "maximum"
+	}
+	return classList(pos, "", []string{name, "objective"},
+		&VSEXP{ValuePos: pos, Immediate: x},
+		namedFloats(pos, objective, nil))
+}
+
+func EvalUniroot(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	f, ok := newCallback(ev, node, "uniroot", args.Values[0], args)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	lower, upper, ok := searchInterval(ev, "uniroot", args, 3, 4)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if !(lower < upper) {
+		return builtinError(ev, "uniroot", "lower < upper  is not fulfilled")
+	}
+	value := func(x float64, name string) (float64, bool) {
+		v := f.scalar([]float64{x}, "invalid function value in 'zeroin'")
+		if f.failed {
+			return 0, false
+		}
+		if math.IsNaN(v) {
+			builtinError(ev, "uniroot", "f.%s = f(%s) is NA", name, name)
+			return 0, false
+		}
+		return v, true
+	}
+	var flower, fupper float64
+	if args.Values[5] != nil {
+		flower = args.float(5, 0)
+	} else if flower, ok = value(lower, "lower"); !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if args.Values[6] != nil {
+		fupper = args.float(6, 0)
+	} else if fupper, ok = value(upper, "upper"); !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if flower*fupper > 0 {
+		return builtinError(ev, "uniroot", "f() values at end points not of opposite sign")
+	}
+	tol := args.float(7, defaultTolerance)
+	maxiter := int(args.float(8, 1000))
+	if maxiter <= 0 {
+		return builtinError(ev, "uniroot", "'maxiter' must be positive")
+	}
+	root, prec, iter := numeric.Zeroin(lower, upper, flower, fupper, func(x float64) float64 {
+		v := f.scalar([]float64{x}, "invalid function value in 'zeroin'")
+		if math.IsNaN(v) && !f.failed {
+			return f.fail("NA value")
+		}
+		return v
+	}, tol, maxiter)
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if iter < 0 {
+		ev.warning("", fmt.Sprintf("_NOT_ converged in %d iterations", maxiter))
+		iter = maxiter
+		prec = calc.NA
+	}
+	froot := f.values([]float64{root})
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return classList(pos, "", []string{"root", "f.root", "iter", "init.it", "estim.prec"},
+		&VSEXP{ValuePos: pos, Immediate: root},
+		namedFloats(pos, froot, nil),
+		integerValue(pos, iter),
+		integerValue(pos, NA_INTEGER),
+		&VSEXP{ValuePos: pos, Immediate: prec})
+}
+
+var quadMessages = []string{"OK", "maximum number of subdivisions reached", "roundoff error was detected",
+	"extremely bad integrand behaviour", "roundoff error is detected in the extrapolation table",
+	"the integral is probably divergent", "the input is invalid"}
+
+func EvalIntegrate(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	f, ok := newCallback(ev, node, "integrate", args.Values[0], args)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	for k, formal := range []string{"lower", "upper"} {
+		if args.Values[k+1] == nil {
+			return builtinError(ev, "integrate", "argument \"%s\" is missing, with no default", formal)
+		}
+	}
+	lower := args.float(1, 0)
+	upper := args.float(2, 0)
+	limit := int(args.float(4, 100))
+	reltol := args.float(5, defaultTolerance)
+	abstol := args.float(6, reltol)
+	stop := args.logical(7, true)
+	if limit < 1 || (abstol <= 0 && reltol < math.Max(50*2.220446049250313e-16, 0.5e-28)) {
+		return builtinError(ev, "integrate", "invalid parameter values")
+	}
+	integrand := func(x []float64) []float64 {
+		v := f.values(x)
+		switch {
+		case f.failed:
+		case len(v) != len(x):
+			f.fail("evaluation of function gave a result of wrong length")
+		default:
+			for _, y := range v {
+				if !isFinite(y) {
+					f.fail("non-finite function value")
+					break
+				}
+			}
+		}
+		if f.failed {
+			return make([]float64, len(x))
+		}
+		return v
+	}
+	var r numeric.QuadResult
+	switch {
+	case math.IsNaN(lower) || math.IsNaN(upper):
+		return builtinError(ev, "integrate", "a limit is NA or NaN")
+	case isFinite(lower) && isFinite(upper):
+		r = numeric.Dqags(integrand, lower, upper, abstol, reltol, limit)
+	case isFinite(lower):
+		r = numeric.Dqagi(integrand, lower, 1, abstol, reltol, limit)
+	case isFinite(upper):
+		r = numeric.Dqagi(integrand, upper, -1, abstol, reltol, limit)
+	default:
+		r = numeric.Dqagi(integrand, 0, 2, abstol, reltol, limit)
+	}
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	message := quadMessages[r.Ier]
+	if r.Ier == numeric.QuadInvalid || r.Ier != numeric.QuadOK && stop {
+		return builtinError(ev, "integrate", "%s", message)
+	}
+	return classList(pos, "integrate", []string{"value", "abs.error", "subdivisions", "message", "call"},
+		&VSEXP{ValuePos: pos, Immediate: r.Value},
+		&VSEXP{ValuePos: pos, Immediate: r.AbsError},
+		integerValue(pos, r.Subdivisions),
+		&TSEXP{ValuePos: pos, String: message},
+		matchedCall(node, []string{"f", "lower", "upper", "...", "subdivisions", "rel.tol", "abs.tol", "stop.on.error"}))
+}
+
+func printIntegrate(w io.Writer, x *RSEXP) {
+	message := asStrings(listComponent(x, "message"))[0]
+	if message != "OK" {
+		fmt.Fprintf(w, "failed with message ‘%s’\n", message)
+		return
+	}
+	fmt.Fprintf(w, "%s with absolute error < %s\n", formatReal(componentFloats(x, "value"), 7)[0],
+		formatReal(componentFloats(x, "abs.error"), 2)[0])
+}
+
+func EvalNlm(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	pos := node.Fun.Pos()
+	f, ok := newCallback(ev, node, "nlm", args.Values[0], args)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if !numericArgument(ev, "nlm", "p", args.Values[1]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	p := asFloats(args.Values[1], &warn)
+	n := len(p)
+	c := numeric.NlmControl{
+		Typsize: recycledFloats(args.Values[4], n, 1),
+		Fscale:  args.float(5, 1),
+		Ndigit:  int(args.float(7, 12)),
+		Gradtol: args.float(8, 1e-6),
+		Steptol: args.float(10, 1e-6),
+		Iterlim: int(args.float(11, 100)),
+	}
+	s := 0.
+	for i, x := range p {
+		s += (x / c.Typsize[i]) * (x / c.Typsize[i])
+	}
+	c.Stepmax = args.float(9, math.Max(1000*math.Sqrt(s), 1000))
+	fn := func(x []float64) float64 {
+		v := f.scalar(x, "invalid function value in 'nlm' optimizer")
+		if math.IsNaN(v) && !f.failed {
+			return math.Inf(1)
+		}
+		return v
+	}
+	r := numeric.Nlm(fn, nil, p, c)
+	if f.failed {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	names := []string{"minimum", "estimate", "gradient"}
+	values := []SEXPItf{
+		&VSEXP{ValuePos: pos, Immediate: r.Minimum},
+		&VSEXP{ValuePos: pos, Slice: r.Estimate},
+		&VSEXP{ValuePos: pos, Slice: r.Gradient}}
+	if args.logical(3, false) {
+		h := numeric.Fdhess(fn, r.Estimate, r.Minimum, c.Typsize, c.Ndigit)
+		if f.failed {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		names = append(names, "hessian")
+		values = append(values, matrixResult(pos, h, nil, nil))
+	}
+	names = append(names, "code", "iterations")
+	values = append(values, integerValue(pos, r.Code), integerValue(pos, r.Iterations))
+	return classList(pos, "", names, values...)
+}
@@ -0,0 +1,47 @@
+package eval
+
+import (
+	"roq/lib/ast"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/options.html
+// options are shared by all copies of the evaluator inside loops, forked evaluators get their own copy.
+// Only warn and mc.cores are used so far: negative values of warn ignore all warnings,
+// mc.cores is the default number of goroutines for parallel functions. Other options are not evaluated.
+
+var usedOptions = map[string]bool{"warn": true, "mc.cores": true}
+
+// the previous values of the given options are returned invisibly
+func EvalOptions(ev *Evaluator, node *ast.CallExpr) SEXPItf {
+	old := &RSEXP{ValuePos: node.Fun.Pos(), Slice: []SEXPItf{}}
+	var names []string
+	for _, arg := range node.Args {
+		tagged, ok := arg.(*ast.TaggedExpr)
+		if !ok || !usedOptions[tagged.Tag] {
+			continue
+		}
+		v := EvalExpr(ev, tagged.Rhs)
+		if _, ok := v.(*ESEXP); ok {
+			return v
+		}
+		previous, ok := ev.options[tagged.Tag]
+		if !ok {
+			previous = &NSEXP{}
+		}
+		old.Slice = append(old.Slice, previous)
+		names = append(names, tagged.Tag)
+		ev.options[tagged.Tag] = v
+	}
+	old.NamesSet(names)
+	ev.Invisible = true
+	return old
+}
+
+func (e *Evaluator) optionFloat(name string, def float64) float64 {
+	v, ok := e.options[name]
+	if !ok || !isAtomic(v) || v.Length() == 0 {
+		return def
+	}
+	warn := false
+	return asFloats(v, &warn)[0]
+}
@@ -0,0 +1,208 @@
+package eval
+
+import (
+	"bytes"
+	"fmt"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"runtime"
+	"sync"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/parallel/html/mclapply.html
+// https://stat.ethz.ch/R-manual/R-devel/library/parallel/html/clusterApply.html
+// Each element is evaluated in its own goroutine on a forked evaluator, at most as many
+// at the same time as there are cores. Results come back in order, an error gives an
+// object of class try-error with its message in place of the result. A cluster is just
+// a list of its nodes.
+
+func init() {
+	registerBuiltin("mclapply", []string{"X", "FUN", "...", "mc.preschedule", "mc.set.seed", "mc.silent", "mc.cores", "mc.cleanup", "mc.allow.recursive", "affinity.list"}, EvalMclapply)
+	registerBuiltin("parLapply", []string{"cl", "X", "fun", "...", "chunk.size"}, EvalParLapply)
+	registerBuiltin("parSapply", []string{"cl", "X", "FUN", "...", "simplify", "USE.NAMES", "chunk.size"}, EvalParSapply)
+	registerBuiltin("makeCluster", []string{"spec", "type", "..."}, EvalMakeCluster)
+	registerBuiltin("stopCluster", []string{"cl"}, EvalStopCluster)
+	registerBuiltin("detectCores", []string{"all.tests", "logical"}, EvalDetectCores)
+}
+
+// An evaluator for a goroutine: assignments go to its own global frame, through which
+// the frames of the parent are seen. They are not changed while the parent waits.
+// Its random generator is spawned from the generator of the parent.
+func (e *Evaluator) fork() *Evaluator {
+	f := *e
+	f.topFrame = NewFrame(e.topFrame)
+	f.globalFrame = f.topFrame
+	f.state = normalState
+	f.Invisible = false
+	f.warnings = new([]string)
+	f.futures = sequentialPlan()
+	e.getRNGstate()
+	f.rng = e.rng.Spawn()
+	e.putRNGstate()
+	f.options = make(map[string]SEXPItf, len(e.options))
+	for k, v := range e.options {
+		f.options[k] = v
+	}
+	return &f
+}
+
+func tryError(msg string) SEXPItf {
+	r := &TSEXP{String: "Error in FUN(X[[i]], ...) : " + msg}
+	class := "try-error"
+	r.ClassSet(&class)
+	return r
+}
+
+func defaultCores(ev *Evaluator) int {
+	return int(ev.optionFloat("mc.cores", 2))
+}
+
+// FUN applied to the elements of X on at most cores goroutines, errors raised by FUN are
+// recovered as try-errors. Each goroutine writes to its own output, which is relayed in
+// the order of the elements.
+func parallelApply(ev *Evaluator, node *ast.CallExpr, funcname string, f *VSEXP, X SEXPItf, extra []SEXPItf, extraTags []string, cores int) []SEXPItf {
+	elements := asElements(ev, X)
+	r := make([]SEXPItf, len(elements))
+	forks := make([]*Evaluator, len(elements))
+	outputs := make([]bytes.Buffer, len(elements))
+	ev.rng.ResetStreams() // every call starts with the same streams, as mc.reset.stream() in R
+	for n := range elements {
+		forks[n] = ev.fork() // before any goroutine reads the frames of ev
+		forks[n].out = &outputs[n]
+	}
+	slots := make(chan bool, cores)
+	var wg sync.WaitGroup
+	for n, e := range elements {
+		wg.Add(1)
+		slots <- true
+		go func(n int, e SEXPItf) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			defer func() {
+				if p := recover(); p != nil {
+					r[n] = tryError(panicMessage(p))
+				}
+			}()
+			v := callFunction(forks[n], node, "FUN", f, append([]SEXPItf{e}, extra...), append([]string{""}, extraTags...))
+			if e, ok := v.(*ESEXP); ok {
+				msg := e.Message
+				if msg == "" {
+					msg = "evaluation failed"
+				}
+				v = tryError(msg)
+			}
+			r[n] = v
+		}(n, e)
+	}
+	wg.Wait()
+	errors := 0
+	for n := range r {
+		ev.out.Write(outputs[n].Bytes())
+		*ev.warnings = append(*ev.warnings, *forks[n].warnings...)
+		if c := r[n].Class(); c != nil && *c == "try-error" {
+			errors++
+		}
+	}
+	if errors > 0 {
+		ev.warning(funcname+"()", fmt.Sprintf("%d function calls resulted in an error", errors))
+	}
+	return r
+}
+
+func clusterCores(ev *Evaluator, funcname string, cl SEXPItf) (int, bool) {
+	if cl == nil || sexpType(cl) == NILSXP {
+		return defaultCores(ev), true
+	}
+	if c := cl.Class(); sexpType(cl) != VECSXP || c == nil || *c != "cluster" {
+		builtinError(ev, funcname, "not a valid cluster")
+		return 0, false
+	}
+	return cl.Length(), true
+}
+
+func EvalMclapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	X := args.Values[0]
+	if X == nil {
+		return builtinError(ev, "mclapply", "argument \"X\" is missing, with no default")
+	}
+	f, ok := matchFunction(ev, "mclapply", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	cores := int(args.float(6, float64(defaultCores(ev))))
+	if cores < 1 {
+		return builtinError(ev, "mclapply", "'mc.cores' must be >= 1")
+	}
+	r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: parallelApply(ev, node, "mclapply", f, X, args.Dots, args.DotNames, cores)}
+	r.NamesSet(X.Names())
+	return r
+}
+
+func EvalParLapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	cores, ok := clusterCores(ev, "parLapply", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	X := args.Values[1]
+	if X == nil {
+		return builtinError(ev, "parLapply", "argument \"X\" is missing, with no default")
+	}
+	f, ok := matchFunction(ev, "parLapply", args.Values[2])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: parallelApply(ev, node, "parLapply", f, X, args.Dots, args.DotNames, cores)}
+	r.NamesSet(X.Names())
+	return r
+}
+
+func EvalParSapply(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	cores, ok := clusterCores(ev, "parSapply", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	X := args.Values[1]
+	if X == nil {
+		return builtinError(ev, "parSapply", "argument \"X\" is missing, with no default")
+	}
+	f, ok := matchFunction(ev, "parSapply", args.Values[2])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	values := parallelApply(ev, node, "parSapply", f, X, args.Dots, args.DotNames, cores)
+	names := useNames(X, args.logical(5, true))
+	if !args.logical(4, true) {
+		r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: values}
+		r.NamesSet(names)
+		return r
+	}
+	return simplifyResults(ev, node.Fun.Pos(), values, names)
+}
+
+func EvalMakeCluster(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n := int(args.float(0, float64(defaultCores(ev))))
+	if n < 1 {
+		return builtinError(ev, "makeCluster", "numeric 'names' must be >= 1")
+	}
+	nodes := make([]SEXPItf, n)
+	for k := range nodes {
+		nodes[k] = &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(k + 1), Integer: k + 1}
+	}
+	r := &RSEXP{ValuePos: node.Fun.Pos(), Slice: nodes}
+	class := "cluster"
+	r.ClassSet(&class)
+	return r
+}
+
+func EvalStopCluster(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if _, ok := clusterCores(ev, "stopCluster", args.Values[0]); !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+func EvalDetectCores(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n := runtime.NumCPU()
+	return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(n), Integer: n}
+}
@@ -12,27 +12,20 @@ import (
 func EvalLength(ev *Evaluator, node *ast.CallExpr) (r *ISEXP) {
 	TRACE := ev.Trace
 	if TRACE {
-		println("Length")
-	}
-	ex := node.Args[0]
-	switch ex.(type) {
-	case *ast.IndexExpr:
-		iterator := EvalIndexExpressionToIterator(ev, ex.(*ast.IndexExpr).Index)
-		return &ISEXP{ValuePos: node.Fun.Pos(), Integer: iterator.Length()}
-	default:
-		val := EvalExpr(ev, node.Args[0])
-		return &ISEXP{ValuePos: node.Fun.Pos(), Integer: val.Length()}
+		ev.println("Length")
 	}
+	val := EvalExpr(ev, node.Args[0])
+	return &ISEXP{ValuePos: node.Fun.Pos(), Integer: val.Length()}
 }
 
 
 func EvalPrint(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
 	TRACE := ev.Trace
 	if TRACE {
-		println("PrintExpr")
+		ev.println("PrintExpr")
 	}
 	value := EvalExpr(ev, node.Args[0])
-	PrintResult(value)
+	PrintResult(ev.out, value)
 	ev.Invisible = true
 	return nil
 }
@@ -41,46 +34,54 @@ func EvalPrint(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
 func EvalCat(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
 	TRACE := ev.Trace
 	if TRACE {
-		println("CatExpr")
+		ev.println("CatExpr")
 	}
 	for n := 0; n < len(node.Args); n++ {
 		r = EvalExpr(ev, node.Args[n])
 		if n > 0 {
-			fmt.Printf(" ")
+			fmt.Fprintf(ev.out, " ")
 		}
 		switch r.(type) {
 		case *TSEXP:
-			fmt.Printf(strings.Replace(r.(*TSEXP).String, "\\n", "\n", -1)) // needs strings.Map
-		case *ISEXP:
-			fmt.Printf("%g", r.(*ISEXP).Immediate) // TODO
+			for k, v := range stringSlice(r.(*TSEXP)) {
+				if k > 0 {
+					fmt.Fprintf(ev.out, " ")
+				}
+				if v == NA_CHARACTER {
+					v = "NA"
+				}
+				fmt.Fprint(ev.out, strings.Replace(v, "\\n", "\n", -1)) // needs strings.Map
+			}
+		case *ISEXP, *LSEXP, *CSEXP:
+			values := asStrings(r)
+			for k, v := range values {
+				if v == NA_CHARACTER {
+					values[k] = "NA"
+				}
+			}
+			fmt.Fprintf(ev.out, "%s", strings.Join(values, " "))
 		case *VSEXP:
 			if r.(*VSEXP).Slice == nil {
-				fmt.Printf("%g",r.(*VSEXP).Immediate)
+				fmt.Fprintf(ev.out, "%s", formatFloat(r.(*VSEXP).Immediate))
 			} else {
 				for n, v := range r.(*VSEXP).Slice {
 					if n > 0 {
-						fmt.Printf(" ")
+						fmt.Fprintf(ev.out, " ")
 					}
-					fmt.Printf(" %g", v) // R has small e for exponential format
+					fmt.Fprintf(ev.out, " %s", formatFloat(v)) // R has small e for exponential format
 				}
 			}
 		default:
-			println("?CAT")
+			ev.println("?CAT")
 		}
 	}
 	ev.Invisible = true
 	return nil
 }
 
-// strongly stripped down call of c()
-// Therefore, all elements are evaluated within the context of the call
-// TODO recursive=TRUE/FALSE
+// c() combines its arguments, which are evaluated within the context of the call
 // TODO faster vector literals, composed just of floats
 
-// TODO document difference!
-// - R returns double, if there is at least one double, here we decide on first type
-// - inside string vectors, there is no conversion of numbers but an error thrown!
-
 /* The output type is determined from the highest type of the
    components in the hierarchy NULL < raw < logical < integer <
    double < complex < character < list < expression.
@@ -89,40 +90,29 @@ func EvalCat(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
 func EvalColumn(ev *Evaluator, node *ast.CallExpr) (r SEXPItf) {
 	TRACE := ev.Trace
 	if TRACE {
-		println("Column")
+		ev.println("Column")
 	}
 
 	if len(node.Args) > 0 {
-		evaluatedArgs := EvalArgswithDotDotArguments(ev, "c", node.Args)
-		switch evaluatedArgs[0].(type) {
-		case *ISEXP:
-			c := make([]int, len(evaluatedArgs))
-			for n, v := range evaluatedArgs {
-				c[n] = v.(*ISEXP).Integer
-			}
-			return &ISEXP{ValuePos: node.Fun.Pos(), Slice: c}
-		case *VSEXP:
-			c := make([]float64, len(evaluatedArgs))
-			for n, v := range evaluatedArgs {
-				switch v.(type) {
-				case *VSEXP:
-					c[n] = v.(*VSEXP).Immediate
-				case *ISEXP:
-					c[n] = v.(*ISEXP).Immediate
-				default:
-					panic("Error in function c")
-				}
-			}
-			return &VSEXP{ValuePos: node.Fun.Pos(), Slice: c}
-		case *TSEXP:
-			c := make([]string, len(evaluatedArgs))
-			for n, v := range evaluatedArgs {
-				c[n] = v.(*TSEXP).String
+		evaluatedArgs, names := EvalArgswithDotDotArguments(ev, "c", node.Args)
+		values := make([]SEXPItf, 0, len(evaluatedArgs))
+		tags := make([]string, 0, len(evaluatedArgs))
+		recursive, useNames := false, true
+		for n, v := range evaluatedArgs {
+			switch names[n] {
+			case "recursive":
+				recursive = isTrue(ev, v)
+			case "use.names":
+				useNames = isTrue(ev, v)
+			default:
+				values = append(values, v)
+				tags = append(tags, names[n])
 			}
-			return &TSEXP{ValuePos: node.Fun.Pos(), Slice: c}
-		default:
-			panic("Error in function c") // TODO
 		}
+		if hasBig(values) {
+			return bigCombine(ev, node.Fun.Pos(), values)
+		}
+		return combine(ev, node.Fun.Pos(), values, tags, recursive, useNames)
 	} else {
 		return nil
 	}
@@ -132,17 +122,24 @@ func EvalList(ev *Evaluator, node *ast.CallExpr) (r *RSEXP) {
 	TRACE := ev.Trace
 	DEBUG := ev.Debug
 	if TRACE {
-		println("list")
+		ev.println("list")
 	}
 	if DEBUG {
-		println("process given arguments for list function")
+		ev.println("process given arguments for list function")
 	}
-	evaluatedArgs := EvalArgswithDotDotArguments(ev, "list", node.Args)
+	evaluatedArgs, names := EvalArgswithDotDotArguments(ev, "list", node.Args)
 	if DEBUG {
-		println("List of evaluated args for function: list")
-		PrintListofSExpressions(evaluatedArgs)
+		ev.println("List of evaluated args for function: list")
+		PrintListofSExpressions(ev, evaluatedArgs)
+	}
+	r = &RSEXP{ValuePos: node.Fun.Pos(), Slice: evaluatedArgs}
+	for _, name := range names {
+		if name != "" {
+			r.NamesSet(names)
+			break
+		}
 	}
-	return &RSEXP{ValuePos: node.Fun.Pos(), Slice: evaluatedArgs}
+	return r
 }
 
 // TODO documentation and comparison
@@ -150,7 +147,7 @@ func EvalList(ev *Evaluator, node *ast.CallExpr) (r *RSEXP) {
 func EvalPairlist(ev *Evaluator, node *ast.CallExpr) (r *RSEXP) {
 	TRACE := ev.Trace
 	if TRACE {
-		println("Pairlist")
+		ev.println("Pairlist")
 	}
 
 	return &RSEXP{ValuePos: node.Fun.Pos(),
@@ -159,7 +156,7 @@ func EvalPairlist(ev *Evaluator, node *ast.CallExpr) (r *RSEXP) {
 }
 
 func EvalTypeof(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
-	if arityOK("typeof", 1, node) {
+	if arityOK(ev, "typeof", 1, node) {
 		object := EvalExpr(ev, node.Args[0])
 		var r string
 		if object == nil {
@@ -167,15 +164,20 @@ func EvalTypeof(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
 		} else {
 			switch object.(type) {
 			case *VSEXP:
-				if object.(*VSEXP).Body == nil {
-					r = "double"
-				} else {
+				switch sexpType(object) {
+				case CLOSXP:
 					r = "closure"
+				case BUILTINSXP:
+					r = "builtin"
+				default:
+					r = "double"
 				}
 			case *ISEXP:
 				r = "integer"
-//				case *LSEXP:
-//					r="logical"
+			case *CSEXP:
+				r = "complex"
+			case *LSEXP:
+				r = "logical"
 			case *TSEXP:
 				r = "character"
 			case *RSEXP:
@@ -186,6 +188,10 @@ func EvalTypeof(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
 				}
 			case *NSEXP:
 				r = "NULL"
+			case *XSEXP:
+				r = "externalptr"
+			case *QSEXP:
+				r = "language"
 			default:
 				panic("unknown type")
 			}
@@ -196,22 +202,24 @@ func EvalTypeof(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
 }
 
 func EvalClass(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
-	if arityOK("class", 1, node) {
+	if arityOK(ev, "class", 1, node) {
 		object := EvalExpr(ev, node.Args[0])
 		s := object.Class()
 		if s == nil {
 			var r string
 			switch object.(type) {
 			case *VSEXP:
-				if object.(*VSEXP).Body == nil {
-					r = "numeric"
-				} else {
+				if isFunction(object) {
 					r = "function"
+				} else {
+					r = "numeric"
 				}
 			case *ISEXP:
-				r = "numeric"
-//				case *LSEXP:
-//					r="logical"
+				r = "integer"
+			case *CSEXP:
+				r = "complex"
+			case *LSEXP:
+				r = "logical"
 			case *TSEXP:
 				r = "character"
 			case *RSEXP:
@@ -222,6 +230,14 @@ func EvalClass(ev *Evaluator, node *ast.CallExpr) (r *TSEXP) {
 				}
 			case *NSEXP:
 				r = "NULL"
+			case *XSEXP:
+				r = "externalptr"
+			case *QSEXP:
+				if _, ok := object.(*QSEXP).X.(*ast.Ident); ok {
+					r = "name"
+				} else {
+					r = "call"
+				}
 			default:
 				panic("unknown type")
 			}
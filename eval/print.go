@@ -1,194 +1,374 @@
 package eval
 
 import (
+	"io"
+	"roq/calc"
 	"roq/version"
 	"fmt"
 	"math"
 	"roq/lib/ast"
 	"roq/lib/token"
+	"strings"
 )
 
 
+// print methods of classed lists, as those of models
+var printMethods = map[string]func(io.Writer, *RSEXP){}
+
+func registerPrintMethod(class string, method func(io.Writer, *RSEXP)) {
+	printMethods[class] = method
+}
+
 // TODO typeswitch should depend on Kind
-func PrintResult(r SEXPItf) {
+func PrintResult(w io.Writer, r SEXPItf) {
 	if r == nil {
-		fmt.Printf("FALSE/NULL")
+		fmt.Fprintf(w, "FALSE/NULL")
+	} else if isAtomic(r) && r.Length() == 0 {
+		printEmpty(w, r)
 	} else {
 		switch r.(type) {
 		case *VSEXP:
-			PrintResultV(r.(*VSEXP))
+			PrintResultV(w, r.(*VSEXP))
 		case *ISEXP:
-			PrintResultI(r.(*ISEXP))
+			PrintResultI(w, r.(*ISEXP))
+		case *CSEXP:
+			PrintResultC(w, r.(*CSEXP))
+		case *LSEXP:
+			PrintResultL(w, r.(*LSEXP))
 		case *RSEXP:
-			PrintResultR(r.(*RSEXP))
+			if class := r.Class(); class != nil && printMethods[*class] != nil {
+				printMethods[*class](w, r.(*RSEXP))
+				return
+			}
+			PrintResultR(w, r.(*RSEXP))
 		case *TSEXP:
-			PrintResultT(r.(*TSEXP))
+			PrintResultT(w, r.(*TSEXP))
 		case *ESEXP:
-			PrintResultE(r.(*ESEXP))
+			PrintResultE(w, r.(*ESEXP))
 		case *QSEXP:
-			ast.FilteredPrint(nil,r.(*QSEXP).X,ast.QuotedExprFilter, true)
+			if isFormula(r) {
+				printFormula(w, r.(*QSEXP))
+				return
+			}
+			ast.FilteredFprint(w, nil,r.(*QSEXP).X,ast.QuotedExprFilter, true)
 		case *NSEXP:
-			println("NULL")
+			fmt.Fprintln(w, "NULL")
+		case *XSEXP:
+			if b, ok := bigValues(r); ok {
+				printBig(w, r.(*XSEXP), b)
+				return
+			}
+			if s, ok := sparseValue(r); ok {
+				printSparse(w, r.(*XSEXP), s)
+				return
+			}
+			fmt.Fprintf(w, "<%v>\n", r.(*XSEXP).Pointer)
 		default:
 			panic("?prnt")
 		}
 	}
 }
 
-func PrintResultR(r *RSEXP) {
+func PrintResultR(w io.Writer, r *RSEXP) {
 	if r == nil {
-		println("ERROR: uncatched NULL pointer: ", r) // TODO fatalState
+		fmt.Fprintln(w, "ERROR: uncatched NULL pointer: ", r) // TODO fatalState
+		return
+	}
+	if r.Slice != nil && len(r.Slice) == 0 {
+		if r.Names() != nil {
+			fmt.Fprintf(w, "named list()\n")
+		} else {
+			fmt.Fprintf(w, "list()\n")
+		}
 		return
 	}
 	if r.Slice == nil {
-		fmt.Printf("[[1]]\n")
-		PrintResult(r.CAR)
-		fmt.Printf("\n")
-		fmt.Printf("[[2]]\n")
-		PrintResult(r.CDR)
-		fmt.Printf("\n")
+		fmt.Fprintf(w, "[[1]]\n")
+		PrintResult(w, r.CAR)
+		fmt.Fprintf(w, "\n")
+		fmt.Fprintf(w, "[[2]]\n")
+		PrintResult(w, r.CDR)
+		fmt.Fprintf(w, "\n")
 	} else {
+		names := r.Names()
 		for n, v := range r.Slice {
-			fmt.Printf("[[%d]]\n", n+1)
-			PrintResult(v)
-			fmt.Printf("\n")
+			if n < len(names) && names[n] != "" {
+				fmt.Fprintf(w, "$%s\n", names[n])
+			} else {
+				fmt.Fprintf(w, "[[%d]]\n", n+1)
+			}
+			PrintResult(w, v)
+			fmt.Fprintf(w, "\n")
 		}
 	}
 }
 
-func PrintResultT(r *TSEXP) {
+// zero-length vectors are printed by their type
+func printEmpty(w io.Writer, r SEXPItf) {
+	name := typeName(sexpType(r))
+	if name == "double" {
+		name = "numeric"
+	}
+	if r.Names() != nil {
+		name = "named " + name
+	}
+	fmt.Fprintf(w, "%s(0)\n", name)
+}
+
+// named vectors are printed with their names above the values
+func printNamed(w io.Writer, names []string, values []string) {
+	for n, name := range names {
+		if n > 0 {
+			fmt.Fprintf(w, "\t")
+		}
+		fmt.Fprintf(w, "%s", name)
+	}
+	fmt.Fprintf(w, "\n")
+	for n, v := range values {
+		if n > 0 {
+			fmt.Fprintf(w, "\t")
+		}
+		fmt.Fprintf(w, "%s", v)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+// missing values are printed as NA, not a number as NaN
+func formatFloat(v float64) string {
+	switch {
+	case calc.IsNA(v):
+		return "NA"
+	case math.IsInf(v, 1):
+		return "Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case v == 0:
+		return "0" // also negative zero
+	default:
+		return fmt.Sprintf("%g", v)
+	}
+}
+
+func quoteString(v string) string {
+	if v == NA_CHARACTER {
+		return "NA"
+	}
+	return "\"" + v + "\""
+}
+
+func formatLogical(v int) string {
+	switch v {
+	case FALSE:
+		return "FALSE"
+	case TRUE:
+		return "TRUE"
+	default:
+		return "NA"
+	}
+}
+
+func PrintResultL(w io.Writer, r *LSEXP) {
+	slice := logicalSlice(r)
+	values := make([]string, len(slice))
+	for n, v := range slice {
+		values[n] = formatLogical(v)
+	}
+	if r.Names() != nil {
+		printNamed(w, r.Names(), values)
+		return
+	}
+	fmt.Fprintf(w, "[1]")
+	for _, v := range values {
+		fmt.Fprintf(w, " %s", v)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func PrintResultT(w io.Writer, r *TSEXP) {
+	if r.Names() != nil {
+		values := make([]string, r.Length())
+		for n, v := range stringSlice(r) {
+			values[n] = quoteString(v)
+		}
+		printNamed(w, r.Names(), values)
+		return
+	}
 	if r.Slice == nil {
-		fmt.Printf("[1] \"%s\"",r.String)
+		fmt.Fprintf(w, "[1] %s",quoteString(r.String))
 	} else {
-		fmt.Printf("[%d]", len(r.Slice))
+		fmt.Fprintf(w, "[%d]", len(r.Slice))
 		for _, v := range r.Slice {
-			fmt.Printf(" \"%s\"", v)
+			fmt.Fprintf(w, " %s", quoteString(v))
 		}
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(w, "\n")
 }
 
-func PrintResultI(r *ISEXP) {
+// atomic vectors without dimensions are printed in one line
+func printVector(w io.Writer, r SEXPItf, values []string) {
+	if r.Names() != nil {
+		printNamed(w, r.Names(), values)
+		return
+	}
+	fmt.Fprintf(w, "[1]")
+	for _, v := range values {
+		fmt.Fprintf(w, " %s", v)
+	}
+	fmt.Fprintf(w, "\n")
+}
+
+func PrintResultC(w io.Writer, r *CSEXP) {
+	slice := complexSlice(r)
+	values := make([]string, len(slice))
+	for n, v := range slice {
+		values[n] = formatComplex(v)
+	}
+	if rdim := r.Dim(); len(rdim) == 2 && len(slice) == rdim[0]*rdim[1] {
+		printMatrixValues(w, values, rdim[0], rdim[1], dimnamesAt(r, 0), dimnamesAt(r, 1))
+		return
+	}
+	printVector(w, r, values)
+}
+
+func PrintResultI(w io.Writer, r *ISEXP) {
 	rdim := r.Dim()
-	if rdim == nil {
-		fmt.Printf("[1] %d\n", r.Integer)
+	if r.Slice != nil && len(rdim) == 2 {
+		slice := make([]float64, len(r.Slice))
+		for n, v := range r.Slice {
+			slice[n] = float64(v)
+		}
+		if r.Dimnames() != nil {
+			printMatrixDimnames(w, slice, rdim[0], rdim[1], dimnamesAt(r, 0), dimnamesAt(r, 1))
+		} else {
+			printMatrix(w, slice, rdim[0], rdim[1])
+		}
+	} else if r.Slice != nil || rdim == nil {
+		slice := integerSlice(r)
+		values := make([]string, len(slice))
+		for n, v := range slice {
+			values[n] = formatInteger(v)
+		}
+		printVector(w, r, values)
 	} else {
-		fmt.Printf("[%d]", len(rdim))
+		fmt.Fprintf(w, "[%d]", len(rdim))
 		for _, v := range rdim {
-			fmt.Printf(" %d", v)
+			fmt.Fprintf(w, " %d", v)
 		}
-		fmt.Printf("\n")
+		fmt.Fprintf(w, "\n")
 	}
 }
 
-func PrintResultE(r *ESEXP) {
+func PrintResultE(w io.Writer, r *ESEXP) {
 	switch r.Kind {
 	case token.ILLEGAL:
 		//if DEBUG {
-			//println("ILLEGAL RESULT")
+			//ev.println("ILLEGAL RESULT")
 		//}
 	case token.VERSION:
-		version.PrintVersion()
+		version.FprintVersion(w)
 	case token.EOF:
 	default:
-		fmt.Printf("%s",r.Message)
+		fmt.Fprintf(w, "%s",r.Message)
 	}
 }
 
-func PrintResultV(r *VSEXP) {
+func PrintResultV(w io.Writer, r *VSEXP) {
 	if r== nil {
-		fmt.Printf("nil\n")
+		fmt.Fprintf(w, "nil\n")
 	} else if r.Body != nil {
-		fmt.Printf("function(")
+		fmt.Fprintf(w, "function(")
 		for n, field := range r.Fieldlist {
 			if n > 0 {
-				fmt.Printf(",")
+				fmt.Fprintf(w, ",")
 			}
 			switch field.Type.(type){
 			case *ast.Ident:
 				identifier := field.Type.(*ast.Ident)
-				fmt.Printf(identifier.Name)
+				fmt.Fprint(w, identifier.Name)
 			case *ast.Ellipsis:
-				fmt.Printf("...")
+				fmt.Fprintf(w, "...")
 			default:
 				panic("while printing function")
 			}
 		}
-		fmt.Printf(")\n")
+		fmt.Fprintf(w, ")\n")
+	} else if r.builtin != nil {
+		fmt.Fprintf(w, "function(%s) .Primitive(\"%s\")\n", strings.Join(r.builtin.formals, ","), r.builtin.name)
+	} else if r.Names() != nil && r.Dim() == nil {
+		values := make([]string, r.Length())
+		for n, v := range floatSlice(r) {
+			values[n] = formatFloat(v)
+		}
+		printNamed(w, r.Names(), values)
 	} else {
 		if r.Slice == nil {
-			if r.Immediate == math.NaN(){
-				fmt.Printf("NAN\n")
-			} else {
-				fmt.Printf("[1] %g\n", r.Immediate) // R has small e for exponential format
-			}
+			fmt.Fprintf(w, "[1] %s\n", formatFloat(r.Immediate)) // R has small e for exponential format
 		} else {
 			rdim := r.Dim()
 			if rdim == nil {
-				fmt.Printf("[1]")
-				printArray(r.Slice)
+				fmt.Fprintf(w, "[1]")
+				printArray(w, r.Slice)
 			} else if len(rdim) == 2 && r.Dimnames() != nil {
-				printMatrixDimnames(r.Slice,
+				printMatrixDimnames(w, r.Slice,
 					rdim[0],
 					rdim[1],
-					r.Dimnames().Slice[0].(*TSEXP).Slice,
-					r.Dimnames().Slice[1].(*TSEXP).Slice)
+					dimnamesAt(r, 0),
+					dimnamesAt(r, 1))
 			} else if len(rdim) == 2 {
-				printMatrix(r.Slice, rdim[0], rdim[1])
+				printMatrix(w, r.Slice, rdim[0], rdim[1])
 			} else {
-				fmt.Printf("[")
+				fmt.Fprintf(w, "[")
 				for n, v := range rdim {
 					if n > 0 {
-						fmt.Printf(",")
+						fmt.Fprintf(w, ",")
 					}
-					fmt.Printf("%d", v)
+					fmt.Fprintf(w, "%d", v)
 				}
-				fmt.Printf("]")
-				printArray(r.Slice)
+				fmt.Fprintf(w, "]")
+				printArray(w, r.Slice)
 			}
 		}
 	}
 }
 
-func printArray(slice []float64) {
+func printArray(w io.Writer, slice []float64) {
 	for _, v := range slice {
-		fmt.Printf(" %g", v)
+		fmt.Fprintf(w, " %s", formatFloat(v))
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(w, "\n")
+}
+
+func printMatrixDimnames(w io.Writer, slice []float64, rows int, cols int, rownames []string, colnames []string) {
+	values := make([]string, len(slice))
+	for n, v := range slice {
+		values[n] = formatFloat(v)
+	}
+	printMatrixValues(w, values, rows, cols, rownames, colnames)
+}
+
+func printMatrix(w io.Writer, slice []float64, rows int, cols int) {
+	printMatrixDimnames(w, slice, rows, cols, nil, nil)
 }
 
-func printMatrixDimnames(slice []float64, rows int, cols int, rownames []string, colnames []string) {
+// formatted values in column major order, missing dimnames are replaced by indices
+func printMatrixValues(w io.Writer, values []string, rows int, cols int, rownames []string, colnames []string) {
 	for col := 0; col < cols; col++ {
 		if col < len(colnames) {
-			fmt.Printf("\t%s", colnames[col])
+			fmt.Fprintf(w, "\t%s", colnames[col])
 		} else {
-			fmt.Printf("\t[,%d]", col+1)
+			fmt.Fprintf(w, "\t[,%d]", col+1)
 		}
 	}
-	fmt.Printf("\n")
+	fmt.Fprintf(w, "\n")
 	for row := 0; row < rows; row++ {
 		if row < len(rownames) {
-			fmt.Printf("%s",rownames[row])
+			fmt.Fprintf(w, "%s",rownames[row])
 		} else {
-			fmt.Printf("[%d]", row+1)
-		}
-		for col := 0; col < cols; col++ {
-			fmt.Printf("\t%s", fmt.Sprintf("%g",slice[row+rows*col]))
+			fmt.Fprintf(w, "[%d]", row+1)
 		}
-		fmt.Printf("\n")
-	}
-}
-
-func printMatrix(slice []float64, rows int, cols int) {
-	for col := 0; col < cols; col++ {
-		fmt.Printf("\t[,%d]", col+1)
-	}
-	fmt.Printf("\n")
-	for row := 0; row < rows; row++ {
-		fmt.Printf("[%d]", row+1)
 		for col := 0; col < cols; col++ {
-			fmt.Printf("\t%s", fmt.Sprintf("%g",slice[row+rows*col]))
+			fmt.Fprintf(w, "\t%s", values[row+rows*col])
 		}
-		fmt.Printf("\n")
+		fmt.Fprintf(w, "\n")
 	}
 }
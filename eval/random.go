@@ -0,0 +1,284 @@
+package eval
+
+import (
+	"math"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/random"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/Random.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/Uniform.html
+// https://stat.ethz.ch/R-manual/R-devel/library/parallel/html/RngStream.html
+// Random numbers are drawn from the generator of the evaluator, which is shared with
+// its copies inside loops. Its state is read from and written to .Random.seed in the
+// global frame, as in R. Forked evaluators get a generator spawned from their parent:
+// with L'Ecuyer-CMRG the next stream, so that goroutines give the same numbers in any order.
+
+func init() {
+	registerBuiltin("set.seed", []string{"seed", "kind", "normal.kind", "sample.kind"}, EvalSetSeed)
+	registerBuiltin("RNGkind", []string{"kind", "normal.kind", "sample.kind"}, EvalRNGkind)
+	registerBuiltin("sample", []string{"x", "size", "replace", "prob"}, EvalSample)
+	registerBuiltin("sample.int", []string{"n", "size", "replace", "prob", "useHash"}, EvalSampleInt)
+	registerBuiltin("nextRNGStream", []string{"seed"}, EvalNextRNGStream)
+	registerBuiltin("nextRNGSubStream", []string{"seed"}, EvalNextRNGSubStream)
+}
+
+// an invalid .Random.seed is ignored with a warning and replaced by a seed from time
+func (e *Evaluator) getRNGstate() {
+	v, ok := e.globalFrame.Objects[".Random.seed"]
+	if !ok {
+		return
+	}
+	if sexpType(v) != INTSXP {
+		e.warning("", "'.Random.seed' is not an integer vector but of type '"+typeName(sexpType(v))+"', so ignored")
+		e.rng.Randomize()
+		return
+	}
+	warn := false
+	if err := e.rng.SetState(asIntegers(v, &warn)); err != nil {
+		e.warning("", err.Error())
+		e.rng.Randomize()
+	}
+}
+
+func (e *Evaluator) putRNGstate() {
+	e.globalFrame.Insert(".Random.seed", &ISEXP{Slice: e.rng.State()})
+}
+
+// the kind matching a prefix of the argument, "default" for the first one
+func rngKindArgument(ev *Evaluator, funcname string, x SEXPItf, kinds []string, what string) (string, bool) {
+	if x == nil || sexpType(x) == NILSXP {
+		return "", true
+	}
+	if sexpType(x) != STRSXP || x.Length() != 1 {
+		builtinError(ev, funcname, "'%s' must be a character string of length 1 (RNG to be used).", what)
+		return "", false
+	}
+	name := asStrings(x)[0]
+	if name == "default" {
+		return kinds[len(kinds)-1], true
+	}
+	for _, k := range kinds {
+		if name != "" && strings.HasPrefix(k, name) {
+			return k, true
+		}
+	}
+	builtinError(ev, funcname, "'%s' is not a valid choice", name)
+	return "", false
+}
+
+// the kinds are set in the order of the arguments, a new kind of generator is seeded from the old one
+func setRNGkind(ev *Evaluator, funcname string, args *Arguments, first int) bool {
+	kind, ok := rngKindArgument(ev, funcname, args.Values[first], random.Kinds, "kind")
+	if !ok {
+		return false
+	}
+	normal, ok := rngKindArgument(ev, funcname, args.Values[first+1], random.NormalKinds, "normal.kind")
+	if !ok {
+		return false
+	}
+	sample, ok := rngKindArgument(ev, funcname, args.Values[first+2], random.SampleKinds, "sample.kind")
+	if !ok {
+		return false
+	}
+	if kind != "" {
+		ev.rng.SetKind(kind)
+	}
+	if normal != "" {
+		ev.rng.NormalKind = normal
+	}
+	if sample != "" {
+		if sample == random.Rounding {
+			ev.warning(funcname+"()", "non-uniform 'Rounding' sampler used")
+		}
+		ev.rng.SampleKind = sample
+	}
+	return true
+}
+
+func EvalRNGkind(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	ev.getRNGstate()
+	old := &TSEXP{ValuePos: node.Fun.Pos(), Slice: []string{ev.rng.Kind, ev.rng.NormalKind, ev.rng.SampleKind}}
+	if !setRNGkind(ev, "RNGkind", args, 0) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	for _, v := range args.Values {
+		if v != nil {
+			ev.Invisible = true
+			ev.putRNGstate()
+		}
+	}
+	return old
+}
+
+// set.seed(NULL) seeds from time
+func EvalSetSeed(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	seed := args.Values[0]
+	if seed == nil {
+		return builtinError(ev, "set.seed", "argument \"seed\" is missing, with no default")
+	}
+	ev.getRNGstate()
+	if !setRNGkind(ev, "set.seed", args, 1) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if sexpType(seed) == NILSXP {
+		ev.rng.Randomize()
+	} else {
+		warn := false
+		if !isAtomic(seed) || seed.Length() == 0 || sexpType(seed) == STRSXP || asIntegers(seed, &warn)[0] == NA_INTEGER {
+			return builtinError(ev, "set.seed", "supplied seed is not a valid integer")
+		}
+		ev.rng.Seed(int32(asIntegers(seed, &warn)[0]))
+	}
+	ev.putRNGstate()
+	ev.Invisible = true
+	return &NSEXP{}
+}
+
+// sample(n) for a single number n >= 1 samples 1:n, otherwise the elements of x
+func EvalSample(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "sample", "argument \"x\" is missing, with no default")
+	}
+	warn := false
+	switch sexpType(x) {
+	case INTSXP, REALSXP:
+		if x.Length() == 1 {
+			// as for sample.int, a population of size zero gives integer(0)
+			if v := asFloats(x, &warn)[0]; !math.IsInf(v, 0) && (v >= 1 || v == 0) {
+				return sampleIndices(ev, node, "sample", v, args)
+			}
+		}
+	}
+	if !isAtomic(x) && sexpType(x) != VECSXP && sexpType(x) != NILSXP {
+		return builtinError(ev, "sample", "invalid first argument")
+	}
+	r := sampleIndices(ev, node, "sample", float64(x.Length()), args)
+	if isError(r) {
+		return r
+	}
+	offsets := asIntegers(r, &warn)
+	for n := range offsets {
+		offsets[n]--
+	}
+	return indexElements(ev, x, offsets)
+}
+
+func EvalSampleInt(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	n := args.Values[0]
+	if n == nil {
+		return builtinError(ev, "sample.int", "argument \"n\" is missing, with no default")
+	}
+	if !isAtomic(n) || sexpType(n) == STRSXP || n.Length() != 1 {
+		return builtinError(ev, "sample.int", "invalid first argument")
+	}
+	warn := false
+	return sampleIndices(ev, node, "sample.int", asFloats(n, &warn)[0], args)
+}
+
+// one-based indices of a sample of size from 1:n, hashing for large n and small samples without replacement
+func sampleIndices(ev *Evaluator, node *ast.CallExpr, funcname string, dn float64, args *Arguments) SEXPItf {
+	if math.IsNaN(dn) || dn < 0 || dn > math.MaxInt32 {
+		return builtinError(ev, funcname, "invalid first argument")
+	}
+	n := int(dn)
+	size := n
+	if v := args.Values[1]; v != nil && sexpType(v) != NILSXP {
+		warn := false
+		if !isAtomic(v) || v.Length() != 1 || sexpType(v) == STRSXP {
+			return builtinError(ev, funcname, "invalid 'size' argument")
+		}
+		s := asFloats(v, &warn)[0]
+		if math.IsNaN(s) || s < 0 || s > math.MaxInt32 {
+			return builtinError(ev, funcname, "invalid 'size' argument")
+		}
+		size = int(s)
+	}
+	replace := args.logical(2, false)
+	prob := args.Values[3]
+	ev.getRNGstate()
+	defer ev.putRNGstate()
+	var r []int
+	switch {
+	case prob != nil && sexpType(prob) != NILSXP:
+		if !isAtomic(prob) || sexpType(prob) == STRSXP {
+			return builtinError(ev, funcname, "incorrect number of probabilities")
+		}
+		warn := false
+		p := asFloats(prob, &warn)
+		if len(p) != n {
+			return builtinError(ev, funcname, "incorrect number of probabilities")
+		}
+		if !fixupProb(ev, funcname, p, size, replace) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		r = ev.rng.SampleProb(p, size, replace)
+	case !replace && size > n:
+		return builtinError(ev, funcname, "cannot take a sample larger than the population when 'replace = FALSE'")
+	case !replace && useHash(args, n > 1e7 && size <= n/2):
+		r = ev.rng.SampleHash(n, size)
+	default:
+		r = ev.rng.Sample(n, size, replace)
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Slice: r}
+}
+
+// only sample.int has the argument useHash
+func useHash(args *Arguments, def bool) bool {
+	if len(args.Values) < 5 {
+		return def
+	}
+	return args.logical(4, def)
+}
+
+// probabilities are checked and normalized to sum up to one
+func fixupProb(ev *Evaluator, funcname string, p []float64, size int, replace bool) bool {
+	sum := 0.0
+	positive := 0
+	for _, v := range p {
+		switch {
+		case math.IsNaN(v) || math.IsInf(v, 0):
+			builtinError(ev, funcname, "NA in probability vector")
+			return false
+		case v < 0:
+			builtinError(ev, funcname, "negative probability")
+			return false
+		case v > 0:
+			positive++
+			sum += v
+		}
+	}
+	if positive == 0 || (!replace && size > positive) {
+		builtinError(ev, funcname, "too few positive probabilities")
+		return false
+	}
+	for n := range p {
+		p[n] /= sum
+	}
+	return true
+}
+
+// the seed of .Random.seed for L'Ecuyer-CMRG
+func streamSeed(ev *Evaluator, funcname string, seed SEXPItf, sub bool) SEXPItf {
+	if seed == nil {
+		return builtinError(ev, funcname, "argument \"seed\" is missing, with no default")
+	}
+	warn := false
+	if sexpType(seed) != INTSXP || seed.Length() != 7 || asIntegers(seed, &warn)[0]%100 != 7 {
+		return builtinError(ev, funcname, "invalid value of 'seed'")
+	}
+	s := asIntegers(seed, &warn)
+	next, _ := random.NextStream(s[1:], sub)
+	return &ISEXP{ValuePos: seed.Pos(), Slice: append([]int{s[0]}, next...)}
+}
+
+func EvalNextRNGStream(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return streamSeed(ev, "nextRNGStream", args.Values[0], false)
+}
+
+func EvalNextRNGSubStream(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return streamSeed(ev, "nextRNGSubStream", args.Values[0], true)
+}
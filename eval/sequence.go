@@ -0,0 +1,455 @@
+package eval
+
+import (
+	"fmt"
+	"math"
+	"roq/lib/ast"
+	"roq/lib/token"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/seq.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/rep.html
+// Sequences are integers, if the start is a whole number within the range of integers,
+// otherwise doubles. Repetitions and reversals keep the type and the names of their argument.
+// Vectors of a given mode and length are filled with zeros, FALSE, empty strings or NULL.
+
+func init() {
+	registerBuiltin("seq", []string{"from", "to", "by", "length.out", "along.with", "..."}, EvalSeq)
+	registerBuiltin("seq_len", []string{"length.out"}, EvalSeqLen)
+	registerBuiltin("seq_along", []string{"along.with"}, EvalSeqAlong)
+	registerBuiltin("rep", []string{"x", "times", "length.out", "each", "..."}, EvalRep)
+	registerBuiltin("rep_len", []string{"x", "length.out"}, EvalRepLen)
+	registerBuiltin("rep.int", []string{"x", "times"}, EvalRepInt)
+	registerBuiltin("rev", []string{"x"}, EvalRev)
+	registerBuiltin("vector", []string{"mode", "length"}, EvalVector)
+	for _, mode := range []string{"logical", "integer", "numeric", "double", "complex", "character"} {
+		mode := mode
+		registerBuiltin(mode, []string{"length"}, func(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+			return newModeVector(ev, node, mode, mode, args.Values[0])
+		})
+	}
+}
+
+// the first element of an operand of from:to
+func colonArgument(ev *Evaluator, x SEXPItf) (float64, bool) {
+	if x == nil || !isAtomic(x) || x.Length() == 0 {
+		errorf("argument of length 0")
+		return 0, false
+	}
+	if x.Length() > 1 {
+		ev.warning("", fmt.Sprintf("numerical expression has %d elements: only the first used", x.Length()))
+	}
+	warn := false
+	v := asFloats(x, &warn)[0]
+	if math.IsNaN(v) {
+		errorf("NA/NaN argument")
+		return 0, false
+	}
+	return v, true
+}
+
+func EvalColon(ev *Evaluator, x SEXPItf, y SEXPItf) SEXPItf {
+	from, ok := colonArgument(ev, x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	to, ok := colonArgument(ev, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return colonSequence(ev, from, to)
+}
+
+// ascending or descending in steps of one, ending before passing to
+func colonSequence(ev *Evaluator, from float64, to float64) SEXPItf {
+	n := math.Floor(math.Abs(to-from)+1e-10) + 1
+	if n > math.MaxInt32 {
+		return errorf("result would be too long a vector")
+	}
+	step := 1.0
+	if from > to {
+		step = -1
+	}
+	last := from + step*(n-1)
+	if from == math.Trunc(from) && integerRange(from) && integerRange(last) {
+		slice := make([]int, int(n))
+		for k := range slice {
+			slice[k] = int(from) + k*int(step)
+		}
+		return &ISEXP{Slice: slice}
+	}
+	slice := make([]float64, int(n))
+	for k := range slice {
+		slice[k] = from + float64(k)*step
+	}
+	return &VSEXP{Slice: slice}
+}
+
+func integerRange(v float64) bool {
+	return v > math.MinInt32 && v <= math.MaxInt32
+}
+
+func integerSequence(n int) *ISEXP {
+	slice := make([]int, n)
+	for k := range slice {
+		slice[k] = k + 1
+	}
+	return &ISEXP{Slice: slice}
+}
+
+func seqArgument(ev *Evaluator, funcname string, formal string, v SEXPItf) (float64, bool) {
+	if !isAtomic(v) || sexpType(v) == STRSXP || v.Length() != 1 {
+		builtinError(ev, funcname, "'%s' must be of length 1", formal)
+		return 0, false
+	}
+	warn := false
+	f := asFloats(v, &warn)[0]
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		builtinError(ev, funcname, "'%s' must be a finite number", formal)
+		return 0, false
+	}
+	return f, true
+}
+
+func EvalSeq(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	from, to, by, lengthOut, along := args.Values[0], args.Values[1], args.Values[2], args.Values[3], args.Values[4]
+	if along != nil {
+		lengthOut = &ISEXP{Immediate: float64(along.Length()), Integer: along.Length()}
+		if from == nil && to == nil && by == nil {
+			return integerSequence(along.Length())
+		}
+	}
+	if from != nil && to == nil && by == nil && lengthOut == nil {
+		if from.Length() == 1 && sexpType(from) != STRSXP {
+			f, ok := seqArgument(ev, "seq", "from", from)
+			if !ok {
+				return &ESEXP{Kind: token.ILLEGAL}
+			}
+			return colonSequence(ev, 1, f)
+		}
+		return integerSequence(from.Length())
+	}
+	f, t := 1.0, 1.0
+	var ok bool
+	if from != nil {
+		if f, ok = seqArgument(ev, "seq", "from", from); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+	}
+	if to != nil {
+		if t, ok = seqArgument(ev, "seq", "to", to); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+	}
+	if lengthOut == nil {
+		if by == nil {
+			return colonSequence(ev, f, t)
+		}
+		b, ok := seqArgument(ev, "seq", "by", by)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		return seqBy(ev, from, to, by, f, t, b)
+	}
+	lo, ok := seqArgument(ev, "seq", "length.out", lengthOut)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if lo < 0 {
+		return builtinError(ev, "seq", "'length.out' must be a non-negative number")
+	}
+	n := int(math.Ceil(lo))
+	switch {
+	case by == nil:
+		if from == nil && to == nil {
+			return integerSequence(n)
+		}
+		if to == nil {
+			t = f + float64(n) - 1
+		} else if from == nil {
+			f = t - float64(n) + 1
+		}
+		if n == 1 {
+			return &VSEXP{Slice: []float64{f}}
+		}
+		slice := make([]float64, n)
+		if n > 0 {
+			step := (t - f) / float64(n-1)
+			for k := range slice {
+				slice[k] = f + float64(k)*step
+			}
+			slice[n-1] = t
+		}
+		return &VSEXP{Slice: slice}
+	case from == nil || to == nil:
+		b, ok := seqArgument(ev, "seq", "by", by)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		if from == nil {
+			f = t - float64(n-1)*b
+		}
+		slice := make([]float64, n)
+		for k := range slice {
+			slice[k] = f + float64(k)*b
+		}
+		return &VSEXP{Slice: slice}
+	default:
+		return builtinError(ev, "seq", "too many arguments")
+	}
+}
+
+// steps of by, which do not pass to; integers stay integers
+func seqBy(ev *Evaluator, from SEXPItf, to SEXPItf, by SEXPItf, f float64, t float64, b float64) SEXPItf {
+	del := t - f
+	if del == 0 && t == 0 {
+		return to
+	}
+	n := del / b
+	if math.IsNaN(n) || math.IsInf(n, 0) {
+		if b == 0 && del == 0 {
+			return from
+		}
+		return builtinError(ev, "seq", "invalid '(to - from)/by' in seq(.)")
+	}
+	if n < 0 {
+		return builtinError(ev, "seq", "wrong sign in 'by' argument")
+	}
+	if n > math.MaxInt32 {
+		return builtinError(ev, "seq", "'by' argument is much too small")
+	}
+	if math.Abs(del)/math.Max(math.Abs(t), math.Abs(f)) < 100*2.220446049250313e-16 {
+		return from
+	}
+	isInteger := func(v SEXPItf) bool { return v != nil && sexpType(v) == INTSXP }
+	if isInteger(from) && isInteger(to) && isInteger(by) {
+		slice := make([]int, int(n)+1)
+		for k := range slice {
+			slice[k] = int(f) + k*int(b)
+		}
+		return &ISEXP{Slice: slice}
+	}
+	slice := make([]float64, int(n+1e-10)+1)
+	for k := range slice {
+		slice[k] = f + float64(k)*b
+		if (b > 0 && slice[k] > t) || (b < 0 && slice[k] < t) {
+			slice[k] = t
+		}
+	}
+	return &VSEXP{Slice: slice}
+}
+
+func EvalSeqLen(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	v := args.Values[0]
+	if v == nil {
+		return builtinError(ev, "seq_len", "argument \"length.out\" is missing, with no default")
+	}
+	if !isAtomic(v) || v.Length() != 1 {
+		return builtinError(ev, "seq_len", "argument of length 0")
+	}
+	warn := false
+	n := asFloats(v, &warn)[0]
+	if math.IsNaN(n) || n < 0 {
+		return builtinError(ev, "seq_len", "argument must be coercible to non-negative integer")
+	}
+	return integerSequence(int(n))
+}
+
+func EvalSeqAlong(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	v := args.Values[0]
+	if v == nil {
+		return builtinError(ev, "seq_along", "argument \"along.with\" is missing, with no default")
+	}
+	return integerSequence(v.Length())
+}
+
+func repCounts(ev *Evaluator, funcname string, formal string, v SEXPItf) ([]int, bool) {
+	warn := false
+	counts := asIntegers(v, &warn)
+	for _, c := range counts {
+		if c == NA_INTEGER || c < 0 {
+			builtinError(ev, funcname, "invalid '%s' argument", formal)
+			return nil, false
+		}
+	}
+	return counts, true
+}
+
+// offsets for n elements repeated each times, then as a whole or elementwise times,
+// finally recycled to lengthOut, if not negative
+func repOffsets(ev *Evaluator, funcname string, n int, times []int, lengthOut int, each int) ([]int, bool) {
+	offsets := make([]int, 0, n*each)
+	for k := 0; k < n; k++ {
+		for e := 0; e < each; e++ {
+			offsets = append(offsets, k)
+		}
+	}
+	if lengthOut >= 0 {
+		r := make([]int, lengthOut)
+		for k := range r {
+			if len(offsets) == 0 {
+				r[k] = -1
+			} else {
+				r[k] = offsets[k%len(offsets)]
+			}
+		}
+		return r, true
+	}
+	switch len(times) {
+	case 1:
+		r := make([]int, 0, len(offsets)*times[0])
+		for t := 0; t < times[0]; t++ {
+			r = append(r, offsets...)
+		}
+		return r, true
+	case len(offsets):
+		r := []int{}
+		for k, o := range offsets {
+			for t := 0; t < times[k]; t++ {
+				r = append(r, o)
+			}
+		}
+		return r, true
+	default:
+		builtinError(ev, funcname, "invalid 'times' argument")
+		return nil, false
+	}
+}
+
+func repeated(ev *Evaluator, x SEXPItf, offsets []int) SEXPItf {
+	if sexpType(x) == NILSXP {
+		return &NSEXP{}
+	}
+	r := indexElements(ev, x, offsets)
+	if _, ok := r.(*ESEXP); !ok {
+		r.NamesSet(selectNames(x.Names(), offsets))
+	}
+	return r
+}
+
+func EvalRep(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "rep", "attempt to replicate an object of type 'symbol'")
+	}
+	times := []int{1}
+	if !args.missing(1) {
+		var ok bool
+		if times, ok = repCounts(ev, "rep", "times", args.Values[1]); !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+	}
+	lengthOut := -1
+	if !args.missing(2) {
+		if l := args.float(2, -1); !math.IsNaN(l) {
+			if l < 0 {
+				return builtinError(ev, "rep", "invalid 'length.out' argument")
+			}
+			lengthOut = int(l)
+		}
+	}
+	each := 1
+	if !args.missing(3) {
+		e := args.float(3, 1)
+		if math.IsNaN(e) || e < 0 {
+			return builtinError(ev, "rep", "invalid 'each' argument")
+		}
+		each = int(e)
+	}
+	offsets, ok := repOffsets(ev, "rep", x.Length(), times, lengthOut, each)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return repeated(ev, x, offsets)
+}
+
+func EvalRepLen(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	l := args.float(1, math.NaN())
+	if x == nil || math.IsNaN(l) || l < 0 {
+		return builtinError(ev, "rep_len", "invalid 'length.out' value")
+	}
+	offsets, _ := repOffsets(ev, "rep_len", x.Length(), nil, int(l), 1)
+	r := repeated(ev, x, offsets)
+	r.NamesSet(nil)
+	return r
+}
+
+func EvalRepInt(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil || args.missing(1) {
+		return builtinError(ev, "rep.int", "invalid type (NULL) for 'times' (must be a vector)")
+	}
+	times, ok := repCounts(ev, "rep.int", "times", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	offsets, ok := repOffsets(ev, "rep.int", x.Length(), times, -1, 1)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := repeated(ev, x, offsets)
+	r.NamesSet(nil)
+	return r
+}
+
+func EvalRev(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "rev", "argument \"x\" is missing, with no default")
+	}
+	offsets := make([]int, x.Length())
+	for k := range offsets {
+		offsets[k] = len(offsets) - 1 - k
+	}
+	return repeated(ev, x, offsets)
+}
+
+func newModeVector(ev *Evaluator, node *ast.CallExpr, funcname string, mode string, length SEXPItf) SEXPItf {
+	n := 0
+	if length != nil {
+		warn := false
+		if !isAtomic(length) || length.Length() != 1 {
+			return builtinError(ev, funcname, "invalid 'length' argument")
+		}
+		v := asFloats(length, &warn)[0]
+		if math.IsNaN(v) || v < 0 || v > math.MaxInt32 {
+			return builtinError(ev, funcname, "invalid 'length' argument")
+		}
+		n = int(v)
+	}
+	t, ok := modeType(mode)
+	if !ok || t == ANYSXP {
+		return builtinError(ev, funcname, "vector: cannot make a vector of mode '%s'.", mode)
+	}
+	pos := node.Fun.Pos()
+	switch t {
+	case LGLSXP:
+		return &LSEXP{ValuePos: pos, Slice: make([]int, n)}
+	case INTSXP:
+		return &ISEXP{ValuePos: pos, Slice: make([]int, n)}
+	case REALSXP:
+		return &VSEXP{ValuePos: pos, Slice: make([]float64, n)}
+	case CPLXSXP:
+		return &CSEXP{ValuePos: pos, Slice: make([]complex128, n)}
+	case STRSXP:
+		return &TSEXP{ValuePos: pos, Slice: make([]string, n)}
+	default:
+		r := &RSEXP{ValuePos: pos, Slice: make([]SEXPItf, n)}
+		for k := range r.Slice {
+			r.Slice[k] = &NSEXP{}
+		}
+		return r
+	}
+}
+
+func EvalVector(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	mode := "logical"
+	if m := args.Values[0]; m != nil {
+		if sexpType(m) != STRSXP || m.Length() != 1 {
+			return builtinError(ev, "vector", "invalid 'mode' argument")
+		}
+		mode = asStrings(m)[0]
+	}
+	return newModeVector(ev, node, "vector", mode, args.Values[1])
+}
@@ -0,0 +1,320 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"strconv"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/unique.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/match.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/sets.html
+// Elements are compared by a key of their value after coercion to a common type.
+// NA matches NA, NaN matches NaN and both zeros are equal.
+
+func init() {
+	registerBuiltin("unique", []string{"x", "incomparables", "fromLast", "..."}, EvalUnique)
+	registerBuiltin("duplicated", []string{"x", "incomparables", "fromLast", "..."}, EvalDuplicated)
+	registerBuiltin("anyDuplicated", []string{"x", "incomparables", "fromLast", "..."}, EvalAnyDuplicated)
+	registerBuiltin("which", []string{"x", "arr.ind", "useNames"}, EvalWhich)
+	registerBuiltin("which.max", []string{"x"}, EvalWhichMax)
+	registerBuiltin("which.min", []string{"x"}, EvalWhichMin)
+	registerBuiltin("match", []string{"x", "table", "nomatch", "incomparables"}, EvalMatch)
+	registerBuiltin("%in%", []string{"x", "table"}, EvalIn)
+	registerBuiltin("union", []string{"x", "y"}, EvalUnion)
+	registerBuiltin("intersect", []string{"x", "y"}, EvalIntersect)
+	registerBuiltin("setdiff", []string{"x", "y"}, EvalSetdiff)
+}
+
+func floatKey(v float64) string {
+	switch {
+	case calc.IsNA(v):
+		return "NA"
+	case math.IsNaN(v):
+		return "NaN"
+	case v == 0:
+		return "0"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// keys of elements, lists are compared by type and keys of their elements
+func elementKeys(x SEXPItf) []string {
+	warn := false
+	switch x.(type) {
+	case *VSEXP:
+		slice := asFloats(x, &warn)
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = floatKey(v)
+		}
+		return r
+	case *CSEXP:
+		slice := asComplexes(x, &warn)
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = floatKey(real(v)) + "," + floatKey(imag(v))
+		}
+		return r
+	case *RSEXP:
+		slice := x.(*RSEXP).Slice
+		r := make([]string, len(slice))
+		for n, v := range slice {
+			r[n] = typeName(sexpType(v)) + "\x01" + strings.Join(elementKeys(v), "\x02")
+		}
+		return r
+	case nil, *NSEXP:
+		return []string{}
+	default:
+		return asStrings(x)
+	}
+}
+
+func vectorArgument(ev *Evaluator, funcname string, x SEXPItf) bool {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"x\" is missing, with no default")
+		return false
+	}
+	if !isAtomic(x) && sexpType(x) != VECSXP && sexpType(x) != NILSXP {
+		builtinError(ev, funcname, "%s() applies only to vectors", funcname)
+		return false
+	}
+	return true
+}
+
+// flags for elements equal to an earlier one, or to a later one if fromLast
+func duplicatedFlags(keys []string, fromLast bool) []bool {
+	seen := map[string]bool{}
+	r := make([]bool, len(keys))
+	for n := range keys {
+		k := n
+		if fromLast {
+			k = len(keys) - 1 - n
+		}
+		r[k] = seen[keys[k]]
+		seen[keys[k]] = true
+	}
+	return r
+}
+
+func EvalUnique(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !vectorArgument(ev, "unique", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if sexpType(x) == NILSXP {
+		return &NSEXP{}
+	}
+	offsets := []int{}
+	for n, d := range duplicatedFlags(elementKeys(x), args.logical(2, false)) {
+		if !d {
+			offsets = append(offsets, n)
+		}
+	}
+	return indexElements(ev, x, offsets)
+}
+
+func EvalDuplicated(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !vectorArgument(ev, "duplicated", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	flags := duplicatedFlags(elementKeys(x), args.logical(2, false))
+	r := make([]int, len(flags))
+	for n, d := range flags {
+		r[n] = logical(d)
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+}
+
+func EvalAnyDuplicated(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !vectorArgument(ev, "anyDuplicated", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	for n, d := range duplicatedFlags(elementKeys(x), args.logical(2, false)) {
+		if d {
+			return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(n + 1), Integer: n + 1}
+		}
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: 0, Integer: 0}
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/which.html
+// indices of TRUE values with their names
+func EvalWhich(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "which", "argument \"x\" is missing, with no default")
+	}
+	truth, ok := truthValues(x)
+	if !ok {
+		return builtinError(ev, "which", "argument to 'which' is not logical")
+	}
+	offsets := []int{}
+	for n, v := range truth {
+		if v == TRUE {
+			offsets = append(offsets, n)
+		}
+	}
+	indices := make([]int, len(offsets))
+	for n, o := range offsets {
+		indices[n] = o + 1
+	}
+	r := &ISEXP{ValuePos: node.Fun.Pos(), Slice: indices}
+	if args.logical(2, true) {
+		r.NamesSet(selectNames(x.Names(), offsets))
+	}
+	return r
+}
+
+// index of the first extreme, ignoring missing values
+func whichExtremum(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, sign float64) SEXPItf {
+	if x == nil {
+		return builtinError(ev, funcname, "argument \"x\" is missing, with no default")
+	}
+	switch sexpType(x) {
+	case NILSXP, LGLSXP, INTSXP, REALSXP:
+	default:
+		return builtinError(ev, funcname, "invalid 'type' (%s) of argument", typeName(sexpType(x)))
+	}
+	warn := false
+	best := -1
+	slice := asFloats(x, &warn)
+	for n, v := range slice {
+		if !math.IsNaN(v) && (best < 0 || (v-slice[best])*sign > 0) {
+			best = n
+		}
+	}
+	r := &ISEXP{ValuePos: node.Fun.Pos(), Slice: []int{}}
+	if best >= 0 {
+		r.Slice = []int{best + 1}
+		r.NamesSet(selectNames(x.Names(), []int{best}))
+	}
+	return r
+}
+
+func EvalWhichMax(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return whichExtremum(ev, node, "which.max", args.Values[0], 1)
+}
+
+func EvalWhichMin(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return whichExtremum(ev, node, "which.min", args.Values[0], -1)
+}
+
+// both arguments coerced to their common type, which is returned as well
+func commonVectors(ev *Evaluator, funcname string, x SEXPItf, y SEXPItf) (SEXPItf, SEXPItf, bool) {
+	for _, v := range []SEXPItf{x, y} {
+		if v != nil && !isAtomic(v) && sexpType(v) != VECSXP && sexpType(v) != NILSXP {
+			builtinError(ev, funcname, "'%s' requires vector arguments", funcname)
+			return nil, nil, false
+		}
+	}
+	t := highestType([]SEXPItf{x, y})
+	if t == NILSXP {
+		return &NSEXP{}, &NSEXP{}, true
+	}
+	return coerceVector(ev, x, t), coerceVector(ev, y, t), true
+}
+
+// zero-based offsets of the first matches in table, -1 for no match
+func matchOffsets(x SEXPItf, table SEXPItf) []int {
+	positions := map[string]int{}
+	for n, k := range elementKeys(table) {
+		if _, ok := positions[k]; !ok {
+			positions[k] = n
+		}
+	}
+	keys := elementKeys(x)
+	r := make([]int, len(keys))
+	for n, k := range keys {
+		if p, ok := positions[k]; ok {
+			r[n] = p
+		} else {
+			r[n] = -1
+		}
+	}
+	return r
+}
+
+func EvalMatch(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, table, ok := commonVectors(ev, "match", args.Values[0], args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	nomatch := NA_INTEGER
+	if v := args.Values[2]; v != nil && isAtomic(v) && v.Length() > 0 {
+		warn := false
+		nomatch = asIntegers(v, &warn)[0]
+	}
+	offsets := matchOffsets(x, table)
+	r := make([]int, len(offsets))
+	for n, o := range offsets {
+		if o < 0 {
+			r[n] = nomatch
+		} else {
+			r[n] = o + 1
+		}
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Slice: r}
+}
+
+func EvalIn(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, table, ok := commonVectors(ev, "match", args.Values[0], args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	offsets := matchOffsets(x, table)
+	r := make([]int, len(offsets))
+	for n, o := range offsets {
+		r[n] = logical(o >= 0)
+	}
+	return &LSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+}
+
+// unique elements of x, which are found or not found in y, taken from result
+func selectMatching(ev *Evaluator, result SEXPItf, x SEXPItf, y SEXPItf, found bool) SEXPItf {
+	if sexpType(result) == NILSXP {
+		return &NSEXP{}
+	}
+	offsets := []int{}
+	matches := matchOffsets(x, y)
+	for n, d := range duplicatedFlags(elementKeys(x), false) {
+		if !d && (matches[n] >= 0) == found {
+			offsets = append(offsets, n)
+		}
+	}
+	return indexElements(ev, result, offsets)
+}
+
+func EvalUnion(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, y, ok := commonVectors(ev, "union", args.Values[0], args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	xy := combine(ev, node.Fun.Pos(), []SEXPItf{x, y}, nil, false, false)
+	return selectMatching(ev, xy, xy, &NSEXP{}, false)
+}
+
+func EvalIntersect(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if sexpType(args.Values[0]) == NILSXP || sexpType(args.Values[1]) == NILSXP {
+		return &NSEXP{}
+	}
+	x, y, ok := commonVectors(ev, "intersect", args.Values[0], args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return selectMatching(ev, x, x, y, true)
+}
+
+func EvalSetdiff(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x, y, ok := commonVectors(ev, "setdiff", args.Values[0], args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return selectMatching(ev, args.Values[0], x, y, false)
+}
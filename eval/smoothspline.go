@@ -0,0 +1,360 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+	"sort"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/smooth.spline.html
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/predict.smooth.spline.html
+// x values closer than tol are tied, their y are averaged with the sums of their weights.
+// The unique x are scaled to [0, 1], the fit of numeric.SmoothingSpline is kept as
+// coefficients of the B-splines on these knots in the component fit.
+
+var smoothSplineFormals = []string{"x", "y", "w", "df", "spar", "lambda", "cv", "all.knots", "nknots", "keep.data", "df.offset", "penalty", "control.spar", "tol", "keep.stuff"}
+
+func init() {
+	registerBuiltin("smooth.spline", smoothSplineFormals, EvalSmoothSpline)
+	registerPrintMethod("smooth.spline", printSmoothSpline)
+}
+
+// the number of inner knots for n unique x, as .nknots.smspl
+func smoothSplineKnots(n int) int {
+	if n < 50 {
+		return n
+	}
+	a1, a2, a3, a4 := math.Log2(50), math.Log2(100), math.Log2(140), math.Log2(200)
+	switch {
+	case n < 200:
+		return int(math.Pow(2, a1+(a2-a1)*float64(n-50)/150))
+	case n < 800:
+		return int(math.Pow(2, a2+(a3-a2)*float64(n-200)/600))
+	case n < 3200:
+		return int(math.Pow(2, a3+(a4-a3)*float64(n-800)/2400))
+	}
+	return int(200 + math.Pow(float64(n-3200), 0.2))
+}
+
+func EvalSmoothSpline(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	var x, y []float64
+	warn := false
+	switch {
+	case args.missing(0):
+		return builtinError(ev, "smooth.spline", "argument \"x\" is missing, with no default")
+	case !numericArgument(ev, "smooth.spline", "x", args.Values[0]):
+		return &ESEXP{Kind: token.ILLEGAL}
+	case isNull(args.Values[1]):
+		y = asFloats(args.Values[0], &warn)
+		for k := range y {
+			x = append(x, float64(k+1))
+		}
+	case !numericArgument(ev, "smooth.spline", "y", args.Values[1]):
+		return &ESEXP{Kind: token.ILLEGAL}
+	default:
+		x, y = asFloats(args.Values[0], &warn), asFloats(args.Values[1], &warn)
+		if len(x) != len(y) {
+			return builtinError(ev, "smooth.spline", "'x' and 'y' lengths differ")
+		}
+	}
+	w := make([]float64, len(x))
+	if isNull(args.Values[2]) {
+		for k := range w {
+			w[k] = 1
+		}
+	} else {
+		if !numericArgument(ev, "smooth.spline", "w", args.Values[2]) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		if w = asFloats(args.Values[2], &warn); len(w) != len(x) {
+			return builtinError(ev, "smooth.spline", "lengths of 'x' and 'w' must match")
+		}
+	}
+	var fx, fy, fw []float64
+	for k := range x {
+		if !math.IsNaN(x[k]) && !math.IsInf(x[k], 0) && !math.IsNaN(y[k]) && !math.IsInf(y[k], 0) && !math.IsNaN(w[k]) {
+			fx, fy, fw = append(fx, x[k]), append(fy, y[k]), append(fw, w[k])
+		}
+	}
+	x, y, w = fx, fy, fw
+	n := len(x)
+	positive, sumw := 0., 0.
+	for _, v := range w {
+		if v < 0 {
+			return builtinError(ev, "smooth.spline", "all weights should be non-negative")
+		}
+		if v > 0 {
+			positive++
+		}
+		sumw += v
+	}
+	if positive == 0 {
+		return builtinError(ev, "smooth.spline", "some weights should be positive")
+	}
+	for k := range w {
+		w[k] *= positive / sumw
+	}
+
+	// tied x
+	sorted := append([]float64(nil), x...)
+	sort.Float64s(sorted)
+	mean := 0.
+	for _, v := range x {
+		mean += v / float64(n)
+	}
+	iqr := quantiles(sorted, []float64{0.25, 0.75}, 7)
+	tol := args.float(13, 1e-6*(iqr[1]-iqr[0]))
+	if !(tol > 0) || math.IsInf(tol, 0) {
+		return builtinError(ev, "smooth.spline", "'tol' must be strictly positive and finite")
+	}
+	groups := map[float64]int{}
+	var ux, keys []float64
+	for _, v := range x {
+		key := math.Round((v - mean) / tol)
+		if _, ok := groups[key]; !ok {
+			groups[key] = len(keys)
+			keys = append(keys, key)
+			ux = append(ux, v)
+		}
+	}
+	order := make([]int, len(keys))
+	for k := range order {
+		order[k] = k
+	}
+	sort.Slice(order, func(a, b int) bool { return keys[order[a]] < keys[order[b]] })
+	rank := make([]int, len(keys))
+	for r, k := range order {
+		rank[k] = r
+	}
+	nx := len(keys)
+	if nx <= 3 {
+		return builtinError(ev, "smooth.spline", "need at least four unique 'x' values")
+	}
+	ox := make([]int, n)
+	wbar, ybar, xbar := make([]float64, nx), make([]float64, nx), make([]float64, nx)
+	yssw := 0.
+	for k, v := range x {
+		ox[k] = rank[groups[math.Round((v-mean)/tol)]]
+		wbar[ox[k]] += w[k]
+		ybar[ox[k]] += w[k] * y[k]
+		yssw += w[k] * y[k] * y[k]
+	}
+	sortedX := make([]float64, nx)
+	for k, v := range ux {
+		sortedX[rank[k]] = v
+	}
+	ux = sortedX
+	for k := range ybar {
+		if wbar[k] > 0 {
+			ybar[k] /= wbar[k]
+		}
+		yssw -= wbar[k] * ybar[k] * ybar[k]
+	}
+	cv := args.logical(6, false)
+	if cv && nx < n {
+		ev.warning("smooth.spline()", "cross-validation with non-unique 'x' values seems doubtful")
+	}
+	r := ux[nx-1] - ux[0]
+	for k, v := range ux {
+		xbar[k] = (v - ux[0]) / r
+	}
+
+	// knots
+	nknots := nx
+	if !args.logical(7, false) && !isNull(args.Values[8]) {
+		nknots = int(args.float(8, float64(nx)))
+		if nknots < 1 {
+			return builtinError(ev, "smooth.spline", "'nknots' must be at least 1")
+		}
+		if nknots > nx {
+			return builtinError(ev, "smooth.spline", "cannot use more inner knots than unique 'x' values")
+		}
+	} else if !args.logical(7, false) {
+		nknots = smoothSplineKnots(nx)
+	}
+	knots := make([]float64, nknots)
+	for k := range knots {
+		i := 0
+		if nknots > 1 {
+			i = int(float64(k) * float64(nx-1) / float64(nknots-1))
+		}
+		knots[k] = xbar[i]
+	}
+
+	// criterion and smoothing parameter
+	icrit := 1
+	if cv {
+		icrit = 2
+	}
+	dofoff := args.float(10, 0)
+	penalty := args.float(11, 1)
+	if !args.missing(3) {
+		if df := args.float(3, 0); df > 1 && df <= float64(nx) {
+			icrit, dofoff = 3, df
+		} else {
+			ev.warning("smooth.spline()", fmt.Sprintf("not using invalid df; must have 1 < df <= n := #{unique x} = %d", nx))
+		}
+	}
+	low, high, ctol, eps, maxit := -1.5, 1.5, 1e-4, 2e-8, 500.
+	if control, ok := args.Values[12].(*RSEXP); ok {
+		for _, c := range []struct {
+			name  string
+			value *float64
+		}{{"low", &low}, {"high", &high}, {"tol", &ctol}, {"eps", &eps}, {"maxit", &maxit}} {
+			if v := componentFloats(control, c.name); len(v) > 0 {
+				*c.value = v[0]
+			}
+		}
+	}
+	s := numeric.NewSmoothingSpline(xbar, ybar, wbar, yssw, knots)
+	spar, ratio := calc.NA, s.Ratio
+	ispar, iter := 1, 0
+	switch {
+	case !isNull(args.Values[5]):
+		ratio = calc.NA
+		if !s.Fit(args.float(5, 0), icrit, dofoff, penalty) {
+			return builtinError(ev, "smooth.spline", "smoothing parameter value too small or too large")
+		}
+	case !isNull(args.Values[4]):
+		spar = args.float(4, 0)
+		if !s.Fit(s.LambdaOf(spar), icrit, dofoff, penalty) {
+			return builtinError(ev, "smooth.spline", "smoothing parameter value too small or too large")
+		}
+	default:
+		ispar = 0
+		spar, iter = s.MinimizeSpar(low, high, ctol, eps, int(maxit), icrit, dofoff, penalty)
+	}
+	if math.IsNaN(s.Df) {
+		return builtinError(ev, "smooth.spline", "NA lev[]; probably smoothing parameter 'spar' way too large!")
+	}
+
+	// criteria of the data before ties are combined
+	cvCrit, sw := 0., 0.
+	for k := range x {
+		e := y[k] - s.Fitted[ox[k]]
+		if cv {
+			ww := wbar[ox[k]]
+			if ww == 0 {
+				ww = 1
+			}
+			e /= 1 - s.Lev[ox[k]]*w[k]/ww
+		}
+		cvCrit += w[k] * e * e
+		sw += w[k]
+	}
+	cvCrit /= sw
+	if !cv {
+		d := 1 - (args.float(10, 0)+penalty*s.Df)/float64(n)
+		cvCrit /= d * d
+	}
+	penCrit := 0.
+	for k := range ybar {
+		penCrit += wbar[k] * (ybar[k] - s.Fitted[k]) * (ybar[k] - s.Fitted[k])
+	}
+	pos := node.Fun.Pos()
+	fit := classList(pos, "smooth.spline.fit", []string{"knot", "nk", "min", "range", "coef"},
+		&VSEXP{ValuePos: pos, Slice: s.Knots},
+		integerValue(pos, len(s.Coef)),
+		&VSEXP{ValuePos: pos, Immediate: ux[0]},
+		&VSEXP{ValuePos: pos, Immediate: r},
+		&VSEXP{ValuePos: pos, Slice: s.Coef})
+	iparms := &ISEXP{ValuePos: pos, Slice: []int{icrit, ispar, iter}}
+	iparms.NamesSet([]string{"icrit", "ispar", "iter"})
+	names := []string{"x", "y", "w", "yin", "tol"}
+	values := []SEXPItf{
+		&VSEXP{ValuePos: pos, Slice: ux},
+		&VSEXP{ValuePos: pos, Slice: s.Fitted},
+		&VSEXP{ValuePos: pos, Slice: wbar},
+		&VSEXP{ValuePos: pos, Slice: ybar},
+		&VSEXP{ValuePos: pos, Immediate: tol},
+	}
+	if args.logical(9, true) {
+		names = append(names, "data")
+		values = append(values, classList(pos, "", []string{"x", "y", "w"},
+			&VSEXP{ValuePos: pos, Slice: x}, &VSEXP{ValuePos: pos, Slice: y}, &VSEXP{ValuePos: pos, Slice: w}))
+	}
+	names = append(names, "n", "lev", "cv", "cv.crit", "pen.crit", "crit", "df", "spar", "ratio", "lambda", "iparms", "fit", "call")
+	values = append(values,
+		integerValue(pos, n),
+		&VSEXP{ValuePos: pos, Slice: s.Lev},
+		&LSEXP{ValuePos: pos, Immediate: logical(cv)},
+		&VSEXP{ValuePos: pos, Immediate: cvCrit},
+		&VSEXP{ValuePos: pos, Immediate: penCrit},
+		&VSEXP{ValuePos: pos, Immediate: s.Crit},
+		&VSEXP{ValuePos: pos, Immediate: s.Df},
+		&VSEXP{ValuePos: pos, Immediate: spar},
+		&VSEXP{ValuePos: pos, Immediate: ratio},
+		&VSEXP{ValuePos: pos, Immediate: s.Lambda},
+		iparms,
+		fit,
+		matchedCall(node, smoothSplineFormals))
+	return classList(pos, "smooth.spline", names, values...)
+}
+
+// the fitted spline or its derivative at x, linear beyond the range of the knots
+func predictSmoothSpline(ev *Evaluator, pos token.Pos, object *RSEXP, x SEXPItf, deriv int) SEXPItf {
+	if isNull(x) {
+		if deriv == 0 {
+			return classList(pos, "", []string{"x", "y"}, listComponent(object, "x"), listComponent(object, "y"))
+		}
+		x = listComponent(object, "x")
+	} else if !numericArgument(ev, "predict", "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	if deriv < 0 || deriv > 3 {
+		return builtinError(ev, "predict", "'deriv' must be between 0 and 3")
+	}
+	fit := listComponent(object, "fit")
+	knots, coef := componentFloats(fit, "knot"), componentFloats(fit, "coef")
+	min, r := componentFloats(fit, "min")[0], componentFloats(fit, "range")[0]
+	eval := func(u float64, deriv int) float64 {
+		return numeric.BSplineValue(knots, coef, u, deriv)
+	}
+	warn := false
+	xs := asFloats(x, &warn)
+	y := make([]float64, len(xs))
+	for k, v := range xs {
+		u := (v - min) / r
+		switch {
+		case math.IsNaN(u):
+			y[k] = calc.NA
+		case u >= 0 && u <= 1:
+			y[k] = eval(u, deriv)
+		case deriv == 0 && u < 0:
+			y[k] = eval(0, 0) + eval(0, 1)*u
+		case deriv == 0:
+			y[k] = eval(1, 0) + eval(1, 1)*(u-1)
+		case deriv == 1 && u < 0:
+			y[k] = eval(0, 1)
+		case deriv == 1:
+			y[k] = eval(1, 1)
+		}
+		y[k] /= math.Pow(r, float64(deriv))
+	}
+	return classList(pos, "", []string{"x", "y"}, &VSEXP{ValuePos: pos, Slice: xs}, &VSEXP{ValuePos: pos, Slice: y})
+}
+
+// as print.smooth.spline
+func printSmoothSpline(w io.Writer, x *RSEXP) {
+	format := func(name string) string {
+		return formatReal(componentFloats(x, name), 7)[0]
+	}
+	fmt.Fprintf(w, "Call:\n%s\n", deparseCall(listComponent(x, "call")))
+	iterations := ""
+	if ip := listComponent(x, "iparms"); ip != nil && asIntegers(ip, new(bool))[1] != 1 {
+		iterations = fmt.Sprintf("(%d iterations) ", asIntegers(ip, new(bool))[2])
+	}
+	fmt.Fprintf(w, "\nSmoothing Parameter  spar= %s  lambda= %s %s\n", format("spar"), format("lambda"), iterations)
+	fmt.Fprintf(w, "Equivalent Degrees of Freedom (Df): %s\n", format("df"))
+	fmt.Fprintf(w, "Penalized Criterion (RSS): %s\n", format("pen.crit"))
+	if cv := listComponent(x, "cv"); cv != nil && asLogicals(cv)[0] == TRUE {
+		fmt.Fprintf(w, "PRESS(l.o.o. CV): %s\n", format("cv.crit"))
+	} else {
+		fmt.Fprintf(w, "GCV: %s\n", format("cv.crit"))
+	}
+}
@@ -0,0 +1,302 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"sort"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/sort.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/order.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/rank.html
+// Sorting is stable, ties keep their original order, also when decreasing.
+// Strings are compared bytewise and not according to a locale.
+
+func init() {
+	registerBuiltin("sort", []string{"x", "decreasing", "na.last", "..."}, EvalSort)
+	registerBuiltin("order", []string{"...", "na.last", "decreasing", "method"}, EvalOrder)
+	registerBuiltin("rank", []string{"x", "na.last", "ties.method"}, EvalRank)
+}
+
+// placement of missing values
+const (
+	naRemove = iota
+	naFirst
+	naLast
+	naKeep
+)
+
+// the values of a vector to be ordered
+type sortKey struct {
+	floats  []float64
+	imag    []float64
+	strings []string
+}
+
+func newSortKey(ev *Evaluator, funcname string, x SEXPItf) (*sortKey, bool) {
+	warn := false
+	switch sexpType(x) {
+	case NILSXP, LGLSXP, INTSXP, REALSXP:
+		return &sortKey{floats: asFloats(x, &warn)}, true
+	case CPLXSXP:
+		slice := asComplexes(x, &warn)
+		k := &sortKey{floats: make([]float64, len(slice)), imag: make([]float64, len(slice))}
+		for n, v := range slice {
+			k.floats[n], k.imag[n] = real(v), imag(v)
+		}
+		return k, true
+	case STRSXP:
+		return &sortKey{strings: asStrings(x)}, true
+	default:
+		builtinError(ev, funcname, "argument is not a vector")
+		return nil, false
+	}
+}
+
+func (k *sortKey) length() int {
+	if k.strings != nil {
+		return len(k.strings)
+	}
+	return len(k.floats)
+}
+
+func (k *sortKey) missing(i int) bool {
+	if k.strings != nil {
+		return k.strings[i] == NA_CHARACTER
+	}
+	return math.IsNaN(k.floats[i]) || (k.imag != nil && math.IsNaN(k.imag[i]))
+}
+
+func (k *sortKey) compare(i int, j int) int {
+	if k.strings != nil {
+		return strings.Compare(k.strings[i], k.strings[j])
+	}
+	switch {
+	case k.floats[i] < k.floats[j]:
+		return -1
+	case k.floats[i] > k.floats[j]:
+		return 1
+	case k.imag == nil || k.imag[i] == k.imag[j]:
+		return 0
+	case k.imag[i] < k.imag[j]:
+		return -1
+	default:
+		return 1
+	}
+}
+
+// missing values are placed independently of the direction
+func compareKeys(keys []*sortKey, i int, j int, decreasing bool, na int) int {
+	for _, k := range keys {
+		mi, mj := k.missing(i), k.missing(j)
+		switch {
+		case mi && mj:
+			continue
+		case mi != mj:
+			if mi == (na == naFirst) {
+				return -1
+			}
+			return 1
+		}
+		c := k.compare(i, j)
+		if decreasing {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// zero-based offsets of the stable ordering
+func orderOffsets(keys []*sortKey, length int, decreasing bool, na int) []int {
+	offsets := make([]int, 0, length)
+	for i := 0; i < length; i++ {
+		if na == naRemove {
+			missing := false
+			for _, k := range keys {
+				missing = missing || k.missing(i)
+			}
+			if missing {
+				continue
+			}
+		}
+		offsets = append(offsets, i)
+	}
+	sort.SliceStable(offsets, func(a, b int) bool {
+		return compareKeys(keys, offsets[a], offsets[b], decreasing, na) < 0
+	})
+	return offsets
+}
+
+// TRUE, FALSE, NA or "keep", if allowed
+func naPlacement(ev *Evaluator, funcname string, v SEXPItf, def int, keep bool) (int, bool) {
+	if v == nil {
+		return def, true
+	}
+	if keep && sexpType(v) == STRSXP && v.Length() == 1 && asStrings(v)[0] == "keep" {
+		return naKeep, true
+	}
+	if !isAtomic(v) || v.Length() != 1 {
+		builtinError(ev, funcname, "invalid 'na.last' argument")
+		return 0, false
+	}
+	switch asLogicals(v)[0] {
+	case TRUE:
+		return naLast, true
+	case FALSE:
+		return naFirst, true
+	default:
+		return naRemove, true
+	}
+}
+
+func EvalSort(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "sort", "argument \"x\" is missing, with no default")
+	}
+	if sexpType(x) == NILSXP {
+		return &NSEXP{}
+	}
+	if !isAtomic(x) {
+		return builtinError(ev, "sort", "'x' must be atomic")
+	}
+	na, ok := naPlacement(ev, "sort", args.Values[2], naRemove, false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	key, ok := newSortKey(ev, "sort", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return repeated(ev, x, orderOffsets([]*sortKey{key}, x.Length(), args.logical(1, false), na))
+}
+
+func EvalOrder(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	na, ok := naPlacement(ev, "order", args.Values[1], naLast, false)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	keys := []*sortKey{}
+	length := -1
+	for _, v := range args.Dots {
+		key, ok := newSortKey(ev, "order", v)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		if length >= 0 && key.length() != length {
+			return builtinError(ev, "order", "argument lengths differ")
+		}
+		length = key.length()
+		keys = append(keys, key)
+	}
+	if length < 0 {
+		return &ISEXP{Slice: []int{}}
+	}
+	offsets := orderOffsets(keys, length, args.logical(2, false), na)
+	for n := range offsets {
+		offsets[n]++
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Slice: offsets}
+}
+
+var tiesMethods = []string{"average", "first", "last", "random", "max", "min"}
+
+func EvalRank(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "rank", "argument \"x\" is missing, with no default")
+	}
+	na, ok := naPlacement(ev, "rank", args.Values[1], naLast, true)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	ties := "average"
+	if v := args.Values[2]; v != nil {
+		ties = ""
+		if sexpType(v) == STRSXP && v.Length() >= 1 {
+			for _, m := range tiesMethods {
+				if strings.HasPrefix(m, asStrings(v)[0]) && asStrings(v)[0] != "" {
+					ties = m
+					break
+				}
+			}
+		}
+		if ties == "" {
+			return builtinError(ev, "rank", "'arg' should be one of “average”, “first”, “last”, “random”, “max”, “min”")
+		}
+	}
+	key, ok := newSortKey(ev, "rank", x)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	length := x.Length()
+	sorted := orderOffsets([]*sortKey{key}, length, false, na)
+	var u []float64
+	if ties == "random" {
+		// as order(x, runif(n)) in R, one uniform deviate for each non-missing element
+		u = make([]float64, length)
+		ev.getRNGstate()
+		defer ev.putRNGstate()
+		for i := range u {
+			if !key.missing(i) {
+				u[i] = ev.rng.UnifRand()
+			}
+		}
+	}
+	ranks := make([]float64, length)
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && !key.missing(sorted[i]) && !key.missing(sorted[j+1]) && key.compare(sorted[i], sorted[j+1]) == 0 {
+			j++
+		}
+		group := sorted[i : j+1]
+		if ties == "random" {
+			shuffled := append([]int(nil), group...)
+			sort.SliceStable(shuffled, func(a, b int) bool { return u[shuffled[a]] < u[shuffled[b]] })
+			group = shuffled
+		}
+		for k, o := range group {
+			switch ties {
+			case "average":
+				ranks[o] = float64(i+j)/2 + 1
+			case "first", "random":
+				ranks[o] = float64(i + k + 1)
+			case "last":
+				ranks[o] = float64(j - k + 1)
+			case "max":
+				ranks[o] = float64(j + 1)
+			case "min":
+				ranks[o] = float64(i + 1)
+			}
+		}
+		i = j + 1
+	}
+	offsets := make([]int, 0, length)
+	for i := 0; i < length; i++ {
+		switch {
+		case !key.missing(i):
+		case na == naRemove:
+			continue
+		case na == naKeep:
+			ranks[i] = calc.NA
+		}
+		offsets = append(offsets, i)
+	}
+	var r SEXPItf = &VSEXP{ValuePos: node.Fun.Pos(), Slice: ranks}
+	if ties != "average" {
+		warn := false
+		r = &ISEXP{ValuePos: node.Fun.Pos(), Slice: asIntegers(r, &warn)}
+	}
+	return repeated(ev, setNames(r, x.Names()), offsets)
+}
+
+func setNames(x SEXPItf, names []string) SEXPItf {
+	x.NamesSet(names)
+	return x
+}
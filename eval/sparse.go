@@ -0,0 +1,510 @@
+package eval
+
+import (
+	"fmt"
+	"io"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"roq/numeric"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/matmult.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/t.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/colSums.html
+// https://cran.r-project.org/web/packages/Matrix/Matrix.pdf
+// Sparse matrices of class dgCMatrix are held in external values with the dim and
+// dimnames of their extents, their non-zeros are stored in compressed columns by
+// package numeric. The matrix product, t, rowSums and colSums work on sparse and
+// dense matrices alike, elementwise operators keep sparse matrices sparse, if zeros
+// are mapped to zeros.
+
+func init() {
+	registerBuiltin("sparseMatrix", []string{"i", "j", "x", "dims", "dimnames"}, EvalSparseMatrix)
+	registerBuiltin("as.matrix", []string{"x"}, EvalAsMatrix)
+	registerBuiltin("as", []string{"object", "Class"}, EvalAsClass)
+	registerBuiltin("nnzero", []string{"x"}, EvalNnzero)
+	registerBuiltin("t", []string{"x"}, EvalTranspose)
+	registerBuiltin("%*%", []string{"x", "y"}, EvalMatrixProduct)
+	registerBuiltin("rowSums", []string{"x", "na.rm"}, EvalRowSums)
+	registerBuiltin("colSums", []string{"x", "na.rm"}, EvalColSums)
+}
+
+func newSparse(pos token.Pos, s *numeric.Sparse, rownames []string, colnames []string) *XSEXP {
+	class := "dgCMatrix"
+	r := &XSEXP{ValuePos: pos, Pointer: s}
+	r.ClassSet(&class)
+	r.DimSet([]int{s.Rows, s.Cols})
+	if rownames != nil || colnames != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(rownames), namesOrNull(colnames)}})
+	}
+	return r
+}
+
+func sparseValue(x SEXPItf) (*numeric.Sparse, bool) {
+	if p, ok := x.(*XSEXP); ok {
+		s, ok := p.Pointer.(*numeric.Sparse)
+		return s, ok
+	}
+	return nil, false
+}
+
+func isSparse(x SEXPItf) bool {
+	_, ok := sparseValue(x)
+	return ok
+}
+
+func sparseDense(x SEXPItf, s *numeric.Sparse) *VSEXP {
+	return matrixResult(x.Pos(), s.Dense(), dimnamesAt(x, 0), dimnamesAt(x, 1))
+}
+
+// the values of a numeric matrix or of a vector as single column, missing values are kept
+func denseValues(x SEXPItf) (*numeric.Matrix, bool) {
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+	default:
+		return nil, false
+	}
+	warn := false
+	data := asFloats(x, &warn)
+	if dim := x.Dim(); len(dim) == 2 {
+		return &numeric.Matrix{Rows: dim[0], Cols: dim[1], Data: data}, true
+	}
+	return &numeric.Matrix{Rows: len(data), Cols: 1, Data: data}, true
+}
+
+func printSparse(w io.Writer, x *XSEXP, s *numeric.Sparse) {
+	fmt.Fprintf(w, "%d x %d sparse Matrix of class \"dgCMatrix\"\n", s.Rows, s.Cols)
+	rownames, colnames := dimnamesAt(x, 0), dimnamesAt(x, 1)
+	for col := 0; col < s.Cols; col++ {
+		if col < len(colnames) {
+			fmt.Fprintf(w, "\t%s", colnames[col])
+		} else {
+			fmt.Fprintf(w, "\t[,%d]", col+1)
+		}
+	}
+	fmt.Fprintf(w, "\n")
+	// the next stored element of each column
+	next := append([]int(nil), s.P[:s.Cols]...)
+	for row := 0; row < s.Rows; row++ {
+		if row < len(rownames) {
+			fmt.Fprintf(w, "%s", rownames[row])
+		} else {
+			fmt.Fprintf(w, "[%d]", row+1)
+		}
+		for col := 0; col < s.Cols; col++ {
+			if k := next[col]; k < s.P[col+1] && s.I[k] == row {
+				fmt.Fprintf(w, "\t%s", formatFloat(s.X[k]))
+				next[col]++
+			} else {
+				fmt.Fprintf(w, "\t.")
+			}
+		}
+		fmt.Fprintf(w, "\n")
+	}
+}
+
+// the triplets of one based indices, x defaults to ones and dims to the largest indices
+func EvalSparseMatrix(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if !numericArgument(ev, "sparseMatrix", "i", args.Values[0]) || !numericArgument(ev, "sparseMatrix", "j", args.Values[1]) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	warn := false
+	i := append([]int(nil), asIntegers(args.Values[0], &warn)...)
+	j := append([]int(nil), asIntegers(args.Values[1], &warn)...)
+	if len(i) != len(j) {
+		return builtinError(ev, "sparseMatrix", "'i' and 'j' must have the same length")
+	}
+	x := []float64{1}
+	if v := args.Values[2]; v != nil {
+		if !numericArgument(ev, "sparseMatrix", "x", v) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		x = asFloats(v, &warn)
+	}
+	if len(x) == 1 {
+		for len(x) < len(i) {
+			x = append(x, x[0])
+		}
+	} else if len(x) != len(i) {
+		return builtinError(ev, "sparseMatrix", "'x' must have the same length as 'i' and 'j'")
+	}
+	rows, cols := 0, 0
+	for k := range i {
+		if i[k] == NA_INTEGER || j[k] == NA_INTEGER || i[k] < 1 || j[k] < 1 {
+			return builtinError(ev, "sparseMatrix", "'i' and 'j' must be positive integers")
+		}
+		rows, cols = calc.IntMax(rows, i[k]), calc.IntMax(cols, j[k])
+		i[k]--
+		j[k]--
+	}
+	if v := args.Values[3]; v != nil {
+		dims := asIntegers(v, &warn)
+		if len(dims) != 2 || dims[0] == NA_INTEGER || dims[1] == NA_INTEGER {
+			return builtinError(ev, "sparseMatrix", "'dims' must be of length 2")
+		}
+		if dims[0] < rows || dims[1] < cols {
+			return builtinError(ev, "sparseMatrix", "'dims' must contain all (i,j) pairs")
+		}
+		rows, cols = dims[0], dims[1]
+	}
+	r := newSparse(node.Fun.Pos(), numeric.NewSparse(rows, cols, i, j, x[:len(i)]), nil, nil)
+	if v := args.Values[4]; v != nil {
+		dimnames, ok := v.(*RSEXP)
+		if !ok || len(dimnames.Slice) != 2 {
+			return builtinError(ev, "sparseMatrix", "'dimnames' must be a list of length 2")
+		}
+		r.DimnamesSet(dimnames)
+	}
+	return r
+}
+
+// a vector becomes a single column named by its names
+func EvalAsMatrix(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "as.matrix", "argument \"x\" is missing, with no default")
+	}
+	if s, ok := sparseValue(x); ok {
+		return sparseDense(x, s)
+	}
+	if len(x.Dim()) == 2 {
+		return x
+	}
+	if !isAtomic(x) {
+		return builtinError(ev, "as.matrix", "cannot coerce to a matrix")
+	}
+	offsets := make([]int, x.Length())
+	for k := range offsets {
+		offsets[k] = k
+	}
+	r := indexElements(ev, x, offsets)
+	r.DimSet([]int{len(offsets), 1})
+	if names := x.Names(); names != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{&TSEXP{Slice: names}, &NSEXP{}}})
+	}
+	return r
+}
+
+// conversions between sparse and dense matrices, and to the basic vector types
+func EvalAsClass(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	object := args.Values[0]
+	if object == nil {
+		return builtinError(ev, "as", "argument \"object\" is missing, with no default")
+	}
+	class, ok := args.Values[1].(*TSEXP)
+	if !ok || class.Length() != 1 {
+		return builtinError(ev, "as", "'Class' must be a single string")
+	}
+	switch name := stringSlice(class)[0]; name {
+	case "matrix":
+		return EvalAsMatrix(ev, node, &Arguments{Values: []SEXPItf{object}})
+	case "dgCMatrix", "CsparseMatrix", "sparseMatrix":
+		if isSparse(object) {
+			return object
+		}
+		m, ok := denseValues(object)
+		if !ok {
+			return builtinError(ev, "as", "no method or default for coercing to “%s”", name)
+		}
+		rownames, colnames := dimnamesAt(object, 0), dimnamesAt(object, 1)
+		if len(object.Dim()) != 2 {
+			rownames = object.Names()
+		}
+		return newSparse(node.Fun.Pos(), numeric.SparseFromDense(m), rownames, colnames)
+	default:
+		if t, ok := modeType(name); ok && t != ANYSXP {
+			return asVector(ev, "as", object, t)
+		}
+		return builtinError(ev, "as", "no method or default for coercing to “%s”", name)
+	}
+}
+
+func EvalNnzero(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	n := 0
+	if s, ok := sparseValue(x); ok {
+		n = s.Nnz()
+	} else if m, ok := denseValues(x); ok {
+		for _, v := range m.Data {
+			if v != 0 {
+				n++
+			}
+		}
+	} else {
+		return builtinError(ev, "nnzero", "'x' must be a numeric matrix")
+	}
+	return &ISEXP{ValuePos: node.Fun.Pos(), Immediate: float64(n), Integer: n}
+}
+
+// vectors are transposed to a single row, the elements keep their type
+func EvalTranspose(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "t", "argument \"x\" is missing, with no default")
+	}
+	if s, ok := sparseValue(x); ok {
+		return newSparse(node.Fun.Pos(), s.T(), dimnamesAt(x, 1), dimnamesAt(x, 0))
+	}
+	dim := x.Dim()
+	if !isAtomic(x) || len(dim) > 2 {
+		return builtinError(ev, "t", "argument is not a matrix")
+	}
+	rows, cols := x.Length(), 1
+	rownames, colnames := x.Names(), []string(nil)
+	if len(dim) == 2 {
+		rows, cols = dim[0], dim[1]
+		rownames, colnames = dimnamesAt(x, 0), dimnamesAt(x, 1)
+	}
+	offsets := make([]int, rows*cols)
+	for i := 0; i < rows; i++ {
+		for j := 0; j < cols; j++ {
+			offsets[j+i*cols] = i + j*rows
+		}
+	}
+	r := indexElements(ev, x, offsets)
+	r.DimSet([]int{cols, rows})
+	if rownames != nil || colnames != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(colnames), namesOrNull(rownames)}})
+	}
+	return r
+}
+
+// an operand of the matrix product, vectors are taken as single column until their
+// shape is decided by the other operand
+type productOperand struct {
+	dense    *numeric.Matrix
+	sparse   *numeric.Sparse
+	rows     int
+	cols     int
+	vector   bool
+	rownames []string
+	colnames []string
+}
+
+func productArgument(ev *Evaluator, formal string, x SEXPItf) (*productOperand, bool) {
+	if x == nil {
+		builtinError(ev, "%*%", "argument \"%s\" is missing, with no default", formal)
+		return nil, false
+	}
+	if s, ok := sparseValue(x); ok {
+		return &productOperand{sparse: s, rows: s.Rows, cols: s.Cols, rownames: dimnamesAt(x, 0), colnames: dimnamesAt(x, 1)}, true
+	}
+	m, ok := denseValues(x)
+	if !ok {
+		builtinError(ev, "%*%", "requires numeric matrix/vector arguments")
+		return nil, false
+	}
+	return &productOperand{dense: m, rows: m.Rows, cols: m.Cols, vector: len(x.Dim()) != 2, rownames: dimnamesAt(x, 0), colnames: dimnamesAt(x, 1)}, true
+}
+
+func (o *productOperand) asRow() {
+	o.rows, o.cols = 1, o.rows
+	o.dense.Rows, o.dense.Cols = o.rows, o.cols
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/matmult.html
+// A vector is a row or a column, whichever makes the operands conformable,
+// two vectors of the same length give their inner product.
+func EvalMatrixProduct(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	a, ok := productArgument(ev, "x", args.Values[0])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	b, ok := productArgument(ev, "y", args.Values[1])
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	switch {
+	case a.vector && b.vector:
+		if a.rows == b.rows {
+			a.asRow()
+		} else if a.rows == 1 {
+			b.asRow()
+		}
+	case a.vector:
+		if a.rows == b.rows {
+			a.asRow()
+		}
+	case b.vector:
+		if a.cols != b.rows && a.cols == 1 {
+			b.asRow()
+		}
+	}
+	if a.cols != b.rows {
+		return builtinError(ev, "%*%", "non-conformable arguments")
+	}
+	pos := node.Fun.Pos()
+	switch {
+	case a.sparse != nil && b.sparse != nil:
+		return newSparse(pos, a.sparse.Mul(b.sparse), a.rownames, b.colnames)
+	case a.sparse != nil:
+		return matrixResult(pos, a.sparse.MulDense(b.dense), a.rownames, b.colnames)
+	case b.sparse != nil:
+		return matrixResult(pos, numeric.DenseMul(a.dense, b.sparse), a.rownames, b.colnames)
+	default:
+		return matrixResult(pos, a.dense.Mul(b.dense), a.rownames, b.colnames)
+	}
+}
+
+// sums over the rows or the columns of a matrix, arrays are taken as matrices of their first extent
+func marginSums(ev *Evaluator, node *ast.CallExpr, funcname string, args *Arguments, byRow bool) SEXPItf {
+	x := args.Values[0]
+	narm := args.logical(1, false)
+	margin := 1
+	if byRow {
+		margin = 0
+	}
+	var r []float64
+	if s, ok := sparseValue(x); ok {
+		if byRow {
+			r = s.RowSums(narm)
+		} else {
+			r = s.ColSums(narm)
+		}
+	} else {
+		dim := x.Dim()
+		if len(dim) < 2 {
+			return builtinError(ev, funcname, "'x' must be an array of at least two dimensions")
+		}
+		m, ok := denseValues(x)
+		if !ok {
+			return builtinError(ev, funcname, "'x' must be numeric")
+		}
+		rows := dim[0]
+		if byRow {
+			r = make([]float64, rows)
+		} else {
+			r = make([]float64, len(m.Data)/rows)
+		}
+		for k, v := range m.Data {
+			if narm && v != v {
+				continue
+			}
+			if byRow {
+				r[k%rows] += v
+			} else {
+				r[k/rows] += v
+			}
+		}
+		if !byRow && len(dim) > 2 {
+			result := &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+			result.DimSet(dim[1:])
+			return result
+		}
+	}
+	result := &VSEXP{ValuePos: node.Fun.Pos(), Slice: r}
+	result.NamesSet(dimnamesAt(x, margin))
+	return result
+}
+
+func EvalRowSums(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return marginSums(ev, node, "rowSums", args, true)
+}
+
+func EvalColSums(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return marginSums(ev, node, "colSums", args, false)
+}
+
+var sparseOperators = map[token.Token]func(float64, float64) float64{
+	token.PLUS:           calc.FPLUS,
+	token.MINUS:          calc.FMINUS,
+	token.MULTIPLICATION: calc.FMULTIPLICATION,
+	token.DIVISION:       calc.FDIVISION,
+	token.EXPONENTIATION: calc.FEXPONENTIATION,
+	token.MODULUS:        calc.FMODULUS,
+}
+
+func numericScalar(x SEXPItf) (float64, bool) {
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+		if x.Length() == 1 && x.Dim() == nil {
+			warn := false
+			return asFloats(x, &warn)[0], true
+		}
+	}
+	return 0, false
+}
+
+// elementwise operators on sparse matrices of the same extents, or with a scalar,
+// stay sparse if zeros give zeros, all other operands are made dense
+func sparseArithmetic(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	f := sparseOperators[op]
+	a, aok := sparseValue(x)
+	b, bok := sparseValue(y)
+	if f != nil {
+		u, uok := numericScalar(x)
+		v, vok := numericScalar(y)
+		switch {
+		case aok && bok && f(0, 0) == 0:
+			if a.Rows != b.Rows || a.Cols != b.Cols {
+				return errorf("non-conformable arrays")
+			}
+			names := x
+			if x.Dimnames() == nil {
+				names = y
+			}
+			return newSparse(x.Pos(), a.Merge(b, f), dimnamesAt(names, 0), dimnamesAt(names, 1))
+		case aok && vok && f(0, v) == 0:
+			return newSparse(x.Pos(), a.Map(func(w float64) float64 { return f(w, v) }), dimnamesAt(x, 0), dimnamesAt(x, 1))
+		case bok && uok && f(u, 0) == 0:
+			return newSparse(y.Pos(), b.Map(func(w float64) float64 { return f(u, w) }), dimnamesAt(y, 0), dimnamesAt(y, 1))
+		}
+	}
+	if aok {
+		x = sparseDense(x, a)
+	}
+	if bok {
+		y = sparseDense(y, b)
+	}
+	return EvalArithmetic(ev, op, x, y)
+}
+
+// a single subscript gives the elements in column-major order, two subscripts a sparse
+// submatrix or, if dropped to one extent, a vector
+func sparseIndex(ev *Evaluator, array SEXPItf, s *numeric.Sparse, index []ast.Expr) SEXPItf {
+	subscripts, drop := evalIndexArguments(ev, index)
+	if len(subscripts) == 1 {
+		if subscripts[0] == nil {
+			return array
+		}
+		offsets := iteratorOffsets(EvalIndexExpressionToIterator(ev, subscripts[0], array.Length(), nil))
+		r := make([]float64, len(offsets))
+		for k, offset := range offsets {
+			r[k] = calc.NA
+			if offset >= 0 && offset < array.Length() {
+				r[k] = s.At(offset%s.Rows, offset/s.Rows)
+			}
+		}
+		return &VSEXP{ValuePos: array.Pos(), Slice: r}
+	}
+	if len(subscripts) != 2 {
+		return errorf("incorrect number of dimensions")
+	}
+	dim := []int{s.Rows, s.Cols}
+	selected := make([][]int, 2)
+	for k, ex := range subscripts {
+		selected[k] = iteratorOffsets(EvalIndexExpressionToIterator(ev, ex, dim[k], dimnamesAt(array, k)))
+		for _, i := range selected[k] {
+			if i < 0 || i >= dim[k] {
+				return errorf("subscript out of bounds")
+			}
+		}
+	}
+	rownames, colnames := selectNames(dimnamesAt(array, 0), selected[0]), selectNames(dimnamesAt(array, 1), selected[1])
+	sub := s.Submatrix(selected[0], selected[1])
+	if !drop || (len(selected[0]) != 1 && len(selected[1]) != 1) {
+		return newSparse(array.Pos(), sub, rownames, colnames)
+	}
+	values := sub.Dense().Data
+	if len(values) == 1 {
+		return &VSEXP{ValuePos: array.Pos(), Immediate: values[0]}
+	}
+	r := &VSEXP{ValuePos: array.Pos(), Slice: values}
+	if len(selected[0]) == 1 {
+		r.NamesSet(colnames)
+	} else {
+		r.NamesSet(rownames)
+	}
+	return r
+}
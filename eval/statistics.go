@@ -0,0 +1,394 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/00Index.html
+// Descriptive statistics of numeric vectors. The columns of a matrix are variables for var and cor.
+
+func init() {
+	registerBuiltin("median", []string{"x", "na.rm", "..."}, EvalMedian)
+	registerBuiltin("var", []string{"x", "y", "na.rm", "use"}, EvalVar)
+	registerBuiltin("sd", []string{"x", "na.rm"}, EvalSd)
+	registerBuiltin("cor", []string{"x", "y", "use", "method"}, EvalCor)
+	registerBuiltin("quantile", []string{"x", "probs", "na.rm", "names", "type", "..."}, EvalQuantile)
+}
+
+func numericArgument(ev *Evaluator, funcname string, formal string, x SEXPItf) bool {
+	if x == nil {
+		builtinError(ev, funcname, "argument \"%s\" is missing, with no default", formal)
+		return false
+	}
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+		return true
+	default:
+		builtinError(ev, funcname, "'%s' must be numeric", formal)
+		return false
+	}
+}
+
+func medianFloats(slice []float64) float64 {
+	n := len(slice)
+	if n == 0 {
+		return calc.NA
+	}
+	sorted := append([]float64(nil), slice...)
+	sort.Float64s(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// integers keep their type for an odd number of values
+func EvalMedian(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !numericArgument(ev, "median", "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	pos := node.Fun.Pos()
+	slice, na, nan := summaryFloats([]SEXPItf{x}, args.logical(1, false))
+	if na || nan {
+		return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+	}
+	m := medianFloats(slice)
+	if sexpType(x) != REALSXP && len(slice)%2 == 1 {
+		return &ISEXP{ValuePos: pos, Immediate: m, Integer: int(m)}
+	}
+	return &VSEXP{ValuePos: pos, Immediate: m}
+}
+
+// the columns of a matrix or a vector as single column
+func columns(x SEXPItf) ([][]float64, []string) {
+	warn := false
+	slice := asFloats(x, &warn)
+	dim := x.Dim()
+	if len(dim) != 2 {
+		return [][]float64{slice}, nil
+	}
+	r := make([][]float64, dim[1])
+	for col := range r {
+		r[col] = slice[col*dim[0] : (col+1)*dim[0]]
+	}
+	return r, dimnamesAt(x, 1)
+}
+
+// pairs with a missing value in any column are removed, if na.rm is set
+func completeColumns(cols [][]float64) [][]float64 {
+	if len(cols) == 0 {
+		return cols
+	}
+	r := make([][]float64, len(cols))
+	for row := range cols[0] {
+		complete := true
+		for _, col := range cols {
+			complete = complete && !math.IsNaN(col[row])
+		}
+		if complete {
+			for k, col := range cols {
+				r[k] = append(r[k], col[row])
+			}
+		}
+	}
+	for k := range r {
+		if r[k] == nil {
+			r[k] = []float64{}
+		}
+	}
+	return r
+}
+
+// two passes: means first, then the sum of the products of the deviations
+func covariance(a []float64, b []float64) float64 {
+	n := len(a)
+	if n < 2 {
+		return calc.NA
+	}
+	ma, mb := calc.Mean(a), calc.Mean(b)
+	products := make([]float64, n)
+	for k := range a {
+		products[k] = (a[k] - ma) * (b[k] - mb)
+	}
+	return calc.SumQuotient(products, float64(n-1))
+}
+
+// missing if one of the standard deviations is zero
+func correlation(a []float64, b []float64) float64 {
+	sa, sb := math.Sqrt(covariance(a, a)), math.Sqrt(covariance(b, b))
+	if sa == 0 || sb == 0 {
+		return calc.NA
+	}
+	c := covariance(a, b) / (sa * sb)
+	if c > 1 {
+		return 1
+	} else if c < -1 {
+		return -1
+	}
+	return c
+}
+
+// variables are the columns of x and y, a single variable each gives a scalar
+func pairwise(ev *Evaluator, node *ast.CallExpr, funcname string, x SEXPItf, y SEXPItf, narm bool, f func([]float64, []float64) float64) SEXPItf {
+	if !numericArgument(ev, funcname, "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	xcols, xnames := columns(x)
+	ycols, ynames := xcols, xnames
+	if y != nil && sexpType(y) != NILSXP {
+		if !numericArgument(ev, funcname, "y", y) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		ycols, ynames = columns(y)
+		if len(ycols[0]) != len(xcols[0]) {
+			return builtinError(ev, funcname, "incompatible dimensions")
+		}
+	}
+	if narm {
+		all := completeColumns(append(append([][]float64(nil), xcols...), ycols...))
+		xcols, ycols = all[:len(xcols)], all[len(xcols):]
+	}
+	pos := node.Fun.Pos()
+	if x.Dim() == nil && (y == nil || y.Dim() == nil) && len(xcols) == 1 && len(ycols) == 1 {
+		return &VSEXP{ValuePos: pos, Immediate: pairValue(xcols[0], ycols[0], f)}
+	}
+	slice := make([]float64, len(xcols)*len(ycols))
+	for j, b := range ycols {
+		for i, a := range xcols {
+			slice[i+j*len(xcols)] = pairValue(a, b, f)
+		}
+	}
+	r := &VSEXP{ValuePos: pos, Slice: slice}
+	r.DimSet([]int{len(xcols), len(ycols)})
+	if xnames != nil || ynames != nil {
+		r.DimnamesSet(&RSEXP{Slice: []SEXPItf{namesOrNull(xnames), namesOrNull(ynames)}})
+	}
+	return r
+}
+
+func namesOrNull(names []string) SEXPItf {
+	if names == nil {
+		return &NSEXP{}
+	}
+	return &TSEXP{Slice: names}
+}
+
+func pairValue(a []float64, b []float64, f func([]float64, []float64) float64) float64 {
+	for k := range a {
+		if calc.IsNA(a[k]) || calc.IsNA(b[k]) {
+			return calc.NA
+		}
+	}
+	for k := range a {
+		if math.IsNaN(a[k]) || math.IsNaN(b[k]) {
+			return math.NaN()
+		}
+	}
+	return f(a, b)
+}
+
+func EvalVar(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return pairwise(ev, node, "var", args.Values[0], args.Values[1], args.logical(2, false), covariance)
+}
+
+func EvalSd(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !numericArgument(ev, "sd", "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	slice, na, nan := summaryFloats([]SEXPItf{x}, args.logical(1, false))
+	pos := node.Fun.Pos()
+	if na {
+		return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+	} else if nan {
+		return &VSEXP{ValuePos: pos, Immediate: math.NaN()}
+	}
+	return &VSEXP{ValuePos: pos, Immediate: math.Sqrt(covariance(slice, slice))}
+}
+
+// average ranks for ties
+func averageRanks(slice []float64) []float64 {
+	order := make([]int, len(slice))
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool { return slice[order[i]] < slice[order[j]] })
+	r := make([]float64, len(slice))
+	for i := 0; i < len(order); {
+		j := i
+		for j+1 < len(order) && slice[order[j+1]] == slice[order[i]] {
+			j++
+		}
+		for k := i; k <= j; k++ {
+			r[order[k]] = float64(i+j)/2 + 1
+		}
+		i = j + 1
+	}
+	return r
+}
+
+func spearman(a []float64, b []float64) float64 {
+	return correlation(averageRanks(a), averageRanks(b))
+}
+
+// tau-b, which corrects for ties
+func kendall(a []float64, b []float64) float64 {
+	var concordant, tiesA, tiesB, total float64
+	for i := range a {
+		for j := 0; j < i; j++ {
+			sa, sb := calc.Sign(a[i]-a[j]), calc.Sign(b[i]-b[j])
+			concordant += sa * sb
+			if sa == 0 {
+				tiesA++
+			}
+			if sb == 0 {
+				tiesB++
+			}
+			total++
+		}
+	}
+	return concordant / math.Sqrt((total-tiesA)*(total-tiesB))
+}
+
+var correlations = map[string]func([]float64, []float64) float64{
+	"pearson":  correlation,
+	"spearman": spearman,
+	"kendall":  kendall,
+}
+
+func EvalCor(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	f := correlation
+	if !args.missing(3) {
+		method := args.Values[3]
+		if sexpType(method) != STRSXP || method.Length() != 1 {
+			return builtinError(ev, "cor", "invalid 'method' argument")
+		}
+		m := stringSlice(method.(*TSEXP))[0]
+		f = nil
+		for name, g := range correlations {
+			if m != "" && strings.HasPrefix(name, m) {
+				f = g
+			}
+		}
+		if f == nil {
+			return builtinError(ev, "cor", "invalid 'method' argument")
+		}
+	}
+	narm := false
+	if !args.missing(2) && sexpType(args.Values[2]) == STRSXP {
+		use := stringSlice(args.Values[2].(*TSEXP))[0]
+		narm = use == "complete.obs" || use == "pairwise.complete.obs" || use == "na.or.complete"
+	}
+	return pairwise(ev, node, "cor", args.Values[0], args.Values[1], narm, f)
+}
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/quantile.html
+// types 1 to 3 are discontinuous, types 4 to 9 interpolate between order statistics
+func quantiles(sorted []float64, probs []float64, t int) []float64 {
+	n := len(sorted)
+	r := make([]float64, len(probs))
+	if n == 0 {
+		for k := range r {
+			r[k] = calc.NA
+		}
+		return r
+	}
+	if t == 7 {
+		for k, p := range probs {
+			index := float64(n-1) * p
+			lo, hi := math.Floor(index), math.Ceil(index)
+			q := sorted[int(lo)]
+			if h := index - lo; index > lo && sorted[int(hi)] != q {
+				q = (1-h)*q + h*sorted[int(hi)]
+			}
+			r[k] = q
+		}
+		return r
+	}
+	const fuzz = 4 * 2.220446049250313e-16
+	padded := append(append([]float64{sorted[0], sorted[0]}, sorted...), sorted[n-1], sorted[n-1])
+	for k, p := range probs {
+		var j, h float64
+		if t <= 3 {
+			nppm := float64(n) * p
+			if t == 3 {
+				nppm -= 0.5
+			}
+			j = math.Floor(nppm + fuzz)
+			switch t {
+			case 1:
+				h = float64(logical(nppm > j))
+			case 2:
+				h = (float64(logical(nppm > j)) + 1) / 2
+			case 3:
+				h = float64(logical(nppm != j || math.Mod(j, 2) == 1))
+			}
+		} else {
+			ab := map[int][2]float64{4: {0, 1}, 5: {0.5, 0.5}, 6: {0, 0}, 8: {1.0 / 3, 1.0 / 3}, 9: {3.0 / 8, 3.0 / 8}}[t]
+			nppm := ab[0] + p*(float64(n)+1-ab[0]-ab[1])
+			j = math.Floor(nppm + fuzz)
+			h = nppm - j
+			if math.Abs(h) < fuzz {
+				h = 0
+			}
+		}
+		lo, hi := padded[int(j)+1], padded[int(j)+2]
+		switch {
+		case h == 1:
+			r[k] = hi
+		case 0 < h && h < 1 && lo != hi:
+			r[k] = (1-h)*lo + h*hi
+		default:
+			r[k] = lo
+		}
+	}
+	return r
+}
+
+func EvalQuantile(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if !numericArgument(ev, "quantile", "x", x) {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	probs := []float64{0, 0.25, 0.5, 0.75, 1}
+	if !args.missing(1) {
+		if !numericArgument(ev, "quantile", "probs", args.Values[1]) {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		warn := false
+		probs = append([]float64(nil), asFloats(args.Values[1], &warn)...)
+	}
+	eps := 100 * 2.220446049250313e-16
+	for k, p := range probs {
+		if p < -eps || p > 1+eps {
+			return builtinError(ev, "quantile", "'probs' outside [0,1]")
+		}
+		probs[k] = math.Max(0, math.Min(1, p))
+	}
+	t := int(args.float(4, 7))
+	if t < 1 || t > 9 {
+		return builtinError(ev, "quantile", "'type' must be one of 1 to 9")
+	}
+	slice, na, nan := summaryFloats([]SEXPItf{x}, args.logical(2, false))
+	if na || nan {
+		return builtinError(ev, "quantile", "missing values and NaN's not allowed if 'na.rm' is FALSE")
+	}
+	sorted := append([]float64(nil), slice...)
+	sort.Float64s(sorted)
+	r := &VSEXP{ValuePos: node.Fun.Pos(), Slice: quantiles(sorted, probs, t)}
+	if args.logical(3, true) {
+		names := make([]string, len(probs))
+		for k, p := range probs {
+			names[k] = strconv.FormatFloat(100*p, 'g', 7, 64) + "%"
+		}
+		r.NamesSet(names)
+	}
+	return r
+}
@@ -10,7 +10,7 @@ type SEXPTYPE int
 
 // The list of tokens.
 const (
-	NILSXP     = iota //	0	NULL
+	NILSXP SEXPTYPE = iota //	0	NULL
 	SYMSXP            //	1	symbols
 	LISTSXP           //	2	pairlists
 	CLOSXP            //	3	closures
@@ -21,7 +21,7 @@ const (
 	BUILTINSXP        //	8	builtin functions
 	CHARSXP           //	9	internal character strings
 	LGLSXP            //	10	logical vectors
-	INTSXP     = 13   //	13	integer vectors
+	INTSXP SEXPTYPE = iota + 2 //	13	integer vectors, codes 11 and 12 are unused
 	REALSXP           //	14	numeric vectors
 	CPLXSXP           //	15	complex vectors
 	STRSXP            //	16	character vectors
@@ -66,7 +66,8 @@ type SEXPItf interface {
 	DimSet([]int)
 	Dimnames() *RSEXP
 	DimnamesSet(*RSEXP)
-//	Names([]string)
+	Names() []string
+	NamesSet([]string)
 	Class() *string
 	ClassSet(*string)
 	//	Atom()		interface{} // TODO Length=1 => Atom(), is this dispatching really faster?
@@ -94,8 +95,10 @@ type VSEXP struct {
 	Fieldlist []*ast.Field   // only if function
 	ellipsis  bool           // only if function
 	Body      *ast.BlockStmt // only if function: BlockStmt or single Stmt
+	builtin   *builtin       // only if builtin function
 	Immediate float64        // single value FLOAT
 	Slice     []float64      // "A slice is a reference to an array"
+	Attributes *RSEXP        // further attributes by name, as the confidence level of an interval
 }
 
 // Index domain
@@ -107,6 +110,32 @@ type ISEXP struct {
 	Slice     []int   // "A slice is a reference to an array"
 }
 
+// Logical domain: TRUE, FALSE and NA
+type LSEXP struct {
+	ValuePos token.Pos
+	SEXP
+	Immediate int   // single value
+	Slice     []int // "A slice is a reference to an array"
+}
+
+const (
+	FALSE      = 0
+	TRUE       = 1
+	NA_LOGICAL = math.MinInt32 // same as NA_integer_ in R
+	NA_INTEGER = math.MinInt32
+)
+
+// NA_character_ is distinct from the string "NA", as it can not be entered as a literal
+const NA_CHARACTER = "\x00NA"
+
+// Complex domain
+type CSEXP struct {
+	ValuePos token.Pos
+	SEXP
+	Immediate complex128   // single value
+	Slice     []complex128 // "A slice is a reference to an array"
+}
+
 // Recursive domain
 type RSEXP struct {
 	ValuePos token.Pos
@@ -115,6 +144,7 @@ type RSEXP struct {
 	CDR   SEXPItf
 	TAG   SEXPItf
 	Slice []SEXPItf
+	Attributes *RSEXP // further attributes by name, as the heading of anova tables
 }
 
 // NULL, FALSE
@@ -136,6 +166,15 @@ type QSEXP struct {
 	ValuePos token.Pos
 	SEXP
 	X        interface{} // quoted expresion or stmt
+	Frame    *Frame      // environment of formulae
+	Attributes *RSEXP    // further attributes by name, as those of terms
+}
+
+// External domain: objects of the host language like channels
+type XSEXP struct {
+	ValuePos token.Pos
+	SEXP
+	Pointer interface{}
 }
 
 // Errors and exceptions
@@ -159,6 +198,12 @@ func (x *SEXP) Dimnames() *RSEXP {
 func (x *SEXP) DimnamesSet(v *RSEXP) {
 	x.dimnames = v
 }
+func (x *SEXP) Names() []string {
+	return x.names
+}
+func (x *SEXP) NamesSet(v []string) {
+	x.names = v
+}
 func (x *SEXP) Class() *string {
 	return x.class
 }
@@ -215,6 +260,28 @@ func (x *ISEXP) FloatGet() float64 {
 	return x.Immediate
 }
 
+func (x *LSEXP) Pos() token.Pos {
+	return x.ValuePos
+}
+func (x *LSEXP) Length() int {
+	if x.Slice == nil {
+		return 1
+	} else {
+		return len(x.Slice)
+	}
+}
+
+func (x *CSEXP) Pos() token.Pos {
+	return x.ValuePos
+}
+func (x *CSEXP) Length() int {
+	if x.Slice == nil {
+		return 1
+	} else {
+		return len(x.Slice)
+	}
+}
+
 func (x *RSEXP) Pos() token.Pos {
 	return x.ValuePos
 }
@@ -244,6 +311,19 @@ func (x *QSEXP) Length() int {
 	return 1
 }
 
+func (x *XSEXP) Pos() token.Pos {
+	return x.ValuePos
+}
+func (x *XSEXP) Length() int {
+	if b, ok := bigValues(x); ok {
+		return len(b.values)
+	}
+	if s, ok := sparseValue(x); ok {
+		return s.Rows * s.Cols
+	}
+	return 1
+}
+
 func (x *NSEXP) Pos() token.Pos {
 	return x.ValuePos
 }
@@ -251,3 +331,40 @@ func (x *NSEXP) Pos() token.Pos {
 func (x *ESEXP) Pos() token.Pos {
 	return x.ValuePos
 }
+
+// immediate values are returned as slices of length one
+
+func floatSlice(x *VSEXP) []float64 {
+	if x.Slice == nil {
+		return []float64{x.Immediate}
+	}
+	return x.Slice
+}
+
+func integerSlice(x *ISEXP) []int {
+	if x.Slice == nil {
+		return []int{x.Integer}
+	}
+	return x.Slice
+}
+
+func logicalSlice(x *LSEXP) []int {
+	if x.Slice == nil {
+		return []int{x.Immediate}
+	}
+	return x.Slice
+}
+
+func complexSlice(x *CSEXP) []complex128 {
+	if x.Slice == nil {
+		return []complex128{x.Immediate}
+	}
+	return x.Slice
+}
+
+func stringSlice(x *TSEXP) []string {
+	if x.Slice == nil {
+		return []string{x.String}
+	}
+	return x.Slice
+}
@@ -0,0 +1,341 @@
+package eval
+
+import (
+	"math"
+	"roq/calc"
+	"roq/lib/ast"
+	"roq/lib/token"
+	"sort"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/groupGeneric.html
+// Summary functions combine all their arguments. Missing values give a missing result,
+// unless they are removed by na.rm=TRUE. NA takes precedence over NaN.
+
+func init() {
+	registerBuiltin("sum", []string{"...", "na.rm"}, EvalSum)
+	registerBuiltin("prod", []string{"...", "na.rm"}, EvalProd)
+	registerBuiltin("max", []string{"...", "na.rm"}, EvalMax)
+	registerBuiltin("min", []string{"...", "na.rm"}, EvalMin)
+	registerBuiltin("range", []string{"...", "na.rm", "finite"}, EvalRange)
+	registerBuiltin("mean", []string{"x", "trim", "na.rm", "..."}, EvalMean)
+}
+
+// the arguments of a summary function must be atomic and are coerced to the highest type
+func summaryType(ev *Evaluator, funcname string, values []SEXPItf) (SEXPTYPE, bool) {
+	t := highestType(values)
+	if t == VECSXP {
+		for _, v := range values {
+			if !isAtomic(v) && sexpType(v) != NILSXP {
+				builtinError(ev, funcname, "invalid 'type' (%s) of argument", typeName(sexpType(v)))
+				return t, false
+			}
+		}
+	}
+	return t, true
+}
+
+// doubles without NA and NaN if removed, otherwise with a flag for both
+func summaryFloats(values []SEXPItf, narm bool) (r []float64, na bool, nan bool) {
+	warn := false
+	for _, v := range values {
+		for _, f := range asFloats(v, &warn) {
+			switch {
+			case calc.IsNA(f):
+				na = true
+			case math.IsNaN(f):
+				nan = true
+			default:
+				r = append(r, f)
+				continue
+			}
+			if !narm {
+				r = append(r, f)
+			}
+		}
+	}
+	return r, na && !narm, nan && !narm
+}
+
+func summaryIntegers(values []SEXPItf, narm bool) (r []int, na bool) {
+	warn := false
+	for _, v := range values {
+		for _, i := range asIntegers(v, &warn) {
+			if i == NA_INTEGER {
+				na = true
+				if narm {
+					continue
+				}
+			}
+			r = append(r, i)
+		}
+	}
+	return r, na && !narm
+}
+
+func summaryComplexes(values []SEXPItf, narm bool) (r []complex128, na bool) {
+	warn := false
+	for _, v := range values {
+		for _, c := range asComplexes(v, &warn) {
+			if isNAComplex(c) || math.IsNaN(real(c)) || math.IsNaN(imag(c)) {
+				na = na || isNAComplex(c)
+				if narm {
+					continue
+				}
+			}
+			r = append(r, c)
+		}
+	}
+	return r, na && !narm
+}
+
+func EvalSum(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if hasBig(args.Dots) {
+		return bigSummary(ev, node.Fun.Pos(), "sum", args.Dots, args.logical(1, false))
+	}
+	t, ok := summaryType(ev, "sum", args.Dots)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	narm := args.logical(1, false)
+	pos := node.Fun.Pos()
+	switch t {
+	case NILSXP, LGLSXP, INTSXP:
+		slice, na := summaryIntegers(args.Dots, narm)
+		if na {
+			return &ISEXP{ValuePos: pos, Immediate: calc.NA, Integer: NA_INTEGER}
+		}
+		var s int64
+		for _, i := range slice {
+			s += int64(i)
+		}
+		if s > math.MaxInt32 || s <= math.MinInt32 {
+			ev.warning("", "integer overflow - use sum(as.numeric(.))")
+			return &ISEXP{ValuePos: pos, Immediate: calc.NA, Integer: NA_INTEGER}
+		}
+		return &ISEXP{ValuePos: pos, Immediate: float64(s), Integer: int(s)}
+	case REALSXP:
+		slice, na, _ := summaryFloats(args.Dots, narm)
+		if na {
+			return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+		}
+		return &VSEXP{ValuePos: pos, Immediate: calc.Sum(slice)}
+	case CPLXSXP:
+		slice, na := summaryComplexes(args.Dots, narm)
+		if na {
+			return &CSEXP{ValuePos: pos, Immediate: naComplex}
+		}
+		var s complex128
+		for _, c := range slice {
+			s += c
+		}
+		return &CSEXP{ValuePos: pos, Immediate: s}
+	default:
+		return builtinError(ev, "sum", "invalid 'type' (%s) of argument", typeName(t))
+	}
+}
+
+func EvalProd(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	if hasBig(args.Dots) {
+		return bigSummary(ev, node.Fun.Pos(), "prod", args.Dots, args.logical(1, false))
+	}
+	t, ok := summaryType(ev, "prod", args.Dots)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	narm := args.logical(1, false)
+	pos := node.Fun.Pos()
+	switch t {
+	case NILSXP, LGLSXP, INTSXP, REALSXP:
+		slice, na, _ := summaryFloats(args.Dots, narm)
+		if na {
+			return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+		}
+		p := 1.0
+		for _, f := range slice {
+			p *= f
+		}
+		return &VSEXP{ValuePos: pos, Immediate: p}
+	case CPLXSXP:
+		slice, na := summaryComplexes(args.Dots, narm)
+		if na {
+			return &CSEXP{ValuePos: pos, Immediate: naComplex}
+		}
+		var p complex128 = 1
+		for _, c := range slice {
+			p *= c
+		}
+		return &CSEXP{ValuePos: pos, Immediate: p}
+	default:
+		return builtinError(ev, "prod", "invalid 'type' (%s) of argument", typeName(t))
+	}
+}
+
+func EvalMax(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return extremum(ev, node, args, "max", 1)
+}
+
+func EvalMin(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	return extremum(ev, node, args, "min", -1)
+}
+
+// sign is 1 for the maximum and -1 for the minimum
+func extremum(ev *Evaluator, node *ast.CallExpr, args *Arguments, funcname string, sign int) SEXPItf {
+	t, ok := summaryType(ev, funcname, args.Dots)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	narm := args.logical(1, false)
+	pos := node.Fun.Pos()
+	empty := func() SEXPItf {
+		limit := math.Inf(-sign)
+		ev.warning("", "no non-missing arguments to "+funcname+"; returning "+formatFloat(limit))
+		return &VSEXP{ValuePos: pos, Immediate: limit}
+	}
+	switch t {
+	case NILSXP, LGLSXP, INTSXP:
+		slice, na := summaryIntegers(args.Dots, narm)
+		if na {
+			return &ISEXP{ValuePos: pos, Immediate: calc.NA, Integer: NA_INTEGER}
+		}
+		if len(slice) == 0 {
+			return empty()
+		}
+		r := slice[0]
+		for _, i := range slice[1:] {
+			if (i-r)*sign > 0 {
+				r = i
+			}
+		}
+		return &ISEXP{ValuePos: pos, Immediate: float64(r), Integer: r}
+	case REALSXP:
+		slice, na, nan := summaryFloats(args.Dots, narm)
+		switch {
+		case na:
+			return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+		case nan:
+			return &VSEXP{ValuePos: pos, Immediate: math.NaN()}
+		case len(slice) == 0:
+			return empty()
+		}
+		r := slice[0]
+		for _, f := range slice[1:] {
+			if (sign > 0 && f > r) || (sign < 0 && f < r) {
+				r = f
+			}
+		}
+		return &VSEXP{ValuePos: pos, Immediate: r}
+	case STRSXP:
+		var slice []string
+		for _, v := range args.Dots {
+			for _, s := range asStrings(v) {
+				if s == NA_CHARACTER {
+					if !narm {
+						return &TSEXP{ValuePos: pos, String: NA_CHARACTER}
+					}
+					continue
+				}
+				slice = append(slice, s)
+			}
+		}
+		if len(slice) == 0 {
+			return builtinError(ev, funcname, "no non-missing arguments to %s", funcname)
+		}
+		r := slice[0]
+		for _, s := range slice[1:] {
+			if strings.Compare(s, r)*sign > 0 {
+				r = s
+			}
+		}
+		return &TSEXP{ValuePos: pos, String: r}
+	default:
+		return builtinError(ev, funcname, "invalid 'type' (%s) of argument", typeName(t))
+	}
+}
+
+// the minimum and the maximum, finite=TRUE also removes infinite values
+func EvalRange(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	values := args.Dots
+	if args.logical(2, false) {
+		t, ok := summaryType(ev, "range", values)
+		if !ok {
+			return &ESEXP{Kind: token.ILLEGAL}
+		}
+		if t == LGLSXP || t == INTSXP || t == REALSXP {
+			slice, _, _ := summaryFloats(values, true)
+			finite := []float64{}
+			for _, f := range slice {
+				if !math.IsInf(f, 0) {
+					finite = append(finite, f)
+				}
+			}
+			values = []SEXPItf{coerceVector(ev, &VSEXP{Slice: finite}, t)}
+			args = &Arguments{Values: []SEXPItf{nil, &LSEXP{Immediate: TRUE}, nil}, Dots: values}
+		}
+	}
+	min := extremum(ev, node, args, "range", -1)
+	if _, ok := min.(*ESEXP); ok {
+		return min
+	}
+	max := extremum(ev, node, args, "range", 1)
+	return combine(ev, node.Fun.Pos(), []SEXPItf{min, max}, []string{"", ""}, false, false)
+}
+
+// a trimmed mean drops the given fraction of the sorted values at each end
+func EvalMean(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	x := args.Values[0]
+	if x == nil {
+		return builtinError(ev, "mean", "argument \"x\" is missing, with no default")
+	}
+	narm := args.logical(2, false)
+	pos := node.Fun.Pos()
+	switch sexpType(x) {
+	case LGLSXP, INTSXP, REALSXP:
+		slice, na, _ := summaryFloats([]SEXPItf{x}, narm)
+		if na {
+			return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+		}
+		if !args.missing(1) {
+			trim := args.float(1, 0)
+			if math.IsNaN(trim) || args.Values[1].Length() != 1 {
+				return builtinError(ev, "mean", "'trim' must be numeric of length one")
+			}
+			if trim > 0 && len(slice) > 0 {
+				if anyNaN(slice) {
+					return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+				}
+				if trim >= 0.5 {
+					return &VSEXP{ValuePos: pos, Immediate: medianFloats(slice)}
+				}
+				lo := int(math.Floor(float64(len(slice)) * trim))
+				sorted := append([]float64(nil), slice...)
+				sort.Float64s(sorted)
+				slice = sorted[lo : len(sorted)-lo]
+			}
+		}
+		return &VSEXP{ValuePos: pos, Immediate: calc.Mean(slice)}
+	case CPLXSXP:
+		slice, na := summaryComplexes([]SEXPItf{x}, narm)
+		if na {
+			return &CSEXP{ValuePos: pos, Immediate: naComplex}
+		}
+		var s complex128
+		for _, c := range slice {
+			s += c
+		}
+		return &CSEXP{ValuePos: pos, Immediate: s / complex(float64(len(slice)), 0)}
+	default:
+		ev.warning("mean.default("+deparseValue(x)+")", "argument is not numeric or logical: returning NA")
+		return &VSEXP{ValuePos: pos, Immediate: calc.NA}
+	}
+}
+
+func anyNaN(slice []float64) bool {
+	for _, f := range slice {
+		if math.IsNaN(f) {
+			return true
+		}
+	}
+	return false
+}
@@ -10,7 +10,7 @@ import "math"
 // Only the first element of value1 is used. All other elements are ignored.
 // If value1 has any type other than a logical or a numeric vector an error is signalled.
 
-func isTrue(e SEXPItf) bool {
+func isTrue(ev *Evaluator, e SEXPItf) bool {
 	if e == nil {
 		return false
 	}
@@ -31,13 +31,17 @@ func isTrue(e SEXPItf) bool {
 					if e.(*VSEXP).Slice[0] == 0 { // R like behaviour
 						return false
 					} else {
-						println("true like R")
+						ev.println("true like R")
 						return true
 					}
 				} else {
 					return false
 				}
 			}
+		case *LSEXP:
+			return e.Length() > 0 && logicalSlice(e.(*LSEXP))[0] == TRUE
+		case *TSEXP:
+			return e.Length() > 0 && stringSlice(e.(*TSEXP))[0] != NA_CHARACTER // result of a comparison
 		default:
 			return false
 	}
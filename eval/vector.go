@@ -1,8 +1,11 @@
 package eval
 
 import (
+	"math"
+	"math/cmplx"
 	"roq/calc"
 	"roq/lib/token"
+	"strings"
 )
 
 func EvalVectorOp(x *VSEXP, y *VSEXP, FUN func(float64, float64) float64) *VSEXP {
@@ -66,3 +69,428 @@ func EvalOp(op token.Token, x *VSEXP, y *VSEXP) *VSEXP {
 		panic("?Op: " + op.String())
 	}
 }
+
+// https://cran.r-project.org/doc/manuals/R-lang.html#Arithmetic-operators
+// operands are coerced to the highest type, logicals are treated as integers
+// division and exponentiation of integers give doubles
+
+func arithmeticType(op token.Token, x SEXPItf, y SEXPItf) (SEXPTYPE, bool) {
+	t := highestType([]SEXPItf{x, y})
+	switch t {
+	case NILSXP, LGLSXP, INTSXP:
+		if op == token.DIVISION || op == token.EXPONENTIATION {
+			return REALSXP, true
+		}
+		return INTSXP, true
+	case REALSXP, CPLXSXP:
+		return t, true
+	default:
+		return t, false
+	}
+}
+
+func EvalArithmetic(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	if isBig(x) || isBig(y) {
+		return bigArithmetic(ev, op.String(), x, y)
+	}
+	if isSparse(x) || isSparse(y) {
+		return sparseArithmetic(ev, op, x, y)
+	}
+	t, ok := arithmeticType(op, x, y)
+	if !ok {
+		return errorf("non-numeric argument to binary operator")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	x, y = coerceVector(ev, x, t), coerceVector(ev, y, t)
+	switch t {
+	case INTSXP:
+		r, overflow := EvalIntegerOp(op, x.(*ISEXP), y.(*ISEXP))
+		if overflow {
+			ev.warning("", "NAs produced by integer overflow")
+		}
+		return o.setAttributes(r)
+	case CPLXSXP:
+		return o.setAttributes(EvalComplexOp(ev, op, x.(*CSEXP), y.(*CSEXP)))
+	default:
+		return o.setAttributes(EvalOp(op, x.(*VSEXP), y.(*VSEXP)))
+	}
+}
+
+// https://cran.r-project.org/doc/manuals/R-lang.html#Recycling-rules
+// The shorter operand is recycled to the length of the longer one, with a warning,
+// if the longer length is not a multiple of the shorter one. A zero-length operand
+// gives a zero-length result.
+// Arrays must have the same extents and a vector must not be longer than an array.
+
+type operands struct {
+	length   int
+	dim      []int
+	dimnames *RSEXP
+	names    []string
+}
+
+func matchOperands(ev *Evaluator, x SEXPItf, y SEXPItf) (*operands, bool) {
+	lenx, leny := x.Length(), y.Length()
+	o := &operands{length: mappedLength(lenx, leny)}
+	xdim, ydim := x.Dim(), y.Dim()
+	switch {
+	case xdim != nil && ydim != nil:
+		if !sameDims(xdim, ydim) {
+			errorf("non-conformable arrays")
+			return nil, false
+		}
+		o.dim, o.dimnames = xdim, x.Dimnames()
+		if o.dimnames == nil {
+			o.dimnames = y.Dimnames()
+		}
+	case xdim != nil && (leny != 0 || lenx == 0):
+		o.dim, o.dimnames = xdim, x.Dimnames()
+	case ydim != nil && (lenx != 0 || leny == 0):
+		o.dim, o.dimnames = ydim, y.Dimnames()
+	}
+	if o.dim != nil {
+		product := 1
+		for _, extent := range o.dim {
+			product *= extent
+		}
+		if product != o.length {
+			errorf("dims [product %d] do not match the length of object [%d]", product, o.length)
+			return nil, false
+		}
+	}
+	// names are taken from an operand of the resulting length, x first
+	if lenx == o.length && x.Names() != nil {
+		o.names = x.Names()
+	} else if leny == o.length {
+		o.names = y.Names()
+	}
+	if o.length > 0 && (o.length%lenx != 0 || o.length%leny != 0) {
+		ev.warning("", "longer object length is not a multiple of shorter object length")
+	}
+	return o, true
+}
+
+func sameDims(a []int, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for n := range a {
+		if a[n] != b[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func (o *operands) setAttributes(r SEXPItf) SEXPItf {
+	if _, ok := r.(*ESEXP); ok {
+		return r
+	}
+	r.NamesSet(o.names)
+	if o.dim != nil {
+		r.DimSet(o.dim)
+		r.DimnamesSet(o.dimnames)
+	}
+	return r
+}
+
+// results outside of the range of 32 bit integers are missing values
+func integerOp(op token.Token, a int, b int) (int, bool) {
+	if a == NA_INTEGER || b == NA_INTEGER {
+		return NA_INTEGER, false
+	}
+	var r int
+	switch op {
+	case token.PLUS:
+		r = a + b
+	case token.MINUS:
+		r = a - b
+	case token.MULTIPLICATION:
+		r = a * b
+	case token.MODULUS:
+		if b == 0 {
+			return NA_INTEGER, false
+		}
+		r = a % b
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+	default:
+		panic("?Op: " + op.String())
+	}
+	if r > math.MaxInt32 || r <= math.MinInt32 {
+		return NA_INTEGER, true
+	}
+	return r, false
+}
+
+func EvalIntegerOp(op token.Token, x *ISEXP, y *ISEXP) (*ISEXP, bool) {
+	overflow := false
+	if x.Slice == nil && y.Slice == nil {
+		r, o := integerOp(op, x.Integer, y.Integer)
+		return &ISEXP{Immediate: float64(r), Integer: r}, o
+	}
+	a, b := integerSlice(x), integerSlice(y)
+	length := calc.IntMax(len(a), len(b))
+	if len(a) == 0 || len(b) == 0 {
+		length = 0
+	}
+	r := make([]int, length)
+	for n := range r {
+		var o bool
+		r[n], o = integerOp(op, a[n%len(a)], b[n%len(b)])
+		overflow = overflow || o
+	}
+	return &ISEXP{Slice: r}, overflow
+}
+
+func complexOp(op token.Token, a complex128, b complex128) complex128 {
+	switch op {
+	case token.PLUS:
+		return a + b
+	case token.MINUS:
+		return a - b
+	case token.MULTIPLICATION:
+		return a * b
+	case token.DIVISION:
+		return a / b
+	case token.EXPONENTIATION:
+		return cmplx.Pow(a, b)
+	default:
+		panic("?Op: " + op.String())
+	}
+}
+
+func EvalComplexOp(ev *Evaluator, op token.Token, x *CSEXP, y *CSEXP) SEXPItf {
+	if op == token.MODULUS {
+		return errorf("invalid operation on complex numbers")
+	}
+	if x.Slice == nil && y.Slice == nil {
+		return &CSEXP{Immediate: complexOp(op, x.Immediate, y.Immediate)}
+	}
+	a, b := complexSlice(x), complexSlice(y)
+	length := calc.IntMax(len(a), len(b))
+	if len(a) == 0 || len(b) == 0 {
+		length = 0
+	}
+	r := make([]complex128, length)
+	for n := range r {
+		r[n] = complexOp(op, a[n%len(a)], b[n%len(b)])
+	}
+	return &CSEXP{Slice: r}
+}
+
+// comparisons are done on doubles, complex numbers or strings
+// and return logical vectors, which are NA for missing values and NaN
+func EvalComparison(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	if isBig(x) || isBig(y) {
+		return bigComparison(ev, op, x, y)
+	}
+	if !(isAtomic(x) || sexpType(x) == NILSXP) || !(isAtomic(y) || sexpType(y) == NILSXP) {
+		return errorf("comparison of these types is not implemented")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	return o.setAttributes(compareVectors(ev, op, x, y))
+}
+
+func compareVectors(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	var r []int
+	switch highestType([]SEXPItf{x, y}) {
+	case NILSXP, LGLSXP, INTSXP, REALSXP:
+		a := floatSlice(coerceVector(ev, x, REALSXP).(*VSEXP))
+		b := floatSlice(coerceVector(ev, y, REALSXP).(*VSEXP))
+		r = make([]int, mappedLength(len(a), len(b)))
+		for n := range r {
+			u, v := a[n%len(a)], b[n%len(b)]
+			if math.IsNaN(u) || math.IsNaN(v) {
+				r[n] = NA_LOGICAL
+			} else if u < v {
+				r[n] = comparisonResult(op, -1)
+			} else if u > v {
+				r[n] = comparisonResult(op, 1)
+			} else {
+				r[n] = comparisonResult(op, 0)
+			}
+		}
+	case CPLXSXP:
+		if op != token.EQUAL && op != token.UNEQUAL {
+			return errorf("invalid comparison with complex values")
+		}
+		a := complexSlice(coerceVector(ev, x, CPLXSXP).(*CSEXP))
+		b := complexSlice(coerceVector(ev, y, CPLXSXP).(*CSEXP))
+		r = make([]int, mappedLength(len(a), len(b)))
+		for n := range r {
+			u, v := a[n%len(a)], b[n%len(b)]
+			if cmplx.IsNaN(u) || cmplx.IsNaN(v) {
+				r[n] = NA_LOGICAL
+			} else if u == v {
+				r[n] = comparisonResult(op, 0)
+			} else {
+				r[n] = comparisonResult(op, 1)
+			}
+		}
+	case STRSXP:
+		a := stringSlice(coerceVector(ev, x, STRSXP).(*TSEXP))
+		b := stringSlice(coerceVector(ev, y, STRSXP).(*TSEXP))
+		r = make([]int, mappedLength(len(a), len(b)))
+		for n := range r {
+			u, v := a[n%len(a)], b[n%len(b)]
+			if u == NA_CHARACTER || v == NA_CHARACTER {
+				r[n] = NA_LOGICAL
+			} else {
+				r[n] = comparisonResult(op, strings.Compare(u, v))
+			}
+		}
+	default:
+		return errorf("comparison of these types is not implemented")
+	}
+	if isScalar(x) && isScalar(y) {
+		return &LSEXP{Immediate: r[0]}
+	}
+	return &LSEXP{Slice: r}
+}
+
+// https://cran.r-project.org/doc/manuals/R-lang.html#Logical-operators
+// & and | work elementwise with the truth tables of R including NA.
+// NaN counts as FALSE like zero, and strings are TRUE unless missing.
+
+func truthValues(x SEXPItf) ([]int, bool) {
+	r := make([]int, x.Length())
+	switch x.(type) {
+	case *LSEXP:
+		copy(r, logicalSlice(x.(*LSEXP)))
+	case *ISEXP:
+		for n, v := range integerSlice(x.(*ISEXP)) {
+			if v == NA_INTEGER {
+				r[n] = NA_LOGICAL
+			} else {
+				r[n] = logical(v != 0)
+			}
+		}
+	case *VSEXP:
+		if isFunction(x) {
+			return nil, false
+		}
+		for n, v := range floatSlice(x.(*VSEXP)) {
+			if calc.IsNA(v) {
+				r[n] = NA_LOGICAL
+			} else {
+				r[n] = logical(v != 0 && !math.IsNaN(v))
+			}
+		}
+	case *CSEXP:
+		for n, v := range complexSlice(x.(*CSEXP)) {
+			if isNAComplex(v) {
+				r[n] = NA_LOGICAL
+			} else {
+				r[n] = logical(v != 0 && !cmplx.IsNaN(v))
+			}
+		}
+	case *TSEXP:
+		for n, v := range stringSlice(x.(*TSEXP)) {
+			r[n] = logical(v != NA_CHARACTER)
+		}
+	case *NSEXP:
+	default:
+		return nil, false
+	}
+	return r, true
+}
+
+func logicalOp(op token.Token, a int, b int) int {
+	switch op {
+	case token.ANDVECTOR:
+		if a == FALSE || b == FALSE {
+			return FALSE
+		}
+		if a == NA_LOGICAL || b == NA_LOGICAL {
+			return NA_LOGICAL
+		}
+		return TRUE
+	case token.ORVECTOR:
+		if a == TRUE || b == TRUE {
+			return TRUE
+		}
+		if a == NA_LOGICAL || b == NA_LOGICAL {
+			return NA_LOGICAL
+		}
+		return FALSE
+	default:
+		panic("?Logical: " + op.String())
+	}
+}
+
+func EvalLogical(ev *Evaluator, op token.Token, x SEXPItf, y SEXPItf) SEXPItf {
+	a, okx := truthValues(x)
+	b, oky := truthValues(y)
+	if !okx || !oky {
+		return errorf("operations are possible only for numeric, logical or complex types")
+	}
+	o, ok := matchOperands(ev, x, y)
+	if !ok {
+		return &ESEXP{Kind: token.ILLEGAL}
+	}
+	r := make([]int, o.length)
+	for n := range r {
+		r[n] = logicalOp(op, a[n%len(a)], b[n%len(b)])
+	}
+	if isScalar(x) && isScalar(y) {
+		return o.setAttributes(&LSEXP{Immediate: r[0]})
+	}
+	return o.setAttributes(&LSEXP{Slice: r})
+}
+
+func EvalNot(ev *Evaluator, x SEXPItf) SEXPItf {
+	a, ok := truthValues(x)
+	if !ok {
+		return errorf("invalid argument type")
+	}
+	for n, v := range a {
+		if v != NA_LOGICAL {
+			a[n] = TRUE - v
+		}
+	}
+	var r *LSEXP
+	if isScalar(x) {
+		r = &LSEXP{Immediate: a[0]}
+	} else {
+		r = &LSEXP{Slice: a}
+	}
+	r.NamesSet(x.Names())
+	r.DimSet(x.Dim())
+	r.DimnamesSet(x.Dimnames())
+	return r
+}
+
+func mappedLength(lenx int, leny int) int {
+	if lenx == 0 || leny == 0 {
+		return 0
+	}
+	return calc.IntMax(lenx, leny)
+}
+
+// the logical result of a comparison, for which c is the sign of x-y
+func comparisonResult(op token.Token, c int) int {
+	switch op {
+	case token.EQUAL:
+		return logical(c == 0)
+	case token.UNEQUAL:
+		return logical(c != 0)
+	case token.LESS:
+		return logical(c < 0)
+	case token.LESSEQUAL:
+		return logical(c <= 0)
+	case token.GREATER:
+		return logical(c > 0)
+	case token.GREATEREQUAL:
+		return logical(c >= 0)
+	default:
+		panic("?Vcomp: " + op.String())
+	}
+}
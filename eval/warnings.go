@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"fmt"
+	"roq/lib/ast"
+	"strings"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/warning.html
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/stop.html
+// warnings are collected while evaluating a toplevel statement and printed after its result.
+// Errors, raised by stop and by builtins, unwind the evaluation as panics. They are printed
+// by the toplevel, which goes on with the next statement.
+
+func init() {
+	registerBuiltin("stop", []string{"...", "call."}, EvalStop)
+}
+
+// warnings are ignored, if the option warn is negative
+func (e *Evaluator) warning(call string, msg string) {
+	if e.optionFloat("warn", 0) < 0 {
+		return
+	}
+	if call != "" {
+		msg = "In " + call + " : " + msg
+	}
+	*e.warnings = append(*e.warnings, msg)
+}
+
+func printWarnings(ev *Evaluator) {
+	warnings := *ev.warnings
+	switch {
+	case len(warnings) == 0:
+		return
+	case len(warnings) == 1:
+		fmt.Fprintf(ev.out, "Warning message:\n%s\n", warnings[0])
+	case len(warnings) <= 10:
+		fmt.Fprintf(ev.out, "Warning messages:\n")
+		for n, w := range warnings {
+			fmt.Fprintf(ev.out, "%d: %s\n", n+1, w)
+		}
+	default:
+		fmt.Fprintf(ev.out, "There were %d warnings\n", len(warnings))
+	}
+	*ev.warnings = nil
+}
+
+// An R error, raised by panic. It unwinds the evaluation up to the toplevel statement or to
+// the goroutine, in which it occurred.
+type condition struct {
+	call    string // the call, which raised the error, if any
+	message string
+}
+
+func (c *condition) String() string {
+	if c.call == "" {
+		return "Error: " + c.message
+	}
+	return "Error in " + c.call + " : " + c.message
+}
+
+// the message of a recovered panic
+func panicMessage(p interface{}) string {
+	if c, ok := p.(*condition); ok {
+		return c.message
+	}
+	return fmt.Sprint(p)
+}
+
+func EvalStop(ev *Evaluator, node *ast.CallExpr, args *Arguments) SEXPItf {
+	var msg strings.Builder
+	for _, v := range args.Dots {
+		if v != nil && sexpType(v) != NILSXP {
+			msg.WriteString(strings.Join(asStrings(v), ""))
+		}
+	}
+	c := &condition{message: msg.String()}
+	if args.logical(1, true) && ev.call != "" {
+		c.call = ev.call + "()"
+	}
+	panic(c)
+}
@@ -1,8 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"roq/eval"
+	"roq/lib/parser"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 // eval unquotes only directly given identifers but evaluates normal expressions
@@ -45,3 +52,77 @@ func TestConstructedCall(t *testing.T) {
 	quicktestValue(t, "f<-function(a){a*2};eval(call(\"f\",3))",6, 0)
 	quicktestValue(t, "f<-function(a){a*2};b=\"f\";eval(call(b,3))",6, 0)
 }
+
+// interpreters running at the same time do not see each other's variables, options or output
+func TestConcurrentEvaluators(t *testing.T) {
+	script := `
+		options(warn = 1)
+		f <- function(n) { s <- 0; for (i in 1:n) s <- s + i; s }
+		x <- sapply(1:20, f)
+		x[20]
+		as.integer("a")
+		unlist(mclapply(1:4, function(k) k * id, mc.cores = 2))
+		plan("multicore", workers = 2)
+		y %<-% { f(id) }
+		y`
+	results := make(chan error, 8)
+	for id := 1; id <= 8; id++ {
+		go func(id int) {
+			var out, log bytes.Buffer
+			ev := eval.NewEvaluator(&out, &log, false, false)
+			_, err := ev.Eval("", "id <- "+strconv.Itoa(id)+script, parser.AllErrors, true)
+			expected := fmt.Sprintf("[1] 210\n[1] NA\nWarning message:\nNAs introduced by coercion\n[1] %d %d %d %d\n[1] %d\n",
+				id, 2*id, 3*id, 4*id, id*(id+1)/2)
+			if err == nil && out.String() != expected {
+				err = fmt.Errorf("evaluator %d printed\n%s", id, out.String())
+			}
+			results <- err
+		}(id)
+	}
+	for n := 0; n < 8; n++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+// syntax errors and failures of the evaluator are returned instead of panics
+func TestEvalError(t *testing.T) {
+	var out, log bytes.Buffer
+	ev := eval.NewEvaluator(&out, &log, false, false)
+	if _, err := ev.Eval("", "x <- 1\ny <- `x`", parser.AllErrors, true); err == nil {
+		t.Error("missing syntax error")
+	}
+	r, err := ev.Eval("", "x + 1", parser.AllErrors, false)
+	if err != nil || r.(*eval.VSEXP).Immediate != 2 {
+		t.Error("evaluator lost its global frame:", err)
+	}
+}
+
+// warnings of the scanner and tracing of the parser go to the writers of the evaluator
+func TestEvalParserOutput(t *testing.T) {
+	var out, log bytes.Buffer
+	ev := eval.NewEvaluator(&out, &log, false, false)
+	ev.Eval("", "x <- 1.5L\nx", parser.AllErrors|parser.Trace, true)
+	expected := "Warning message:\ninteger literal 1.5L contains decimal; using numeric value \n[1] 1.5\n"
+	if out.String() != expected {
+		t.Errorf("printed\n%s", out.String())
+	}
+	if !strings.Contains(log.String(), "Statement (") {
+		t.Errorf("tracing of the parser not written to log:\n%s", log.String())
+	}
+}
+
+// a recv() without sender is ended by cancelling the context of the evaluator
+func TestEvalContext(t *testing.T) {
+	var out bytes.Buffer
+	ev := eval.NewEvaluator(&out, &out, false, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	ev.SetContext(ctx)
+	time.AfterFunc(10*time.Millisecond, cancel)
+	ev.Eval("", "ch <- chan()\nrecv(ch)\nselect(recv(ch))\n1", parser.AllErrors, true)
+	expected := "Error in recv() : context canceled\nError in select() : context canceled\n[1] 1\n"
+	if out.String() != expected {
+		t.Errorf("printed\n%s", out.String())
+	}
+}
@@ -39,3 +39,150 @@ func ExampleMissingReturnValue() {
 // Output:
 //[1] 133
 }
+
+func ExampleStop() {
+	eval.EvalFileForTest("test/functions/stop.r")
+// Output:
+//Error in f() : bad
+//[1] 1
+//Error in h() : halt
+//Error in g() : negative value: -4
+//[1] 2
+//Error: no call
+//Error: at toplevel
+//Error in sqrt() : non-numeric argument to mathematical function
+//[1] 1
+}
+
+func ExampleFunctional() {
+	eval.EvalFileForTest("test/functions/functional.r")
+// Output:
+//[[1]]
+//[1] 2
+//
+//[[2]]
+//[1] 4
+//
+//[1] 1 2 3
+//	a	b
+//[1]	1	2
+//[2]	1	4
+//[1] 2 4 6
+//[1] 5 7 9
+//[[1]]
+//[1] 3
+//
+//[[2]]
+//[1] 8
+//
+//[1] 1 3 6 10
+//[1] 2400
+//b	c
+//3	5
+//[1] 2
+//[1] 3
+//[1] 6
+//[1] 9
+//[1] 3 4 5
+//[1] 120
+//[1] 4
+//[1] TRUE
+//list()
+//list()
+//[1] 6
+//a
+//1
+//[[1]]
+//[1] 1
+//
+//[[2]]
+//[1] 2
+//
+//$a.x
+//[1] 1
+//
+//$b.x
+//[1] 2
+//
+//[1] 2 4 6
+//a	b
+//"a"	"b"
+//numeric(0)
+//Error in vapply() : values must be type 'integer',
+//  but FUN(X[[1]]) result is type 'character'
+//[1] 0 0
+//[1] ""
+}
+
+func ExampleParallel() {
+	eval.EvalFileForTest("test/functions/parallel.r")
+// Output:
+//[1] 1 4 9 16
+//a	b	c
+//10	20	30
+//[1] 2 3 4
+//[1] 1 2 3
+//[1] 5
+//Warning message:
+//In mclapply() : 1 function calls resulted in an error
+//[1] "try-error"
+//Warning message:
+//In mclapply() : 1 function calls resulted in an error
+//[1] "Error in FUN(X[[i]], ...) : boom"
+//[2] "try-error" "try-error"
+//Warning message:
+//In mclapply() : 2 function calls resulted in an error
+//Error: none
+//Error: none
+//[1] 1 2
+}
+
+func ExampleChannels() {
+	eval.EvalFileForTest("test/functions/channels.r")
+// Output:
+//[1] 42
+//[1] 2 4 6
+//a	b
+//100	2
+//a	b
+//1	2
+//<channel: buffer 0>
+//[1] "externalptr"
+//[1] "channel"
+//[1] 0
+//[1] "nothing"
+//[1] 2
+//[1] 7
+//NULL
+//Error in send() : send on closed channel
+//Error in close() : close of closed channel
+//[1] TRUE
+//Error in recv() : timeout after 0.01 seconds
+//Error in send() : timeout after 0.01 seconds
+//[1] 2
+}
+
+func ExampleFutures() {
+	eval.EvalFileForTest("test/functions/futures.r")
+// Output:
+//[1] "sequential"
+//[1] 5050
+//[1] TRUE
+//[1] "Future"
+//[1] 55 210 465 820
+//[1] 200
+//[1] 15
+//Error in sqrt() : non-numeric argument to mathematical function
+//[1] 1
+//Warning message:
+//NAs introduced by coercion
+//outside
+//inside
+//[1] 42
+//Error in sqrt() : non-numeric argument to mathematical function
+//Error: object 'undefined_var' not found
+//Error: object 'undefined_var' not found
+//Error in log: nothing
+//[1] 5
+//Error: in future
+}
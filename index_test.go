@@ -1,6 +1,7 @@
 package main
 
 import (
+	"math"
 	"testing"
 )
 
@@ -31,3 +32,72 @@ func TestChainedIndex(t *testing.T) {
 }
 
 
+func TestMatrixIndex(t *testing.T) {
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[2,3]", []float64{66}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[,2]", []float64{33,44}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[2,]", []float64{22,44,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[1:2,c(1,3)]", []float64{11,22,55,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); dimnames(a)<-list(c(\"x\",\"y\"),c(\"u\",\"v\",\"w\")); a[\"y\",\"v\"]", []float64{44}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); m=c(1,2,3,1); dim(m)<-c(2,2); a[m]", []float64{55,22}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66,77,88); dim(a)<-c(2,2,2); a[2,1,2]", []float64{66}, 0)
+}
+
+func TestMatrixIndexedAssignment(t *testing.T) {
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[2,3]<-0; a", []float64{11,22,33,44,55,0}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); dim(a)<-c(2,3); a[,2]<-c(1,2); a", []float64{11,22,1,2,55,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33); b=a; a[2]<-0; b", []float64{11,22,33}, 0)
+}
+
+func TestNegativeIndex(t *testing.T) {
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); a[-1]", []float64{22,33,44,55,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); a[-(1:4)]", []float64{55,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); a[c(-1,0,-6,-7)]", []float64{22,33,44,55}, 0)
+}
+
+func TestLogicalIndex(t *testing.T) {
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); a[c(TRUE,FALSE)]", []float64{11,33,55}, 0)
+	quicktestSlice(t, "a=c(11,22,33,44,55,66); a[c(FALSE,FALSE,TRUE)]", []float64{33,66}, 0)
+	quicktestSlice(t, "a=c(11,22,33); a[TRUE]", []float64{11,22,33}, 0)
+	quicktestSlice(t, "a=c(1,5,10); a[a >= 5]", []float64{5,10}, 0)
+	quicktestSlice(t, "a=c(11,NaN,33); a[a != 11]", []float64{math.NaN(),33}, 0)
+}
+
+func TestCharacterIndex(t *testing.T) {
+	quicktestSlice(t, "a=c(x=11,y=22,z=33); a[\"y\"]", []float64{22}, 0)
+	quicktestSlice(t, "a=c(x=11,y=22,z=33); a[c(\"z\",\"x\")]", []float64{33,11}, 0)
+	quicktestSlice(t, "a=c(x=11,y=22,z=33); a[\"w\"]", []float64{math.NaN()}, 0)
+	quicktestSlice(t, "a=c(x=11,y=22); a[\"z\"]<-33; a", []float64{11,22,33}, 0)
+	quicktestSlice(t, "a=c(11,22,33); names(a)<-1:3; a[\"2\"]", []float64{22}, 0)
+}
+
+func TestZeroAndOutOfRangeIndex(t *testing.T) {
+	quicktestSlice(t, "a=c(11,22,33); a[c(0,2,0)]", []float64{22}, 0)
+	quicktestSlice(t, "a=c(11,22,33); a[c(1,5)]", []float64{11,math.NaN()}, 0)
+	quicktestSlice(t, "a=c(11,22,33); a[3:1]", []float64{33,22,11}, 0)
+	quicktestSlice(t, "a=c(11,22,33); b=c(2,3); a[b[1]]", []float64{22}, 0)
+}
+
+func TestListIndexByName(t *testing.T) {
+	quicktestValue(t, "a=list(alpha=11,beta=22); a[[\"beta\"]]", 22, 0)
+	quicktestValue(t, "a=list(alpha=11,beta=22); a[[\"al\", exact=FALSE]]", 11, 0)
+	quicktestValue(t, "a=list(alpha=11,beta=22); a$be", 22, 0)
+	quicktestValue(t, "a=list(alpha=11,beta=22); a$gamma<-33; a[[3]]", 33, 0)
+	quicktestValue(t, "a=c(11,22,33); a[[2]]", 22, 0)
+	quicktestValue(t, "a=c(11,22,33,44); dim(a)<-c(2,2); a[[1,2]]", 33, 0)
+}
+
+// $ binds tighter than [ and [[, so l$a[2] is (l$a)[2]
+func TestSubsetPrecedence(t *testing.T) {
+	quicktestSlice(t, "l=list(a=c(11,22,33)); l$a[2]", []float64{22}, 0)
+	quicktestValue(t, "l=list(a=c(11,22,33)); l$a[[2]]", 22, 0)
+	quicktestValue(t, "l=list(b=list(x=1,y=2)); l$b$y", 2, 0)
+	quicktestValue(t, "fit=list(coefficients=c(0.5,1.5)); fit$coefficients[[2]]", 1.5, 0)
+}
+
+func TestNestedAssignment(t *testing.T) {
+	quicktestSlice(t, "l=list(a=c(11,22,33)); l$a[2]<-0; l$a", []float64{11,0,33}, 0)
+	quicktestValue(t, "l=list(b=list(x=1,y=2)); l$b$y<-5; l$b[[\"y\"]]", 5, 0)
+	quicktestValue(t, "l=list(a=c(11,22,33)); l[[\"a\"]][[3]]<-0; sum(l$a)", 33, 0)
+	quicktestValue(t, "l=list(); l$b$c$d<-7; l$b$c$d", 7, 0)
+	quicktestSlice(t, "a=c(x=1,y=2); names(a)[2]<-\"z\"; a[\"z\"]", []float64{2}, 0)
+}
@@ -162,6 +162,12 @@ type (
 		Body *BlockStmt // function body
 	}
 
+	// A BlockExpr node represents a braced statement list used as an expression,
+	// its value is the value of the last statement.
+	BlockExpr struct {
+		Body *BlockStmt
+	}
+
 	// A CompositeLit node represents a composite literal.
 	CompositeLit struct {
 		Type   Expr      // literal type; or nil
@@ -188,20 +194,20 @@ type (
 		Sel *Ident // field selector
 	}
 
-	// An IndexExpr node represents an expression followed by an index.
+	// An IndexExpr node represents an expression followed by one index per dimension.
 	IndexExpr struct {
 		Array  Expr      // expression
 		Left   token.Pos // position of "["
-		Index  Expr      // index expression
+		Index  []Expr    // index expressions; nil for empty subscripts
 		Right  token.Pos // position of "]"
 	}
 
-	// An IndexExpr node represents an expression followed by an index.
+	// A ListIndexExpr node represents an expression followed by one index per dimension.
 	ListIndexExpr struct {
 		Array  Expr      // expression
-		Left   token.Pos // position of "["
-		Index  Expr      // index expression
-		Right  token.Pos // position of "]"
+		Left   token.Pos // position of "[["
+		Index  []Expr    // index expressions
+		Right  token.Pos // position of "]]"
 	}
 
 	// A CallExpr node represents an expression followed by an argument list.
@@ -288,6 +294,7 @@ func (x *Ident) Pos() token.Pos      { return x.NamePos }
 func (x *Ellipsis) Pos() token.Pos   { return x.ValuePos }
 func (x *BasicLit) Pos() token.Pos   { return x.ValuePos }
 func (x *FuncLit) Pos() token.Pos    { return x.Type.Pos() }
+func (x *BlockExpr) Pos() token.Pos  { return x.Body.Lbrace }
 func (x *CompositeLit) Pos() token.Pos {
 	if x.Type != nil {
 		return x.Type.Pos()
@@ -318,6 +325,7 @@ func (x *Ident) End() token.Pos          { return token.Pos(int(x.NamePos) + len
 func (x *Ellipsis) End() token.Pos       { return x.ValuePos + 2 }
 func (x *BasicLit) End() token.Pos       { return token.Pos(int(x.ValuePos) + len(x.Value)) }
 func (x *FuncLit) End() token.Pos        { return x.Body.End() }
+func (x *BlockExpr) End() token.Pos      { return x.Body.Rbrace + 1 }
 func (x *CompositeLit) End() token.Pos   { return x.Right + 1 }
 func (x *ParenExpr) End() token.Pos      { return x.Right + 1 }
 func (x *QuotedExpr) End() token.Pos     { return x.Right + 1 }
@@ -347,6 +355,7 @@ func (*Ident) exprNode()          {}
 func (*Ellipsis) exprNode()       {}
 func (*BasicLit) exprNode()       {}
 func (*FuncLit) exprNode()        {}
+func (*BlockExpr) exprNode()      {}
 func (*CompositeLit) exprNode()   {}
 func (*ParenExpr) exprNode()      {}
 func (*QuotedExpr) exprNode()     {}
@@ -54,6 +54,10 @@ func FilteredPrint(fset *token.FileSet, x interface{}, f FieldFilter, dense bool
 	return fprint(os.Stdout, fset, x, f, dense)
 }
 
+func FilteredFprint(w io.Writer, fset *token.FileSet, x interface{}, f FieldFilter, dense bool) error {
+	return fprint(w, fset, x, f, dense)
+}
+
 func fprint(w io.Writer, fset *token.FileSet, x interface{}, f FieldFilter, dense bool) (err error) {
 	// setup printer
 	p := printer{
@@ -205,6 +205,11 @@ func (p *Parser) parseOperand(lhs bool) ast.Expr {
 			return &ast.ParenExpr{Left: lparen, X: x, Right: rparen}
 		case token.FUNCTION:
 			return p.parseFuncLit()
+		case token.LBRACE:
+			p.exprLev++
+			body := p.parseBlockStmt()
+			p.exprLev--
+			return &ast.BlockExpr{Body: body}
 		}
 	}
 
@@ -269,26 +274,43 @@ func (p *Parser) parseIndex(x ast.Expr) ast.Expr {
 	}
 
 	lbrack := p.expect(token.LBRACK)
-	var index ast.Expr
-	if p.tok != token.SEQUENCE {  // TODO comma for dims
-		index = p.parseRhs()
-	}
+	p.exprLev++
+	index := p.parseSubscriptList(token.RBRACK)
 	p.exprLev--
 	rbrack := p.expect(token.RBRACK)
 
 	return &ast.IndexExpr{Array: x, Left: lbrack, Index: index, Right: rbrack}
 }
 
+// comma separated subscripts, one for each dimension
+// empty subscripts as in x[,2] are kept as nil, tagged arguments like drop=FALSE as TaggedExpr
+func (p *Parser) parseSubscriptList(closing token.Token) (list []ast.Expr) {
+	if p.trace {
+		defer un(trace(p, "SubscriptList"))
+	}
+
+	for {
+		if p.tok == token.COMMA || p.tok == closing {
+			list = append(list, nil)
+		} else {
+			list = append(list, p.parseParameter())
+		}
+		if p.tok != token.COMMA {
+			break
+		}
+		p.next()
+	}
+	return
+}
+
 func (p *Parser) parseListIndex(x ast.Expr) ast.Expr {
 	if p.trace {
 		defer un(trace(p, "ListIndex"))
 	}
 
 	dlbrack := p.expect(token.DOUBLELBRACK)
-	var index ast.Expr
-	if p.tok != token.SEQUENCE {  // TODO comma for dims
-		index = p.parseRhs()
-	}
+	p.exprLev++
+	index := p.parseSubscriptList(token.DOUBLERBRACK)
 	p.exprLev--
 	drbrack := p.expect(token.DOUBLERBRACK)
 
@@ -459,6 +481,16 @@ L:
 				sel := &ast.Ident{NamePos: pos, Name: "_"}
 				x = &ast.SelectorExpr{X: x, Sel: sel}
 			}
+		case token.DOUBLECOLON:
+			// all builtins share one namespace, so the package is dropped
+			p.next()
+			x = p.parseIdent()
+		case token.SUBSET, token.SLOT:
+			// $ and @ bind tighter than indices and calls, so l$a[2] is (l$a)[2]
+			pos, op := p.pos, p.tok
+			p.next()
+			y := p.parseOperand(false)
+			x = &ast.BinaryExpr{X: x, OpPos: pos, Op: op, Y: y}
 		case token.LBRACK:
 			x = p.parseIndex(x)
 		case token.DOUBLELBRACK:
@@ -496,6 +528,11 @@ func (p *Parser) parseUnaryExpr(lhs bool) ast.Expr {
 		p.next()
 		x := p.parseUnaryExpr(lhs)
 		return &ast.UnaryExpr{OpPos: pos, Op: op, X: x}
+	case token.TILDE: // one-sided formula, the operand extends as far as for the binary operator
+		pos := p.pos
+		p.next()
+		x := p.parseBinaryExpr(lhs, token.TILDE.Precedence()+1)
+		return &ast.UnaryExpr{OpPos: pos, Op: token.TILDE, X: x}
 	case token.ELLIPSIS:
 		pos := p.pos
 		p.next()
@@ -539,10 +576,15 @@ func (p *Parser) parseBinaryExpr(lhs bool, prec1 int) ast.Expr {
 		if oprec < prec1 {
 			return r
 		}
+		lit := p.lit
 		pos := p.expect(operator)
 		if lhs {
 		}
 		y := p.parseBinaryExpr(false, oprec+1)
+		if operator == token.SPECIAL { // a %x% b is the call `%x%`(a, b)
+			r = &ast.CallExpr{Fun: &ast.Ident{NamePos: pos, Name: lit}, Left: pos, Args: []ast.Expr{r, y}, Right: pos}
+			continue
+		}
 		r = &ast.BinaryExpr{X: r, OpPos: pos, Op: operator, Y: y}
 	}
 	return r
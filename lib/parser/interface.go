@@ -11,6 +11,7 @@ import (
 	"errors"
 	"io"
 	"io/ioutil"
+	"os"
 	"roq/lib/ast"
 	"roq/lib/token"
 )
@@ -125,8 +126,9 @@ func ParseFile(fset *token.FileSet, filename string, src interface{}, mode Mode)
 }
 */
 
-// parserInit and parseIter are derived from SPLITTED ParseFile and parseFile to allow for subsequent parsing
-func ParseInit(fset *token.FileSet, filename string, src interface{}, mode Mode) (r *Parser, err error) {
+// parserInit and parseIter are derived from SPLITTED ParseFile and parseFile to allow for subsequent parsing.
+// The echo of the input and warnings are written to out, tracing to log.
+func ParseInit(fset *token.FileSet, filename string, src interface{}, mode Mode, out io.Writer, log io.Writer) (r *Parser, err error) {
 
 	if fset == nil {
 		panic("parser.ParseFile: no token.FileSet provided (fset == nil)")
@@ -139,12 +141,17 @@ func ParseInit(fset *token.FileSet, filename string, src interface{}, mode Mode)
 	}
 
 	var p Parser
-	p.init(fset, filename, text, mode)
+	p.init(fset, filename, text, mode, out, log)
 	assert(&p != nil, "nil instead of parser")
 	return &p, err
 }
 
 
+// Errors returns the syntax errors found so far or nil
+func Errors(p *Parser) error {
+	return p.errors.Err()
+}
+
 func ParseIter(p *Parser) (s ast.Stmt, tok token.Token) {
 
 	if true /* p.mode&ImportsOnly == 0 */ {
@@ -183,7 +190,7 @@ func ParseExprFrom(fset *token.FileSet, filename string, src interface{}, mode M
 	}()
 
 	// parse expr
-	p.init(fset, filename, text, mode)
+	p.init(fset, filename, text, mode, os.Stdout, os.Stderr)
 	e := p.parseRhs()
 
 	// If a semicolon was inserted, consume it;
@@ -24,6 +24,7 @@ package parser
 
 import (
 	"fmt"
+	"io"
 	"roq/lib/scanner"
 	"roq/lib/token"
 )
@@ -39,6 +40,8 @@ type Parser struct {
 	debug  bool // == (mode & Debug != 0)
 	echo   bool // == (mode & Echo  != 0)
 	indent int  // indentation used for tracing output
+	out    io.Writer // echo of the input and warnings
+	log    io.Writer // tracing and debugging
 
 	// Next token
 	pos token.Pos   // token position
@@ -58,15 +61,16 @@ type Parser struct {
 
 }
 
-func (p *Parser) init(fset *token.FileSet, filename string, src []byte, mode Mode) {
+func (p *Parser) init(fset *token.FileSet, filename string, src []byte, mode Mode, out io.Writer, log io.Writer) {
 	p.file = fset.AddFile(filename, -1, len(src))
+	p.out, p.log = out, log
 
 	p.trace = mode&Trace != 0 // for convenience (p.trace is used frequently)
 	p.debug = mode&Debug != 0 // for convenience (p.debug is used frequently)
 	p.echo  = mode&Echo  != 0
 
 	eh := func(pos token.Position, msg string) { p.errors.Add(pos, msg) }
-	p.scanner.Init(p.file, src, eh, p.echo)
+	p.scanner.Init(p.file, src, eh, p.echo, out)
 
 
 	p.next()
@@ -78,13 +82,13 @@ func (p *Parser) init(fset *token.FileSet, filename string, src []byte, mode Mod
 
 func (p *Parser) printTrace(a ...interface{}) {
 	pos := p.file.Position(p.pos)
-	fmt.Printf("%5d:%3d: ", pos.Line, pos.Column)
+	fmt.Fprintf(p.log, "%5d:%3d: ", pos.Line, pos.Column)
 	i := p.indent
 	for i > 0 {
-		fmt.Print(". ")
+		fmt.Fprint(p.log, ". ")
 		i -= 1
 	}
-	fmt.Println(a...)
+	fmt.Fprintln(p.log, a...)
 }
 
 func trace0(p *Parser, msg string) *Parser {
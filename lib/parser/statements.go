@@ -18,6 +18,7 @@
 package parser
 
 import (
+	"fmt"
 	"roq/lib/ast"
 	"roq/lib/token"
 )
@@ -236,8 +237,8 @@ func (p *Parser) parseStmt() (s ast.Stmt) {
 	case
 		// tokens that may start an expression
 		token.IDENT, token.INT, token.FLOAT, token.IMAG, token.STRING, token.FUNCTION, token.LPAREN, // operands
-		token.NULL, token.NA, token.INF, token.NAN, token.TRUE, token.FALSE, // constants
-		token.PLUS, token.MINUS, token.NOT, // unary operators
+		token.NULL, token.NA, token.NA_INTEGER, token.NA_REAL, token.NA_CHARACTER, token.INF, token.NAN, token.TRUE, token.FALSE, // constants
+		token.PLUS, token.MINUS, token.NOT, token.TILDE, // unary operators
 		token.LBRACK,
 		token.QUOTE, token.EVAL, token.CALL:
 		a := p.parseAssignment() // this parses an assignment or an expression stmt!
@@ -274,7 +275,7 @@ func (p *Parser) parseStmt() (s ast.Stmt) {
 		// a semicolon may be omitted before a closing "}"
 		s = &ast.EmptyStmt{Semicolon: p.pos, Implicit: true}
 	case token.EOF:
-		println("EOF encountered during parseStmt")
+		fmt.Fprintln(p.log, "EOF encountered during parseStmt")
 		s = &ast.EOFStmt{EOF: p.pos}
 	default:
 		// no statement found
@@ -10,6 +10,7 @@ package scanner
 
 import (
 	"fmt"
+	"io"
 	"roq/lib/token"
 	"path/filepath"
 	"unicode"
@@ -35,6 +36,7 @@ type Scanner struct {
 	err  ErrorHandler // error reporting; or nil
 	echo bool
 	start bool
+	out  io.Writer // echo and warnings
 
 	// scanning state
 	ch         rune // current character
@@ -43,6 +45,7 @@ type Scanner struct {
 	lineOffset int  // current line offset
 	insertSemi bool // insert a semicolon before next newline
 	lastchar   bool // if last char is reached
+	brackets   []token.Token // open brackets, to tell "]]" from nested "]" "]"
 
 	// public state - ok to modify
 	ErrorCount int // number of errors encountered
@@ -59,7 +62,7 @@ func (s *Scanner) Echo() {
 		for (s.lineOffset+i) < len(s.src) {   		// print complete line
 			c := s.src[s.lineOffset+i]
 			if c>32 {s.start=false}      			// lines containing only whitespace won't unset start
-			print(string(c))
+			fmt.Fprint(s.out, string(c))
 			if  c == '\n' {break}
 			i++
 		}
@@ -70,7 +73,7 @@ func (s *Scanner) Echo() {
 			printline(s)
 		}
 	}
-	if s.ch<0{println()}
+	if s.ch<0{fmt.Fprintln(s.out)}
 }
 
 
@@ -128,7 +131,9 @@ func (s *Scanner) next() {
 // Note that Init may call err if there is an error in the first character
 // of the file.
 //
-func (s *Scanner) Init(file *token.File, src []byte, err ErrorHandler, echo bool) {
+// The echo of the input and warnings are written to out.
+//
+func (s *Scanner) Init(file *token.File, src []byte, err ErrorHandler, echo bool, out io.Writer) {
 	// Explicitly initialize all fields since a scanner may be reused.
 	if file.Size() != len(src) {
 		panic(fmt.Sprintf("file size (%d) does not match src len (%d)", file.Size(), len(src)))
@@ -138,12 +143,14 @@ func (s *Scanner) Init(file *token.File, src []byte, err ErrorHandler, echo bool
 	s.src = src
 	s.err = err
 	s.echo = echo
+	s.out = out
 
 	s.ch = ' '
 	s.offset = 0
 	s.rdOffset = 0
 	s.lineOffset = 0
 	s.insertSemi = false
+	s.brackets = nil
 	s.ErrorCount = 0
 
 	s.next()
@@ -389,9 +396,8 @@ exponent:
 	// integer literal
 	if s.ch == 'L' {
 		if seenDecimalPoint {
-			println("Warning message:")
 			// there is a trailing space
-			println("integer literal "+ string(s.src[offs:s.offset])+"L contains decimal; using numeric value ")
+			fmt.Fprintf(s.out, "Warning message:\ninteger literal %sL contains decimal; using numeric value \n", s.src[offs:s.offset])
 		} else {
 			tok=token.INT
 		}
@@ -480,6 +486,19 @@ func (s *Scanner) scanEscape(quote rune) bool {
 	return true
 }
 
+// special operators like %in% are enclosed in percent signs on a single line
+func (s *Scanner) scanSpecial() (token.Token, string) {
+	offs := s.offset - 1 // '%' opening already consumed
+	for s.ch != '%' {
+		if s.ch == '\n' || s.ch < 0 {
+			return token.ILLEGAL, ""
+		}
+		s.next()
+	}
+	s.next()
+	return token.SPECIAL, string(s.src[offs:s.offset])
+}
+
 func (s *Scanner) scanString(terminator rune) string {
 	// opening quote already consumed
 	offs := s.offset
@@ -659,10 +678,19 @@ scanAgain:
 				tok = token.IDENT
 			} else {
 				insertSemi = true
+				lit = "." // the dot of model formulae
 				tok = token.NA
 			}
 		case ':':
-			tok = token.SEQUENCE
+			if s.ch == ':' {
+				s.next()
+				if s.ch == ':' {
+					s.next()
+				}
+				tok = token.DOUBLECOLON
+			} else {
+				tok = token.SEQUENCE
+			}
 		case '$':
 			tok = token.SUBSET
 		case '@':
@@ -684,9 +712,15 @@ scanAgain:
 			} else {
 			tok = token.LBRACK
 			}
+			s.brackets = append(s.brackets, tok)
 		case ']':
 			insertSemi = true
-			if s.ch == ']' {
+			open := token.LBRACK
+			if n := len(s.brackets); n > 0 {
+				open = s.brackets[n-1]
+				s.brackets = s.brackets[:n-1]
+			}
+			if s.ch == ']' && open == token.DOUBLELBRACK {
 				s.next()
 				tok = token.DOUBLERBRACK
 			} else {
@@ -732,10 +766,12 @@ scanAgain:
 				s.next()
 				tok = token.MODULUS
 			} else {
-				tok = token.ILLEGAL
+				tok, lit = s.scanSpecial()
 			}
 		case '^':
 			tok = token.EXPONENTIATION
+		case '~':
+			tok = token.TILDE
 		case '<':
 			if s.ch == '-' {
 				s.next()
@@ -53,6 +53,9 @@ const (
 
 	NULL
 	NA // TODO: extension documentation: Single dot is treated as missing value
+	NA_INTEGER
+	NA_REAL
+	NA_CHARACTER
 	INF
 	NAN
 	TRUE
@@ -152,6 +155,8 @@ const (
 
 	// R SPECIALOPERATORS
 
+	SPECIAL              // %x%	Special binary operators, evaluated as call of the function "%x%"
+
 	/*
 	   %x%	Special binary operators, x can be replaced by any valid name
 	   %/%	Integer divide, binary
@@ -205,6 +210,9 @@ var tokenNames = [...]string{
 
 	NULL:  "NULL",
 	NA:    "NA",
+	NA_INTEGER:   "NA_integer_",
+	NA_REAL:      "NA_real_",
+	NA_CHARACTER: "NA_character_",
 	INF:   "Inf",
 	NAN:   "NaN",
 	TRUE:  "TRUE",
@@ -221,6 +229,7 @@ var tokenNames = [...]string{
 	MULTIPLICATION:       "*",  // *	Multiplication, binary
 	DIVISION:             "/",  // /	Division, binary
 	MODULUS:              "%%", // %%	Modulus, binary
+	SPECIAL:              "%x%", // %x%	Special binary operator
 	EXPONENTIATION:       "^",  // ^	Exponentiation, binary
 	LESS:                 "<",  // <	Less than, binary
 	GREATER:              ">",  // >	Greater than, binary
@@ -338,7 +347,7 @@ func (op Token) Precedence() int {
 		return 13
 	case SEQUENCE:
 		return 12
-	case MODULUS:
+	case MODULUS, SPECIAL:
 		return 11
 	case MULTIPLICATION, DIVISION:
 		return 10
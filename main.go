@@ -17,10 +17,6 @@ import (
 	"roq/version"
 )
 
-var TRACE bool
-var DEBUG bool
-var ECHO  bool
-
 func myerrorhandler(pos token.Position, msg string) {
 	println("SCANNER ERROR", pos.Filename, pos.Line, pos.Column, msg)
 }
@@ -35,7 +31,7 @@ func mainScan(filePtr *string, srcString string, ECHO bool) {
 	}
 	var s scanner.Scanner
 	file := fset.AddFile(*filePtr, fset.Base(), len(src)) // register input "file"
-	s.Init(file, src, myerrorhandler, ECHO)
+	s.Init(file, src, myerrorhandler, ECHO, os.Stdout)
 
 	// Repeated calls to Scan yield the token sequence found in the input
 	for {
@@ -53,7 +49,7 @@ func mainScan(filePtr *string, srcString string, ECHO bool) {
 
 func mainParse(filePtr *string, src interface{}, parserOpts parser.Mode) {
 	fset := token.NewFileSet() // positions are relative to fset
-	p, err := parser.ParseInit(fset, *filePtr, src, parserOpts)
+	p, err := parser.ParseInit(fset, *filePtr, src, parserOpts, os.Stdout, os.Stderr)
 	if err != nil {
 		fmt.Println(err)
 		return
@@ -87,9 +83,9 @@ func main() {
 	exprPtr := flag.String("expr", "", "expression to process")
 	flag.Parse()
 
-	TRACE = *traceFlagPtr || *traceLongPtr
-	DEBUG = *debugFlagPtr || *debugLongPtr
-	ECHO = *echoFlagPtr || *echoLongPtr
+	TRACE := *traceFlagPtr || *traceLongPtr
+	DEBUG := *debugFlagPtr || *debugLongPtr
+	ECHO := *echoFlagPtr || *echoLongPtr
 	PRINT := true
 
 	if *versionPtr {
@@ -122,9 +118,7 @@ func main() {
 			strconv.Itoa(version.DAY) + 
 			")")
 		reader := bufio.NewReader(os.Stdin)
-		//for s, _, err:= reader.ReadLine(); err==nil; {
-			//eval.EvalStringForTest(string(s))
-		//}
+		ev := eval.NewEvaluator(os.Stdout, os.Stderr, TRACE, DEBUG)
 		for true {
 			fmt.Print("> ")
 			s, _, err := reader.ReadLine()
@@ -134,7 +128,7 @@ func main() {
 			if ECHO {
 				println("> "+string(s))
 			}
-			eval.EvalStringForTest(string(s))
+			ev.Eval("", string(s), parser.AllErrors, PRINT)
 		}
 	} else if *scanPtr {
 		mainScan(filePtr, *exprPtr, ECHO)
@@ -40,9 +40,9 @@ func ExampleMissingValues() {
 		r = 1  +2 %% 10 + NA
 		r`)
 	// Output:
+	// [1] NA
 	// [1] NaN
-	// [1] NaN
-	// [1] NaN
+	// [1] NA
 }
 
 
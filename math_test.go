@@ -0,0 +1,731 @@
+package main
+
+import (
+	"roq/eval"
+	"testing"
+)
+
+func ExampleMathFunctions() {
+	eval.EvalFileForTest("test/math/functions.r")
+	// Output:
+	//a	b
+	//2	3
+	//[1] NaN
+	//Warning message:
+	//NaNs produced
+	//[1] 0+1i
+	//[1] 2
+	//[1] 3
+	//[1] 0 NA NaN
+	//Warning message:
+	//NaNs produced
+	//[1] 0 2 2
+	//[1] 0.12 -0.12 2.67
+	//[1] 120
+	//[1] 120000
+	//[1] 24
+	//[1] NaN
+	//Warning message:
+	//NaNs produced
+	//[1] 10
+	//[1] 6
+	//[1] 1 120
+	//[1] 3
+	//[1] 5
+	//[1] -1 0 1
+	//	[,1]	[,2]
+	//[1]	1	3
+	//[2]	2	4
+	//Error in sqrt() : non-numeric argument to mathematical function
+}
+
+func TestMathFunctions(t *testing.T) {
+	quicktestValue(t, "exp(log(10))", 10, 1e-12)
+	quicktestValue(t, "log1p(1e-10)", 1e-10, 1e-20)
+	quicktestValue(t, "expm1(1e-10)", 1e-10, 1e-20)
+	quicktestValue(t, "log10(1000)", 3, 0)
+	quicktestValue(t, "atan(1)*4", 3.141592653589793, 1e-15)
+	quicktestValue(t, "cosh(0)", 1, 0)
+	quicktestValue(t, "trunc(-1.7)", -1, 0)
+	quicktestValue(t, "ceiling(-1.7)", -1, 0)
+	quicktestValue(t, "round(2.5)", 2, 0)
+	quicktestValue(t, "round(0.15, 1)", 0.1, 0)
+	quicktestValue(t, "signif(0.000123456, 3)", 0.000123, 1e-18)
+	quicktestValue(t, "gamma(0.5)^2", 3.141592653589793, 1e-14)
+	quicktestValue(t, "lgamma(100)", 359.1342053695754, 1e-10)
+	quicktestValue(t, "digamma(1)", -0.5772156649015329, 1e-14)
+	quicktestValue(t, "digamma(-0.5)", 0.03648997397857652, 1e-14)
+	quicktestValue(t, "beta(2,3)", 1.0/12, 1e-15)
+	quicktestValue(t, "lbeta(2,3)", -2.484906649788, 1e-12)
+	quicktestValue(t, "choose(50,25)", 126410606437752, 0)
+	quicktestValue(t, "choose(2.5,2)", 1.875, 1e-15)
+	quicktestValue(t, "factorial(10)", 3628800, 1e-6)
+}
+
+func ExampleStatistics() {
+	eval.EvalFileForTest("test/math/statistics.r")
+	// Output:
+	//[1] 0
+	//[1] 3
+	//[1] 2
+	//[1] 2
+	//[1] 1.5
+	//0%	25%	50%	75%	100%
+	//1	3.25	5.5	7.75	10
+	//10%	50%
+	//1.5	5.5
+	//[1] 1 3
+	//[1] 4 5 6
+	//a	b	c
+	//1	2	2
+	//a	b	c
+	//1	3	6
+	//[1] 1 NA NA
+	//[1] 1 2 6 24
+	//[1] 1 3 3 5
+	//[1] 5 3 3 1
+	//[1] 2 2
+	//	[,1]	[,2]
+	//[1]	1	8
+	//[2]	2	16
+}
+
+func TestStatistics(t *testing.T) {
+	quicktestValue(t, "sum(c(0.1,0.2,0.3))", 0.6, 0)
+	quicktestValue(t, "mean(c(1,2,3,4,100), trim=0.5)", 3, 0)
+	quicktestValue(t, "sd(c(1,2,3,4))", 1.2909944487358056, 1e-15)
+	quicktestValue(t, "cor(c(1,2,3,4,5), c(2,4,5,4,5))", 0.7745966692414834, 1e-15)
+	quicktestValue(t, "cor(c(1,2,3,4,5), c(2,4,5,4,5), method=\"spearman\")", 0.7378647873726218, 1e-15)
+	quicktestValue(t, "cor(c(1,2,3,4,5), c(2,4,5,4,5), method=\"kendall\")", 0.6708203932499369, 1e-15)
+	quicktestSlice(t, "quantile(c(1,2,3,4,5,6,7,8,9,10), probs=c(0.1,0.33), type=6)", []float64{1.1, 3.63}, 1e-12)
+	quicktestSlice(t, "quantile(c(1,2,3,4,5,6,7,8,9,10), probs=c(0.1,0.33), type=8)", []float64{1.3666666666666667, 3.7433333333333333}, 1e-12)
+	quicktestSlice(t, "quantile(c(1,2,3,4,5,6,7,8,9,10), probs=c(0.1,0.5), type=3)", []float64{1, 5}, 0)
+}
+
+func ExampleRandom() {
+	eval.EvalFileForTest("test/math/random.r")
+	// Output:
+	//[1] 0.2655087 0.3721239 0.5728534
+	//[1] 1.3709584 -0.5646982 0.3631284
+	//[1] 0.7551818 1.1816428
+	//[1] 2 2 3 5 2
+	//[1] 4 4 5 7 4
+	//[1] 3 10 2 8 6 9 1 7 5 4
+	//[1] 1 5 10 8 2 4 6 9 7 3
+	//[1] 30 30 20 30 30
+	//[3] "Mersenne-Twister" "Inversion" "Rejection"
+	//[1] 10407 1806547166 -983674937 643431772 1162448557 -959247990 -133913213
+	//[1] 10407 1801422725 -2057975723 1156894209 1595475487 210384600 -1655729657
+	//[1] 0.3411064 0.3123993 0.1494334 0.7767615
+	//[1] 0.3411064 0.3123993 0.1494334 0.7767615
+	//[1] 0.3411064
+	//[1] NaN NaN
+	//Warning message:
+	//NAs produced
+	//Error in sample() : cannot take a sample larger than the population when 'replace = FALSE'
+	//integer(0)
+}
+
+func ExampleDistributions() {
+	eval.EvalFileForTest("test/math/distributions.r")
+	// Output:
+	//[1] 0.9750021
+	//[1] 1.959964
+	//[1] 0.8413447 0.5 0.1586553
+	//[1] -804.6084
+	//[1] -3.9139462
+	//[1] 0.1171875
+	//[1] 0.5039893
+	//[1] 0.1353353 0.2706706 0.2706706 0.180447 0.0902235
+	//[1] 1 3 5
+	//[1] 2.228139
+	//[1] 0.069663
+	//[1] 3.841459 5.991465 7.814728
+	//[1] 4.102821
+	//[1] 0.7618967
+	//[1] 5.035041
+	//[1] 0.579825
+	//[1] 2.0736
+	//[1] 0.657
+	//[1] 0.1367188
+	//[1] 0.0004114
+	//[1] 0.7558914
+	//[1] 2.4976638
+	//[1] 1
+	//[1] 1.0986123
+	//[1] 0.8646647
+	//[1] NaN
+	//Warning message:
+	//NaNs produced
+	//Error in dgamma() : specify 'rate' or 'scale' but not both
+	//Error in dnbinom() : 'prob' and 'mu' both specified
+	//[1] 1.3
+	//[1] 0.4
+	//[1] 182
+}
+
+func ExampleLinearAlgebra() {
+	eval.EvalFileForTest("test/math/linalg.r")
+	// Output:
+	//	[,1]	[,2]
+	//[1]	0.6	-0.2
+	//[2]	-0.2	0.4
+	//[1] 0.2 0.6
+	//[1] 2
+	//[1] 1.447214 0.894427
+	//	[,1]	[,2]
+	//[1]	-2.236068	-4.91935
+	//[2]	0	-0.894427
+	//	[,1]	[,2]
+	//[1]	-0.447214	-0.894427
+	//[2]	-0.894427	0.447214
+	//[1] -0.666667 1.5
+	//[1] 0.166667 -0.333333 0.166667
+	//	[,1]	[,2]
+	//[1]	1.414214	0.707107
+	//[2]	0	1.581139
+	//	[,1]	[,2]
+	//[1]	0.6	-0.2
+	//[2]	-0.2	0.4
+	//[1] 5.464986 0.365966
+	//	[,1]	[,2]
+	//[1]	-0.576048	-0.817416
+	//[2]	-0.817416	0.576048
+	//	[,1]	[,2]
+	//[1]	-0.404554	0.914514
+	//[2]	-0.914514	-0.404554
+	//[1] 3.618034 1.381966
+	//	[,1]	[,2]
+	//[1]	0.525731	-0.850651
+	//[2]	0.850651	0.525731
+	//	[,1]	[,2]
+	//[1]	-0.565767	-0.909377
+	//[2]	-0.824565	0.415974
+	//[1] -2
+	//[1] -1
+	//[1] 0.074651 1.264911
+	//[1] 1 1
+	//Error in solve() : Lapack routine dgesv: system is exactly singular: U[2,2] = 0
+	//Error in chol() : the leading minor of order 2 is not positive
+	//[1] 0+1i 0-1i
+	//	[,1]	[,2]
+	//[1]	0.707107+0i	0.707107+0i
+	//[2]	0-0.707107i	0+0.707107i
+	//	[,1]	[,2]
+	//[1]	0.707107	-0.707107
+	//[2]	0.707107	0.707107
+	//	[,1]	[,2]
+	//[1]	-0.707107	-0.707107
+	//[2]	0.707107	-0.707107
+	//	[,1]	[,2]	[,3]
+	//[1]	-0.5	-0.707107	0.5
+	//[2]	0.707107	0	0.707107
+	//[3]	-0.5	0.707107	0.5
+}
+
+func ExampleFormula() {
+	eval.EvalFileForTest("test/math/formula.r")
+	// Output:
+	//y ~ x + log(z)
+	//[1] "formula"
+	//[3] "y" "x" "z"
+	//y ~ a * b
+	//attr(,"variables")
+	//list(y, a, b)
+	//attr(,"factors")
+	//	a	b	a:b
+	//y	0	0	0
+	//a	1	0	1
+	//b	0	1	1
+	//attr(,"term.labels")
+	//[3] "a" "b" "a:b"
+	//attr(,"order")
+	//[1] 1 1 2
+	//attr(,"intercept")
+	//[1] 1
+	//attr(,"response")
+	//[1] 1
+	//[6] "a" "b" "c" "a:b" "a:c" "b:c"
+	//[2] "a" "a:b"
+	//[2] "u" "v"
+	//log(y) ~ x
+	//y ~ x - 1
+	//$y
+	//[1] 1 3 4
+	//
+	//$x
+	//[1] 1 3 4
+	//
+	//	(Intercept)	x	gb	gc	x:gb	x:gc
+	//1	1	1	0	0	0	0
+	//2	1	2	1	0	2	0
+	//3	1	3	0	0	0	0
+	//4	1	4	0	1	0	4
+	//	ga	gb	gc
+	//1	1	0	0
+	//2	0	1	0
+	//3	1	0	0
+	//4	0	0	1
+	//Error in model.frame() : variable lengths differ (found for 'x')
+	//Error in terms() : '.' in formula and no 'data' argument
+}
+
+func ExampleModels() {
+	eval.EvalFileForTest("test/math/models.r")
+	// Output:
+	//
+	//Call:
+	//lm(formula = y ~ x)
+	//
+	//Residuals:
+	//     Min      1Q  Median      3Q     Max
+	//-0.8917 -0.1542  0.0750  0.2354  0.5000
+	//
+	//Coefficients:
+	//             Estimate Std. Error t value Pr(>|t|)
+	//(Intercept)  0.05000    0.36628   0.137    0.896
+	//x            1.99167    0.07253  27.458 1.54e-07 ***
+	//---
+	//Signif. codes:  0 ‘***’ 0.001 ‘**’ 0.01 ‘*’ 0.05 ‘.’ 0.1 ‘ ’ 1
+	//
+	//Residual standard error: 0.4701 on 6 degrees of freedom
+	//Multiple R-squared:  0.9921,	Adjusted R-squared:  0.9908
+	//F-statistic: 754 on 1 and 6 DF,  p-value: 1.543e-07
+	//
+	//Analysis of Variance Table
+	//
+	//Response: y
+	//           Df  Sum Sq Mean Sq F value    Pr(>F)
+	//x          1 166.603 166.603  753.95 1.543e-07 ***
+	//Residuals  6   1.326   0.221
+	//---
+	//Signif. codes:  0 ‘***’ 0.001 ‘**’ 0.01 ‘*’ 0.05 ‘.’ 0.1 ‘ ’ 1
+	//1	2
+	//17.974999999999998	19.96666666666666
+	//
+	//Call:
+	//glm(formula = dead ~ dose, family = binomial)
+	//
+	//Coefficients:
+	//             Estimate Std. Error z value Pr(>|z|)
+	//(Intercept)  -2.2903     1.7991  -1.273    0.203
+	//dose          0.5279     0.3377   1.563    0.118
+	//
+	//(Dispersion parameter for binomial family taken to be 1)
+	//
+	//     Null deviance: 13.4602  on 9  degrees of freedom
+	//Residual deviance:  9.8027  on 8  degrees of freedom
+	//AIC: 13.803
+	//
+	//Number of Fisher Scoring iterations: 4
+	//
+	//1	2
+	//0.2747545141589533	0.9711446337287382
+	//
+	//Call:
+	//lm(formula = y ~ x)
+	//
+	//Coefficients:
+	//(Intercept)            x
+	//       0.050        1.992
+	//
+	//[1] NA 5.4523 0
+	//[1] NA 0.0655 1
+	//Error in glm() : y values must be 0 <= y <= 1
+	//Analysis of Variance Table
+	//
+	//Model 1: y ~ 1
+	//Model 2: y ~ x
+	//   Res.Df     RSS Df Sum of Sq      F    Pr(>F)
+	//1      7 167.929
+	//2      6   1.326  1     166.6 753.95 1.543e-07 ***
+	//---
+	//Signif. codes:  0 ‘***’ 0.001 ‘**’ 0.01 ‘*’ 0.05 ‘.’ 0.1 ‘ ’ 1
+	//Analysis of Deviance Table
+	//
+	//Model 1: dead ~ 1
+	//Model 2: dead ~ dose
+	//   Resid. Df Resid. Dev Df Deviance Pr(>Chi)
+	//1         9    13.4602
+	//2         8     9.8027  1   3.6575  0.05582 .
+	//---
+	//Signif. codes:  0 ‘***’ 0.001 ‘**’ 0.01 ‘*’ 0.05 ‘.’ 0.1 ‘ ’ 1
+}
+
+func ExampleOptim() {
+	eval.EvalFileForTest("test/math/optim.r")
+	// Output:
+	//[1] 1.0003 1.0005
+	//function	gradient
+	//195	NA
+	//[1] 0
+	//[1] 1 1
+	//function	gradient
+	//110	43
+	//function	gradient
+	//402	101
+	//[1] 1
+	//[1] -1.4112 2
+	//[1] "CONVERGENCE: REL_REDUCTION_OF_F <= FACTR*EPSMCH"
+	//[1] 0.333333
+	//[1] 1.259921
+	//0.5 with absolute error < 4.7e-05
+	//1.253314 with absolute error < 0.00012
+	//3.141593 with absolute error < 2.7e-05
+	//failed with message ‘maximum number of subdivisions reached’
+	//[1] 1 2
+	//[1] 1
+	//[1] 2
+}
+
+func ExampleHtest() {
+	eval.EvalFileForTest("test/math/htest.r")
+	// Output:
+	//	Welch Two Sample t-test
+	//
+	//data:  x and y
+	//t = -1.8608, df = 17.776, p-value = 0.07939
+	//alternative hypothesis: true difference in means is not equal to 0
+	//95 percent confidence interval:
+	//  -3.3654832  0.2054832
+	//sample estimates:
+	//mean of x mean of y
+	//      0.75      2.33
+	//
+	//[1] 0.002833
+	//
+	//	One Sample t-test
+	//
+	//data:  x
+	//t = -0.4419, df = 9, p-value = 0.3345
+	//alternative hypothesis: true mean is less than 1
+	//95 percent confidence interval:
+	//      -Inf 1.787055
+	//sample estimates:
+	//mean of x
+	//      0.75
+	//
+	//df
+	//18
+	//
+	//	Wilcoxon signed rank exact test
+	//
+	//data:  x and y
+	//V = 40, p-value = 0.01953
+	//alternative hypothesis: true location shift is greater than 0
+	//
+	//[1] 0.019076
+	//
+	//	Wilcoxon rank sum exact test
+	//
+	//data:  x and y
+	//W = 35, p-value = 0.2544
+	//alternative hypothesis: true location shift is not equal to 0
+	//95 percent confidence interval:
+	//  -0.15  0.76
+	//sample estimates:
+	//difference in location
+	//                  0.305
+	//
+	//[1] "Wilcoxon signed rank test with continuity correction"
+	//Warning messages:
+	//1: In wilcox.test() : cannot compute exact p-value with ties
+	//2: In wilcox.test() : cannot compute exact confidence interval with ties
+	//
+	//	Pearson's Chi-squared test
+	//
+	//data:  M
+	//X-squared = 30.07, df = 2, p-value = 2.954e-07
+	//
+	//[1] 0.040594
+	//
+	//	Fisher's Exact Test for Count Data
+	//
+	//data:  T
+	//p-value = 0.4857
+	//alternative hypothesis: true odds ratio is not equal to 1
+	//95 percent confidence interval:
+	//    0.2117329 621.9337505
+	//sample estimates:
+	//odds ratio
+	//   6.408309
+	//
+	//[1] 0.313569 Inf
+	//
+	//	Kendall's rank correlation tau
+	//
+	//data:  x and y
+	//T = 26, p-value = 0.05972
+	//alternative hypothesis: true tau is greater than 0
+	//sample estimates:
+	//       tau
+	//0.4444444
+	//
+	//[1] 0.048399
+	//
+	//	Shapiro-Wilk normality test
+	//
+	//data:  x
+	//W = 0.84001, p-value = 0.05777
+	//
+	//W
+	//0.9135
+	//
+	//	Pearson's product-moment correlation
+	//
+	//data:  x and y
+	//t = 1.8411, df = 7, p-value = 0.1082
+	//alternative hypothesis: true correlation is not equal to 0
+	//95 percent confidence interval:
+	//  -0.1497426  0.8955795
+	//sample estimates:
+	//       cor
+	//0.5711816
+	//
+	//Error in t.test() : not enough 'x' observations
+}
+
+func ExampleFFT() {
+	eval.EvalFileForTest("test/math/fft.r")
+	// Output:
+	//[1] 10+0i -2+2i -2+0i -2-2i
+	//[1] 28+0i -3.5+7.267825i -3.5+2.791157i -3.5+0.798852i -3.5-0.798852i -3.5-2.791157i -3.5-7.267825i
+	//[1] 1 2 3 4 5 6 7 8
+	//[1] 0
+	//	[,1]	[,2]	[,3]
+	//[1]	21+0i	-6+3.464102i	-6-3.464102i
+	//[2]	-3+0i	0+0i	0+0i
+	//	[,1]	[,2]	[,3]
+	//[1]	3+0i	7+0i	11+0i
+	//[2]	-1+0i	-1+0i	-1+0i
+	//[1] 0.5 2 3.5 3 0
+	//[1] 3 5 7
+	//[1] 2 3 4 1
+	//[1] NA 6 9 12 15 NA
+	//[1] NA 1.5 2.5 3.5 4.5 5.5
+	//[1] 3 5 7 9 6
+	//[1] 1 2.5 4.25 6.125 8.0625
+	//[1] 2 3.25 5.125 7.375
+	//[1] 8 15 100 1024
+	//[1] 9
+	//[1] 5
+	//[1] 1-2i 0+1i
+	//[1] 0
+	//Error in mvfft() : vector-valued (multivariate) series required
+}
+
+func ExampleInterpolate() {
+	eval.EvalFileForTest("test/math/interpolate.r")
+	// Output:
+	//[1] 3 3.75 NA NA
+	//[1] 2 15
+	//[1] 3 3.5
+	//[1] 2
+	//$x
+	//[1] 1 2 3
+	//
+	//$y
+	//[1] 10 20 30
+	//
+	//[1] 0 3.5 14
+	//[1] 1 4 7 10
+	//[1] 2 7 9 15
+	//[1] 3.44951 6.775
+	//[1] 15.625 125
+	//[1] 18.75
+	//[1] 2.18913 2.18913
+	//[1] 1.625 2 3.0625 13
+	//[1] 0
+	//[1] 1.875 2 3 7.041667
+	//Error in approx() : need at least two non-NA values to interpolate
+	//[1] "smooth.spline"
+	//icrit	ispar	iter
+	//1	0	11
+	//[1] 0.7801305 0.1112206 2.635278 4187.776 244.1044
+	//[1] -1.6713 21.9471 108.0549
+	//[1] 3.49 3.8097
+	//[1] 9.998
+	//[1] 0.001052
+}
+
+func ExampleBigz() {
+	eval.EvalFileForTest("test/math/bigz.r")
+	// Output:
+	//Big Integer ('bigz') :
+	//[1] 1267650600228229401496703205376
+	//Big Integer ('bigz') :
+	//[1] 1267650600228229401496703205377
+	//Big Integer ('bigz') :
+	//[1] 370370367037037036703703703670
+	//Big Integer ('bigz') object of length 2:
+	//[1] 1 2
+	//Big Integer ('bigz') object of length 2:
+	//[1] 2 -3
+	//[1] 3
+	//[1] -4
+	//[1] 3
+	//Big Integer ('bigz') object of length 3:
+	//[1] 1 2 3
+	//Big Integer ('bigz') :
+	//[1] -5
+	//Big Integer ('bigz') :
+	//[1] -2
+	//Big Integer ('bigz') :
+	//[1] 255
+	//Big Integer ('bigz') :
+	//[1] NA
+	//Big Rational ('bigq') :
+	//[1] 1/2
+	//Big Rational ('bigq') :
+	//[1] 1/2
+	//Big Rational ('bigq') :
+	//[1] 3602879701896397/36028797018963968
+	//Big Rational ('bigq') :
+	//[1] 22
+	//Big Rational ('bigq') :
+	//[1] 9/4
+	//Big Rational ('bigq') :
+	//[1] 1/2
+	//Big Rational ('bigq') :
+	//[1] 11/2
+	//Big Rational ('bigq') :
+	//[1] NA
+	//Big Integer ('bigz') :
+	//[1] 3
+	//Big Integer ('bigz') :
+	//[1] 2
+	//Big Integer ('bigz') object of length 3:
+	//[1] 6 4 6
+	//Big Integer ('bigz') :
+	//[1] 12
+	//Big Integer ('bigz') :
+	//[1] 15511210043330985984000000
+	//Big Integer ('bigz') object of length 6:
+	//[1] 1 1 2 6 24 120
+	//Big Integer ('bigz') :
+	//[1] 100891344545564193334812497256
+	//Big Integer ('bigz') :
+	//[1] 3
+	//[1] 0 2 0 2
+	//[1] 1
+	//[1] 0.25
+	//[1] "2432902008176640000"
+	//[1] 6
+	//[1] "bigq"
+	//[1] TRUE
+	//[1] FALSE
+	//bigz(0)
+	//Error: exponent must be an integer
+	//[1] FALSE FALSE FALSE FALSE TRUE TRUE
+	//[1] TRUE TRUE TRUE TRUE TRUE FALSE
+	//[1] TRUE
+	//[1] TRUE
+	//[1] NA FALSE
+	//Big Integer ('bigz') :
+	//[1] 154
+	//Big Integer ('bigz') :
+	//[1] 2432902008176640000
+	//Big Rational ('bigq') :
+	//[1] 11/6
+	//Big Integer ('bigz') :
+	//[1] NA
+	//Big Integer ('bigz') :
+	//[1] 1
+	//Big Integer ('bigz') object of length 3:
+	//[1] 1 2 1180591620717411303424
+	//Big Rational ('bigq') object of length 2:
+	//[1] 1 1/2
+	//Big Integer ('bigz') object of length 2:
+	//[1] 2 6
+	//Big Integer ('bigz') object of length 5:
+	//[1] 1 2 6 24 120
+	//Big Integer ('bigz') object of length 3:
+	//[1] 6 24 120
+	//Big Integer ('bigz') :
+	//[1] NA
+}
+
+func ExampleSparse() {
+	eval.EvalFileForTest("test/math/sparse.r")
+	// Output:
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	5	.	.
+	//[2]	.	.	6
+	//[3]	.	5	.
+	//[1] "dgCMatrix"
+	//[2] 3 3
+	//[1] 3
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	5	.	.
+	//[2]	.	.	5
+	//[3]	.	6	.
+	//	[,1]	[,2]	[,3]
+	//[1]	5	0	0
+	//[2]	0	0	6
+	//[3]	0	5	0
+	//[1] 5 6 5
+	//[1] 5 5 6
+	//	[,1]
+	//[1]	5
+	//[2]	6
+	//[3]	5
+	//	[,1]	[,2]	[,3]
+	//[1]	5	15	12
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	25	.	.
+	//[2]	.	36	.
+	//[3]	.	.	25
+	//[1] 6
+	//[1] 5 0 0
+	//2 x 2 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]
+	//[1]	.	6
+	//[2]	5	.
+	//[1] 0
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	10	.	.
+	//[2]	.	.	12
+	//[3]	.	10	.
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	10	.	.
+	//[2]	.	.	12
+	//[3]	.	10	.
+	//3 x 3 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]	[,3]
+	//[1]	-5	.	.
+	//[2]	.	.	-6
+	//[3]	.	-5	.
+	//	[,1]	[,2]	[,3]
+	//[1]	6	1	1
+	//[2]	1	1	7
+	//[3]	1	6	1
+	//2 x 2 sparse Matrix of class "dgCMatrix"
+	//	x	y
+	//a	.	1
+	//b	1	.
+	//x	y
+	//1	0
+	//2 x 2 sparse Matrix of class "dgCMatrix"
+	//	[,1]	[,2]
+	//[1]	1	.
+	//[2]	.	2
+	//	x	y
+	//[1]	0	1
+	//[2]	2	0
+	//	[,1]	[,2]
+	//[1]	1	0
+	//[2]	0	4
+	//	[,1]	[,2]
+	//[1]	1	0
+	//[2]	0	2
+	//	[,1]
+	//[1]	11
+	//[1] 1 2
+	//Error in sparseMatrix() : 'i' and 'j' must be positive integers
+	//Error in %*%() : non-conformable arguments
+}
@@ -0,0 +1,374 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The beta distribution and the t and F distributions derived from it. The
+// distribution function evaluates the continued fraction of the incomplete beta
+// function in the tail where it converges fast, scaled by the binomial density.
+
+func Dbeta(x float64, a float64, b float64, logD bool) float64 {
+	if isNaN(x, a, b) {
+		return x + a + b
+	}
+	if a < 0 || b < 0 {
+		return math.NaN()
+	}
+	if x < 0 || x > 1 {
+		return d0(logD)
+	}
+	// limit cases with point masses
+	if a == 0 || b == 0 || math.IsInf(a, 1) || math.IsInf(b, 1) {
+		var at float64
+		switch {
+		case a == 0 && b == 0:
+			if x == 0 || x == 1 {
+				return posInf
+			}
+			return d0(logD)
+		case a == 0 || math.IsInf(a/b, 1):
+			at = 0
+		case b == 0 || math.IsInf(b/a, 1):
+			at = 1
+		default:
+			at = 0.5
+		}
+		if x == at {
+			return posInf
+		}
+		return d0(logD)
+	}
+	if x == 0 {
+		switch {
+		case a > 1:
+			return d0(logD)
+		case a < 1:
+			return posInf
+		}
+		return dVal(b, logD)
+	}
+	if x == 1 {
+		switch {
+		case b > 1:
+			return d0(logD)
+		case b < 1:
+			return posInf
+		}
+		return dVal(a, logD)
+	}
+	var lval float64
+	if a <= 2 || b <= 2 {
+		lval = (a-1)*math.Log(x) + (b-1)*math.Log1p(-x) - Lbeta(a, b)
+	} else {
+		lval = math.Log(a+b-1) + dbinomRaw(a-1, a+b-2, x, 1-x, true)
+	}
+	return dExp(lval, logD)
+}
+
+func Pbeta(x float64, a float64, b float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, a, b) {
+		return x + a + b
+	}
+	if a < 0 || b < 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return dt0(lowerTail, logP)
+	}
+	if x >= 1 {
+		return dt1(lowerTail, logP)
+	}
+	return pbetaRaw(x, a, b, lowerTail, logP)
+}
+
+func pbetaRaw(x float64, a float64, b float64, lowerTail bool, logP bool) float64 {
+	if a == 0 || b == 0 || math.IsInf(a, 1) || math.IsInf(b, 1) {
+		switch {
+		case a == 0 && b == 0:
+			if logP {
+				return -ln2
+			}
+			return 0.5
+		case a == 0 || math.IsInf(a/b, 1):
+			return dt1(lowerTail, logP)
+		case b == 0 || math.IsInf(b/a, 1):
+			return dt0(lowerTail, logP)
+		case x < 0.5:
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	// the continued fraction converges fast below the mean, use the symmetry above
+	lower := x < (a+1)/(a+b+2)
+	y := 0.5 - x + 0.5
+	if !lower {
+		x, y, a, b = y, x, b, a
+	}
+	lp := dbinomRaw(a, a+b, x, y, true) + math.Log(b/(a+b)) + math.Log(betacf(x, a, b))
+	if lower != lowerTail {
+		if logP {
+			return log1Exp(lp)
+		}
+		return -math.Expm1(lp)
+	}
+	return dExp(lp, logP)
+}
+
+// the continued fraction of the incomplete beta function by the modified Lentz's method
+func betacf(x float64, a float64, b float64) float64 {
+	const (
+		maxit = 100000000
+		tiny  = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c := 1.
+	d := 1 - qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1.; m < maxit; m++ {
+		m2 := 2 * m
+		aa := m * (b - m) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + m) * (qab + m) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < dblEpsilon {
+			break
+		}
+	}
+	return h
+}
+
+func Qbeta(p float64, a float64, b float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, a, b) {
+		return p + a + b
+	}
+	if a < 0 || b < 0 {
+		return math.NaN()
+	}
+	if v, done := qP01Boundaries(p, 0, 1, lowerTail, logP); done {
+		return v
+	}
+	return continuousQuantile(p, 0, 1, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pbeta(x, a, b, lowerTail, logP)
+	})
+}
+
+func Dt(x float64, n float64, logD bool) float64 {
+	if isNaN(x, n) {
+		return x + n
+	}
+	if n <= 0 {
+		return math.NaN()
+	}
+	if !isFinite(x) {
+		return d0(logD)
+	}
+	if !isFinite(n) {
+		return Dnorm(x, 0, 1, logD)
+	}
+	t := -bd0(n/2, (n+1)/2) + stirlerr((n+1)/2) - stirlerr(n/2)
+	x2n := x * x / n
+	var u, lx2n, ax float64
+	large := x2n > 1/dblEpsilon
+	switch {
+	case large:
+		ax = math.Abs(x)
+		lx2n = math.Log(ax) - math.Log(n)/2
+		u = n * lx2n
+	case x2n > 0.2:
+		lx2n = math.Log(1+x2n) / 2
+		u = n * lx2n
+	default:
+		lx2n = math.Log1p(x2n) / 2
+		u = -bd0(n/2, (n+x*x)/2) + x*x/2
+	}
+	if logD {
+		return t - u - (lnSqrt2Pi + lx2n)
+	}
+	isqrt := math.Exp(-lx2n)
+	if large {
+		isqrt = math.Sqrt(n) / ax
+	}
+	return math.Exp(t-u) * oneSqrt2Pi * isqrt
+}
+
+func Pt(x float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, n) {
+		return x + n
+	}
+	if n <= 0 {
+		return math.NaN()
+	}
+	if !isFinite(x) {
+		if x < 0 {
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	if !isFinite(n) {
+		return Pnorm(x, 0, 1, lowerTail, logP)
+	}
+	var val float64
+	nx := 1 + (x/n)*x
+	if nx > 1e100 {
+		lval := -0.5*n*(2*math.Log(math.Abs(x))-math.Log(n)) - Lbeta(0.5*n, 0.5) - math.Log(0.5*n)
+		val = dExp(lval, logP)
+	} else if n > x*x {
+		val = Pbeta(x*x/(n+x*x), 0.5, n/2, false, logP)
+	} else {
+		val = Pbeta(1/nx, n/2, 0.5, true, logP)
+	}
+	// val is twice the tail beyond |x|
+	if x <= 0 {
+		lowerTail = !lowerTail
+	}
+	if logP {
+		if lowerTail {
+			return math.Log1p(-0.5 * math.Exp(val))
+		}
+		return val - ln2
+	}
+	return dCval(val/2, lowerTail)
+}
+
+func Qt(p float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, n) {
+		return p + n
+	}
+	if v, done := qP01Boundaries(p, negInf, posInf, lowerTail, logP); done {
+		return v
+	}
+	if n <= 0 {
+		return math.NaN()
+	}
+	return symmetricQuantile(p, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pt(x, n, lowerTail, logP)
+	})
+}
+
+func Df(x float64, m float64, n float64, logD bool) float64 {
+	if isNaN(x, m, n) {
+		return x + m + n
+	}
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return d0(logD)
+	}
+	if x == 0 {
+		switch {
+		case m > 2:
+			return d0(logD)
+		case m == 2:
+			return d1(logD)
+		}
+		return posInf
+	}
+	if !isFinite(m) && !isFinite(n) {
+		if x == 1 {
+			return posInf
+		}
+		return d0(logD)
+	}
+	if !isFinite(n) {
+		return Dgamma(x, m/2, 2/m, logD)
+	}
+	if m > 1e14 {
+		dens := Dgamma(1/x, n/2, 2/n, logD)
+		if logD {
+			return dens - 2*math.Log(x)
+		}
+		return dens / (x * x)
+	}
+	f := 1 / (n + x*m)
+	q := n * f
+	p := x * m * f
+	var dens float64
+	if m >= 2 {
+		f = m * q / 2
+		dens = dbinomRaw((m-2)/2, (m+n-2)/2, p, q, logD)
+	} else {
+		f = m * m * q / (2 * p * (m + n))
+		dens = dbinomRaw(m/2, (m+n)/2, p, q, logD)
+	}
+	if logD {
+		return math.Log(f) + dens
+	}
+	return f * dens
+}
+
+func Pf(x float64, m float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, m, n) {
+		return x + m + n
+	}
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return dt0(lowerTail, logP)
+	}
+	if math.IsInf(x, 1) {
+		return dt1(lowerTail, logP)
+	}
+	if math.IsInf(n, 1) {
+		if math.IsInf(m, 1) {
+			switch {
+			case x < 1:
+				return dt0(lowerTail, logP)
+			case x == 1:
+				if logP {
+					return -ln2
+				}
+				return 0.5
+			}
+			return dt1(lowerTail, logP)
+		}
+		return Pchisq(x*m, m, lowerTail, logP)
+	}
+	if math.IsInf(m, 1) {
+		return Pchisq(n/x, n, !lowerTail, logP)
+	}
+	if m*x > n {
+		return Pbeta(n/(n+m*x), n/2, m/2, !lowerTail, logP)
+	}
+	return Pbeta(m*x/(n+m*x), m/2, n/2, lowerTail, logP)
+}
+
+func Qf(p float64, m float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, m, n) {
+		return p + m + n
+	}
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	return continuousQuantile(p, 0, posInf, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pf(x, m, n, lowerTail, logP)
+	})
+}
@@ -0,0 +1,465 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The binomial, Poisson, geometric, negative binomial and hypergeometric
+// distributions. Densities are zero at non-integer x, distribution functions are
+// taken from the gamma and beta distributions, quantiles are searched from the
+// Cornish-Fisher expansion.
+
+// R_D_negInonint
+func negInonint(x float64) bool {
+	return x < 0 || nonint(x)
+}
+
+// the starting point of the search for a quantile from mean, standard deviation and skewness
+func cornishFisher(p float64, mu float64, sigma float64, gamma float64, lowerTail bool, logP bool) float64 {
+	z := Qnorm(p, 0, 1, lowerTail, logP)
+	return math.Floor(mu + sigma*(z+gamma*(z*z-1)/6) + 0.5)
+}
+
+func Dbinom(x float64, n float64, p float64, logD bool) float64 {
+	if isNaN(x, n, p) {
+		return x + n + p
+	}
+	if p < 0 || p > 1 || negInonint(n) {
+		return math.NaN()
+	}
+	if nonint(x) || x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	return dbinomRaw(forceint(x), forceint(n), p, 1-p, logD)
+}
+
+func Pbinom(x float64, n float64, p float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, n, p) {
+		return x + n + p
+	}
+	if !isFinite(n) || !isFinite(p) || nonint(n) {
+		return math.NaN()
+	}
+	n = forceint(n)
+	if n < 0 || p < 0 || p > 1 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	x = math.Floor(x + 1e-7)
+	if n <= x {
+		return dt1(lowerTail, logP)
+	}
+	return Pbeta(p, x+1, n-x, !lowerTail, logP)
+}
+
+func Qbinom(p float64, n float64, pr float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, n, pr) {
+		return p + n + pr
+	}
+	if !isFinite(n) || !isFinite(pr) || !isFinite(p) && !logP || nonint(n) {
+		return math.NaN()
+	}
+	n = forceint(n)
+	if pr < 0 || pr > 1 || n < 0 {
+		return math.NaN()
+	}
+	if v, done := qP01Boundaries(p, 0, n, lowerTail, logP); done {
+		return v
+	}
+	if pr == 0 || n == 0 {
+		return 0
+	}
+	q := 1 - pr
+	if q == 0 {
+		return n
+	}
+	mu := n * pr
+	sigma := math.Sqrt(n * pr * q)
+	y := cornishFisher(p, mu, sigma, (q-pr)/sigma, lowerTail, logP)
+	return discreteQuantile(p, y, 0, n, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pbinom(x, n, pr, lowerTail, logP)
+	})
+}
+
+func Dpois(x float64, lambda float64, logD bool) float64 {
+	if isNaN(x, lambda) {
+		return x + lambda
+	}
+	if lambda < 0 {
+		return math.NaN()
+	}
+	if nonint(x) || x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	return dpoisRaw(forceint(x), lambda, logD)
+}
+
+func Ppois(x float64, lambda float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, lambda) {
+		return x + lambda
+	}
+	if lambda < 0 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if lambda == 0 || !isFinite(x) {
+		return dt1(lowerTail, logP)
+	}
+	x = math.Floor(x + 1e-7)
+	return Pgamma(lambda, x+1, 1, !lowerTail, logP)
+}
+
+func Qpois(p float64, lambda float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, lambda) {
+		return p + lambda
+	}
+	if !isFinite(lambda) || lambda < 0 {
+		return math.NaN()
+	}
+	if lambda == 0 {
+		return 0
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	sigma := math.Sqrt(lambda)
+	y := cornishFisher(p, lambda, sigma, 1/sigma, lowerTail, logP)
+	return discreteQuantile(p, y, 0, posInf, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Ppois(x, lambda, lowerTail, logP)
+	})
+}
+
+func Dgeom(x float64, p float64, logD bool) float64 {
+	if isNaN(x, p) {
+		return x + p
+	}
+	if p <= 0 || p > 1 {
+		return math.NaN()
+	}
+	if nonint(x) || x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	prob := dbinomRaw(0, forceint(x), p, 1-p, logD)
+	if logD {
+		return math.Log(p) + prob
+	}
+	return p * prob
+}
+
+func Pgeom(x float64, p float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, p) {
+		return x + p
+	}
+	if p <= 0 || p > 1 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if !isFinite(x) {
+		return dt1(lowerTail, logP)
+	}
+	x = math.Floor(x + 1e-7)
+	if p == 1 {
+		return dt1(lowerTail, logP)
+	}
+	x = math.Log1p(-p) * (x + 1)
+	if logP {
+		return dtClog(x, lowerTail, true)
+	}
+	if lowerTail {
+		return -math.Expm1(x)
+	}
+	return math.Exp(x)
+}
+
+func Qgeom(p float64, prob float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, prob) {
+		return p + prob
+	}
+	if prob <= 0 || prob > 1 || qP01Invalid(p, logP) {
+		return math.NaN()
+	}
+	if prob == 1 {
+		return 0
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	return math.Max(0, math.Ceil(dtClog(p, lowerTail, logP)/math.Log1p(-prob)-1-1e-12))
+}
+
+func Dnbinom(x float64, size float64, prob float64, logD bool) float64 {
+	if isNaN(x, size, prob) {
+		return x + size + prob
+	}
+	if prob <= 0 || prob > 1 || size < 0 {
+		return math.NaN()
+	}
+	if nonint(x) || x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	if x == 0 && size == 0 {
+		return d1(logD)
+	}
+	x = forceint(x)
+	if !isFinite(size) {
+		size = math.MaxFloat64
+	}
+	ans := dbinomRaw(size, x+size, prob, 1-prob, logD)
+	p := size / (size + x)
+	if logD {
+		return math.Log(p) + ans
+	}
+	return p * ans
+}
+
+func DnbinomMu(x float64, size float64, mu float64, logD bool) float64 {
+	if isNaN(x, size, mu) {
+		return x + size + mu
+	}
+	if mu < 0 || size < 0 {
+		return math.NaN()
+	}
+	if nonint(x) || x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	if x == 0 && size == 0 {
+		return d1(logD)
+	}
+	x = forceint(x)
+	if !isFinite(size) {
+		return dpoisRaw(x, mu, logD)
+	}
+	if x == 0 {
+		if size < mu {
+			return dExp(size*math.Log(size/(size+mu)), logD)
+		}
+		return dExp(size*math.Log1p(-mu/(size+mu)), logD)
+	}
+	if x < 1e-10*size {
+		p := math.Log(mu / (1 + mu/size))
+		if size < mu {
+			p = math.Log(size / (1 + size/mu))
+		}
+		lg, _ := math.Lgamma(x + 1)
+		return dExp(x*p-mu-lg+math.Log1p(x*(x-1)/(2*size)), logD)
+	}
+	p := size / (size + x)
+	ans := dbinomRaw(size, x+size, size/(size+mu), mu/(size+mu), logD)
+	if logD {
+		return math.Log(p) + ans
+	}
+	return p * ans
+}
+
+func Pnbinom(x float64, size float64, prob float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, size, prob) {
+		return x + size + prob
+	}
+	if !isFinite(size) || !isFinite(prob) || size < 0 || prob <= 0 || prob > 1 {
+		return math.NaN()
+	}
+	if size == 0 {
+		if x >= 0 {
+			return dt1(lowerTail, logP)
+		}
+		return dt0(lowerTail, logP)
+	}
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if !isFinite(x) {
+		return dt1(lowerTail, logP)
+	}
+	x = math.Floor(x + 1e-7)
+	return Pbeta(prob, size, x+1, lowerTail, logP)
+}
+
+func PnbinomMu(x float64, size float64, mu float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, size, mu) {
+		return x + size + mu
+	}
+	if !isFinite(mu) || size < 0 || mu < 0 {
+		return math.NaN()
+	}
+	if size == 0 {
+		if x >= 0 {
+			return dt1(lowerTail, logP)
+		}
+		return dt0(lowerTail, logP)
+	}
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if !isFinite(x) {
+		return dt1(lowerTail, logP)
+	}
+	if !isFinite(size) {
+		return Ppois(x, mu, lowerTail, logP)
+	}
+	x = math.Floor(x + 1e-7)
+	return Pbeta(size/(size+mu), size, x+1, lowerTail, logP)
+}
+
+func Qnbinom(p float64, size float64, prob float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, size, prob) {
+		return p + size + prob
+	}
+	if prob == 0 && size == 0 {
+		return 0
+	}
+	if prob <= 0 || prob > 1 || size < 0 {
+		return math.NaN()
+	}
+	if prob == 1 || size == 0 {
+		return 0
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	Q := 1 / prob
+	P := (1 - prob) * Q
+	sigma := math.Sqrt(size * P * Q)
+	y := cornishFisher(p, size*P, sigma, (Q+P)/sigma, lowerTail, logP)
+	return discreteQuantile(p, y, 0, posInf, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pnbinom(x, size, prob, lowerTail, logP)
+	})
+}
+
+func QnbinomMu(p float64, size float64, mu float64, lowerTail bool, logP bool) float64 {
+	if math.IsInf(size, 1) {
+		return Qpois(p, mu, lowerTail, logP)
+	}
+	return Qnbinom(p, size, size/(size+mu), lowerTail, logP)
+}
+
+// x white balls drawn in k draws from an urn with m white and n black balls
+func Dhyper(x float64, m float64, n float64, k float64, logD bool) float64 {
+	if isNaN(x, m, n, k) {
+		return x + m + n + k
+	}
+	if negInonint(m) || negInonint(n) || negInonint(k) || k > m+n {
+		return math.NaN()
+	}
+	if x < 0 || nonint(x) {
+		return d0(logD)
+	}
+	x, m, n, k = forceint(x), forceint(m), forceint(n), forceint(k)
+	if k < x || m < x || k-x > n {
+		return d0(logD)
+	}
+	if k == 0 {
+		if x == 0 {
+			return d1(logD)
+		}
+		return d0(logD)
+	}
+	p := k / (m + n)
+	q := (m + n - k) / (m + n)
+	p1 := dbinomRaw(x, m, p, q, logD)
+	p2 := dbinomRaw(k-x, n, p, q, logD)
+	p3 := dbinomRaw(k, m+n, p, q, logD)
+	if logD {
+		return p1 + p2 - p3
+	}
+	return p1 * p2 / p3
+}
+
+// the ratio of P[X <= x] to P[X = x], summed down from x
+func pdhyper(x float64, m float64, n float64, k float64, logP bool) float64 {
+	sum, term := 0., 1.
+	for x > 0 && term >= dblEpsilon*sum {
+		term *= x * (n - k + x) / (k + 1 - x) / (m + 1 - x)
+		sum += term
+		x--
+	}
+	if logP {
+		return math.Log1p(sum)
+	}
+	return 1 + sum
+}
+
+func Phyper(x float64, m float64, n float64, k float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, m, n, k) {
+		return x + m + n + k
+	}
+	x = math.Floor(x + 1e-7)
+	m, n, k = forceint(m), forceint(n), forceint(k)
+	if m < 0 || n < 0 || !isFinite(m+n) || k < 0 || k > m+n {
+		return math.NaN()
+	}
+	if x*(m+n) > k*m {
+		// sum the shorter tail
+		m, n = n, m
+		x = k - x - 1
+		lowerTail = !lowerTail
+	}
+	if x < 0 || x < k-n {
+		return dt0(lowerTail, logP)
+	}
+	if x >= m || x >= k {
+		return dt1(lowerTail, logP)
+	}
+	d := Dhyper(x, m, n, k, logP)
+	if (!logP && d == 0) || (logP && math.IsInf(d, -1)) {
+		return dt0(lowerTail, logP)
+	}
+	pd := pdhyper(x, m, n, k, logP)
+	if logP {
+		return dtLog(d+pd, lowerTail)
+	}
+	return dLval(d*pd, lowerTail)
+}
+
+func Qhyper(p float64, m float64, n float64, k float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, m, n, k) {
+		return p + m + n + k
+	}
+	if !isFinite(p) || !isFinite(m) || !isFinite(n) || !isFinite(k) {
+		return math.NaN()
+	}
+	m, n, k = forceint(m), forceint(n), forceint(k)
+	N := m + n
+	if m < 0 || n < 0 || k < 0 || k > N {
+		return math.NaN()
+	}
+	xstart := math.Max(0, k-n)
+	xend := math.Min(k, m)
+	if v, done := qP01Boundaries(p, xstart, xend, lowerTail, logP); done {
+		return v
+	}
+	xr := xstart
+	xb := k - xr
+	small := N < 1000
+	term := lfastchoose(m, xr) + lfastchoose(n, xb) - lfastchoose(N, k)
+	if small {
+		term = math.Exp(term)
+	}
+	m -= xr
+	n -= xb
+	p = dtQIv(p, lowerTail, logP)
+	p *= 1 - 1000*dblEpsilon
+	sum := term
+	if !small {
+		sum = math.Exp(term)
+	}
+	for sum < p && xr < xend {
+		xr++
+		n++
+		if small {
+			term *= (m / xr) * (xb / n)
+			sum += term
+		} else {
+			term += math.Log(m/xr) + math.Log(xb/n)
+			sum += math.Exp(term)
+		}
+		xb--
+		m--
+	}
+	return xr
+}
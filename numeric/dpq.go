@@ -0,0 +1,236 @@
+package numeric
+
+import (
+	"math"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/stats/html/Distributions.html
+// Densities, distribution functions and quantiles as in R's nmath. Every function takes
+// the flags of R: lowerTail selects P[X <= x] instead of P[X > x], logP gives and takes
+// probabilities on log scale, logD gives the density on log scale. Invalid parameters give NaN.
+// The helpers below are the macros of nmath/dpq.h.
+
+var (
+	posInf = math.Inf(1)
+	negInf = math.Inf(-1)
+)
+
+const (
+	ln2        = math.Ln2
+	lnSqrt2Pi  = 0.918938533204672741780329736406 // log(sqrt(2*pi))
+	ln2Pi      = 1.837877066409345483560659472811 // log(2*pi)
+	oneSqrt2Pi = 0.398942280401432677939946059934 // 1/sqrt(2*pi)
+	dblEpsilon = 2.220446049250313e-16
+	dblMin     = 2.2250738585072014e-308
+)
+
+func isNaN(x ...float64) bool {
+	for _, v := range x {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+// R_D__0 and R_D__1
+func d0(logP bool) float64 {
+	if logP {
+		return negInf
+	}
+	return 0
+}
+
+func d1(logP bool) float64 {
+	if logP {
+		return 0
+	}
+	return 1
+}
+
+// R_DT_0 and R_DT_1
+func dt0(lowerTail bool, logP bool) float64 {
+	if lowerTail {
+		return d0(logP)
+	}
+	return d1(logP)
+}
+
+func dt1(lowerTail bool, logP bool) float64 {
+	if lowerTail {
+		return d1(logP)
+	}
+	return d0(logP)
+}
+
+// R_D_val: a probability on the requested scale
+func dVal(x float64, logP bool) float64 {
+	if logP {
+		return math.Log(x)
+	}
+	return x
+}
+
+// R_D_Clog: the complement on the requested scale
+func dClog(p float64, logP bool) float64 {
+	if logP {
+		return math.Log1p(-p)
+	}
+	return 0.5 - p + 0.5
+}
+
+// R_DT_val
+func dtVal(x float64, lowerTail bool, logP bool) float64 {
+	if lowerTail {
+		return dVal(x, logP)
+	}
+	return dClog(x, logP)
+}
+
+// R_D_exp
+func dExp(x float64, logP bool) float64 {
+	if logP {
+		return x
+	}
+	return math.Exp(x)
+}
+
+// R_D_fexp: exp(x)/sqrt(f) on the requested scale
+func dFexp(f float64, x float64, logP bool) float64 {
+	if logP {
+		return -0.5*math.Log(f) + x
+	}
+	return math.Exp(x) / math.Sqrt(f)
+}
+
+// R_D_Lval and R_D_Cval
+func dLval(p float64, lowerTail bool) float64 {
+	if lowerTail {
+		return p
+	}
+	return 0.5 - p + 0.5
+}
+
+func dCval(p float64, lowerTail bool) float64 {
+	if lowerTail {
+		return 0.5 - p + 0.5
+	}
+	return p
+}
+
+// R_DT_qIv: the lower tail probability on normal scale
+func dtQIv(p float64, lowerTail bool, logP bool) float64 {
+	if logP {
+		if lowerTail {
+			return math.Exp(p)
+		}
+		return -math.Expm1(p)
+	}
+	return dLval(p, lowerTail)
+}
+
+// R_DT_CIv: the upper tail probability on normal scale
+func dtCIv(p float64, lowerTail bool, logP bool) float64 {
+	if logP {
+		if lowerTail {
+			return -math.Expm1(p)
+		}
+		return math.Exp(p)
+	}
+	return dCval(p, lowerTail)
+}
+
+// R_Log1_Exp: log(1 - exp(x)) for x <= 0
+func log1Exp(x float64) float64 {
+	if x > -ln2 {
+		return math.Log(-math.Expm1(x))
+	}
+	return math.Log1p(-math.Exp(x))
+}
+
+// R_DT_Clog: log of the upper tail probability
+func dtClog(p float64, lowerTail bool, logP bool) float64 {
+	if lowerTail {
+		if logP {
+			return log1Exp(p)
+		}
+		return math.Log1p(-p)
+	}
+	if logP {
+		return p
+	}
+	return math.Log(p)
+}
+
+// R_DT_Log: log of the lower tail probability for p on log scale
+func dtLog(p float64, lowerTail bool) float64 {
+	if lowerTail {
+		return p
+	}
+	return log1Exp(p)
+}
+
+// log(1 + exp(x)) without overflow
+func log1pexp(x float64) float64 {
+	if x <= 18 {
+		return math.Log1p(math.Exp(x))
+	}
+	if x > 33.3 {
+		return x
+	}
+	return x + math.Exp(-x)
+}
+
+// R_Q_P01_check
+func qP01Invalid(p float64, logP bool) bool {
+	return (logP && p > 0) || (!logP && (p < 0 || p > 1))
+}
+
+// R_Q_P01_boundaries: the quantile at the bounds of p, or NaN for p out of range
+func qP01Boundaries(p float64, left float64, right float64, lowerTail bool, logP bool) (float64, bool) {
+	if logP {
+		switch {
+		case p > 0:
+			return math.NaN(), true
+		case p == 0:
+			if lowerTail {
+				return right, true
+			}
+			return left, true
+		case math.IsInf(p, -1):
+			if lowerTail {
+				return left, true
+			}
+			return right, true
+		}
+		return 0, false
+	}
+	switch {
+	case p < 0 || p > 1:
+		return math.NaN(), true
+	case p == 0:
+		if lowerTail {
+			return left, true
+		}
+		return right, true
+	case p == 1:
+		if lowerTail {
+			return right, true
+		}
+		return left, true
+	}
+	return 0, false
+}
+
+// R_forceint and R_nonint
+func forceint(x float64) float64 {
+	return math.RoundToEven(x)
+}
+
+func nonint(x float64) bool {
+	return math.Abs(x-forceint(x)) > 1e-7*math.Max(1, math.Abs(x))
+}
@@ -0,0 +1,771 @@
+package numeric
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// Eigenvalues and eigenvectors by the EISPACK algorithms as adapted in JAMA:
+// tridiagonalization and the implicit QL algorithm for symmetric matrices,
+// reduction to Hessenberg form and the shifted QR algorithm otherwise.
+// As in R the values are decreasing, by modulus for general matrices, and
+// the vectors are normalized to unit length. As in LAPACK dgeev complex
+// vectors are rotated to make their largest component real.
+
+// the lower triangle of a is used. The signs of the vectors follow LAPACK dsyevr as called
+// by R: a matrix of order two is diagonalized by the rotation of dlaev2, otherwise the largest
+// component of each eigenvector of the tridiagonal form is positive, as in the MRRR
+// algorithm of dstemr, where it is the twist index.
+func SymmetricEigen(a *Matrix) ([]float64, *Matrix) {
+	n := a.Rows
+	values := make([]float64, n)
+	vectors := NewMatrix(n, n)
+	switch n {
+	case 0:
+		return values, vectors
+	case 1:
+		values[0] = a.At(0, 0)
+		vectors.Set(0, 0, 1)
+		return values, vectors
+	case 2:
+		rt1, rt2, cs, sn := laev2(a.At(0, 0), a.At(1, 0), a.At(1, 1))
+		if rt1 >= rt2 {
+			values[0], values[1] = rt1, rt2
+			vectors.Data = []float64{cs, sn, -sn, cs}
+		} else {
+			values[0], values[1] = rt2, rt1
+			vectors.Data = []float64{-sn, cs, cs, sn}
+		}
+		return values, vectors
+	}
+	A := make([][]float64, n)
+	Q := make([][]float64, n)
+	V := make([][]float64, n)
+	for i := range A {
+		A[i], Q[i], V[i] = make([]float64, n), make([]float64, n), make([]float64, n)
+		for j := 0; j <= i; j++ {
+			A[i][j] = a.At(i, j)
+			A[j][i] = a.At(i, j)
+		}
+		Q[i][i], V[i][i] = 1, 1
+	}
+	d := make([]float64, n)
+	e := make([]float64, n)
+	tridiagonal(A, Q, d, e)
+	tql2(V, d, e)
+	for j := 0; j < n; j++ {
+		r, max := 0, 0.
+		for k := 0; k < n; k++ {
+			// of equal components the last one, as dlar1v takes the last minimum
+			if math.Abs(V[k][j]) >= max*(1-1e-10) {
+				r, max = k, math.Max(max, math.Abs(V[k][j]))
+			}
+		}
+		if V[r][j] < 0 {
+			for k := 0; k < n; k++ {
+				V[k][j] = -V[k][j]
+			}
+		}
+	}
+	// tql2 sorts increasing
+	for j := 0; j < n; j++ {
+		values[j] = d[n-1-j]
+		for i := 0; i < n; i++ {
+			z := 0.
+			for k := 0; k < n; k++ {
+				z += Q[i][k] * V[k][n-1-j]
+			}
+			vectors.Set(i, j, z)
+		}
+	}
+	return values, vectors
+}
+
+// the eigenvalues rt1 and rt2 of [a b; b c], |rt1| >= |rt2|, and the unit eigenvector (cs, sn)
+// of rt1, as computed by LAPACK dlaev2
+func laev2(a float64, b float64, c float64) (float64, float64, float64, float64) {
+	sm, df, tb := a+c, a-c, b+b
+	adf, ab := math.Abs(df), math.Abs(tb)
+	acmx, acmn := c, a
+	if math.Abs(a) > math.Abs(c) {
+		acmx, acmn = a, c
+	}
+	var rt, rt1, rt2 float64
+	switch {
+	case adf > ab:
+		rt = adf * math.Sqrt(1+(ab/adf)*(ab/adf))
+	case adf < ab:
+		rt = ab * math.Sqrt(1+(adf/ab)*(adf/ab))
+	default:
+		rt = ab * math.Sqrt2
+	}
+	sgn1 := 1.
+	switch {
+	case sm < 0:
+		rt1 = (sm - rt) / 2
+		sgn1 = -1
+		rt2 = (acmx/rt1)*acmn - (b/rt1)*b
+	case sm > 0:
+		rt1 = (sm + rt) / 2
+		rt2 = (acmx/rt1)*acmn - (b/rt1)*b
+	default:
+		rt1, rt2 = rt/2, -rt/2
+	}
+	cs, sgn2 := df+rt, 1.
+	if df < 0 {
+		cs, sgn2 = df-rt, -1
+	}
+	var cs1, sn1 float64
+	switch {
+	case math.Abs(cs) > ab:
+		ct := -tb / cs
+		sn1 = 1 / math.Sqrt(1+ct*ct)
+		cs1 = ct * sn1
+	case ab == 0:
+		cs1, sn1 = 1, 0
+	default:
+		tn := -cs / tb
+		cs1 = 1 / math.Sqrt(1+tn*tn)
+		sn1 = tn * cs1
+	}
+	if sgn1 == sgn2 {
+		cs1, sn1 = -sn1, cs1
+	}
+	return rt1, rt2, cs1, sn1
+}
+
+// Householder reduction to tridiagonal form from the lower triangle as LAPACK dsytd2, the
+// reflections are accumulated in Q. d is the diagonal, e[i] the element left of d[i].
+func tridiagonal(A [][]float64, Q [][]float64, d []float64, e []float64) {
+	n := len(d)
+	for i := 0; i < n-1; i++ {
+		// the reflector H = I - tau*v*v' of dlarfg, which annihilates A[i+2:][i]
+		m := n - i - 1
+		v := make([]float64, m)
+		alpha, xnorm := A[i+1][i], 0.
+		for k := 1; k < m; k++ {
+			xnorm = math.Hypot(xnorm, A[i+1+k][i])
+		}
+		e[i+1] = alpha
+		if xnorm != 0 {
+			beta := -math.Copysign(math.Hypot(alpha, xnorm), alpha)
+			tau := (beta - alpha) / beta
+			v[0] = 1
+			for k := 1; k < m; k++ {
+				v[k] = A[i+1+k][i] / (alpha - beta)
+			}
+			e[i+1] = beta
+			// A := H*A*H on the trailing block as a rank two update
+			w := make([]float64, m)
+			vw := 0.
+			for k := 0; k < m; k++ {
+				for l := 0; l < m; l++ {
+					w[k] += tau * A[i+1+k][i+1+l] * v[l]
+				}
+				vw += w[k] * v[k]
+			}
+			for k := range w {
+				w[k] -= tau / 2 * vw * v[k]
+			}
+			for k := 0; k < m; k++ {
+				for l := 0; l < m; l++ {
+					A[i+1+k][i+1+l] -= v[k]*w[l] + w[k]*v[l]
+				}
+			}
+			for _, row := range Q {
+				s := 0.
+				for k := 0; k < m; k++ {
+					s += row[i+1+k] * v[k]
+				}
+				for k := 0; k < m; k++ {
+					row[i+1+k] -= tau * s * v[k]
+				}
+			}
+		}
+		d[i] = A[i][i]
+	}
+	d[n-1] = A[n-1][n-1]
+}
+
+// symmetric tridiagonal QL algorithm
+func tql2(V [][]float64, d []float64, e []float64) {
+	n := len(d)
+	for i := 1; i < n; i++ {
+		e[i-1] = e[i]
+	}
+	e[n-1] = 0
+	f, tst1 := 0., 0.
+	eps := math.Pow(2, -52)
+	for l := 0; l < n; l++ {
+		// find a small subdiagonal element
+		tst1 = math.Max(tst1, math.Abs(d[l])+math.Abs(e[l]))
+		m := l
+		for m < n-1 && math.Abs(e[m]) > eps*tst1 {
+			m++
+		}
+		if m > l {
+			for {
+				// compute the implicit shift
+				g := d[l]
+				p := (d[l+1] - g) / (2 * e[l])
+				r := math.Hypot(p, 1)
+				if p < 0 {
+					r = -r
+				}
+				d[l] = e[l] / (p + r)
+				d[l+1] = e[l] * (p + r)
+				dl1 := d[l+1]
+				h := g - d[l]
+				for i := l + 2; i < n; i++ {
+					d[i] -= h
+				}
+				f += h
+				// implicit QL transformation
+				p = d[m]
+				c, c2, c3 := 1., 1., 1.
+				el1 := e[l+1]
+				s, s2 := 0., 0.
+				for i := m - 1; i >= l; i-- {
+					c3 = c2
+					c2 = c
+					s2 = s
+					g = c * e[i]
+					h = c * p
+					r = math.Hypot(p, e[i])
+					e[i+1] = s * r
+					s = e[i] / r
+					c = p / r
+					p = c*d[i] - s*g
+					d[i+1] = h + s*(c*g+s*d[i])
+					for k := 0; k < n; k++ {
+						h = V[k][i+1]
+						V[k][i+1] = s*V[k][i] + c*h
+						V[k][i] = c*V[k][i] - s*h
+					}
+				}
+				p = -s * s2 * c3 * el1 * e[l] / dl1
+				e[l] = s * p
+				d[l] = c * p
+				if math.Abs(e[l]) <= eps*tst1 {
+					break
+				}
+			}
+		}
+		d[l] += f
+		e[l] = 0
+	}
+	for i := 0; i < n-1; i++ {
+		k, p := i, d[i]
+		for j := i + 1; j < n; j++ {
+			if d[j] < p {
+				k, p = j, d[j]
+			}
+		}
+		if k != i {
+			d[k], d[i] = d[i], p
+			for j := 0; j < n; j++ {
+				V[j][i], V[j][k] = V[j][k], V[j][i]
+			}
+		}
+	}
+}
+
+// the values and the vectors by column of a general matrix
+func Eigen(a *Matrix) ([]complex128, [][]complex128) {
+	n := a.Rows
+	H := make([][]float64, n)
+	V := make([][]float64, n)
+	for i := range H {
+		H[i] = make([]float64, n)
+		V[i] = make([]float64, n)
+		for j := range H[i] {
+			H[i][j] = a.At(i, j)
+		}
+	}
+	d := make([]float64, n)
+	e := make([]float64, n)
+	if n > 0 {
+		orthes(H, V)
+		hqr2(H, V, d, e)
+	}
+	values := make([]complex128, n)
+	vectors := make([][]complex128, n)
+	for j := 0; j < n; j++ {
+		values[j] = complex(d[j], e[j])
+		v := make([]complex128, n)
+		for i := 0; i < n; i++ {
+			switch {
+			case e[j] > 0:
+				v[i] = complex(V[i][j], V[i][j+1])
+			case e[j] < 0:
+				v[i] = complex(V[i][j-1], -V[i][j])
+			default:
+				v[i] = complex(V[i][j], 0)
+			}
+		}
+		norm, largest := 0., 0
+		for i, z := range v {
+			norm = math.Hypot(norm, cmplx.Abs(z))
+			if cmplx.Abs(z) > cmplx.Abs(v[largest]) {
+				largest = i
+			}
+		}
+		if norm > 0 {
+			scale := complex(norm, 0)
+			if e[j] != 0 {
+				scale *= v[largest] / complex(cmplx.Abs(v[largest]), 0)
+			}
+			for i := range v {
+				// adding zero turns negative zeros positive
+				v[i] = v[i]/scale + 0
+			}
+		}
+		vectors[j] = v
+	}
+	order := make([]int, n)
+	for k := range order {
+		order[k] = k
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return cmplx.Abs(values[order[i]]) > cmplx.Abs(values[order[j]])
+	})
+	sortedValues := make([]complex128, n)
+	sortedVectors := make([][]complex128, n)
+	for k, o := range order {
+		sortedValues[k], sortedVectors[k] = values[o], vectors[o]
+	}
+	return sortedValues, sortedVectors
+}
+
+// nonsymmetric reduction to Hessenberg form by orthogonal similarity transformations
+func orthes(H [][]float64, V [][]float64) {
+	n := len(H)
+	low, high := 0, n-1
+	ort := make([]float64, n)
+	for m := low + 1; m <= high-1; m++ {
+		scale := 0.
+		for i := m; i <= high; i++ {
+			scale += math.Abs(H[i][m-1])
+		}
+		if scale == 0 {
+			continue
+		}
+		h := 0.
+		for i := high; i >= m; i-- {
+			ort[i] = H[i][m-1] / scale
+			h += ort[i] * ort[i]
+		}
+		g := math.Sqrt(h)
+		if ort[m] > 0 {
+			g = -g
+		}
+		h -= ort[m] * g
+		ort[m] -= g
+		for j := m; j < n; j++ {
+			f := 0.
+			for i := high; i >= m; i-- {
+				f += ort[i] * H[i][j]
+			}
+			f /= h
+			for i := m; i <= high; i++ {
+				H[i][j] -= f * ort[i]
+			}
+		}
+		for i := 0; i <= high; i++ {
+			f := 0.
+			for j := high; j >= m; j-- {
+				f += ort[j] * H[i][j]
+			}
+			f /= h
+			for j := m; j <= high; j++ {
+				H[i][j] -= f * ort[j]
+			}
+		}
+		ort[m] *= scale
+		H[m][m-1] = scale * g
+	}
+	// accumulate transformations
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			V[i][j] = 0
+		}
+		V[i][i] = 1
+	}
+	for m := high - 1; m >= low+1; m-- {
+		if H[m][m-1] == 0 {
+			continue
+		}
+		for i := m + 1; i <= high; i++ {
+			ort[i] = H[i][m-1]
+		}
+		for j := m; j <= high; j++ {
+			g := 0.
+			for i := m; i <= high; i++ {
+				g += ort[i] * V[i][j]
+			}
+			// double division avoids possible underflow
+			g = (g / ort[m]) / H[m][m-1]
+			for i := m; i <= high; i++ {
+				V[i][j] += g * ort[i]
+			}
+		}
+	}
+}
+
+// complex scalar division
+func cdiv(xr float64, xi float64, yr float64, yi float64) (float64, float64) {
+	if math.Abs(yr) > math.Abs(yi) {
+		r := yi / yr
+		d := yr + r*yi
+		return (xr + r*xi) / d, (xi - r*xr) / d
+	}
+	r := yr / yi
+	d := yi + r*yr
+	return (r*xr + xi) / d, (r*xi - xr) / d
+}
+
+// nonsymmetric reduction from Hessenberg to real Schur form and back substitution for the vectors
+func hqr2(H [][]float64, V [][]float64, d []float64, e []float64) {
+	nn := len(H)
+	n := nn - 1
+	low, high := 0, nn-1
+	eps := math.Pow(2, -52)
+	exshift := 0.
+	var p, q, r, s, z, t, w, x, y float64
+
+	// store roots isolated by balancing and compute the matrix norm
+	norm := 0.
+	for i := 0; i < nn; i++ {
+		if i < low || i > high {
+			d[i] = H[i][i]
+			e[i] = 0
+		}
+		for j := max(i-1, 0); j < nn; j++ {
+			norm += math.Abs(H[i][j])
+		}
+	}
+
+	// outer loop over eigenvalue index
+	iter := 0
+	for n >= low {
+		// look for a single small sub-diagonal element
+		l := n
+		for l > low {
+			s = math.Abs(H[l-1][l-1]) + math.Abs(H[l][l])
+			if s == 0 {
+				s = norm
+			}
+			if math.Abs(H[l][l-1]) < eps*s {
+				break
+			}
+			l--
+		}
+		switch {
+		case l == n: // one root found
+			H[n][n] += exshift
+			d[n] = H[n][n]
+			e[n] = 0
+			n--
+			iter = 0
+		case l == n-1: // two roots found
+			w = H[n][n-1] * H[n-1][n]
+			p = (H[n-1][n-1] - H[n][n]) / 2
+			q = p*p + w
+			z = math.Sqrt(math.Abs(q))
+			H[n][n] += exshift
+			H[n-1][n-1] += exshift
+			x = H[n][n]
+			if q >= 0 { // real pair
+				if p >= 0 {
+					z = p + z
+				} else {
+					z = p - z
+				}
+				d[n-1] = x + z
+				d[n] = d[n-1]
+				if z != 0 {
+					d[n] = x - w/z
+				}
+				e[n-1] = 0
+				e[n] = 0
+				x = H[n][n-1]
+				s = math.Abs(x) + math.Abs(z)
+				p = x / s
+				q = z / s
+				r = math.Sqrt(p*p + q*q)
+				p /= r
+				q /= r
+				// row modification
+				for j := n - 1; j < nn; j++ {
+					z = H[n-1][j]
+					H[n-1][j] = q*z + p*H[n][j]
+					H[n][j] = q*H[n][j] - p*z
+				}
+				// column modification
+				for i := 0; i <= n; i++ {
+					z = H[i][n-1]
+					H[i][n-1] = q*z + p*H[i][n]
+					H[i][n] = q*H[i][n] - p*z
+				}
+				// accumulate transformations
+				for i := low; i <= high; i++ {
+					z = V[i][n-1]
+					V[i][n-1] = q*z + p*V[i][n]
+					V[i][n] = q*V[i][n] - p*z
+				}
+			} else { // complex pair
+				d[n-1] = x + p
+				d[n] = x + p
+				e[n-1] = z
+				e[n] = -z
+			}
+			n -= 2
+			iter = 0
+		default: // no convergence yet
+			x = H[n][n]
+			y, w = 0, 0
+			if l < n {
+				y = H[n-1][n-1]
+				w = H[n][n-1] * H[n-1][n]
+			}
+			// Wilkinson's original ad hoc shift
+			if iter == 10 {
+				exshift += x
+				for i := low; i <= n; i++ {
+					H[i][i] -= x
+				}
+				s = math.Abs(H[n][n-1]) + math.Abs(H[n-1][n-2])
+				x = 0.75 * s
+				y = x
+				w = -0.4375 * s * s
+			}
+			// MATLAB's new ad hoc shift
+			if iter == 30 {
+				s = (y - x) / 2
+				s = s*s + w
+				if s > 0 {
+					s = math.Sqrt(s)
+					if y < x {
+						s = -s
+					}
+					s = x - w/((y-x)/2+s)
+					for i := low; i <= n; i++ {
+						H[i][i] -= s
+					}
+					exshift += s
+					x, y, w = 0.964, 0.964, 0.964
+				}
+			}
+			iter++
+			// look for two consecutive small sub-diagonal elements
+			m := n - 2
+			for m >= l {
+				z = H[m][m]
+				r = x - z
+				s = y - z
+				p = (r*s-w)/H[m+1][m] + H[m][m+1]
+				q = H[m+1][m+1] - z - r - s
+				r = H[m+2][m+1]
+				s = math.Abs(p) + math.Abs(q) + math.Abs(r)
+				p /= s
+				q /= s
+				r /= s
+				if m == l {
+					break
+				}
+				if math.Abs(H[m][m-1])*(math.Abs(q)+math.Abs(r)) <
+					eps*(math.Abs(p)*(math.Abs(H[m-1][m-1])+math.Abs(z)+math.Abs(H[m+1][m+1]))) {
+					break
+				}
+				m--
+			}
+			for i := m + 2; i <= n; i++ {
+				H[i][i-2] = 0
+				if i > m+2 {
+					H[i][i-3] = 0
+				}
+			}
+			// double QR step involving rows l:n and columns m:n
+			for k := m; k <= n-1; k++ {
+				notlast := k != n-1
+				if k != m {
+					p = H[k][k-1]
+					q = H[k+1][k-1]
+					r = 0
+					if notlast {
+						r = H[k+2][k-1]
+					}
+					x = math.Abs(p) + math.Abs(q) + math.Abs(r)
+					if x == 0 {
+						continue
+					}
+					p /= x
+					q /= x
+					r /= x
+				}
+				s = math.Sqrt(p*p + q*q + r*r)
+				if p < 0 {
+					s = -s
+				}
+				if s == 0 {
+					continue
+				}
+				if k != m {
+					H[k][k-1] = -s * x
+				} else if l != m {
+					H[k][k-1] = -H[k][k-1]
+				}
+				p += s
+				x = p / s
+				y = q / s
+				z = r / s
+				q /= p
+				r /= p
+				// row modification
+				for j := k; j < nn; j++ {
+					p = H[k][j] + q*H[k+1][j]
+					if notlast {
+						p += r * H[k+2][j]
+						H[k+2][j] -= p * z
+					}
+					H[k][j] -= p * x
+					H[k+1][j] -= p * y
+				}
+				// column modification
+				for i := 0; i <= min(n, k+3); i++ {
+					p = x*H[i][k] + y*H[i][k+1]
+					if notlast {
+						p += z * H[i][k+2]
+						H[i][k+2] -= p * r
+					}
+					H[i][k] -= p
+					H[i][k+1] -= p * q
+				}
+				// accumulate transformations
+				for i := low; i <= high; i++ {
+					p = x*V[i][k] + y*V[i][k+1]
+					if notlast {
+						p += z * V[i][k+2]
+						V[i][k+2] -= p * r
+					}
+					V[i][k] -= p
+					V[i][k+1] -= p * q
+				}
+			}
+		}
+	}
+
+	// backsubstitute to find vectors of upper triangular form
+	if norm == 0 {
+		return
+	}
+	for n = nn - 1; n >= 0; n-- {
+		p = d[n]
+		q = e[n]
+		if q == 0 { // real vector
+			l := n
+			H[n][n] = 1
+			for i := n - 1; i >= 0; i-- {
+				w = H[i][i] - p
+				r = 0
+				for j := l; j <= n; j++ {
+					r += H[i][j] * H[j][n]
+				}
+				if e[i] < 0 {
+					z = w
+					s = r
+					continue
+				}
+				l = i
+				if e[i] == 0 {
+					if w != 0 {
+						H[i][n] = -r / w
+					} else {
+						H[i][n] = -r / (eps * norm)
+					}
+				} else { // solve real equations
+					x = H[i][i+1]
+					y = H[i+1][i]
+					q = (d[i]-p)*(d[i]-p) + e[i]*e[i]
+					t = (x*s - z*r) / q
+					H[i][n] = t
+					if math.Abs(x) > math.Abs(z) {
+						H[i+1][n] = (-r - w*t) / x
+					} else {
+						H[i+1][n] = (-s - y*t) / z
+					}
+				}
+				// overflow control
+				t = math.Abs(H[i][n])
+				if (eps*t)*t > 1 {
+					for j := i; j <= n; j++ {
+						H[j][n] /= t
+					}
+				}
+			}
+		} else if q < 0 { // complex vector
+			l := n - 1
+			// last vector component imaginary so matrix is triangular
+			if math.Abs(H[n][n-1]) > math.Abs(H[n-1][n]) {
+				H[n-1][n-1] = q / H[n][n-1]
+				H[n-1][n] = -(H[n][n] - p) / H[n][n-1]
+			} else {
+				H[n-1][n-1], H[n-1][n] = cdiv(0, -H[n-1][n], H[n-1][n-1]-p, q)
+			}
+			H[n][n-1] = 0
+			H[n][n] = 1
+			for i := n - 2; i >= 0; i-- {
+				ra, sa := 0., 0.
+				for j := l; j <= n; j++ {
+					ra += H[i][j] * H[j][n-1]
+					sa += H[i][j] * H[j][n]
+				}
+				w = H[i][i] - p
+				if e[i] < 0 {
+					z = w
+					r = ra
+					s = sa
+					continue
+				}
+				l = i
+				if e[i] == 0 {
+					H[i][n-1], H[i][n] = cdiv(-ra, -sa, w, q)
+				} else { // solve complex equations
+					x = H[i][i+1]
+					y = H[i+1][i]
+					vr := (d[i]-p)*(d[i]-p) + e[i]*e[i] - q*q
+					vi := (d[i] - p) * 2 * q
+					if vr == 0 && vi == 0 {
+						vr = eps * norm * (math.Abs(w) + math.Abs(q) + math.Abs(x) + math.Abs(y) + math.Abs(z))
+					}
+					H[i][n-1], H[i][n] = cdiv(x*r-z*ra+q*sa, x*s-z*sa-q*ra, vr, vi)
+					if math.Abs(x) > math.Abs(z)+math.Abs(q) {
+						H[i+1][n-1] = (-ra - w*H[i][n-1] + q*H[i][n]) / x
+						H[i+1][n] = (-sa - w*H[i][n] - q*H[i][n-1]) / x
+					} else {
+						H[i+1][n-1], H[i+1][n] = cdiv(-r-y*H[i][n-1], -s-y*H[i][n], z, q)
+					}
+				}
+				// overflow control
+				t = math.Max(math.Abs(H[i][n-1]), math.Abs(H[i][n]))
+				if (eps*t)*t > 1 {
+					for j := i; j <= n; j++ {
+						H[j][n-1] /= t
+						H[j][n] /= t
+					}
+				}
+			}
+		}
+	}
+	// back transformation to get eigenvectors of the original matrix
+	for j := nn - 1; j >= low; j-- {
+		for i := low; i <= high; i++ {
+			z = 0
+			for k := low; k <= min(j, high); k++ {
+				z += V[i][k] * H[k][j]
+			}
+			V[i][j] = z
+		}
+	}
+}
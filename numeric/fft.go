@@ -0,0 +1,161 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The discrete Fourier transform for any length as R's fft, unnormalized in both
+// directions. The length is split into its prime factors by decimation in time,
+// each factor is transformed directly, except for prime factors above
+// bluesteinPrime, which are computed as a convolution of power of two length
+// (Bluestein's chirp z-transform).
+
+const bluesteinPrime = 64
+
+// FFT returns sum_j z[j] exp(-2 pi i jk/n), with inverse the sum with exp(2 pi i jk/n).
+func FFT(z []complex128, inverse bool) []complex128 {
+	sign := -1.
+	if inverse {
+		sign = 1
+	}
+	r := make([]complex128, len(z))
+	if len(z) > 0 {
+		transform(r, z, 1, len(z), sign)
+	}
+	return r
+}
+
+// FFTArray transforms z with the dimensions dim along each of them, as fft for arrays.
+func FFTArray(z []complex128, dim []int, inverse bool) []complex128 {
+	r := append([]complex128{}, z...)
+	stride := 1
+	for _, n := range dim {
+		line := make([]complex128, n)
+		for start := 0; start < len(r); start++ {
+			if (start/stride)%n != 0 {
+				continue
+			}
+			for k := range line {
+				line[k] = r[start+k*stride]
+			}
+			for k, v := range FFT(line, inverse) {
+				r[start+k*stride] = v
+			}
+		}
+		stride *= n
+	}
+	return r
+}
+
+// the root of unity exp(sign 2 pi i k/n), exact at quarter turns
+func twiddle(k int, n int, sign float64) complex128 {
+	k %= n
+	switch {
+	case k == 0:
+		return 1
+	case 2*k == n:
+		return -1
+	case 4*k == n:
+		return complex(0, sign)
+	case 4*k == 3*n:
+		return complex(0, -sign)
+	}
+	s, c := math.Sincos(2 * math.Pi * float64(k) / float64(n))
+	return complex(c, sign*s)
+}
+
+func smallestFactor(n int) int {
+	for p := 2; p*p <= n; p++ {
+		if n%p == 0 {
+			return p
+		}
+	}
+	return n
+}
+
+// transform writes the transform of in[0], in[stride], ... of length n to out
+func transform(out []complex128, in []complex128, stride int, n int, sign float64) {
+	if n == 1 {
+		out[0] = in[0]
+		return
+	}
+	p := smallestFactor(n)
+	if p == n && p > bluesteinPrime {
+		x := make([]complex128, n)
+		for j := range x {
+			x[j] = in[j*stride]
+		}
+		copy(out, bluestein(x, sign))
+		return
+	}
+	m := n / p
+	// the transforms of the p decimated sequences
+	for r := 0; r < p; r++ {
+		transform(out[r*m:(r+1)*m], in[r*stride:], stride*p, m, sign)
+	}
+	roots := make([]complex128, p)
+	for r := range roots {
+		roots[r] = twiddle(r, p, sign)
+	}
+	t := make([]complex128, p)
+	for k := 0; k < m; k++ {
+		for r := 0; r < p; r++ {
+			t[r] = out[r*m+k] * twiddle(r*k, n, sign)
+		}
+		for q := 0; q < p; q++ {
+			var s complex128
+			for r := 0; r < p; r++ {
+				s += t[r] * roots[(r*q)%p]
+			}
+			out[q*m+k] = s
+		}
+	}
+}
+
+// bluestein transforms by a circular convolution with jk = (j^2 + k^2 - (k-j)^2)/2
+func bluestein(x []complex128, sign float64) []complex128 {
+	n := len(x)
+	m := 1
+	for m < 2*n-1 {
+		m *= 2
+	}
+	// the chirp exp(sign pi i j^2/n)
+	chirp := make([]complex128, n)
+	for j := range chirp {
+		chirp[j] = twiddle(j*j%(2*n), 2*n, sign)
+	}
+	a := make([]complex128, m)
+	b := make([]complex128, m)
+	for j := 0; j < n; j++ {
+		a[j] = x[j] * chirp[j]
+		b[j] = complex(real(chirp[j]), -imag(chirp[j]))
+		if j > 0 {
+			b[m-j] = b[j]
+		}
+	}
+	fa, fb := FFT(a, false), FFT(b, false)
+	for k := range fa {
+		fa[k] *= fb[k]
+	}
+	c := FFT(fa, true)
+	r := make([]complex128, n)
+	for k := range r {
+		r[k] = chirp[k] * c[k] / complex(float64(m), 0)
+	}
+	return r
+}
+
+// Nextn returns the smallest integer not less than n that is a product of factors.
+func Nextn(n int, factors []int) int {
+	for ; ; n++ {
+		k := n
+		for _, f := range factors {
+			for k%f == 0 && k > 1 {
+				k /= f
+			}
+		}
+		if k <= 1 {
+			return n
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package numeric
+
+import (
+	"math"
+)
+
+// One-dimensional minimization and root finding by Brent's methods, as R's
+// Brent_fmin for optimize and R_zeroin2 for uniroot. Golden section steps and
+// bisection safeguard parabolic and inverse quadratic interpolation.
+
+// Fmin returns an approximation of a minimum of f in [ax, bx] within tol.
+func Fmin(ax float64, bx float64, f func(float64) float64, tol float64) float64 {
+	// c is the squared inverse of the golden ratio
+	c := (3. - math.Sqrt(5.)) * .5
+	eps := math.Sqrt(dblEpsilon)
+	a := ax
+	b := bx
+	v := a + c*(b-a)
+	w := v
+	x := v
+	d := 0.
+	e := 0.
+	fx := f(x)
+	fv := fx
+	fw := fx
+	tol3 := tol / 3.
+	for {
+		xm := (a + b) * .5
+		tol1 := eps*math.Abs(x) + tol3
+		t2 := tol1 * 2.
+		if math.Abs(x-xm) <= t2-(b-a)*.5 {
+			break
+		}
+		p := 0.
+		q := 0.
+		r := 0.
+		if math.Abs(e) > tol1 { // fit a parabola
+			r = (x - w) * (fx - fv)
+			q = (x - v) * (fx - fw)
+			p = (x-v)*q - (x-w)*r
+			q = (q - r) * 2.
+			if q > 0. {
+				p = -p
+			} else {
+				q = -q
+			}
+			r = e
+			e = d
+		}
+		if math.Abs(p) >= math.Abs(q*.5*r) || p <= q*(a-x) || p >= q*(b-x) {
+			// a golden section step
+			if x < xm {
+				e = b - x
+			} else {
+				e = a - x
+			}
+			d = c * e
+		} else {
+			// a parabolic interpolation step, f must not be evaluated too close to ax or bx
+			d = p / q
+			u := x + d
+			if u-a < t2 || b-u < t2 {
+				d = tol1
+				if x >= xm {
+					d = -d
+				}
+			}
+		}
+		// f must not be evaluated too close to x
+		var u float64
+		switch {
+		case math.Abs(d) >= tol1:
+			u = x + d
+		case d > 0.:
+			u = x + tol1
+		default:
+			u = x - tol1
+		}
+		fu := f(u)
+		if fu <= fx {
+			if u < x {
+				b = x
+			} else {
+				a = x
+			}
+			v, w, x = w, x, u
+			fv, fw, fx = fw, fx, fu
+		} else {
+			if u < x {
+				a = u
+			} else {
+				b = u
+			}
+			if fu <= fw || w == x {
+				v, fv = w, fw
+				w, fw = u, fu
+			} else if fu <= fv || v == x || v == w {
+				v, fv = u, fu
+			}
+		}
+	}
+	return x
+}
+
+// Zeroin returns a root of f in [ax, bx], where fa and fb are of opposite sign, with the
+// estimated precision and the number of iterations, which are -1 without convergence.
+func Zeroin(ax float64, bx float64, fa float64, fb float64, f func(float64) float64, tol float64, maxit int) (float64, float64, int) {
+	a, b := ax, bx
+	c, fc := a, fa
+	if fa == 0 {
+		return a, 0, 0
+	}
+	if fb == 0 {
+		return b, 0, 0
+	}
+	for it := 1; it <= maxit+1; it++ {
+		prevStep := b - a
+		if math.Abs(fc) < math.Abs(fb) {
+			// swap data for b to be the best approximation
+			a, b, c = b, c, b
+			fa, fb, fc = fb, fc, fb
+		}
+		tolAct := 2*dblEpsilon*math.Abs(b) + tol/2
+		newStep := (c - b) / 2
+		if math.Abs(newStep) <= tolAct || fb == 0 {
+			return b, math.Abs(c - b), it - 1
+		}
+		// interpolation may be tried if the previous step was large enough and in the right direction
+		if math.Abs(prevStep) >= tolAct && math.Abs(fa) > math.Abs(fb) {
+			var p, q float64
+			cb := c - b
+			if a == c { // linear interpolation
+				t1 := fb / fa
+				p = cb * t1
+				q = 1.0 - t1
+			} else { // inverse quadratic interpolation
+				q = fa / fc
+				t1 := fb / fc
+				t2 := fb / fa
+				p = t2 * (cb*q*(q-t1) - (b-a)*(t1-1.0))
+				q = (q - 1.0) * (t1 - 1.0) * (t2 - 1.0)
+			}
+			if p > 0 {
+				q = -q
+			} else {
+				p = -p
+			}
+			if p < (0.75*cb*q-math.Abs(tolAct*q)/2) && p < math.Abs(prevStep*q/2) {
+				newStep = p / q
+			}
+		}
+		if math.Abs(newStep) < tolAct { // a step not less than the tolerance
+			if newStep > 0 {
+				newStep = tolAct
+			} else {
+				newStep = -tolAct
+			}
+		}
+		a, fa = b, fb
+		b += newStep
+		fb = f(b)
+		if (fb > 0 && fc > 0) || (fb < 0 && fc < 0) {
+			// c is kept on the other side of the root
+			c, fc = a, fa
+		}
+	}
+	return b, -1, -1
+}
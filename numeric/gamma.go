@@ -0,0 +1,162 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The gamma and chi-squared distributions. The distribution function sums the
+// series of the lower tail below the mode and evaluates the continued fraction of
+// the upper tail above it, both scaled by the Poisson density on log scale.
+
+func Dgamma(x float64, shape float64, scale float64, logD bool) float64 {
+	if isNaN(x, shape, scale) {
+		return x + shape + scale
+	}
+	if shape < 0 || scale <= 0 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return d0(logD)
+	}
+	if shape == 0 {
+		if x == 0 {
+			return posInf
+		}
+		return d0(logD)
+	}
+	if x == 0 {
+		switch {
+		case shape < 1:
+			return posInf
+		case shape > 1:
+			return d0(logD)
+		case logD:
+			return -math.Log(scale)
+		}
+		return 1 / scale
+	}
+	if shape < 1 {
+		pr := dpoisRaw(shape, x/scale, logD)
+		if logD {
+			if isFinite(shape / x) {
+				return pr + math.Log(shape/x)
+			}
+			return pr + math.Log(shape) - math.Log(x)
+		}
+		return pr * shape / x
+	}
+	pr := dpoisRaw(shape-1, x/scale, logD)
+	if logD {
+		return pr - math.Log(scale)
+	}
+	return pr / scale
+}
+
+func Pgamma(x float64, shape float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, shape, scale) {
+		return x + shape + scale
+	}
+	if shape < 0 || scale <= 0 {
+		return math.NaN()
+	}
+	x /= scale
+	if math.IsNaN(x) {
+		return x
+	}
+	if shape == 0 {
+		if x <= 0 {
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	return pgammaRaw(x, shape, lowerTail, logP)
+}
+
+func pgammaRaw(x float64, a float64, lowerTail bool, logP bool) float64 {
+	const maxit = 100000000
+	if x <= 0 {
+		return dt0(lowerTail, logP)
+	}
+	if math.IsInf(x, 1) {
+		return dt1(lowerTail, logP)
+	}
+	if math.IsInf(a, 1) {
+		return dt0(lowerTail, logP)
+	}
+	var lp float64 // log of the tail computed directly
+	lower := x < a+1
+	if lower {
+		sum, term := 1., 1.
+		for n := 1.; n < maxit; n++ {
+			term *= x / (a + n)
+			sum += term
+			if term < sum*dblEpsilon {
+				break
+			}
+		}
+		lp = dpoisRaw(a, x, true) + math.Log(sum)
+	} else {
+		// modified Lentz's method for the continued fraction of the upper tail
+		const tiny = 1e-300
+		b := x + 1 - a
+		c := 1 / tiny
+		d := 1 / b
+		h := d
+		for i := 1.; i < maxit; i++ {
+			an := -i * (i - a)
+			b += 2
+			d = an*d + b
+			if math.Abs(d) < tiny {
+				d = tiny
+			}
+			c = b + an/c
+			if math.Abs(c) < tiny {
+				c = tiny
+			}
+			d = 1 / d
+			del := d * c
+			h *= del
+			if math.Abs(del-1) < dblEpsilon {
+				break
+			}
+		}
+		lp = math.Log(a) + dpoisRaw(a, x, true) + math.Log(h)
+	}
+	if lower != lowerTail {
+		if logP {
+			return log1Exp(lp)
+		}
+		return -math.Expm1(lp)
+	}
+	return dExp(lp, logP)
+}
+
+func Qgamma(p float64, shape float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, shape, scale) {
+		return p + shape + scale
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	if shape < 0 || scale <= 0 {
+		return math.NaN()
+	}
+	if shape == 0 {
+		return 0
+	}
+	return continuousQuantile(p, 0, posInf, lowerTail, logP, func(x float64, lowerTail bool, logP bool) float64 {
+		return Pgamma(x, shape, scale, lowerTail, logP)
+	})
+}
+
+func Dchisq(x float64, df float64, logD bool) float64 {
+	return Dgamma(x, df/2, 2, logD)
+}
+
+func Pchisq(x float64, df float64, lowerTail bool, logP bool) float64 {
+	return Pgamma(x, df/2, 2, lowerTail, logP)
+}
+
+func Qchisq(p float64, df float64, lowerTail bool, logP bool) float64 {
+	return Qgamma(p, df/2, 2, lowerTail, logP)
+}
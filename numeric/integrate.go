@@ -0,0 +1,637 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Adaptive quadrature as QUADPACK's dqags and dqagi used by R's integrate. The
+// interval with the largest error estimate is bisected, each part integrated by
+// the 21-point Gauss-Kronrod rule, or by the 15-point rule after transforming an
+// infinite range to (0, 1], and the sequence of approximations is extrapolated by
+// Wynn's epsilon algorithm.
+
+// Integrand evaluates a function at all points of x at once, as R's integrate does.
+type Integrand func(x []float64) []float64
+
+// the codes of QuadResult.Ier
+const (
+	QuadOK = iota
+	QuadMaxSubdivisions
+	QuadRoundoff
+	QuadBadIntegrand
+	QuadRoundoffExtrapolation
+	QuadDivergent
+	QuadInvalid
+)
+
+type QuadResult struct {
+	Value        float64
+	AbsError     float64
+	Subdivisions int
+	Neval        int
+	Ier          int
+}
+
+const (
+	uflow = 2.2250738585072014e-308 // d1mach(1)
+	oflow = math.MaxFloat64         // d1mach(2)
+)
+
+// the abscissae and weights of the 21-point Kronrod rule and the 10-point Gauss rule
+var xgk21 = []float64{
+	0.995657163025808080735527280689003,
+	0.973906528517171720077964012084452,
+	0.930157491355708226001207180059508,
+	0.865063366688984510732096688423493,
+	0.780817726586416897063717578345042,
+	0.679409568299024406234327365114874,
+	0.562757134668604683339000099272694,
+	0.433395394129247190799265943165784,
+	0.294392862701460198131126603103866,
+	0.148874338981631210884826001129720,
+	0.000000000000000000000000000000000}
+
+var wgk21 = []float64{
+	0.011694638867371874278064396062192,
+	0.032558162307964727478818972459390,
+	0.054755896574351996031381300244580,
+	0.075039674810919952767043140916190,
+	0.093125454583697605535065465083366,
+	0.109387158802297641899210590325805,
+	0.123491976262065851077208067502806,
+	0.134709217311473325928054001771707,
+	0.142775938577060080797094273138717,
+	0.147739104901338491374841515972068,
+	0.149445554002916905664936468389821}
+
+var wg10 = []float64{
+	0.066671344308688137593568809893332,
+	0.149451349150580593145776339657697,
+	0.219086362515982043995534934228163,
+	0.269266719309996355091226921569469,
+	0.295524224714752870173892994651338}
+
+// the abscissae and weights of the 15-point Kronrod rule and the 7-point Gauss rule
+var xgk15 = []float64{
+	0.991455371120812639206854697526329,
+	0.949107912342758524526189684047851,
+	0.864864423359769072789712788640926,
+	0.741531185599394439863864773280788,
+	0.586087235467691130294144845693013,
+	0.405845151377397166906606412076961,
+	0.207784955007898467600689403773245,
+	0.000000000000000000000000000000000}
+
+var wgk15 = []float64{
+	0.022935322010529224963732008058970,
+	0.063092092629978553290700663189204,
+	0.104790010322250183839876322541518,
+	0.140653259715525918745189590510238,
+	0.169004726639267902826583426598550,
+	0.190350578064785409913256402421014,
+	0.204432940075298892414161999234649,
+	0.209482141084727828012999174891714}
+
+var wg7 = []float64{
+	0.0, 0.129484966168869693270611432679082,
+	0.0, 0.279705391489276667901467771423780,
+	0.0, 0.381830050505118944950369775488975,
+	0.0, 0.417959183673469387755102040816327}
+
+// a quadrature rule on [a, b] returning the integral, its error estimate and the
+// integrals of |f| and of |f - mean|
+type quadRule func(a float64, b float64) (float64, float64, float64, float64)
+
+// the error estimate of the difference of Kronrod and Gauss rules as QUADPACK scales it
+func quadError(resk float64, resg float64, hlgth float64, resabs float64, resasc float64) float64 {
+	abserr := math.Abs((resk - resg) * hlgth)
+	if resasc != 0 && abserr != 0 {
+		abserr = resasc * math.Min(1, math.Pow(200*abserr/resasc, 1.5))
+	}
+	if resabs > uflow/(50*dblEpsilon) {
+		abserr = math.Max(dblEpsilon*50*resabs, abserr)
+	}
+	return abserr
+}
+
+// dqk21
+func qk21(f Integrand) quadRule {
+	return func(a float64, b float64) (float64, float64, float64, float64) {
+		centr := 0.5 * (a + b)
+		hlgth := 0.5 * (b - a)
+		dhlgth := math.Abs(hlgth)
+		x := make([]float64, 21)
+		x[0] = centr
+		for j := 1; j <= 5; j++ {
+			absc := hlgth * xgk21[2*j-1]
+			x[2*j-1] = centr - absc
+			x[2*j] = centr + absc
+		}
+		for j := 1; j <= 5; j++ {
+			absc := hlgth * xgk21[2*j-2]
+			x[2*j+9] = centr - absc
+			x[2*j+10] = centr + absc
+		}
+		fv := f(x)
+		fc := fv[0]
+		resg := 0.
+		resk := wgk21[10] * fc
+		resabs := math.Abs(resk)
+		fv1 := make([]float64, 10)
+		fv2 := make([]float64, 10)
+		for j := 1; j <= 5; j++ {
+			jtw := 2 * j
+			fval1, fval2 := fv[2*j-1], fv[2*j]
+			fv1[jtw-1], fv2[jtw-1] = fval1, fval2
+			fsum := fval1 + fval2
+			resg += wg10[j-1] * fsum
+			resk += wgk21[jtw-1] * fsum
+			resabs += wgk21[jtw-1] * (math.Abs(fval1) + math.Abs(fval2))
+		}
+		for j := 1; j <= 5; j++ {
+			jtwm1 := 2*j - 1
+			fval1, fval2 := fv[2*j+9], fv[2*j+10]
+			fv1[jtwm1-1], fv2[jtwm1-1] = fval1, fval2
+			fsum := fval1 + fval2
+			resk += wgk21[jtwm1-1] * fsum
+			resabs += wgk21[jtwm1-1] * (math.Abs(fval1) + math.Abs(fval2))
+		}
+		reskh := resk * 0.5
+		resasc := wgk21[10] * math.Abs(fc-reskh)
+		for j := 0; j < 10; j++ {
+			resasc += wgk21[j] * (math.Abs(fv1[j]-reskh) + math.Abs(fv2[j]-reskh))
+		}
+		resabs *= dhlgth
+		resasc *= dhlgth
+		return resk * hlgth, quadError(resk, resg, hlgth, resabs, resasc), resabs, resasc
+	}
+}
+
+// dqk15i on the transformed range, inf is 1 for (bound, Inf), -1 for (-Inf, bound) and 2 for both
+func qk15i(f Integrand, boun float64, inf int) quadRule {
+	dinf := float64(inf)
+	if inf > 1 {
+		dinf = 1
+	}
+	return func(a float64, b float64) (float64, float64, float64, float64) {
+		centr := 0.5 * (a + b)
+		hlgth := 0.5 * (b - a)
+		t := make([]float64, 15) // the points in (0, 1]
+		t[0] = centr
+		for j := 0; j < 7; j++ {
+			absc := hlgth * xgk15[j]
+			t[2*j+1] = centr - absc
+			t[2*j+2] = centr + absc
+		}
+		x := make([]float64, 15, 30)
+		for k, tk := range t {
+			x[k] = boun + dinf*(1-tk)/tk
+		}
+		if inf == 2 {
+			for k := 0; k < 15; k++ {
+				x = append(x, -x[k])
+			}
+		}
+		fx := f(x)
+		fv := make([]float64, 15)
+		for k, tk := range t {
+			fv[k] = fx[k]
+			if inf == 2 {
+				fv[k] += fx[k+15]
+			}
+			fv[k] = (fv[k] / tk) / tk
+		}
+		fc := fv[0]
+		resg := wg7[7] * fc
+		resk := wgk15[7] * fc
+		resabs := math.Abs(resk)
+		for j := 0; j < 7; j++ {
+			fval1, fval2 := fv[2*j+1], fv[2*j+2]
+			fsum := fval1 + fval2
+			resg += wg7[j] * fsum
+			resk += wgk15[j] * fsum
+			resabs += wgk15[j] * (math.Abs(fval1) + math.Abs(fval2))
+		}
+		reskh := resk * 0.5
+		resasc := wgk15[7] * math.Abs(fc-reskh)
+		for j := 0; j < 7; j++ {
+			resasc += wgk15[j] * (math.Abs(fv[2*j+1]-reskh) + math.Abs(fv[2*j+2]-reskh))
+		}
+		resabs *= hlgth
+		resasc *= hlgth
+		return resk * hlgth, quadError(resk, resg, hlgth, resabs, resasc), resabs, resasc
+	}
+}
+
+// Dqags integrates f over the finite interval [a, b].
+func Dqags(f Integrand, a float64, b float64, epsabs float64, epsrel float64, limit int) QuadResult {
+	r := dqagse(qk21(f), a, b, epsabs, epsrel, limit)
+	r.Neval = 42*r.Subdivisions - 21
+	return r
+}
+
+// Dqagi integrates f over an infinite range, see qk15i for inf.
+func Dqagi(f Integrand, bound float64, inf int, epsabs float64, epsrel float64, limit int) QuadResult {
+	if inf == 2 {
+		bound = 0
+	}
+	r := dqagse(qk15i(f, bound, inf), 0, 1, epsabs, epsrel, limit)
+	r.Neval = 30*r.Subdivisions - 15
+	if inf == 2 {
+		r.Neval *= 2
+	}
+	return r
+}
+
+// the common part of dqagse and dqagie
+func dqagse(rule quadRule, a float64, b float64, epsabs float64, epsrel float64, limit int) QuadResult {
+	epmach := dblEpsilon
+	if limit < 1 || epsabs <= 0 && epsrel < math.Max(50*epmach, 0.5e-28) {
+		return QuadResult{Ier: QuadInvalid}
+	}
+	alist := make([]float64, limit+1)
+	blist := make([]float64, limit+1)
+	rlist := make([]float64, limit+1)
+	elist := make([]float64, limit+1)
+	iord := make([]int, limit+1)
+	rlist2 := make([]float64, 53)
+	res3la := make([]float64, 4)
+	alist[1], blist[1] = a, b
+	ier := QuadOK
+	ierro := 0
+	result, abserr, defabs, resabs := rule(a, b)
+	dres := math.Abs(result)
+	errbnd := math.Max(epsabs, epsrel*dres)
+	last := 1
+	rlist[1] = result
+	elist[1] = abserr
+	iord[1] = 1
+	if abserr <= 100*epmach*defabs && abserr > errbnd {
+		ier = QuadRoundoff
+	}
+	if limit == 1 {
+		ier = QuadMaxSubdivisions
+	}
+	if ier != QuadOK || (abserr <= errbnd && abserr != resabs) || abserr == 0 {
+		return QuadResult{Value: result, AbsError: abserr, Subdivisions: last, Ier: ier}
+	}
+	rlist2[1] = result
+	errmax := abserr
+	maxerr := 1
+	area := result
+	errsum := abserr
+	abserr = oflow
+	nrmax := 1
+	nres := 0
+	numrl2 := 2
+	ktmin := 0
+	extrap := false
+	noext := false
+	iroff1, iroff2, iroff3 := 0, 0, 0
+	ksgn := -1
+	if dres >= (1-50*epmach)*defabs {
+		ksgn = 1
+	}
+	var small, erlarg, ertest, correc float64
+	for last = 2; last <= limit; last++ {
+		// bisect the subinterval with the largest error estimate
+		a1 := alist[maxerr]
+		b1 := 0.5 * (alist[maxerr] + blist[maxerr])
+		a2 := b1
+		b2 := blist[maxerr]
+		erlast := errmax
+		area1, error1, _, defab1 := rule(a1, b1)
+		area2, error2, _, defab2 := rule(a2, b2)
+		area12 := area1 + area2
+		erro12 := error1 + error2
+		errsum = errsum + erro12 - errmax
+		area = area + area12 - rlist[maxerr]
+		if defab1 != error1 && defab2 != error2 {
+			if math.Abs(rlist[maxerr]-area12) <= 1e-5*math.Abs(area12) && erro12 >= .99*errmax {
+				if extrap {
+					iroff2++
+				} else {
+					iroff1++
+				}
+			}
+			if last > 10 && erro12 > errmax {
+				iroff3++
+			}
+		}
+		rlist[maxerr] = area1
+		rlist[last] = area2
+		errbnd = math.Max(epsabs, epsrel*math.Abs(area))
+		// test for roundoff error and eventually set the error flag
+		if iroff1+iroff2 >= 10 || iroff3 >= 20 {
+			ier = QuadRoundoff
+		}
+		if iroff2 >= 5 {
+			ierro = 3
+		}
+		if last == limit {
+			ier = QuadMaxSubdivisions
+		}
+		// bad integrand behaviour at a point of the integration range
+		if math.Max(math.Abs(a1), math.Abs(b2)) <= (1+100*epmach)*(math.Abs(a2)+1000*uflow) {
+			ier = QuadBadIntegrand
+		}
+		if error2 > error1 {
+			alist[maxerr] = a2
+			alist[last] = a1
+			blist[last] = b1
+			rlist[maxerr] = area2
+			rlist[last] = area1
+			elist[maxerr] = error2
+			elist[last] = error1
+		} else {
+			alist[last] = a2
+			blist[maxerr] = b1
+			blist[last] = b2
+			elist[maxerr] = error1
+			elist[last] = error2
+		}
+		maxerr, errmax, nrmax = dqpsrt(limit, last, maxerr, elist, iord, nrmax)
+		if errsum <= errbnd {
+			return sumResult(rlist, last, errsum, ier)
+		}
+		if ier != QuadOK {
+			break
+		}
+		if last == 2 {
+			small = math.Abs(b-a) * 0.375
+			erlarg = errsum
+			ertest = errbnd
+			rlist2[2] = area
+			continue
+		}
+		if noext {
+			continue
+		}
+		erlarg -= erlast
+		if math.Abs(b1-a1) > small {
+			erlarg += erro12
+		}
+		if !extrap {
+			// test whether the interval to be bisected next is the smallest interval
+			if math.Abs(blist[maxerr]-alist[maxerr]) > small {
+				continue
+			}
+			extrap = true
+			nrmax = 2
+		}
+		if ierro != 3 && erlarg > ertest {
+			// the smallest interval has the largest error, before bisecting decrease
+			// the sum of the errors over the larger intervals and extrapolate later
+			id := nrmax
+			jupbnd := last
+			if last > 2+limit/2 {
+				jupbnd = limit + 3 - last
+			}
+			large := false
+			for k := id; k <= jupbnd; k++ {
+				maxerr = iord[nrmax]
+				errmax = elist[maxerr]
+				if math.Abs(blist[maxerr]-alist[maxerr]) > small {
+					large = true
+					break
+				}
+				nrmax++
+			}
+			if large {
+				continue
+			}
+		}
+		// perform extrapolation
+		numrl2++
+		rlist2[numrl2] = area
+		var reseps, abseps float64
+		reseps, abseps, numrl2, nres = dqelg(numrl2, rlist2, res3la, nres)
+		ktmin++
+		if ktmin > 5 && abserr < 1e-3*errsum {
+			ier = QuadDivergent
+		}
+		if abseps < abserr {
+			ktmin = 0
+			abserr = abseps
+			result = reseps
+			correc = erlarg
+			ertest = math.Max(epsabs, epsrel*math.Abs(reseps))
+			if abserr <= ertest {
+				break
+			}
+		}
+		// prepare bisection of the smallest interval
+		if numrl2 == 1 {
+			noext = true
+		}
+		if ier == QuadDivergent {
+			break
+		}
+		maxerr = iord[1]
+		errmax = elist[maxerr]
+		nrmax = 1
+		extrap = false
+		small *= 0.5
+		erlarg = errsum
+	}
+	if last > limit {
+		last = limit
+	}
+	// set the final result and error estimate
+	if abserr == oflow {
+		return sumResult(rlist, last, errsum, ier)
+	}
+	if ier+ierro != 0 {
+		if ierro == 3 {
+			abserr += correc
+		}
+		if ier == QuadOK {
+			ier = QuadBadIntegrand
+		}
+		if result != 0 && area != 0 {
+			if abserr/math.Abs(result) > errsum/math.Abs(area) {
+				return sumResult(rlist, last, errsum, ier)
+			}
+		} else if abserr > errsum {
+			return sumResult(rlist, last, errsum, ier)
+		} else if area == 0 {
+			return finalResult(result, abserr, last, ier)
+		}
+	}
+	// test on divergence
+	if ksgn == -1 && math.Max(math.Abs(result), math.Abs(area)) <= defabs*0.01 {
+		return finalResult(result, abserr, last, ier)
+	}
+	if 0.01 > result/area || result/area > 100 || errsum > math.Abs(area) {
+		ier = QuadInvalid
+	}
+	return finalResult(result, abserr, last, ier)
+}
+
+func finalResult(result float64, abserr float64, last int, ier int) QuadResult {
+	if ier > QuadRoundoff {
+		ier--
+	}
+	return QuadResult{Value: result, AbsError: abserr, Subdivisions: last, Ier: ier}
+}
+
+// the sum of the integrals over all subintervals
+func sumResult(rlist []float64, last int, errsum float64, ier int) QuadResult {
+	result := 0.
+	for k := 1; k <= last; k++ {
+		result += rlist[k]
+	}
+	return finalResult(result, errsum, last, ier)
+}
+
+// dqpsrt maintains the descending ordering of the error estimates in iord and returns the
+// subinterval to be bisected next, its error and the position in iord
+func dqpsrt(limit int, last int, maxerr int, elist []float64, iord []int, nrmax int) (int, float64, int) {
+	if last <= 2 {
+		iord[1] = 1
+		iord[2] = 2
+	} else {
+		errmax := elist[maxerr]
+		// the error of the interval bisected last may be smaller than those of its successors
+		ido := nrmax - 1
+		for i := 1; i <= ido; i++ {
+			isucc := iord[nrmax-1]
+			if errmax <= elist[isucc] {
+				break
+			}
+			iord[nrmax] = isucc
+			nrmax--
+		}
+		jupbn := last
+		if last > limit/2+2 {
+			jupbn = limit + 3 - last
+		}
+		errmin := elist[last]
+		jbnd := jupbn - 1
+		ibeg := nrmax + 1
+		i := ibeg
+		for ; i <= jbnd; i++ {
+			isucc := iord[i]
+			if errmax >= elist[isucc] {
+				break
+			}
+			iord[i-1] = isucc
+		}
+		if i > jbnd {
+			iord[jbnd] = maxerr
+			iord[jupbn] = last
+		} else {
+			// insert errmin by traversing the list bottom-up
+			iord[i-1] = maxerr
+			k := jbnd
+			j := i
+			for ; j <= jbnd; j++ {
+				isucc := iord[k]
+				if errmin < elist[isucc] {
+					break
+				}
+				iord[k+1] = isucc
+				k--
+			}
+			if j > jbnd {
+				iord[i] = last
+			} else {
+				iord[k+1] = last
+			}
+		}
+	}
+	maxerr = iord[nrmax]
+	return maxerr, elist[maxerr], nrmax
+}
+
+// dqelg is Wynn's epsilon algorithm on the n elements of epstab, returning the
+// extrapolated value, its error and the new n and nres
+func dqelg(n int, epstab []float64, res3la []float64, nres int) (float64, float64, int, int) {
+	epmach := dblEpsilon
+	nres++
+	abserr := oflow
+	result := epstab[n]
+	if n < 3 {
+		return result, math.Max(abserr, 5*epmach*math.Abs(result)), n, nres
+	}
+	const limexp = 50
+	epstab[n+2] = epstab[n]
+	newelm := (n - 1) / 2
+	epstab[n] = oflow
+	num := n
+	k1 := n
+	for i := 1; i <= newelm; i++ {
+		k2 := k1 - 1
+		k3 := k1 - 2
+		res := epstab[k1+2]
+		e0 := epstab[k3]
+		e1 := epstab[k2]
+		e2 := res
+		e1abs := math.Abs(e1)
+		delta2 := e2 - e1
+		err2 := math.Abs(delta2)
+		tol2 := math.Max(math.Abs(e2), e1abs) * epmach
+		delta3 := e1 - e0
+		err3 := math.Abs(delta3)
+		tol3 := math.Max(e1abs, math.Abs(e0)) * epmach
+		if err2 <= tol2 && err3 <= tol3 {
+			// e0, e1 and e2 are equal to within machine accuracy, convergence is assumed
+			return res, math.Max(err2+err3, 5*epmach*math.Abs(res)), n, nres
+		}
+		e3 := epstab[k1]
+		epstab[k1] = e1
+		delta1 := e1 - e3
+		err1 := math.Abs(delta1)
+		tol1 := math.Max(e1abs, math.Abs(e3)) * epmach
+		// two elements are very close to each other, omit a part of the table
+		if err1 <= tol1 || err2 <= tol2 || err3 <= tol3 {
+			n = i + i - 1
+			break
+		}
+		ss := 1/delta1 + 1/delta2 - 1/delta3
+		epsinf := math.Abs(ss * e1)
+		if epsinf <= 1e-4 {
+			n = i + i - 1
+			break
+		}
+		res = e1 + 1/ss
+		epstab[k1] = res
+		k1 -= 2
+		e := err2 + math.Abs(res-e2) + err3
+		if e <= abserr {
+			abserr = e
+			result = res
+		}
+	}
+	// shift the table
+	if n == limexp {
+		n = 2*(limexp/2) - 1
+	}
+	ib := 1
+	if (num/2)*2 == num {
+		ib = 2
+	}
+	ie := newelm + 1
+	for i := 1; i <= ie; i++ {
+		ib2 := ib + 2
+		epstab[ib] = epstab[ib2]
+		ib = ib2
+	}
+	if num != n {
+		indx := num - n + 1
+		for i := 1; i <= n; i++ {
+			epstab[i] = epstab[indx]
+			indx++
+		}
+	}
+	if nres < 4 {
+		res3la[nres] = result
+		abserr = oflow
+	} else {
+		abserr = math.Abs(result-res3la[3]) + math.Abs(result-res3la[2]) + math.Abs(result-res3la[1])
+		res3la[1] = res3la[2]
+		res3la[2] = res3la[3]
+		res3la[3] = result
+	}
+	return result, math.Max(abserr, 5*epmach*math.Abs(result)), n, nres
+}
@@ -0,0 +1,465 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Interpolation of sorted points with unique x as R's approx.c and splines.c.
+// Cubic splines are kept as coefficients of y + b dx + c dx^2 + d dx^3 for each
+// knot, monotone Hermite splines (Fritsch and Carlson) by their slopes.
+
+// Approx interpolates at v linearly or as step function with the weight f of the
+// right value, ylow and yhigh are given outside of the range of x.
+func Approx(v float64, x []float64, y []float64, constant bool, ylow float64, yhigh float64, f float64) float64 {
+	n := len(x)
+	if math.IsNaN(v) {
+		return v
+	}
+	if n == 0 {
+		return math.NaN()
+	}
+	i, j := 0, n-1
+	if v < x[i] {
+		return ylow
+	}
+	if v > x[j] {
+		return yhigh
+	}
+	for i < j-1 { // x[i] <= v <= x[j]
+		ij := (i + j) / 2
+		if v < x[ij] {
+			j = ij
+		} else {
+			i = ij
+		}
+	}
+	switch {
+	case v == x[j]:
+		return y[j]
+	case v == x[i]:
+		return y[i]
+	case !constant:
+		return y[i] + (y[j]-y[i])*((v-x[i])/(x[j]-x[i]))
+	}
+	r := 0.
+	if f1 := 1 - f; f1 != 0 {
+		r += y[i] * f1
+	}
+	if f != 0 {
+		r += y[j] * f
+	}
+	return r
+}
+
+// the methods of cubic splines, in the order of R's SplineCoef
+const (
+	SplinePeriodic = 1 + iota
+	SplineNatural
+	SplineFmm
+)
+
+type Spline struct {
+	Method  int
+	X, Y    []float64
+	B, C, D []float64
+}
+
+// NewSpline computes the coefficients of the cubic spline through x and y, with
+// periodic end conditions, natural ones or those of Forsythe, Malcolm and Moler.
+func NewSpline(method int, x []float64, y []float64) *Spline {
+	n := len(x)
+	s := &Spline{Method: method, X: x, Y: y, B: make([]float64, n), C: make([]float64, n), D: make([]float64, n)}
+	switch method {
+	case SplinePeriodic:
+		s.periodic()
+	case SplineNatural:
+		s.natural()
+	default:
+		s.fmm()
+	}
+	return s
+}
+
+// the straight line through two points
+func (s *Spline) line() bool {
+	if len(s.X) >= 3 {
+		return false
+	}
+	if len(s.X) == 2 {
+		s.B[0] = (s.Y[1] - s.Y[0]) / (s.X[1] - s.X[0])
+		s.B[1] = s.B[0]
+	}
+	return true
+}
+
+func (s *Spline) natural() {
+	if s.line() {
+		return
+	}
+	n := len(s.X)
+	// the arrays are used from 1 as in the C code
+	x, y := append([]float64{0}, s.X...), append([]float64{0}, s.Y...)
+	b, c, d := make([]float64, n+1), make([]float64, n+1), make([]float64, n+1)
+	nm1 := n - 1
+	// the tridiagonal system with the diagonal b, the offdiagonal d and the right hand side c
+	d[1] = x[2] - x[1]
+	c[2] = (y[2] - y[1]) / d[1]
+	for i := 2; i < n; i++ {
+		d[i] = x[i+1] - x[i]
+		b[i] = 2 * (d[i-1] + d[i])
+		c[i+1] = (y[i+1] - y[i]) / d[i]
+		c[i] = c[i+1] - c[i]
+	}
+	for i := 3; i < n; i++ {
+		t := d[i-1] / b[i-1]
+		b[i] = b[i] - t*d[i-1]
+		c[i] = c[i] - t*c[i-1]
+	}
+	c[nm1] = c[nm1] / b[nm1]
+	for i := n - 2; i > 1; i-- {
+		c[i] = (c[i] - d[i]*c[i+1]) / b[i]
+	}
+	c[1], c[n] = 0, 0
+	b[1] = (y[2]-y[1])/d[1] - d[1]*c[2]
+	d[1] = c[2] / d[1]
+	b[n] = (y[n]-y[nm1])/d[nm1] + d[nm1]*c[nm1]
+	for i := 2; i < n; i++ {
+		b[i] = (y[i+1]-y[i])/d[i] - d[i]*(c[i+1]+2*c[i])
+		d[i] = (c[i+1] - c[i]) / d[i]
+		c[i] = 3 * c[i]
+	}
+	c[n], d[n] = 0, 0
+	copy(s.B, b[1:])
+	copy(s.C, c[1:])
+	copy(s.D, d[1:])
+}
+
+func (s *Spline) fmm() {
+	if s.line() {
+		return
+	}
+	n := len(s.X)
+	x, y := append([]float64{0}, s.X...), append([]float64{0}, s.Y...)
+	b, c, d := make([]float64, n+1), make([]float64, n+1), make([]float64, n+1)
+	nm1 := n - 1
+	d[1] = x[2] - x[1]
+	c[2] = (y[2] - y[1]) / d[1]
+	for i := 2; i < n; i++ {
+		d[i] = x[i+1] - x[i]
+		b[i] = 2 * (d[i-1] + d[i])
+		c[i+1] = (y[i+1] - y[i]) / d[i]
+		c[i] = c[i+1] - c[i]
+	}
+	// the third derivatives at the ends from divided differences
+	b[1] = -d[1]
+	b[n] = -d[nm1]
+	c[1], c[n] = 0, 0
+	if n > 3 {
+		c[1] = c[3]/(x[4]-x[2]) - c[2]/(x[3]-x[1])
+		c[n] = c[nm1]/(x[n]-x[n-2]) - c[n-2]/(x[nm1]-x[n-3])
+		c[1] = c[1] * d[1] * d[1] / (x[4] - x[1])
+		c[n] = -c[n] * d[nm1] * d[nm1] / (x[n] - x[n-3])
+	}
+	for i := 2; i <= n; i++ {
+		t := d[i-1] / b[i-1]
+		b[i] = b[i] - t*d[i-1]
+		c[i] = c[i] - t*c[i-1]
+	}
+	c[n] = c[n] / b[n]
+	for i := nm1; i >= 1; i-- {
+		c[i] = (c[i] - d[i]*c[i+1]) / b[i]
+	}
+	b[n] = (y[n]-y[n-1])/d[n-1] + d[n-1]*(c[n-1]+2*c[n])
+	for i := 1; i <= nm1; i++ {
+		b[i] = (y[i+1]-y[i])/d[i] - d[i]*(c[i+1]+2*c[i])
+		d[i] = (c[i+1] - c[i]) / d[i]
+		c[i] = 3 * c[i]
+	}
+	c[n] = 3 * c[n]
+	d[n] = d[nm1]
+	copy(s.B, b[1:])
+	copy(s.C, c[1:])
+	copy(s.D, d[1:])
+}
+
+// the first and the last y are equal
+func (s *Spline) periodic() {
+	n := len(s.X)
+	x, y := append([]float64{0}, s.X...), append([]float64{0}, s.Y...)
+	b, c, d, e := make([]float64, n+1), make([]float64, n+1), make([]float64, n+1), make([]float64, n+1)
+	switch {
+	case n < 3:
+		return
+	case n == 3:
+		b[1] = -(y[1] - y[2]) * (x[1] - 2*x[2] + x[3]) / (x[3] - x[2]) / (x[2] - x[1])
+		b[2], b[3] = b[1], b[1]
+		c[1] = -3 * (y[1] - y[2]) / (x[3] - x[2]) / (x[2] - x[1])
+		c[2] = -c[1]
+		c[3] = c[1]
+		d[1] = -2 * c[1] / 3 / (x[2] - x[1])
+		d[2] = -d[1] * (x[2] - x[1]) / (x[3] - x[2])
+		d[3] = d[1]
+	default:
+		nm1 := n - 1
+		// the cyclic system A c = B
+		d[1] = x[2] - x[1]
+		d[nm1] = x[n] - x[nm1]
+		b[1] = 2 * (d[1] + d[nm1])
+		c[1] = (y[2]-y[1])/d[1] - (y[n]-y[nm1])/d[nm1]
+		for i := 2; i < n; i++ {
+			d[i] = x[i+1] - x[i]
+			b[i] = 2 * (d[i] + d[i-1])
+			c[i] = (y[i+1]-y[i])/d[i] - (y[i]-y[i-1])/d[i-1]
+		}
+		// Cholesky decomposition
+		b[1] = math.Sqrt(b[1])
+		e[1] = (x[n] - x[nm1]) / b[1]
+		t := 0.
+		for i := 1; i <= nm1-2; i++ {
+			d[i] = d[i] / b[i]
+			if i != 1 {
+				e[i] = -e[i-1] * d[i-1] / b[i]
+			}
+			b[i+1] = math.Sqrt(b[i+1] - d[i]*d[i])
+			t += e[i] * e[i]
+		}
+		d[nm1-1] = (d[nm1-1] - e[nm1-2]*d[nm1-2]) / b[nm1-1]
+		b[nm1] = math.Sqrt(b[nm1] - d[nm1-1]*d[nm1-1] - t)
+		// forward elimination
+		c[1] = c[1] / b[1]
+		t = 0
+		for i := 2; i <= nm1-1; i++ {
+			c[i] = (c[i] - d[i-1]*c[i-1]) / b[i]
+			t += e[i-1] * c[i-1]
+		}
+		c[nm1] = (c[nm1] - d[nm1-1]*c[nm1-1] - t) / b[nm1]
+		// backward substitution
+		c[nm1] = c[nm1] / b[nm1]
+		c[nm1-1] = (c[nm1-1] - d[nm1-1]*c[nm1]) / b[nm1-1]
+		for i := nm1 - 2; i >= 1; i-- {
+			c[i] = (c[i] - d[i]*c[i+1] - e[i]*c[nm1]) / b[i]
+		}
+		c[n] = c[1]
+		for i := 1; i <= nm1; i++ {
+			h := x[i+1] - x[i]
+			b[i] = (y[i+1]-y[i])/h - h*(c[i+1]+2*c[i])
+			d[i] = (c[i+1] - c[i]) / h
+			c[i] = 3 * c[i]
+		}
+		b[n], c[n], d[n] = b[1], c[1], d[1]
+	}
+	copy(s.B, b[1:])
+	copy(s.C, c[1:])
+	copy(s.D, d[1:])
+}
+
+// Eval evaluates the spline at u, periodic splines are continued periodically and
+// natural splines linearly to the left.
+func (s *Spline) Eval(u []float64) []float64 {
+	x, n := s.X, len(s.X)
+	v := make([]float64, len(u))
+	for l := range u {
+		v[l] = u[l]
+		if s.Method == SplinePeriodic && n > 1 {
+			dx := x[n-1] - x[0]
+			v[l] = math.Mod(u[l]-x[0], dx)
+			if v[l] < 0 {
+				v[l] += dx
+			}
+			v[l] += x[0]
+		}
+	}
+	i := 0
+	for l, ul := range v {
+		if ul < x[i] || (i < n-1 && x[i+1] < ul) {
+			// x[i] <= ul <= x[i+1]
+			i = 0
+			j := n
+			for {
+				k := (i + j) / 2
+				if ul < x[k] {
+					j = k
+				} else {
+					i = k
+				}
+				if j <= i+1 {
+					break
+				}
+			}
+		}
+		dx := ul - x[i]
+		d := s.D[i]
+		if s.Method == SplineNatural && ul < x[0] {
+			d = 0
+		}
+		v[l] = s.Y[i] + dx*(s.B[i]+dx*(s.C[i]+dx*d))
+	}
+	return v
+}
+
+// Derivative returns the spline of the derivative of order deriv as splinefun does.
+func (s *Spline) Derivative(deriv int) *Spline {
+	n := len(s.X)
+	zero := make([]float64, n)
+	scaled := func(a []float64, f float64) []float64 {
+		r := make([]float64, n)
+		for k, v := range a {
+			r[k] = f * v
+		}
+		return r
+	}
+	r := &Spline{Method: s.Method, X: s.X, D: zero}
+	switch deriv {
+	case 1:
+		r.Y, r.B, r.C = s.B, scaled(s.C, 2), scaled(s.D, 3)
+	case 2:
+		r.Y, r.B, r.C = scaled(s.C, 2), scaled(s.D, 6), zero
+	case 3:
+		r.Y, r.B, r.C = scaled(s.D, 6), zero, zero
+	default:
+		return s
+	}
+	return r
+}
+
+// Hyman restricts the slopes of a spline through monotone points to keep it
+// monotone and recomputes the other coefficients, as R's hyman_filter and spl_coef_conv.
+func (s *Spline) Hyman() {
+	n := len(s.X)
+	if n < 2 {
+		return
+	}
+	ss := make([]float64, n-1)
+	for i := range ss {
+		ss[i] = (s.Y[i+1] - s.Y[i]) / (s.X[i+1] - s.X[i])
+	}
+	for i := 0; i < n; i++ {
+		s0, s1 := ss[0], ss[n-2]
+		if i > 0 {
+			s0 = ss[i-1]
+		}
+		if i < n-1 {
+			s1 = ss[i]
+		}
+		t1 := math.Min(math.Abs(s0), math.Abs(s1))
+		sig := s.B[i]
+		if s0*s1 > 0 {
+			sig = s1
+		}
+		if sig >= 0 {
+			s.B[i] = math.Min(math.Max(0, s.B[i]), 3*t1)
+		} else {
+			s.B[i] = math.Max(math.Min(0, s.B[i]), -3*t1)
+		}
+	}
+	var dd float64
+	for i := 0; i < n-1; i++ {
+		h := s.X[i+1] - s.X[i]
+		y := -(s.Y[i+1] - s.Y[i])
+		b0, b1 := s.B[i], s.B[i+1]
+		s.C[i] = -(3*y + (2*b0+b1)*h) / (h * h)
+		if i == n-2 {
+			s.C[n-1] = (3*y + (b0+2*b1)*h) / (h * h)
+		}
+		dd = (2*y/h + b0 + b1) / (h * h)
+		s.D[i] = dd
+	}
+	s.D[n-1] = dd
+}
+
+// MonoFC adjusts the slopes m at the points with the secants sx between them to
+// keep the Hermite spline monotone.
+func MonoFC(m []float64, sx []float64) {
+	for k := 0; k < len(m)-1; k++ {
+		sk := sx[k]
+		if sk == 0 {
+			m[k], m[k+1] = 0, 0
+			continue
+		}
+		alpha, beta := m[k]/sk, m[k+1]/sk
+		a2b3 := 2*alpha + beta - 3
+		ab23 := alpha + 2*beta - 3
+		if a2b3 > 0 && ab23 > 0 && alpha*(a2b3+ab23) < a2b3*a2b3 {
+			// outside of the region of monotonicity
+			tauS := 3 * sk / math.Sqrt(alpha*alpha+beta*beta)
+			m[k] = tauS * alpha
+			m[k+1] = tauS * beta
+		}
+	}
+}
+
+// Hermite is the cubic Hermite spline through the points with the slopes M.
+type Hermite struct {
+	X, Y, M []float64
+}
+
+// Eval evaluates the derivative of order deriv, linearly extrapolated outside of
+// the points unless cubic.
+func (h *Hermite) Eval(u []float64, deriv int, cubic bool) []float64 {
+	x0, y0, m := h.X, h.Y, h.M
+	n := len(x0)
+	r := make([]float64, len(u))
+	for l, x := range u {
+		if math.IsNaN(x) {
+			r[l] = x
+			continue
+		}
+		i := FindInterval(x0, x) // x0[i-1] <= x < x0[i]
+		if !cubic && (i == 0 || i == n) {
+			k := 0
+			if i == n {
+				k = n - 1
+			}
+			switch deriv {
+			case 0:
+				r[l] = y0[k] + m[k]*(x-x0[k])
+			case 1:
+				r[l] = m[k]
+			}
+			continue
+		}
+		// the interval from 0 and the extrapolation by the outer polynomials
+		i = int(math.Max(0, math.Min(float64(i-1), float64(n-2))))
+		dx := x0[i+1] - x0[i]
+		t := (x - x0[i]) / dx
+		secant := (y0[i+1] - y0[i]) / dx
+		switch deriv {
+		case 0:
+			t1 := t - 1
+			h01 := t * t * (3 - 2*t)
+			h00 := 1 - h01
+			tt1 := t * t1
+			h10 := tt1 * t1
+			h11 := tt1 * t
+			r[l] = y0[i]*h00 + dx*m[i]*h10 + y0[i+1]*h01 + dx*m[i+1]*h11
+		case 1:
+			t1 := t - 1
+			h01 := -6 * t * t1
+			h10 := (3*t - 1) * t1
+			h11 := (3*t - 2) * t
+			r[l] = secant*h01 + m[i]*h10 + m[i+1]*h11
+		case 2:
+			h01 := 6 * (1 - 2*t)
+			h10 := 2 * (3*t - 2)
+			h11 := 2 * (3*t - 1)
+			r[l] = (secant*h01 + m[i]*h10 + m[i+1]*h11) / dx
+		default:
+			r[l] = (secant*-12 + m[i]*6 + m[i+1]*6) / dx
+		}
+	}
+	return r
+}
+
+// FindInterval returns the number of the sorted x not greater than v.
+func FindInterval(x []float64, v float64) int {
+	lo, hi := 0, len(x)
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if x[mid] <= v {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
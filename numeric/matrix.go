@@ -0,0 +1,494 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Dense matrices stored by column as in R, LINPACK and LAPACK, and the
+// decompositions of linear algebra: LU with partial pivoting for solve and
+// determinants, Householder QR with the limited column pivoting of LINPACK
+// dqrdc2, Cholesky, singular values by the Golub-Kahan-Reinsch algorithm and
+// eigenvalues by the EISPACK algorithms as adapted in JAMA.
+
+type Matrix struct {
+	Rows int
+	Cols int
+	Data []float64
+}
+
+func NewMatrix(rows int, cols int) *Matrix {
+	return &Matrix{Rows: rows, Cols: cols, Data: make([]float64, rows*cols)}
+}
+
+func Identity(n int, cols int) *Matrix {
+	m := NewMatrix(n, cols)
+	for k := 0; k < n && k < cols; k++ {
+		m.Set(k, k, 1)
+	}
+	return m
+}
+
+func (m *Matrix) At(i int, j int) float64 {
+	return m.Data[i+j*m.Rows]
+}
+
+func (m *Matrix) Set(i int, j int, v float64) {
+	m.Data[i+j*m.Rows] = v
+}
+
+func (m *Matrix) Clone() *Matrix {
+	return &Matrix{Rows: m.Rows, Cols: m.Cols, Data: append([]float64(nil), m.Data...)}
+}
+
+// the column j, sharing the data
+func (m *Matrix) Col(j int) []float64 {
+	return m.Data[j*m.Rows : (j+1)*m.Rows]
+}
+
+func (m *Matrix) T() *Matrix {
+	r := NewMatrix(m.Cols, m.Rows)
+	for i := 0; i < m.Rows; i++ {
+		for j := 0; j < m.Cols; j++ {
+			r.Set(j, i, m.At(i, j))
+		}
+	}
+	return r
+}
+
+func (m *Matrix) Mul(b *Matrix) *Matrix {
+	r := NewMatrix(m.Rows, b.Cols)
+	for j := 0; j < b.Cols; j++ {
+		for k := 0; k < m.Cols; k++ {
+			bkj := b.At(k, j)
+			if bkj == 0 {
+				continue
+			}
+			for i := 0; i < m.Rows; i++ {
+				r.Data[i+j*r.Rows] += m.At(i, k) * bkj
+			}
+		}
+	}
+	return r
+}
+
+// the maximum absolute column sum
+func (m *Matrix) Norm1() float64 {
+	norm := 0.
+	for j := 0; j < m.Cols; j++ {
+		s := 0.
+		for _, v := range m.Col(j) {
+			s += math.Abs(v)
+		}
+		norm = math.Max(norm, s)
+	}
+	return norm
+}
+
+func (m *Matrix) IsSymmetric(tol float64) bool {
+	if m.Rows != m.Cols {
+		return false
+	}
+	for j := 0; j < m.Cols; j++ {
+		for i := j + 1; i < m.Rows; i++ {
+			a, b := m.At(i, j), m.At(j, i)
+			if math.Abs(a-b) > tol*math.Max(1, math.Max(math.Abs(a), math.Abs(b))) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// the Euclidean norm without overflow, as BLAS dnrm2
+func nrm2(x []float64) float64 {
+	scale, ssq := 0., 1.
+	for _, v := range x {
+		if v != 0 {
+			a := math.Abs(v)
+			if scale < a {
+				ssq = 1 + ssq*(scale/a)*(scale/a)
+				scale = a
+			} else {
+				ssq += (a / scale) * (a / scale)
+			}
+		}
+	}
+	return scale * math.Sqrt(ssq)
+}
+
+func dot(x []float64, y []float64) float64 {
+	s := 0.
+	for k := range x {
+		s += x[k] * y[k]
+	}
+	return s
+}
+
+type LU struct {
+	lu       *Matrix
+	pivot    []int
+	sign     float64
+	Singular int // the first zero pivot counted from 1, or 0
+}
+
+// LU decomposition with partial pivoting of a square matrix, as LAPACK dgetrf
+func NewLU(a *Matrix) *LU {
+	lu := a.Clone()
+	n := a.Rows
+	pivot := make([]int, n)
+	sign := 1.
+	singular := 0
+	for j := 0; j < n; j++ {
+		p := j
+		for i := j + 1; i < n; i++ {
+			if math.Abs(lu.At(i, j)) > math.Abs(lu.At(p, j)) {
+				p = i
+			}
+		}
+		pivot[j] = p
+		if p != j {
+			for k := 0; k < n; k++ {
+				v := lu.At(p, k)
+				lu.Set(p, k, lu.At(j, k))
+				lu.Set(j, k, v)
+			}
+			sign = -sign
+		}
+		d := lu.At(j, j)
+		if d == 0 {
+			if singular == 0 {
+				singular = j + 1
+			}
+			continue
+		}
+		for i := j + 1; i < n; i++ {
+			lu.Set(i, j, lu.At(i, j)/d)
+		}
+		for k := j + 1; k < n; k++ {
+			f := lu.At(j, k)
+			if f == 0 {
+				continue
+			}
+			for i := j + 1; i < n; i++ {
+				lu.Data[i+k*n] -= lu.At(i, j) * f
+			}
+		}
+	}
+	return &LU{lu: lu, pivot: pivot, sign: sign, Singular: singular}
+}
+
+// the solution of A x = b for a nonsingular A
+func (d *LU) Solve(b *Matrix) *Matrix {
+	x := b.Clone()
+	n := d.lu.Rows
+	for c := 0; c < x.Cols; c++ {
+		col := x.Col(c)
+		for j, p := range d.pivot {
+			col[j], col[p] = col[p], col[j]
+		}
+		for j := 0; j < n; j++ {
+			for i := j + 1; i < n; i++ {
+				col[i] -= d.lu.At(i, j) * col[j]
+			}
+		}
+		for j := n - 1; j >= 0; j-- {
+			col[j] /= d.lu.At(j, j)
+			for i := 0; i < j; i++ {
+				col[i] -= d.lu.At(i, j) * col[j]
+			}
+		}
+	}
+	return x
+}
+
+// the logarithm of the modulus of the determinant and its sign
+func (d *LU) LogDet() (float64, float64) {
+	modulus, sign := 0., d.sign
+	for j := 0; j < d.lu.Rows; j++ {
+		v := d.lu.At(j, j)
+		if v < 0 {
+			sign = -sign
+		}
+		modulus += math.Log(math.Abs(v))
+	}
+	return modulus, sign
+}
+
+// the reciprocal condition number in the 1-norm, computed from the inverse
+func (d *LU) Rcond(a *Matrix) float64 {
+	if d.Singular != 0 {
+		return 0
+	}
+	inv := d.Solve(Identity(a.Rows, a.Rows))
+	return 1 / (a.Norm1() * inv.Norm1())
+}
+
+type QR struct {
+	QR    *Matrix   // R in the upper triangle, the Householder vectors below
+	Qraux []float64 // the first elements of the Householder vectors
+	Pivot []int     // the original columns, counted from 0
+	Rank  int
+}
+
+// Householder QR of LINPACK dqrdc2: columns with a norm below tol times
+// their original norm are moved to the end and not counted in the rank
+func NewQR(a *Matrix, tol float64) *QR {
+	x := a.Clone()
+	n, p := x.Rows, x.Cols
+	qraux := make([]float64, p)
+	work := make([][2]float64, p)
+	pivot := make([]int, p)
+	for j := 0; j < p; j++ {
+		pivot[j] = j
+		qraux[j] = nrm2(x.Col(j))
+		work[j] = [2]float64{qraux[j], qraux[j]}
+		if work[j][1] == 0 {
+			work[j][1] = 1
+		}
+	}
+	k := p + 1
+	lup := p
+	if n < lup {
+		lup = n
+	}
+	for l := 0; l < lup; l++ {
+		// cycle negligible columns to the end
+		for l+1 < k && qraux[l] < work[l][1]*tol {
+			col := append([]float64(nil), x.Col(l)...)
+			copy(x.Data[l*n:], x.Data[(l+1)*n:])
+			copy(x.Col(p-1), col)
+			i, t, w := pivot[l], qraux[l], work[l]
+			copy(pivot[l:], pivot[l+1:])
+			copy(qraux[l:], qraux[l+1:])
+			copy(work[l:], work[l+1:])
+			pivot[p-1], qraux[p-1], work[p-1] = i, t, w
+			k--
+		}
+		if l == n-1 {
+			break
+		}
+		xl := x.Col(l)[l:]
+		nrmxl := nrm2(xl)
+		if nrmxl == 0 {
+			continue
+		}
+		if xl[0] != 0 {
+			nrmxl = math.Copysign(nrmxl, xl[0])
+		}
+		for i := range xl {
+			xl[i] /= nrmxl
+		}
+		xl[0] = 1 + xl[0]
+		for j := l + 1; j < p; j++ {
+			xj := x.Col(j)[l:]
+			t := -dot(xl, xj) / xl[0]
+			for i := range xj {
+				xj[i] += t * xl[i]
+			}
+			if qraux[j] == 0 {
+				continue
+			}
+			tt := 1 - (math.Abs(xj[0])/qraux[j])*(math.Abs(xj[0])/qraux[j])
+			tt = math.Max(tt, 0)
+			if math.Abs(tt) < 1e-6 {
+				qraux[j] = nrm2(xj[1:])
+				work[j][0] = qraux[j]
+			} else {
+				qraux[j] *= math.Sqrt(tt)
+			}
+		}
+		qraux[l] = xl[0]
+		xl[0] = -nrmxl
+	}
+	k--
+	if n < k {
+		k = n
+	}
+	return &QR{QR: x, Qraux: qraux, Pivot: pivot, Rank: k}
+}
+
+// the Householder transformations applied to the columns of y, as in LINPACK dqrsl
+func (q *QR) apply(y *Matrix, transpose bool) *Matrix {
+	r := y.Clone()
+	n := q.QR.Rows
+	ju := q.Rank
+	if n-1 < ju {
+		ju = n - 1
+	}
+	for c := 0; c < r.Cols; c++ {
+		col := r.Col(c)
+		for s := 0; s < ju; s++ {
+			j := s
+			if !transpose {
+				j = ju - 1 - s
+			}
+			if q.Qraux[j] == 0 {
+				continue
+			}
+			xj := append([]float64(nil), q.QR.Col(j)[j:]...)
+			xj[0] = q.Qraux[j]
+			t := -dot(xj, col[j:]) / xj[0]
+			for i := range xj {
+				col[j+i] += t * xj[i]
+			}
+		}
+	}
+	return r
+}
+
+func (q *QR) Qty(y *Matrix) *Matrix {
+	return q.apply(y, true)
+}
+
+func (q *QR) Qy(y *Matrix) *Matrix {
+	return q.apply(y, false)
+}
+
+// the coefficients of the least squares fit by column of y, NaN for columns beyond the rank
+func (q *QR) Coef(y *Matrix) *Matrix {
+	qty := q.Qty(y)
+	k := q.Rank
+	b := NewMatrix(q.QR.Cols, y.Cols)
+	for c := 0; c < y.Cols; c++ {
+		col := qty.Col(c)
+		z := make([]float64, k)
+		copy(z, col[:k])
+		for j := k - 1; j >= 0; j-- {
+			z[j] /= q.QR.At(j, j)
+			for i := 0; i < j; i++ {
+				z[i] -= q.QR.At(i, j) * z[j]
+			}
+		}
+		for j := range q.Pivot {
+			if j < k {
+				b.Set(q.Pivot[j], c, z[j])
+			} else {
+				b.Set(q.Pivot[j], c, math.NaN())
+			}
+		}
+	}
+	return b
+}
+
+// the fitted values or the residuals of the least squares fit
+func (q *QR) Fitted(y *Matrix, residuals bool) *Matrix {
+	qty := q.Qty(y)
+	for c := 0; c < qty.Cols; c++ {
+		col := qty.Col(c)
+		for i := range col {
+			if (i < q.Rank) == residuals {
+				col[i] = 0
+			}
+		}
+	}
+	return q.Qy(qty)
+}
+
+// the upper triangular R with t(R) R = a, or the order of the first minor which is not positive definite
+func Cholesky(a *Matrix) (*Matrix, int) {
+	n := a.Rows
+	r := NewMatrix(n, n)
+	for j := 0; j < n; j++ {
+		s := a.At(j, j)
+		for k := 0; k < j; k++ {
+			s -= r.At(k, j) * r.At(k, j)
+		}
+		if s <= 0 || math.IsNaN(s) {
+			return nil, j + 1
+		}
+		d := math.Sqrt(s)
+		r.Set(j, j, d)
+		for i := j + 1; i < n; i++ {
+			t := a.At(j, i)
+			for k := 0; k < j; k++ {
+				t -= r.At(k, j) * r.At(k, i)
+			}
+			r.Set(j, i, t/d)
+		}
+	}
+	return r, 0
+}
+
+// the inverse of t(R) R from the leading size x size block of the upper triangular R
+func Chol2inv(r *Matrix, size int) *Matrix {
+	rinv := NewMatrix(size, size)
+	for j := 0; j < size; j++ {
+		rinv.Set(j, j, 1/r.At(j, j))
+		for i := j - 1; i >= 0; i-- {
+			s := 0.
+			for k := i + 1; k <= j; k++ {
+				s += r.At(i, k) * rinv.At(k, j)
+			}
+			rinv.Set(i, j, -s/r.At(i, i))
+		}
+	}
+	return rinv.Mul(rinv.T())
+}
+
+// the solution of a triangular system with the leading k x k block of r, or the index of a zero on the diagonal
+func TriangularSolve(r *Matrix, b *Matrix, k int, upper bool, transpose bool) (*Matrix, int) {
+	for j := 0; j < k; j++ {
+		if r.At(j, j) == 0 {
+			return nil, j + 1
+		}
+	}
+	// with the transpose an upper triangle works as a lower one
+	t := r
+	if transpose {
+		t = r.T()
+		upper = !upper
+	}
+	x := NewMatrix(k, b.Cols)
+	for c := 0; c < b.Cols; c++ {
+		col := x.Col(c)
+		copy(col, b.Col(c)[:k])
+		if upper {
+			for j := k - 1; j >= 0; j-- {
+				col[j] /= t.At(j, j)
+				for i := 0; i < j; i++ {
+					col[i] -= t.At(i, j) * col[j]
+				}
+			}
+		} else {
+			for j := 0; j < k; j++ {
+				col[j] /= t.At(j, j)
+				for i := j + 1; i < k; i++ {
+					col[i] -= t.At(i, j) * col[j]
+				}
+			}
+		}
+	}
+	return x, 0
+}
+
+// the columns of m completed to an orthonormal basis with cols columns by Gram-Schmidt on the unit vectors
+func CompleteBasis(m *Matrix, cols int) *Matrix {
+	r := NewMatrix(m.Rows, cols)
+	copy(r.Data, m.Data)
+	k := m.Cols
+	for e := 0; e < m.Rows && k < cols; e++ {
+		v := r.Col(k)
+		for i := range v {
+			v[i] = 0
+		}
+		v[e] = 1
+		// twice is enough for orthogonality
+		for pass := 0; pass < 2; pass++ {
+			for j := 0; j < k; j++ {
+				t := dot(r.Col(j), v)
+				for i, u := range r.Col(j) {
+					v[i] -= t * u
+				}
+			}
+		}
+		norm := nrm2(v)
+		if norm < 1e-8 {
+			continue
+		}
+		for i := range v {
+			v[i] /= norm
+		}
+		k++
+	}
+	return r
+}
@@ -0,0 +1,283 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The normal and log-normal distributions. The distribution function is the
+// rational Chebyshev approximation of Cody (1969).
+
+func Dnorm(x float64, mu float64, sigma float64, logD bool) float64 {
+	if isNaN(x, mu, sigma) {
+		return x + mu + sigma
+	}
+	if sigma < 0 {
+		return math.NaN()
+	}
+	if !isFinite(sigma) {
+		return d0(logD)
+	}
+	if !isFinite(x) && mu == x {
+		return math.NaN()
+	}
+	if sigma == 0 {
+		if x == mu {
+			return posInf
+		}
+		return d0(logD)
+	}
+	x = (x - mu) / sigma
+	if !isFinite(x) {
+		return d0(logD)
+	}
+	x = math.Abs(x)
+	if x >= 2*math.Sqrt(math.MaxFloat64) {
+		return d0(logD)
+	}
+	if logD {
+		return -(lnSqrt2Pi + 0.5*x*x + math.Log(sigma))
+	}
+	if x < 5 {
+		return oneSqrt2Pi * math.Exp(-0.5*x*x) / sigma
+	}
+	if x > math.Sqrt(-2*ln2*(-1021+1-53)) {
+		return 0
+	}
+	// split x = x1 + x2 to avoid the cancellation in x*x
+	x1 := math.Ldexp(math.RoundToEven(math.Ldexp(x, 16)), -16)
+	x2 := x - x1
+	return oneSqrt2Pi / sigma * (math.Exp(-0.5*x1*x1) * math.Exp((-0.5*x2-x1)*x2))
+}
+
+func Pnorm(x float64, mu float64, sigma float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, mu, sigma) {
+		return x + mu + sigma
+	}
+	if !isFinite(x) && mu == x {
+		return math.NaN()
+	}
+	if sigma <= 0 {
+		if sigma < 0 {
+			return math.NaN()
+		}
+		if x < mu {
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	p := (x - mu) / sigma
+	if !isFinite(p) {
+		if x < mu {
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	cum, ccum := pnormBoth(p, logP)
+	if lowerTail {
+		return cum
+	}
+	return ccum
+}
+
+var (
+	pnormA = [5]float64{2.2352520354606839287, 161.02823106855587881, 1067.6894854603709582,
+		18154.981253343561249, 0.065682337918207449113}
+	pnormB = [4]float64{47.20258190468824187, 976.09855173777669322, 10260.932208618978205,
+		45507.789335026729956}
+	pnormC = [9]float64{0.39894151208813466764, 8.8831497943883759412, 93.506656132177855979,
+		597.27027639480026226, 2494.5375852903726711, 6848.1904505362823326,
+		11602.651437647350124, 9842.7148383839780218, 1.0765576773720192317e-8}
+	pnormD = [8]float64{22.266688044328115691, 235.38790178262499861, 1519.377599407554805,
+		6485.558298266760755, 18615.571640885098091, 34900.952721145977266,
+		38912.003286093271411, 19685.429676859990727}
+	pnormP = [6]float64{0.21589853405795699, 0.1274011611602473639, 0.022235277870649807,
+		0.001421619193227893466, 2.9112874951168792e-5, 0.02307344176494017303}
+	pnormQ = [5]float64{1.28426009614491121, 0.468238212480865118, 0.0659881378689285515,
+		0.00378239633202758244, 7.29751555083966205e-5}
+)
+
+// both tails of the standard normal distribution at x
+func pnormBoth(x float64, logP bool) (cum float64, ccum float64) {
+	const sqrt32 = 5.656854249492380195206754896838
+	var temp float64
+	// the tail probability exp(-x^2/2) * temp, with x^2 split to keep its precision
+	del := func(y float64) {
+		xsq := math.Trunc(y*16) / 16
+		d := (y - xsq) * (y + xsq)
+		if logP {
+			cum = (-xsq * xsq * 0.5) - d*0.5 + math.Log(temp)
+			ccum = math.Log1p(-math.Exp(-xsq*xsq*0.5) * math.Exp(-d*0.5) * temp)
+		} else {
+			cum = math.Exp(-xsq*xsq*0.5) * math.Exp(-d*0.5) * temp
+			ccum = 1.0 - cum
+		}
+		if x > 0 {
+			cum, ccum = ccum, cum
+		}
+	}
+	y := math.Abs(x)
+	switch {
+	case y <= 0.67448975:
+		var xnum, xden float64
+		if y > dblEpsilon*0.5 {
+			xsq := x * x
+			xnum = pnormA[4] * xsq
+			xden = xsq
+			for i := 0; i < 3; i++ {
+				xnum = (xnum + pnormA[i]) * xsq
+				xden = (xden + pnormB[i]) * xsq
+			}
+		}
+		temp = x * (xnum + pnormA[3]) / (xden + pnormB[3])
+		cum, ccum = 0.5+temp, 0.5-temp
+		if logP {
+			cum, ccum = math.Log(cum), math.Log(ccum)
+		}
+	case y <= sqrt32:
+		xnum := pnormC[8] * y
+		xden := y
+		for i := 0; i < 7; i++ {
+			xnum = (xnum + pnormC[i]) * y
+			xden = (xden + pnormD[i]) * y
+		}
+		temp = (xnum + pnormC[7]) / (xden + pnormD[7])
+		del(y)
+	case (logP && y < 1e170) || (-37.5193 < x && x < 37.5193):
+		xsq := 1.0 / (x * x)
+		xnum := pnormP[5] * xsq
+		xden := xsq
+		for i := 0; i < 4; i++ {
+			xnum = (xnum + pnormP[i]) * xsq
+			xden = (xden + pnormQ[i]) * xsq
+		}
+		temp = xsq * (xnum + pnormP[4]) / (xden + pnormQ[4])
+		temp = (oneSqrt2Pi - temp) / y
+		del(y)
+	default:
+		if x > 0 {
+			return d1(logP), d0(logP)
+		}
+		return d0(logP), d1(logP)
+	}
+	return cum, ccum
+}
+
+// algorithm AS 241 of Wichura (1988)
+func Qnorm(p float64, mu float64, sigma float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, mu, sigma) {
+		return p + mu + sigma
+	}
+	if v, done := qP01Boundaries(p, negInf, posInf, lowerTail, logP); done {
+		return v
+	}
+	if sigma < 0 {
+		return math.NaN()
+	}
+	if sigma == 0 {
+		return mu
+	}
+	p0 := dtQIv(p, lowerTail, logP)
+	q := p0 - 0.5
+	if math.Abs(q) <= 0.425 {
+		r := .180625 - q*q
+		val := q * (((((((r*2509.0809287301226727+
+			33430.575583588128105)*r+67265.770927008700853)*r+
+			45921.953931549871457)*r+13731.693765509461125)*r+
+			1971.5909503065514427)*r+133.14166789178437745)*r +
+			3.387132872796366608) /
+			(((((((r*5226.495278852545925+
+				28729.085735721942674)*r+39307.89580009271061)*r+
+				21213.794301586595867)*r+5394.1960214247511077)*r+
+				687.1870074920579083)*r+42.313330701600911252)*r + 1.)
+		return mu + sigma*val
+	}
+	var r float64
+	if logP && ((lowerTail && q <= 0) || (!lowerTail && q > 0)) {
+		r = p
+	} else if q > 0 {
+		r = math.Log(dtCIv(p, lowerTail, logP))
+	} else {
+		r = math.Log(p0)
+	}
+	r = math.Sqrt(-r)
+	var val float64
+	if r <= 5 {
+		r += -1.6
+		val = (((((((r*7.7454501427834140764e-4+
+			.0227238449892691845833)*r+.24178072517745061177)*
+			r+1.27045825245236838258)*r+
+			3.64784832476320460504)*r+5.7694972214606914055)*
+			r+4.6303378461565452959)*r +
+			1.42343711074968357734) /
+			(((((((r*
+				1.05075007164441684324e-9+5.475938084995344946e-4)*
+				r+.0151986665636164571966)*r+
+				.14810397642748007459)*r+.68976733498510000455)*
+				r+1.6763848301838038494)*r+
+				2.05319162663775882187)*r + 1.)
+	} else {
+		r += -5.
+		val = (((((((r*2.01033439929228813265e-7+
+			2.71155556874348757815e-5)*r+
+			.0012426609473880784386)*r+.026532189526576123093)*
+			r+.29656057182850489123)*r+
+			1.7848265399172913358)*r+5.4637849111641143699)*
+			r + 6.6579046435011037772) /
+			(((((((r*
+				2.04426310338993978564e-15+1.4215117583164458887e-7)*
+				r+1.8463183175100546818e-5)*r+
+				7.868691311456132591e-4)*r+.0148753612908506148525)*
+				r+.13692988092273580531)*r+
+				.59983220655588793769)*r + 1.)
+	}
+	if q < 0 {
+		val = -val
+	}
+	return mu + sigma*val
+}
+
+func Dlnorm(x float64, meanlog float64, sdlog float64, logD bool) float64 {
+	if isNaN(x, meanlog, sdlog) {
+		return x + meanlog + sdlog
+	}
+	if sdlog < 0 {
+		return math.NaN()
+	}
+	if sdlog == 0 {
+		if math.Log(x) == meanlog {
+			return posInf
+		}
+		return d0(logD)
+	}
+	if x <= 0 {
+		return d0(logD)
+	}
+	y := (math.Log(x) - meanlog) / sdlog
+	if logD {
+		return -(lnSqrt2Pi + 0.5*y*y + math.Log(x*sdlog))
+	}
+	return oneSqrt2Pi * math.Exp(-0.5*y*y) / (x * sdlog)
+}
+
+func Plnorm(x float64, meanlog float64, sdlog float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, meanlog, sdlog) {
+		return x + meanlog + sdlog
+	}
+	if sdlog < 0 {
+		return math.NaN()
+	}
+	if x > 0 {
+		return Pnorm(math.Log(x), meanlog, sdlog, lowerTail, logP)
+	}
+	return dt0(lowerTail, logP)
+}
+
+func Qlnorm(p float64, meanlog float64, sdlog float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, meanlog, sdlog) {
+		return p + meanlog + sdlog
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	return math.Exp(Qnorm(p, meanlog, sdlog, lowerTail, logP))
+}
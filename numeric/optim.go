@@ -0,0 +1,651 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The general purpose minimizers of R's optim: the Nelder-Mead simplex search,
+// the variable metric method BFGS and conjugate gradients as in Nash's Compact
+// Numerical Methods, and a limited memory BFGS method for box constraints. They
+// are ports of R's optim.c except for L-BFGS-B, which projects the steps of a
+// limited memory quasi-Newton method onto the bounds.
+
+// Objective is a function of the parameters to be minimized.
+type Objective func(x []float64) float64
+
+// Gradient stores the gradient of the objective at x in g.
+type Gradient func(x []float64, g []float64)
+
+// OptimControl holds the tuning parameters of all methods, see optim's control list.
+type OptimControl struct {
+	Maxit  int
+	Abstol float64
+	Reltol float64
+	Alpha  float64 // reflection, contraction and expansion of Nelder-Mead
+	Beta   float64
+	Gamma  float64
+	Type   int // the update of CG: 1 Fletcher-Reeves, 2 Polak-Ribiere, 3 Beale-Sorenson
+	Lmm    int // the number of corrections kept by L-BFGS-B
+	Factr  float64
+	Pgtol  float64
+}
+
+// the codes of OptimResult.Fail, as optim's convergence
+const (
+	OptimNotFinite  = -1 // the objective is not finite at the initial parameters
+	OptimConverged  = 0
+	OptimMaxit      = 1
+	OptimDegenerate = 10 // the simplex of Nelder-Mead did not shrink
+	OptimWarning    = 51
+	OptimError      = 52
+)
+
+type OptimResult struct {
+	Par     []float64
+	Value   float64
+	Fncount int
+	Grcount int
+	Fail    int
+	Message string // only set by L-BFGS-B
+}
+
+const (
+	big      = 1.0e+35 // replaces non-finite values in Nelder-Mead
+	stepredn = 0.2
+	acctol   = 0.0001
+	reltest  = 10.0
+)
+
+func finiteOr(f float64, v float64) float64 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return v
+	}
+	return f
+}
+
+// Nmmin is the Nelder-Mead simplex search.
+func Nmmin(fn Objective, b []float64, c OptimControl) OptimResult {
+	n := len(b)
+	bvec := append([]float64{}, b...)
+	if c.Maxit <= 0 {
+		return OptimResult{Par: bvec, Value: fn(bvec)}
+	}
+	// the columns of P are the n+1 vertices and the centroid, the last row their function values
+	n1 := n + 1
+	C := n + 2
+	P := make([][]float64, n1)
+	for i := range P {
+		P[i] = make([]float64, C)
+	}
+	f := fn(bvec)
+	if !isFinite(f) {
+		return OptimResult{Par: bvec, Value: f, Fail: OptimNotFinite}
+	}
+	funcount := 1
+	fail := OptimConverged
+	convtol := c.Reltol * (math.Abs(f) + c.Reltol)
+	P[n1-1][0] = f
+	for i := 0; i < n; i++ {
+		P[i][0] = bvec[i]
+	}
+	L := 1
+	size := 0.
+	step := 0.
+	for i := 0; i < n; i++ {
+		if 0.1*math.Abs(bvec[i]) > step {
+			step = 0.1 * math.Abs(bvec[i])
+		}
+	}
+	if step == 0 {
+		step = 0.1
+	}
+	for j := 2; j <= n1; j++ {
+		for i := 0; i < n; i++ {
+			P[i][j-1] = bvec[i]
+		}
+		trystep := step
+		for P[j-2][j-1] == bvec[j-2] {
+			P[j-2][j-1] = bvec[j-2] + trystep
+			trystep *= 10
+		}
+		size += trystep
+	}
+	oldsize := size
+	calcvert := true
+	for {
+		if calcvert {
+			for j := 0; j < n1; j++ {
+				if j+1 != L {
+					for i := 0; i < n; i++ {
+						bvec[i] = P[i][j]
+					}
+					f = finiteOr(fn(bvec), big)
+					funcount++
+					P[n1-1][j] = f
+				}
+			}
+			calcvert = false
+		}
+		VL := P[n1-1][L-1]
+		VH := VL
+		H := L
+		for j := 1; j <= n1; j++ {
+			if j != L {
+				f = P[n1-1][j-1]
+				if f < VL {
+					L = j
+					VL = f
+				}
+				if f > VH {
+					H = j
+					VH = f
+				}
+			}
+		}
+		if VH <= VL+convtol || VL <= c.Abstol {
+			break
+		}
+		for i := 0; i < n; i++ {
+			temp := -P[i][H-1]
+			for j := 0; j < n1; j++ {
+				temp += P[i][j]
+			}
+			P[i][C-1] = temp / float64(n)
+		}
+		for i := 0; i < n; i++ {
+			bvec[i] = (1+c.Alpha)*P[i][C-1] - c.Alpha*P[i][H-1]
+		}
+		f = finiteOr(fn(bvec), big)
+		funcount++
+		VR := f
+		if VR < VL {
+			// extension
+			P[n1-1][C-1] = f
+			for i := 0; i < n; i++ {
+				f = c.Gamma*bvec[i] + (1-c.Gamma)*P[i][C-1]
+				P[i][C-1] = bvec[i]
+				bvec[i] = f
+			}
+			f = finiteOr(fn(bvec), big)
+			funcount++
+			if f < VR {
+				for i := 0; i < n; i++ {
+					P[i][H-1] = bvec[i]
+				}
+				P[n1-1][H-1] = f
+			} else {
+				for i := 0; i < n; i++ {
+					P[i][H-1] = P[i][C-1]
+				}
+				P[n1-1][H-1] = VR
+			}
+		} else {
+			// reduction
+			if VR < VH {
+				for i := 0; i < n; i++ {
+					P[i][H-1] = bvec[i]
+				}
+				P[n1-1][H-1] = VR
+			}
+			for i := 0; i < n; i++ {
+				bvec[i] = (1-c.Beta)*P[i][H-1] + c.Beta*P[i][C-1]
+			}
+			f = finiteOr(fn(bvec), big)
+			funcount++
+			if f < P[n1-1][H-1] {
+				for i := 0; i < n; i++ {
+					P[i][H-1] = bvec[i]
+				}
+				P[n1-1][H-1] = f
+			} else if VR >= VH {
+				// shrink towards the lowest vertex
+				calcvert = true
+				size = 0
+				for j := 0; j < n1; j++ {
+					if j+1 != L {
+						for i := 0; i < n; i++ {
+							P[i][j] = c.Beta*(P[i][j]-P[i][L-1]) + P[i][L-1]
+							size += math.Abs(P[i][j] - P[i][L-1])
+						}
+					}
+				}
+				if size < oldsize {
+					oldsize = size
+				} else {
+					fail = OptimDegenerate
+					break
+				}
+			}
+		}
+		if funcount > c.Maxit {
+			break
+		}
+	}
+	x := make([]float64, n)
+	for i := 0; i < n; i++ {
+		x[i] = P[i][L-1]
+	}
+	if funcount > c.Maxit {
+		fail = OptimMaxit
+	}
+	return OptimResult{Par: x, Value: P[n1-1][L-1], Fncount: funcount, Fail: fail}
+}
+
+// Vmmin is the variable metric method BFGS.
+func Vmmin(fn Objective, gr Gradient, b []float64, c OptimControl) OptimResult {
+	n := len(b)
+	b = append([]float64{}, b...)
+	if c.Maxit <= 0 {
+		return OptimResult{Par: b, Value: fn(b)}
+	}
+	g := make([]float64, n)
+	t := make([]float64, n)
+	X := make([]float64, n)
+	cv := make([]float64, n)
+	B := make([][]float64, n)
+	for i := range B {
+		B[i] = make([]float64, i+1)
+	}
+	f := fn(b)
+	if !isFinite(f) {
+		return OptimResult{Par: b, Value: f, Fail: OptimNotFinite}
+	}
+	fmin := f
+	funcount, gradcount := 1, 1
+	gr(b, g)
+	iter := 1
+	ilast := gradcount
+	count := 0
+	for {
+		if ilast == gradcount {
+			for i := 0; i < n; i++ {
+				for j := 0; j < i; j++ {
+					B[i][j] = 0
+				}
+				B[i][i] = 1
+			}
+		}
+		for i := 0; i < n; i++ {
+			X[i] = b[i]
+			cv[i] = g[i]
+		}
+		gradproj := 0.
+		for i := 0; i < n; i++ {
+			s := 0.
+			for j := 0; j <= i; j++ {
+				s -= B[i][j] * g[j]
+			}
+			for j := i + 1; j < n; j++ {
+				s -= B[j][i] * g[j]
+			}
+			t[i] = s
+			gradproj += s * g[i]
+		}
+		if gradproj < 0 { // search direction is downhill
+			steplength := 1.0
+			accpoint := false
+			for {
+				count = 0
+				for i := 0; i < n; i++ {
+					b[i] = X[i] + steplength*t[i]
+					if reltest+X[i] == reltest+b[i] { // no change
+						count++
+					}
+				}
+				if count < n {
+					f = fn(b)
+					funcount++
+					accpoint = isFinite(f) && f <= fmin+gradproj*steplength*acctol
+					if !accpoint {
+						steplength *= stepredn
+					}
+				}
+				if count == n || accpoint {
+					break
+				}
+			}
+			// stop if the value is small or its relative change is low
+			enough := f > c.Abstol && math.Abs(f-fmin) > c.Reltol*(math.Abs(fmin)+c.Reltol)
+			if !enough {
+				count = n
+				fmin = f
+			}
+			if count < n { // making progress
+				fmin = f
+				gr(b, g)
+				gradcount++
+				iter++
+				D1 := 0.
+				for i := 0; i < n; i++ {
+					t[i] = steplength * t[i]
+					cv[i] = g[i] - cv[i]
+					D1 += t[i] * cv[i]
+				}
+				if D1 > 0 {
+					D2 := 0.
+					for i := 0; i < n; i++ {
+						s := 0.
+						for j := 0; j <= i; j++ {
+							s += B[i][j] * cv[j]
+						}
+						for j := i + 1; j < n; j++ {
+							s += B[j][i] * cv[j]
+						}
+						X[i] = s
+						D2 += s * cv[i]
+					}
+					D2 = 1 + D2/D1
+					for i := 0; i < n; i++ {
+						for j := 0; j <= i; j++ {
+							B[i][j] += (D2*t[i]*t[j] - X[i]*t[j] - t[i]*X[j]) / D1
+						}
+					}
+				} else {
+					ilast = gradcount
+				}
+			} else if ilast < gradcount { // no progress
+				count = 0
+				ilast = gradcount
+			}
+		} else { // uphill search resets unless it has just been reset
+			count = 0
+			if ilast == gradcount {
+				count = n
+			} else {
+				ilast = gradcount
+			}
+		}
+		if iter >= c.Maxit {
+			break
+		}
+		if gradcount-ilast > 2*n {
+			ilast = gradcount // periodic restart
+		}
+		if count == n && ilast == gradcount {
+			break
+		}
+	}
+	fail := OptimConverged
+	if iter >= c.Maxit {
+		fail = OptimMaxit
+	}
+	return OptimResult{Par: b, Value: fmin, Fncount: funcount, Grcount: gradcount, Fail: fail}
+}
+
+// Cgmin is the conjugate gradients method.
+func Cgmin(fn Objective, gr Gradient, b []float64, c OptimControl) OptimResult {
+	n := len(b)
+	bvec := append([]float64{}, b...)
+	if c.Maxit <= 0 {
+		return OptimResult{Par: bvec, Value: fn(bvec)}
+	}
+	cv := make([]float64, n)
+	g := make([]float64, n)
+	t := make([]float64, n)
+	X := make([]float64, n)
+	const setstep = 1.7
+	cyclimit := n
+	tol := c.Reltol * float64(n) * math.Sqrt(c.Reltol)
+	f := fn(bvec)
+	if !isFinite(f) {
+		return OptimResult{Par: bvec, Value: f, Fail: OptimNotFinite}
+	}
+	fmin := f
+	funcount, gradcount := 1, 0
+	steplength := 1.0
+	for {
+		for i := 0; i < n; i++ {
+			t[i] = 0
+			cv[i] = bvec[i]
+		}
+		cycle := 0
+		oldstep := 1.0
+		count := 0
+		var G1 float64
+		for {
+			cycle++
+			count++
+			gradcount++
+			if gradcount > c.Maxit {
+				return OptimResult{Par: bvec, Value: fmin, Fncount: funcount, Grcount: gradcount, Fail: OptimMaxit}
+			}
+			gr(bvec, g)
+			G1 = 0
+			G2 := 0.
+			for i := 0; i < n; i++ {
+				X[i] = bvec[i]
+				switch c.Type {
+				case 2: // Polak-Ribiere
+					G1 += g[i] * (g[i] - cv[i])
+					G2 += cv[i] * cv[i]
+				case 3: // Beale-Sorenson
+					G1 += g[i] * (g[i] - cv[i])
+					G2 += t[i] * (g[i] - cv[i])
+				default: // Fletcher-Reeves
+					G1 += g[i] * g[i]
+					G2 += cv[i] * cv[i]
+				}
+				cv[i] = g[i]
+			}
+			if G1 > tol {
+				G3 := 1.0
+				if G2 > 0 {
+					G3 = G1 / G2
+				}
+				gradproj := 0.
+				for i := 0; i < n; i++ {
+					t[i] = t[i]*G3 - g[i]
+					gradproj += t[i] * g[i]
+				}
+				steplength = oldstep
+				accpoint := false
+				for {
+					count = 0
+					for i := 0; i < n; i++ {
+						bvec[i] = X[i] + steplength*t[i]
+						if reltest+X[i] == reltest+bvec[i] { // no change
+							count++
+						}
+					}
+					if count < n {
+						f = fn(bvec)
+						funcount++
+						accpoint = isFinite(f) && f <= fmin+gradproj*steplength*acctol
+						if !accpoint {
+							steplength *= stepredn
+						} else {
+							fmin = f
+						}
+					}
+					if count == n || accpoint {
+						break
+					}
+				}
+				if count < n {
+					// a quadratic interpolation along the line
+					newstep := 2 * (f - fmin - gradproj*steplength)
+					if newstep > 0 {
+						newstep = -(gradproj * steplength * steplength / newstep)
+						for i := 0; i < n; i++ {
+							bvec[i] = X[i] + newstep*t[i]
+						}
+						fmin = f
+						f = fn(bvec)
+						funcount++
+						if f < fmin {
+							fmin = f
+						} else {
+							for i := 0; i < n; i++ {
+								bvec[i] = X[i] + steplength*t[i]
+							}
+						}
+					}
+				}
+			}
+			oldstep = setstep * steplength
+			if oldstep > 1 {
+				oldstep = 1
+			}
+			if count == n || G1 <= tol || cycle == cyclimit {
+				break
+			}
+		}
+		if cycle == 1 && (count == n || G1 <= tol || fmin <= c.Abstol) {
+			break
+		}
+	}
+	return OptimResult{Par: bvec, Value: fmin, Fncount: funcount, Grcount: gradcount, Fail: OptimConverged}
+}
+
+func project(x []float64, lower []float64, upper []float64) {
+	for i := range x {
+		if x[i] < lower[i] {
+			x[i] = lower[i]
+		} else if x[i] > upper[i] {
+			x[i] = upper[i]
+		}
+	}
+}
+
+// Lbfgsb minimizes within the bounds lower and upper. The variables at a bound, whose
+// gradient points outwards, are held fixed, the others follow the two-loop recursion of
+// limited memory BFGS with a backtracking search along the projected path.
+func Lbfgsb(fn Objective, gr Gradient, b []float64, lower []float64, upper []float64, c OptimControl) OptimResult {
+	n := len(b)
+	x := append([]float64{}, b...)
+	project(x, lower, upper)
+	f := fn(x)
+	if !isFinite(f) {
+		return OptimResult{Par: x, Value: f, Fail: OptimNotFinite, Message: "L-BFGS-B needs finite values of 'fn'"}
+	}
+	g := make([]float64, n)
+	gr(x, g)
+	count := 1
+	var S, Y [][]float64
+	var rho []float64
+	d := make([]float64, n)
+	xnew := make([]float64, n)
+	gnew := make([]float64, n)
+	free := make([]bool, n)
+	q := make([]float64, n)
+	alpha := make([]float64, c.Lmm)
+	const epsmch = 2.220446049250313e-16
+	for iter := 0; ; iter++ {
+		pgnorm := 0.
+		for i := range x {
+			pg := math.Max(math.Min(x[i]-g[i], upper[i]), lower[i]) - x[i]
+			pgnorm = math.Max(pgnorm, math.Abs(pg))
+		}
+		if pgnorm <= c.Pgtol {
+			return OptimResult{Par: x, Value: f, Fncount: count, Grcount: count, Message: "CONVERGENCE: NORM OF PROJECTED GRADIENT <= PGTOL"}
+		}
+		if iter >= c.Maxit {
+			return OptimResult{Par: x, Value: f, Fncount: count, Grcount: count, Fail: OptimMaxit, Message: "NEW_X"}
+		}
+		for i := range x {
+			free[i] = !(x[i] <= lower[i] && g[i] > 0 || x[i] >= upper[i] && g[i] < 0)
+			q[i] = 0
+			if free[i] {
+				q[i] = g[i]
+			}
+		}
+		dot := func(u []float64, v []float64) float64 {
+			s := 0.
+			for i := range u {
+				if free[i] {
+					s += u[i] * v[i]
+				}
+			}
+			return s
+		}
+		for k := len(S) - 1; k >= 0; k-- {
+			alpha[k] = rho[k] * dot(S[k], q)
+			for i := range q {
+				q[i] -= alpha[k] * Y[k][i]
+			}
+		}
+		gamma := 1.
+		if k := len(S) - 1; k >= 0 {
+			gamma = 1 / (rho[k] * dot(Y[k], Y[k]))
+		}
+		for i := range q {
+			q[i] *= gamma
+		}
+		for k := range S {
+			beta := rho[k] * dot(Y[k], q)
+			for i := range q {
+				q[i] += (alpha[k] - beta) * S[k][i]
+			}
+		}
+		gd := 0.
+		for i := range d {
+			d[i] = 0
+			if free[i] {
+				d[i] = -q[i]
+				gd += d[i] * g[i]
+			}
+		}
+		if gd >= 0 { // not a descent direction, restart with steepest descent
+			S, Y, rho = nil, nil, nil
+			for i := range d {
+				d[i] = 0
+				if free[i] {
+					d[i] = -g[i]
+				}
+			}
+		}
+		step := 1.
+		if len(S) == 0 {
+			dnorm := 0.
+			for _, v := range d {
+				dnorm += v * v
+			}
+			step = math.Min(1/math.Sqrt(dnorm), 1)
+		}
+		var fnew float64
+		accepted := false
+		for tries := 0; tries < 20; tries++ {
+			decrease := 0.
+			for i := range x {
+				xnew[i] = x[i] + step*d[i]
+			}
+			project(xnew, lower, upper)
+			for i := range x {
+				decrease += g[i] * (xnew[i] - x[i])
+			}
+			fnew = fn(xnew)
+			count++
+			if isFinite(fnew) && fnew <= f+acctol*decrease {
+				accepted = true
+				break
+			}
+			step *= 0.5
+		}
+		if !accepted {
+			return OptimResult{Par: x, Value: f, Fncount: count, Grcount: count, Fail: OptimError, Message: "ABNORMAL_TERMINATION_IN_LNSRCH"}
+		}
+		gr(xnew, gnew)
+		s := make([]float64, n)
+		y := make([]float64, n)
+		sy, yy := 0., 0.
+		for i := range x {
+			s[i] = xnew[i] - x[i]
+			y[i] = gnew[i] - g[i]
+			sy += s[i] * y[i]
+			yy += y[i] * y[i]
+		}
+		if sy > epsmch*yy {
+			if len(S) == c.Lmm {
+				S, Y, rho = S[1:], Y[1:], rho[1:]
+			}
+			S, Y, rho = append(S, s), append(Y, y), append(rho, 1/sy)
+		}
+		fold := f
+		copy(x, xnew)
+		copy(g, gnew)
+		f = fnew
+		if (fold-f)/math.Max(math.Max(math.Abs(fold), math.Abs(f)), 1) <= c.Factr*epsmch {
+			return OptimResult{Par: x, Value: f, Fncount: count, Grcount: count, Message: "CONVERGENCE: REL_REDUCTION_OF_F <= FACTR*EPSMCH"}
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Quantiles without a closed form are found by bisection. Continuous distributions
+// bisect over the float64 numbers themselves, ordered as unsigned integers, so that
+// 64 evaluations of the distribution function give the quantile to the last bit.
+
+func orderedKey(x float64) uint64 {
+	b := math.Float64bits(x)
+	if b>>63 == 1 {
+		return ^b
+	}
+	return b | 1<<63
+}
+
+func fromKey(k uint64) float64 {
+	if k>>63 == 1 {
+		return math.Float64frombits(k &^ (1 << 63))
+	}
+	return math.Float64frombits(^k)
+}
+
+// the smallest x in [lo, hi] with ok(x), for ok monotone and true at hi
+func bisect(lo float64, hi float64, ok func(float64) bool) float64 {
+	if ok(lo) {
+		return lo
+	}
+	a, b := orderedKey(lo), orderedKey(hi)
+	for b-a > 1 {
+		m := a + (b-a)/2
+		if ok(fromKey(m)) {
+			b = m
+		} else {
+			a = m
+		}
+	}
+	return fromKey(b)
+}
+
+type cdf func(x float64, lowerTail bool, logP bool) float64
+
+// the quantile of a continuous distribution with support in [lo, hi]
+func continuousQuantile(p float64, lo float64, hi float64, lowerTail bool, logP bool, f cdf) float64 {
+	if lowerTail {
+		return bisect(lo, hi, func(x float64) bool { return f(x, true, logP) >= p })
+	}
+	return bisect(lo, hi, func(x float64) bool { return f(x, false, logP) <= p })
+}
+
+// the quantile of a distribution symmetric around zero
+func symmetricQuantile(p float64, lowerTail bool, logP bool, f cdf) float64 {
+	if dtQIv(p, lowerTail, logP) > 0.5 {
+		return continuousQuantile(p, 0, posInf, lowerTail, logP, f)
+	}
+	x := continuousQuantile(p, 0, posInf, !lowerTail, logP, f)
+	if x == 0 {
+		return 0
+	}
+	return -x
+}
+
+// The quantile of a discrete distribution on the integers from lo: the smallest y with
+// P[X <= y] >= p for the lower tail and P[X > y] <= p for the upper one. As in nmath
+// the probability is fuzzed by a few epsilons against rounding in the distribution
+// function. The search starts from the guess y, gallops, and bisects.
+func discreteQuantile(p float64, y float64, lo float64, hi float64, lowerTail bool, logP bool, f cdf) float64 {
+	if logP {
+		e := 2 * dblEpsilon
+		if lowerTail && p > -math.MaxFloat64 {
+			p *= 1 + e
+		} else {
+			p *= 1 - e
+		}
+	} else {
+		e := 8 * dblEpsilon
+		if lowerTail {
+			p *= 1 - e
+		} else if 1-p > 4*e {
+			p *= 1 + e
+		}
+	}
+	ok := func(x float64) bool {
+		if lowerTail {
+			return f(x, true, logP) >= p
+		}
+		return f(x, false, logP) <= p
+	}
+	y = math.Max(lo, math.Min(hi, math.Floor(y)))
+	if !isFinite(y) {
+		y = lo
+	}
+	// bracket the answer in (a, b]
+	var a, b float64
+	if ok(y) {
+		b = y
+		step := 1.
+		for {
+			a = b - step
+			if a < lo {
+				a = lo - 1
+				break
+			}
+			if !ok(a) {
+				break
+			}
+			b = a
+			step *= 2
+		}
+	} else {
+		a = y
+		step := 1.
+		for {
+			b = a + step
+			if b >= hi || math.IsInf(b, 1) {
+				b = hi
+				break
+			}
+			if ok(b) {
+				break
+			}
+			a = b
+			step *= 2
+		}
+	}
+	for b-a > 1 {
+		m := math.Floor(a + (b-a)/2)
+		if ok(m) {
+			b = m
+		} else {
+			a = m
+		}
+	}
+	return b
+}
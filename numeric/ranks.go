@@ -0,0 +1,427 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Exact distributions of rank statistics under the null hypothesis, as in R's nmath
+// signrank.c and wilcox.c and the C code of cor.test (ckendall, AS 89 for Spearman's S).
+// The frequencies are counted by recursion and tabulated per call, so there is no
+// state shared between goroutines.
+
+// the number of subsets of 1..n with sum k, for k up to half of the maximum
+func signrankCounts(n int) []float64 {
+	c := n * (n + 1) / 4
+	w := make([]float64, c+1)
+	w[0] = 1
+	if c >= 1 {
+		w[1] = 1
+	}
+	for j := 2; j <= n; j++ {
+		end := j * (j + 1) / 2
+		if end > c {
+			end = c
+		}
+		for i := end; i >= j; i-- {
+			w[i] += w[i-j]
+		}
+	}
+	return w
+}
+
+func csignrank(w []float64, k int, n int) float64 {
+	u := n * (n + 1) / 2
+	if k < 0 || k > u {
+		return 0
+	}
+	if k > u/2 {
+		k = u - k
+	}
+	return w[k]
+}
+
+// the Wilcoxon signed rank statistic for n observations
+func Dsignrank(x float64, n float64, logD bool) float64 {
+	if isNaN(x, n) {
+		return x + n
+	}
+	n = forceint(n)
+	if n <= 0 {
+		return math.NaN()
+	}
+	if math.Abs(x-forceint(x)) > 1e-7 {
+		return d0(logD)
+	}
+	x = forceint(x)
+	if x < 0 || x > n*(n+1)/2 {
+		return d0(logD)
+	}
+	nn := int(n)
+	return dExp(math.Log(csignrank(signrankCounts(nn), int(x), nn))-n*ln2, logD)
+}
+
+func Psignrank(x float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, n) {
+		return x + n
+	}
+	if !isFinite(n) {
+		return math.NaN()
+	}
+	n = forceint(n)
+	if n <= 0 {
+		return math.NaN()
+	}
+	x = forceint(x + 1e-7)
+	if x < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if x >= n*(n+1)/2 {
+		return dt1(lowerTail, logP)
+	}
+	nn := int(n)
+	w := signrankCounts(nn)
+	f := math.Exp(-n * ln2)
+	p := 0.
+	if x <= n*(n+1)/4 {
+		for i := 0; i <= int(x); i++ {
+			p += csignrank(w, i, nn) * f
+		}
+	} else {
+		x = n*(n+1)/2 - x
+		for i := 0; i < int(x); i++ {
+			p += csignrank(w, i, nn) * f
+		}
+		lowerTail = !lowerTail
+	}
+	return dtVal(p, lowerTail, logP)
+}
+
+func Qsignrank(x float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, n) {
+		return x + n
+	}
+	if !isFinite(x) && !logP || !isFinite(n) {
+		return math.NaN()
+	}
+	if qP01Invalid(x, logP) {
+		return math.NaN()
+	}
+	n = forceint(n)
+	if n <= 0 {
+		return math.NaN()
+	}
+	if x == dt0(lowerTail, logP) {
+		return 0
+	}
+	if x == dt1(lowerTail, logP) {
+		return n * (n + 1) / 2
+	}
+	x = dtQIv(x, lowerTail, logP)
+	nn := int(n)
+	w := signrankCounts(nn)
+	f := math.Exp(-n * ln2)
+	p := 0.
+	q := 0
+	if x <= 0.5 {
+		x = x - 10*dblEpsilon
+		for {
+			p += csignrank(w, q, nn) * f
+			if p >= x {
+				break
+			}
+			q++
+		}
+	} else {
+		x = 1 - x + 10*dblEpsilon
+		for {
+			p += csignrank(w, q, nn) * f
+			if p > x {
+				q = nn*(nn+1)/2 - q
+				break
+			}
+			q++
+		}
+	}
+	return float64(q)
+}
+
+// the frequencies of the Mann-Whitney statistic for samples of sizes m and n
+type wilcoxCounts map[[3]int]float64
+
+func (w wilcoxCounts) count(k int, m int, n int) float64 {
+	u := m * n
+	if k < 0 || k > u {
+		return 0
+	}
+	c := u / 2
+	if k > c {
+		k = u - k
+	}
+	i, j := m, n
+	if m >= n {
+		i, j = n, m
+	}
+	if j == 0 {
+		if k == 0 {
+			return 1
+		}
+		return 0
+	}
+	// with k < j only the first k of the sorted y can be below any x
+	if k < j {
+		return w.count(k, i, k)
+	}
+	key := [3]int{i, j, k}
+	if r, ok := w[key]; ok {
+		return r
+	}
+	r := w.count(k-j, i-1, j) + w.count(k, i, j-1)
+	w[key] = r
+	return r
+}
+
+// choose(n, k) for integers as a product, rounded
+func chooseInt(n float64, k float64) float64 {
+	if n-k < k {
+		k = n - k
+	}
+	r := 1.
+	for j := 1.; j <= k; j++ {
+		r *= (n - k + j) / j
+	}
+	return math.Round(r)
+}
+
+// the Wilcoxon rank sum statistic for samples of sizes m and n
+func Dwilcox(x float64, m float64, n float64, logD bool) float64 {
+	if isNaN(x, m, n) {
+		return x + m + n
+	}
+	m, n = forceint(m), forceint(n)
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	if math.Abs(x-forceint(x)) > 1e-7 {
+		return d0(logD)
+	}
+	x = forceint(x)
+	if x < 0 || x > m*n {
+		return d0(logD)
+	}
+	c := wilcoxCounts{}.count(int(x), int(m), int(n))
+	if logD {
+		return math.Log(c) - math.Log(chooseInt(m+n, n))
+	}
+	return c / chooseInt(m+n, n)
+}
+
+func Pwilcox(q float64, m float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(q, m, n) {
+		return q + m + n
+	}
+	if !isFinite(m) || !isFinite(n) {
+		return math.NaN()
+	}
+	m, n = forceint(m), forceint(n)
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	q = math.Floor(q + 1e-7)
+	if q < 0 {
+		return dt0(lowerTail, logP)
+	}
+	if q >= m*n {
+		return dt1(lowerTail, logP)
+	}
+	w := wilcoxCounts{}
+	mm, nn := int(m), int(n)
+	c := chooseInt(m+n, n)
+	p := 0.
+	// the shorter range is summed
+	if q <= m*n/2 {
+		for i := 0; i <= int(q); i++ {
+			p += w.count(i, mm, nn) / c
+		}
+	} else {
+		q = m*n - q
+		for i := 0; i < int(q); i++ {
+			p += w.count(i, mm, nn) / c
+		}
+		lowerTail = !lowerTail
+	}
+	return dtVal(p, lowerTail, logP)
+}
+
+func Qwilcox(x float64, m float64, n float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, m, n) {
+		return x + m + n
+	}
+	if !isFinite(x) && !logP || !isFinite(m) || !isFinite(n) {
+		return math.NaN()
+	}
+	if qP01Invalid(x, logP) {
+		return math.NaN()
+	}
+	m, n = forceint(m), forceint(n)
+	if m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	if x == dt0(lowerTail, logP) {
+		return 0
+	}
+	if x == dt1(lowerTail, logP) {
+		return m * n
+	}
+	x = dtQIv(x, lowerTail, logP)
+	w := wilcoxCounts{}
+	mm, nn := int(m), int(n)
+	c := chooseInt(m+n, n)
+	p := 0.
+	q := 0
+	if x <= 0.5 {
+		x = x - 10*dblEpsilon
+		for {
+			p += w.count(q, mm, nn) / c
+			if p >= x {
+				break
+			}
+			q++
+		}
+	} else {
+		x = 1 - x + 10*dblEpsilon
+		for {
+			p += w.count(q, mm, nn) / c
+			if p > x {
+				q = mm*nn - q
+				break
+			}
+			q++
+		}
+	}
+	return float64(q)
+}
+
+// Pkendall gives P[T <= q] for the number T of concordant pairs among n observations.
+func Pkendall(q float64, n int) float64 {
+	q = math.Floor(q + 1e-7)
+	u := n * (n - 1) / 2
+	if q < 0 {
+		return 0
+	}
+	if q > float64(u) {
+		return 1
+	}
+	w := make([][]float64, n+1)
+	var ckendall func(k int, n int) float64
+	ckendall = func(k int, n int) float64 {
+		u := n * (n - 1) / 2
+		if k < 0 || k > u {
+			return 0
+		}
+		if w[n] == nil {
+			w[n] = make([]float64, u+1)
+			for i := range w[n] {
+				w[n][i] = -1
+			}
+		}
+		if w[n][k] < 0 {
+			if n == 1 {
+				w[n][k] = 0
+				if k == 0 {
+					w[n][k] = 1
+				}
+			} else {
+				s := 0.
+				for i := 0; i < n; i++ {
+					s += ckendall(k-i, n-1)
+				}
+				w[n][k] = s
+			}
+		}
+		return w[n][k]
+	}
+	p := 0.
+	for j := 0; j <= int(q); j++ {
+		p += ckendall(j, n)
+	}
+	nfac := 1.
+	for i := 2; i <= n; i++ {
+		nfac *= float64(i)
+	}
+	return p / nfac
+}
+
+// Prho gives P[S >= is] or with lowerTail P[S < is] for Spearman's statistic
+// S = sum (r_i - i)^2 of n observations, exactly for n <= 9 and otherwise by an
+// Edgeworth series (Best and Roberts, AS 89).
+func Prho(is float64, n int, lowerTail bool) float64 {
+	const (
+		c1  = 0.2274
+		c2  = 0.2531
+		c3  = 0.1745
+		c4  = 0.0758
+		c5  = 0.1033
+		c6  = 0.3932
+		c7  = 0.0879
+		c8  = 0.0151
+		c9  = 0.0072
+		c10 = 0.0831
+		c11 = 0.0131
+		c12 = 4.6e-4
+	)
+	pv := 1.
+	if lowerTail {
+		pv = 0
+	}
+	if n <= 1 || is <= 0 {
+		return pv
+	}
+	n3 := float64(n)
+	n3 *= (n3*n3 - 1) / 3 // the maximum of S
+	if is > n3 {
+		return 1 - pv
+	}
+	if n <= 9 {
+		// count the permutations with S >= is
+		l := make([]int, n)
+		for i := range l {
+			l[i] = i + 1
+		}
+		nfac, ifr := 0, 0
+		var permute func(k int)
+		permute = func(k int) {
+			if k == n {
+				nfac++
+				ise := 0
+				for i, r := range l {
+					d := i + 1 - r
+					ise += d * d
+				}
+				if is <= float64(ise) {
+					ifr++
+				}
+				return
+			}
+			for i := k; i < n; i++ {
+				l[k], l[i] = l[i], l[k]
+				permute(k + 1)
+				l[k], l[i] = l[i], l[k]
+			}
+		}
+		permute(0)
+		if lowerTail {
+			ifr = nfac - ifr
+		}
+		return float64(ifr) / float64(nfac)
+	}
+	b := 1 / float64(n)
+	x := (6*(is-1)*b/(float64(n)*float64(n)-1) - 1) * math.Sqrt(1/b-1)
+	y := x * x
+	u := x * b * (c1 + b*(c2+c3*b) + y*(-c4+b*(c5+c6*b)-y*b*(c7+c8*b-y*(c9-c10*b+y*b*(c11-c12*y)))))
+	y = u / math.Exp(y/2)
+	if lowerTail {
+		y = -y
+	}
+	pv = y + Pnorm(x, 0, 1, lowerTail, false)
+	return math.Max(0, math.Min(1, pv))
+}
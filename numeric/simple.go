@@ -0,0 +1,336 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Distributions with closed forms: uniform, exponential, Weibull, Cauchy and logistic.
+
+func Dunif(x float64, a float64, b float64, logD bool) float64 {
+	if isNaN(x, a, b) {
+		return x + a + b
+	}
+	if b <= a {
+		return math.NaN()
+	}
+	if a <= x && x <= b {
+		if logD {
+			return -math.Log(b - a)
+		}
+		return 1 / (b - a)
+	}
+	return d0(logD)
+}
+
+func Punif(x float64, a float64, b float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, a, b) {
+		return x + a + b
+	}
+	if b < a || !isFinite(a) || !isFinite(b) {
+		return math.NaN()
+	}
+	if x >= b {
+		return dt1(lowerTail, logP)
+	}
+	if x <= a {
+		return dt0(lowerTail, logP)
+	}
+	if lowerTail {
+		return dVal((x-a)/(b-a), logP)
+	}
+	return dVal((b-x)/(b-a), logP)
+}
+
+func Qunif(p float64, a float64, b float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, a, b) {
+		return p + a + b
+	}
+	if qP01Invalid(p, logP) || !isFinite(a) || !isFinite(b) || b < a {
+		return math.NaN()
+	}
+	if b == a {
+		return a
+	}
+	return a + dtQIv(p, lowerTail, logP)*(b-a)
+}
+
+func Dexp(x float64, scale float64, logD bool) float64 {
+	if isNaN(x, scale) {
+		return x + scale
+	}
+	if scale <= 0 {
+		return math.NaN()
+	}
+	if x < 0 {
+		return d0(logD)
+	}
+	if logD {
+		return -x/scale - math.Log(scale)
+	}
+	return math.Exp(-x/scale) / scale
+}
+
+func Pexp(x float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, scale) {
+		return x + scale
+	}
+	if scale < 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return dt0(lowerTail, logP)
+	}
+	return expTail(-(x / scale), lowerTail, logP)
+}
+
+// the distribution function from the log of the upper tail
+func expTail(x float64, lowerTail bool, logP bool) float64 {
+	if !lowerTail {
+		return dExp(x, logP)
+	}
+	if logP {
+		return log1Exp(x)
+	}
+	return -math.Expm1(x)
+}
+
+func Qexp(p float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, scale) {
+		return p + scale
+	}
+	if scale < 0 || qP01Invalid(p, logP) {
+		return math.NaN()
+	}
+	if p == dt0(lowerTail, logP) {
+		return 0
+	}
+	return -scale * dtClog(p, lowerTail, logP)
+}
+
+func Dweibull(x float64, shape float64, scale float64, logD bool) float64 {
+	if isNaN(x, shape, scale) {
+		return x + shape + scale
+	}
+	if shape <= 0 || scale <= 0 {
+		return math.NaN()
+	}
+	if x < 0 || !isFinite(x) {
+		return d0(logD)
+	}
+	if x == 0 && shape < 1 {
+		return posInf
+	}
+	tmp1 := math.Pow(x/scale, shape-1)
+	tmp2 := tmp1 * (x / scale)
+	if logD {
+		return -tmp2 + math.Log(shape*tmp1/scale)
+	}
+	return shape * tmp1 * math.Exp(-tmp2) / scale
+}
+
+func Pweibull(x float64, shape float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, shape, scale) {
+		return x + shape + scale
+	}
+	if shape <= 0 || scale <= 0 {
+		return math.NaN()
+	}
+	if x <= 0 {
+		return dt0(lowerTail, logP)
+	}
+	return expTail(-math.Pow(x/scale, shape), lowerTail, logP)
+}
+
+func Qweibull(p float64, shape float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, shape, scale) {
+		return p + shape + scale
+	}
+	if shape <= 0 || scale <= 0 {
+		return math.NaN()
+	}
+	if v, done := qP01Boundaries(p, 0, posInf, lowerTail, logP); done {
+		return v
+	}
+	return scale * math.Pow(-dtClog(p, lowerTail, logP), 1/shape)
+}
+
+func Dcauchy(x float64, location float64, scale float64, logD bool) float64 {
+	if isNaN(x, location, scale) {
+		return x + location + scale
+	}
+	if scale <= 0 {
+		return math.NaN()
+	}
+	y := (x - location) / scale
+	if logD {
+		return -math.Log(math.Pi * scale * (1 + y*y))
+	}
+	return 1 / (math.Pi * scale * (1 + y*y))
+}
+
+func Pcauchy(x float64, location float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, location, scale) {
+		return x + location + scale
+	}
+	if scale <= 0 {
+		return math.NaN()
+	}
+	x = (x - location) / scale
+	if math.IsNaN(x) {
+		return x
+	}
+	if !isFinite(x) {
+		if x < 0 {
+			return dt0(lowerTail, logP)
+		}
+		return dt1(lowerTail, logP)
+	}
+	if !lowerTail {
+		x = -x
+	}
+	if math.Abs(x) > 1 {
+		y := math.Atan(1/x) / math.Pi
+		if x > 0 {
+			return dClog(y, logP)
+		}
+		return dVal(-y, logP)
+	}
+	return dVal(0.5+math.Atan(x)/math.Pi, logP)
+}
+
+// tan(pi*x), exact at multiples of 1/4
+func tanpi(x float64) float64 {
+	x = math.Mod(x, 1)
+	if x <= -0.5 {
+		x++
+	} else if x > 0.5 {
+		x--
+	}
+	switch x {
+	case 0:
+		return 0
+	case 0.5:
+		return math.NaN()
+	case 0.25:
+		return 1
+	case -0.25:
+		return -1
+	}
+	return math.Tan(math.Pi * x)
+}
+
+func Qcauchy(p float64, location float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, location, scale) {
+		return p + location + scale
+	}
+	if qP01Invalid(p, logP) {
+		return math.NaN()
+	}
+	if scale <= 0 || !isFinite(scale) {
+		if scale == 0 {
+			return location
+		}
+		return math.NaN()
+	}
+	inf := func() float64 {
+		if lowerTail {
+			return location + scale*posInf
+		}
+		return location - scale*posInf
+	}
+	if logP {
+		if p > -1 {
+			if p == 0 {
+				return inf()
+			}
+			lowerTail = !lowerTail
+			p = -math.Expm1(p)
+		} else {
+			p = math.Exp(p)
+		}
+	} else if p > 0.5 {
+		if p == 1 {
+			return inf()
+		}
+		p = 1 - p
+		lowerTail = !lowerTail
+	}
+	if p == 0.5 {
+		return location
+	}
+	if p == 0 {
+		return -inf()
+	}
+	if lowerTail {
+		return location - scale/tanpi(p)
+	}
+	return location + scale/tanpi(p)
+}
+
+func Dlogis(x float64, location float64, scale float64, logD bool) float64 {
+	if isNaN(x, location, scale) {
+		return x + location + scale
+	}
+	if scale <= 0 {
+		return math.NaN()
+	}
+	x = math.Abs((x - location) / scale)
+	e := math.Exp(-x)
+	f := 1 + e
+	if logD {
+		return -(x + math.Log(scale*f*f))
+	}
+	return e / (scale * f * f)
+}
+
+func Plogis(x float64, location float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(x, location, scale) {
+		return x + location + scale
+	}
+	if scale <= 0 {
+		return math.NaN()
+	}
+	x = (x - location) / scale
+	if math.IsNaN(x) {
+		return x
+	}
+	if !isFinite(x) {
+		if x > 0 {
+			return dt1(lowerTail, logP)
+		}
+		return dt0(lowerTail, logP)
+	}
+	if lowerTail {
+		x = -x
+	}
+	if logP {
+		return -log1pexp(x)
+	}
+	return 1 / (1 + math.Exp(x))
+}
+
+func Qlogis(p float64, location float64, scale float64, lowerTail bool, logP bool) float64 {
+	if isNaN(p, location, scale) {
+		return p + location + scale
+	}
+	if v, done := qP01Boundaries(p, negInf, posInf, lowerTail, logP); done {
+		return v
+	}
+	if scale < 0 {
+		return math.NaN()
+	}
+	if scale == 0 {
+		return location
+	}
+	switch {
+	case logP && lowerTail:
+		p = p - log1Exp(p)
+	case logP:
+		p = log1Exp(p) - p
+	case lowerTail:
+		p = math.Log(p / (1 - p))
+	default:
+		p = math.Log((1 - p) / p)
+	}
+	return location + scale*p
+}
@@ -0,0 +1,272 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Smoothing splines as R's sbart.c: cubic B-splines on knots in [0, 1] are fitted by
+// penalized least squares, the penalty is the integral of the squared second
+// derivative. The smoothing parameter spar gives lambda = ratio 256^(3 spar - 1), where
+// ratio relates the traces of the weighted cross products and of the penalty matrix.
+// Without spar it is found by Brent's minimization of the criterion over spar.
+
+// BSplineBasis returns the derivative deriv of the four cubic B-splines, which do not
+// vanish on [t[left], t[left+1]), at x.
+func BSplineBasis(t []float64, left int, x float64, deriv int) []float64 {
+	b := []float64{1}
+	for k := 1; k < 4; k++ {
+		next := make([]float64, k+1)
+		for j := 0; j < k; j++ {
+			i := left - k + 1 + j
+			d := t[i+k] - t[i]
+			if d <= 0 {
+				continue
+			}
+			if k >= 4-deriv {
+				v := float64(k) * b[j] / d
+				next[j] -= v
+				next[j+1] += v
+			} else {
+				v := b[j] / d
+				next[j] += (t[i+k] - x) * v
+				next[j+1] += (x - t[i]) * v
+			}
+		}
+		b = next
+	}
+	return b
+}
+
+// KnotInterval returns left with t[left] <= x < t[left+1] for the nk coefficients,
+// the last interval includes its right end.
+func KnotInterval(t []float64, nk int, x float64) int {
+	left := FindInterval(t[:nk], x) - 1
+	if left < 3 {
+		left = 3
+	}
+	return left
+}
+
+// BSplineValue evaluates the spline with the coefficients coef on the knots t.
+func BSplineValue(t []float64, coef []float64, x float64, deriv int) float64 {
+	if deriv > 3 {
+		return 0
+	}
+	left := KnotInterval(t, len(coef), x)
+	r := 0.
+	for j, v := range BSplineBasis(t, left, x, deriv) {
+		r += coef[left-3+j] * v
+	}
+	return r
+}
+
+type SmoothingSpline struct {
+	Knots  []float64
+	x      []float64
+	y      []float64
+	w      []float64
+	ssw    float64 // the weighted sum of squares within tied x, added to the RSS
+	xwx    *Matrix
+	xwy    []float64
+	sigma  *Matrix
+	Ratio  float64
+	Lambda float64 // of the last fit
+	Coef   []float64
+	Fitted []float64
+	Lev    []float64
+	Df     float64
+	Crit   float64
+}
+
+// NewSmoothingSpline prepares the fit of the sorted x in [0, 1] with the weights w
+// and the interior knots.
+func NewSmoothingSpline(x []float64, y []float64, w []float64, ssw float64, knots []float64) *SmoothingSpline {
+	t := make([]float64, 0, len(knots)+6)
+	t = append(t, knots[0], knots[0], knots[0])
+	t = append(t, knots...)
+	t = append(t, knots[len(knots)-1], knots[len(knots)-1], knots[len(knots)-1])
+	nk := len(knots) + 2
+	s := &SmoothingSpline{Knots: t, x: x, y: y, w: w, ssw: ssw, xwx: NewMatrix(nk, nk), xwy: make([]float64, nk), sigma: NewMatrix(nk, nk)}
+	for i, v := range x {
+		left := KnotInterval(t, nk, v)
+		b := BSplineBasis(t, left, v, 0)
+		for j := range b {
+			s.xwy[left-3+j] += w[i] * b[j] * y[i]
+			for k := range b {
+				s.xwx.Data[(left-3+j)+nk*(left-3+k)] += w[i] * b[j] * b[k]
+			}
+		}
+	}
+	// B'' is linear between knots, the integrals use 0.333 as sgram.f
+	for left := 3; left < nk; left++ {
+		h := t[left+1] - t[left]
+		if h <= 0 {
+			continue
+		}
+		y1 := BSplineBasis(t, left, t[left], 2)
+		y2 := BSplineBasis(t, left, t[left+1], 2)
+		for j := range y2 {
+			y2[j] -= y1[j]
+		}
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				s.sigma.Data[(left-3+j)+nk*(left-3+k)] += h * (y1[j]*y1[k] + (y2[j]*y1[k]+y2[k]*y1[j])*.5 + y2[j]*y2[k]*.333)
+			}
+		}
+	}
+	t1, t2 := 0., 0.
+	for i := 2; i < nk-3; i++ {
+		t1 += s.xwx.At(i, i)
+		t2 += s.sigma.At(i, i)
+	}
+	s.Ratio = t1 / t2
+	return s
+}
+
+// LambdaOf returns the smoothing parameter for spar.
+func (s *SmoothingSpline) LambdaOf(spar float64) float64 {
+	return s.Ratio * math.Pow(16, spar*6-2)
+}
+
+// Fit solves the penalized least squares for lambda and computes the criterion:
+// 1 generalized and 2 ordinary cross-validation, 3 the squared distance of the
+// degrees of freedom to dofoff. It returns false if the system is not positive definite.
+func (s *SmoothingSpline) Fit(lambda float64, icrit int, dofoff float64, penalty float64) bool {
+	nk := len(s.xwy)
+	a := s.xwx.Clone()
+	for k := range a.Data {
+		a.Data[k] += lambda * s.sigma.Data[k]
+	}
+	r, info := Cholesky(a)
+	if info != 0 {
+		return false
+	}
+	rhs := &Matrix{Rows: nk, Cols: 1, Data: append([]float64(nil), s.xwy...)}
+	z, _ := TriangularSolve(r, rhs, nk, true, true)
+	c, _ := TriangularSolve(r, z, nk, true, false)
+	s.Lambda, s.Coef = lambda, c.Data
+	inv := Chol2inv(r, nk)
+	s.Fitted = make([]float64, len(s.x))
+	s.Lev = make([]float64, len(s.x))
+	s.Df = 0
+	for i, v := range s.x {
+		left := KnotInterval(s.Knots, nk, v)
+		b := BSplineBasis(s.Knots, left, v, 0)
+		for j := range b {
+			s.Fitted[i] += b[j] * s.Coef[left-3+j]
+			for k := range b {
+				s.Lev[i] += b[j] * inv.At(left-3+j, left-3+k) * b[k]
+			}
+		}
+		s.Lev[i] *= s.w[i]
+		s.Df += s.Lev[i]
+	}
+	switch icrit {
+	case 1:
+		rss, sumw := s.ssw, 0.
+		for i := range s.x {
+			rss += s.w[i] * (s.y[i] - s.Fitted[i]) * (s.y[i] - s.Fitted[i])
+			sumw += s.w[i]
+		}
+		d := 1 - (dofoff+penalty*s.Df)/sumw
+		s.Crit = rss / sumw / (d * d)
+	case 2:
+		s.Crit = 0
+		for i := range s.x {
+			e := (s.y[i] - s.Fitted[i]) / (1 - s.Lev[i])
+			s.Crit += s.w[i] * e * e
+		}
+		s.Crit /= float64(len(s.x))
+	case 3:
+		s.Crit = 3 + (dofoff-s.Df)*(dofoff-s.Df)
+	}
+	return true
+}
+
+// MinimizeSpar returns the spar in [low, high] with the least criterion and the number
+// of iterations of the golden section search with parabolic steps of sbart.c. As there,
+// the fit is that of the last spar tried, the criterion that of the spar returned.
+func (s *SmoothingSpline) MinimizeSpar(low float64, high float64, tol float64, eps float64, maxit int, icrit int, dofoff float64, penalty float64) (float64, int) {
+	const gold = 0.381966011250105151795413165634
+	crit := func(spar float64) float64 {
+		if !s.Fit(s.LambdaOf(spar), icrit, dofoff, penalty) || math.IsNaN(s.Crit) || math.IsInf(s.Crit, 0) {
+			return 2e100
+		}
+		return s.Crit
+	}
+	a, b := low, high
+	v := a + gold*(b-a)
+	w, x := v, v
+	d, e := 0., 0.
+	fx := crit(x)
+	fv, fw := fx, fx
+	iter := 0
+	for {
+		xm := (a + b) / 2
+		tol1 := eps*math.Abs(x) + tol/3
+		tol2 := tol1 * 2
+		iter++
+		if math.Abs(x-xm) <= tol2-(b-a)/2 || iter > maxit {
+			break
+		}
+		parabolic := false
+		if math.Abs(e) > tol1 {
+			r := (x - w) * (fx - fv)
+			q := (x - v) * (fx - fw)
+			p := (x-v)*q - (x-w)*r
+			q = (q - r) * 2
+			if q > 0 {
+				p = -p
+			}
+			q = math.Abs(q)
+			r = e
+			e = d
+			parabolic = math.Abs(p) < math.Abs(.5*q*r) && q*(a-x) < p && p < q*(b-x)
+			if parabolic {
+				d = p / q
+				u := x + d
+				if u-a < tol2 || b-u < tol2 {
+					d = math.Copysign(tol1, xm-x)
+				}
+			}
+		}
+		if !parabolic {
+			if x >= xm {
+				e = a - x
+			} else {
+				e = b - x
+			}
+			d = gold * e
+		}
+		u := x + math.Copysign(tol1, d)
+		if math.Abs(d) >= tol1 {
+			u = x + d
+		}
+		fu := crit(u)
+		if fu <= fx {
+			if u >= x {
+				a = x
+			} else {
+				b = x
+			}
+			v, fv = w, fw
+			w, fw = x, fx
+			x, fx = u, fu
+		} else {
+			if u < x {
+				a = u
+			} else {
+				b = u
+			}
+			switch {
+			case fu <= fw || w == x:
+				v, fv = w, fw
+				w, fw = u, fu
+			case fu <= fv || v == x || v == w:
+				v, fv = u, fu
+			}
+		}
+	}
+	s.Crit = fx
+	return x, iter
+}
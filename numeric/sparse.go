@@ -0,0 +1,292 @@
+package numeric
+
+import (
+	"sort"
+)
+
+// Sparse matrices in compressed sparse column form, as the class dgCMatrix of R's
+// Matrix package. The non-zeros of column j are stored at P[j]:P[j+1] of the row
+// indices I and the values X, ordered by row, so that storage and the operations
+// below are proportional to the number of non-zeros rather than to the extents.
+
+type Sparse struct {
+	Rows int
+	Cols int
+	P    []int
+	I    []int
+	X    []float64
+}
+
+type sparseEntries struct {
+	i []int
+	x []float64
+}
+
+func (e sparseEntries) Len() int           { return len(e.i) }
+func (e sparseEntries) Less(a, b int) bool { return e.i[a] < e.i[b] }
+func (e sparseEntries) Swap(a, b int) {
+	e.i[a], e.i[b] = e.i[b], e.i[a]
+	e.x[a], e.x[b] = e.x[b], e.x[a]
+}
+
+// NewSparse collects the values x at the zero based positions (i, j), duplicates are
+// summed and zeros are dropped.
+func NewSparse(rows int, cols int, i []int, j []int, x []float64) *Sparse {
+	p := make([]int, cols+1)
+	for _, c := range j {
+		p[c+1]++
+	}
+	for c := 0; c < cols; c++ {
+		p[c+1] += p[c]
+	}
+	next := append([]int(nil), p[:cols]...)
+	ci, cx := make([]int, len(i)), make([]float64, len(i))
+	for k, c := range j {
+		ci[next[c]], cx[next[c]] = i[k], x[k]
+		next[c]++
+	}
+	s := &Sparse{Rows: rows, Cols: cols, P: make([]int, cols+1)}
+	for c := 0; c < cols; c++ {
+		column := sparseEntries{ci[p[c]:p[c+1]], cx[p[c]:p[c+1]]}
+		sort.Stable(column)
+		for k := 0; k < column.Len(); k++ {
+			if n := len(s.I); n > s.P[c] && s.I[n-1] == column.i[k] {
+				s.X[n-1] += column.x[k]
+			} else {
+				s.I = append(s.I, column.i[k])
+				s.X = append(s.X, column.x[k])
+			}
+		}
+		s.dropZeros(c)
+		s.P[c+1] = len(s.I)
+	}
+	return s
+}
+
+// dropZeros removes the zeros of column c, which are the last entries stored
+func (s *Sparse) dropZeros(c int) {
+	n := s.P[c]
+	for k := s.P[c]; k < len(s.I); k++ {
+		if s.X[k] != 0 {
+			s.I[n], s.X[n] = s.I[k], s.X[k]
+			n++
+		}
+	}
+	s.I, s.X = s.I[:n], s.X[:n]
+}
+
+func SparseFromDense(m *Matrix) *Sparse {
+	s := &Sparse{Rows: m.Rows, Cols: m.Cols, P: make([]int, m.Cols+1)}
+	for c := 0; c < m.Cols; c++ {
+		for i, v := range m.Col(c) {
+			if v != 0 {
+				s.I = append(s.I, i)
+				s.X = append(s.X, v)
+			}
+		}
+		s.P[c+1] = len(s.I)
+	}
+	return s
+}
+
+func (s *Sparse) Dense() *Matrix {
+	m := NewMatrix(s.Rows, s.Cols)
+	for c := 0; c < s.Cols; c++ {
+		for k := s.P[c]; k < s.P[c+1]; k++ {
+			m.Set(s.I[k], c, s.X[k])
+		}
+	}
+	return m
+}
+
+// Nnz returns the number of stored non-zeros.
+func (s *Sparse) Nnz() int {
+	return s.P[s.Cols]
+}
+
+// Stored returns the position of the element (i, j) in I and X and whether it is stored.
+func (s *Sparse) Stored(i int, j int) (int, bool) {
+	rows := s.I[s.P[j]:s.P[j+1]]
+	k := sort.SearchInts(rows, i)
+	return s.P[j] + k, k < len(rows) && rows[k] == i
+}
+
+func (s *Sparse) At(i int, j int) float64 {
+	if k, ok := s.Stored(i, j); ok {
+		return s.X[k]
+	}
+	return 0
+}
+
+// T returns the transpose, its columns are filled in the order of the rows.
+func (s *Sparse) T() *Sparse {
+	r := &Sparse{Rows: s.Cols, Cols: s.Rows, P: make([]int, s.Rows+1), I: make([]int, s.Nnz()), X: make([]float64, s.Nnz())}
+	for _, i := range s.I {
+		r.P[i+1]++
+	}
+	for i := 0; i < s.Rows; i++ {
+		r.P[i+1] += r.P[i]
+	}
+	next := append([]int(nil), r.P[:s.Rows]...)
+	for c := 0; c < s.Cols; c++ {
+		for k := s.P[c]; k < s.P[c+1]; k++ {
+			n := next[s.I[k]]
+			r.I[n], r.X[n] = c, s.X[k]
+			next[s.I[k]]++
+		}
+	}
+	return r
+}
+
+// Mul returns the sparse product s b, accumulating each column in a dense work vector.
+func (s *Sparse) Mul(b *Sparse) *Sparse {
+	r := &Sparse{Rows: s.Rows, Cols: b.Cols, P: make([]int, b.Cols+1)}
+	work := make([]float64, s.Rows)
+	mark := make([]int, s.Rows)
+	for i := range mark {
+		mark[i] = -1
+	}
+	for c := 0; c < b.Cols; c++ {
+		start := len(r.I)
+		for kb := b.P[c]; kb < b.P[c+1]; kb++ {
+			k, v := b.I[kb], b.X[kb]
+			for ks := s.P[k]; ks < s.P[k+1]; ks++ {
+				i := s.I[ks]
+				if mark[i] != c {
+					mark[i] = c
+					work[i] = 0
+					r.I = append(r.I, i)
+				}
+				work[i] += s.X[ks] * v
+			}
+		}
+		sort.Ints(r.I[start:])
+		for _, i := range r.I[start:] {
+			r.X = append(r.X, work[i])
+		}
+		r.dropZeros(c)
+		r.P[c+1] = len(r.I)
+	}
+	return r
+}
+
+// MulDense returns the dense product s b.
+func (s *Sparse) MulDense(b *Matrix) *Matrix {
+	r := NewMatrix(s.Rows, b.Cols)
+	for c := 0; c < b.Cols; c++ {
+		col := r.Col(c)
+		for k := 0; k < s.Cols; k++ {
+			v := b.At(k, c)
+			if v == 0 {
+				continue
+			}
+			for ks := s.P[k]; ks < s.P[k+1]; ks++ {
+				col[s.I[ks]] += s.X[ks] * v
+			}
+		}
+	}
+	return r
+}
+
+// DenseMul returns the dense product a s.
+func DenseMul(a *Matrix, s *Sparse) *Matrix {
+	r := NewMatrix(a.Rows, s.Cols)
+	for c := 0; c < s.Cols; c++ {
+		col := r.Col(c)
+		for ks := s.P[c]; ks < s.P[c+1]; ks++ {
+			v := s.X[ks]
+			for i, w := range a.Col(s.I[ks]) {
+				col[i] += w * v
+			}
+		}
+	}
+	return r
+}
+
+// RowSums and ColSums add the non-zeros, skipping missing values with narm.
+func (s *Sparse) RowSums(narm bool) []float64 {
+	r := make([]float64, s.Rows)
+	for k, i := range s.I {
+		if !narm || s.X[k] == s.X[k] {
+			r[i] += s.X[k]
+		}
+	}
+	return r
+}
+
+func (s *Sparse) ColSums(narm bool) []float64 {
+	r := make([]float64, s.Cols)
+	for c := range r {
+		for _, v := range s.X[s.P[c]:s.P[c+1]] {
+			if !narm || v == v {
+				r[c] += v
+			}
+		}
+	}
+	return r
+}
+
+// Map applies f with f(0) = 0 to the non-zeros.
+func (s *Sparse) Map(f func(float64) float64) *Sparse {
+	r := &Sparse{Rows: s.Rows, Cols: s.Cols, P: make([]int, s.Cols+1)}
+	for c := 0; c < s.Cols; c++ {
+		for k := s.P[c]; k < s.P[c+1]; k++ {
+			r.I = append(r.I, s.I[k])
+			r.X = append(r.X, f(s.X[k]))
+		}
+		r.dropZeros(c)
+		r.P[c+1] = len(r.I)
+	}
+	return r
+}
+
+// Merge applies f with f(0, 0) = 0 elementwise to s and b of the same extents,
+// merging their columns.
+func (s *Sparse) Merge(b *Sparse, f func(float64, float64) float64) *Sparse {
+	r := &Sparse{Rows: s.Rows, Cols: s.Cols, P: make([]int, s.Cols+1)}
+	for c := 0; c < s.Cols; c++ {
+		ks, kb := s.P[c], b.P[c]
+		for ks < s.P[c+1] || kb < b.P[c+1] {
+			switch {
+			case kb == b.P[c+1] || ks < s.P[c+1] && s.I[ks] < b.I[kb]:
+				r.I = append(r.I, s.I[ks])
+				r.X = append(r.X, f(s.X[ks], 0))
+				ks++
+			case ks == s.P[c+1] || b.I[kb] < s.I[ks]:
+				r.I = append(r.I, b.I[kb])
+				r.X = append(r.X, f(0, b.X[kb]))
+				kb++
+			default:
+				r.I = append(r.I, s.I[ks])
+				r.X = append(r.X, f(s.X[ks], b.X[kb]))
+				ks++
+				kb++
+			}
+		}
+		r.dropZeros(c)
+		r.P[c+1] = len(r.I)
+	}
+	return r
+}
+
+// Submatrix selects the zero based rows and columns in the given order, which
+// may repeat them.
+func (s *Sparse) Submatrix(rows []int, cols []int) *Sparse {
+	positions := make(map[int][]int, len(rows))
+	for n, i := range rows {
+		positions[i] = append(positions[i], n)
+	}
+	r := &Sparse{Rows: len(rows), Cols: len(cols), P: make([]int, len(cols)+1)}
+	for n, c := range cols {
+		start := len(r.I)
+		for k := s.P[c]; k < s.P[c+1]; k++ {
+			for _, i := range positions[s.I[k]] {
+				r.I = append(r.I, i)
+				r.X = append(r.X, s.X[k])
+			}
+		}
+		sort.Sort(sparseEntries{r.I[start:], r.X[start:]})
+		r.P[n+1] = len(r.I)
+	}
+	return r
+}
@@ -0,0 +1,190 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Saddle point expansions of Loader (2000) for binomial and Poisson probabilities,
+// on which most densities are built, and the logarithm of the beta function.
+
+var sferrHalves = [31]float64{
+	0.0,                           // n=0 - wrong, place holder only
+	0.1534264097200273452913848,   // 0.5
+	0.0810614667953272582196702,   // 1.0
+	0.0548141210519176538961390,   // 1.5
+	0.0413406959554092940938221,   // 2.0
+	0.03316287351993628748511048,  // 2.5
+	0.02767792568499833914878929,  // 3.0
+	0.02374616365629749597132920,  // 3.5
+	0.02079067210376509311152277,  // 4.0
+	0.01848845053267318523077934,  // 4.5
+	0.01664469118982119216319487,  // 5.0
+	0.01513497322191737887351255,  // 5.5
+	0.01387612882307074799874573,  // 6.0
+	0.01281046524292022692424986,  // 6.5
+	0.01189670994589177009505572,  // 7.0
+	0.01110455975820691732662991,  // 7.5
+	0.010411265261972096497478567, // 8.0
+	0.009799416126158803298389475, // 8.5
+	0.009255462182712732917728637, // 9.0
+	0.008768700134139385462952823, // 9.5
+	0.008330563433362871256469318, // 10.0
+	0.007934114564314020547248100, // 10.5
+	0.007573675487951840794972024, // 11.0
+	0.007244554301320383179543912, // 11.5
+	0.006942840107209529865664152, // 12.0
+	0.006665247032707682442354394, // 12.5
+	0.006408994188004207068439631, // 13.0
+	0.006171712263039457647532867, // 13.5
+	0.005951370112758847735624416, // 14.0
+	0.005746216513010115682023589, // 14.5
+	0.005554733551962801371038690, // 15.0
+}
+
+// stirlerr(n) = log(n!) - log(sqrt(2*pi*n)*(n/e)^n)
+func stirlerr(n float64) float64 {
+	const (
+		s0 = 0.083333333333333333333        // 1/12
+		s1 = 0.00277777777777777777778      // 1/360
+		s2 = 0.00079365079365079365079365   // 1/1260
+		s3 = 0.000595238095238095238095238  // 1/1680
+		s4 = 0.0008417508417508417508417508 // 1/1188
+	)
+	if n <= 15 {
+		nn := n + n
+		if nn == math.Trunc(nn) {
+			return sferrHalves[int(nn)]
+		}
+		lg, _ := math.Lgamma(n + 1)
+		return lg - (n+0.5)*math.Log(n) + n - lnSqrt2Pi
+	}
+	nn := n * n
+	switch {
+	case n > 500:
+		return (s0 - s1/nn) / n
+	case n > 80:
+		return (s0 - (s1-s2/nn)/nn) / n
+	case n > 35:
+		return (s0 - (s1-(s2-s3/nn)/nn)/nn) / n
+	}
+	return (s0 - (s1-(s2-(s3-s4/nn)/nn)/nn)/nn) / n
+}
+
+// bd0(x, np) = x log(x/np) + np - x, computed without cancellation
+func bd0(x float64, np float64) float64 {
+	if !isFinite(x) || !isFinite(np) || np == 0 {
+		return math.NaN()
+	}
+	if math.Abs(x-np) < 0.1*(x+np) {
+		v := (x - np) / (x + np)
+		s := (x - np) * v
+		if math.Abs(s) < dblMin {
+			return s
+		}
+		ej := 2 * x * v
+		v = v * v
+		for j := 1; j < 1000; j++ {
+			ej *= v
+			s1 := s + ej/float64(2*j+1)
+			if s1 == s {
+				return s1
+			}
+			s = s1
+		}
+	}
+	return x*math.Log(x/np) + np - x
+}
+
+// the binomial probability of x in n trials for real x and n, q = 1 - p
+func dbinomRaw(x float64, n float64, p float64, q float64, logP bool) float64 {
+	if p == 0 {
+		if x == 0 {
+			return d1(logP)
+		}
+		return d0(logP)
+	}
+	if q == 0 {
+		if x == n {
+			return d1(logP)
+		}
+		return d0(logP)
+	}
+	if x == 0 {
+		if n == 0 {
+			return d1(logP)
+		}
+		lc := n * math.Log(q)
+		if p < 0.1 {
+			lc = -bd0(n, n*q) - n*p
+		}
+		return dExp(lc, logP)
+	}
+	if x == n {
+		lc := n * math.Log(p)
+		if q < 0.1 {
+			lc = -bd0(n, n*p) - n*q
+		}
+		return dExp(lc, logP)
+	}
+	if x < 0 || x > n {
+		return d0(logP)
+	}
+	lc := stirlerr(n) - stirlerr(x) - stirlerr(n-x) - bd0(x, n*p) - bd0(n-x, n*q)
+	lf := ln2Pi + math.Log(x) + math.Log1p(-x/n)
+	return dExp(lc-0.5*lf, logP)
+}
+
+// the Poisson probability of x for real x
+func dpoisRaw(x float64, lambda float64, logP bool) float64 {
+	switch {
+	case lambda == 0:
+		if x == 0 {
+			return d1(logP)
+		}
+		return d0(logP)
+	case !isFinite(lambda) || x < 0:
+		return d0(logP)
+	case x <= lambda*dblMin:
+		return dExp(-lambda, logP)
+	case lambda < x*dblMin:
+		if math.IsInf(x, 1) {
+			return d0(logP)
+		}
+		lg, _ := math.Lgamma(x + 1)
+		return dExp(-lambda+x*math.Log(lambda)-lg, logP)
+	}
+	return dFexp(2*math.Pi*x, -stirlerr(x)-bd0(x, lambda), logP)
+}
+
+// log(beta(a, b)) with the corrections of Stirling's formula for large arguments
+func Lbeta(a float64, b float64) float64 {
+	if isNaN(a, b) {
+		return a + b
+	}
+	p, q := math.Min(a, b), math.Max(a, b)
+	switch {
+	case p < 0:
+		return math.NaN()
+	case p == 0:
+		return posInf
+	case math.IsInf(q, 1):
+		return negInf
+	case p >= 10:
+		corr := stirlerr(p) + stirlerr(q) - stirlerr(p+q)
+		return math.Log(q)*-0.5 + lnSqrt2Pi + corr + (p-0.5)*math.Log(p/(p+q)) + q*math.Log1p(-p/(p+q))
+	case q >= 10:
+		corr := stirlerr(q) - stirlerr(p+q)
+		lg, _ := math.Lgamma(p)
+		return lg + corr + p - p*math.Log(p+q) + (q-0.5)*math.Log1p(-p/(p+q))
+	case q < 1e-306:
+		lp, _ := math.Lgamma(p)
+		lq, _ := math.Lgamma(q)
+		lpq, _ := math.Lgamma(p + q)
+		return lp + (lq - lpq)
+	}
+	return math.Log(math.Gamma(p) * (math.Gamma(q) / math.Gamma(p+q)))
+}
+
+func lfastchoose(n float64, k float64) float64 {
+	return -math.Log(n+1) - Lbeta(n-k+1, k+1)
+}
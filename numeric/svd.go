@@ -0,0 +1,356 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Singular value decomposition by Householder bidiagonalization and the
+// implicitly shifted QR algorithm of Golub, Kahan and Reinsch, as in LINPACK
+// dsvdc and its adaptation in JAMA. The singular values are decreasing and
+// the vectors have the opposite sign, which gives the signs of LAPACK in R.
+
+type SVD struct {
+	D []float64
+	U *Matrix // the left singular vectors, n x min(n, p)
+	V *Matrix // the right singular vectors, p x min(n, p)
+}
+
+func NewSVD(a *Matrix) *SVD {
+	if a.Rows < a.Cols {
+		// the decomposition of the transpose has u and v interchanged
+		s := NewSVD(a.T())
+		s.U, s.V = s.V, s.U
+		return s
+	}
+	m, n := a.Rows, a.Cols
+	A := make([][]float64, m)
+	for i := range A {
+		A[i] = make([]float64, n)
+		for j := range A[i] {
+			A[i][j] = a.At(i, j)
+		}
+	}
+	nu := n
+	s := make([]float64, n)
+	U := make([][]float64, m)
+	for i := range U {
+		U[i] = make([]float64, nu)
+	}
+	V := make([][]float64, n)
+	for i := range V {
+		V[i] = make([]float64, n)
+	}
+	e := make([]float64, n)
+	work := make([]float64, m)
+
+	// reduce A to bidiagonal form, storing the diagonal elements in s and the super-diagonal elements in e
+	nct := min(m-1, n)
+	nrt := max(0, min(n-2, m))
+	for k := 0; k < max(nct, nrt); k++ {
+		if k < nct {
+			s[k] = 0
+			for i := k; i < m; i++ {
+				s[k] = math.Hypot(s[k], A[i][k])
+			}
+			if s[k] != 0 {
+				if A[k][k] < 0 {
+					s[k] = -s[k]
+				}
+				for i := k; i < m; i++ {
+					A[i][k] /= s[k]
+				}
+				A[k][k] += 1
+			}
+			s[k] = -s[k]
+		}
+		for j := k + 1; j < n; j++ {
+			if k < nct && s[k] != 0 {
+				t := 0.
+				for i := k; i < m; i++ {
+					t += A[i][k] * A[i][j]
+				}
+				t = -t / A[k][k]
+				for i := k; i < m; i++ {
+					A[i][j] += t * A[i][k]
+				}
+			}
+			e[j] = A[k][j]
+		}
+		if k < nct {
+			for i := k; i < m; i++ {
+				U[i][k] = A[i][k]
+			}
+		}
+		if k < nrt {
+			e[k] = 0
+			for i := k + 1; i < n; i++ {
+				e[k] = math.Hypot(e[k], e[i])
+			}
+			if e[k] != 0 {
+				if e[k+1] < 0 {
+					e[k] = -e[k]
+				}
+				for i := k + 1; i < n; i++ {
+					e[i] /= e[k]
+				}
+				e[k+1] += 1
+			}
+			e[k] = -e[k]
+			if k+1 < m && e[k] != 0 {
+				for i := k + 1; i < m; i++ {
+					work[i] = 0
+				}
+				for j := k + 1; j < n; j++ {
+					for i := k + 1; i < m; i++ {
+						work[i] += e[j] * A[i][j]
+					}
+				}
+				for j := k + 1; j < n; j++ {
+					t := -e[j] / e[k+1]
+					for i := k + 1; i < m; i++ {
+						A[i][j] += t * work[i]
+					}
+				}
+			}
+			for i := k + 1; i < n; i++ {
+				V[i][k] = e[i]
+			}
+		}
+	}
+
+	// set up the final bidiagonal matrix of order p
+	p := min(n, m+1)
+	if nct < n {
+		s[nct] = A[nct][nct]
+	}
+	if m < p {
+		s[p-1] = 0
+	}
+	if nrt+1 < p {
+		e[nrt] = A[nrt][p-1]
+	}
+	e[p-1] = 0
+
+	// generate U
+	for j := nct; j < nu; j++ {
+		for i := 0; i < m; i++ {
+			U[i][j] = 0
+		}
+		U[j][j] = 1
+	}
+	for k := nct - 1; k >= 0; k-- {
+		if s[k] != 0 {
+			for j := k + 1; j < nu; j++ {
+				t := 0.
+				for i := k; i < m; i++ {
+					t += U[i][k] * U[i][j]
+				}
+				t = -t / U[k][k]
+				for i := k; i < m; i++ {
+					U[i][j] += t * U[i][k]
+				}
+			}
+			for i := k; i < m; i++ {
+				U[i][k] = -U[i][k]
+			}
+			U[k][k] = 1 + U[k][k]
+			for i := 0; i < k; i++ {
+				U[i][k] = 0
+			}
+		} else {
+			for i := 0; i < m; i++ {
+				U[i][k] = 0
+			}
+			U[k][k] = 1
+		}
+	}
+
+	// generate V
+	for k := n - 1; k >= 0; k-- {
+		if k < nrt && e[k] != 0 {
+			for j := k + 1; j < n; j++ {
+				t := 0.
+				for i := k + 1; i < n; i++ {
+					t += V[i][k] * V[i][j]
+				}
+				t = -t / V[k+1][k]
+				for i := k + 1; i < n; i++ {
+					V[i][j] += t * V[i][k]
+				}
+			}
+		}
+		for i := 0; i < n; i++ {
+			V[i][k] = 0
+		}
+		V[k][k] = 1
+	}
+
+	// main iteration loop for the singular values
+	pp := p - 1
+	eps := math.Pow(2, -52)
+	tiny := math.Pow(2, -966)
+	for p > 0 {
+		// kase = 1 if s[p] and e[k-1] are negligible and k < p
+		// kase = 2 if s[k] is negligible and k < p
+		// kase = 3 if e[k-1] is negligible, k < p, and s[k], ..., s[p] are not negligible (qr step)
+		// kase = 4 if e[p-1] is negligible (convergence)
+		var k, kase int
+		for k = p - 2; k >= 0; k-- {
+			if math.Abs(e[k]) <= tiny+eps*(math.Abs(s[k])+math.Abs(s[k+1])) {
+				e[k] = 0
+				break
+			}
+		}
+		if k == p-2 {
+			kase = 4
+		} else {
+			var ks int
+			for ks = p - 1; ks > k; ks-- {
+				t := 0.
+				if ks != p {
+					t += math.Abs(e[ks])
+				}
+				if ks != k+1 {
+					t += math.Abs(e[ks-1])
+				}
+				if math.Abs(s[ks]) <= tiny+eps*t {
+					s[ks] = 0
+					break
+				}
+			}
+			switch {
+			case ks == k:
+				kase = 3
+			case ks == p-1:
+				kase = 1
+			default:
+				kase = 2
+				k = ks
+			}
+		}
+		k++
+
+		switch kase {
+		case 1: // deflate negligible s[p]
+			f := e[p-2]
+			e[p-2] = 0
+			for j := p - 2; j >= k; j-- {
+				t := math.Hypot(s[j], f)
+				cs, sn := s[j]/t, f/t
+				s[j] = t
+				if j != k {
+					f = -sn * e[j-1]
+					e[j-1] = cs * e[j-1]
+				}
+				for i := 0; i < n; i++ {
+					t = cs*V[i][j] + sn*V[i][p-1]
+					V[i][p-1] = -sn*V[i][j] + cs*V[i][p-1]
+					V[i][j] = t
+				}
+			}
+		case 2: // split at negligible s[k]
+			f := e[k-1]
+			e[k-1] = 0
+			for j := k; j < p; j++ {
+				t := math.Hypot(s[j], f)
+				cs, sn := s[j]/t, f/t
+				s[j] = t
+				f = -sn * e[j]
+				e[j] = cs * e[j]
+				for i := 0; i < m; i++ {
+					t = cs*U[i][j] + sn*U[i][k-1]
+					U[i][k-1] = -sn*U[i][j] + cs*U[i][k-1]
+					U[i][j] = t
+				}
+			}
+		case 3: // one qr step
+			scale := math.Max(math.Max(math.Max(math.Max(math.Abs(s[p-1]), math.Abs(s[p-2])), math.Abs(e[p-2])), math.Abs(s[k])), math.Abs(e[k]))
+			sp := s[p-1] / scale
+			spm1 := s[p-2] / scale
+			epm1 := e[p-2] / scale
+			sk := s[k] / scale
+			ek := e[k] / scale
+			b := ((spm1+sp)*(spm1-sp) + epm1*epm1) / 2
+			c := (sp * epm1) * (sp * epm1)
+			shift := 0.
+			if b != 0 || c != 0 {
+				shift = math.Sqrt(b*b + c)
+				if b < 0 {
+					shift = -shift
+				}
+				shift = c / (b + shift)
+			}
+			f := (sk+sp)*(sk-sp) + shift
+			g := sk * ek
+			for j := k; j < p-1; j++ {
+				t := math.Hypot(f, g)
+				cs, sn := f/t, g/t
+				if j != k {
+					e[j-1] = t
+				}
+				f = cs*s[j] + sn*e[j]
+				e[j] = cs*e[j] - sn*s[j]
+				g = sn * s[j+1]
+				s[j+1] = cs * s[j+1]
+				for i := 0; i < n; i++ {
+					t = cs*V[i][j] + sn*V[i][j+1]
+					V[i][j+1] = -sn*V[i][j] + cs*V[i][j+1]
+					V[i][j] = t
+				}
+				t = math.Hypot(f, g)
+				cs, sn = f/t, g/t
+				s[j] = t
+				f = cs*e[j] + sn*s[j+1]
+				s[j+1] = -sn*e[j] + cs*s[j+1]
+				g = sn * e[j+1]
+				e[j+1] = cs * e[j+1]
+				if j < m-1 {
+					for i := 0; i < m; i++ {
+						t = cs*U[i][j] + sn*U[i][j+1]
+						U[i][j+1] = -sn*U[i][j] + cs*U[i][j+1]
+						U[i][j] = t
+					}
+				}
+			}
+			e[p-2] = f
+		case 4: // convergence: make the singular value positive and order it
+			if s[k] <= 0 {
+				if s[k] < 0 {
+					s[k] = -s[k]
+				} else {
+					s[k] = 0
+				}
+				for i := 0; i <= pp; i++ {
+					V[i][k] = -V[i][k]
+				}
+			}
+			for k < pp && s[k] < s[k+1] {
+				s[k], s[k+1] = s[k+1], s[k]
+				if k < n-1 {
+					for i := 0; i < n; i++ {
+						V[i][k], V[i][k+1] = V[i][k+1], V[i][k]
+					}
+				}
+				if k < m-1 {
+					for i := 0; i < m; i++ {
+						U[i][k], U[i][k+1] = U[i][k+1], U[i][k]
+					}
+				}
+				k++
+			}
+			p--
+		}
+	}
+	r := &SVD{D: s, U: NewMatrix(m, nu), V: NewMatrix(n, n)}
+	for i := 0; i < m; i++ {
+		for j := 0; j < nu; j++ {
+			r.U.Set(i, j, -U[i][j])
+		}
+	}
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			r.V.Set(i, j, -V[i][j])
+		}
+	}
+	return r
+}
@@ -0,0 +1,160 @@
+package numeric
+
+import (
+	"math"
+)
+
+// The Shapiro-Wilk W test of normality as R's swilk.c, after Royston's algorithm
+// AS R94 (1995) with coefficients approximated by polynomials for 3 <= n <= 5000.
+
+// the fault codes of Swilk
+const (
+	SwilkOK        = 0
+	SwilkTooLarge  = 2
+	SwilkZeroRange = 6
+	SwilkUnsorted  = 7
+)
+
+// the polynomial cc[0] + cc[1] x + ... of order len(cc)
+func poly(cc []float64, x float64) float64 {
+	r := cc[0]
+	if nord := len(cc); nord > 1 {
+		p := x * cc[nord-1]
+		for j := nord - 2; j > 0; j-- {
+			p = (p + cc[j]) * x
+		}
+		r += p
+	}
+	return r
+}
+
+func isign(i int) float64 {
+	switch {
+	case i > 0:
+		return 1
+	case i < 0:
+		return -1
+	}
+	return 0
+}
+
+// Swilk returns W and its p-value for the sorted sample x with a fault code.
+func Swilk(x []float64) (float64, float64, int) {
+	const small = 1e-19
+	g := []float64{-2.273, .459}
+	c1 := []float64{0., .221157, -.147981, -2.07119, 4.434685, -2.706056}
+	c2 := []float64{0., .042981, -.293762, -1.752461, 5.682633, -3.582633}
+	c3 := []float64{.544, -.39978, .025054, -6.714e-4}
+	c4 := []float64{1.3822, -.77857, .062767, -.0020322}
+	c5 := []float64{-1.5861, -.31082, -.083751, .0038915}
+	c6 := []float64{-.4803, -.082676, .0030302}
+
+	n := len(x)
+	nn2 := n / 2
+	a := make([]float64, nn2+1) // from 1
+	an := float64(n)
+	if n == 3 {
+		a[1] = math.Sqrt(0.5)
+	} else {
+		an25 := an + .25
+		summ2 := 0.
+		for i := 1; i <= nn2; i++ {
+			a[i] = Qnorm((float64(i)-.375)/an25, 0, 1, true, false)
+			summ2 += a[i] * a[i]
+		}
+		summ2 *= 2
+		ssumm2 := math.Sqrt(summ2)
+		rsn := 1 / math.Sqrt(an)
+		a1 := poly(c1, rsn) - a[1]/ssumm2
+		// normalize the coefficients
+		var i1 int
+		var fac float64
+		if n > 5 {
+			i1 = 3
+			a2 := -a[2]/ssumm2 + poly(c2, rsn)
+			fac = math.Sqrt((summ2 - 2*(a[1]*a[1]) - 2*(a[2]*a[2])) / (1 - 2*(a1*a1) - 2*(a2*a2)))
+			a[2] = a2
+		} else {
+			i1 = 2
+			fac = math.Sqrt((summ2 - 2*(a[1]*a[1])) / (1 - 2*(a1*a1)))
+		}
+		a[1] = a1
+		for i := i1; i <= nn2; i++ {
+			a[i] /= -fac
+		}
+	}
+	rng := x[n-1] - x[0]
+	if rng < small {
+		return 1, 1, SwilkZeroRange
+	}
+	// the sort order is checked on the scaled values
+	ifault := SwilkOK
+	xx := x[0] / rng
+	sx := xx
+	sa := -a[1]
+	for i, j := 1, n-1; i < n; j-- {
+		xi := x[i] / rng
+		if xx-xi > small {
+			ifault = SwilkUnsorted
+		}
+		sx += xi
+		i++
+		if i != j {
+			sa += isign(i-j) * a[imin(i, j)]
+		}
+		xx = xi
+	}
+	if n > 5000 {
+		ifault = SwilkTooLarge
+	}
+	// W is the squared correlation between the data and the coefficients
+	sa /= an
+	sx /= an
+	ssa, ssx, sax := 0., 0., 0.
+	for i, j := 0, n-1; i < n; i, j = i+1, j-1 {
+		var asa float64
+		if i != j {
+			asa = isign(i-j)*a[1+imin(i, j)] - sa
+		} else {
+			asa = -sa
+		}
+		xsx := x[i]/rng - sx
+		ssa += asa * asa
+		ssx += xsx * xsx
+		sax += asa * xsx
+	}
+	// w1 is 1 - W, calculated so to avoid rounding errors for W near 1
+	ssassx := math.Sqrt(ssa * ssx)
+	w1 := (ssassx - sax) * (ssassx + sax) / (ssa * ssx)
+	w := 1 - w1
+	if n == 3 { // the exact p-value
+		const (
+			pi6  = 1.90985931710274 // 6/pi
+			stqr = 1.04719755119660 // asin(sqrt(3/4))
+		)
+		return w, math.Max(0, pi6*(math.Asin(math.Sqrt(w))-stqr)), ifault
+	}
+	y := math.Log(w1)
+	xx = math.Log(an)
+	var m, s float64
+	if n <= 11 {
+		gamma := poly(g, an)
+		if y >= gamma {
+			return w, 1e-99, ifault
+		}
+		y = -math.Log(gamma - y)
+		m = poly(c3, an)
+		s = math.Exp(poly(c4, an))
+	} else {
+		m = poly(c5, xx)
+		s = math.Exp(poly(c6, xx))
+	}
+	return w, Pnorm(y, m, s, false, false), ifault
+}
+
+func imin(i int, j int) int {
+	if i < j {
+		return i
+	}
+	return j
+}
@@ -0,0 +1,324 @@
+package numeric
+
+import (
+	"math"
+)
+
+// Unconstrained minimization as R's nlm with the line search method of uncmin
+// (Dennis and Schnabel): a quasi-Newton step on the secant (BFGS) approximation of
+// the Hessian, shortened by backtracking, with gradients by finite differences
+// unless they are given. Forward differences are replaced by central differences
+// once the line search fails with them.
+
+// NlmControl holds the tuning parameters of nlm.
+type NlmControl struct {
+	Typsize []float64
+	Fscale  float64
+	Ndigit  int
+	Gradtol float64
+	Stepmax float64
+	Steptol float64
+	Iterlim int
+}
+
+type NlmResult struct {
+	Minimum    float64
+	Estimate   []float64
+	Gradient   []float64
+	Code       int
+	Iterations int
+}
+
+// forward differences of f at x with value fx
+func forwardGradient(fn Objective, x []float64, fx float64, sx []float64, rnoise float64, g []float64) {
+	xt := append([]float64{}, x...)
+	for j := range x {
+		stepsz := math.Sqrt(rnoise) * math.Max(math.Abs(x[j]), 1/sx[j])
+		xt[j] = x[j] + stepsz
+		g[j] = (fn(xt) - fx) / stepsz
+		xt[j] = x[j]
+	}
+}
+
+// central differences of f at x
+func centralGradient(fn Objective, x []float64, sx []float64, rnoise float64, g []float64) {
+	xt := append([]float64{}, x...)
+	for j := range x {
+		stepi := math.Pow(rnoise, 1.0/3.0) * math.Max(math.Abs(x[j]), 1/sx[j])
+		xt[j] = x[j] + stepi
+		fplus := fn(xt)
+		xt[j] = x[j] - stepi
+		fminus := fn(xt)
+		xt[j] = x[j]
+		g[j] = (fplus - fminus) / (stepi * 2)
+	}
+}
+
+// lnsrch finds xpls = x + lambda p with sufficient decrease, the code is 0 on success
+// and 1 if no such point sufficiently distinct from x was found
+func lnsrch(fn Objective, x []float64, f float64, g []float64, p []float64, sx []float64, stepmx float64, steptl float64, xpls []float64) (float64, int, bool) {
+	mxtake := false
+	sln := 0.
+	for i := range p {
+		sln += sx[i] * sx[i] * p[i] * p[i]
+	}
+	sln = math.Sqrt(sln)
+	if sln > stepmx { // the Newton step is longer than the maximum allowed
+		scl := stepmx / sln
+		for i := range p {
+			p[i] *= scl
+		}
+		sln = stepmx
+	}
+	slp := 0.
+	rln := 0.
+	for i := range p {
+		slp += g[i] * p[i]
+		rln = math.Max(rln, math.Abs(p[i])/math.Max(math.Abs(x[i]), 1/sx[i]))
+	}
+	rmnlmb := steptl / rln
+	almbda := 1.0
+	var plmbda, pfpls float64
+	for {
+		for i := range x {
+			xpls[i] = x[i] + almbda*p[i]
+		}
+		fpls := fn(xpls)
+		if fpls <= f+slp*1e-4*almbda { // a satisfactory xpls is found
+			if almbda == 1.0 && sln > stepmx*.99 {
+				mxtake = true
+			}
+			return fpls, 0, mxtake
+		}
+		if almbda < rmnlmb {
+			return fpls, 1, mxtake
+		}
+		// backtrack along the quadratic or cubic model of f
+		var tlmbda float64
+		switch {
+		case !isFinite(fpls):
+			almbda *= 0.1
+			continue
+		case almbda == 1.0:
+			tlmbda = -almbda * slp / ((fpls - f - slp) * 2.)
+		default:
+			t1 := fpls - f - almbda*slp
+			t2 := pfpls - f - plmbda*slp
+			t3 := 1. / (almbda - plmbda)
+			a3 := t3 * (t1/(almbda*almbda) - t2/(plmbda*plmbda))
+			b := t3 * (t2*almbda/(plmbda*plmbda) - t1*plmbda/(almbda*almbda))
+			disc := b*b - a3*3.*slp
+			if disc > b*b { // only one positive critical point, it must be the minimum
+				tlmbda = (-b + math.Copysign(1, a3)*math.Sqrt(disc)) / (a3 * 3.)
+			} else { // both critical points positive, the first is the minimum
+				tlmbda = (-b - math.Copysign(1, a3)*math.Sqrt(disc)) / (a3 * 3.)
+			}
+			if tlmbda > almbda*.5 {
+				tlmbda = almbda * .5
+			}
+		}
+		plmbda = almbda
+		pfpls = fpls
+		if tlmbda < almbda*.1 {
+			almbda *= .1
+		} else {
+			almbda = tlmbda
+		}
+	}
+}
+
+// Nlm minimizes fn from x0, with the gradient gr or finite differences if gr is nil.
+func Nlm(fn Objective, gr Gradient, x0 []float64, c NlmControl) NlmResult {
+	n := len(x0)
+	sx := make([]float64, n)
+	for i := range sx {
+		sx[i] = 1 / c.Typsize[i]
+	}
+	rnoise := math.Max(math.Pow(10, -float64(c.Ndigit)), dblEpsilon)
+	x := append([]float64{}, x0...)
+	xpls := make([]float64, n)
+	g := make([]float64, n)
+	gpls := make([]float64, n)
+	p := make([]float64, n)
+	central := false
+	gradient := func(x []float64, fx float64, g []float64) {
+		switch {
+		case gr != nil:
+			gr(x, g)
+		case central:
+			centralGradient(fn, x, sx, rnoise, g)
+		default:
+			forwardGradient(fn, x, fx, sx, rnoise, g)
+		}
+	}
+	// the relative gradient is tested against gradtol
+	small := func(x []float64, f float64, g []float64) bool {
+		d := math.Max(math.Abs(f), c.Fscale)
+		rgx := 0.
+		for i := range x {
+			rgx = math.Max(rgx, math.Abs(g[i])*math.Max(math.Abs(x[i]), 1/sx[i])/d)
+		}
+		return rgx <= c.Gradtol
+	}
+	f := fn(x)
+	gradient(x, f, g)
+	if small(x, f, g) {
+		return NlmResult{Minimum: f, Estimate: x, Gradient: g, Code: 1}
+	}
+	// the approximation of the Hessian starts from the scaling of the parameters
+	H := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		H.Set(i, i, sx[i]*sx[i])
+	}
+	icscmx := 0
+	noupdt := true
+	for itncnt := 1; ; itncnt++ {
+		// the quasi-Newton step solves H p = -g
+		L, info := Cholesky(H)
+		if info != 0 {
+			for i := 0; i < n; i++ {
+				for j := 0; j < n; j++ {
+					H.Set(i, j, 0)
+				}
+				H.Set(i, i, sx[i]*sx[i])
+			}
+			L, _ = Cholesky(H)
+		}
+		rhs := NewMatrix(n, 1)
+		for i := range g {
+			rhs.Data[i] = -g[i]
+		}
+		y, _ := TriangularSolve(L, rhs, n, true, true)
+		step, _ := TriangularSolve(L, y, n, true, false)
+		copy(p, step.Data)
+		fpls, iretcd, mxtake := lnsrch(fn, x, f, g, p, sx, c.Stepmax, c.Steptol, xpls)
+		if iretcd == 1 && gr == nil && !central {
+			// retry with central differences
+			central = true
+			gradient(x, f, g)
+			itncnt--
+			continue
+		}
+		gradient(xpls, fpls, gpls)
+		// termination
+		code := 0
+		switch {
+		case iretcd == 1:
+			code = 3
+		case small(xpls, fpls, gpls):
+			code = 1
+		default:
+			rsx := 0.
+			for i := range x {
+				rsx = math.Max(rsx, math.Abs(xpls[i]-x[i])/math.Max(math.Abs(xpls[i]), 1/sx[i]))
+			}
+			switch {
+			case rsx <= c.Steptol:
+				code = 2
+			case itncnt >= c.Iterlim:
+				code = 4
+			case mxtake:
+				icscmx++
+				if icscmx >= 5 {
+					code = 5
+				}
+			default:
+				icscmx = 0
+			}
+		}
+		if code != 0 {
+			if code == 3 {
+				return NlmResult{Minimum: f, Estimate: x, Gradient: g, Code: code, Iterations: itncnt}
+			}
+			return NlmResult{Minimum: fpls, Estimate: xpls, Gradient: gpls, Code: code, Iterations: itncnt}
+		}
+		// BFGS update of the Hessian unless the step or the change of the gradient is negligible
+		s := make([]float64, n)
+		dy := make([]float64, n)
+		den1, snorm2, ynrm2 := 0., 0., 0.
+		for i := range s {
+			s[i] = xpls[i] - x[i]
+			dy[i] = gpls[i] - g[i]
+			den1 += s[i] * dy[i]
+			snorm2 += sx[i] * sx[i] * s[i] * s[i]
+			ynrm2 += dy[i] * dy[i]
+		}
+		if den1 >= math.Sqrt(dblEpsilon)*math.Sqrt(snorm2)*math.Sqrt(ynrm2) {
+			hs := H.Mul(&Matrix{Rows: n, Cols: 1, Data: s}).Data
+			den2 := 0.
+			for i := range s {
+				den2 += s[i] * hs[i]
+			}
+			if noupdt { // the initial approximation is scaled by the first step
+				alp := den1 / den2
+				for k := range H.Data {
+					H.Data[k] *= alp
+				}
+				for i := range hs {
+					hs[i] *= alp
+				}
+				den2 = den1
+				noupdt = false
+			}
+			reltol := rnoise
+			if gr == nil && !central {
+				reltol = math.Sqrt(rnoise)
+			}
+			skip := true
+			for i := range s {
+				if math.Abs(dy[i]-hs[i]) >= reltol*math.Max(math.Abs(g[i]), math.Abs(gpls[i])) {
+					skip = false
+					break
+				}
+			}
+			if !skip {
+				for i := 0; i < n; i++ {
+					for j := 0; j < n; j++ {
+						H.Set(i, j, H.At(i, j)+dy[i]*dy[j]/den1-hs[i]*hs[j]/den2)
+					}
+				}
+			}
+		}
+		copy(x, xpls)
+		copy(g, gpls)
+		f = fpls
+	}
+}
+
+// Fdhess approximates the Hessian of fn at x with value fval by second differences.
+func Fdhess(fn Objective, x []float64, fval float64, typsize []float64, ndigit int) *Matrix {
+	n := len(x)
+	x = append([]float64{}, x...)
+	eta := math.Pow(10.0, -float64(ndigit)/3.0)
+	step := make([]float64, n)
+	f := make([]float64, n)
+	for i := 0; i < n; i++ {
+		step[i] = eta * math.Max(x[i], typsize[i])
+		if typsize[i] < 0 {
+			step[i] = -step[i]
+		}
+		tempi := x[i]
+		x[i] += step[i]
+		step[i] = x[i] - tempi
+		f[i] = fn(x)
+		x[i] = tempi
+	}
+	h := NewMatrix(n, n)
+	for i := 0; i < n; i++ {
+		tempi := x[i]
+		x[i] = x[i] + step[i]*2
+		fii := fn(x)
+		h.Set(i, i, ((fval-f[i])+(fii-f[i]))/(step[i]*step[i]))
+		x[i] = tempi + step[i]
+		for j := i + 1; j < n; j++ {
+			tempj := x[j]
+			x[j] = x[j] + step[j]
+			fij := fn(x)
+			h.Set(i, j, ((fval-f[i])+(fij-f[j]))/(step[i]*step[j]))
+			h.Set(j, i, h.At(i, j))
+			x[j] = tempj
+		}
+		x[i] = tempi
+	}
+	return h
+}
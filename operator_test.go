@@ -47,22 +47,135 @@ func TestArithmetic(t *testing.T) {
 func ExampleComparison() {
 	eval.EvalFileForTest("test/operator/comparison.r")
 	// Output:
-	//[1] 2
-	//nil
-	//nil
-	//[1] 1
-	//[1] 2
-	//nil
+//[1] TRUE
+//[1] FALSE
+//[1] FALSE
+//[1] TRUE
+//[1] TRUE
+//[1] FALSE
+//
+//[1] FALSE
+//[1] TRUE
+//[1] TRUE
+//[1] FALSE
+//
+//[1] TRUE
+//[1] FALSE
+//[1] FALSE
+//[1] FALSE
+//[1] FALSE
+//[1] TRUE
+//
+//[1] "logical"
+//[1] 5 10
+//[1] 1 10
+//[1] TRUE FALSE NA
+//logical(0)
+}
 
-	//nil
-	//[1] 3
-	//[1] 4
-	//nil
+func ExampleCoercion() {
+	eval.EvalFileForTest("test/operator/coercion.r")
+	// Output:
+//[3] "1" "a" "TRUE"
+//[1] 1 2
+//[1] 1+0i 0+2i
+//a	b1	b2
+//1	2	3
+//[1] 1 NA
+//Warning message:
+//NAs introduced by coercion
+//[1] 3
+//[1] TRUE NA FALSE
+//[3] "1.5" "1e+05" "123456"
+//[1] TRUE
+//[1] FALSE TRUE FALSE
+//a	b.c	b.d
+//1	2	3
+//[1] 2
+//[1] 2.5
+//[1] TRUE
+//[3] "1.5" "2" "1e-20"
+//[3] "TRUE" NA NA
+}
+
+func TestCoercion(t *testing.T) {
+	quicktestSlice(t, "c(TRUE,2,3L)", []float64{1,2,3}, 0)
+	quicktestSlice(t, "c(1,NULL,c(2,3))", []float64{1,2,3}, 0)
+	quicktestSlice(t, "as.numeric(c(\"1.5\",\"1e3\",\" 2 \"))", []float64{1.5,1000,2}, 0)
+	quicktestValue(t, "as.numeric(\"0x10\")", 16, 0)
+	quicktestValue(t, "as.numeric(TRUE)+1", 2, 0)
+	quicktestSlice(t, "as.numeric(list(4,5))", []float64{4,5}, 0)
+	quicktestValue(t, "TRUE+1.5", 2.5, 0)
+	quicktestValue(t, "as.numeric(as.character(0.1))", 0.1, 0)
+}
 
+func ExampleMissing() {
+	eval.EvalFileForTest("test/operator/missing.r")
+	// Output:
+	//[1] 1 NA 3 NaN
+	//[1] FALSE TRUE FALSE TRUE
+	//[1] FALSE FALSE FALSE TRUE
+	//[1] FALSE
+	//[1] TRUE
+	//[1] NA
+	//[1] "integer"
+	//[1] "double"
+	//[1] "character"
+	//[2] "a" NA
+	//[1] NA
+	//[1] NA
 	//[1] 4
-	//nil
+	//[1] 1.5
+	//[1] 5
+	//[1] 1 3
+	//[1] TRUE FALSE FALSE
+}
+
+func TestMissing(t *testing.T) {
+	quicktestValue(t, "sum(c(1,NA,3), na.rm=TRUE)", 4, 0)
+	quicktestValue(t, "mean(c(1,2,NA), na.rm=TRUE)", 1.5, 0)
+	quicktestValue(t, "prod(c(2,NaN,3), na.rm=TRUE)", 6, 0)
+	quicktestValue(t, "min(c(4,NA,2), na.rm=TRUE)", 2, 0)
+	quicktestSlice(t, "na.omit(c(NA,1,NaN,2))", []float64{1,2}, 0)
+}
+
+func ExampleSequence() {
+	eval.EvalFileForTest("test/operator/sequence.r")
+	// Output:
+	//[1] 5 4 3 2 1
+	//[1] 1.5 2.5 3.5
+	//[1] 3
+	//[1] 2
 	//[1] 1
-	//[1] 4
-	//nil
-	//[1] 4
+	//[1] 0 0.25 0.5 0.75 1
+	//[1] 2 5 8 11
+	//integer(0)
+	//[1] 1 1 2 2 1 1 2 2
+	//[1] 1 1 1 2
+	//c	b	a
+	//3	2	1
+	//[1] 2 5 3 1 4
+	//[1] 3 2 1
+	//[1] 1.5 3 1.5 4
+	//[1] 1 3 1
+	//[1] 1 2 NA 3
+	//[1] FALSE FALSE TRUE
+	//a	c
+	//1	3
+	//[1] 2
+	//[1] 2 NA
+	//[1] FALSE TRUE
+	//[1] 1 2 3 5
+	//[1] 2 4
+	//[1] 1 3 5
+}
+
+func TestSequence(t *testing.T) {
+	quicktestSlice(t, "seq(1, 2, by=0.5)", []float64{1,1.5,2}, 0)
+	quicktestSlice(t, "seq(10, 0, by=-2.5)", []float64{10,7.5,5,2.5,0}, 0)
+	quicktestSlice(t, "seq(0, 1, length.out=3)", []float64{0,0.5,1}, 0)
+	quicktestSlice(t, "0.5:-1.5", []float64{0.5,-0.5,-1.5}, 0)
+	quicktestSlice(t, "sort(c(2.5,NA,-1,0))", []float64{-1,0,2.5}, 0)
+	quicktestSlice(t, "rank(c(3,1,4,1))", []float64{3,1.5,4,1.5}, 0)
+	quicktestSlice(t, "rep(c(0.5,1), length.out=3)", []float64{0.5,1,0.5}, 0)
 }
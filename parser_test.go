@@ -9,19 +9,17 @@ func ExampleIdentifiers() {
 	eval.EvalFileForTest("test/parser/identifiers.r")
 // Output:
 //Error: object 'a_2' not found
-//Error: object '..b' not found
-//Error: object '._c' not found
-//Error: object 'a...b' not found
+//[1] 24.6
 }
 
 
 func ExampleNan() {
 	eval.EvalFileForTest("test/parser/nan.r")
 // Output:
-//[1] NaN
-//[1] NaN
-//[1] NaN
-//[1] NaN
+//[1] NA
+//[1] NA
+//[1] NA
+//[1] NA
 }
 
 func ExampleReturnFunction() {
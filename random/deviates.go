@@ -0,0 +1,578 @@
+package random
+
+import (
+	"math"
+	"roq/numeric"
+)
+
+// Deviates of the continuous distributions and of the discrete ones built on them,
+// as in R's nmath: gamma by algorithms GD and GS of Ahrens and Dieter (1974, 1982),
+// beta by algorithms BB and BC of Cheng (1978), hypergeometric by HIN and H2PE of
+// Kachitvichyanukul and Schmeiser (1985). Invalid parameters give NaN.
+
+func (g *Generator) Unif(a float64, b float64) float64 {
+	if !isFinite(a) || !isFinite(b) || b < a {
+		return math.NaN()
+	}
+	if a == b {
+		return a
+	}
+	return a + (b-a)*g.UnifRand()
+}
+
+func (g *Generator) Exp(scale float64) float64 {
+	if !isFinite(scale) || scale <= 0 {
+		if scale == 0 {
+			return 0
+		}
+		return math.NaN()
+	}
+	return scale * g.ExpRand()
+}
+
+func (g *Generator) Norm(mu float64, sigma float64) float64 {
+	if math.IsNaN(mu) || math.IsInf(sigma, 0) || math.IsNaN(sigma) || sigma < 0 {
+		return math.NaN()
+	}
+	if sigma == 0 || math.IsInf(mu, 0) {
+		return mu
+	}
+	return mu + sigma*g.NormRand()
+}
+
+func (g *Generator) Gamma(a float64, scale float64) float64 {
+	const (
+		sqrt32 = 5.656854
+		expM1  = 0.36787944117144233 // exp(-1)
+		q1     = 0.04166669
+		q2     = 0.02083148
+		q3     = 0.00801191
+		q4     = 0.00144121
+		q5     = -7.388e-5
+		q6     = 2.4511e-4
+		q7     = 2.424e-4
+		a1     = 0.3333333
+		a2     = -0.250003
+		a3     = 0.2000062
+		a4     = -0.1662921
+		a5     = 0.1423657
+		a6     = -0.1367177
+		a7     = 0.1233795
+	)
+	if math.IsNaN(a) || math.IsNaN(scale) {
+		return math.NaN()
+	}
+	if a <= 0 || scale <= 0 {
+		if scale == 0 || a == 0 {
+			return 0
+		}
+		return math.NaN()
+	}
+	if math.IsInf(a, 0) || math.IsInf(scale, 0) {
+		return math.Inf(1)
+	}
+	if a < 1 {
+		// algorithm GS
+		e := 1 + expM1*a
+		var x float64
+		for {
+			p := e * g.UnifRand()
+			if p >= 1 {
+				x = -math.Log((e - p) / a)
+				if g.ExpRand() >= (1-a)*math.Log(x) {
+					break
+				}
+			} else {
+				x = math.Exp(math.Log(p) / a)
+				if g.ExpRand() >= x {
+					break
+				}
+			}
+		}
+		return scale * x
+	}
+	// algorithm GD
+	s2 := a - 0.5
+	s := math.Sqrt(s2)
+	d := sqrt32 - s*12
+	// immediate acceptance of a normal deviate
+	t := g.NormRand()
+	x := s + 0.5*t
+	if t >= 0 {
+		return scale * x * x
+	}
+	// squeeze acceptance
+	u := g.UnifRand()
+	if d*u <= t*t*t {
+		return scale * x * x
+	}
+	r := 1 / a
+	q0 := ((((((q7*r+q6)*r+q5)*r+q4)*r+q3)*r+q2)*r + q1) * r
+	var b, si, c float64
+	switch {
+	case a <= 3.686:
+		b = 0.463 + s + 0.178*s2
+		si = 1.235
+		c = 0.195/s - 0.079 + 0.16*s
+	case a <= 13.022:
+		b = 1.654 + 0.0076*s2
+		si = 1.68/s + 0.275
+		c = 0.062/s + 0.024
+	default:
+		b = 1.77
+		si = 0.75
+		c = 0.1515 / s
+	}
+	quotient := func(t float64) float64 {
+		v := t / (s + s)
+		if math.Abs(v) <= 0.25 {
+			return q0 + 0.5*t*t*((((((a7*v+a6)*v+a5)*v+a4)*v+a3)*v+a2)*v+a1)*v
+		}
+		return q0 - s*t + 0.25*t*t + (s2+s2)*math.Log(1+v)
+	}
+	// quotient acceptance
+	if x > 0 && math.Log(1-u) <= quotient(t) {
+		return scale * x * x
+	}
+	// double exponential rejection
+	for {
+		e := g.ExpRand()
+		u = g.UnifRand()
+		u = u + u - 1
+		if u < 0 {
+			t = b - si*e
+		} else {
+			t = b + si*e
+		}
+		if t >= -0.71874483771719 {
+			q := quotient(t)
+			if q > 0 && c*math.Abs(u) <= math.Expm1(q)*math.Exp(e-0.5*t*t) {
+				break
+			}
+		}
+	}
+	x = s + 0.5*t
+	return scale * x * x
+}
+
+func (g *Generator) Beta(aa float64, bb float64) float64 {
+	const expmax = 1024 * math.Ln2 // log(DBL_MAX)
+	if math.IsNaN(aa) || math.IsNaN(bb) || aa < 0 || bb < 0 {
+		return math.NaN()
+	}
+	switch {
+	case math.IsInf(aa, 1) && math.IsInf(bb, 1):
+		return 0.5
+	case aa == 0 && bb == 0:
+		if g.UnifRand() < 0.5 {
+			return 0
+		}
+		return 1
+	case math.IsInf(aa, 1) || bb == 0:
+		return 1
+	case math.IsInf(bb, 1) || aa == 0:
+		return 0
+	}
+	a := math.Min(aa, bb)
+	b := math.Max(aa, bb)
+	alpha := a + b
+	var beta, v, w float64
+	vw := func(u1 float64, aa float64) {
+		v = beta * math.Log(u1/(1-u1))
+		if v <= expmax {
+			w = aa * math.Exp(v)
+			if math.IsInf(w, 1) {
+				w = math.MaxFloat64
+			}
+		} else {
+			w = math.MaxFloat64
+		}
+	}
+	if a <= 1 {
+		// algorithm BC
+		beta = 1 / a
+		delta := 1 + b - a
+		k1 := delta * (0.0138889 + 0.0416667*a) / (b*beta - 0.777778)
+		k2 := 0.25 + (0.5+0.25/delta)*a
+		for {
+			u1 := g.UnifRand()
+			u2 := g.UnifRand()
+			var z float64
+			if u1 < 0.5 {
+				y := u1 * u2
+				z = u1 * y
+				if 0.25*u2+z-y >= k1 {
+					continue
+				}
+			} else {
+				z = u1 * u1 * u2
+				if z <= 0.25 {
+					vw(u1, b)
+					break
+				}
+				if z >= k2 {
+					continue
+				}
+			}
+			vw(u1, b)
+			if alpha*(math.Log(alpha/(a+w))+v)-1.3862944 >= math.Log(z) {
+				break
+			}
+		}
+		if aa == a {
+			return a / (a + w)
+		}
+		return w / (a + w)
+	}
+	// algorithm BB
+	beta = math.Sqrt((alpha - 2) / (2*a*b - alpha))
+	gamma := a + 1/beta
+	for {
+		u1 := g.UnifRand()
+		u2 := g.UnifRand()
+		vw(u1, a)
+		z := u1 * u1 * u2
+		r := gamma*v - 1.3862944
+		s := a + r - w
+		if s+2.609438 >= 5*z {
+			break
+		}
+		t := math.Log(z)
+		if s > t {
+			break
+		}
+		if r+alpha*math.Log(alpha/(b+w)) >= t {
+			break
+		}
+	}
+	if aa != a {
+		return b / (b + w)
+	}
+	return w / (b + w)
+}
+
+func (g *Generator) Chisq(df float64) float64 {
+	if math.IsInf(df, 0) || math.IsNaN(df) || df < 0 {
+		return math.NaN()
+	}
+	return g.Gamma(df/2, 2)
+}
+
+func (g *Generator) T(df float64) float64 {
+	if math.IsNaN(df) || df <= 0 {
+		return math.NaN()
+	}
+	if math.IsInf(df, 0) {
+		return g.NormRand()
+	}
+	num := g.NormRand()
+	return num / math.Sqrt(g.Chisq(df)/df)
+}
+
+func (g *Generator) F(m float64, n float64) float64 {
+	if math.IsNaN(m) || math.IsNaN(n) || m <= 0 || n <= 0 {
+		return math.NaN()
+	}
+	num, den := 1., 1.
+	if !math.IsInf(m, 0) {
+		num = g.Chisq(m) / m
+	}
+	if !math.IsInf(n, 0) {
+		den = g.Chisq(n) / n
+	}
+	return num / den
+}
+
+func (g *Generator) Geom(p float64) float64 {
+	if math.IsNaN(p) || math.IsInf(p, 0) || p <= 0 || p > 1 {
+		return math.NaN()
+	}
+	return g.Pois(g.Gamma(1, (1-p)/p))
+}
+
+func (g *Generator) NBinom(size float64, prob float64) float64 {
+	if math.IsNaN(prob) || math.IsInf(prob, 0) || math.IsNaN(size) || size <= 0 || prob <= 0 || prob > 1 {
+		return math.NaN()
+	}
+	if math.IsInf(size, 0) {
+		size = math.MaxFloat64 / 2
+	}
+	if prob == 1 {
+		return 0
+	}
+	return g.Pois(g.Gamma(size, (1-prob)/prob))
+}
+
+func (g *Generator) NBinomMu(size float64, mu float64) float64 {
+	if math.IsNaN(mu) || math.IsInf(mu, 0) || math.IsNaN(size) || size <= 0 || mu < 0 {
+		return math.NaN()
+	}
+	if math.IsInf(size, 0) {
+		size = math.MaxFloat64 / 2
+	}
+	if mu == 0 {
+		return 0
+	}
+	return g.Pois(g.Gamma(size, mu/size))
+}
+
+func (g *Generator) Lnorm(meanlog float64, sdlog float64) float64 {
+	if math.IsNaN(meanlog) || math.IsInf(sdlog, 0) || math.IsNaN(sdlog) || sdlog < 0 {
+		return math.NaN()
+	}
+	return math.Exp(g.Norm(meanlog, sdlog))
+}
+
+func (g *Generator) Weibull(shape float64, scale float64) float64 {
+	if !isFinite(shape) || !isFinite(scale) || shape <= 0 || scale <= 0 {
+		if scale == 0 {
+			return 0
+		}
+		return math.NaN()
+	}
+	return scale * math.Pow(-math.Log(g.UnifRand()), 1/shape)
+}
+
+func (g *Generator) Cauchy(location float64, scale float64) float64 {
+	if math.IsNaN(location) || !isFinite(scale) || scale < 0 {
+		return math.NaN()
+	}
+	if scale == 0 || math.IsInf(location, 0) {
+		return location
+	}
+	return location + scale*math.Tan(math.Pi*g.UnifRand())
+}
+
+func (g *Generator) Logis(location float64, scale float64) float64 {
+	if math.IsNaN(location) || !isFinite(scale) {
+		return math.NaN()
+	}
+	if scale == 0 || math.IsInf(location, 0) {
+		return location
+	}
+	u := g.UnifRand()
+	return location + scale*math.Log(u/(1-u))
+}
+
+func isFinite(x float64) bool {
+	return !math.IsNaN(x) && !math.IsInf(x, 0)
+}
+
+var afcTable = [8]float64{
+	0.0,
+	0.0,
+	0.69314718055994530941723212145817,
+	1.79175946922805500081247735838070,
+	3.17805383034794561964694160129705,
+	4.78749174278204599424770093452324,
+	6.57925121201010099506017829290394,
+	8.52516136106541430016553103634712,
+}
+
+// log(i!) by table and by Stirling's formula
+func afc(i int) float64 {
+	if i <= 7 {
+		return afcTable[i]
+	}
+	di := float64(i)
+	return (di+0.5)*math.Log(di) - di + 0.918938533204672741780329736406 +
+		(0.0833333333333333-0.00277777777777778/(di*di))/di
+}
+
+// the number of white balls in k draws from an urn with nn1 white and nn2 black balls
+func (g *Generator) Hyper(nn1in float64, nn2in float64, kkin float64) float64 {
+	const (
+		deltal = 0.0078
+		deltau = 0.0034
+	)
+	if !isFinite(nn1in) || !isFinite(nn2in) || !isFinite(kkin) {
+		return math.NaN()
+	}
+	nn1in = math.RoundToEven(nn1in)
+	nn2in = math.RoundToEven(nn2in)
+	kkin = math.RoundToEven(kkin)
+	if nn1in < 0 || nn2in < 0 || kkin < 0 || kkin > nn1in+nn2in {
+		return math.NaN()
+	}
+	if nn1in >= math.MaxInt32 || nn2in >= math.MaxInt32 || kkin >= math.MaxInt32 {
+		if kkin == 1 {
+			return g.Binom(kkin, nn1in/(nn1in+nn2in))
+		}
+		return numeric.Qhyper(g.UnifRand(), nn1in, nn2in, kkin, false, false)
+	}
+	nn1, nn2, kk := int(nn1in), int(nn2in), int(kkin)
+	tn := nn1in + nn2in
+	n1, n2 := nn1, nn2
+	if nn1 > nn2 {
+		n1, n2 = nn2, nn1
+	}
+	k := kk
+	if kkin+kkin >= tn {
+		k = int(tn - kkin)
+	}
+	m := int((float64(k) + 1) * (float64(n1) + 1) / (tn + 2))
+	minjx := k - n2
+	if minjx < 0 {
+		minjx = 0
+	}
+	maxjx := k
+	if n1 < maxjx {
+		maxjx = n1
+	}
+	fn1, fn2, fk, fm := float64(n1), float64(n2), float64(k), float64(m)
+	var ix int
+	switch {
+	case minjx == maxjx:
+		ix = maxjx
+	case m-minjx < 10:
+		// algorithm HIN, inversion scaled against underflow
+		const (
+			scale = 1e25
+			con   = 57.5646273248511421 // log(scale)
+		)
+		var lw float64
+		if k < n2 {
+			lw = afc(n2) + afc(n1+n2-k) - afc(n2-k) - afc(n1+n2)
+		} else {
+			lw = afc(n1) + afc(k) - afc(k-n2) - afc(n1+n2)
+		}
+		w := math.Exp(lw + con)
+	inversion:
+		for {
+			ix = minjx
+			u := g.UnifRand() * scale
+			p := w
+			for u > p {
+				u -= p
+				p *= (fn1 - float64(ix)) * float64(k-ix)
+				ix++
+				p = p / float64(ix) / float64(n2-k+ix)
+				if ix > maxjx {
+					continue inversion
+				}
+			}
+			break
+		}
+	default:
+		// algorithm H2PE
+		s := math.Sqrt((tn - fk) * fk * fn1 * fn2 / (tn - 1) / tn / tn)
+		d := float64(int(1.5*s)) + .5
+		xl := fm - d + .5
+		xr := fm + d + .5
+		a := afc(m) + afc(n1-m) + afc(k-m) + afc(n2-k+m)
+		kl := math.Exp(a - afc(int(xl)) - afc(int(fn1-xl)) - afc(int(fk-xl)) - afc(int(fn2-fk+xl)))
+		kr := math.Exp(a - afc(int(xr-1)) - afc(int(fn1-xr+1)) - afc(int(fk-xr+1)) - afc(int(fn2-fk+xr-1)))
+		lamdl := -math.Log(xl * (fn2 - fk + xl) / (fn1 - xl + 1) / (fk - xl + 1))
+		lamdr := -math.Log((fn1 - xr + 1) * (fk - xr + 1) / xr / (fn2 - fk + xr))
+		p1 := d + d
+		p2 := p1 + kl/lamdl
+		p3 := p2 + kr/lamdr
+		for nuv := 1; ; nuv++ {
+			if nuv > 10000 {
+				return math.NaN()
+			}
+			u := g.UnifRand() * p3
+			v := g.UnifRand()
+			switch {
+			case u < p1:
+				ix = int(xl + u)
+			case u <= p2:
+				ix = int(xl + math.Log(v)/lamdl)
+				if ix < minjx {
+					continue
+				}
+				v = v * (u - p1) * lamdl
+			default:
+				ix = int(xr - math.Log(v)/lamdr)
+				if ix > maxjx {
+					continue
+				}
+				v = v * (u - p2) * lamdr
+			}
+			if m < 100 || ix <= 50 {
+				// explicit evaluation
+				f := 1.
+				if m < ix {
+					for i := m + 1; i <= ix; i++ {
+						fi := float64(i)
+						f = f * (fn1 - fi + 1) * (fk - fi + 1) / (fn2 - fk + fi) / fi
+					}
+				} else if m > ix {
+					for i := ix + 1; i <= m; i++ {
+						fi := float64(i)
+						f = f * fi * (fn2 - fk + fi) / (fn1 - fi + 1) / (fk - fi + 1)
+					}
+				}
+				if v <= f {
+					break
+				}
+				continue
+			}
+			// squeeze using upper and lower bounds
+			y := float64(ix)
+			y1 := y + 1
+			ym := y - fm
+			yn := fn1 - y + 1
+			yk := fk - y + 1
+			nk := fn2 - fk + y1
+			r := -ym / y1
+			s := ym / yn
+			t := ym / yk
+			e := -ym / nk
+			gg := yn*yk/(y1*nk) - 1
+			dg := 1.
+			if gg < 0 {
+				dg = 1 + gg
+			}
+			gu := gg * (1 + gg*(-0.5+gg/3))
+			gl := gu - .25*(gg*gg*gg*gg)/dg
+			xm := fm + 0.5
+			xn := fn1 - fm + 0.5
+			xk := fk - fm + 0.5
+			nm := fn2 - fk + xm
+			ub := y*gu - fm*gl + deltau +
+				xm*r*(1+r*(-0.5+r/3)) +
+				xn*s*(1+s*(-0.5+s/3)) +
+				xk*t*(1+t*(-0.5+t/3)) +
+				nm*e*(1+e*(-0.5+e/3))
+			alv := math.Log(v)
+			if alv > ub {
+				continue
+			}
+			dr := xm * (r * r * r * r)
+			if r < 0 {
+				dr /= 1 + r
+			}
+			ds := xn * (s * s * s * s)
+			if s < 0 {
+				ds /= 1 + s
+			}
+			dt := xk * (t * t * t * t)
+			if t < 0 {
+				dt /= 1 + t
+			}
+			de := nm * (e * e * e * e)
+			if e < 0 {
+				de /= 1 + e
+			}
+			if alv < ub-0.25*(dr+ds+dt+de)+(y+fm)*(gl-gu)-deltal {
+				break
+			}
+			// Stirling's formula to machine accuracy
+			if alv <= a-afc(ix)-afc(n1-ix)-afc(k-ix)-afc(n2-k+ix) {
+				break
+			}
+		}
+	}
+	// back from the smaller colour and the shorter draw
+	if kkin+kkin >= tn {
+		if nn1 > nn2 {
+			ix = kk - nn2 + ix
+		} else {
+			ix = nn1 - ix
+		}
+	} else if nn1 > nn2 {
+		ix = kk - ix
+	}
+	return float64(ix)
+}
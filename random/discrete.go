@@ -0,0 +1,288 @@
+package random
+
+import (
+	"math"
+	"roq/numeric"
+)
+
+// Binomial deviates by algorithm BTPE of Kachitvichyanukul and Schmeiser (1988),
+// Poisson deviates by algorithm PD of Ahrens and Dieter (1982), both as in R's nmath.
+// Invalid parameters give NaN.
+
+func (g *Generator) Binom(nin float64, pp float64) float64 {
+	if math.IsNaN(nin) || math.IsInf(nin, 0) {
+		return math.NaN()
+	}
+	r := math.RoundToEven(nin)
+	if r != nin || math.IsNaN(pp) || math.IsInf(pp, 0) || r < 0 || pp < 0 || pp > 1 {
+		return math.NaN()
+	}
+	if r == 0 || pp == 0 {
+		return 0
+	}
+	if pp == 1 {
+		return r
+	}
+	if r >= math.MaxInt32 {
+		return numeric.Qbinom(g.UnifRand(), r, pp, false, false)
+	}
+	n := int(r)
+	p := math.Min(pp, 1-pp)
+	q := 1 - p
+	np := float64(n) * p
+	r = p / q
+	gg := r * float64(n+1)
+	var ix int
+	if np < 30 {
+		// inverse cdf logic for mean less than 30
+		qn := math.Pow(q, float64(n))
+		for {
+			ix = 0
+			f := qn
+			u := g.UnifRand()
+			for {
+				if u < f {
+					return binomResult(ix, n, pp)
+				}
+				if ix > 110 {
+					break
+				}
+				u -= f
+				ix++
+				f *= gg/float64(ix) - r
+			}
+		}
+	}
+	fm := np + p
+	m := int(fm)
+	npq := np * q
+	p1 := float64(int(2.195*math.Sqrt(npq)-4.6*q)) + 0.5
+	xm := float64(m) + 0.5
+	xl := xm - p1
+	xr := xm + p1
+	c := 0.134 + 20.5/(15.3+float64(m))
+	al := (fm - xl) / (fm - xl*p)
+	xll := al * (1 + 0.5*al)
+	al = (xr - fm) / (xr * q)
+	xlr := al * (1 + 0.5*al)
+	p2 := p1 * (1 + c + c)
+	p3 := p2 + c/xll
+	p4 := p3 + c/xlr
+	for {
+		u := g.UnifRand() * p4
+		v := g.UnifRand()
+		// triangular region
+		if u <= p1 {
+			ix = int(xm - p1*v + u)
+			return binomResult(ix, n, pp)
+		}
+		if u <= p2 {
+			// parallelogram region
+			x := xl + (u-p1)/c
+			v = v*c + 1 - math.Abs(xm-x)/p1
+			if v > 1 || v <= 0 {
+				continue
+			}
+			ix = int(x)
+		} else if u > p3 {
+			// right tail
+			ix = int(xr - math.Log(v)/xlr)
+			if ix > n {
+				continue
+			}
+			v = v * (u - p3) * xlr
+		} else {
+			// left tail
+			ix = int(xl + math.Log(v)/xll)
+			if ix < 0 {
+				continue
+			}
+			v = v * (u - p2) * xll
+		}
+		k := ix - m
+		if k < 0 {
+			k = -k
+		}
+		if k <= 20 || float64(k) >= npq/2-1 {
+			// explicit evaluation
+			f := 1.0
+			if m < ix {
+				for i := m + 1; i <= ix; i++ {
+					f *= gg/float64(i) - r
+				}
+			} else if m > ix {
+				for i := ix + 1; i <= m; i++ {
+					f /= gg/float64(i) - r
+				}
+			}
+			if v <= f {
+				return binomResult(ix, n, pp)
+			}
+			continue
+		}
+		// squeezing using upper and lower bounds on log(f(x))
+		fk := float64(k)
+		amaxp := (fk / npq) * ((fk*(fk/3.+0.625)+0.1666666666666)/npq + 0.5)
+		ynorm := -fk * fk / (2.0 * npq)
+		alv := math.Log(v)
+		if alv < ynorm-amaxp {
+			return binomResult(ix, n, pp)
+		}
+		if alv <= ynorm+amaxp {
+			// Stirling's (actually de Moivre's) formula to machine accuracy
+			x1 := float64(ix + 1)
+			f1 := fm + 1
+			z := float64(n+1) - fm
+			w := float64(n-ix) + 1
+			z2, x2, f2, w2 := z*z, x1*x1, f1*f1, w*w
+			if alv <= xm*math.Log(f1/x1)+(float64(n-m)+0.5)*math.Log(z/w)+float64(ix-m)*math.Log(w*p/(x1*q))+
+				stirling(f1, f2)+stirling(z, z2)+stirling(x1, x2)+stirling(w, w2) {
+				return binomResult(ix, n, pp)
+			}
+		}
+	}
+}
+
+func stirling(x float64, x2 float64) float64 {
+	return (13860. - (462.-(132.-(99.-140./x2)/x2)/x2)/x2) / x / 166320.
+}
+
+// the deviate was drawn for min(p, 1-p)
+func binomResult(ix int, n int, p float64) float64 {
+	if p > 0.5 {
+		ix = n - ix
+	}
+	return float64(ix)
+}
+
+const (
+	a0 = -0.5
+	a1 = 0.3333333
+	a2 = -0.2500068
+	a3 = 0.2000118
+	a4 = -0.1661269
+	a5 = 0.1421878
+	a6 = -0.1384794
+	a7 = 0.1250060
+
+	one7  = 0.1428571428571428571
+	one12 = 0.0833333333333333333
+	one24 = 0.0416666666666666667
+
+	oneSqrt2Pi = 0.398942280401432677939946059934 // 1/sqrt(2pi)
+)
+
+var factorials = [10]float64{1., 1., 2., 6., 24., 120., 720., 5040., 40320., 362880.}
+
+func (g *Generator) Pois(mu float64) float64 {
+	if math.IsNaN(mu) || math.IsInf(mu, 0) || mu < 0 {
+		return math.NaN()
+	}
+	if mu <= 0 {
+		return 0
+	}
+	if mu < 10 {
+		return g.poisInversion(mu)
+	}
+	s := math.Sqrt(mu)
+	d := 6 * mu * mu
+	bigL := math.Floor(mu - 1.1484)
+	var pois, fk, difmuk, u float64
+
+	// Step N. normal sample
+	gg := mu + s*g.NormRand()
+	if gg >= 0 {
+		pois = math.Floor(gg)
+		// Step I. immediate acceptance if pois is large enough
+		if pois >= bigL {
+			return pois
+		}
+		// Step S. squeeze acceptance
+		fk = pois
+		difmuk = mu - fk
+		u = g.UnifRand()
+		if d*u >= difmuk*difmuk*difmuk {
+			return pois
+		}
+	}
+
+	// Step P. preparations for steps Q and H
+	omega := oneSqrt2Pi / s
+	b1 := one24 / mu
+	b2 := 0.3 * b1 * b1
+	c3 := one7 * b1 * b2
+	c2 := b2 - 15.*c3
+	c1 := b1 - 6.*b2 + 45.*c3
+	c0 := 1. - b1 + 3.*b2 - 15.*c3
+	c := 0.1069 / mu // guarantees majorization by the hat function
+
+	// procedure F: calculation of px, py, fx, fy
+	f := func() (px, py, fx, fy float64) {
+		if pois < 10 {
+			px = -mu
+			py = math.Pow(mu, pois) / factorials[int(pois)]
+		} else {
+			del := one12 / fk
+			del = del * (1. - 4.8*del*del)
+			v := difmuk / fk
+			if math.Abs(v) <= 0.25 {
+				px = fk*v*v*(((((((a7*v+a6)*v+a5)*v+a4)*v+a3)*v+a2)*v+a1)*v+a0) - del
+			} else {
+				px = fk*math.Log(1.+v) - difmuk - del
+			}
+			py = oneSqrt2Pi / math.Sqrt(fk)
+		}
+		x := (0.5 - difmuk) / s
+		xx := x * x
+		fx = -0.5 * xx
+		fy = omega * (((c3*xx+c2)*xx+c1)*xx + c0)
+		return
+	}
+
+	if gg >= 0 {
+		// Step Q. quotient acceptance
+		px, py, fx, fy := f()
+		if fy-u*fy <= py*math.Exp(px-fx) {
+			return pois
+		}
+	}
+	for {
+		// Step E. double exponential sample from the Laplace hat
+		e := g.ExpRand()
+		u = 2*g.UnifRand() - 1
+		t := 1.8 + e
+		if u < 0 {
+			t = 1.8 - e
+		}
+		if t > -0.6744 {
+			pois = math.Floor(mu + s*t)
+			fk = pois
+			difmuk = mu - fk
+			// Step H. hat acceptance
+			px, py, fx, fy := f()
+			if c*math.Abs(u) <= py*math.Exp(px+e)-fy*math.Exp(fx+e) {
+				return pois
+			}
+		}
+	}
+}
+
+// table lookup of the cumulative probabilities up to 35 for small mu
+func (g *Generator) poisInversion(mu float64) float64 {
+	p := math.Exp(-mu)
+	q := p
+	for {
+		u := g.UnifRand()
+		if u <= p {
+			return 0
+		}
+		pk, qk := p, q
+		for k := 1; k <= 35; k++ {
+			pk *= mu / float64(k)
+			qk += pk
+			if u <= qk {
+				return float64(k)
+			}
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package random
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/Random.html
+// The uniform generator, normal generator and sampler of R with their state.
+// The same seed gives the same stream of numbers as GNU R. A generator belongs
+// to one evaluator and is not safe for concurrent use.
+
+const (
+	MersenneTwister = "Mersenne-Twister"
+	LEcuyerCMRG     = "L'Ecuyer-CMRG"
+	Inversion       = "Inversion"
+	Rejection       = "Rejection"
+	Rounding        = "Rounding"
+)
+
+// the first kind is the default
+var Kinds = []string{MersenneTwister, LEcuyerCMRG}
+var NormalKinds = []string{Inversion}
+var SampleKinds = []string{Rejection, Rounding}
+
+// codes of the kinds in the first element of .Random.seed
+var kindCodes = map[string]int{MersenneTwister: 3, LEcuyerCMRG: 7, Inversion: 4, Rounding: 0, Rejection: 1}
+
+type Generator struct {
+	Kind       string
+	NormalKind string
+	SampleKind string
+	seeded     bool
+	mt         mersenneTwister
+	lecuyer    lecuyer
+	streams    int     // number of generators spawned since the state changed
+	streamSeed lecuyer // the stream of the last spawned generator
+}
+
+// a generator with the default kinds, seeded from time and process id at first use
+func New() *Generator {
+	return &Generator{Kind: MersenneTwister, NormalKind: Inversion, SampleKind: Rejection}
+}
+
+func timeSeed() uint32 {
+	now := time.Now()
+	return uint32(now.UnixNano()/1000) ^ uint32(os.Getpid())<<16
+}
+
+func (g *Generator) init(seed uint32) {
+	for j := 0; j < 50; j++ { // initial scrambling
+		seed = 69069*seed + 1
+	}
+	switch g.Kind {
+	case LEcuyerCMRG:
+		g.lecuyer.init(seed)
+	default:
+		g.mt.init(seed)
+	}
+	g.seeded = true
+	g.streams = 0
+}
+
+// as set.seed(seed) in R
+func (g *Generator) Seed(seed int32) {
+	g.init(uint32(seed))
+}
+
+// a new seed from time and process id, as set.seed(NULL)
+func (g *Generator) Randomize() {
+	g.init(timeSeed())
+}
+
+// changing the kind seeds the new generator from the current one
+func (g *Generator) SetKind(kind string) {
+	u := g.UnifRand()
+	g.Kind = kind
+	g.init(uint32(u * math.MaxUint32))
+}
+
+// uniform in the open interval (0,1)
+func (g *Generator) UnifRand() float64 {
+	if !g.seeded {
+		g.Randomize()
+	}
+	g.streams = 0
+	switch g.Kind {
+	case LEcuyerCMRG:
+		return g.lecuyer.genrand()
+	default:
+		return fixup(g.mt.genrand())
+	}
+}
+
+const i2_32m1 = 2.328306437080797e-10 // 1/(2^32 - 1)
+
+// ensure 0 and 1 are never returned
+func fixup(x float64) float64 {
+	if x <= 0 {
+		return 0.5 * i2_32m1
+	}
+	if 1-x <= 0 {
+		return 1 - 0.5*i2_32m1
+	}
+	return x
+}
+
+// the kinds and seeds as integers, which are stored in .Random.seed
+func (g *Generator) State() []int {
+	if !g.seeded {
+		g.Randomize()
+	}
+	code := kindCodes[g.Kind] + 100*kindCodes[g.NormalKind] + 10000*kindCodes[g.SampleKind]
+	var seeds []uint32
+	switch g.Kind {
+	case LEcuyerCMRG:
+		seeds = g.lecuyer[:]
+	default:
+		seeds = append([]uint32{uint32(g.mt.mti)}, g.mt.mt[:]...)
+	}
+	r := make([]int, 1+len(seeds))
+	r[0] = code
+	for n, s := range seeds {
+		r[n+1] = int(int32(s))
+	}
+	return r
+}
+
+func equalInts(x []int, y []int) bool {
+	if len(x) != len(y) {
+		return false
+	}
+	for n := range x {
+		if x[n] != y[n] {
+			return false
+		}
+	}
+	return true
+}
+
+func kindOfCode(kinds []string, code int) (string, bool) {
+	for _, k := range kinds {
+		if kindCodes[k] == code {
+			return k, true
+		}
+	}
+	return "", false
+}
+
+// restores a state as returned by State, invalid seeds are replaced by a seed from time.
+// Spawned streams continue, if the state is unchanged.
+func (g *Generator) SetState(state []int) error {
+	if g.seeded && equalInts(state, g.State()) {
+		return nil
+	}
+	if len(state) == 0 || state[0] < 0 || state[0] > 11000 {
+		return fmt.Errorf("'.Random.seed' is not a valid integer, so ignored")
+	}
+	kind, ok1 := kindOfCode(Kinds, state[0]%100)
+	normal, ok2 := kindOfCode(NormalKinds, state[0]%10000/100)
+	sample, ok3 := kindOfCode(SampleKinds, state[0]/10000)
+	if !ok1 || !ok2 || !ok3 {
+		return fmt.Errorf("'.Random.seed[1]' is not a valid RNG kind so ignored")
+	}
+	seeds := make([]uint32, len(state)-1)
+	for n := range seeds {
+		seeds[n] = uint32(int32(state[n+1]))
+	}
+	g.Kind, g.NormalKind, g.SampleKind = kind, normal, sample
+	g.seeded = true
+	g.streams = 0
+	switch kind {
+	case LEcuyerCMRG:
+		if len(seeds) != len(g.lecuyer) {
+			return fmt.Errorf("'.Random.seed' has wrong length")
+		}
+		copy(g.lecuyer[:], seeds)
+		if !g.lecuyer.valid() {
+			g.Randomize()
+		}
+	default:
+		if len(seeds) != 1+mtN {
+			return fmt.Errorf("'.Random.seed' has wrong length")
+		}
+		g.mt.mti = int(int32(seeds[0]))
+		copy(g.mt.mt[:], seeds[1:])
+		if g.mt.mti <= 0 {
+			g.mt.mti = mtN
+		}
+		zero := true
+		for _, s := range g.mt.mt {
+			zero = zero && s == 0
+		}
+		if zero {
+			g.Randomize()
+		}
+	}
+	return nil
+}
+
+// A generator for a goroutine, which depends only on the state of its parent and the
+// number of generators spawned since. L'Ecuyer-CMRG gives the next stream as nextRNGStream,
+// other kinds are seeded from the state of the parent.
+func (g *Generator) Spawn() *Generator {
+	if !g.seeded {
+		g.Randomize()
+	}
+	c := &Generator{Kind: g.Kind, NormalKind: g.NormalKind, SampleKind: g.SampleKind, seeded: true}
+	switch g.Kind {
+	case LEcuyerCMRG:
+		if g.streams == 0 {
+			g.streamSeed = g.lecuyer
+		}
+		g.streamSeed = g.streamSeed.nextStream()
+		c.lecuyer = g.streamSeed
+	default:
+		s := g.mt
+		c.init(uint32(s.genrand()*math.MaxUint32) + uint32(g.streams))
+	}
+	g.streams++
+	return c
+}
+
+// the next spawned generator starts again from the first stream after the current state
+func (g *Generator) ResetStreams() {
+	g.streams = 0
+}
@@ -0,0 +1,136 @@
+package random
+
+// https://stat.ethz.ch/R-manual/R-devel/library/parallel/html/RngStream.html
+// The combined multiple-recursive generator MRG32k3a of L'Ecuyer (1999) as in R, with streams
+// and substreams, which are 2^127 and 2^76 steps apart. The matrices to jump ahead are powers
+// of the transition matrices, computed by repeated squaring.
+
+const (
+	m1    = 4294967087
+	m2    = 4294944443
+	normc = 2.328306549295727688e-10
+	a12   = 1403580
+	a13n  = 810728
+	a21   = 527612
+	a23n  = 1370589
+)
+
+type lecuyer [6]uint32
+
+type matrix [3][3]uint64
+
+var (
+	a1p127 = matrixPower(matrix{{0, 1, 0}, {0, 0, 1}, {m1 - a13n, a12, 0}}, 127, m1)
+	a2p127 = matrixPower(matrix{{0, 1, 0}, {0, 0, 1}, {m2 - a23n, 0, a21}}, 127, m2)
+	a1p76  = matrixPower(matrix{{0, 1, 0}, {0, 0, 1}, {m1 - a13n, a12, 0}}, 76, m1)
+	a2p76  = matrixPower(matrix{{0, 1, 0}, {0, 0, 1}, {m2 - a23n, 0, a21}}, 76, m2)
+)
+
+// a^(2^e) modulo m
+func matrixPower(a matrix, e int, m uint64) matrix {
+	for ; e > 0; e-- {
+		a = matrixProduct(a, a, m)
+	}
+	return a
+}
+
+func matrixProduct(a matrix, b matrix, m uint64) matrix {
+	var r matrix
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				r[i][j] = (r[i][j] + a[i][k]*b[k][j]%m) % m
+			}
+		}
+	}
+	return r
+}
+
+func matrixVector(a matrix, v []uint32, m uint64) []uint32 {
+	r := make([]uint32, 3)
+	for i := 0; i < 3; i++ {
+		var s uint64
+		for k := 0; k < 3; k++ {
+			s = (s + a[i][k]*uint64(v[k])%m) % m
+		}
+		r[i] = uint32(s)
+	}
+	return r
+}
+
+// the seeds are taken from the congruential generator, skipping values not below m2
+func (s *lecuyer) init(seed uint32) {
+	for j := range s {
+		seed = 69069*seed + 1
+		for seed >= m2 {
+			seed = 69069*seed + 1
+		}
+		s[j] = seed
+	}
+}
+
+// not all zero and below the moduli
+func (s *lecuyer) valid() bool {
+	zero1, zero2 := true, true
+	for j := 0; j < 3; j++ {
+		if s[j] >= m1 || s[j+3] >= m2 {
+			return false
+		}
+		zero1 = zero1 && s[j] == 0
+		zero2 = zero2 && s[j+3] == 0
+	}
+	return !zero1 && !zero2
+}
+
+func (s *lecuyer) genrand() float64 {
+	p1 := (a12*int64(s[1]) - a13n*int64(s[0])) % m1
+	if p1 < 0 {
+		p1 += m1
+	}
+	s[0], s[1], s[2] = s[1], s[2], uint32(p1)
+	p2 := (a21*int64(s[5]) - a23n*int64(s[3])) % m2
+	if p2 < 0 {
+		p2 += m2
+	}
+	s[3], s[4], s[5] = s[4], s[5], uint32(p2)
+	if p1 > p2 {
+		return float64(p1-p2) * normc
+	}
+	return float64(p1-p2+m1) * normc
+}
+
+func (s lecuyer) jump(a1 matrix, a2 matrix) lecuyer {
+	var r lecuyer
+	copy(r[:3], matrixVector(a1, s[:3], m1))
+	copy(r[3:], matrixVector(a2, s[3:], m2))
+	return r
+}
+
+func (s lecuyer) nextStream() lecuyer {
+	return s.jump(a1p127, a2p127)
+}
+
+func (s lecuyer) nextSubStream() lecuyer {
+	return s.jump(a1p76, a2p76)
+}
+
+// the seed of the next stream or substream for the seeds of .Random.seed without its kind
+func NextStream(seeds []int, sub bool) ([]int, bool) {
+	var s lecuyer
+	if len(seeds) != len(s) {
+		return nil, false
+	}
+	for n := range s {
+		s[n] = uint32(int32(seeds[n]))
+	}
+	if sub {
+		s = s.nextSubStream()
+	} else {
+		s = s.nextStream()
+	}
+	r := make([]int, len(s))
+	for n := range s {
+		r[n] = int(int32(s[n]))
+	}
+	return r, true
+}
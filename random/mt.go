@@ -0,0 +1,69 @@
+package random
+
+// Mersenne-Twister MT19937 of Matsumoto and Nishimura as used by R,
+// including its initialization by a linear congruential generator.
+
+const (
+	mtN        = 624
+	mtM        = 397
+	matrixA    = 0x9908b0df
+	upperMask  = 0x80000000
+	lowerMask  = 0x7fffffff
+	temperingB = 0x9d2c5680
+	temperingC = 0xefc60000
+)
+
+type mersenneTwister struct {
+	mti int
+	mt  [mtN]uint32
+}
+
+// the state is filled by the congruential generator, which continues from the scrambled seed.
+// Its first value goes to the position, which is reset, as in the seed vector of R.
+func (s *mersenneTwister) init(seed uint32) {
+	seed = 69069*seed + 1
+	for j := 0; j < mtN; j++ {
+		seed = 69069*seed + 1
+		s.mt[j] = seed
+	}
+	s.mti = mtN
+}
+
+// the original initialization, used if the state was never set
+func (s *mersenneTwister) sgenrand(seed uint32) {
+	for i := 0; i < mtN; i++ {
+		s.mt[i] = seed & 0xffff0000
+		seed = 69069*seed + 1
+		s.mt[i] |= (seed & 0xffff0000) >> 16
+		seed = 69069*seed + 1
+	}
+	s.mti = mtN
+}
+
+func (s *mersenneTwister) genrand() float64 {
+	mag01 := [2]uint32{0, matrixA}
+	if s.mti >= mtN {
+		if s.mti == mtN+1 {
+			s.sgenrand(4357)
+		}
+		kk := 0
+		for ; kk < mtN-mtM; kk++ {
+			y := (s.mt[kk] & upperMask) | (s.mt[kk+1] & lowerMask)
+			s.mt[kk] = s.mt[kk+mtM] ^ (y >> 1) ^ mag01[y&1]
+		}
+		for ; kk < mtN-1; kk++ {
+			y := (s.mt[kk] & upperMask) | (s.mt[kk+1] & lowerMask)
+			s.mt[kk] = s.mt[kk+mtM-mtN] ^ (y >> 1) ^ mag01[y&1]
+		}
+		y := (s.mt[mtN-1] & upperMask) | (s.mt[0] & lowerMask)
+		s.mt[mtN-1] = s.mt[mtM-1] ^ (y >> 1) ^ mag01[y&1]
+		s.mti = 0
+	}
+	y := s.mt[s.mti]
+	s.mti++
+	y ^= y >> 11
+	y ^= (y << 7) & temperingB
+	y ^= (y << 15) & temperingC
+	y ^= y >> 18
+	return float64(y) * 2.3283064365386963e-10 // [0,1)
+}
@@ -0,0 +1,71 @@
+package random
+
+import (
+	"roq/numeric"
+)
+
+// Normal and exponential deviates as in R's nmath: inversion of the normal
+// distribution and the exponential algorithm SA of Ahrens and Dieter (1972).
+
+const big = 134217728 // 2^27
+
+// one uniform deviate alone is not precise enough for the tails
+func (g *Generator) NormRand() float64 {
+	u := g.UnifRand()
+	u = float64(int(big*u)) + g.UnifRand()
+	return numeric.Qnorm(u/big, 0, 1, true, false)
+}
+
+// q[k-1] = sum(log(2)^k / k!) for k = 1, ..., 16
+var expQ = [16]float64{
+	0.6931471805599453,
+	0.9333736875190459,
+	0.9888777961838675,
+	0.9984959252914960040,
+	0.9998292811061389,
+	0.9999833164100727,
+	0.9999985508257328,
+	0.9999998906925558,
+	0.9999999924734159,
+	0.9999999995283275,
+	0.9999999999728814,
+	0.9999999999985598,
+	0.9999999999999289,
+	0.9999999999999968,
+	0.9999999999999999,
+	1.0000000000000000,
+}
+
+// standard exponential deviate
+func (g *Generator) ExpRand() float64 {
+	a := 0.
+	u := g.UnifRand()
+	for u <= 0 || u >= 1 {
+		u = g.UnifRand()
+	}
+	for {
+		u += u
+		if u > 1 {
+			break
+		}
+		a += expQ[0]
+	}
+	u -= 1
+	if u <= expQ[0] {
+		return a + u
+	}
+	i := 0
+	ustar := g.UnifRand()
+	umin := ustar
+	for {
+		ustar = g.UnifRand()
+		if umin > ustar {
+			umin = ustar
+		}
+		i++
+		if u <= expQ[i] {
+			break
+		}
+	}
+	return a + umin*expQ[0]
+}
@@ -0,0 +1,241 @@
+package random
+
+import (
+	"math"
+)
+
+// https://stat.ethz.ch/R-manual/R-devel/library/base/html/sample.html
+// Sampling as in R's random.c: uniform indices by rejection from the next power
+// of two or by rounding, weighted sampling by the cumulative probabilities in
+// descending order or by Walker's alias method for more than 200 likely values.
+// The results are one-based indices.
+
+// a uniform index in 0 ... dn-1
+func (g *Generator) UnifIndex(dn float64) float64 {
+	if g.SampleKind == Rounding {
+		return math.Floor(dn * g.UnifRand())
+	}
+	if dn <= 0 {
+		return 0
+	}
+	bits := int(math.Ceil(math.Log2(dn)))
+	for {
+		dv := g.rbits(bits)
+		if dn > dv {
+			return dv
+		}
+	}
+}
+
+// random bits in chunks of 16
+func (g *Generator) rbits(bits int) float64 {
+	var v int64
+	for n := 0; n <= bits; n += 16 {
+		v1 := int64(math.Floor(g.UnifRand() * 65536))
+		v = 65536*v + v1
+	}
+	return float64(v & (int64(1)<<uint(bits) - 1))
+}
+
+// k of n with or without replacement
+func (g *Generator) Sample(n int, k int, replace bool) []int {
+	r := make([]int, k)
+	if replace || k < 2 {
+		for i := range r {
+			r[i] = int(g.UnifIndex(float64(n))) + 1
+		}
+		return r
+	}
+	x := make([]int, n)
+	for i := range x {
+		x[i] = i
+	}
+	for i := range r {
+		j := int(g.UnifIndex(float64(n)))
+		r[i] = x[j] + 1
+		n--
+		x[j] = x[n]
+	}
+	return r
+}
+
+// k of n without replacement by rejecting duplicates, for large n and small k
+func (g *Generator) SampleHash(n int, k int) []int {
+	r := make([]int, 0, k)
+	seen := make(map[int]bool, k)
+	for len(r) < k {
+		v := int(g.UnifIndex(float64(n))) + 1
+		if !seen[v] {
+			seen[v] = true
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// k values drawn with the given probabilities, which sum up to one and are modified
+func (g *Generator) SampleProb(p []float64, k int, replace bool) []int {
+	n := len(p)
+	if !replace {
+		return g.probSampleNoReplace(p, k)
+	}
+	nc := 0
+	for _, v := range p {
+		if float64(n)*v > 0.1 {
+			nc++
+		}
+	}
+	if nc > 200 {
+		return g.walkerSample(p, k)
+	}
+	return g.probSampleReplace(p, k)
+}
+
+func identities(n int) []int {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i + 1
+	}
+	return perm
+}
+
+func (g *Generator) probSampleReplace(p []float64, k int) []int {
+	perm := identities(len(p))
+	revsort(p, perm)
+	for i := 1; i < len(p); i++ {
+		p[i] += p[i-1]
+	}
+	r := make([]int, k)
+	for i := range r {
+		u := g.UnifRand()
+		j := 0
+		for ; j < len(p)-1; j++ {
+			if u <= p[j] {
+				break
+			}
+		}
+		r[i] = perm[j]
+	}
+	return r
+}
+
+func (g *Generator) probSampleNoReplace(p []float64, k int) []int {
+	perm := identities(len(p))
+	revsort(p, perm)
+	total := 1.0
+	r := make([]int, k)
+	n1 := len(p) - 1
+	for i := range r {
+		rt := total * g.UnifRand()
+		mass := 0.0
+		j := 0
+		for ; j < n1; j++ {
+			mass += p[j]
+			if rt <= mass {
+				break
+			}
+		}
+		r[i] = perm[j]
+		total -= p[j]
+		copy(p[j:n1], p[j+1:n1+1])
+		copy(perm[j:n1], perm[j+1:n1+1])
+		n1--
+	}
+	return r
+}
+
+func (g *Generator) walkerSample(p []float64, k int) []int {
+	n := len(p)
+	q := make([]float64, n)
+	a := make([]int, n)
+	hl := make([]int, n)
+	h, l := -1, n // small values are pushed from the start, large ones from the end
+	for i := range p {
+		q[i] = p[i] * float64(n)
+		if q[i] < 1 {
+			h++
+			hl[h] = i
+		} else {
+			l--
+			hl[l] = i
+		}
+	}
+	if h >= 0 && l < n {
+		for m := 0; m < n-1; m++ {
+			i := hl[m]
+			j := hl[l]
+			a[i] = j
+			q[j] += q[i] - 1
+			if q[j] < 1 {
+				l++
+			}
+			if l >= n {
+				break
+			}
+		}
+	}
+	for i := range q {
+		q[i] += float64(i)
+	}
+	r := make([]int, k)
+	for i := range r {
+		u := g.UnifRand() * float64(n)
+		m := int(u)
+		if u < q[m] {
+			r[i] = m + 1
+		} else {
+			r[i] = a[m] + 1
+		}
+	}
+	return r
+}
+
+// heapsort of a into descending order, permuting ib alongside, as revsort of R
+func revsort(a []float64, ib []int) {
+	n := len(a)
+	if n <= 1 {
+		return
+	}
+	// one-based indices as in the original
+	at := func(i int) *float64 { return &a[i-1] }
+	bt := func(i int) *int { return &ib[i-1] }
+	l := (n >> 1) + 1
+	ir := n
+	for {
+		var ra float64
+		var ii int
+		if l > 1 {
+			l--
+			ra = *at(l)
+			ii = *bt(l)
+		} else {
+			ra = *at(ir)
+			ii = *bt(ir)
+			*at(ir) = *at(1)
+			*bt(ir) = *bt(1)
+			ir--
+			if ir == 1 {
+				*at(1) = ra
+				*bt(1) = ii
+				return
+			}
+		}
+		i := l
+		j := l << 1
+		for j <= ir {
+			if j < ir && *at(j) > *at(j + 1) {
+				j++
+			}
+			if ra > *at(j) {
+				*at(i) = *at(j)
+				*bt(i) = *bt(j)
+				i = j
+				j += j
+			} else {
+				j = ir + 1
+			}
+		}
+		*at(i) = ra
+		*bt(i) = ii
+	}
+}
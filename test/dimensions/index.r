@@ -0,0 +1,18 @@
+x <- c(1,2,3,4,5,6)
+dim(x) <- c(2,3)
+x[2,3]
+x[,2]
+x[1,,drop=FALSE]
+dimnames(x) <- list(c("a1","a2"),c("b1","b2","b3"))
+x[c("a1","a2"),c("b1","b3")]
+x[2,3] <- 100
+x[,1] <- c(7,8)
+x
+x[1,2,3]
+y <- c(1,2,3)
+y[undefined_index]
+y[list(1)]
+z <- 5
+z <- y[list(1)]
+z
+y[-c(1,NA)]
@@ -0,0 +1,45 @@
+ch <- chan()
+go(send(ch, 42))
+recv(ch)
+worker <- function(jobs, results) {
+	for (j in 1:3) {
+		x <- recv(jobs)
+		send(results, x * 2)
+	}
+}
+jobs <- chan(3)
+results <- chan()
+go(worker(jobs, results))
+send(jobs, 1); send(jobs, 2); send(jobs, 3)
+c(recv(results), recv(results), recv(results))
+x <- c(a=1, b=2)
+done <- chan(1)
+go(function() {
+	x[1] <- 100
+	send(done, x)
+})
+y <- recv(done)
+y
+x
+ch
+typeof(ch)
+class(ch)
+empty <- chan()
+r <- select(recv(empty), default = "nothing")
+r$index
+r$value
+buf <- chan(1)
+r <- select(recv(empty), send(buf, 7))
+r$index
+recv(buf)
+close(buf)
+recv(buf)
+send(buf, 1)
+close(buf)
+is.null(recv(buf))
+never <- chan()
+recv(never, timeout = 0.01)
+send(never, 1, timeout = 0.01)
+one <- chan(1)
+send(one, 2, timeout = 0)
+recv(one, timeout = 0)
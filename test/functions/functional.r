@@ -0,0 +1,33 @@
+lapply(1:2, function(x) x*2)
+sapply(c(1,4,9), sqrt)
+sapply(c(a=1,b=2), function(x) c(x, x^2))
+vapply(1:3, function(x) x*2, 0)
+mapply(function(x, y) x + y, 1:3, 4:6)
+Map(function(x, y) x * y, 1:2, 3:4)
+Reduce(function(a, b) a + b, 1:4, accumulate=TRUE)
+Reduce(function(a, b) a * b, 1:4, 100)
+Filter(function(x) x > 2, c(a=1,b=3,c=5))
+Position(function(x) x > 2, c(1,3,5))
+Find(function(x) x > 2, c(1,3,5))
+do.call("sum", list(1, 2, 3))
+do.call(function(x, y) x - y, list(y=1, x=10))
+f <- function(x, y) x + y
+vf <- Vectorize(f)
+vf(1:3, 2)
+fact <- function(n) if (n <= 1) 1 else n * Recall(n - 1)
+fact(5)
+g <- match.fun("length")
+g(1:4)
+is.function(sum)
+sapply(list(), function(x) x)
+Reduce(function(a, b) a + b, list(), accumulate=TRUE)
+sapply(list(3), function(x) x * 2)
+sapply(list(c(a=1)), function(x) x)
+sapply(1:2, function(i) list(i))
+sapply(c(a=1,b=2), function(i) list(x=i))
+vapply(1:3, function(x) x*2, numeric(1))
+vapply(c("a","b"), function(s) s, character(1))
+vapply(list(), function(x) x, numeric(1))
+vapply(1:2, function(x) "a", integer(1))
+numeric(2)
+character(1)
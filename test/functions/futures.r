@@ -0,0 +1,37 @@
+plan()
+slow <- function(n) {
+	s <- 0
+	for (i in 1:n) s <- s + i
+	s
+}
+n <- 100
+f <- future({ slow(n) })
+value(f)
+resolved(f)
+class(f)
+plan("multicore", workers = 2)
+fs <- lapply(1:4, function(k) future(slow(k * 10)))
+unlist(value(fs))
+x %<-% { n * 2 }
+x
+n <- 5
+y %<-% slow(n)
+n <- 1000
+y
+plan(sequential)
+e <- future(sqrt("a"))
+value(e)
+w <- future({ as.integer("z"); 1 })
+value(w)
+plan("multicore")
+p <- future({ cat("inside\n"); 42 })
+cat("outside\n")
+value(p)
+value(e)
+u <- future({ undefined_var + 1 })
+value(u)
+value(u)
+q <- future({ cat("Error in log: nothing\n"); 5 })
+value(q)
+s <- future(stop("in future"))
+value(s)
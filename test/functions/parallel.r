@@ -0,0 +1,15 @@
+r <- parallel::mclapply(1:4, function(x) x^2, mc.cores=2)
+unlist(r)
+cl <- makeCluster(3)
+parSapply(cl, c(a=1,b=2,c=3), function(x, k) x * k, k=10)
+unlist(parLapply(cl, 1:3, function(x) x + 1))
+stopCluster(cl)
+y <- 5
+unlist(mclapply(1:3, function(x) { y <<- x; y }))
+y
+r <- mclapply(list(1, "a"), function(x) sqrt(x))
+class(r[[2]])
+r <- mclapply(1:3, function(i) if (i == 2) stop("boom") else i)
+r[[2]]
+sapply(mclapply(1:2, function(i) undefined_var + i), class)
+unlist(mclapply(1:2, function(i) { cat("Error: none\n"); i }))
@@ -0,0 +1,13 @@
+f <- function() { stop("bad"); 42 }
+f()
+h <- function() { for (i in 1:3) { if (i == 2) stop("halt"); print(i) }; "done" }
+h()
+g <- function(x) { if (x < 0) stop("negative value: ", x); sqrt(x) }
+g(-4)
+g(4)
+k <- function() stop("no call", call. = FALSE)
+k()
+stop("at toplevel")
+y <- 1
+y <- sqrt("a") + 1
+y
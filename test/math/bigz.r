@@ -0,0 +1,57 @@
+x <- as.bigz(2)^100
+x
+x + 1
+as.bigz("123456789012345678901234567890") * 3
+as.bigz(c(7, -7)) %% 3
+as.bigz(c(7, -7)) %/% 3
+7 %/% 2
+-7 %/% 2
+7.5 %/% 2
+as.bigz(1:3)
+-as.bigz(5)
+as.bigz(-2.7)
+as.bigz("0xff")
+as.bigz(NA)
+as.bigz(3) / 6
+as.bigq(1, 3) + as.bigq(1, 6)
+as.bigq(0.1)
+as.bigq("22/7") * 7
+as.bigq(2, 3)^-2
+as.bigz(2)^-1
+as.bigz(5) + 0.5
+as.bigz(1) / 0
+numerator(as.bigq(6, 4))
+denominator(as.bigq(6, 4))
+gcd(as.bigz(12), c(18, 8, -30))
+lcm(4, 6)
+factorialZ(25)
+factorialZ(0:5)
+chooseZ(100, 50)
+chooseZ(-2, 2)
+isprime(c(1, 2, 91, 97))
+isprime(as.bigz(2)^127 - 1)
+as.numeric(as.bigq(1, 4))
+as.character(factorialZ(20))
+length(factorialZ(0:5))
+class(as.bigq(1, 2))
+is.bigz(as.bigz(1))
+is.bigq(as.bigz(1))
+as.bigz(NULL)
+as.bigz(2)^0.5
+z <- factorialZ(0:5)
+z > 10
+z == c(1, 1, 2, 6, 24, 121)
+as.bigq(1, 3) < 0.34
+as.bigz(2)^100 >= as.bigz(2)^99
+c(as.bigz(NA), 1) != 1
+sum(z)
+prod(as.bigz(1:20))
+sum(as.bigq(1, 2), as.bigq(1, 3), 1)
+sum(c(as.bigz(1), NA))
+sum(c(as.bigz(1), NA), na.rm = TRUE)
+c(as.bigz(1), 2, as.bigz(2)^70)
+c(as.bigz(1), as.bigq(1, 2))
+z[3:4]
+z[-1]
+z[z > 5]
+z[8]
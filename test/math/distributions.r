@@ -0,0 +1,32 @@
+round(pnorm(1.96), 7)
+round(qnorm(0.975), 7)
+round(pnorm(c(-1, 0, 1), lower.tail=FALSE), 7)
+round(pnorm(-40, log.p=TRUE), 4)
+round(qnorm(-10, log.p=TRUE), 7)
+round(dbinom(3, 10, 0.5), 7)
+round(pbinom(5000, 10000, 0.5), 7)
+round(dpois(0:4, 2), 7)
+qpois(c(0.1, 0.5, 0.9), 3)
+round(qt(0.975, 10), 6)
+round(pt(-2, 3), 7)
+round(qchisq(0.95, 1:3), 6)
+round(qf(0.95, 2, 10), 6)
+round(pgamma(2, shape=3, rate=2), 7)
+round(qgamma(0.5, 2, scale=3), 6)
+round(pbeta(0.3, 2, 5), 7)
+round(dbeta(0.4, 3, 4), 7)
+round(pgeom(2, 0.3), 7)
+round(dnbinom(3, 5, 0.5), 7)
+round(dhyper(1, 10, 7, 8), 7)
+round(plnorm(2), 7)
+round(qweibull(0.5, 2, 3), 7)
+qcauchy(0.75)
+round(qlogis(0.75), 7)
+round(pexp(1, 2), 7)
+dnorm(1, sd=-1)
+dgamma(1, 2, rate=2, scale=2)
+dnbinom(1, 2, prob=0.5, mu=2)
+set.seed(7)
+round(mean(rgamma(10000, 2.5, 2)), 1)
+round(mean(rbeta(10000, 2, 3)), 1)
+round(mean(rhyper(10000, 500, 600, 400)))
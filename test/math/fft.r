@@ -0,0 +1,23 @@
+fft(1:4)
+round(fft(1:7), 6)
+Re(fft(fft(1:8), inverse = TRUE) / 8)
+x <- cos((1:67)^2 / 5)
+round(max(Mod(fft(fft(x), inverse = TRUE) / 67 - x)), 12)
+m <- 1:6
+dim(m) <- c(2, 3)
+round(fft(m), 6)
+mvfft(m)
+round(convolve(1:3, c(0, 1, 0.5), type = "open"), 10)
+round(convolve(1:4, c(1, 1), type = "filter"), 10)
+round(convolve(1:4, c(0, 1, 0, 0)), 10)
+filter(1:6, c(1, 1, 1))
+filter(1:6, c(0.5, 0.5), sides = 1)
+filter(1:5, c(1, 1), circular = TRUE)
+filter(1:5, 0.5, method = "recursive")
+filter(1:4, c(0.5, 0.25), method = "rec", init = c(1, 2))
+nextn(c(7, 13, 100, 1001))
+nextn(7, factors = 3)
+Mod(3+4i)
+Conj(c(1+2i, -1i))
+Im(2)
+mvfft(1:4)
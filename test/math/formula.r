@@ -0,0 +1,16 @@
+f <- y ~ x + log(z)
+f
+class(f)
+all.vars(f)
+terms(y ~ a*b)
+attr(terms(y ~ (a + b + c)^2), "term.labels")
+attr(terms(y ~ a/b), "term.labels")
+attr(terms(y ~ ., data=list(y=1, u=2, v=3)), "term.labels")
+update.formula(y ~ x + w, log(.) ~ . - w)
+update.formula(y ~ x, ~ . - 1)
+x <- c(1,2,3,4)
+model.frame(y ~ x, list(y=c(1,NA,3,4)))
+model.matrix(y ~ x*g, list(g=c("a","b","a","c"), y=1:4))
+model.matrix(~ g - 1, list(g=c("a","b","a","c")))
+model.frame(y ~ x, list(y=1:3))
+terms(y ~ .)
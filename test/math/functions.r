@@ -0,0 +1,22 @@
+sqrt(c(a=4,b=9))
+sqrt(-1)
+sqrt(-1+0i)
+log(100, 10)
+log(8, base=2)
+log(c(1,NA,-1))
+round(c(0.5,1.5,2.5))
+round(c(0.125,-0.125,2.675), 2)
+round(123.456, -1)
+signif(123456, 2)
+gamma(5)
+gamma(0)
+choose(5,2)
+choose(-3,2)
+factorial(c(0,5))
+abs(-3L)
+abs(3+4i)
+sign(c(-2,0,3))
+x = c(1,4,9,16)
+dim(x) = c(2,2)
+sqrt(x)
+sqrt("a")
@@ -0,0 +1,31 @@
+x <- c(0.7, -1.6, -0.2, -1.2, -0.1, 3.4, 3.7, 0.8, 0.0, 2.0)
+y <- c(1.9, 0.8, 1.1, 0.1, -0.1, 4.4, 5.5, 1.6, 4.6, 3.4)
+t.test(x, y)
+r <- t.test(x, y, paired = TRUE)
+round(r$p.value, 6)
+t.test(x, mu = 1, alternative = "less")
+round(t.test(x, y, var.equal = TRUE)$parameter, 6)
+x <- c(1.83, 0.50, 1.62, 2.48, 1.68, 1.88, 1.55, 3.06, 1.30)
+y <- c(0.878, 0.647, 0.598, 2.05, 1.06, 1.29, 1.06, 3.14, 1.29)
+wilcox.test(x, y, paired = TRUE, alternative = "greater")
+round(wilcox.test(y - x, alternative = "less", exact = FALSE, correct = FALSE)$p.value, 6)
+x <- c(0.80, 0.83, 1.89, 1.04, 1.45, 1.38, 1.91, 1.64, 0.73, 1.46)
+y <- c(1.15, 0.88, 0.90, 0.74, 1.21)
+wilcox.test(x, y, conf.int = TRUE)
+wilcox.test(c(x, 1.46), conf.int = TRUE)$method
+M <- c(762, 484, 327, 239, 468, 477)
+dim(M) <- c(2, 3)
+chisq.test(M)
+round(chisq.test(c(89, 37, 30, 28, 2), p = c(40, 20, 20, 15, 5), rescale.p = TRUE)$p.value, 6)
+T <- c(3, 1, 1, 3)
+dim(T) <- c(2, 2)
+fisher.test(T)
+round(fisher.test(T, alternative = "greater")$conf.int, 6)
+x <- c(44.4, 45.9, 41.9, 53.3, 44.7, 44.1, 50.7, 45.2, 60.1)
+y <- c(2.6, 3.1, 2.5, 5.0, 3.6, 4.0, 5.2, 2.8, 3.8)
+cor.test(x, y, method = "kendall", alternative = "greater")
+round(cor.test(x, y, method = "spearman", alternative = "g")$p.value, 6)
+shapiro.test(x)
+round(shapiro.test(y)$statistic, 4)
+cor.test(x, y)
+t.test(1)
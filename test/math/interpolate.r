@@ -0,0 +1,34 @@
+x <- 1:10
+y <- c(2, 4, 3, 7, 8, 6, 9, 12, 11, 15)
+approx(x, y, xout = c(1.5, 2.25, 0, NA))$y
+approx(x, y, xout = c(0, 11), rule = 2)$y
+approx(x, y, xout = c(1.5, 2.5), method = "constant", f = 0.5)$y
+approx(c(1, 2, 2, 3), c(1, 2, 4, 3), xout = 2, ties = min)$y
+approx(c(3, 1, 2), c(30, 10, 20), n = 3)
+f <- approxfun(x, y, yleft = 0)
+f(c(0, 2.5, 9.75))
+s <- spline(x, y, n = 4)
+s$x
+round(s$y, 6)
+round(spline(x, y, xout = c(1.5, 5.5), method = "natural")$y, 6)
+g <- splinefun(c(1, 2, 3.5, 4, 6), c(1, 2, 3.5, 4, 6)^3)
+g(c(2.5, 5))
+g(2.5, deriv = 1)
+p <- splinefun(c(0, 1, 2.5, 3, 5), c(1, 3, 0, 2, 1), method = "periodic")
+round(p(c(0.5, 5.5)), 6)
+m <- splinefun(c(1, 2, 3, 4, 5), c(1, 2, 2, 5, 9), method = "monoH.FC")
+m(c(1.5, 2.5, 3.5, 6))
+m(2.5, deriv = 1)
+h <- splinefun(c(1, 2, 3, 4, 5), c(1, 2, 2, 5, 9), method = "hyman")
+round(h(c(1.5, 2.5, 3.5, 4.5)), 6)
+approx(c(1, 2), c(NA, 1), xout = 1.5)
+speed <- c(4,4,7,7,8,9,10,10,10,11,11,12,12,12,12,13,13,13,13,14,14,14,14,15,15,15,16,16,17,17,17,18,18,18,18,19,19,19,20,20,20,20,20,22,23,24,24,24,24,25)
+dist <- c(2,10,4,22,16,10,18,26,34,17,28,14,20,24,28,26,34,34,46,26,36,60,80,20,26,54,32,40,32,40,50,42,56,76,84,36,46,68,32,48,52,56,64,66,54,70,92,93,120,85)
+ss <- smooth.spline(speed, dist)
+class(ss)
+ss$iparms
+signif(c(ss$spar, ss$lambda, ss$df, ss$pen.crit, ss$cv.crit), 7)
+round(predict(ss, c(3, 10, 30))$y, 4)
+round(predict(ss, c(10, 15), deriv = 1)$y, 4)
+round(smooth.spline(speed, dist, df = 10)$df, 3)
+round(smooth.spline(speed, dist, spar = 0.5)$lambda, 6)
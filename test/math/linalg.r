@@ -0,0 +1,50 @@
+a <- c(2, 1, 1, 3)
+dim(a) <- c(2, 2)
+m <- c(1, 2, 3, 4)
+dim(m) <- c(2, 2)
+x <- c(1, 1, 1, 1, 2, 3)
+dim(x) <- c(3, 2)
+solve(a)
+solve(a, c(1, 2))
+q <- qr(m)
+q$rank
+round(q$qraux, 6)
+round(qr.R(q), 6)
+round(qr.Q(q), 6)
+qx <- qr(x)
+round(qr.coef(qx, c(1, 2, 4)), 6)
+round(qr.resid(qx, c(1, 2, 4)), 6)
+round(chol(a), 6)
+round(chol2inv(chol(a)), 6)
+s <- svd(m)
+round(s$d, 6)
+round(s$u, 6)
+round(s$v, 6)
+e <- eigen(a)
+round(e$values, 6)
+round(e$vectors, 6)
+round(eigen(m)$vectors, 6)
+det(m)
+determinant(m)$sign
+round(backsolve(chol(a), c(1, 2)), 6)
+l <- c(1, 2, 0, 4)
+dim(l) <- c(2, 2)
+forwardsolve(l, c(1, 6))
+s <- c(1, 2, 2, 4)
+dim(s) <- c(2, 2)
+solve(s)
+chol(s)
+r <- c(0, 1, -1, 0)
+dim(r) <- c(2, 2)
+e <- eigen(r)
+e$values
+round(e$vectors, 6)
+p <- c(2, 1, 1, 2)
+dim(p) <- c(2, 2)
+round(eigen(p)$vectors, 6)
+p <- c(1, -1, -1, 1)
+dim(p) <- c(2, 2)
+round(eigen(p)$vectors, 6)
+p <- c(2, -1, 0, -1, 2, -1, 0, -1, 2)
+dim(p) <- c(3, 3)
+round(eigen(p)$vectors, 6)
@@ -0,0 +1,24 @@
+x <- c(1,2,3,4,5,6,7,8)
+y <- c(2.1,3.9,6.2,7.8,10.1,12.5,13.1,16.4)
+fit <- lm(y ~ x)
+summary(fit)
+anova(fit)
+predict(fit, newdata=list(x=c(9,10)))
+dose <- c(1,2,3,4,5,6,7,8,9,10)
+dead <- c(0,0,1,0,1,1,0,1,1,1)
+b <- glm(dead ~ dose, family=binomial)
+summary(b)
+predict(b, newdata=list(dose=c(2.5,11)), type="response")
+counts <- c(18,17,15,20,10,20,25,13,12)
+outcome <- c("1","2","3","1","2","3","1","2","3")
+treatment <- c("1","1","1","2","2","2","3","3","3")
+p <- glm(counts ~ outcome + treatment, family = poisson())
+a <- anova(p)
+fit
+round(a$Deviance, 4)
+round(a$"Pr(>Chi)", 4)
+glm(y ~ x, family=binomial)
+fit0 <- lm(y ~ 1)
+anova(fit0, fit)
+b0 <- glm(dead ~ 1, family=binomial)
+anova(b0, b)
@@ -0,0 +1,29 @@
+fr <- function(x) 100 * (x[2] - x[1] * x[1])^2 + (1 - x[1])^2
+grr <- function(x) c(-400 * x[1] * (x[2] - x[1] * x[1]) - 2 * (1 - x[1]), 200 * (x[2] - x[1] * x[1]))
+r <- optim(c(-1.2,1), fr)
+round(r$par, 4)
+r$counts
+r$convergence
+r <- optim(c(-1.2,1), fr, grr, method = "BFGS")
+round(r$par, 4)
+r$counts
+r <- optim(c(-1.2,1), fr, grr, method = "CG")
+r$counts
+r$convergence
+r <- optim(c(-1.2,1), fr, grr, method = "L-BFGS-B", lower = c(-2, 2))
+round(r$par, 4)
+r$message
+f <- function (x, a) (x - a)^2
+o <- optimize(f, c(0, 1), tol = 0.0001, a = 1/3)
+round(o$minimum, 6)
+u <- uniroot(function(x) x^3 - 2, c(0, 2), tol = 1e-9)
+round(u$root, 6)
+integrate(dnorm, 0, Inf)
+integrate(function(x) exp(-(x^2)/2), 0, Inf)
+integrate(function(x) 1/((x+1)*sqrt(x)), lower = 0, upper = Inf)
+integrate(function(x) 1/x, 0, 1, stop.on.error = FALSE)
+f <- function(x) sum((x-1:length(x))^2)
+n <- nlm(f, c(10,10))
+round(n$estimate, 6)
+n$code
+n$iterations
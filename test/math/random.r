@@ -0,0 +1,30 @@
+set.seed(1)
+round(runif(3), 7)
+set.seed(42)
+round(rnorm(3), 7)
+set.seed(1)
+round(rexp(2), 7)
+set.seed(1)
+rpois(5, 3)
+set.seed(1)
+rbinom(5, 10, 0.5)
+set.seed(123)
+sample(10)
+set.seed(42)
+sample(1:10)
+x = c(10, 20, 30)
+set.seed(42)
+sample(x, 5, replace=TRUE, prob=c(0, 1, 1))
+RNGkind()
+RNGkind("L'Ecuyer-CMRG")
+set.seed(123)
+.Random.seed
+nextRNGStream(.Random.seed)
+round(unlist(mclapply(1:4, function(i) runif(1), mc.cores=1)), 7)
+round(unlist(mclapply(1:4, function(i) runif(1), mc.cores=4)), 7)
+.Random.seed <- nextRNGStream(.Random.seed)
+round(runif(1), 7)
+RNGkind("default")
+runif(2, 1, 0)
+sample(5, 6)
+sample(0)
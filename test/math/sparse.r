@@ -0,0 +1,33 @@
+m <- sparseMatrix(i = c(1, 3, 2, 1), j = c(1, 2, 3, 1), x = c(4, 5, 6, 1))
+m
+class(m)
+dim(m)
+nnzero(m)
+t(m)
+as.matrix(m)
+rowSums(m)
+colSums(m)
+m %*% c(1, 1, 1)
+c(1, 2, 3) %*% m
+m %*% t(m)
+m[2, 3]
+m[1, ]
+m[2:3, 2:3]
+m[5]
+m * 2
+m + m
+-m
+m + 1
+s <- sparseMatrix(c(1, 2), c(2, 1), dims = c(2, 2), dimnames = list(c("a", "b"), c("x", "y")))
+s
+s["b", ]
+d <- c(1, 0, 0, 2)
+dim(d) <- c(2, 2)
+as(d, "dgCMatrix")
+d %*% s
+d %*% d
+t(d)
+c(1, 2) %*% c(3, 4)
+rowSums(d)
+sparseMatrix(c(0, 1), c(1, 1))
+m %*% d
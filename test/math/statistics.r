@@ -0,0 +1,19 @@
+sum(c(1e20,1,-1e20))
+mean(c(1,2,3,4,100), trim=0.2)
+median(c(3L,1L,2L))
+median(c(4,1,2,NA), na.rm=TRUE)
+var(c(1,2,3,4,5), c(2,4,5,4,5))
+quantile(c(1,2,3,4,5,6,7,8,9,10))
+quantile(c(1,2,3,4,5,6,7,8,9,10), probs=c(0.1,0.5), type=2)
+range(c(3,1,NA,Inf), na.rm=TRUE, finite=TRUE)
+pmax(c(1,5,3), c(4,2,6))
+pmin(c(a=1,b=5,c=3), 2)
+cumsum(c(a=1,b=2,c=3))
+cumsum(c(1L,NA,3L))
+cumprod(c(1,2,3,4))
+cummax(c(1,3,2,5))
+cummin(c(5,3,4,1))
+diff(c(1,4,9,16), differences=2)
+m = c(1,2,4,8,16,32)
+dim(m) = c(3,2)
+diff(m)
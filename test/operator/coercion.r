@@ -0,0 +1,19 @@
+c(1,"a",TRUE)
+c(TRUE,2L)
+c(1,2i)
+c(a=1,b=c(2,3))
+as.numeric(c("1","x"))
+as.integer(3.9)
+as.logical(c("T","no","FALSE"))
+as.character(c(1.5,100000,123456))
+is.numeric(1L)
+is.na(c(1,NA,3))
+unlist(list(a=1,b=list(c=2,d=3)))
+TRUE + TRUE
+5L / 2L
+"a" < "b"
+x <- c(11,22,33)
+names(x) <- c(1.5, 2, 1e-20)
+names(x)
+names(x) <- TRUE
+names(x)
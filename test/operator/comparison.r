@@ -17,6 +17,13 @@ cat("\n")
 1 < 2 < 3 < 4
 3 > 5 > 1
 3 < 5 > 1
-3 < 5 > 4 # ambiguous
+3 < 5 > 4
 5 >=3 < 1
-5 >=3 < 4 # ambiguous
+5 >=3 < 4
+cat("\n")
+typeof(1 < 2)
+x <- c(1,5,10)
+x[x >= 5]
+x[x != 5]
+c("a","b",NA) < "b"
+NULL == 1
@@ -0,0 +1,18 @@
+x = c(1,NA,3,NaN)
+x
+is.na(x)
+is.nan(x)
+anyNA(c(1,2))
+anyNA(list(1,NA))
+NA_integer_
+typeof(NA_integer_)
+typeof(NA_real_)
+typeof(NA_character_)
+c("a",NA_character_)
+NA + 1L
+sum(x)
+sum(x, na.rm=TRUE)
+mean(c(1,2,NA), na.rm=TRUE)
+max(c(2,NA,5), na.rm=TRUE)
+na.omit(c(1,NA,3))
+complete.cases(c(1,NA,3), c(4,5,NA))
@@ -0,0 +1,20 @@
+c(1,2,3,4) + c(10,20)
+c(1,2,3) + c(10,20)
+x = c(a=1,b=2,c=3,d=4)
+x * c(1,2)
+c(1,2) * x
+x[0] + 1
+c(1,2) > x[0]
+m = c(1,2,3,4)
+dim(m) = c(2,2)
+m + c(10,20)
+n = c(1,2,3,4,5,6)
+dim(n) = c(2,3)
+m + n
+m * c(1,2,3,4,5)
+c(TRUE,FALSE,NA) & c(TRUE,NA)
+c(TRUE,FALSE,NA) | NA
+!c(a=TRUE,b=FALSE)
+x > 1 & x < 4
+options(warn=-1)
+c(1,2,3) - c(1,2)
@@ -0,0 +1,22 @@
+5:1
+1.5:4
+for (i in 3:1) print(i)
+seq(0, 1, by=0.25)
+seq(2, 11, length.out=4)
+seq_len(0)
+rep(1:2, times=2, each=2)
+rep(1:2, c(3,1))
+rev(c(a=1,b=2,c=3))
+order(c(3,1,2,NA,1))
+sort(c(3,1,NA,2), decreasing=TRUE)
+rank(c(10,20,10,NA))
+rank(c(10,20,10), ties.method="min")
+unique(c(1,2,2,NA,NA,3))
+duplicated(c("a","b","a"))
+which(c(a=TRUE,b=FALSE,c=TRUE))
+which.max(c(3,7,7,1))
+match(c(2,5), c(1,2,3))
+c(1,2) %in% c(2,3)
+union(c(1,2,3), c(2,5))
+intersect(1:5, c(4,2,9))
+setdiff(1:5, c(2,4))
@@ -1 +1,4 @@
 a.a = 23. + .1 * a_2 + ..b * ._c / a...b
+a_2 <- 1; ..b <- 2; ._c <- 3; a...b <- 4
+a.a = 23. + .1 * a_2 + ..b * ._c / a...b
+a.a
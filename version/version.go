@@ -1,6 +1,11 @@
 package version
 
-import "runtime"
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
 
 const MAJOR = "0"
 const MINOR = "1.6"
@@ -9,18 +14,21 @@ const MONTH = 06
 const DAY = 11
 
 func PrintVersion() {
-	print("platform       "); println(runtime.GOARCH + "-pc-" + runtime.GOOS)
-	print("arch           "); println(runtime.GOARCH)
-	print("os             "); println(runtime.GOOS)
-	print("status         "); println("proof of concept")
-	print("major          "); println(MAJOR)
-	print("minor          "); println(MINOR)
-	print("year           "); println(YEAR)
-	print("month          "); println(MONTH)
-	print("day            "); println(DAY)
-	print("language       "); println("roq")
-	print("nickname       "); println("R core in go")
-	return
+	FprintVersion(os.Stdout)
+}
+
+func FprintVersion(w io.Writer) {
+	fmt.Fprintln(w, "platform       "+runtime.GOARCH+"-pc-"+runtime.GOOS)
+	fmt.Fprintln(w, "arch           "+runtime.GOARCH)
+	fmt.Fprintln(w, "os             "+runtime.GOOS)
+	fmt.Fprintln(w, "status         proof of concept")
+	fmt.Fprintln(w, "major          "+MAJOR)
+	fmt.Fprintln(w, "minor          "+MINOR)
+	fmt.Fprintln(w, "year          ", YEAR)
+	fmt.Fprintln(w, "month         ", MONTH)
+	fmt.Fprintln(w, "day           ", DAY)
+	fmt.Fprintln(w, "language       roq")
+	fmt.Fprintln(w, "nickname       R core in go")
 }
 
 //platform       x86_64-pc-linux-gnu         